@@ -0,0 +1,98 @@
+// Command logpush-collector is a lightweight Logpush destination that
+// measures batches where they are delivered and forwards the measurements
+// to a central LogpushEstimator. It has no database and builds without cgo:
+//
+//	CGO_ENABLED=0 go build -o logpush-collector ./cmd/collector
+//	logpush-collector -listen :8080 -url https://estimator.example.com:8080/ingest/measurements
+//
+// Point the Logpush job's destination_conf at the collector's address. On
+// SIGINT or SIGTERM it stops accepting batches and forwards what is queued
+// before exiting. See package collector for the forwarding behavior.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/cliflags"
+	"github.com/melatonein5/LogpushEstimator/src/collector"
+)
+
+// run serves the collector until ctx is done.
+//
+// Parameters:
+//   - ctx: Stops the collector when cancelled, e.g. on SIGTERM
+//   - args: Command line arguments
+//   - out: Writer for usage errors and logs
+//
+// Returns:
+//   - int: Process exit code; 0 when every measurement was forwarded, 1
+//     otherwise, and 2 for bad arguments
+func run(ctx context.Context, args []string, out io.Writer) int {
+	cfg := collector.Config{Header: make(http.Header)}
+	var listen string
+	flags := flag.NewFlagSet("logpush-collector", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.StringVar(&listen, "listen", ":8080", "Address to accept Logpush batches on")
+	flags.StringVar(&cfg.URL, "url", "", "Central instance's measurements URL, e.g. http://estimator:8080/ingest/measurements")
+	flags.DurationVar(&cfg.FlushInterval, "flush-interval", collector.DefaultFlushInterval, "Longest wait before measurements are forwarded")
+	flags.IntVar(&cfg.MaxPending, "max-pending", collector.DefaultMaxPending, "Measurements queued while the central instance is unreachable")
+	flags.Var(cliflags.Header(cfg.Header), "header", "Extra forwarding request header as \"Name: value\"; repeatable")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	cfg.Logger = slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	c, err := collector.New(cfg)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 2
+	}
+	server := &http.Server{Addr: listen, Handler: c, ReadHeaderTimeout: 10 * time.Second}
+	served := make(chan error, 1)
+	go func() { served <- server.ListenAndServe() }()
+	cfg.Logger.Info("Collector listening", "address", listen, "url", cfg.URL)
+
+	forwarded := make(chan error, 1)
+	runCtx, stopRun := context.WithCancel(context.Background())
+	go func() { forwarded <- c.Run(runCtx) }()
+
+	code := 0
+	select {
+	case <-ctx.Done():
+	case err := <-served:
+		cfg.Logger.Error("Collector server failed", "error", err)
+		code = 1
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		cfg.Logger.Warn("Collector server did not shut down cleanly", "error", err)
+	}
+
+	// Batches accepted before shutdown are forwarded before exiting
+	stopRun()
+	if err := <-forwarded; err != nil {
+		cfg.Logger.Error("Measurements left undelivered", "error", err, "pending", c.Stats().Pending)
+		code = 1
+	}
+	stats := c.Stats()
+	cfg.Logger.Info("Collector stopped", "batches", stats.Batches, "forwarded", stats.Forwarded, "dropped", stats.Dropped)
+	return code
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout)
+	stop()
+	os.Exit(code)
+}
@@ -31,8 +31,8 @@ func TestFullApplicationFlow(t *testing.T) {
 	defer db.Close()
 
 	// Create test servers
-	ingestionServer := createIngestionServer(db)
-	guiServer := createGUIServer(db)
+	ingestionServer := createIngestionServer(testEstimator(t, db))
+	guiServer := createGUIServer(testEstimator(t, db))
 
 	ingestionTestServer := httptest.NewServer(ingestionServer.Handler)
 	defer ingestionTestServer.Close()
@@ -180,8 +180,8 @@ func TestConcurrentIngestAndQuery(t *testing.T) {
 	defer db.Close()
 
 	// Create test servers
-	ingestionServer := createIngestionServer(db)
-	guiServer := createGUIServer(db)
+	ingestionServer := createIngestionServer(testEstimator(t, db))
+	guiServer := createGUIServer(testEstimator(t, db))
 
 	ingestionTestServer := httptest.NewServer(ingestionServer.Handler)
 	defer ingestionTestServer.Close()
@@ -276,8 +276,8 @@ func TestAPITimeRangeIntegration(t *testing.T) {
 	defer db.Close()
 
 	// Create servers
-	ingestionServer := createIngestionServer(db)
-	guiServer := createGUIServer(db)
+	ingestionServer := createIngestionServer(testEstimator(t, db))
+	guiServer := createGUIServer(testEstimator(t, db))
 
 	// Insert test data by using the API
 	ingestionTestServer := httptest.NewServer(ingestionServer.Handler)
@@ -363,8 +363,8 @@ func TestErrorHandling(t *testing.T) {
 	defer db.Close()
 
 	// Create servers
-	ingestionServer := createIngestionServer(db)
-	guiServer := createGUIServer(db)
+	ingestionServer := createIngestionServer(testEstimator(t, db))
+	guiServer := createGUIServer(testEstimator(t, db))
 
 	ingestionTestServer := httptest.NewServer(ingestionServer.Handler)
 	defer ingestionTestServer.Close()
@@ -433,8 +433,8 @@ func TestErrorHandling(t *testing.T) {
 		}
 		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusInternalServerError {
-			t.Errorf("Expected 500 for invalid time format, got %d", resp.StatusCode)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for invalid time format, got %d", resp.StatusCode)
 		}
 
 		var response handlers.APIResponse
@@ -448,3 +448,49 @@ func TestErrorHandling(t *testing.T) {
 		}
 	})
 }
+
+func TestFeatureGatedEndpointsIntegration(t *testing.T) {
+	tempFile := "test_feature_gates.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	guiTestServer := httptest.NewServer(createGUIServer(testEstimator(t, db)).Handler)
+	defer guiTestServer.Close()
+
+	defer featureFlags.Set("burst-detection", featureFlags.Enabled("burst-detection"))
+
+	featureFlags.Set("burst-detection", false)
+	resp, err := http.Get(guiTestServer.URL + "/api/stats/bursts")
+	if err != nil {
+		t.Fatalf("Failed to call bursts endpoint: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for disabled feature, got %d", resp.StatusCode)
+	}
+
+	featureFlags.Set("burst-detection", true)
+	resp, err = http.Get(guiTestServer.URL + "/api/stats/bursts")
+	if err != nil {
+		t.Fatalf("Failed to call bursts endpoint: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for enabled feature, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(guiTestServer.URL + "/api/version")
+	if err != nil {
+		t.Fatalf("Failed to call version endpoint: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for version endpoint, got %d", resp.StatusCode)
+	}
+}
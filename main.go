@@ -25,36 +25,294 @@
 // The application will start both servers and be ready to accept log data and serve
 // the dashboard interface.
 //
+// With LPE_PORT_FALLBACK=true, a server whose port is taken listens on an
+// ephemeral port instead. The addresses actually bound are logged, listed
+// under "listeners" in /api/version, and written with the process ID to
+// LPE_READY_FILE (if set) once the estimator is ready.
+//
+// On SIGINT or SIGTERM both servers stop accepting connections and finish
+// their in-flight requests, waiting up to LPE_SHUTDOWN_TIMEOUT (default
+// 10s), before the background jobs stop and the database is closed, so no
+// accepted batch is lost and the SQLite file is left clean.
+//
+// With LPE_INGEST_BUFFER set to a number of batches, /ingest answers as
+// soon as a batch is queued, and a background writer inserts queued batches
+// in groups of up to LPE_INGEST_FLUSH_SIZE (default 100) at least every
+// LPE_INGEST_FLUSH_INTERVAL (default 250ms), which keeps SQLite commits off
+// the request path. Both are tuned to the load from there unless
+// LPE_INGEST_AUTOTUNE=false. The queue is written out on shutdown; batches
+// still queued when the process crashes are lost.
+//
 // # API Endpoints
 //
 // Ingestion Server (8080):
 //   - POST /ingest - Accept log data for size tracking
-//   - GET /health - Health check endpoint
+//   - POST /ingest/{zone} - Accept log data for a Cloudflare zone
+//   - POST /t/{tenant}/ingest - Accept log data for a tenant
+//   - POST /ingest/measurements - Accept batch measurements from an edge collector (cmd/collector)
+//   - POST /t/{tenant}/ingest/measurements - Accept collector measurements for a tenant
+//   - GET /health - Health check endpoint with database status and cache warm-up progress
 //
 // GUI Server (8081):
 //   - GET / - Dashboard interface
 //   - GET /api/stats/summary - Summary statistics
 //   - GET /api/logs/recent - Recent log entries
 //   - GET /api/logs/time-range - Time-filtered log data
-//   - GET /api/charts/time-series - Time series chart data
+//   - GET /api/logs/since - Records after an ID cursor
+//   - GET /api/logs/count - Batch, record and byte totals without the records
+//   - GET /api/charts/time-series - Time series chart data at a resolution suited to the range
 //   - GET /api/charts/size-breakdown - Size breakdown chart data
+//   - GET /api/annotations - Chart annotations such as Cloudflare incidents affecting Logpush
+//   - GET /api/charts/minutes - Per-minute chart data (rolling 48h window)
+//   - GET /api/stats/bursts - Burst detection over per-minute data
+//   - GET /api/stats/rates - Bytes and batches per second over the last 1m, 5m and 1h
+//   - GET /api/slo/ingest - Ingest availability and error budget
+//   - GET /api/stats/forwarding - Relay latency and status codes per upstream destination
+//   - GET /api/stats/diff - Compare totals, datasets and batch sizes between two windows
+//   - GET /api/stats/change-points - Days on which the daily volume shifted to a new level
+//   - GET /api/stats/dimensions - Volume per parsed dimension value (dataset-parsers flag)
+//   - GET /api/charts/by-dimension - Volume over time per dimension value, e.g. dim=status_class
+//   - GET /api/dimensions - Dimensions registered with the dataset parsers
+//   - GET /api/dimensions/query - Dimension totals, e.g. dimension=colo&group_by=day,value
+//   - GET /api/samples - Redacted samples of ingested payloads
+//   - GET /api/samples/redactions - Audit of redactions applied to samples
+//   - GET /api/version - Build version and feature flag state
+//   - GET, PUT /api/preferences - Per-browser dashboard preferences
+//   - GET, POST /api/views - List and save named query definitions
+//   - GET, PUT, DELETE /api/views/{name} - Manage a single saved view
+//   - GET /views/{name} - Dashboard page for a saved view
+//   - GET, POST /api/scenarios - List and save estimation scenarios
+//   - GET, PUT, DELETE /api/scenarios/{name} - Manage a single scenario
+//   - GET /api/scenarios/compare - Monthly volume and cost of scenarios side by side
+//   - GET /api/recommendations - Ranked cost reduction suggestions with estimated monthly savings
+//   - GET /api/estimate/cost - Projected monthly storage, request and egress cost under a pricing model or preset
+//   - GET /api/estimate/forecast - Bytes and log lines projected to the end of the month, with confidence bounds
+//   - GET /api/dashboard/layout - Widgets the dashboard renders, with their data endpoints and parameters
+//   - GET /api/estimates/coverage - Logpush volume versus Cloudflare zone analytics
+//   - GET /api/estimates/bandwidth - Destination throughput (Mbit/s, events/s, requests/s) for observed bursts
+//   - POST /api/cloudflare/jobs/create - Create or preview a Logpush job pushing to this instance
+//   - GET /api/cloudflare/jobs - Tracked Logpush jobs with their health
+//   - GET /api/cloudflare/jobs/{id}/health - Last synced status of a Logpush job
+//   - GET /api/export - Raw records in a time range streamed as CSV or NDJSON
+//   - GET /api/export/csv - Raw records in a time range as CSV
+//   - GET /api/export/manifest - Checksummed manifest for an export
+//   - POST /api/export/verify - Verify a previous export against the database
+//   - POST /api/exports - Build an export in the background
+//   - GET, DELETE /api/exports/{id} - Status of an export job, or remove it and its file
+//   - GET /api/exports/{id}/download - The finished export file
+//   - GET, PUT /api/admin/config - Read or declaratively apply configuration
+//   - GET /api/admin/config/export - Export configuration as a YAML document
+//   - POST /api/admin/config/import - Replace configuration from an exported document
+//   - GET, POST /api/alerts - List and create alert rules (supports dry_run)
+//   - GET, PUT, DELETE /api/alerts/{name} - Manage a rule with updated_at preconditions
+//   - POST /api/alerts/{name}/test - Evaluate a rule now and publish a test alert
+//   - GET, POST /api/budgets - List and create budgets (supports dry_run)
+//   - GET, PUT, DELETE /api/budgets/{name} - Manage a budget with updated_at preconditions
+//   - POST /api/budgets/{name}/test - Current spend of a budget and a test alert
+//   - POST /api/admin/tenants/bulk - Provision tenants, ingest tokens and budgets from a manifest
+//   - POST /api/admin/tokens/{name}/rotate - New token secret; the old one stays valid for a grace period
+//   - POST /api/admin/delete-range - Delete records in a time range, optionally of one dataset (supports dry_run)
+//   - POST /api/admin/prune - Delete records older than the retention (supports dry_run)
+//   - POST /api/admin/purge-dataset - Delete every record of one dataset (supports dry_run)
+//   - GET, PUT /api/admin/retention - Retention policy the background janitor prunes records by
+//   - GET /api/admin/trash - Deleted record batches that can still be restored
+//   - POST /api/admin/trash/restore - Restore a deleted batch
+//   - GET, POST /api/admin/integrity - Integrity issues, or run the checks now
+//   - GET /api/admin/shards - Monthly shard files and whether their records are read
+//   - GET /api/admin/recovery - Startup recovery scan after an unclean shutdown
+//   - GET /api/admin/api-stats - Per-route request counts, statuses and latency percentiles
+//   - GET /api/admin/events - Domain events published since startup
+//   - GET /api/admin/ingest-pipeline - How ingested batches are written, and insert latency
+//   - GET, DELETE /api/admin/labels - Label cardinality against label_limits, or remove a label
+//   - GET /api/admin/relay - Relay circuit breaker, queued batches and outcomes
+//   - GET /api/admin/outbox - Queued, delivered and dead-lettered webhook notifications
+//   - POST /api/admin/outbox/retry - Resend an undelivered webhook notification
+//   - GET /api/admin/backup - Consistent copy of the database (encrypted when a key is set)
+//   - GET /api/tenants - Tenants with stored records
+//   - GET /api/datasets - Batches, records and bytes per Logpush dataset
+//   - GET /api/zones - Batches, records and bytes per Cloudflare zone
+//   - GET /api/labels - Batches, records and bytes per ingest label
+//   - GET /api/reports/chargeback - Monthly cost allocated across tenants (JSON or CSV)
+//   - GET /t/{tenant}/ - Dashboard scoped to a tenant
+//   - GET /t/{tenant}/api/* - Tenant-scoped subset of the log, stats and chart endpoints
 //   - GET /static/* - Static assets (CSS, JS, images)
+//   - GET /health - Health check endpoint, as on the ingestion server
+//   - GET /metrics - Ingest rate gauges in the Prometheus text format
+//
+// # Feature Flags
+//
+// Experimental endpoints are disabled by default and enabled through the
+// LPE_FEATURES environment variable (comma-separated flag names) or per-flag
+// LPE_FEATURE_<NAME>=true|false overrides. Disabled endpoints return 404.
+//
+// # Cloudflare Integration
+//
+// Setting LPE_CLOUDFLARE_API_TOKEN and LPE_CLOUDFLARE_ZONES enables an hourly
+// sync of zone request counts from the GraphQL Analytics API, which
+// /api/estimates/coverage compares against ingested records. With a token
+// carrying Logs Edit, /api/cloudflare/jobs/create sets up Logpush jobs that
+// push to this instance. The status of those jobs is synced every ten minutes
+// and surfaced through /api/cloudflare/jobs/{id}/health and the dashboard.
+// Setting LPE_CLOUDFLARE_STATUS=true polls the public status page, with or
+// without a token, and marks incidents affecting Logpush on the time series
+// chart through /api/annotations.
+//
+// # Google Sheets
+//
+// Setting LPE_SHEETS_SPREADSHEET_ID and LPE_SHEETS_CREDENTIALS (or
+// LPE_SHEETS_CREDENTIALS_FILE) to a service account key pushes monthly
+// totals, and optionally the per-dataset breakdown and chargeback, to one tab
+// each of the spreadsheet every LPE_SHEETS_INTERVAL (default 24h); see the
+// sheets package.
+//
+// # Relay Mode
+//
+// Setting LPE_RELAY_URL places the estimator in front of an existing
+// destination: /ingest measures each batch, forwards it unchanged to the
+// URL and answers Logpush with the destination's status. LPE_RELAY_KIND
+// selects http (default), s3 for a presigned PUT URL or splunk-hec, with
+// LPE_RELAY_TOKEN as the HEC or bearer token; see the relay package.
+// Batches the destination cannot take are queued in the database, up to
+// LPE_RELAY_QUEUE_MAX_MB (default 1024), and retried in the background, so
+// a destination outage does not fail Logpush delivery.
+//
+// # Encryption
+//
+// Setting LPE_ENCRYPTION_KEY (or LPE_ENCRYPTION_KEY_FILE) to a base64 256-bit
+// key encrypts everything that leaves the database as a file: CSV exports,
+// chargeback CSVs and /api/admin/backup downloads. Sealed files are decrypted with
+//
+//	./logpush-estimator decrypt < logpush-backup.db.enc > logpush-backup.db
+//
+// # Secrets
+//
+// LPE_CLOUDFLARE_API_TOKEN, LPE_ENCRYPTION_KEY, LPE_SHEETS_CREDENTIALS,
+// LPE_RELAY_TOKEN and configured API token secrets may be written as references such as
+// ${file:/run/secrets/token}, ${vault:secret/data/lpe#api_token} or
+// ${aws-sm:prod/lpe#api_token}; see the secrets package. Environment
+// references are resolved at startup and token references whenever the
+// configuration is reloaded.
+//
+// # Tenants
+//
+// Batches posted to /t/{tenant}/ingest are stored under that tenant. Its
+// dashboard at /t/{tenant}/ and the API below it only see the tenant's
+// records. With LPE_TENANT_DOMAIN=lpe.example.com, {tenant}.lpe.example.com
+// serves the same tenant-scoped pages at the root of the subdomain.
+//
+// # Zones
+//
+// Batches posted to /ingest/{zone}, or with ?zone= or an X-Logpush-Zone
+// header, are stored under that Cloudflare zone name or ID, so one instance
+// can receive the Logpush jobs of many zones. /api/zones lists them, and the
+// record endpoints that accept ?dataset= also accept ?zone=.
+//
+// # Labels
+//
+// Each X-LPE-Label-{key}: {value} header on an ingested batch is stored as a
+// label of the batch (at most 8), a free-form tag such as env=prod. The
+// record endpoints filter by ?label=env:prod, and /api/labels lists them.
 //
 // # Data Storage
 //
 // LogpushEstimator uses SQLite for data persistence, storing log size records
-// with timestamps for analysis and visualization.
+// with timestamps for analysis and visualization. The database lives in the
+// platform's data directory (e.g. ~/.local/share/logpush-estimator, or
+// systemd's StateDirectory); see the paths package for the defaults and the
+// LPE_DATA_DIR, LPE_DB_PATH, LPE_TEMPLATE_DIR, LPE_EXPORT_DIR and
+// LPE_SNAPSHOT_FILE overrides.
+// An advisory lock on logpush.db.lock stops a second instance from
+// starting on the same database.
+// The most recent records (LPE_RECENT_BUFFER_SIZE, default 10000) are also
+// kept in memory, so dashboards and feeds polling for new data rarely read
+// the database. The summary, time series and breakdown the dashboard loads
+// first are computed in the background at startup and cached, with progress
+// reported at /health. On shutdown the cache, API statistics and event log
+// are saved to LPE_SNAPSHOT_FILE (default cache-snapshot.json in the data
+// directory) and restored at the next start, so a restart during peak
+// ingest does not begin with a cold dashboard.
+// If the previous process crashed or was killed, which shows in the
+// process ID left in the lock file or in leftover SQLite journals, the
+// database is checked, the per-minute rollup is rebuilt and the saved
+// dashboard cache is discarded before serving; see /api/admin/recovery.
+// With LPE_DB_SHARDING=monthly, records from past months are moved hourly
+// into one file per month next to the database (logpush-2025-09.db), which
+// can be backed up once and moved to cold storage; see
+// /api/admin/shards.
+//
+// # Read-Only Mode
+//
+// ./logpush-estimator --read-only (or LPE_READ_ONLY=true) serves the
+// dashboards and API from a replica or backup of a writable instance's
+// database, for example to expose them publicly while the writable
+// instance stays internal. Ingestion answers 403, as do API requests that
+// would change state, and no background jobs run. The database file is
+// opened read-only, without the writer's lock or schema migrations, so the
+// process replicating it can keep writing.
+//
+// # Service Managers
+//
+// Under systemd with Type=notify, or as a Windows service, the estimator
+// reports itself ready only once both servers listen and the dashboard
+// cache is warm, and pings the systemd watchdog while its database is
+// readable; see the service package.
+//
+// # Health Checks
+//
+// logpush-estimator check --url http://localhost:8081 probes /health on a
+// running instance and exits non-zero when it is unreachable or its database
+// cannot be read, for container HEALTHCHECKs in images without curl.
+//
+// # Simulation
+//
+// logpush-estimator simulate -url http://localhost:8080/ingest -rate 200
+// replays Cloudflare Logpush deliveries (ownership challenge, gzip NDJSON
+// batches, retries) against a running instance; see the simulator package.
+//
+// # Embedding
+//
+// The servers and background jobs are assembled by the logpushestimator
+// package, which other Go services can use to mount the estimator in their
+// own binaries. Ingestion and the background jobs publish domain events
+// (ingest.received, retention.pruned, alert.fired, job.synced) on the
+// Estimator's events.Bus, which embedding services can subscribe to.
+// Webhooks in the configuration document receive the topics they list
+// through a database-backed outbox (see the notify package).
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/melatonein5/LogpushEstimator/src/cliflags"
+	"github.com/melatonein5/LogpushEstimator/src/cloudflare"
 	"github.com/melatonein5/LogpushEstimator/src/database"
-	"github.com/melatonein5/LogpushEstimator/src/gui/handlers"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+	"github.com/melatonein5/LogpushEstimator/src/features"
+	"github.com/melatonein5/LogpushEstimator/src/logpushestimator"
+	"github.com/melatonein5/LogpushEstimator/src/paths"
+	"github.com/melatonein5/LogpushEstimator/src/relay"
+	"github.com/melatonein5/LogpushEstimator/src/secrets"
+	"github.com/melatonein5/LogpushEstimator/src/service"
+	"github.com/melatonein5/LogpushEstimator/src/sheets"
+	"github.com/melatonein5/LogpushEstimator/src/simulator"
 )
 
 // Default server configuration
@@ -65,180 +323,626 @@ var (
 	guiPort = ":8081"
 )
 
-// slogger provides structured logging throughout the application
-var slogger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+// serviceName is the name the process registers under when run as a
+// Windows service
+var serviceName = "LogpushEstimator"
+
+// portFallback makes the servers listen on ephemeral ports when their
+// configured ports are unavailable, read from LPE_PORT_FALLBACK=true.
+var portFallback bool
+
+// readOnly serves dashboards without ingestion, state-changing API
+// requests or background jobs, set with --read-only or LPE_READ_ONLY=true.
+var readOnly bool
 
-// healthHandler provides a health check endpoint that returns service status.
-// It responds with a JSON object containing the service status and name.
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	slogger.Info("Health check request", "remote_addr", r.RemoteAddr)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	response := map[string]string{
-		"status":  "ok",
-		"service": "LogpushEstimator",
-	}
-	json.NewEncoder(w).Encode(response)
+// shutdownTimeout bounds how long the servers wait for in-flight requests
+// on SIGINT or SIGTERM before closing their connections, read from
+// LPE_SHUTDOWN_TIMEOUT.
+var shutdownTimeout = 10 * time.Second
+
+// ingestBuffer configures asynchronous ingestion writes, read from
+// LPE_INGEST_BUFFER, LPE_INGEST_FLUSH_SIZE, LPE_INGEST_FLUSH_INTERVAL and
+// LPE_INGEST_AUTOTUNE. A zero size writes each batch before answering.
+var ingestBuffer struct {
+	size, flushSize int
+	flushInterval   time.Duration
+	autoTune        bool
 }
 
-// makeIngestionHandler creates an HTTP handler for log data ingestion.
-// It accepts POST requests containing log data and stores the payload size
-// along with a timestamp in the database for monitoring purposes.
-//
-// The handler validates the HTTP method (must be POST), reads the request body,
-// measures its size, and stores this information in the database using the
-// provided SQLiteController.
-//
-// Returns appropriate HTTP status codes:
-//   - 200 OK: Successfully processed and stored the log data
-//   - 400 Bad Request: Empty body or failed to read body
-//   - 405 Method Not Allowed: Non-POST requests
-//   - 500 Internal Server Error: Database insertion failures
-func makeIngestionHandler(db *database.SQLiteController) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		slogger.Info("Ingestion request received",
-			"method", r.Method,
-			"remote_addr", r.RemoteAddr,
-			"user_agent", r.UserAgent(),
-			"content_length", r.ContentLength)
-
-		if r.Method != http.MethodPost {
-			slogger.Warn("Invalid HTTP method", "method", r.Method, "remote_addr", r.RemoteAddr)
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			w.Write([]byte("Method not allowed"))
-			return
-		}
+// readyFile, read from LPE_READY_FILE, is written with the listening
+// addresses once the servers are ready; empty disables it.
+var readyFile string
 
-		// Read the entire request body to measure its size
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			slogger.Error("Failed to read request body", "error", err, "remote_addr", r.RemoteAddr)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Failed to read request body"))
-			return
-		}
-		defer r.Body.Close()
+// version is the application version, overridden at build time with
+// -ldflags "-X main.version=..."
+var version = "dev"
 
-		// Calculate the actual body size
-		bodySize := int64(len(body))
+// featureFlags gates experimental endpoints; see the features package for the
+// environment variables that control it.
+var featureFlags = features.NewRegistry(features.Defaults()...)
 
-		// Validate body size is positive (not empty)
-		if bodySize <= 0 {
-			slogger.Warn("Empty request body received", "body_size", bodySize, "remote_addr", r.RemoteAddr)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Request body cannot be empty"))
-			return
-		}
+// cloudflareSettings holds the optional Cloudflare integration settings,
+// read from the environment at startup.
+var cloudflareSettings cloudflare.Settings
 
-		// Insert the computed body size into database
-		err = db.InsertLogSize(bodySize)
-		if err != nil {
-			slogger.Error("Failed to insert log size", "error", err, "body_size", bodySize, "remote_addr", r.RemoteAddr)
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("Failed to write log size"))
-			return
-		}
+// sheetsSettings holds the optional Google Sheets report push settings,
+// read from the environment at startup.
+var sheetsSettings sheets.Settings
 
-		slogger.Info("Log size inserted successfully", "body_size", bodySize, "remote_addr", r.RemoteAddr)
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	}
+// relaySettings holds the optional upstream ingested batches are forwarded
+// to, read from the environment at startup.
+var relaySettings relay.Settings
+
+// encryptionKey seals exports and backups; nil when no key is configured.
+var encryptionKey *encryption.Key
+
+// secretResolver resolves ${provider:path} secret references in the
+// environment and in configured token secrets; nil leaves them as written.
+var secretResolver *secrets.Resolver
+
+// secretEnvVars lists the environment variables that may hold secret
+// references instead of plaintext values.
+var secretEnvVars = []string{"LPE_CLOUDFLARE_API_TOKEN", "LPE_ENCRYPTION_KEY", "LPE_SHEETS_CREDENTIALS", "LPE_RELAY_TOKEN"}
+
+// resolveEnv returns an environment lookup with secret references in
+// secretEnvVars resolved through secretResolver.
+func resolveEnv() (func(string) string, error) {
+	secretResolver = secrets.NewResolver(os.Getenv)
+	return secretResolver.Getenv(context.Background(), os.Getenv, secretEnvVars...)
 }
 
-// createIngestionServer creates and configures the HTTP server for log data ingestion.
-// The server listens on the configured ingestion port and provides endpoints for
-// receiving log data and health checks.
+// storagePaths locates the database, template overrides and exports,
+// resolved from the environment at startup.
+var storagePaths paths.Paths
+
+// tenantDomain is the parent domain whose subdomains scope the GUI to a
+// tenant, read from LPE_TENANT_DOMAIN at startup; empty disables it.
+var tenantDomain string
+
+// slogger provides structured logging throughout the application
+var slogger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+// newEstimator assembles the estimator from the settings read from the
+// environment at startup.
+func newEstimator(db *database.SQLiteController) (*logpushestimator.Estimator, error) {
+	return logpushestimator.New(logpushestimator.Config{
+		DB:            db,
+		Logger:        slogger,
+		Version:       version,
+		Features:      featureFlags,
+		Cloudflare:    cloudflareSettings,
+		Sheets:        sheetsSettings,
+		Relay:         relaySettings,
+		EncryptionKey: encryptionKey,
+		Secrets:       secretResolver,
+		TenantDomain:  tenantDomain,
+		TemplateDir:   storagePaths.Templates,
+		ExportDir:     storagePaths.Exports,
+		SnapshotFile:  storagePaths.Snapshot,
+		ReadOnly:      readOnly,
+
+		IngestBufferSize:    ingestBuffer.size,
+		IngestFlushSize:     ingestBuffer.flushSize,
+		IngestFlushInterval: ingestBuffer.flushInterval,
+		IngestAutoTune:      ingestBuffer.autoTune,
+	})
+}
+
+// createIngestionServer creates the HTTP server for log data ingestion,
+// listening on the configured ingestion port.
 //
 // Endpoints:
 //   - POST /ingest: Accept log data for size tracking
+//   - POST /ingest/{zone}: Accept log data for a Cloudflare zone
+//   - POST /t/{tenant}/ingest: Accept log data for a tenant
 //   - GET /health: Health check endpoint
-func createIngestionServer(db *database.SQLiteController) *http.Server {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/ingest", makeIngestionHandler(db))
-	mux.HandleFunc("/health", healthHandler)
+func createIngestionServer(est *logpushestimator.Estimator) *http.Server {
 	return &http.Server{
 		Addr:    ingestionPort,
-		Handler: mux,
+		Handler: est.IngestHandler,
 	}
 }
 
-// createGUIServer creates and configures the HTTP server for the web dashboard.
-// The server provides both the web interface and REST API endpoints for
-// accessing stored log data and analytics.
+// createGUIServer creates the HTTP server for the web dashboard and REST
+// API, listening on the configured GUI port.
 //
 // Endpoints:
 //   - GET /: Main dashboard interface
 //   - GET /dashboard: Alternative dashboard path
+//   - GET /views/{name}: Dashboard rendered from a saved view
 //   - GET /api/*: REST API endpoints for data access
+//   - GET /t/{tenant}/, /t/{tenant}/api/*: Tenant-scoped dashboard and API
 //   - GET /static/*: Static assets (CSS, JS, images)
-func createGUIServer(db *database.SQLiteController) *http.Server {
-	mux := http.NewServeMux()
-
-	// Dashboard routes (specific paths only)
-	mux.HandleFunc("/dashboard", handlers.MakeDashboardHandler(slogger))
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		// Only serve dashboard for exact root path, otherwise 404
-		if r.URL.Path == "/" {
-			handlers.MakeDashboardHandler(slogger)(w, r)
-		} else {
-			http.NotFound(w, r)
-		}
-	})
+//   - GET /health: Health check endpoint
+//   - GET /metrics: Prometheus ingest rate gauges
+func createGUIServer(est *logpushestimator.Estimator) *http.Server {
+	return &http.Server{
+		Addr:    guiPort,
+		Handler: est.GUIHandler,
+	}
+}
+
+// runDecrypt decrypts a sealed export or backup from in to out using the
+// key from the environment.
+//
+// Returns:
+//   - int: Process exit code
+func runDecrypt(in io.Reader, out io.Writer, getenv func(string) string) int {
+	key, err := encryption.KeyFromEnv(getenv)
+	if err != nil {
+		slogger.Error("Invalid encryption key", "error", err)
+		return 1
+	}
+	if key == nil {
+		slogger.Error("LPE_ENCRYPTION_KEY or LPE_ENCRYPTION_KEY_FILE must be set to decrypt")
+		return 1
+	}
+	sealed, err := io.ReadAll(in)
+	if err != nil {
+		slogger.Error("Failed to read input", "error", err)
+		return 1
+	}
+	plain, err := key.Open(sealed)
+	if err != nil {
+		slogger.Error("Failed to decrypt", "error", err)
+		return 1
+	}
+	if _, err := out.Write(plain); err != nil {
+		slogger.Error("Failed to write output", "error", err)
+		return 1
+	}
+	return 0
+}
 
-	// API routes
-	apiHandlers := handlers.MakeAPIHandlers(db, slogger)
-	for path, handler := range apiHandlers {
-		mux.HandleFunc(path, handler)
+// runCheck probes a running estimator's /health endpoint, for container
+// HEALTHCHECKs in images without curl:
+//
+//	logpush-estimator check --url http://localhost:8081
+//
+// The check passes when /health answers 200 with status ok, which requires
+// the database to be readable.
+//
+// Returns:
+//   - int: Process exit code; 0 when healthy, 1 otherwise
+func runCheck(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(out)
+	url := flags.String("url", "http://localhost"+ingestionPort, "Base URL of the ingestion or GUI server")
+	timeout := flags.Duration("timeout", 5*time.Second, "Time to wait for a response")
+	if err := flags.Parse(args); err != nil {
+		return 2
 	}
 
-	// Static file serving
-	mux.HandleFunc("/static/", handlers.MakeStaticFileHandler(slogger))
+	client := &http.Client{Timeout: *timeout}
+	resp, err := client.Get(strings.TrimSuffix(*url, "/") + "/health")
+	if err != nil {
+		fmt.Fprintf(out, "unhealthy: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
 
-	return &http.Server{
-		Addr:    guiPort,
-		Handler: mux,
+	var health logpushestimator.HealthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		fmt.Fprintf(out, "unhealthy: %s returned an invalid health response: %v\n", resp.Request.URL, err)
+		return 1
 	}
+	if resp.StatusCode != http.StatusOK || health.Status != logpushestimator.HealthOK {
+		fmt.Fprintf(out, "unhealthy: status %q (HTTP %d), database %q\n", health.Status, resp.StatusCode, health.Database)
+		return 1
+	}
+	fmt.Fprintf(out, "healthy: database %s, cache warm-up %s\n", health.Database, health.Warmup.State)
+	return 0
+}
+
+// runSimulate replays Cloudflare Logpush deliveries against a running
+// instance, for integration tests and for validating a deployment:
+//
+//	logpush-estimator simulate -url http://localhost:8080/ingest -rate 200 -duration 1m
+//
+// See the simulator package for the delivery behavior reproduced.
+//
+// Parameters:
+//   - ctx: Ends the simulation early when cancelled, e.g. on Ctrl-C
+//   - args: Command line arguments after "simulate"
+//   - out: Writer for usage errors and the summary
+//
+// Returns:
+//   - int: Process exit code; 0 when every batch was delivered, 1 otherwise,
+//     2 for invalid arguments
+func runSimulate(ctx context.Context, args []string, out io.Writer) int {
+	cfg := simulator.Config{Header: make(http.Header)}
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.StringVar(&cfg.URL, "url", "http://localhost"+ingestionPort+"/ingest", "Ingestion URL, as in the Logpush job's destination_conf")
+	flags.StringVar(&cfg.Dataset, "dataset", "http_requests", "Dataset to generate: "+strings.Join(simulator.Datasets, ", "))
+	flags.Float64Var(&cfg.Rate, "rate", simulator.DefaultRate, "Records generated per second")
+	flags.DurationVar(&cfg.Duration, "duration", time.Minute, "How long to generate records; 0 runs until interrupted")
+	flags.DurationVar(&cfg.Interval, "interval", simulator.DefaultInterval, "Longest wait between uploads")
+	flags.IntVar(&cfg.MaxUploadRecords, "max-upload-records", simulator.DefaultMaxUploadRecords, "Records per batch before an early upload")
+	flags.IntVar(&cfg.MaxUploadBytes, "max-upload-bytes", simulator.DefaultMaxUploadBytes, "Uncompressed bytes per batch before an early upload")
+	flags.IntVar(&cfg.MaxRetries, "retries", simulator.DefaultMaxRetries, "Retries of a failed upload")
+	flags.Int64Var(&cfg.Seed, "seed", 1, "Seed for record contents")
+	flags.Var(cliflags.Header(cfg.Header), "header", "Extra request header as \"Name: value\"; repeatable")
+	flags.BoolVar(&cfg.SkipOwnershipChallenge, "skip-challenge", false, "Skip the destination ownership challenge")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = -1
+	}
+	cfg.Logger = slogger
+
+	stats, err := simulator.Run(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(out, "simulation failed: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(out, "delivered %d batches (%d records, %d bytes, %d bytes gzip); %d retries, %d batches (%d records) dropped\n",
+		stats.Batches, stats.Records, stats.UncompressedBytes, stats.CompressedBytes, stats.Retries, stats.FailedBatches, stats.FailedRecords)
+	if stats.FailedBatches > 0 {
+		return 1
+	}
+	return 0
 }
 
 func main() {
-	slogger.Info("Starting LogpushEstimator", "ingestion_port", ingestionPort, "gui_port", guiPort)
+	// The probe runs inside the container next to the server, so it needs
+	// none of the server's configuration
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		os.Exit(runCheck(os.Args[2:], os.Stdout))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		code := runSimulate(ctx, os.Args[2:], os.Stdout)
+		stop()
+		os.Exit(code)
+	}
 
-	db, err := database.NewSQLiteController("", slogger)
+	getenv, err := resolveEnv()
 	if err != nil {
-		slogger.Error("Failed to initialize SQLite database", "error", err)
+		slogger.Error("Failed to resolve secret references", "error", err)
+		os.Exit(1)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "decrypt" {
+		os.Exit(runDecrypt(os.Stdin, os.Stdout, getenv))
+	}
+
+	serverFlags := flag.NewFlagSet("logpush-estimator", flag.ContinueOnError)
+	serverFlags.BoolVar(&readOnly, "read-only", getenv("LPE_READ_ONLY") == "true",
+		"Serve dashboards only: no ingestion, state-changing API requests or background jobs")
+	if err := serverFlags.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+
+	slogger.Info("Starting LogpushEstimator", "version", version, "ingestion_port", ingestionPort, "gui_port", guiPort, "read_only", readOnly)
+
+	for _, name := range featureFlags.ApplyEnv(getenv) {
+		slogger.Warn("Unknown feature flag in LPE_FEATURES", "flag", name)
+	}
+	for _, flag := range featureFlags.List() {
+		slogger.Info("Feature flag", "name", flag.Name, "enabled", flag.Enabled)
+	}
+
+	// A configured but unusable key must not silently fall back to
+	// writing plaintext files
+	key, err := encryption.KeyFromEnv(getenv)
+	if err != nil {
+		slogger.Error("Invalid encryption key", "error", err)
+		os.Exit(1)
+	}
+	encryptionKey = key
+	slogger.Info("Export and backup encryption", "enabled", encryptionKey != nil)
+
+	storagePaths, err = paths.Resolve(getenv)
+	if err != nil {
+		slogger.Error("Failed to resolve storage paths", "error", err)
 		os.Exit(1)
 	}
-	defer func() {
+	if storagePaths.Legacy {
+		slogger.Warn("Using logpush.db from the working directory; move it to the data directory or set LPE_DB_PATH",
+			"data_dir", storagePaths.DataDir)
+	}
+	if err := os.MkdirAll(filepath.Dir(storagePaths.Database), 0o750); err != nil {
+		slogger.Error("Failed to create data directory", "error", err)
+		os.Exit(1)
+	}
+
+	// Every setting is read before the database is locked, so a typo exits
+	// without leaving the lock behind, which the next start would take for
+	// a crash
+	recentBufferSize := -1
+	if size := getenv("LPE_RECENT_BUFFER_SIZE"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 0 {
+			slogger.Error("LPE_RECENT_BUFFER_SIZE must be a non-negative integer", "value", size)
+			os.Exit(1)
+		}
+		recentBufferSize = n
+	}
+
+	// Fault injection is for resilience testing only; SetFaults logs a
+	// warning whenever it is active
+	var faults *database.Faults
+	if spec := getenv("LPE_DB_FAULTS"); spec != "" {
+		f, err := database.ParseFaults(spec)
+		if err != nil {
+			slogger.Error("Invalid LPE_DB_FAULTS", "error", err)
+			os.Exit(1)
+		}
+		faults = &f
+	}
+
+	sharding := getenv("LPE_DB_SHARDING")
+	if sharding != "" && sharding != "monthly" {
+		slogger.Error("LPE_DB_SHARDING must be \"monthly\" or unset", "value", sharding)
+		os.Exit(1)
+	}
+
+	cloudflareSettings = cloudflare.SettingsFromEnv(getenv)
+	if sheetsSettings, err = sheets.SettingsFromEnv(getenv); err != nil {
+		slogger.Error("Invalid Google Sheets settings", "error", err)
+		os.Exit(1)
+	}
+	if relaySettings, err = relay.SettingsFromEnv(getenv); err != nil {
+		slogger.Error("Invalid relay settings", "error", err)
+		os.Exit(1)
+	}
+	if relaySettings.Enabled() {
+		slogger.Info("Relaying ingested batches", "kind", relaySettings.Kind, "timeout", relaySettings.Timeout)
+	}
+	tenantDomain = getenv("LPE_TENANT_DOMAIN")
+	portFallback = getenv("LPE_PORT_FALLBACK") == "true"
+	readyFile = getenv("LPE_READY_FILE")
+	if v := getenv("LPE_SHUTDOWN_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			slogger.Error("LPE_SHUTDOWN_TIMEOUT must be a positive duration such as 30s", "value", v)
+			os.Exit(1)
+		}
+		shutdownTimeout = d
+	}
+	for name, n := range map[string]*int{"LPE_INGEST_BUFFER": &ingestBuffer.size, "LPE_INGEST_FLUSH_SIZE": &ingestBuffer.flushSize} {
+		if v := getenv(name); v != "" {
+			var err error
+			if *n, err = strconv.Atoi(v); err != nil || *n < 0 {
+				slogger.Error(name+" must be a non-negative integer", "value", v)
+				os.Exit(1)
+			}
+		}
+	}
+	if v := getenv("LPE_INGEST_FLUSH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			slogger.Error("LPE_INGEST_FLUSH_INTERVAL must be a positive duration such as 250ms", "value", v)
+			os.Exit(1)
+		}
+		ingestBuffer.flushInterval = d
+	}
+	ingestBuffer.autoTune = getenv("LPE_INGEST_AUTOTUNE") != "false"
+	if ingestBuffer.size > 0 {
+		slogger.Info("Buffering ingestion writes", "queue", ingestBuffer.size, "flush_size", ingestBuffer.flushSize, "flush_interval", ingestBuffer.flushInterval, "auto_tune", ingestBuffer.autoTune)
+	}
+
+	// A second instance on the same file would double count records, so
+	// refuse to start rather than share it. A read-only instance writes
+	// nothing and leaves the file to whichever process keeps it current
+	var lock *database.FileLock
+	if !readOnly {
+		lock, err = database.LockDatabase(storagePaths.Database)
+		if err != nil {
+			slogger.Error("Failed to lock database", "error", err, "path", storagePaths.Database)
+			os.Exit(1)
+		}
+	}
+	defer lock.Release()
+	// os.Exit skips the deferred calls; a lock left behind would make the
+	// next start report an unclean shutdown
+	exit := func() {
+		lock.Release()
+		os.Exit(1)
+	}
+
+	// Opening the database replays its journals, so look for signs of an
+	// unclean shutdown first
+	shutdownSigns := database.ShutdownSigns(storagePaths.Database, lock)
+
+	var db *database.SQLiteController
+	if readOnly {
+		db, err = database.OpenReadOnly(storagePaths.Database, slogger)
+	} else {
+		db, err = database.NewSQLiteController(storagePaths.Database, slogger)
+	}
+	if err != nil {
+		slogger.Error("Failed to initialize SQLite database", "error", err)
+		exit()
+	}
+	closeDatabase := func() {
 		if err := db.Close(); err != nil {
 			slogger.Error("Failed to close database", "error", err)
 		} else {
 			slogger.Info("Database connection closed successfully")
 		}
-	}()
+	}
+	defer closeDatabase()
+	exit = func() {
+		closeDatabase()
+		lock.Release()
+		os.Exit(1)
+	}
 
-	slogger.Info("SQLite database initialized successfully", "path", "logpush.db")
+	slogger.Info("SQLite database initialized successfully", "path", storagePaths.Database,
+		"templates", storagePaths.Templates, "exports", storagePaths.Exports, "snapshot", storagePaths.Snapshot)
 
-	ingestionServer := createIngestionServer(db)
-	guiServer := createGUIServer(db)
+	if recentBufferSize >= 0 {
+		db.SetRecentBufferSize(recentBufferSize)
+	}
+	if faults != nil {
+		db.SetFaults(*faults)
+	}
+	if sharding == "monthly" {
+		if err := db.ShardByMonth(); err != nil {
+			slogger.Error("Failed to shard the database by month", "error", err)
+			exit()
+		}
+		slogger.Info("Sharding log records by month", "max_attached", database.MaxAttachedShards)
+	}
 
-	slogger.Info("Starting HTTP servers")
+	// A crash can leave derived counters behind the raw records; the scan
+	// is reported at /api/admin/recovery, and a failed one is logged
+	// without stopping the server
+	db.Recover(shutdownSigns, !readOnly)
 
-	go func() {
-		slogger.Info("Starting ingestion server", "port", ingestionPort)
-		if err := ingestionServer.ListenAndServe(); err != nil {
-			slogger.Error("Ingestion server failed", "error", err, "port", ingestionPort)
-			os.Exit(1)
+	est, err := newEstimator(db)
+	if err != nil {
+		slogger.Error("Failed to assemble estimator", "error", err)
+		exit()
+	}
+	ingestionServer := createIngestionServer(est)
+	guiServer := createGUIServer(est)
+
+	// Under systemd or the Windows Service Control Manager, the service is
+	// reported ready once both servers listen and the dashboard cache is
+	// warm, not as soon as the process starts
+	err = service.Run(service.Options{Name: serviceName, Logger: slogger, Healthy: db.Ping}, func(ctx context.Context, ready func()) error {
+		return serve(ctx, ready, est, map[string]*http.Server{"ingestion": ingestionServer, "gui": guiServer})
+	})
+	if err != nil {
+		slogger.Error("LogpushEstimator stopped", "error", err)
+		// The servers have already been shut down, so close the database
+		// cleanly first
+		exit()
+	}
+}
+
+// serve starts the named servers and the background jobs, calls ready once
+// the servers listen and the dashboard cache is warm, and returns when ctx
+// is done (after the servers have finished their in-flight requests and the
+// jobs have stopped and saved the cache snapshot) or a server fails. The addresses the servers listen on are
+// recorded for /api/version and, once ready, written to readyFile.
+func serve(ctx context.Context, ready func(), est *logpushestimator.Estimator, servers map[string]*http.Server) error {
+	defer est.Close()
+	if readyFile != "" {
+		// A file left by an earlier run must not be mistaken for readiness
+		if err := os.Remove(readyFile); err != nil && !os.IsNotExist(err) {
+			return err
 		}
-	}()
+	}
 
-	go func() {
-		slogger.Info("Starting GUI server", "port", guiPort)
-		if err := guiServer.ListenAndServe(); err != nil {
-			slogger.Error("GUI server failed", "error", err, "port", guiPort)
-			os.Exit(1)
+	failed := make(chan error, len(servers))
+	for _, name := range slices.Sorted(maps.Keys(servers)) {
+		srv := servers[name]
+		ln, err := listen(srv.Addr)
+		if err != nil {
+			shutdownServers(servers)
+			return fmt.Errorf("%s server: %w", name, err)
 		}
+		addr := ln.Addr().String()
+		est.Listeners.Set(name, addr)
+		slogger.Info("Starting HTTP server", "server", name, "address", addr)
+		go func() {
+			if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slogger.Error("HTTP server failed", "error", err, "server", name, "address", addr)
+				failed <- err
+			}
+		}()
+	}
+
+	// Warm the dashboard cache and run the periodic pruning, integrity and
+	// Cloudflare sync jobs in the background
+	stopped := make(chan struct{})
+	go func() {
+		est.Runner.Run(ctx)
+		close(stopped)
 	}()
 
-	slogger.Info("LogpushEstimator startup complete - servers running")
-	select {}
+	select {
+	case <-est.Runner.Ready():
+		if err := writeReadyFile(readyFile, est.Listeners.All()); err != nil {
+			shutdownServers(servers)
+			return err
+		}
+		slogger.Info("LogpushEstimator startup complete - servers running", "listeners", est.Listeners.All())
+		ready()
+	case err := <-failed:
+		shutdownServers(servers)
+		return err
+	case <-ctx.Done():
+		shutdownServers(servers)
+		<-stopped
+		return nil
+	}
+
+	select {
+	case err := <-failed:
+		shutdownServers(servers)
+		return err
+	case <-ctx.Done():
+		// Stop ingestion and write out queued batches before the jobs save
+		// the cache snapshot, so the snapshot includes every accepted batch
+		slogger.Info("Shutting down", "timeout", shutdownTimeout)
+		shutdownServers(servers)
+		est.Close()
+		<-stopped
+		return nil
+	}
+}
+
+// shutdownServers stops the servers accepting connections and waits up to
+// shutdownTimeout for in-flight requests, such as ingestion inserts, to
+// finish. Connections still open after that are closed.
+func shutdownServers(servers map[string]*http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for name, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				slogger.Warn("HTTP server did not finish in-flight requests in time", "server", name, "error", err)
+				srv.Close()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// listen listens on addr, or, when portFallback is set and addr cannot be
+// used (typically because the port is taken), on an ephemeral port of the
+// same host.
+func listen(addr string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err == nil || !portFallback {
+		return ln, err
+	}
+	host, _, splitErr := net.SplitHostPort(addr)
+	if splitErr != nil {
+		return nil, err
+	}
+	slogger.Warn("Configured port unavailable, falling back to an ephemeral port", "address", addr, "error", err)
+	return net.Listen("tcp", net.JoinHostPort(host, "0"))
+}
+
+// ReadyFile is the content of the file written to LPE_READY_FILE once the
+// servers are ready.
+type ReadyFile struct {
+	PID       int               `json:"pid"`       // Process ID of the estimator
+	Listeners map[string]string `json:"listeners"` // Listening address by server name
+}
+
+// writeReadyFile writes the listening addresses to path, if set. The file
+// is renamed into place so readers never see it partly written.
+func writeReadyFile(path string, listeners map[string]string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(ReadyFile{PID: os.Getpid(), Listeners: listeners})
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
 }
@@ -2,27 +2,57 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/melatonein5/LogpushEstimator/src/config"
 	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+	"github.com/melatonein5/LogpushEstimator/src/gui/handlers"
+	"github.com/melatonein5/LogpushEstimator/src/logpushestimator"
 )
 
+// testEstimator assembles the estimator for db from the package settings,
+// as main does.
+func testEstimator(t *testing.T, db *database.SQLiteController) *logpushestimator.Estimator {
+	t.Helper()
+	est, err := newEstimator(db)
+	if err != nil {
+		t.Fatalf("Failed to assemble estimator: %v", err)
+	}
+	return est
+}
+
 func TestHealthHandler(t *testing.T) {
+	tempFile := "test_health.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
 	req, err := http.NewRequest("GET", "/health", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(healthHandler)
+	handler := testEstimator(t, db).IngestHandler
 
 	handler.ServeHTTP(rr, req)
 
@@ -40,18 +70,26 @@ func TestHealthHandler(t *testing.T) {
 	}
 
 	// Check the response body
-	var response map[string]string
+	var response struct {
+		Status  string                  `json:"status"`
+		Service string                  `json:"service"`
+		Warmup  handlers.WarmupProgress `json:"warmup"`
+	}
 	err = json.Unmarshal(rr.Body.Bytes(), &response)
 	if err != nil {
 		t.Errorf("Could not parse JSON response: %v", err)
 	}
 
-	if response["status"] != "ok" {
-		t.Errorf("Expected status 'ok', got '%v'", response["status"])
+	if response.Status != "ok" {
+		t.Errorf("Expected status 'ok', got '%v'", response.Status)
+	}
+
+	if response.Service != "LogpushEstimator" {
+		t.Errorf("Expected service 'LogpushEstimator', got '%v'", response.Service)
 	}
 
-	if response["service"] != "LogpushEstimator" {
-		t.Errorf("Expected service 'LogpushEstimator', got '%v'", response["service"])
+	if response.Warmup.State == "" || response.Warmup.Total == 0 {
+		t.Errorf("Expected warm-up progress, got %+v", response.Warmup)
 	}
 }
 
@@ -67,7 +105,7 @@ func TestMakeIngestionHandler(t *testing.T) {
 	}
 	defer db.Close()
 
-	handler := makeIngestionHandler(db)
+	handler := testEstimator(t, db).IngestHandler
 
 	tests := []struct {
 		name           string
@@ -141,7 +179,7 @@ func TestMakeIngestionHandlerDatabaseInteraction(t *testing.T) {
 	}
 	defer db.Close()
 
-	handler := makeIngestionHandler(db)
+	handler := testEstimator(t, db).IngestHandler
 
 	// Send a valid request
 	testData := "This is test log data"
@@ -187,7 +225,7 @@ func TestCreateIngestionServer(t *testing.T) {
 	}
 	defer db.Close()
 
-	server := createIngestionServer(db)
+	server := createIngestionServer(testEstimator(t, db))
 
 	if server == nil {
 		t.Error("createIngestionServer returned nil")
@@ -214,7 +252,7 @@ func TestCreateGUIServer(t *testing.T) {
 	}
 	defer db.Close()
 
-	server := createGUIServer(db)
+	server := createGUIServer(testEstimator(t, db))
 
 	if server == nil {
 		t.Error("createGUIServer returned nil")
@@ -227,6 +265,28 @@ func TestCreateGUIServer(t *testing.T) {
 	if server.Handler == nil {
 		t.Error("Server handler should not be nil")
 	}
+
+	// Unknown API routes get the JSON error envelope
+	rr := httptest.NewRecorder()
+	server.Handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/does-not-exist", nil))
+	var response handlers.APIResponse
+	if rr.Code != http.StatusNotFound || json.Unmarshal(rr.Body.Bytes(), &response) != nil || response.Success {
+		t.Errorf("Expected a JSON 404 for an unknown API route, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Every API route answers OPTIONS preflights
+	rr = httptest.NewRecorder()
+	server.Handler.ServeHTTP(rr, httptest.NewRequest("OPTIONS", "/api/admin/prune", nil))
+	if rr.Code != http.StatusNoContent || rr.Header().Get("Allow") != "POST, OPTIONS" {
+		t.Errorf("Expected 204 with Allow: POST, OPTIONS, got %d %q", rr.Code, rr.Header().Get("Allow"))
+	}
+
+	// Wrong methods get 405 with an Allow header
+	rr = httptest.NewRecorder()
+	server.Handler.ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/version", nil))
+	if rr.Code != http.StatusMethodNotAllowed || rr.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Errorf("Expected 405 with Allow: GET, HEAD, OPTIONS, got %d %q", rr.Code, rr.Header().Get("Allow"))
+	}
 }
 
 func TestIngestionHandlerWithRealRequests(t *testing.T) {
@@ -242,7 +302,7 @@ func TestIngestionHandlerWithRealRequests(t *testing.T) {
 	defer db.Close()
 
 	// Create test server
-	server := createIngestionServer(db)
+	server := createIngestionServer(testEstimator(t, db))
 	testServer := httptest.NewServer(server.Handler)
 	defer testServer.Close()
 
@@ -297,7 +357,7 @@ func TestIngestionHandlerConcurrency(t *testing.T) {
 	}
 	defer db.Close()
 
-	handler := makeIngestionHandler(db)
+	handler := testEstimator(t, db).IngestHandler
 
 	// Test concurrent requests
 	numRequests := 100
@@ -347,3 +407,470 @@ func TestIngestionHandlerConcurrency(t *testing.T) {
 		t.Errorf("Expected at least %d log entries, got %d", numRequests, len(logSizes))
 	}
 }
+
+func TestIngestionHandlerRecordsStats(t *testing.T) {
+	tempFile := "test_ingestion_records.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	handler := testEstimator(t, db).IngestHandler
+
+	body := "{\"a\":1}\n{\"bbb\":333}\n{\"cc\":22}\n"
+	req, err := http.NewRequest("POST", "/ingest", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	logSizes, err := db.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	if len(logSizes) != 1 {
+		t.Fatalf("Expected 1 log entry, got %d", len(logSizes))
+	}
+
+	got := logSizes[0]
+	if got.RecordCount != 3 || got.MinRecordSize != 7 || got.MaxRecordSize != 11 || got.AvgRecordSize != 9 {
+		t.Errorf("Unexpected record stats: %+v", got)
+	}
+}
+
+func TestIngestionHandlerRecordsOutcome(t *testing.T) {
+	tempFile := "test_ingestion_outcome.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	handler := testEstimator(t, db).IngestHandler
+	for _, body := range []string{"one", "", "two"} {
+		req, err := http.NewRequest("POST", "/ingest", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	outcomes, err := db.IngestOutcomesSince(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query ingest outcomes: %v", err)
+	}
+	// The empty body is a client error and must not count against availability
+	if outcomes.Successes != 2 || outcomes.Failures != 0 {
+		t.Errorf("Expected 2 successes and 0 failures, got %+v", outcomes)
+	}
+}
+
+func TestIngestionHandlerTenantPath(t *testing.T) {
+	tempFile := "test_ingestion_tenant.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	handler := testEstimator(t, db).IngestHandler
+	for path, want := range map[string]int{
+		"/t/acme/ingest":   http.StatusOK,
+		"/t/acme/other":    http.StatusNotFound,
+		"/t/ACME/ingest":   http.StatusNotFound,
+		"/t/globex/ingest": http.StatusOK,
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("POST", path, strings.NewReader("{}")))
+		if rr.Code != want {
+			t.Errorf("POST %s: expected %d, got %d", path, want, rr.Code)
+		}
+	}
+
+	logs, err := db.ForTenant("acme").GetAll()
+	if err != nil {
+		t.Fatalf("Failed to query tenant logs: %v", err)
+	}
+	if len(logs) != 1 || logs[0].Tenant != "acme" {
+		t.Errorf("Expected one acme batch, got %+v", logs)
+	}
+}
+
+func TestIngestionHandlerDatasetParsers(t *testing.T) {
+	tempFile := "test_ingestion_dimensions.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	defer featureFlags.Set("dataset-parsers", featureFlags.Enabled("dataset-parsers"))
+	handler := testEstimator(t, db).IngestHandler
+	body := "{\"EdgeResponseStatus\":200}\n{\"EdgeResponseStatus\":503}\n"
+
+	// Parsing is opt-in: nothing is extracted while the flag is off
+	featureFlags.Set("dataset-parsers", false)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/ingest?dataset=http_requests", strings.NewReader(body)))
+	featureFlags.Set("dataset-parsers", true)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/ingest?dataset=http_requests", strings.NewReader(body)))
+
+	values, err := db.QueryDimension("status_class", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query dimensions: %v", err)
+	}
+	if len(values) != 2 || values[0].Records != 1 || values[1].Records != 1 {
+		t.Errorf("Expected one 2xx and one 5xx record from the second batch only, got %+v", values)
+	}
+}
+
+func TestIngestionHandlerSamplesPayloads(t *testing.T) {
+	tempFile := "test_ingestion_samples.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	doc := config.NewDocument()
+	doc.Sampling.EveryN = 2
+	doc.Sampling.RedactPatterns = []config.RedactPattern{{Name: "email", Regex: `[\w.]+@[\w.]+`}}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Failed to enable sampling: %v", err)
+	}
+
+	handler := testEstimator(t, db).IngestHandler
+	for i := 0; i < 4; i++ {
+		body := `{"ClientIP":"192.0.2.1","ClientRequestURI":"/?to=a@example.com","EdgeResponseStatus":200}` + "\n"
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/ingest?dataset=http_requests", strings.NewReader(body)))
+	}
+
+	samples, err := db.ListSamples(10)
+	if err != nil {
+		t.Fatalf("Failed to list samples: %v", err)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("Expected every second batch to be sampled, got %d samples", len(samples))
+	}
+	if strings.Contains(samples[0].Content, "192.0.2.1") || strings.Contains(samples[0].Content, "a@example.com") ||
+		samples[0].Dataset != "http_requests" || samples[0].RedactedFields != 2 {
+		t.Errorf("Expected a redacted http_requests sample, got %+v", samples[0])
+	}
+
+	now := time.Now()
+	audit, err := db.QueryRedactionAudit(now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query redaction audit: %v", err)
+	}
+	if len(audit) != 2 || audit[0].Samples != 2 {
+		t.Errorf("Expected both rules audited for both samples, got %+v", audit)
+	}
+}
+
+func TestServeReportsReadyAfterWarmup(t *testing.T) {
+	tempFile := "test_serve.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	est := testEstimator(t, db)
+	ctx, cancel := context.WithCancel(context.Background())
+	readied := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(ctx, func() { close(readied) }, est, map[string]*http.Server{"ingestion": {Addr: "127.0.0.1:0", Handler: est.IngestHandler}})
+	}()
+
+	select {
+	case <-readied:
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not report readiness")
+	}
+	select {
+	case <-est.Runner.Ready():
+	default:
+		t.Error("Expected readiness only after the cache was warmed")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected serve to stop cleanly, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after cancellation")
+	}
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	tempFile := "test_serve_drain.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	// The handler holds its request open until released, like a slow insert
+	started, release := make(chan struct{}), make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	est := testEstimator(t, db)
+	ctx, cancel := context.WithCancel(context.Background())
+	readied := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(ctx, func() { close(readied) }, est, map[string]*http.Server{"ingestion": {Addr: "127.0.0.1:0", Handler: slow}})
+	}()
+	select {
+	case <-readied:
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not report readiness")
+	}
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Post("http://"+est.Listeners.All()["ingestion"]+"/ingest", "text/plain", strings.NewReader("{}"))
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		t.Fatalf("serve returned before the in-flight request finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	if code := <-status; code != http.StatusAccepted {
+		t.Errorf("Expected the in-flight request to complete, got status %d", code)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected serve to stop cleanly, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after the request finished")
+	}
+}
+
+func TestServeFailsWhenPortInUse(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	err = serve(context.Background(), func() { t.Error("Expected no readiness") }, nil, map[string]*http.Server{"gui": {Addr: ln.Addr().String()}})
+	if err == nil {
+		t.Error("Expected an error when the port is in use")
+	}
+}
+
+func TestServePortFallback(t *testing.T) {
+	tempFile := "test_port_fallback.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	defer func(fallback bool, file string) { portFallback, readyFile = fallback, file }(portFallback, readyFile)
+	portFallback = true
+	readyFile = filepath.Join(t.TempDir(), "ready.json")
+	if err := os.WriteFile(readyFile, []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	est := testEstimator(t, db)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	readied := make(chan struct{})
+	go serve(ctx, func() { close(readied) }, est, map[string]*http.Server{"gui": {Addr: taken.Addr().String(), Handler: est.GUIHandler}})
+
+	select {
+	case <-readied:
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not report readiness")
+	}
+
+	data, err := os.ReadFile(readyFile)
+	if err != nil {
+		t.Fatalf("Failed to read ready file: %v", err)
+	}
+	var ready ReadyFile
+	if err := json.Unmarshal(data, &ready); err != nil {
+		t.Fatalf("Invalid ready file %q: %v", data, err)
+	}
+	addr := ready.Listeners["gui"]
+	if addr == "" || addr == taken.Addr().String() || ready.PID != os.Getpid() {
+		t.Fatalf("Expected a fallback address and this PID, got %+v", ready)
+	}
+
+	resp, err := http.Get("http://" + addr + "/api/version")
+	if err != nil {
+		t.Fatalf("Failed to reach fallback address: %v", err)
+	}
+	defer resp.Body.Close()
+	var version struct {
+		Data handlers.VersionInfo `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		t.Fatalf("Failed to parse version: %v", err)
+	}
+	if version.Data.Listeners["gui"] != addr {
+		t.Errorf("Expected /api/version to report %s, got %v", addr, version.Data.Listeners)
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	tempFile := "test_check.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	server := httptest.NewServer(testEstimator(t, db).GUIHandler)
+	defer server.Close()
+
+	var out bytes.Buffer
+	if code := runCheck([]string{"--url", server.URL + "/"}, &out); code != 0 {
+		t.Errorf("Expected a healthy server to pass, got %d: %s", code, out.String())
+	}
+
+	out.Reset()
+	if code := runCheck([]string{"-url", "http://127.0.0.1:1", "-timeout", "1s"}, &out); code != 1 {
+		t.Errorf("Expected an unreachable server to fail, got %d", code)
+	}
+
+	out.Reset()
+	if code := runCheck([]string{"-bogus"}, &out); code != 2 {
+		t.Errorf("Expected a usage error, got %d", code)
+	}
+
+	db.Close()
+	out.Reset()
+	if code := runCheck([]string{"-url", server.URL}, &out); code != 1 || !strings.Contains(out.String(), "unavailable") {
+		t.Errorf("Expected an unreadable database to fail, got %d: %s", code, out.String())
+	}
+}
+
+func TestRunSimulate(t *testing.T) {
+	tempFile := "test_simulate.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	server := httptest.NewServer(testEstimator(t, db).IngestHandler)
+	defer server.Close()
+
+	var out bytes.Buffer
+	args := []string{"-url", server.URL + "/ingest", "-rate", "400", "-duration", "350ms", "-interval", "100ms", "-dataset", "firewall_events"}
+	if code := runSimulate(context.Background(), args, &out); code != 0 {
+		t.Fatalf("Expected the simulation to succeed, got %d: %s", code, out.String())
+	}
+	var batches, records, bytesSent, gzipBytes int64
+	if _, err := fmt.Sscanf(out.String(), "delivered %d batches (%d records, %d bytes, %d bytes gzip)", &batches, &records, &bytesSent, &gzipBytes); err != nil {
+		t.Fatalf("Unexpected summary %q: %v", out.String(), err)
+	}
+
+	// The ownership challenge is acknowledged without being stored
+	count, err := db.CountByTimeRange(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count.Batches != batches || count.Bytes != gzipBytes || batches < 2 {
+		t.Errorf("Expected %d batches of %d bytes stored, got %+v", batches, gzipBytes, count)
+	}
+
+	out.Reset()
+	if code := runSimulate(context.Background(), []string{"-url", server.URL + "/nowhere", "-duration", "100ms"}, &out); code != 1 {
+		t.Errorf("Expected a rejected ownership challenge to fail, got %d: %s", code, out.String())
+	}
+	out.Reset()
+	if code := runSimulate(context.Background(), []string{"-header", "no-colon"}, &out); code != 2 {
+		t.Errorf("Expected a usage error, got %d", code)
+	}
+}
+
+func TestRunDecrypt(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{3}, encryption.KeySize))
+	env := map[string]string{"LPE_ENCRYPTION_KEY": encoded}
+	getenv := func(k string) string { return env[k] }
+
+	key, err := encryption.ParseKey(encoded)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	sealed, err := key.Seal([]byte("backup contents"))
+	if err != nil {
+		t.Fatalf("Failed to seal: %v", err)
+	}
+
+	var out bytes.Buffer
+	if code := runDecrypt(bytes.NewReader(sealed), &out, getenv); code != 0 || out.String() != "backup contents" {
+		t.Errorf("runDecrypt() = %d, %q", code, out.String())
+	}
+
+	delete(env, "LPE_ENCRYPTION_KEY")
+	if code := runDecrypt(bytes.NewReader(sealed), &out, getenv); code == 0 {
+		t.Error("Expected decrypting without a key to fail")
+	}
+}
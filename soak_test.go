@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/gui/handlers"
+	"github.com/melatonein5/LogpushEstimator/src/logpushestimator"
+	"github.com/melatonein5/LogpushEstimator/src/simulator"
+)
+
+// soakDuration turns TestSoak into a long-running soak test, e.g.
+//
+//	go test -run TestSoak -soak 30m -timeout 0 .
+//
+// Without it TestSoak makes a short smoke run of the same harness.
+var soakDuration = flag.Duration("soak", 0, "run TestSoak for this long instead of a short smoke run")
+
+// Soak limits. Goroutines and memory are compared with the level reached
+// once the estimator is warm and under load; the database may grow by a
+// fixed allowance plus a bounded amount per stored batch.
+const (
+	soakGoroutineSlack   = 25              // Goroutines allowed above the warm level
+	soakRSSGrowthFactor  = 2               // Peak RSS allowed as a multiple of the warm level...
+	soakRSSGrowthSlack   = 64 << 20        // ...plus this many bytes
+	soakDBBaseGrowth     = 2 << 20         // Database growth allowed regardless of load
+	soakDBBytesPerBatch  = 8 << 10         // Database growth allowed per stored batch
+	soakSmokeDuration    = 2 * time.Second // Length of the default smoke run
+	soakSampleInterval   = 250 * time.Millisecond
+	soakGoroutineSettled = 5 * time.Second // Time allowed for goroutines to exit after shutdown
+)
+
+// soakSample is one measurement of the process and database.
+type soakSample struct {
+	at         time.Duration
+	goroutines int
+	rss        uint64
+	dbBytes    int64
+}
+
+// residentBytes returns the process's resident set size, or the memory
+// obtained from the OS by the Go runtime where /proc is unavailable.
+func residentBytes() uint64 {
+	if data, err := os.ReadFile("/proc/self/statm"); err == nil {
+		if fields := strings.Fields(string(data)); len(fields) > 1 {
+			if pages, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+				return pages * uint64(os.Getpagesize())
+			}
+		}
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.Sys
+}
+
+// databaseBytes returns the size of the database file and its WAL and
+// journal files.
+func databaseBytes(path string) int64 {
+	var total int64
+	for _, suffix := range []string{"", "-wal", "-journal", "-shm"} {
+		if info, err := os.Stat(path + suffix); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
+// soakRoutes returns the API routes polled during the soak: every
+// registered route that can be read with a plain GET.
+func soakRoutes(db *database.SQLiteController, logger *slog.Logger) []string {
+	var routes []string
+	for path := range handlers.MakeAPIHandlers(db, logger) {
+		if strings.HasSuffix(path, "/") {
+			continue // Prefix routes need an ID
+		}
+		routes = append(routes, path)
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// TestSoak ingests simulated Logpush traffic while polling every API route,
+// sampling goroutines, resident memory and database size, and fails if any
+// of them grows without bound or goroutines outlive shutdown. It guards the
+// streaming and queueing code against leaks that only show over time.
+func TestSoak(t *testing.T) {
+	duration := *soakDuration
+	if duration == 0 {
+		if testing.Short() {
+			t.Skip("Skipping soak smoke run in short mode")
+		}
+		duration = soakSmokeDuration
+	}
+	baselineGoroutines := runtime.NumGoroutine()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dbPath := filepath.Join(t.TempDir(), "soak.db")
+	db, err := database.NewSQLiteController(dbPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	est, err := logpushestimator.New(logpushestimator.Config{DB: db, Logger: logger, ExportDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to assemble estimator: %v", err)
+	}
+	ingestServer := httptest.NewServer(est.IngestHandler)
+	guiServer := httptest.NewServer(est.GUIHandler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		est.Runner.Run(ctx)
+	}()
+	<-est.Runner.Ready()
+	dbStart := databaseBytes(dbPath)
+
+	// Load: Logpush deliveries for the whole run
+	var ingestStats simulator.Stats
+	var ingestErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ingestStats, ingestErr = simulator.Run(ctx, simulator.Config{
+			URL:      ingestServer.URL + "/ingest",
+			Rate:     500,
+			Duration: duration,
+			Interval: 200 * time.Millisecond,
+			Logger:   logger,
+		})
+	}()
+
+	// Readers: poll every GET route, counting server errors
+	routes := soakRoutes(db, logger)
+	var polls, serverErrors atomic.Int64
+	var failedMu sync.Mutex
+	failed := make(map[string]int)
+	client := &http.Client{Timeout: 30 * time.Second}
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func(offset int) {
+			defer wg.Done()
+			for i := offset; ctx.Err() == nil; i++ {
+				route := routes[i%len(routes)]
+				req, _ := http.NewRequestWithContext(ctx, http.MethodGet, guiServer.URL+route, nil)
+				resp, err := client.Do(req)
+				if err != nil {
+					continue // Cancelled at the end of the run
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				polls.Add(1)
+				if resp.StatusCode >= 500 && resp.StatusCode != http.StatusServiceUnavailable {
+					serverErrors.Add(1)
+					failedMu.Lock()
+					failed[fmt.Sprintf("%s %d", route, resp.StatusCode)]++
+					failedMu.Unlock()
+				}
+			}
+		}(p * len(routes) / 4)
+	}
+
+	// Sampling
+	start := time.Now()
+	var samples []soakSample
+	ticker := time.NewTicker(soakSampleInterval)
+	for time.Since(start) < duration {
+		<-ticker.C
+		samples = append(samples, soakSample{
+			at:         time.Since(start),
+			goroutines: runtime.NumGoroutine(),
+			rss:        residentBytes(),
+			dbBytes:    databaseBytes(dbPath),
+		})
+	}
+	ticker.Stop()
+
+	cancel()
+	wg.Wait()
+	ingestServer.Close()
+	guiServer.Close()
+	client.CloseIdleConnections()
+
+	if ingestErr != nil {
+		t.Fatalf("Simulated ingestion failed: %v", ingestErr)
+	}
+	t.Logf("soak: %v, %d batches (%d records) ingested, %d polls of %d routes, %d samples",
+		duration, ingestStats.Batches, ingestStats.Records, polls.Load(), len(routes), len(samples))
+	if ingestStats.Batches == 0 || ingestStats.FailedBatches > 0 {
+		t.Errorf("Expected every batch to be stored, got %+v", ingestStats)
+	}
+	if n := serverErrors.Load(); n > 0 {
+		t.Errorf("%d polls answered with a server error: %v", n, failed)
+	}
+
+	// The first quarter of the run warms caches, pools and connections;
+	// the rest must stay near the level reached by then.
+	if len(samples) < 4 {
+		t.Fatalf("Too few samples (%d) to judge growth", len(samples))
+	}
+	warm := samples[:len(samples)/4]
+	var warmGoroutines int
+	var warmRSS uint64
+	for _, s := range warm {
+		warmGoroutines = max(warmGoroutines, s.goroutines)
+		warmRSS = max(warmRSS, s.rss)
+	}
+	for _, s := range samples[len(warm):] {
+		if s.goroutines > warmGoroutines+soakGoroutineSlack {
+			t.Errorf("At %v: %d goroutines, warm level was %d", s.at, s.goroutines, warmGoroutines)
+			break
+		}
+	}
+	for _, s := range samples[len(warm):] {
+		if s.rss > warmRSS*soakRSSGrowthFactor+soakRSSGrowthSlack {
+			t.Errorf("At %v: resident memory %d MB, warm level was %d MB", s.at, s.rss>>20, warmRSS>>20)
+			break
+		}
+	}
+
+	growth := databaseBytes(dbPath) - dbStart
+	if limit := soakDBBaseGrowth + soakDBBytesPerBatch*ingestStats.Batches; growth > limit {
+		t.Errorf("Database grew %d bytes for %d batches, above the %d byte limit", growth, ingestStats.Batches, limit)
+	}
+
+	// Everything started for the run must have exited
+	deadline := time.Now().Add(soakGoroutineSettled)
+	for runtime.NumGoroutine() > baselineGoroutines+2 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > baselineGoroutines+2 {
+		buf := make([]byte, 1<<20)
+		t.Errorf("%d goroutines still running after shutdown, %d before the run:\n%s",
+			n, baselineGoroutines, buf[:runtime.Stack(buf, true)])
+	}
+}
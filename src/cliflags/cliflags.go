@@ -0,0 +1,38 @@
+// Package cliflags provides flag.Value types shared by LogpushEstimator's
+// commands, such as the logpush-estimator subcommands and the
+// logpush-collector.
+//
+// # Usage
+//
+//	header := make(http.Header)
+//	flags.Var(cliflags.Header(header), "header", "Extra request header as \"Name: value\"; repeatable")
+package cliflags
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Header collects repeated -header "Name: value" flags into the
+// http.Header it converts.
+type Header http.Header
+
+// String implements flag.Value. Defaults are not shown in usage.
+func (h Header) String() string { return "" }
+
+// Set adds one "Name: value" header, trimming spaces around both.
+//
+// Parameters:
+//   - v: Flag value
+//
+// Returns:
+//   - error: If v has no colon or an empty name
+func (h Header) Set(v string) error {
+	name, value, ok := strings.Cut(v, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("header must be Name: value")
+	}
+	http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	return nil
+}
@@ -0,0 +1,31 @@
+package cliflags
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestHeader(t *testing.T) {
+	header := make(http.Header)
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.Var(Header(header), "header", "")
+
+	if err := flags.Parse([]string{"-header", "X-Logpush-Token: s3cret", "-header", "X-Team:edge", "-header", "X-Team: core"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := header.Get("X-Logpush-Token"); got != "s3cret" {
+		t.Errorf("Expected the token header, got %q", got)
+	}
+	if got := header.Values("X-Team"); len(got) != 2 || got[0] != "edge" || got[1] != "core" {
+		t.Errorf("Expected repeated headers kept in order, got %v", got)
+	}
+
+	for _, v := range []string{"no-colon", ": value"} {
+		if err := Header(header).Set(v); err == nil {
+			t.Errorf("Expected %q to be rejected", v)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Package clock provides the source of the current time for LogpushEstimator.
+//
+// Code that stamps records or computes windows ending now (ingestion, the
+// dashboard API's default ranges, retention) reads the time from a Clock
+// instead of calling time.Now, so tests can run against a fixed or
+// simulated clock, step it through weeks of data, and place it on either
+// side of a DST transition.
+//
+// # Usage
+//
+//	var src clock.Source        // reads the system time
+//	src.Set(testsupport.NewClock(testsupport.Epoch))
+//	now := src.Now()
+//	src.Set(nil)                // back to the system time
+package clock
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Clock reports the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the Clock reading the system time.
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Func adapts a function such as time.Now to a Clock.
+type Func func() time.Time
+
+// Now calls f.
+func (f Func) Now() time.Time { return f() }
+
+// box wraps a Clock so Source always stores the same concrete type.
+type box struct{ c Clock }
+
+// Source is a Clock whose underlying clock can be replaced while it is in
+// use. The zero value reads the system time. It is safe for concurrent use.
+type Source struct {
+	v atomic.Value
+}
+
+// Set replaces the underlying clock.
+//
+// Parameters:
+//   - c: Clock to read from, or nil for the system time
+func (s *Source) Set(c Clock) {
+	if c == nil {
+		c = System
+	}
+	s.v.Store(box{c})
+}
+
+// Now returns the current time of the underlying clock.
+func (s *Source) Now() time.Time {
+	if b, ok := s.v.Load().(box); ok {
+		return b.c.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,24 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSource(t *testing.T) {
+	var src Source
+	if d := time.Since(src.Now()); d < 0 || d > time.Minute {
+		t.Errorf("Expected the zero Source to read the system time, got %v", src.Now())
+	}
+
+	fixed := time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC)
+	src.Set(Func(func() time.Time { return fixed }))
+	if !src.Now().Equal(fixed) {
+		t.Errorf("Expected %v, got %v", fixed, src.Now())
+	}
+
+	src.Set(nil)
+	if d := time.Since(src.Now()); d < 0 || d > time.Minute {
+		t.Errorf("Expected Set(nil) to restore the system time, got %v", src.Now())
+	}
+}
@@ -0,0 +1,126 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// hourlyRequestsQuery counts a zone's HTTP requests per hour using the
+// httpRequestsAdaptiveGroups dataset.
+const hourlyRequestsQuery = `query ($zoneTag: string, $start: Time, $end: Time) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      httpRequestsAdaptiveGroups(limit: 10000, filter: {datetime_geq: $start, datetime_lt: $end}, orderBy: [datetimeHour_ASC]) {
+        count
+        dimensions { datetimeHour }
+      }
+    }
+  }
+}`
+
+// HourlyCount is the number of requests a zone served in one hour.
+type HourlyCount struct {
+	Hour     time.Time // Start of the hour, UTC
+	Requests int64     // Requests served
+}
+
+// ZoneHourlyRequests queries the GraphQL Analytics API for the number of
+// HTTP requests zoneTag served in each hour of [start, end).
+//
+// Parameters:
+//   - ctx: Request context
+//   - zoneTag: Zone identifier
+//   - start: Range start (inclusive)
+//   - end: Range end (exclusive)
+//
+// Returns:
+//   - []HourlyCount: Request counts for hours with traffic, oldest first
+//   - error: Transport errors, *APIError, or GraphQL errors
+func (c *Client) ZoneHourlyRequests(ctx context.Context, zoneTag string, start, end time.Time) ([]HourlyCount, error) {
+	body := map[string]any{
+		"query": hourlyRequestsQuery,
+		"variables": map[string]any{
+			"zoneTag": zoneTag,
+			"start":   start.UTC().Format(time.RFC3339),
+			"end":     end.UTC().Format(time.RFC3339),
+		},
+	}
+	data, err := c.do(ctx, http.MethodPost, "/graphql", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data struct {
+			Viewer struct {
+				Zones []struct {
+					Groups []struct {
+						Count      int64 `json:"count"`
+						Dimensions struct {
+							DatetimeHour time.Time `json:"datetimeHour"`
+						} `json:"dimensions"`
+					} `json:"httpRequestsAdaptiveGroups"`
+				} `json:"zones"`
+			} `json:"viewer"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("cloudflare: decode analytics response: %w", err)
+	}
+	if len(resp.Errors) > 0 {
+		messages := make([]string, 0, len(resp.Errors))
+		for _, e := range resp.Errors {
+			messages = append(messages, e.Message)
+		}
+		return nil, fmt.Errorf("cloudflare: analytics query failed: %s", strings.Join(messages, "; "))
+	}
+
+	var out []HourlyCount
+	for _, zone := range resp.Data.Viewer.Zones {
+		for _, g := range zone.Groups {
+			out = append(out, HourlyCount{Hour: g.Dimensions.DatetimeHour.UTC(), Requests: g.Count})
+		}
+	}
+	return out, nil
+}
+
+// SyncZoneTraffic fetches hourly request counts for every zone over
+// [start, end) and stores them as the expected traffic used by coverage
+// reports. Hours already stored are overwritten, so re-syncing a window picks
+// up late-arriving analytics.
+//
+// Parameters:
+//   - ctx: Request context
+//   - client: Cloudflare API client
+//   - db: Database controller to store the counts in
+//   - zones: Zone identifiers to sync
+//   - start: Range start (inclusive)
+//   - end: Range end (exclusive)
+//
+// Returns:
+//   - error: The first error encountered; zones before it are stored
+func SyncZoneTraffic(ctx context.Context, client *Client, db *database.SQLiteController, zones []string, start, end time.Time) error {
+	for _, zone := range zones {
+		counts, err := client.ZoneHourlyRequests(ctx, zone, start, end)
+		if err != nil {
+			return fmt.Errorf("zone %s: %w", zone, err)
+		}
+		hours := make([]database.ZoneTrafficHour, 0, len(counts))
+		for _, c := range counts {
+			hours = append(hours, database.ZoneTrafficHour{Zone: zone, Hour: c.Hour, Requests: c.Requests})
+		}
+		if err := db.UpsertZoneTraffic(hours); err != nil {
+			return fmt.Errorf("zone %s: %w", zone, err)
+		}
+	}
+	return nil
+}
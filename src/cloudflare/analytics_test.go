@@ -0,0 +1,92 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestZoneHourlyRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" || r.Method != http.MethodPost {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var body struct {
+			Query     string            `json:"query"`
+			Variables map[string]string `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if !strings.Contains(body.Query, "httpRequestsAdaptiveGroups") || body.Variables["zoneTag"] != "zone-a" {
+			t.Errorf("Unexpected query %+v", body)
+		}
+		w.Write([]byte(`{"data":{"viewer":{"zones":[{"httpRequestsAdaptiveGroups":[
+			{"count":120,"dimensions":{"datetimeHour":"2025-09-15T10:00:00Z"}},
+			{"count":80,"dimensions":{"datetimeHour":"2025-09-15T11:00:00Z"}}]}]}},"errors":null}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("secret")
+	client.BaseURL = server.URL
+	start := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	counts, err := client.ZoneHourlyRequests(context.Background(), "zone-a", start, start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query analytics: %v", err)
+	}
+	if len(counts) != 2 || counts[0].Requests != 120 || !counts[1].Hour.Equal(start.Add(time.Hour)) {
+		t.Errorf("Unexpected counts %+v", counts)
+	}
+}
+
+func TestZoneHourlyRequestsGraphQLError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":null,"errors":[{"message":"zone not authorized"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("secret")
+	client.BaseURL = server.URL
+	_, err := client.ZoneHourlyRequests(context.Background(), "zone-a", time.Now().Add(-time.Hour), time.Now())
+	if err == nil || !strings.Contains(err.Error(), "zone not authorized") {
+		t.Errorf("Expected the GraphQL error to be returned, got %v", err)
+	}
+}
+
+func TestSyncZoneTraffic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"viewer":{"zones":[{"httpRequestsAdaptiveGroups":[
+			{"count":50,"dimensions":{"datetimeHour":"2025-09-15T10:00:00Z"}}]}]}}}`))
+	}))
+	defer server.Close()
+
+	tempFile := "test_cloudflare_sync.db"
+	defer os.Remove(tempFile)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer db.Close()
+
+	client := NewClient("secret")
+	client.BaseURL = server.URL
+	start := time.Date(2025, 9, 15, 0, 0, 0, 0, time.UTC)
+	if err := SyncZoneTraffic(context.Background(), client, db, []string{"zone-a", "zone-b"}, start, start.Add(24*time.Hour)); err != nil {
+		t.Fatalf("Failed to sync zone traffic: %v", err)
+	}
+
+	traffic, err := db.QueryZoneTraffic(start, start.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query zone traffic: %v", err)
+	}
+	if len(traffic) != 2 || traffic[0].Zone != "zone-a" || traffic[1].Requests != 50 {
+		t.Errorf("Expected one hour per zone, got %+v", traffic)
+	}
+}
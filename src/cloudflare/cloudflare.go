@@ -0,0 +1,151 @@
+// Package cloudflare is a minimal client for the parts of the Cloudflare API
+// that LogpushEstimator integrates with.
+//
+// The integration is optional. It is enabled by providing an API token
+// through the environment; without one, the rest of the application works
+// unchanged and the Cloudflare-backed endpoints report that nothing is
+// configured. Incidents from the public status page can be polled without
+// a token.
+//
+// # Configuration
+//
+//	LPE_CLOUDFLARE_API_TOKEN=...        API token (Analytics Read, Logs Edit)
+//	LPE_CLOUDFLARE_ACCOUNT_ID=...       Account that owns the zones
+//	LPE_CLOUDFLARE_ZONES=zone1,zone2    Zone IDs to cross-check
+//	LPE_CLOUDFLARE_STATUS=true          Annotate charts with Logpush incidents
+//
+// # Usage
+//
+//	settings := cloudflare.SettingsFromEnv(os.Getenv)
+//	if settings.Enabled() {
+//		client := cloudflare.NewClient(settings.APIToken)
+//		counts, err := client.ZoneHourlyRequests(ctx, zone, start, end)
+//	}
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the Cloudflare API v4 endpoint.
+const DefaultBaseURL = "https://api.cloudflare.com/client/v4"
+
+// requestTimeout bounds every call to the Cloudflare API.
+const requestTimeout = 30 * time.Second
+
+// Settings holds the Cloudflare integration configuration.
+type Settings struct {
+	APIToken        string   // Bearer token for the Cloudflare API
+	AccountID       string   // Account identifier
+	Zones           []string // Zone identifiers to query analytics for
+	StatusIncidents bool     // Poll the public status page for Logpush incidents to annotate charts with
+}
+
+// SettingsFromEnv reads the LPE_CLOUDFLARE_* variables.
+//
+// Parameters:
+//   - getenv: Environment lookup, normally os.Getenv
+//
+// Returns:
+//   - Settings: Parsed settings; empty when nothing is configured
+func SettingsFromEnv(getenv func(string) string) Settings {
+	s := Settings{
+		APIToken:        strings.TrimSpace(getenv("LPE_CLOUDFLARE_API_TOKEN")),
+		AccountID:       strings.TrimSpace(getenv("LPE_CLOUDFLARE_ACCOUNT_ID")),
+		StatusIncidents: getenv("LPE_CLOUDFLARE_STATUS") == "true",
+	}
+	for _, zone := range strings.Split(getenv("LPE_CLOUDFLARE_ZONES"), ",") {
+		if zone = strings.TrimSpace(zone); zone != "" {
+			s.Zones = append(s.Zones, zone)
+		}
+	}
+	return s
+}
+
+// Enabled reports whether an API token is configured.
+func (s Settings) Enabled() bool {
+	return s.APIToken != ""
+}
+
+// Client calls the Cloudflare API with a bearer token.
+type Client struct {
+	BaseURL    string       // API root, DefaultBaseURL unless overridden (e.g. in tests)
+	HTTPClient *http.Client // Transport used for requests
+	token      string
+}
+
+// NewClient creates a client authenticating with apiToken.
+func NewClient(apiToken string) *Client {
+	return &Client{
+		BaseURL:    DefaultBaseURL,
+		HTTPClient: &http.Client{Timeout: requestTimeout},
+		token:      apiToken,
+	}
+}
+
+// APIError is an error reported by the Cloudflare API.
+type APIError struct {
+	Status   int      // HTTP status code
+	Messages []string // Error messages from the response body
+}
+
+func (e *APIError) Error() string {
+	if len(e.Messages) == 0 {
+		return fmt.Sprintf("cloudflare: HTTP %d", e.Status)
+	}
+	return fmt.Sprintf("cloudflare: HTTP %d: %s", e.Status, strings.Join(e.Messages, "; "))
+}
+
+// do sends a request to path with an optional JSON body and returns the raw
+// response body. Non-2xx responses are returned as *APIError carrying the
+// messages from the v4 error envelope.
+func (c *Client) do(ctx context.Context, method, path string, body any) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{Status: resp.StatusCode}
+		var envelope struct {
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		if json.Unmarshal(data, &envelope) == nil {
+			for _, e := range envelope.Errors {
+				apiErr.Messages = append(apiErr.Messages, e.Message)
+			}
+		}
+		return nil, apiErr
+	}
+	return data, nil
+}
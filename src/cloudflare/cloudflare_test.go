@@ -0,0 +1,54 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSettingsFromEnv(t *testing.T) {
+	env := map[string]string{
+		"LPE_CLOUDFLARE_API_TOKEN": " token ",
+		"LPE_CLOUDFLARE_ZONES":     "zone-a, ,zone-b",
+		"LPE_CLOUDFLARE_STATUS":    "true",
+	}
+	s := SettingsFromEnv(func(k string) string { return env[k] })
+	if !s.Enabled() || s.APIToken != "token" {
+		t.Errorf("Expected trimmed token, got %+v", s)
+	}
+	if len(s.Zones) != 2 || s.Zones[0] != "zone-a" || s.Zones[1] != "zone-b" {
+		t.Errorf("Expected two zones, got %v", s.Zones)
+	}
+	if !s.StatusIncidents {
+		t.Errorf("Expected status incident polling enabled")
+	}
+
+	if SettingsFromEnv(func(string) string { return "" }).Enabled() {
+		t.Errorf("Expected integration disabled without a token")
+	}
+}
+
+func TestClientReportsAPIErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Expected bearer token, got %q", r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"success":false,"errors":[{"code":10000,"message":"Authentication error"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("secret")
+	client.BaseURL = server.URL
+	_, err := client.do(context.Background(), http.MethodGet, "/zones", nil)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *APIError, got %v", err)
+	}
+	if apiErr.Status != http.StatusForbidden || len(apiErr.Messages) != 1 || apiErr.Messages[0] != "Authentication error" {
+		t.Errorf("Unexpected error %+v", apiErr)
+	}
+}
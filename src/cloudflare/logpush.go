@@ -0,0 +1,202 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// Scope kinds a Logpush job can belong to.
+const (
+	ScopeZone    = "zones"
+	ScopeAccount = "accounts"
+)
+
+// datasetScopes maps the Logpush datasets this helper knows about to the
+// scope their jobs are created in.
+var datasetScopes = map[string]string{
+	"http_requests":          ScopeZone,
+	"firewall_events":        ScopeZone,
+	"dns_logs":               ScopeZone,
+	"nel_reports":            ScopeZone,
+	"spectrum_events":        ScopeZone,
+	"page_shield_events":     ScopeZone,
+	"audit_logs":             ScopeAccount,
+	"access_requests":        ScopeAccount,
+	"gateway_dns":            ScopeAccount,
+	"gateway_http":           ScopeAccount,
+	"gateway_network":        ScopeAccount,
+	"workers_trace_events":   ScopeAccount,
+	"network_analytics_logs": ScopeAccount,
+}
+
+// DatasetScope returns the scope kind (ScopeZone or ScopeAccount) jobs for
+// dataset are created in, and whether the dataset is known.
+func DatasetScope(dataset string) (string, bool) {
+	scope, ok := datasetScopes[dataset]
+	return scope, ok
+}
+
+// Scope identifies the zone or account a Logpush job belongs to.
+type Scope struct {
+	Kind string `json:"kind"` // ScopeZone or ScopeAccount
+	ID   string `json:"id"`   // Zone or account identifier
+}
+
+// jobsPath returns the Logpush jobs collection path for the scope.
+func (s Scope) jobsPath() string {
+	return "/" + s.Kind + "/" + url.PathEscape(s.ID) + "/logpush/jobs"
+}
+
+// LogpushJob is a Logpush job as represented by the Cloudflare API.
+type LogpushJob struct {
+	ID              int64      `json:"id,omitempty"`            // Job identifier assigned by Cloudflare
+	Name            string     `json:"name,omitempty"`          // Human-readable job name
+	Dataset         string     `json:"dataset"`                 // Dataset the job pushes
+	DestinationConf string     `json:"destination_conf"`        // Destination URL, including auth headers
+	Enabled         bool       `json:"enabled"`                 // Whether the job is pushing
+	LastComplete    *time.Time `json:"last_complete,omitempty"` // Last successful push
+	LastError       *time.Time `json:"last_error,omitempty"`    // Last failed push
+	ErrorMessage    string     `json:"error_message,omitempty"` // Message for the last failure
+}
+
+// HTTPDestination builds the destination_conf for pushing to this
+// instance's ingest endpoint. The dataset is passed as a query parameter so
+// the ingest endpoint knows what it receives, and a non-empty ingestToken is
+// sent by Cloudflare as an Authorization bearer header on every push.
+//
+// Parameters:
+//   - publicURL: HTTPS base URL the ingestion server is reachable at
+//   - dataset: Dataset the job pushes
+//   - ingestToken: Token the ingest endpoint expects, or empty
+//
+// Returns:
+//   - string: destination_conf value
+//   - error: If publicURL is not an absolute HTTPS URL
+func HTTPDestination(publicURL, dataset, ingestToken string) (string, error) {
+	u, err := url.Parse(publicURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("public_url must be an absolute https:// URL")
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/ingest"
+	q := url.Values{}
+	q.Set("dataset", dataset)
+	if ingestToken != "" {
+		q.Set("header_Authorization", "Bearer "+ingestToken)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// RedactDestination replaces header values in a destination_conf so it can
+// be shown or stored without the credentials it carries.
+func RedactDestination(conf string) string {
+	u, err := url.Parse(conf)
+	if err != nil {
+		return conf
+	}
+	q := u.Query()
+	for key := range q {
+		if strings.HasPrefix(key, "header_") {
+			q.Set(key, "REDACTED")
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// envelope is the standard Cloudflare API v4 response wrapper.
+type envelope struct {
+	Success bool            `json:"success"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// doResult performs a v4 API call and decodes the envelope's result into out.
+func (c *Client) doResult(ctx context.Context, method, path string, body, out any) error {
+	data, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("cloudflare: decode response: %w", err)
+	}
+	if !env.Success {
+		return &APIError{Status: http.StatusOK, Messages: []string{"request was not successful"}}
+	}
+	return json.Unmarshal(env.Result, out)
+}
+
+// CreateLogpushJob creates job in scope.
+//
+// Parameters:
+//   - ctx: Request context
+//   - scope: Zone or account to create the job in
+//   - job: Job definition; ID and status fields are ignored
+//
+// Returns:
+//   - LogpushJob: The job as created by Cloudflare, including its ID
+//   - error: Transport errors or *APIError
+func (c *Client) CreateLogpushJob(ctx context.Context, scope Scope, job LogpushJob) (LogpushJob, error) {
+	var created LogpushJob
+	err := c.doResult(ctx, http.MethodPost, scope.jobsPath(), job, &created)
+	return created, err
+}
+
+// GetLogpushJob fetches a job, including its last push status.
+//
+// Parameters:
+//   - ctx: Request context
+//   - scope: Zone or account the job belongs to
+//   - id: Job identifier
+//
+// Returns:
+//   - LogpushJob: The job as reported by Cloudflare
+//   - error: Transport errors or *APIError
+func (c *Client) GetLogpushJob(ctx context.Context, scope Scope, id int64) (LogpushJob, error) {
+	var job LogpushJob
+	err := c.doResult(ctx, http.MethodGet, scope.jobsPath()+"/"+strconv.FormatInt(id, 10), nil, &job)
+	return job, err
+}
+
+// SyncLogpushJobHealth refreshes the stored status of every tracked job from
+// the Cloudflare API. A job that cannot be fetched does not stop the others
+// from being synced; all failures are returned together.
+//
+// Parameters:
+//   - ctx: Context for the API calls
+//   - client: Cloudflare API client
+//   - db: Database holding the tracked jobs
+//
+// Returns:
+//   - error: Any errors encountered, joined
+func SyncLogpushJobHealth(ctx context.Context, client *Client, db *database.SQLiteController) error {
+	jobs, err := db.ListLogpushJobs()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, tracked := range jobs {
+		job, err := client.GetLogpushJob(ctx, Scope{Kind: tracked.Scope, ID: tracked.ScopeID}, tracked.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("job %d: %w", tracked.ID, err))
+			continue
+		}
+		tracked.Enabled = job.Enabled
+		tracked.LastComplete = job.LastComplete
+		tracked.LastError = job.LastError
+		tracked.ErrorMessage = job.ErrorMessage
+		if _, err := db.UpdateLogpushJobHealth(tracked); err != nil {
+			errs = append(errs, fmt.Errorf("job %d: %w", tracked.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,126 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestHTTPDestination(t *testing.T) {
+	conf, err := HTTPDestination("https://estimator.example.com/", "http_requests", "tok en")
+	if err != nil {
+		t.Fatalf("Failed to build destination: %v", err)
+	}
+	if conf != "https://estimator.example.com/ingest?dataset=http_requests&header_Authorization=Bearer+tok+en" {
+		t.Errorf("Unexpected destination %q", conf)
+	}
+
+	conf, _ = HTTPDestination("https://estimator.example.com", "http_requests", "")
+	if conf != "https://estimator.example.com/ingest?dataset=http_requests" {
+		t.Errorf("Expected no header without a token, got %q", conf)
+	}
+
+	for _, bad := range []string{"http://estimator.example.com", "estimator.example.com", ""} {
+		if _, err := HTTPDestination(bad, "http_requests", "token"); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestRedactDestination(t *testing.T) {
+	redacted := RedactDestination("https://estimator.example.com/ingest?header_Authorization=Bearer+secret")
+	if strings.Contains(redacted, "secret") || !strings.Contains(redacted, "header_Authorization=REDACTED") {
+		t.Errorf("Expected the header to be redacted, got %q", redacted)
+	}
+}
+
+func TestCreateLogpushJob(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/zones/zone-a/logpush/jobs" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var job LogpushJob
+		json.NewDecoder(r.Body).Decode(&job)
+		if job.Dataset != "http_requests" || !job.Enabled {
+			t.Errorf("Unexpected job body %+v", job)
+		}
+		job.ID = 42
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "errors": []any{}, "result": job})
+	}))
+	defer server.Close()
+
+	client := NewClient("secret")
+	client.BaseURL = server.URL
+	created, err := client.CreateLogpushJob(context.Background(), Scope{Kind: ScopeZone, ID: "zone-a"}, LogpushJob{
+		Dataset:         "http_requests",
+		DestinationConf: "https://estimator.example.com/ingest",
+		Enabled:         true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+	if created.ID != 42 {
+		t.Errorf("Expected the assigned job ID, got %+v", created)
+	}
+}
+
+func TestDatasetScope(t *testing.T) {
+	if scope, ok := DatasetScope("http_requests"); !ok || scope != ScopeZone {
+		t.Errorf("Expected http_requests to be zone-scoped, got %q %v", scope, ok)
+	}
+	if scope, ok := DatasetScope("audit_logs"); !ok || scope != ScopeAccount {
+		t.Errorf("Expected audit_logs to be account-scoped, got %q %v", scope, ok)
+	}
+	if _, ok := DatasetScope("bogus"); ok {
+		t.Error("Expected unknown dataset to be rejected")
+	}
+}
+
+func TestSyncLogpushJobHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/zones/zone-a/logpush/jobs/1":
+			w.Write([]byte(`{"success":true,"result":{"id":1,"dataset":"http_requests","enabled":true,
+				"last_complete":"2025-09-15T10:00:00Z","last_error":"2025-09-15T10:05:00Z","error_message":"destination returned 401"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"success":false,"errors":[{"code":1000,"message":"job not found"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	tempFile := "test_cloudflare_job_health.db"
+	defer os.Remove(tempFile)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer db.Close()
+	for _, id := range []int64{1, 2} {
+		db.SaveLogpushJob(database.LogpushJob{ID: id, Scope: ScopeZone, ScopeID: "zone-a", Name: "job", Dataset: "http_requests", Destination: "https://example.com/ingest", Enabled: true})
+	}
+
+	client := NewClient("secret")
+	client.BaseURL = server.URL
+	err = SyncLogpushJobHealth(context.Background(), client, db)
+	if err == nil || !strings.Contains(err.Error(), "job 2") {
+		t.Errorf("Expected the missing job to be reported, got %v", err)
+	}
+
+	// The failure of job 2 does not prevent job 1 from being synced
+	job, _, _ := db.GetLogpushJob(1)
+	if job.Health() != database.JobHealthFailing || job.ErrorMessage != "destination returned 401" {
+		t.Errorf("Expected job 1 to be synced as failing, got %+v", job)
+	}
+	if job, _, _ := db.GetLogpushJob(2); job.HealthCheckedAt != nil {
+		t.Errorf("Expected job 2 to stay unsynced, got %+v", job)
+	}
+}
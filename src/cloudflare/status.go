@@ -0,0 +1,137 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// DefaultStatusURL is the API root of Cloudflare's public status page.
+const DefaultStatusURL = "https://www.cloudflarestatus.com/api/v2"
+
+// Incident is an incident reported on the Cloudflare status page.
+type Incident struct {
+	ID         string     `json:"id"`          // Status page incident identifier
+	Name       string     `json:"name"`        // Incident title
+	Status     string     `json:"status"`      // investigating, identified, monitoring, resolved or postmortem
+	Impact     string     `json:"impact"`      // none, minor, major or critical
+	Shortlink  string     `json:"shortlink"`   // Link to the incident page
+	CreatedAt  time.Time  `json:"created_at"`  // When the incident was opened
+	StartedAt  *time.Time `json:"started_at"`  // When the incident began, if reported
+	ResolvedAt *time.Time `json:"resolved_at"` // When it was resolved, or nil while ongoing
+	Components []struct {
+		Name string `json:"name"` // Affected component, e.g. "Logpush"
+	} `json:"components"`
+}
+
+// AffectsLogpush reports whether the incident names Logpush among its
+// affected components or in its title.
+func (i Incident) AffectsLogpush() bool {
+	if strings.Contains(strings.ToLower(i.Name), "logpush") {
+		return true
+	}
+	for _, c := range i.Components {
+		if strings.Contains(strings.ToLower(c.Name), "logpush") {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusClient reads incidents from the Cloudflare status page. The status
+// page is public, so no API token is needed.
+type StatusClient struct {
+	BaseURL    string       // Status page API root, DefaultStatusURL unless overridden (e.g. in tests)
+	HTTPClient *http.Client // Transport used for requests
+}
+
+// NewStatusClient creates a client for the public status page.
+func NewStatusClient() *StatusClient {
+	return &StatusClient{
+		BaseURL:    DefaultStatusURL,
+		HTTPClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Incidents returns the most recent incidents on the status page, newest
+// first. The status page lists the last 50.
+//
+// Parameters:
+//   - ctx: Request context
+//
+// Returns:
+//   - []Incident: Recent incidents, resolved or not
+//   - error: Transport errors, or *APIError for non-2xx responses
+func (c *StatusClient) Incidents(ctx context.Context) ([]Incident, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/incidents.json", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &APIError{Status: resp.StatusCode}
+	}
+	var body struct {
+		Incidents []Incident `json:"incidents"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("cloudflare status: %w", err)
+	}
+	return body.Incidents, nil
+}
+
+// SyncStatusIncidents stores the recent status page incidents affecting
+// Logpush as chart annotations. Incidents already stored are updated, so an
+// ongoing incident gets its end once it is resolved.
+//
+// Parameters:
+//   - ctx: Request context
+//   - client: Status page client
+//   - db: Database controller to store the annotations in
+//
+// Returns:
+//   - int: Number of Logpush incidents stored
+//   - error: Any error fetching or storing the incidents
+func SyncStatusIncidents(ctx context.Context, client *StatusClient, db *database.SQLiteController) (int, error) {
+	incidents, err := client.Incidents(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var annotations []database.Annotation
+	for _, i := range incidents {
+		if !i.AffectsLogpush() {
+			continue
+		}
+		started := i.CreatedAt
+		if i.StartedAt != nil {
+			started = *i.StartedAt
+		}
+		annotations = append(annotations, database.Annotation{
+			Source:     database.AnnotationCloudflareStatus,
+			ExternalID: i.ID,
+			Title:      i.Name,
+			Status:     i.Status,
+			URL:        i.Shortlink,
+			StartedAt:  started,
+			EndedAt:    i.ResolvedAt,
+		})
+	}
+	if len(annotations) == 0 {
+		return 0, nil
+	}
+	return len(annotations), db.UpsertAnnotations(annotations)
+}
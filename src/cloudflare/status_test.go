@@ -0,0 +1,61 @@
+package cloudflare
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestSyncStatusIncidents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/incidents.json" {
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"incidents": [
+			{"id": "a1", "name": "Delayed log delivery", "status": "resolved", "shortlink": "https://stspg.io/a1",
+			 "created_at": "2025-09-15T10:05:00Z", "started_at": "2025-09-15T10:00:00Z", "resolved_at": "2025-09-15T12:00:00Z",
+			 "components": [{"name": "Logpush"}]},
+			{"id": "b2", "name": "Logpush errors for R2 destinations", "status": "investigating",
+			 "created_at": "2025-09-16T08:00:00Z", "resolved_at": null, "components": []},
+			{"id": "c3", "name": "Dashboard latency", "status": "resolved",
+			 "created_at": "2025-09-16T09:00:00Z", "resolved_at": "2025-09-16T10:00:00Z", "components": [{"name": "Dashboard"}]}
+		]}`))
+	}))
+	defer server.Close()
+
+	tempFile := "test_status_incidents.db"
+	defer os.Remove(tempFile)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	client := NewStatusClient()
+	client.BaseURL = server.URL
+	n, err := SyncStatusIncidents(context.Background(), client, db)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected two Logpush incidents stored, got %d, %v", n, err)
+	}
+
+	got, err := db.QueryAnnotations(time.Date(2025, 9, 15, 0, 0, 0, 0, time.UTC), time.Date(2025, 9, 17, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to query annotations: %v", err)
+	}
+	if len(got) != 2 || got[0].ExternalID != "a1" || got[1].ExternalID != "b2" {
+		t.Fatalf("Expected the two Logpush incidents, got %+v", got)
+	}
+	if !got[0].StartedAt.Equal(time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)) || got[0].EndedAt == nil || got[0].URL != "https://stspg.io/a1" {
+		t.Errorf("Expected the resolved incident from its start to its resolution, got %+v", got[0])
+	}
+	if got[1].EndedAt != nil || got[1].Status != "investigating" {
+		t.Errorf("Expected the ongoing incident without an end, got %+v", got[1])
+	}
+}
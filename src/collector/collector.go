@@ -0,0 +1,300 @@
+// Package collector measures Logpush batches at the edge and forwards the
+// measurements to a central LogpushEstimator, so volume can be measured
+// close to the Logpush destination without shipping the logs themselves.
+//
+// A collector is a Logpush HTTP destination like the estimator's /ingest
+// endpoint: it answers the ownership challenge, removes gzip encoding and
+// counts records the same way. It keeps no database. Each batch becomes an
+// ingest.Measurement that is queued in memory and posted in groups to the
+// central instance's POST /ingest/measurements (or
+// /t/{tenant}/ingest/measurements). Measurements that cannot be delivered
+// stay queued, up to MaxPending, and are retried on the next flush.
+//
+// The package depends only on the standard library and package ingest, so
+// it builds without cgo or SQLite for small containers and for runtimes,
+// such as WebAssembly-based edge workers, that serve an http.Handler.
+//
+// # Usage
+//
+//	c, err := collector.New(collector.Config{
+//		URL: "https://estimator.example.com:8080/ingest/measurements",
+//	})
+//	go c.Run(ctx)
+//	http.ListenAndServe(":8080", c)
+//
+// The cmd/collector binary does the same from the command line:
+//
+//	logpush-collector -listen :8080 -url https://estimator.example.com:8080/ingest/measurements
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+)
+
+// Defaults for a Config's zero values.
+const (
+	DefaultFlushInterval = 10 * time.Second // Longest wait before queued measurements are forwarded
+	DefaultMaxPending    = 10_000           // Measurements queued while the central instance is unreachable
+	DefaultMaxFlush      = 1_000            // Measurements forwarded per request
+	DefaultTimeout       = 30 * time.Second // Time allowed for one forwarding request
+)
+
+// Config controls a collector. Zero values use the defaults above.
+type Config struct {
+	URL    string      // Central measurements URL, e.g. http://estimator:8080/ingest/measurements; required
+	Header http.Header // Extra headers sent with every forwarding request
+
+	FlushInterval time.Duration // Longest wait before queued measurements are forwarded
+	MaxPending    int           // Measurements queued before the oldest are dropped
+	MaxFlush      int           // Measurements forwarded per request
+
+	Client *http.Client     // HTTP client (default one with DefaultTimeout)
+	Logger *slog.Logger     // Progress logger (default discards)
+	Now    func() time.Time // Clock batches are timestamped with (default time.Now)
+}
+
+// withDefaults returns c with zero values replaced by defaults.
+func (c Config) withDefaults() Config {
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = DefaultFlushInterval
+	}
+	if c.MaxPending <= 0 {
+		c.MaxPending = DefaultMaxPending
+	}
+	if c.MaxFlush <= 0 {
+		c.MaxFlush = DefaultMaxFlush
+	}
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+	if c.Logger == nil {
+		c.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+	return c
+}
+
+// Stats counts what a collector has done since it started.
+type Stats struct {
+	Batches   int64 `json:"batches"`   // Batches measured
+	Forwarded int64 `json:"forwarded"` // Measurements stored by the central instance
+	Dropped   int64 `json:"dropped"`   // Measurements dropped because the queue was full
+	Pending   int   `json:"pending"`   // Measurements waiting to be forwarded
+}
+
+// Collector measures batches posted to it and forwards the measurements.
+// It is safe for concurrent use.
+type Collector struct {
+	cfg Config
+
+	mu      sync.Mutex
+	pending []ingest.Measurement // Oldest first
+	stats   Stats
+	flushMu sync.Mutex // Serializes flushes so measurements are sent once
+}
+
+// New creates a collector forwarding to cfg.URL.
+//
+// Parameters:
+//   - cfg: Collector settings
+//
+// Returns:
+//   - *Collector: The collector, ready to serve batches
+//   - error: When cfg.URL is missing
+func New(cfg Config) (*Collector, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("collector: URL is required")
+	}
+	return &Collector{cfg: cfg.withDefaults()}, nil
+}
+
+// ServeHTTP accepts a Logpush batch, measures it and queues the
+// measurement, answering as the estimator's /ingest endpoint does: 200 for
+// a batch or the ownership challenge, 400 for an empty or corrupt body or
+// an invalid dataset or zone, 405 for methods other than POST, 413 and 415
+// for bodies that cannot be decoded. The dataset is taken from ?dataset= or
+// the X-Logpush-Dataset header, and the zone from ?zone= or the
+// X-Logpush-Zone header.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	dataset := r.URL.Query().Get("dataset")
+	if dataset == "" {
+		dataset = r.Header.Get(ingest.DatasetHeader)
+	}
+	if dataset != "" && !ingest.ValidDataset(dataset) {
+		http.Error(w, "Invalid dataset name", http.StatusBadRequest)
+		return
+	}
+	zone := r.URL.Query().Get("zone")
+	if zone == "" {
+		zone = r.Header.Get(ingest.ZoneHeader)
+	}
+	if zone != "" && !ingest.ValidZone(zone) {
+		http.Error(w, "Invalid zone name", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil || len(body) == 0 {
+		http.Error(w, "Request body cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if ingest.IsOwnershipChallenge(body, encoding) {
+		w.Write([]byte("OK"))
+		return
+	}
+	m, err := ingest.Measure(body, encoding)
+	switch {
+	case errors.Is(err, ingest.ErrUnsupportedEncoding):
+		http.Error(w, "Content-Encoding must be gzip or identity", http.StatusUnsupportedMediaType)
+		return
+	case errors.Is(err, ingest.ErrDecodedTooLarge):
+		http.Error(w, "Decompressed body is too large", http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
+		http.Error(w, "Failed to decompress request body", http.StatusBadRequest)
+		return
+	}
+	m.Timestamp = c.cfg.Now().UTC()
+	m.Dataset, m.Zone = dataset, zone
+	c.add(m)
+	w.Write([]byte("OK"))
+}
+
+// add queues m, dropping the oldest measurement when the queue is full.
+func (c *Collector) add(m ingest.Measurement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Batches++
+	if len(c.pending) >= c.cfg.MaxPending {
+		c.pending = c.pending[1:]
+		c.stats.Dropped++
+	}
+	c.pending = append(c.pending, m)
+}
+
+// Stats returns the collector's counters.
+func (c *Collector) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Pending = len(c.pending)
+	return stats
+}
+
+// Run forwards queued measurements every FlushInterval until ctx is done,
+// then makes a last attempt to forward what is left.
+//
+// Parameters:
+//   - ctx: Stops the collector when cancelled
+//
+// Returns:
+//   - error: The last flush's error, if measurements are left undelivered
+func (c *Collector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			timeout := c.cfg.Client.Timeout
+			if timeout <= 0 {
+				timeout = DefaultTimeout
+			}
+			final, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			return c.Flush(final)
+		case <-ticker.C:
+			if err := c.Flush(ctx); err != nil {
+				c.cfg.Logger.Warn("Failed to forward measurements", "error", err, "pending", c.Stats().Pending)
+			}
+		}
+	}
+}
+
+// Flush forwards every queued measurement, MaxFlush per request. It stops
+// at the first failure; measurements the central instance did not store
+// stay queued for the next flush.
+//
+// Parameters:
+//   - ctx: Cancels the forwarding requests
+//
+// Returns:
+//   - error: Why forwarding stopped, or nil when the queue was emptied
+func (c *Collector) Flush(ctx context.Context) error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+	for {
+		c.mu.Lock()
+		n := min(len(c.pending), c.cfg.MaxFlush)
+		group := append([]ingest.Measurement(nil), c.pending[:n]...)
+		droppedBefore := c.stats.Dropped
+		c.mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+
+		stored, err := c.send(ctx, group)
+		c.mu.Lock()
+		// Measurements dropped for space while sending were the oldest,
+		// which are the ones being sent
+		dropped := int(c.stats.Dropped - droppedBefore)
+		c.pending = c.pending[max(stored-dropped, 0):]
+		c.stats.Forwarded += int64(stored)
+		c.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		c.cfg.Logger.Info("Forwarded measurements", "count", stored)
+	}
+}
+
+// send posts measurements to the central instance.
+//
+// Returns:
+//   - int: How many measurements, from the start, were stored
+//   - error: Why the rest were not
+func (c *Collector) send(ctx context.Context, measurements []ingest.Measurement) (int, error) {
+	body, err := json.Marshal(map[string][]ingest.Measurement{"measurements": measurements})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	for name, values := range c.cfg.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.cfg.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 == 2 {
+		return len(measurements), nil
+	}
+	stored, _ := strconv.Atoi(resp.Header.Get(ingest.StoredHeader))
+	return min(max(stored, 0), len(measurements)), fmt.Errorf("central instance answered %s: %s", resp.Status, bytes.TrimSpace(message))
+}
@@ -0,0 +1,181 @@
+package collector
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+)
+
+// centralStub records forwarded measurements, storing at most accept of
+// each request's measurements before failing, or all when accept is
+// negative.
+type centralStub struct {
+	mu       sync.Mutex
+	accept   int
+	received []ingest.Measurement
+	requests int
+}
+
+func (s *centralStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Measurements []ingest.Measurement `json:"measurements"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	stored := len(body.Measurements)
+	if s.accept >= 0 && stored > s.accept {
+		stored = s.accept
+	}
+	s.received = append(s.received, body.Measurements[:stored]...)
+	w.Header().Set(ingest.StoredHeader, strconv.Itoa(stored))
+	if stored < len(body.Measurements) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func post(t *testing.T, c *Collector, target string, body []byte, encoding string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", target, bytes.NewReader(body))
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	rr := httptest.NewRecorder()
+	c.ServeHTTP(rr, req)
+	return rr
+}
+
+func gzipped(s string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(s))
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestCollectorForwardsMeasurements(t *testing.T) {
+	stub := &centralStub{accept: -1}
+	central := httptest.NewServer(stub)
+	defer central.Close()
+	at := time.Date(2025, 9, 15, 12, 0, 0, 0, time.UTC)
+	c, err := New(Config{URL: central.URL, MaxFlush: 2, Now: func() time.Time { return at }})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ndjson := strings.Repeat(`{"ClientRequestHost":"example.com"}`+"\n", 10)
+	batch := gzipped(ndjson)
+	if rr := post(t, c, "/ingest?dataset=http_requests&zone=example.com", batch, "gzip"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the batch to be accepted, got %d: %s", rr.Code, rr.Body.String())
+	}
+	post(t, c, "/", []byte("{\"a\":1}\n"), "")
+	post(t, c, "/", []byte("{\"b\":2}\n"), "identity")
+
+	// The ownership challenge and bad batches are answered but not queued
+	if rr := post(t, c, "/", gzipped(`{"content":"tests"}`), "gzip"); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 for the ownership challenge, got %d", rr.Code)
+	}
+	for _, tt := range []struct {
+		target, encoding string
+		body             []byte
+		code             int
+	}{
+		{"/", "", nil, http.StatusBadRequest},
+		{"/", "br", []byte("x"), http.StatusUnsupportedMediaType},
+		{"/", "gzip", []byte("not gzip"), http.StatusBadRequest},
+		{"/?dataset=Bad%20Name", "", []byte("x"), http.StatusBadRequest},
+		{"/?zone=Example.com", "", []byte("x"), http.StatusBadRequest},
+	} {
+		if rr := post(t, c, tt.target, tt.body, tt.encoding); rr.Code != tt.code {
+			t.Errorf("%s %q: expected %d, got %d", tt.target, tt.encoding, tt.code, rr.Code)
+		}
+	}
+	if stats := c.Stats(); stats.Batches != 3 || stats.Pending != 3 {
+		t.Fatalf("Expected 3 queued batches, got %+v", stats)
+	}
+
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if stub.requests != 2 || len(stub.received) != 3 {
+		t.Fatalf("Expected 3 measurements in 2 requests, got %d in %d", len(stub.received), stub.requests)
+	}
+	want := ingest.Measurement{
+		Timestamp:        at,
+		Dataset:          "http_requests",
+		Zone:             "example.com",
+		Filesize:         int64(len(batch)),
+		UncompressedSize: int64(len(ndjson)),
+		Encoding:         "gzip",
+		RecordCount:      10,
+		MinRecordSize:    35,
+		MaxRecordSize:    35,
+		AvgRecordSize:    35,
+	}
+	if got := stub.received[0]; got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	if got := stub.received[2]; got.Encoding != "" || got.Filesize != 8 || got.UncompressedSize != 8 {
+		t.Errorf("Expected identity encoding stored as none, got %+v", got)
+	}
+	if stats := c.Stats(); stats.Forwarded != 3 || stats.Pending != 0 {
+		t.Errorf("Expected everything forwarded, got %+v", stats)
+	}
+}
+
+func TestCollectorRetriesUnstored(t *testing.T) {
+	stub := &centralStub{accept: 1}
+	central := httptest.NewServer(stub)
+	defer central.Close()
+	c, _ := New(Config{URL: central.URL, MaxPending: 3})
+
+	for i := range 4 {
+		post(t, c, "/", []byte(strings.Repeat("x", i+1)), "")
+	}
+	if stats := c.Stats(); stats.Dropped != 1 || stats.Pending != 3 {
+		t.Fatalf("Expected the oldest batch dropped from a full queue, got %+v", stats)
+	}
+
+	// Only what the central instance did not store is sent again
+	if err := c.Flush(context.Background()); err == nil {
+		t.Fatal("Expected the partial failure to be reported")
+	}
+	if stats := c.Stats(); stats.Forwarded != 1 || stats.Pending != 2 {
+		t.Fatalf("Expected 1 forwarded and 2 queued, got %+v", stats)
+	}
+	stub.mu.Lock()
+	stub.accept = -1
+	stub.mu.Unlock()
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	var sizes []int64
+	for _, m := range stub.received {
+		sizes = append(sizes, m.Filesize)
+	}
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[1] != 3 || sizes[2] != 4 {
+		t.Errorf("Expected batches of 2, 3 and 4 bytes forwarded once each, got %v", sizes)
+	}
+
+	// An unreachable central instance keeps everything queued
+	central.Close()
+	post(t, c, "/", []byte("y"), "")
+	if err := c.Flush(context.Background()); err == nil || c.Stats().Pending != 1 {
+		t.Errorf("Expected the measurement to stay queued, got %v, %+v", err, c.Stats())
+	}
+
+	if _, err := New(Config{}); err == nil {
+		t.Error("Expected an error without a URL")
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"bytes"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// Change actions reported in a ChangeSet.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Change is a single object created, updated or deleted by Apply.
+type Change struct {
+	Action string `json:"action"` // ActionCreate, ActionUpdate or ActionDelete
+	Kind   string `json:"kind"`   // Object kind, e.g. "budget"
+	Name   string `json:"name"`   // Object name
+}
+
+// ChangeSet summarizes the difference between the stored configuration and a
+// desired document. Secrets are never included.
+type ChangeSet struct {
+	Changes   []Change `json:"changes"`   // Changes in kind and name order
+	Created   int      `json:"created"`   // Number of objects created
+	Updated   int      `json:"updated"`   // Number of objects updated
+	Deleted   int      `json:"deleted"`   // Number of objects deleted
+	Unchanged int      `json:"unchanged"` // Number of objects already as desired
+}
+
+// Plan compares the stored entries with the desired entries and returns the
+// changes needed to go from one to the other. Both slices must be sorted by
+// kind and name.
+func Plan(current, desired []database.ConfigEntry) ChangeSet {
+	cs := ChangeSet{Changes: []Change{}}
+	less := func(a, b database.ConfigEntry) bool {
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	}
+
+	i, j := 0, 0
+	for i < len(current) || j < len(desired) {
+		switch {
+		case j == len(desired) || (i < len(current) && less(current[i], desired[j])):
+			cs.Changes = append(cs.Changes, Change{ActionDelete, current[i].Kind, current[i].Name})
+			cs.Deleted++
+			i++
+		case i == len(current) || less(desired[j], current[i]):
+			cs.Changes = append(cs.Changes, Change{ActionCreate, desired[j].Kind, desired[j].Name})
+			cs.Created++
+			j++
+		default:
+			if bytes.Equal(current[i].Body, desired[j].Body) {
+				cs.Unchanged++
+			} else {
+				cs.Changes = append(cs.Changes, Change{ActionUpdate, desired[j].Kind, desired[j].Name})
+				cs.Updated++
+			}
+			i++
+			j++
+		}
+	}
+	return cs
+}
+
+// Preview validates doc and returns the change set Apply would produce,
+// without writing anything.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//   - doc: The complete desired configuration
+//
+// Returns:
+//   - ChangeSet: The changes Apply would make
+//   - error: A *ValidationError, or any error encountered while reading
+func Preview(db *database.SQLiteController, doc Document) (ChangeSet, error) {
+	cs, _, err := plan(db, doc)
+	return cs, err
+}
+
+// Apply validates doc, computes the change set against the stored
+// configuration and writes the changes. Applying the same document twice
+// produces an empty change set the second time.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//   - doc: The complete desired configuration
+//
+// Returns:
+//   - ChangeSet: The changes that were applied
+//   - error: A *ValidationError, or any error encountered while storing
+func Apply(db *database.SQLiteController, doc Document) (ChangeSet, error) {
+	cs, desired, err := plan(db, doc)
+	if err != nil || len(cs.Changes) == 0 {
+		return cs, err
+	}
+	if err := db.ReplaceConfigEntries(desired); err != nil {
+		return ChangeSet{}, err
+	}
+	return cs, nil
+}
+
+// plan resolves doc into stored entries and diffs them against the current
+// configuration.
+func plan(db *database.SQLiteController, doc Document) (ChangeSet, []database.ConfigEntry, error) {
+	if err := resolveSecrets(db, &doc); err != nil {
+		return ChangeSet{}, nil, err
+	}
+	desired, err := toEntries(doc)
+	if err != nil {
+		return ChangeSet{}, nil, err
+	}
+	current, err := db.ListConfigEntries()
+	if err != nil {
+		return ChangeSet{}, nil, err
+	}
+	return Plan(current, desired), desired, nil
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestPlan(t *testing.T) {
+	entry := func(kind, name, body string) database.ConfigEntry {
+		return database.ConfigEntry{Kind: kind, Name: name, Body: json.RawMessage(body)}
+	}
+	current := []database.ConfigEntry{
+		entry("budget", "a", `1`),
+		entry("budget", "b", `2`),
+		entry("retention", "default", `3`),
+	}
+	desired := []database.ConfigEntry{
+		entry("alert_rule", "x", `9`),
+		entry("budget", "b", `2`),
+		entry("retention", "default", `4`),
+	}
+
+	cs := Plan(current, desired)
+	want := []Change{
+		{ActionCreate, "alert_rule", "x"},
+		{ActionDelete, "budget", "a"},
+		{ActionUpdate, "retention", "default"},
+	}
+	if len(cs.Changes) != len(want) {
+		t.Fatalf("Expected %d changes, got %+v", len(want), cs.Changes)
+	}
+	for i := range want {
+		if cs.Changes[i] != want[i] {
+			t.Errorf("Change %d: expected %+v, got %+v", i, want[i], cs.Changes[i])
+		}
+	}
+	if cs.Created != 1 || cs.Updated != 1 || cs.Deleted != 1 || cs.Unchanged != 1 {
+		t.Errorf("Unexpected counts: %+v", cs)
+	}
+}
+
+func TestApplyIsIdempotent(t *testing.T) {
+	db := newTestDB(t, "test_config_apply.db")
+
+	cs, err := Apply(db, sampleDocument())
+	if err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	// Five objects plus the retention and sampling settings
+	if cs.Created != 7 || cs.Updated != 0 || cs.Deleted != 0 {
+		t.Errorf("Unexpected first change set: %+v", cs)
+	}
+
+	cs, err = Apply(db, sampleDocument())
+	if err != nil {
+		t.Fatalf("Failed to re-apply config: %v", err)
+	}
+	if len(cs.Changes) != 0 || cs.Unchanged != 7 {
+		t.Errorf("Expected no changes on re-apply, got %+v", cs)
+	}
+
+	doc := sampleDocument()
+	doc.Budgets[0].LimitCost = 250
+	doc.Tokens = nil
+	cs, err = Apply(db, doc)
+	if err != nil {
+		t.Fatalf("Failed to apply changed config: %v", err)
+	}
+	if cs.Updated != 1 || cs.Deleted != 1 {
+		t.Errorf("Expected one update and one delete, got %+v", cs)
+	}
+}
+
+func TestPreviewDoesNotWrite(t *testing.T) {
+	db := newTestDB(t, "test_config_preview.db")
+
+	cs, err := Preview(db, sampleDocument())
+	if err != nil {
+		t.Fatalf("Failed to preview config: %v", err)
+	}
+	if cs.Created != 7 {
+		t.Errorf("Expected 7 planned creates, got %+v", cs)
+	}
+	if entries, _ := db.ListConfigEntries(); len(entries) != 0 {
+		t.Errorf("Expected preview to leave the store empty, got %d entries", len(entries))
+	}
+}
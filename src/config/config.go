@@ -0,0 +1,1095 @@
+// Package config defines the declarative estimator configuration and
+// converts it to and from the objects stored in the database.
+//
+// The configuration covers tenants, pricing models, budgets, alert rules, API
+// tokens, webhooks, retention, payload sampling and label limit settings, and
+// the unit system byte quantities are priced and shown in. It is exchanged as
+// a single document so it can be kept in version control and applied by
+// deployment pipelines.
+//
+// Documents are defined with JSON field names. The admin API exchanges them
+// as YAML (see package yamldoc), exported as logpush-estimator.yaml.
+//
+// # Usage
+//
+//	doc, err := config.Export(db)
+//	if err != nil {
+//		return err
+//	}
+//	doc.Retention.RawDays = 90
+//	err = config.Import(db, doc)
+package config
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/events"
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+	"github.com/melatonein5/LogpushEstimator/src/secrets"
+)
+
+// APIVersion identifies the document schema.
+const APIVersion = "logpush-estimator/v1"
+
+// DocumentKind is the kind written to every exported document.
+const DocumentKind = "EstimatorConfig"
+
+// Redacted replaces token and webhook secrets in exported documents.
+// Importing a token or webhook whose secret is Redacted keeps the secret that
+// is already stored.
+const Redacted = "REDACTED"
+
+// Stored object kinds.
+const (
+	kindTenant       = "tenant"
+	kindPricingModel = "pricing_model"
+	kindBudget       = "budget"
+	kindAlertRule    = "alert_rule"
+	kindToken        = "token"
+	kindWebhook      = "webhook"
+	kindRetention    = "retention"
+	kindSampling     = "sampling"
+	kindLabelLimits  = "label_limits"
+	kindUnits        = "units"
+)
+
+// singletonName is the name of singleton objects such as retention.
+const singletonName = "default"
+
+// namePattern restricts object names to stable, URL-safe identifiers.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// tenantNamePattern restricts tenant names to lowercase DNS labels, so every
+// tenant can be addressed both as /t/{tenant}/ and as a subdomain.
+var tenantNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ValidTenantName reports whether name can be used as a tenant: 1 to 63
+// lowercase letters, digits or hyphens, not starting or ending with a hyphen.
+func ValidTenantName(name string) bool {
+	return tenantNamePattern.MatchString(name)
+}
+
+// Document is the complete declarative estimator configuration.
+type Document struct {
+	APIVersion    string         `json:"apiVersion"`     // Must be APIVersion
+	Kind          string         `json:"kind"`           // Must be DocumentKind
+	Tenants       []Tenant       `json:"tenants"`        // Provisioned tenants
+	PricingModels []PricingModel `json:"pricing_models"` // Destination pricing used for cost estimates
+	Budgets       []Budget       `json:"budgets"`        // Volume or cost limits per period
+	AlertRules    []AlertRule    `json:"alert_rules"`    // Threshold rules evaluated against metrics
+	Tokens        []Token        `json:"tokens"`         // API tokens; secrets are redacted on export
+	Webhooks      []Webhook      `json:"webhooks"`       // Endpoints notified of domain events
+	Retention     Retention      `json:"retention"`      // Data retention settings
+	Sampling      Sampling       `json:"sampling"`       // Payload sampling settings
+	LabelLimits   LabelLimits    `json:"label_limits"`   // Bounds on distinct batch labels
+
+	// Units is the unit system prices are quoted in and sizes are shown in
+	// when a user has not chosen one; empty means UnitsDecimal
+	Units Units `json:"units,omitempty"`
+}
+
+// Units is a unit system for byte quantities.
+type Units string
+
+// Unit systems.
+const (
+	UnitsDecimal Units = "decimal" // GB of 10^9 bytes, as cloud bills count
+	UnitsBinary  Units = "binary"  // GiB of 2^30 bytes
+)
+
+// Binary reports whether u counts in powers of 1024.
+func (u Units) Binary() bool {
+	return u == UnitsBinary
+}
+
+// BytesPerGB returns the bytes in one GB (10^9) or GiB (2^30).
+func (u Units) BytesPerGB() float64 {
+	if u.Binary() {
+		return 1 << 30
+	}
+	return 1e9
+}
+
+// GB returns the unit name prices are quoted per, "GB" or "GiB".
+func (u Units) GB() string {
+	if u.Binary() {
+		return "GiB"
+	}
+	return "GB"
+}
+
+// Tenant is a provisioned customer. Records are stored under any tenant
+// named in an ingest path; provisioning one lets tokens and budgets be
+// scoped to it before its first batch arrives.
+type Tenant struct {
+	Name        string `json:"name"`                  // Tenant name, as in /t/{tenant}/
+	Description string `json:"description,omitempty"` // Free-form description, e.g. the customer's name
+}
+
+// PricingModel prices ingested volume for a log destination.
+type PricingModel struct {
+	Name               string  `json:"name"`                 // Unique model name
+	Currency           string  `json:"currency"`             // ISO 4217 currency code, e.g. "USD"
+	PerGB              float64 `json:"per_gb"`               // Price per GB (or GiB, see Units) ingested
+	PerMillionRecords  float64 `json:"per_million_records"`  // Price per million records
+	PerMillionRequests float64 `json:"per_million_requests"` // Price per million write requests
+	Default            bool    `json:"default"`              // Used when no model is specified
+
+	// Batching models how the destination groups data into objects or
+	// requests, e.g. S3 PUTs or R2 Class A operations; nil prices every
+	// ingested batch as one request
+	Batching *BatchPolicy `json:"batching,omitempty"`
+
+	// Egress lists the transfer charges on the way from Cloudflare to the
+	// destination, each priced per GB delivered
+	Egress []EgressRate `json:"egress,omitempty"`
+
+	// Storage prices the data held at the destination per GB-month, in
+	// tiers by the amount held; empty leaves storage unpriced
+	Storage []PriceTier `json:"storage,omitempty"`
+
+	// Download prices data read back out of the destination per GB, in
+	// tiers by the month's volume; empty leaves downloads unpriced
+	Download []PriceTier `json:"download,omitempty"`
+
+	// Units is the unit every per-GB price and tier bound is in. It is set
+	// from the document's units when the configuration is loaded.
+	Units Units `json:"-"`
+}
+
+// PriceTier is one step of tiered per-GB pricing. A tier applies to the
+// quantity above the previous tier's UpToGB and up to its own; the last
+// tier leaves UpToGB zero to apply to everything beyond.
+type PriceTier struct {
+	UpToGB float64 `json:"up_to_gb"` // Upper bound of the tier in GB; 0 for no bound
+	PerGB  float64 `json:"per_gb"`   // Price per GB (or GB-month) within the tier
+}
+
+// EgressRate is a per-GB transfer charge between two providers or regions,
+// such as a cloud provider's inter-region transfer when the destination
+// bucket is not where data first lands.
+type EgressRate struct {
+	From  string  `json:"from"`   // Where data leaves, e.g. "cloudflare" or "aws:us-east-1"
+	To    string  `json:"to"`     // Where data arrives, e.g. "aws:eu-west-1"
+	PerGB float64 `json:"per_gb"` // Price per GB transferred
+}
+
+// BatchPolicy is the batching a destination applies before writing: a
+// batch is written when it reaches MaxBytes or MaxRecords, or when it has
+// been held for MaxAgeSeconds, whichever comes first. Zero disables a
+// limit; at least one must be set.
+type BatchPolicy struct {
+	MaxBytes      int64 `json:"max_bytes"`       // Largest object or request in bytes
+	MaxRecords    int64 `json:"max_records"`     // Most records per object or request
+	MaxAgeSeconds int   `json:"max_age_seconds"` // Longest a batch is held before it is written
+}
+
+// Budget caps ingested volume or estimated cost over a period.
+type Budget struct {
+	Name         string  `json:"name"`             // Unique budget name
+	Period       string  `json:"period"`           // "daily" or "monthly"
+	LimitBytes   int64   `json:"limit_bytes"`      // Volume limit; 0 for no volume limit
+	LimitCost    float64 `json:"limit_cost"`       // Cost limit; 0 for no cost limit
+	PricingModel string  `json:"pricing_model"`    // Pricing model for LimitCost; empty uses the default
+	Tenant       string  `json:"tenant,omitempty"` // Provisioned tenant whose usage counts; empty counts every tenant
+}
+
+// AlertRule fires when a metric crosses a threshold over a window.
+type AlertRule struct {
+	Name          string  `json:"name"`           // Unique rule name
+	Metric        string  `json:"metric"`         // One of the supported alert metrics
+	Comparison    string  `json:"comparison"`     // ">" or "<"
+	Threshold     float64 `json:"threshold"`      // Value the metric is compared against
+	WindowMinutes int     `json:"window_minutes"` // Evaluation window
+	Enabled       bool    `json:"enabled"`        // Disabled rules are kept but not evaluated
+}
+
+// Token is an API token with the scopes it grants.
+type Token struct {
+	Name   string   `json:"name"`             // Unique token name
+	Scopes []string `json:"scopes"`           // Granted scopes: "ingest", "admin" or both
+	Secret string   `json:"secret"`           // Token value or secret reference; values are Redacted on export
+	Tenant string   `json:"tenant,omitempty"` // Provisioned tenant the token is issued to; empty for instance-wide tokens
+
+	PreviousSecret     string     `json:"previous_secret,omitempty"`      // Secret replaced by the last rotation; Redacted on export
+	PreviousValidUntil *time.Time `json:"previous_valid_until,omitempty"` // When PreviousSecret stops being accepted
+}
+
+// Accepts reports whether secret authenticates as the token: it matches the
+// current secret, or the previous one during its grace period after a
+// rotation. Secret references must have been resolved.
+//
+// Parameters:
+//   - secret: Value presented by the client
+//   - now: Current time, checked against PreviousValidUntil
+//
+// Returns:
+//   - bool: Whether the secret is accepted
+func (t Token) Accepts(secret string, now time.Time) bool {
+	if secret == "" {
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(t.Secret)) == 1 {
+		return true
+	}
+	return t.PreviousSecret != "" && t.PreviousValidUntil != nil && now.Before(*t.PreviousValidUntil) &&
+		subtle.ConstantTimeCompare([]byte(secret), []byte(t.PreviousSecret)) == 1
+}
+
+// AuthenticateIngest checks the token an ingest request presents. Ingest
+// stays open until a token with the ingest scope exists; from then on every
+// request must present one. An instance-wide token authenticates any
+// tenant's ingest path, a tenant's token only that tenant's. Secrets are
+// compared in constant time, and secret references must have been resolved.
+//
+// Parameters:
+//   - credentials: Token candidates from the request, see ingest.Credentials
+//   - tenant: Tenant the request ingests for, or empty
+//   - now: Current time, for secrets in their rotation grace period
+//
+// Returns:
+//   - bool: Whether the request may ingest
+func (d *Document) AuthenticateIngest(credentials []string, tenant string, now time.Time) bool {
+	required := false
+	for _, t := range d.Tokens {
+		if !slices.Contains(t.Scopes, "ingest") {
+			continue
+		}
+		required = true
+		if t.Tenant != "" && t.Tenant != tenant {
+			continue
+		}
+		for _, c := range credentials {
+			if t.Accepts(c, now) {
+				return true
+			}
+		}
+	}
+	return !required
+}
+
+// AuthenticateAdmin checks the token a request to an endpoint that hands out
+// secrets presents, such as token rotation. Unlike ingest, these endpoints
+// are closed until an instance-wide token with the admin scope exists.
+// Secrets are compared in constant time, and secret references must have
+// been resolved.
+//
+// Parameters:
+//   - credentials: Token candidates from the request, see ingest.Credentials
+//   - now: Current time, for secrets in their rotation grace period
+//
+// Returns:
+//   - bool: Whether the request may proceed
+func (d *Document) AuthenticateAdmin(credentials []string, now time.Time) bool {
+	for _, t := range d.Tokens {
+		if t.Tenant != "" || !slices.Contains(t.Scopes, "admin") {
+			continue
+		}
+		for _, c := range credentials {
+			if t.Accepts(c, now) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasAdminToken reports whether an instance-wide token with the admin scope
+// exists. Until one does, the configuration API accepts writes without a
+// token so the first admin token can be added.
+//
+// Returns:
+//   - bool: Whether admin requests can be authenticated
+func (d *Document) HasAdminToken() bool {
+	for _, t := range d.Tokens {
+		if t.Tenant == "" && slices.Contains(t.Scopes, "admin") {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook kinds. A ticket webhook (GitHub or Jira) opens an issue for each
+// alert instead of POSTing the event as is, so it may only subscribe to
+// alert.fired.
+const (
+	WebhookEvent  = ""       // POSTs the event JSON
+	WebhookGitHub = "github" // Opens a GitHub issue; url is the repository's issues endpoint
+	WebhookJira   = "jira"   // Opens a Jira Cloud issue; url is the site's /rest/api/2/issue endpoint
+)
+
+// Webhook receives the domain events on its topics as JSON POST requests.
+// Deliveries go through the outbox, so they are retried and survive
+// restarts.
+type Webhook struct {
+	Name   string   `json:"name"`   // Unique webhook name
+	URL    string   `json:"url"`    // http or https URL events are POSTed to
+	Topics []string `json:"topics"` // Event topics to deliver, e.g. "alert.fired"
+
+	Kind         string   `json:"kind,omitempty"`          // WebhookEvent, WebhookGitHub or WebhookJira
+	Secret       string   `json:"secret,omitempty"`        // Ticket webhooks: GitHub token or Jira "email:api_token", or a secret reference; Redacted on export
+	Project      string   `json:"project,omitempty"`       // Jira project key
+	IssueType    string   `json:"issue_type,omitempty"`    // Jira issue type (default "Task")
+	Labels       []string `json:"labels,omitempty"`        // Labels added to opened tickets
+	DashboardURL string   `json:"dashboard_url,omitempty"` // Base URL of the dashboard, linked from tickets
+}
+
+// Ticket reports whether the webhook opens tickets rather than POSTing
+// events.
+func (h Webhook) Ticket() bool {
+	return h.Kind == WebhookGitHub || h.Kind == WebhookJira
+}
+
+// Retention controls how long data is kept.
+type Retention struct {
+	RawDays              int `json:"raw_days"`               // Days of raw batch records to keep; 0 keeps everything
+	MinuteAggregateHours int `json:"minute_aggregate_hours"` // Hours of per-minute aggregates to keep
+	TrashDays            int `json:"trash_days"`             // Days deleted records stay restorable; 0 uses the default
+
+	// RawPolicy is what pruning does with expiring raw records:
+	// RawPolicyDelete (the default) moves them to the trash, and
+	// RawPolicyArchive first writes them to ArchiveDir, so they outlive
+	// the trash
+	RawPolicy  string `json:"raw_policy,omitempty"`
+	ArchiveDir string `json:"archive_dir,omitempty"` // Absolute directory archives are written to; may be a mounted bucket
+}
+
+// Raw record retention policies.
+const (
+	RawPolicyDelete  = "delete"  // Prune straight to the trash
+	RawPolicyArchive = "archive" // Archive to compressed CSV, then prune
+)
+
+// Archives reports whether pruned raw records are archived first.
+func (r Retention) Archives() bool {
+	return r.RawPolicy == RawPolicyArchive
+}
+
+// RawCutoff returns the time before which raw records have expired at
+// now, truncated to the hour so successive checks agree on the range, and
+// false when RawDays keeps everything.
+func (r Retention) RawCutoff(now time.Time) (time.Time, bool) {
+	if r.RawDays <= 0 {
+		return time.Time{}, false
+	}
+	return now.UTC().Add(-time.Duration(r.RawDays) * 24 * time.Hour).Truncate(time.Hour), true
+}
+
+// TrashRetention returns how long deleted records stay in the trash.
+func (r Retention) TrashRetention() time.Duration {
+	if r.TrashDays <= 0 {
+		return database.DefaultTrashRetention
+	}
+	return time.Duration(r.TrashDays) * 24 * time.Hour
+}
+
+// Sampling controls which ingested batches have a redacted sample of their
+// first record stored for inspection.
+type Sampling struct {
+	EveryN       int      `json:"every_n"`       // Sample every Nth batch; 0 disables sampling
+	MaxBytes     int      `json:"max_bytes"`     // Samples are truncated to this many bytes; 0 uses the default
+	Keep         int      `json:"keep"`          // Number of most recent samples kept; 0 uses the default
+	RedactFields []string `json:"redact_fields"` // Field names whose values are redacted, matched case-insensitively; null uses the defaults
+
+	RedactPatterns []RedactPattern `json:"redact_patterns,omitempty"` // Patterns redacted from string values
+
+	ParseEveryN int `json:"parse_every_n,omitempty"` // Decode one in N batches for dimensions, scaling the counts by N; 0 or 1 decodes every batch
+}
+
+// RedactPattern is a named regular expression (RE2 syntax) whose matches are
+// redacted from any string value in a sample, for personal data that does
+// not live in a fixed field, such as email addresses in query strings.
+type RedactPattern struct {
+	Name  string `json:"name"`  // Unique rule name, reported in the redaction audit
+	Regex string `json:"regex"` // Expression whose matches are redacted
+}
+
+// Default sampling limits.
+const (
+	defaultSampleBytes = 4096
+	defaultSampleKeep  = 100
+)
+
+// defaultRedactFields lists Logpush fields that identify end users.
+var defaultRedactFields = []string{
+	"ClientIP",
+	"ClientRequestUserAgent",
+	"ClientRequestReferer",
+	"RequestHeaders",
+	"ResponseHeaders",
+	"Cookies",
+}
+
+// DefaultSampling returns the sampling settings used when none are stored.
+// Sampling is disabled until every_n is set.
+func DefaultSampling() Sampling {
+	return Sampling{
+		MaxBytes:     defaultSampleBytes,
+		Keep:         defaultSampleKeep,
+		RedactFields: append([]string(nil), defaultRedactFields...),
+	}
+}
+
+// SampleBytes returns the length samples are truncated to.
+func (s Sampling) SampleBytes() int {
+	if s.MaxBytes <= 0 {
+		return defaultSampleBytes
+	}
+	return s.MaxBytes
+}
+
+// ParseInterval returns N when one in N batches is decoded for dimensions.
+func (s Sampling) ParseInterval() int {
+	return max(s.ParseEveryN, 1)
+}
+
+// KeepSamples returns how many samples are kept.
+func (s Sampling) KeepSamples() int {
+	if s.Keep <= 0 {
+		return defaultSampleKeep
+	}
+	return s.Keep
+}
+
+// Redacted returns the field names to redact. Documents that omit
+// redact_fields get the defaults; an explicit empty list redacts nothing.
+func (s Sampling) Redacted() []string {
+	if s.RedactFields == nil {
+		return defaultRedactFields
+	}
+	return s.RedactFields
+}
+
+// Policy compiles the redaction rules. Validated documents always compile.
+//
+// Returns:
+//   - *ingest.RedactionPolicy: Field and pattern rules
+//   - error: If a pattern is not a valid regular expression
+func (s Sampling) Policy() (*ingest.RedactionPolicy, error) {
+	patterns := make([]ingest.RedactPattern, 0, len(s.RedactPatterns))
+	for _, p := range s.RedactPatterns {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %w", p.Name, err)
+		}
+		patterns = append(patterns, ingest.RedactPattern{Name: p.Name, Regex: re})
+	}
+	return ingest.NewRedactionPolicy(s.Redacted(), patterns), nil
+}
+
+// LabelLimits bounds the distinct label keys and values stored with
+// batches (see ingest.LabelGuard), so labels carrying request IDs or other
+// unbounded values cannot grow what label-filtered queries scan without
+// limit.
+type LabelLimits struct {
+	MaxKeys   int    `json:"max_keys"`   // Distinct label keys; 0 uses the default
+	MaxValues int    `json:"max_values"` // Distinct values per key; 0 uses the default
+	Policy    string `json:"policy"`     // "merge" (the default) or "reject"
+}
+
+// DefaultLabelLimits returns the label limits used when none are stored.
+func DefaultLabelLimits() LabelLimits {
+	return LabelLimits{MaxKeys: ingest.DefaultMaxLabelKeys, MaxValues: ingest.DefaultMaxLabelValues, Policy: ingest.LabelPolicyMerge}
+}
+
+// Limits returns the limits to enforce, with defaults for unset fields.
+func (l LabelLimits) Limits() ingest.LabelLimits {
+	out := ingest.LabelLimits{MaxKeys: l.MaxKeys, MaxValues: l.MaxValues, Policy: l.Policy}
+	if out.MaxKeys <= 0 {
+		out.MaxKeys = ingest.DefaultMaxLabelKeys
+	}
+	if out.MaxValues <= 0 {
+		out.MaxValues = ingest.DefaultMaxLabelValues
+	}
+	if out.Policy == "" {
+		out.Policy = ingest.LabelPolicyMerge
+	}
+	return out
+}
+
+// alertMetrics lists the metrics alert rules can reference.
+var alertMetrics = map[string]bool{
+	"bytes_per_hour":   true,
+	"records_per_hour": true,
+	"budget_percent":   true,
+	"ingest_failures":  true,
+}
+
+// tokenScopes lists the scopes a token can be granted: "ingest" for
+// AuthenticateIngest, "admin" for AuthenticateAdmin.
+var tokenScopes = map[string]bool{"ingest": true, "admin": true}
+
+// ValidationError reports a document that cannot be applied as written.
+type ValidationError struct {
+	msg string
+}
+
+func (e *ValidationError) Error() string { return e.msg }
+
+// invalidf formats a *ValidationError.
+func invalidf(format string, args ...any) error {
+	return &ValidationError{msg: fmt.Sprintf(format, args...)}
+}
+
+// checkTiers reports tiers with negative prices, bounds that do not
+// increase, or an unbounded tier before the last.
+func checkTiers(tiers []PriceTier) error {
+	prev := 0.0
+	for i, t := range tiers {
+		if t.PerGB < 0 || t.UpToGB < 0 {
+			return errors.New("prices cannot be negative")
+		}
+		last := i == len(tiers)-1
+		if (t.UpToGB == 0 && !last) || (t.UpToGB != 0 && t.UpToGB <= prev) {
+			return errors.New("tier bounds must increase, with only the last tier unbounded")
+		}
+		prev = t.UpToGB
+	}
+	return nil
+}
+
+// DefaultRetention returns the retention settings used when none are stored.
+func DefaultRetention() Retention {
+	return Retention{
+		MinuteAggregateHours: int(database.MinuteAggregateWindow.Hours()),
+		TrashDays:            int(database.DefaultTrashRetention.Hours() / 24),
+	}
+}
+
+// NewDocument returns an empty document with the default retention settings.
+func NewDocument() Document {
+	return Document{
+		APIVersion:    APIVersion,
+		Kind:          DocumentKind,
+		Tenants:       []Tenant{},
+		PricingModels: []PricingModel{},
+		Budgets:       []Budget{},
+		AlertRules:    []AlertRule{},
+		Tokens:        []Token{},
+		Webhooks:      []Webhook{},
+		Retention:     DefaultRetention(),
+		Sampling:      DefaultSampling(),
+		LabelLimits:   DefaultLabelLimits(),
+	}
+}
+
+// PricingModel returns the named pricing model or, when name is empty, the
+// one marked default, or the only one.
+//
+// Parameters:
+//   - name: Model name; empty selects the default
+//
+// Returns:
+//   - PricingModel: The model
+//   - bool: Whether a model was found
+func (d *Document) PricingModel(name string) (PricingModel, bool) {
+	for _, m := range d.PricingModels {
+		if (name == "" && m.Default) || (name != "" && m.Name == name) {
+			return m, true
+		}
+	}
+	if name == "" && len(d.PricingModels) == 1 {
+		return d.PricingModels[0], true
+	}
+	return PricingModel{}, false
+}
+
+// Validate checks the document, returning a *ValidationError that describes
+// the first invalid field.
+func (d *Document) Validate() error {
+	if d.APIVersion != APIVersion {
+		return invalidf("apiVersion must be %q", APIVersion)
+	}
+	if d.Kind != DocumentKind {
+		return invalidf("kind must be %q", DocumentKind)
+	}
+
+	seen := map[string]bool{}
+	checkName := func(kind, name string) error {
+		if !namePattern.MatchString(name) {
+			return invalidf("%s name %q must be 1-64 letters, digits, '-' or '_'", kind, name)
+		}
+		if seen[kind+"/"+name] {
+			return invalidf("duplicate %s %q", kind, name)
+		}
+		seen[kind+"/"+name] = true
+		return nil
+	}
+
+	tenants := map[string]bool{}
+	for _, t := range d.Tenants {
+		if !ValidTenantName(t.Name) {
+			return invalidf("tenant name %q must be 1-63 lowercase letters, digits or '-', not starting or ending with '-'", t.Name)
+		}
+		if err := checkName(kindTenant, t.Name); err != nil {
+			return err
+		}
+		tenants[t.Name] = true
+	}
+
+	models := map[string]bool{}
+	defaults := 0
+	for _, m := range d.PricingModels {
+		if err := checkName(kindPricingModel, m.Name); err != nil {
+			return err
+		}
+		if len(m.Currency) != 3 {
+			return invalidf("pricing_model %q: currency must be a 3-letter code", m.Name)
+		}
+		if m.PerGB < 0 || m.PerMillionRecords < 0 || m.PerMillionRequests < 0 {
+			return invalidf("pricing_model %q: prices cannot be negative", m.Name)
+		}
+		if b := m.Batching; b != nil {
+			if b.MaxBytes < 0 || b.MaxRecords < 0 || b.MaxAgeSeconds < 0 {
+				return invalidf("pricing_model %q: batching limits cannot be negative", m.Name)
+			}
+			if b.MaxBytes == 0 && b.MaxRecords == 0 && b.MaxAgeSeconds == 0 {
+				return invalidf("pricing_model %q: batching needs max_bytes, max_records or max_age_seconds", m.Name)
+			}
+		}
+		routes := map[string]bool{}
+		for _, e := range m.Egress {
+			if e.From == "" || e.To == "" || e.From == e.To {
+				return invalidf("pricing_model %q: egress needs distinct from and to", m.Name)
+			}
+			if e.PerGB < 0 {
+				return invalidf("pricing_model %q: prices cannot be negative", m.Name)
+			}
+			if routes[e.From+"\x00"+e.To] {
+				return invalidf("pricing_model %q: duplicate egress from %q to %q", m.Name, e.From, e.To)
+			}
+			routes[e.From+"\x00"+e.To] = true
+		}
+		for _, tiers := range [][]PriceTier{m.Storage, m.Download} {
+			if err := checkTiers(tiers); err != nil {
+				return invalidf("pricing_model %q: %v", m.Name, err)
+			}
+		}
+		if m.Default {
+			defaults++
+		}
+		models[m.Name] = true
+	}
+	if defaults > 1 {
+		return invalidf("only one pricing_model can be the default")
+	}
+
+	for _, b := range d.Budgets {
+		if err := checkName(kindBudget, b.Name); err != nil {
+			return err
+		}
+		if b.Period != "daily" && b.Period != "monthly" {
+			return invalidf("budget %q: period must be \"daily\" or \"monthly\"", b.Name)
+		}
+		if b.LimitBytes < 0 || b.LimitCost < 0 || (b.LimitBytes == 0 && b.LimitCost == 0) {
+			return invalidf("budget %q: set a positive limit_bytes or limit_cost", b.Name)
+		}
+		if b.PricingModel != "" && !models[b.PricingModel] {
+			return invalidf("budget %q: unknown pricing_model %q", b.Name, b.PricingModel)
+		}
+		if b.Tenant != "" && !tenants[b.Tenant] {
+			return invalidf("budget %q: unknown tenant %q", b.Name, b.Tenant)
+		}
+	}
+
+	for _, a := range d.AlertRules {
+		if err := checkName(kindAlertRule, a.Name); err != nil {
+			return err
+		}
+		if !alertMetrics[a.Metric] {
+			return invalidf("alert_rule %q: unsupported metric %q", a.Name, a.Metric)
+		}
+		if a.Comparison != ">" && a.Comparison != "<" {
+			return invalidf("alert_rule %q: comparison must be \">\" or \"<\"", a.Name)
+		}
+		if a.WindowMinutes <= 0 {
+			return invalidf("alert_rule %q: window_minutes must be positive", a.Name)
+		}
+	}
+
+	for _, t := range d.Tokens {
+		if err := checkName(kindToken, t.Name); err != nil {
+			return err
+		}
+		if len(t.Scopes) == 0 {
+			return invalidf("token %q: at least one scope is required", t.Name)
+		}
+		for _, s := range t.Scopes {
+			if !tokenScopes[s] {
+				return invalidf("token %q: unknown scope %q", t.Name, s)
+			}
+		}
+		if _, _, err := secrets.ParseReference(t.Secret); err != nil {
+			return invalidf("token %q: %v", t.Name, err)
+		}
+		if t.Tenant != "" && !tenants[t.Tenant] {
+			return invalidf("token %q: unknown tenant %q", t.Name, t.Tenant)
+		}
+		if (t.PreviousSecret == "") != (t.PreviousValidUntil == nil) {
+			return invalidf("token %q: previous_secret and previous_valid_until must be set together", t.Name)
+		}
+	}
+
+	for _, h := range d.Webhooks {
+		if err := checkName(kindWebhook, h.Name); err != nil {
+			return err
+		}
+		if u, err := url.Parse(h.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return invalidf("webhook %q: url must be an absolute http or https URL", h.Name)
+		}
+		if len(h.Topics) == 0 {
+			return invalidf("webhook %q: at least one topic is required", h.Name)
+		}
+		for _, t := range h.Topics {
+			if !slices.Contains(events.Topics, t) {
+				return invalidf("webhook %q: unknown topic %q", h.Name, t)
+			}
+			if h.Ticket() && t != events.TopicAlertFired {
+				return invalidf("webhook %q: %s webhooks only open tickets for %q", h.Name, h.Kind, events.TopicAlertFired)
+			}
+		}
+		switch h.Kind {
+		case WebhookEvent:
+			if h.Secret != "" || h.Project != "" || h.IssueType != "" || len(h.Labels) > 0 {
+				return invalidf("webhook %q: secret, project, issue_type and labels need kind \"github\" or \"jira\"", h.Name)
+			}
+		case WebhookGitHub, WebhookJira:
+			if _, _, err := secrets.ParseReference(h.Secret); err != nil {
+				return invalidf("webhook %q: %v", h.Name, err)
+			}
+			if h.Kind == WebhookJira && h.Project == "" {
+				return invalidf("webhook %q: jira webhooks need a project", h.Name)
+			}
+		default:
+			return invalidf("webhook %q: kind must be \"github\", \"jira\" or empty", h.Name)
+		}
+		if h.DashboardURL != "" {
+			if u, err := url.Parse(h.DashboardURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return invalidf("webhook %q: dashboard_url must be an absolute http or https URL", h.Name)
+			}
+		}
+	}
+
+	if d.Retention.RawDays < 0 {
+		return invalidf("retention: raw_days cannot be negative")
+	}
+	if d.Retention.MinuteAggregateHours <= 0 {
+		return invalidf("retention: minute_aggregate_hours must be positive")
+	}
+	if d.Retention.TrashDays < 0 {
+		return invalidf("retention: trash_days cannot be negative")
+	}
+	switch d.Retention.RawPolicy {
+	case "", RawPolicyDelete:
+	case RawPolicyArchive:
+		if !filepath.IsAbs(d.Retention.ArchiveDir) {
+			return invalidf("retention: the archive raw_policy needs an absolute archive_dir")
+		}
+	default:
+		return invalidf("retention: raw_policy must be \"delete\" or \"archive\"")
+	}
+
+	if d.Sampling.EveryN < 0 {
+		return invalidf("sampling: every_n cannot be negative")
+	}
+	if d.Sampling.MaxBytes < 0 {
+		return invalidf("sampling: max_bytes cannot be negative")
+	}
+	if d.Sampling.Keep < 0 {
+		return invalidf("sampling: keep cannot be negative")
+	}
+	if d.Sampling.ParseEveryN < 0 {
+		return invalidf("sampling: parse_every_n cannot be negative")
+	}
+	for _, f := range d.Sampling.RedactFields {
+		if f == "" {
+			return invalidf("sampling: redact_fields cannot contain empty names")
+		}
+	}
+	patternNames := make(map[string]bool)
+	for _, p := range d.Sampling.RedactPatterns {
+		if !namePattern.MatchString(p.Name) {
+			return invalidf("sampling: invalid redact pattern name %q", p.Name)
+		}
+		if patternNames[p.Name] {
+			return invalidf("sampling: duplicate redact pattern %q", p.Name)
+		}
+		patternNames[p.Name] = true
+		if p.Regex == "" {
+			return invalidf("sampling: redact pattern %q has no regex", p.Name)
+		}
+		if _, err := regexp.Compile(p.Regex); err != nil {
+			return invalidf("sampling: redact pattern %q: %v", p.Name, err)
+		}
+	}
+
+	if d.LabelLimits.MaxKeys < 0 || d.LabelLimits.MaxValues < 0 {
+		return invalidf("label_limits: max_keys and max_values cannot be negative")
+	}
+	switch d.LabelLimits.Policy {
+	case "", ingest.LabelPolicyMerge, ingest.LabelPolicyReject:
+	default:
+		return invalidf("label_limits: policy must be \"merge\" or \"reject\"")
+	}
+
+	switch d.Units {
+	case "", UnitsDecimal, UnitsBinary:
+	default:
+		return invalidf("units must be \"decimal\" or \"binary\"")
+	}
+	return nil
+}
+
+// Export reads the stored configuration as a document with token and
+// webhook secrets redacted.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//
+// Returns:
+//   - Document: The current configuration
+//   - error: Any error encountered while reading or decoding it
+func Export(db *database.SQLiteController) (Document, error) {
+	doc, err := load(db)
+	if err != nil {
+		return doc, err
+	}
+	for i, t := range doc.Tokens {
+		// A reference is where the secret lives, not the secret itself, so
+		// it is exported as written
+		if _, isRef, _ := secrets.ParseReference(t.Secret); !isRef {
+			doc.Tokens[i].Secret = Redacted
+		}
+		if t.PreviousSecret != "" {
+			doc.Tokens[i].PreviousSecret = Redacted
+		}
+	}
+	for i, h := range doc.Webhooks {
+		if _, isRef, _ := secrets.ParseReference(h.Secret); h.Secret != "" && !isRef {
+			doc.Webhooks[i].Secret = Redacted
+		}
+	}
+	return doc, nil
+}
+
+// ResolveReferences replaces token and webhook secrets written as secret
+// references, such as ${vault:secret/data/lpe#ingest}, with the values they
+// refer to.
+//
+// Parameters:
+//   - ctx: Context for secret store requests
+//   - doc: Document to resolve in place
+//   - resolver: Resolver for the secret stores
+//
+// Returns:
+//   - error: The first reference that could not be resolved
+func ResolveReferences(ctx context.Context, doc *Document, resolver *secrets.Resolver) error {
+	for i, t := range doc.Tokens {
+		secret, err := resolver.Resolve(ctx, t.Secret)
+		if err != nil {
+			return fmt.Errorf("token %q: %w", t.Name, err)
+		}
+		doc.Tokens[i].Secret = secret
+	}
+	for i, h := range doc.Webhooks {
+		secret, err := resolver.Resolve(ctx, h.Secret)
+		if err != nil {
+			return fmt.Errorf("webhook %q: %w", h.Name, err)
+		}
+		doc.Webhooks[i].Secret = secret
+	}
+	return nil
+}
+
+// Import validates doc and replaces the stored configuration with it.
+// Objects missing from doc are removed. Tokens and ticket webhooks whose
+// secret is empty or Redacted keep their stored secret; new ones must
+// include one. A Redacted previous token secret likewise keeps the stored
+// one.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//   - doc: The complete desired configuration
+//
+// Returns:
+//   - error: A *ValidationError, or any error encountered while storing
+func Import(db *database.SQLiteController, doc Document) error {
+	_, err := Apply(db, doc)
+	return err
+}
+
+// resolveSecrets validates doc and restores stored secrets for tokens and
+// ticket webhooks that were exported with their secret redacted.
+func resolveSecrets(db *database.SQLiteController, doc *Document) error {
+	if err := doc.Validate(); err != nil {
+		return err
+	}
+	current, err := load(db)
+	if err != nil {
+		return err
+	}
+	tokens := make(map[string]Token, len(current.Tokens))
+	for _, t := range current.Tokens {
+		tokens[t.Name] = t
+	}
+	for i, t := range doc.Tokens {
+		stored, ok := tokens[t.Name]
+		if t.PreviousSecret == Redacted {
+			doc.Tokens[i].PreviousSecret = stored.PreviousSecret
+			if stored.PreviousSecret == "" {
+				doc.Tokens[i].PreviousValidUntil = nil
+			}
+		}
+		if t.Secret != "" && t.Secret != Redacted {
+			continue
+		}
+		if !ok {
+			return invalidf("token %q: secret is required for new tokens", t.Name)
+		}
+		doc.Tokens[i].Secret = stored.Secret
+	}
+	webhookSecrets := make(map[string]string, len(current.Webhooks))
+	for _, h := range current.Webhooks {
+		webhookSecrets[h.Name] = h.Secret
+	}
+	for i, h := range doc.Webhooks {
+		if !h.Ticket() || (h.Secret != "" && h.Secret != Redacted) {
+			continue
+		}
+		secret := webhookSecrets[h.Name]
+		if secret == "" {
+			return invalidf("webhook %q: secret is required for new %s webhooks", h.Name, h.Kind)
+		}
+		doc.Webhooks[i].Secret = secret
+	}
+	return nil
+}
+
+// load reads the stored configuration without redacting secrets.
+func load(db *database.SQLiteController) (Document, error) {
+	doc := NewDocument()
+	entries, err := db.ListConfigEntries()
+	if err != nil {
+		return doc, err
+	}
+	for _, e := range entries {
+		var target any
+		switch e.Kind {
+		case kindTenant:
+			doc.Tenants = append(doc.Tenants, Tenant{})
+			target = &doc.Tenants[len(doc.Tenants)-1]
+		case kindPricingModel:
+			doc.PricingModels = append(doc.PricingModels, PricingModel{})
+			target = &doc.PricingModels[len(doc.PricingModels)-1]
+		case kindBudget:
+			doc.Budgets = append(doc.Budgets, Budget{})
+			target = &doc.Budgets[len(doc.Budgets)-1]
+		case kindAlertRule:
+			doc.AlertRules = append(doc.AlertRules, AlertRule{})
+			target = &doc.AlertRules[len(doc.AlertRules)-1]
+		case kindToken:
+			doc.Tokens = append(doc.Tokens, Token{})
+			target = &doc.Tokens[len(doc.Tokens)-1]
+		case kindWebhook:
+			doc.Webhooks = append(doc.Webhooks, Webhook{})
+			target = &doc.Webhooks[len(doc.Webhooks)-1]
+		case kindRetention:
+			target = &doc.Retention
+		case kindSampling:
+			target = &doc.Sampling
+		case kindLabelLimits:
+			target = &doc.LabelLimits
+		case kindUnits:
+			target = &doc.Units
+		default:
+			// Written by a newer version; leave it alone
+			continue
+		}
+		if err := json.Unmarshal(e.Body, target); err != nil {
+			return doc, fmt.Errorf("decode %s %q: %w", e.Kind, e.Name, err)
+		}
+	}
+	for i := range doc.PricingModels {
+		doc.PricingModels[i].Units = doc.Units
+	}
+	return doc, nil
+}
+
+// toEntries flattens doc into stored objects, sorted by kind and name.
+func toEntries(doc Document) ([]database.ConfigEntry, error) {
+	var entries []database.ConfigEntry
+	add := func(kind, name string, v any) error {
+		body, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, database.ConfigEntry{Kind: kind, Name: name, Body: body})
+		return nil
+	}
+	for _, t := range doc.Tenants {
+		if err := add(kindTenant, t.Name, t); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range doc.PricingModels {
+		if err := add(kindPricingModel, m.Name, m); err != nil {
+			return nil, err
+		}
+	}
+	for _, b := range doc.Budgets {
+		if err := add(kindBudget, b.Name, b); err != nil {
+			return nil, err
+		}
+	}
+	for _, a := range doc.AlertRules {
+		if err := add(kindAlertRule, a.Name, a); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range doc.Tokens {
+		if err := add(kindToken, t.Name, t); err != nil {
+			return nil, err
+		}
+	}
+	for _, h := range doc.Webhooks {
+		if err := add(kindWebhook, h.Name, h); err != nil {
+			return nil, err
+		}
+	}
+	if err := add(kindRetention, singletonName, doc.Retention); err != nil {
+		return nil, err
+	}
+	if err := add(kindSampling, singletonName, doc.Sampling); err != nil {
+		return nil, err
+	}
+	// Default label limits and units are not stored, so documents that
+	// omit them apply cleanly
+	if doc.LabelLimits.Limits() != DefaultLabelLimits().Limits() {
+		if err := add(kindLabelLimits, singletonName, doc.LabelLimits); err != nil {
+			return nil, err
+		}
+	}
+	if doc.Units != "" {
+		if err := add(kindUnits, singletonName, doc.Units); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Kind != entries[j].Kind {
+			return entries[i].Kind < entries[j].Kind
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
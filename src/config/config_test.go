@@ -0,0 +1,354 @@
+package config
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+)
+
+func newTestDB(t *testing.T, path string) *database.SQLiteController {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(path, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(path)
+	})
+	return db
+}
+
+func sampleDocument() Document {
+	doc := NewDocument()
+	doc.PricingModels = []PricingModel{{Name: "r2", Currency: "USD", PerGB: 0.015, PerMillionRequests: 4.5, Default: true,
+		Batching: &BatchPolicy{MaxBytes: 100_000_000, MaxAgeSeconds: 300},
+		Egress:   []EgressRate{{From: "cloudflare", To: "aws:us-east-1", PerGB: 0.02}}}}
+	doc.Budgets = []Budget{{Name: "monthly", Period: "monthly", LimitCost: 100, PricingModel: "r2"}}
+	doc.AlertRules = []AlertRule{{Name: "spike", Metric: "bytes_per_hour", Comparison: ">", Threshold: 1e9, WindowMinutes: 60, Enabled: true}}
+	doc.Tokens = []Token{{Name: "pipeline", Scopes: []string{"admin"}, Secret: "s3cret"}}
+	doc.Webhooks = []Webhook{{Name: "ops", URL: "https://hooks.example.com/lpe", Topics: []string{"alert.fired"}}}
+	doc.Retention.RawDays = 90
+	return doc
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	db := newTestDB(t, "test_config_roundtrip.db")
+
+	empty, err := Export(db)
+	if err != nil {
+		t.Fatalf("Failed to export empty config: %v", err)
+	}
+	if empty.APIVersion != APIVersion || empty.Retention != DefaultRetention() {
+		t.Errorf("Expected default document, got %+v", empty)
+	}
+
+	if err := Import(db, sampleDocument()); err != nil {
+		t.Fatalf("Failed to import config: %v", err)
+	}
+	exported, err := Export(db)
+	if err != nil {
+		t.Fatalf("Failed to export config: %v", err)
+	}
+	if len(exported.Budgets) != 1 || exported.Retention.RawDays != 90 {
+		t.Errorf("Unexpected exported document: %+v", exported)
+	}
+	if exported.Tokens[0].Secret != Redacted {
+		t.Errorf("Expected token secret to be redacted, got %q", exported.Tokens[0].Secret)
+	}
+
+	// Re-importing the redacted export must keep the stored secret
+	if err := Import(db, exported); err != nil {
+		t.Fatalf("Failed to re-import exported config: %v", err)
+	}
+	stored, err := load(db)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if stored.Tokens[0].Secret != "s3cret" {
+		t.Errorf("Expected stored secret to survive re-import, got %q", stored.Tokens[0].Secret)
+	}
+
+	// Objects missing from the document are removed
+	exported.AlertRules = nil
+	if err := Import(db, exported); err != nil {
+		t.Fatalf("Failed to import config: %v", err)
+	}
+	if stored, _ := load(db); len(stored.AlertRules) != 0 {
+		t.Errorf("Expected alert rules to be removed, got %+v", stored.AlertRules)
+	}
+}
+
+func TestTicketWebhookSecrets(t *testing.T) {
+	db := newTestDB(t, "test_config_ticket_webhook.db")
+
+	doc := sampleDocument()
+	doc.Webhooks = append(doc.Webhooks, Webhook{Name: "issues", URL: "https://api.github.com/repos/acme/logging/issues",
+		Topics: []string{"alert.fired"}, Kind: WebhookGitHub, Secret: "ghp_secret", Labels: []string{"cost"}})
+	if err := Import(db, doc); err != nil {
+		t.Fatalf("Failed to import config: %v", err)
+	}
+	exported, err := Export(db)
+	if err != nil {
+		t.Fatalf("Failed to export config: %v", err)
+	}
+	if exported.Webhooks[0].Name != "issues" || exported.Webhooks[0].Secret != Redacted || exported.Webhooks[1].Secret != "" {
+		t.Errorf("Expected only the ticket webhook's secret, redacted, got %+v", exported.Webhooks)
+	}
+
+	if err := Import(db, exported); err != nil {
+		t.Fatalf("Failed to re-import exported config: %v", err)
+	}
+	if stored, _ := load(db); stored.Webhooks[0].Secret != "ghp_secret" {
+		t.Errorf("Expected webhook secret to survive re-import, got %q", stored.Webhooks[0].Secret)
+	}
+}
+
+func TestImportRejectsInvalidDocuments(t *testing.T) {
+	db := newTestDB(t, "test_config_invalid.db")
+
+	tests := []struct {
+		name   string
+		mutate func(*Document)
+	}{
+		{"Wrong version", func(d *Document) { d.APIVersion = "v0" }},
+		{"Duplicate budget", func(d *Document) { d.Budgets = append(d.Budgets, d.Budgets[0]) }},
+		{"Unknown pricing model", func(d *Document) { d.Budgets[0].PricingModel = "s3" }},
+		{"Budget without limit", func(d *Document) { d.Budgets[0].LimitCost = 0 }},
+		{"Batching without limits", func(d *Document) { d.PricingModels[0].Batching = &BatchPolicy{} }},
+		{"Negative batch size", func(d *Document) { d.PricingModels[0].Batching = &BatchPolicy{MaxBytes: -1, MaxAgeSeconds: 60} }},
+		{"Egress without destination", func(d *Document) { d.PricingModels[0].Egress[0].To = "" }},
+		{"Negative egress price", func(d *Document) { d.PricingModels[0].Egress[0].PerGB = -0.01 }},
+		{"Duplicate egress route", func(d *Document) {
+			d.PricingModels[0].Egress = append(d.PricingModels[0].Egress, d.PricingModels[0].Egress[0])
+		}},
+		{"Negative storage price", func(d *Document) { d.PricingModels[0].Storage = []PriceTier{{PerGB: -1}} }},
+		{"Unbounded tier before the last", func(d *Document) { d.PricingModels[0].Storage = []PriceTier{{PerGB: 1}, {PerGB: 0.5}} }},
+		{"Decreasing download tiers", func(d *Document) {
+			d.PricingModels[0].Download = []PriceTier{{UpToGB: 100, PerGB: 0.1}, {UpToGB: 50, PerGB: 0.05}, {PerGB: 0.01}}
+		}},
+		{"Invalid tenant name", func(d *Document) { d.Tenants = []Tenant{{Name: "Acme"}} }},
+		{"Duplicate tenant", func(d *Document) { d.Tenants = []Tenant{{Name: "acme"}, {Name: "acme"}} }},
+		{"Budget for unknown tenant", func(d *Document) { d.Budgets[0].Tenant = "acme" }},
+		{"Token for unknown tenant", func(d *Document) { d.Tokens[0].Tenant = "acme" }},
+		{"Unsupported metric", func(d *Document) { d.AlertRules[0].Metric = "vibes" }},
+		{"Unknown scope", func(d *Document) { d.Tokens[0].Scopes = []string{"root"} }},
+		{"New token without secret", func(d *Document) { d.Tokens[0].Secret = Redacted }},
+		{"Malformed secret reference", func(d *Document) { d.Tokens[0].Secret = "${vault:secret/lpe}" }},
+		{"Relative webhook URL", func(d *Document) { d.Webhooks[0].URL = "/hook" }},
+		{"Webhook without topics", func(d *Document) { d.Webhooks[0].Topics = nil }},
+		{"Unknown webhook topic", func(d *Document) { d.Webhooks[0].Topics = []string{"ingest.dropped"} }},
+		{"Unknown webhook kind", func(d *Document) { d.Webhooks[0].Kind = "slack" }},
+		{"Ticket webhook for other topics", func(d *Document) {
+			d.Webhooks[0].Kind, d.Webhooks[0].Secret, d.Webhooks[0].Topics = WebhookGitHub, "ghp", []string{"job.synced"}
+		}},
+		{"Jira webhook without project", func(d *Document) { d.Webhooks[0].Kind, d.Webhooks[0].Secret = WebhookJira, "a:b" }},
+		{"New ticket webhook without secret", func(d *Document) { d.Webhooks[0].Kind = WebhookGitHub }},
+		{"Secret on an event webhook", func(d *Document) { d.Webhooks[0].Secret = "x" }},
+		{"Relative dashboard URL", func(d *Document) { d.Webhooks[0].DashboardURL = "lpe.example.com" }},
+		{"Negative retention", func(d *Document) { d.Retention.RawDays = -1 }},
+		{"Unknown raw policy", func(d *Document) { d.Retention.RawPolicy = "compress" }},
+		{"Archive without directory", func(d *Document) { d.Retention.RawPolicy = RawPolicyArchive }},
+		{"Relative archive directory", func(d *Document) {
+			d.Retention.RawPolicy, d.Retention.ArchiveDir = RawPolicyArchive, "archive"
+		}},
+		{"Negative sampling rate", func(d *Document) { d.Sampling.EveryN = -1 }},
+		{"Negative parse rate", func(d *Document) { d.Sampling.ParseEveryN = -1 }},
+		{"Empty redact field", func(d *Document) { d.Sampling.RedactFields = []string{""} }},
+		{"Invalid redact regex", func(d *Document) {
+			d.Sampling.RedactPatterns = []RedactPattern{{Name: "email", Regex: "(unclosed"}}
+		}},
+		{"Duplicate redact pattern", func(d *Document) {
+			d.Sampling.RedactPatterns = []RedactPattern{{Name: "email", Regex: "@"}, {Name: "email", Regex: "@"}}
+		}},
+		{"Negative label limit", func(d *Document) { d.LabelLimits.MaxValues = -1 }},
+		{"Unknown label policy", func(d *Document) { d.LabelLimits.Policy = "drop" }},
+		{"Unknown units", func(d *Document) { d.Units = "metric" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := sampleDocument()
+			tt.mutate(&doc)
+			err := Import(db, doc)
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Errorf("Expected a ValidationError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestUnits(t *testing.T) {
+	db := newTestDB(t, "test_config_units.db")
+
+	doc := sampleDocument()
+	doc.Units = UnitsBinary
+	if err := Import(db, doc); err != nil {
+		t.Fatalf("Failed to import config: %v", err)
+	}
+	exported, err := Export(db)
+	if err != nil {
+		t.Fatalf("Failed to export config: %v", err)
+	}
+	if exported.Units != UnitsBinary || exported.PricingModels[0].Units != UnitsBinary {
+		t.Errorf("Expected binary units on the document and its models, got %q and %q", exported.Units, exported.PricingModels[0].Units)
+	}
+
+	// Omitting units reverts to decimal
+	exported.Units = ""
+	if err := Import(db, exported); err != nil {
+		t.Fatalf("Failed to import config: %v", err)
+	}
+	if stored, _ := load(db); stored.Units.Binary() || stored.PricingModels[0].Units.BytesPerGB() != 1e9 || stored.Units.GB() != "GB" {
+		t.Errorf("Expected decimal units, got %q", stored.Units)
+	}
+	if UnitsBinary.BytesPerGB() != 1<<30 || UnitsBinary.GB() != "GiB" {
+		t.Errorf("Expected a GiB of 2^30 bytes")
+	}
+}
+
+func TestSamplingDefaults(t *testing.T) {
+	var s Sampling
+	if s.SampleBytes() != defaultSampleBytes || s.KeepSamples() != defaultSampleKeep {
+		t.Errorf("Expected zero limits to use the defaults, got %d and %d", s.SampleBytes(), s.KeepSamples())
+	}
+	if len(s.Redacted()) != len(defaultRedactFields) {
+		t.Errorf("Expected omitted redact_fields to use the defaults, got %v", s.Redacted())
+	}
+	s.RedactFields = []string{}
+	if len(s.Redacted()) != 0 {
+		t.Errorf("Expected an explicit empty list to redact nothing, got %v", s.Redacted())
+	}
+	if s.ParseInterval() != 1 {
+		t.Errorf("Expected every batch to be parsed by default, got 1 in %d", s.ParseInterval())
+	}
+	s.ParseEveryN = 20
+	if s.ParseInterval() != 20 {
+		t.Errorf("Expected 1 in 20 batches to be parsed, got 1 in %d", s.ParseInterval())
+	}
+}
+
+func TestLabelLimits(t *testing.T) {
+	db := newTestDB(t, "test_config_label_limits.db")
+
+	if got := (LabelLimits{}).Limits(); got != DefaultLabelLimits().Limits() {
+		t.Errorf("Expected zero limits to use the defaults, got %+v", got)
+	}
+
+	doc := sampleDocument()
+	doc.LabelLimits = LabelLimits{MaxValues: 10, Policy: "reject"}
+	if err := Import(db, doc); err != nil {
+		t.Fatalf("Failed to import config: %v", err)
+	}
+	exported, err := Export(db)
+	if err != nil {
+		t.Fatalf("Failed to export config: %v", err)
+	}
+	want := ingest.LabelLimits{MaxKeys: ingest.DefaultMaxLabelKeys, MaxValues: 10, Policy: ingest.LabelPolicyReject}
+	if got := exported.LabelLimits.Limits(); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	// Default limits are not stored
+	exported.LabelLimits = LabelLimits{}
+	if err := Import(db, exported); err != nil {
+		t.Fatalf("Failed to import config: %v", err)
+	}
+	entries, _ := db.ListConfigEntries()
+	for _, e := range entries {
+		if e.Kind == kindLabelLimits {
+			t.Errorf("Expected default label limits not to be stored, got %s", e.Body)
+		}
+	}
+}
+
+func TestSamplingPolicy(t *testing.T) {
+	s := Sampling{
+		RedactFields:   []string{"ClientIP"},
+		RedactPatterns: []RedactPattern{{Name: "email", Regex: `[\w.]+@[\w.]+`}},
+	}
+	policy, err := s.Policy()
+	if err != nil {
+		t.Fatalf("Failed to compile policy: %v", err)
+	}
+	got, audit, err := policy.Redact([]byte(`{"ClientIP":"192.0.2.1","Path":"/?u=a@example.com"}`))
+	if err != nil {
+		t.Fatalf("Failed to redact: %v", err)
+	}
+	if string(got) != `{"ClientIP":"[REDACTED]","Path":"/?u=[REDACTED]"}` || audit.Total() != 2 {
+		t.Errorf("Unexpected redaction %s %v", got, audit)
+	}
+}
+
+func TestAuthenticateIngest(t *testing.T) {
+	at := time.Date(2025, 9, 20, 12, 0, 0, 0, time.UTC)
+	doc := NewDocument()
+	if !doc.AuthenticateIngest(nil, "", at) {
+		t.Fatal("Expected ingest to be open without ingest tokens")
+	}
+	doc.Tokens = []Token{{Name: "ops", Scopes: []string{"admin"}, Secret: "r"}}
+	if !doc.AuthenticateIngest(nil, "", at) {
+		t.Fatal("Expected tokens without the ingest scope to leave ingest open")
+	}
+
+	until := at.Add(time.Hour)
+	doc.Tokens = append(doc.Tokens,
+		Token{Name: "logpush", Scopes: []string{"ingest"}, Secret: "n3w", PreviousSecret: "old", PreviousValidUntil: &until},
+		Token{Name: "acme", Scopes: []string{"ingest"}, Secret: "acme-token", Tenant: "acme"},
+	)
+	for _, tt := range []struct {
+		credentials []string
+		tenant      string
+		at          time.Time
+		want        bool
+	}{
+		{nil, "", at, false},
+		{[]string{"wrong"}, "", at, false},
+		{[]string{"r"}, "", at, false},
+		{[]string{"wrong", "n3w"}, "", at, true},
+		{[]string{"n3w"}, "acme", at, true},
+		{[]string{"old"}, "", at, true},
+		{[]string{"old"}, "", until, false},
+		{[]string{"acme-token"}, "acme", at, true},
+		{[]string{"acme-token"}, "", at, false},
+		{[]string{"acme-token"}, "globex", at, false},
+	} {
+		if got := doc.AuthenticateIngest(tt.credentials, tt.tenant, tt.at); got != tt.want {
+			t.Errorf("%q for tenant %q at %s: expected %v, got %v", tt.credentials, tt.tenant, tt.at, tt.want, got)
+		}
+	}
+}
+
+func TestAuthenticateAdmin(t *testing.T) {
+	at := time.Date(2025, 9, 20, 12, 0, 0, 0, time.UTC)
+	doc := NewDocument()
+	if doc.AuthenticateAdmin(nil, at) {
+		t.Fatal("Expected admin endpoints to be closed without admin tokens")
+	}
+
+	doc.Tokens = []Token{
+		{Name: "logpush", Scopes: []string{"ingest"}, Secret: "ingest-token"},
+		{Name: "ops", Scopes: []string{"ingest", "admin"}, Secret: "admin-token"},
+		{Name: "acme", Scopes: []string{"admin"}, Secret: "acme-token", Tenant: "acme"},
+	}
+	for _, tt := range []struct {
+		credentials []string
+		want        bool
+	}{
+		{nil, false},
+		{[]string{"ingest-token"}, false},
+		{[]string{"acme-token"}, false},
+		{[]string{"wrong", "admin-token"}, true},
+	} {
+		if got := doc.AuthenticateAdmin(tt.credentials, at); got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.credentials, tt.want, got)
+		}
+	}
+}
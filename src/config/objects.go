@@ -0,0 +1,390 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/secrets"
+)
+
+// ErrModified is returned when an object was changed or removed after the
+// time given as a precondition.
+var ErrModified = errors.New("config: object was modified")
+
+// ErrNotRotatable is returned when rotating a token whose secret is a secret
+// reference; such tokens are rotated in their secret store.
+var ErrNotRotatable = errors.New("config: token secret is a secret reference")
+
+// ErrExists is returned when an object to be created already exists.
+var ErrExists = errors.New("config: object already exists")
+
+// editMu serializes single-object edits, so that the read, the precondition
+// check and the write of one edit are not interleaved with another's.
+var editMu sync.Mutex
+
+// StoredBudget is a budget with the time it last changed, for single-object
+// edits.
+type StoredBudget struct {
+	Budget
+	UpdatedAt time.Time `json:"updated_at"` // When the budget last changed; the precondition for edits
+}
+
+// StoredAlertRule is an alert rule with the time it last changed, for
+// single-object edits.
+type StoredAlertRule struct {
+	AlertRule
+	UpdatedAt time.Time `json:"updated_at"` // When the rule last changed; the precondition for edits
+}
+
+// StoredRetention is the retention policy with the time it last changed,
+// for single-object edits.
+type StoredRetention struct {
+	Retention
+	UpdatedAt time.Time `json:"updated_at"` // When the policy last changed; zero if never stored
+}
+
+// GetRetention returns the stored retention policy, or the default one
+// when none is stored.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//
+// Returns:
+//   - StoredRetention: The policy with its modification time
+//   - error: Any error encountered while reading
+func GetRetention(db *database.SQLiteController) (StoredRetention, error) {
+	doc, times, err := loadWithTimes(db, kindRetention)
+	if err != nil {
+		return StoredRetention{}, err
+	}
+	return StoredRetention{doc.Retention, times[singletonName]}, nil
+}
+
+// SaveRetention replaces the retention policy and validates the resulting
+// configuration as Apply does.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//   - r: Retention policy to store
+//   - unmodifiedSince: UpdatedAt the caller read; zero skips the check
+//
+// Returns:
+//   - StoredRetention: The stored policy
+//   - error: ErrModified, a *ValidationError, or any error while storing
+func SaveRetention(db *database.SQLiteController, r Retention, unmodifiedSince time.Time) (StoredRetention, error) {
+	updated, err := edit(db, kindRetention, singletonName, unmodifiedSince, putRetention(r))
+	return StoredRetention{r, updated}, err
+}
+
+// CheckRetention validates the configuration that SaveRetention would
+// store, without storing it.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//   - r: Retention policy to check
+//
+// Returns:
+//   - error: A *ValidationError, or any error while reading
+func CheckRetention(db *database.SQLiteController, r Retention) error {
+	return check(db, putRetention(r))
+}
+
+// putRetention returns a change replacing the retention policy with r.
+func putRetention(r Retention) func(*Document) error {
+	return func(doc *Document) error {
+		doc.Retention = r
+		return nil
+	}
+}
+
+// ListBudgets returns the stored budgets ordered by name.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//
+// Returns:
+//   - []StoredBudget: Budgets with their modification times
+//   - error: Any error encountered while reading
+func ListBudgets(db *database.SQLiteController) ([]StoredBudget, error) {
+	doc, times, err := loadWithTimes(db, kindBudget)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]StoredBudget, len(doc.Budgets))
+	for i, b := range doc.Budgets {
+		out[i] = StoredBudget{b, times[b.Name]}
+	}
+	return out, nil
+}
+
+// SaveBudget creates or replaces a budget and validates the resulting
+// configuration as Apply does.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//   - b: Budget to store
+//   - unmodifiedSince: UpdatedAt the caller read; zero skips the check
+//
+// Returns:
+//   - StoredBudget: The stored budget
+//   - error: ErrModified, a *ValidationError, or any error while storing
+func SaveBudget(db *database.SQLiteController, b Budget, unmodifiedSince time.Time) (StoredBudget, error) {
+	updated, err := edit(db, kindBudget, b.Name, unmodifiedSince, putBudget(b))
+	return StoredBudget{b, updated}, err
+}
+
+// CheckBudget validates the configuration that SaveBudget would store,
+// without storing it.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//   - b: Budget to check
+//
+// Returns:
+//   - error: A *ValidationError, or any error while reading
+func CheckBudget(db *database.SQLiteController, b Budget) error {
+	return check(db, putBudget(b))
+}
+
+// putBudget returns a change adding b or replacing the budget of its name.
+func putBudget(b Budget) func(*Document) error {
+	return func(doc *Document) error {
+		i := slices.IndexFunc(doc.Budgets, func(x Budget) bool { return x.Name == b.Name })
+		if i < 0 {
+			doc.Budgets = append(doc.Budgets, b)
+		} else {
+			doc.Budgets[i] = b
+		}
+		return nil
+	}
+}
+
+// DeleteBudget removes a budget.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//   - name: Budget name
+//   - unmodifiedSince: UpdatedAt the caller read; zero skips the check
+//
+// Returns:
+//   - bool: Whether the budget existed
+//   - error: ErrModified, or any error while storing
+func DeleteBudget(db *database.SQLiteController, name string, unmodifiedSince time.Time) (bool, error) {
+	found := false
+	_, err := edit(db, kindBudget, name, unmodifiedSince, func(doc *Document) error {
+		doc.Budgets = slices.DeleteFunc(doc.Budgets, func(x Budget) bool {
+			found = found || x.Name == name
+			return x.Name == name
+		})
+		return nil
+	})
+	return found, err
+}
+
+// ListAlertRules returns the stored alert rules ordered by name.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//
+// Returns:
+//   - []StoredAlertRule: Rules with their modification times
+//   - error: Any error encountered while reading
+func ListAlertRules(db *database.SQLiteController) ([]StoredAlertRule, error) {
+	doc, times, err := loadWithTimes(db, kindAlertRule)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]StoredAlertRule, len(doc.AlertRules))
+	for i, a := range doc.AlertRules {
+		out[i] = StoredAlertRule{a, times[a.Name]}
+	}
+	return out, nil
+}
+
+// SaveAlertRule creates or replaces an alert rule and validates the
+// resulting configuration as Apply does.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//   - a: Rule to store
+//   - unmodifiedSince: UpdatedAt the caller read; zero skips the check
+//
+// Returns:
+//   - StoredAlertRule: The stored rule
+//   - error: ErrModified, a *ValidationError, or any error while storing
+func SaveAlertRule(db *database.SQLiteController, a AlertRule, unmodifiedSince time.Time) (StoredAlertRule, error) {
+	updated, err := edit(db, kindAlertRule, a.Name, unmodifiedSince, putAlertRule(a))
+	return StoredAlertRule{a, updated}, err
+}
+
+// CheckAlertRule validates the configuration that SaveAlertRule would store,
+// without storing it.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//   - a: Rule to check
+//
+// Returns:
+//   - error: A *ValidationError, or any error while reading
+func CheckAlertRule(db *database.SQLiteController, a AlertRule) error {
+	return check(db, putAlertRule(a))
+}
+
+// putAlertRule returns a change adding a or replacing the rule of its name.
+func putAlertRule(a AlertRule) func(*Document) error {
+	return func(doc *Document) error {
+		i := slices.IndexFunc(doc.AlertRules, func(x AlertRule) bool { return x.Name == a.Name })
+		if i < 0 {
+			doc.AlertRules = append(doc.AlertRules, a)
+		} else {
+			doc.AlertRules[i] = a
+		}
+		return nil
+	}
+}
+
+// DeleteAlertRule removes an alert rule.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//   - name: Rule name
+//   - unmodifiedSince: UpdatedAt the caller read; zero skips the check
+//
+// Returns:
+//   - bool: Whether the rule existed
+//   - error: ErrModified, or any error while storing
+func DeleteAlertRule(db *database.SQLiteController, name string, unmodifiedSince time.Time) (bool, error) {
+	found := false
+	_, err := edit(db, kindAlertRule, name, unmodifiedSince, func(doc *Document) error {
+		doc.AlertRules = slices.DeleteFunc(doc.AlertRules, func(x AlertRule) bool {
+			found = found || x.Name == name
+			return x.Name == name
+		})
+		return nil
+	})
+	return found, err
+}
+
+// RotateToken replaces a token's secret. The replaced secret stays valid
+// until validUntil, so clients can switch to the new one without a gap; a
+// validUntil that is not in the future revokes it at once.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//   - name: Token name
+//   - secret: New secret
+//   - validUntil: When the replaced secret stops being accepted
+//
+// Returns:
+//   - bool: Whether the token exists
+//   - error: ErrNotRotatable, or any error while storing
+func RotateToken(db *database.SQLiteController, name, secret string, validUntil time.Time) (bool, error) {
+	found := false
+	_, err := edit(db, kindToken, name, time.Time{}, func(doc *Document) error {
+		i := slices.IndexFunc(doc.Tokens, func(t Token) bool { return t.Name == name })
+		if i < 0 {
+			return nil
+		}
+		found = true
+		t := &doc.Tokens[i]
+		if _, isRef, _ := secrets.ParseReference(t.Secret); isRef {
+			return ErrNotRotatable
+		}
+		t.PreviousSecret, t.PreviousValidUntil = t.Secret, &validUntil
+		t.Secret = secret
+		return nil
+	})
+	return found, err
+}
+
+// loadWithTimes reads the stored configuration and the modification time of
+// each object of one kind, keyed by name.
+func loadWithTimes(db *database.SQLiteController, kind string) (Document, map[string]time.Time, error) {
+	doc, err := load(db)
+	if err != nil {
+		return doc, nil, err
+	}
+	entries, err := db.ListConfigEntries()
+	if err != nil {
+		return doc, nil, err
+	}
+	times := make(map[string]time.Time)
+	for _, e := range entries {
+		if e.Kind == kind {
+			times[e.Name] = e.UpdatedAt
+		}
+	}
+	return doc, times, nil
+}
+
+// Update applies a change spanning several objects to the stored
+// configuration in one transaction, as Apply does, so either every object is
+// stored or none is. change may return an error, such as one wrapping
+// ErrExists, to abandon the update.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//   - change: Modifies the stored configuration in place
+//   - dryRun: Only compute the change set
+//
+// Returns:
+//   - ChangeSet: The objects created, updated and deleted
+//   - error: The error from change, a *ValidationError, or any error while storing
+func Update(db *database.SQLiteController, change func(*Document) error, dryRun bool) (ChangeSet, error) {
+	editMu.Lock()
+	defer editMu.Unlock()
+
+	doc, err := load(db)
+	if err != nil {
+		return ChangeSet{}, err
+	}
+	if err := change(&doc); err != nil {
+		return ChangeSet{}, err
+	}
+	if dryRun {
+		return Preview(db, doc)
+	}
+	return Apply(db, doc)
+}
+
+// check validates the stored configuration with change applied.
+func check(db *database.SQLiteController, change func(*Document) error) error {
+	doc, err := load(db)
+	if err != nil {
+		return err
+	}
+	if err := change(&doc); err != nil {
+		return err
+	}
+	return doc.Validate()
+}
+
+// edit applies one object's change to the stored configuration. When
+// unmodifiedSince is set, the object must exist with exactly that
+// modification time. It returns the object's modification time afterwards,
+// which is unchanged when the edit changed nothing.
+func edit(db *database.SQLiteController, kind, name string, unmodifiedSince time.Time, change func(*Document) error) (time.Time, error) {
+	editMu.Lock()
+	defer editMu.Unlock()
+
+	doc, times, err := loadWithTimes(db, kind)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !unmodifiedSince.IsZero() && !times[name].Equal(unmodifiedSince) {
+		return time.Time{}, ErrModified
+	}
+	if err := change(&doc); err != nil {
+		return time.Time{}, err
+	}
+	if _, err := Apply(db, doc); err != nil {
+		return time.Time{}, err
+	}
+	if _, times, err = loadWithTimes(db, kind); err != nil {
+		return time.Time{}, err
+	}
+	return times[name], nil
+}
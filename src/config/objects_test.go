@@ -0,0 +1,174 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSaveBudgetPreconditions(t *testing.T) {
+	db := newTestDB(t, "test_config_objects.db")
+	if err := Import(db, sampleDocument()); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	budgets, err := ListBudgets(db)
+	if err != nil || len(budgets) != 1 || budgets[0].UpdatedAt.IsZero() {
+		t.Fatalf("Expected the imported budget with its updated_at, got %+v, %v", budgets, err)
+	}
+	read := budgets[0]
+
+	time.Sleep(10 * time.Millisecond)
+	changed := read.Budget
+	changed.LimitCost = 250
+	saved, err := SaveBudget(db, changed, read.UpdatedAt)
+	if err != nil {
+		t.Fatalf("SaveBudget failed: %v", err)
+	}
+	if !saved.UpdatedAt.After(read.UpdatedAt) {
+		t.Errorf("Expected updated_at to advance, got %v then %v", read.UpdatedAt, saved.UpdatedAt)
+	}
+
+	// A second writer still holding the first read loses
+	changed.LimitCost = 500
+	if _, err := SaveBudget(db, changed, read.UpdatedAt); !errors.Is(err, ErrModified) {
+		t.Errorf("Expected ErrModified for a stale updated_at, got %v", err)
+	}
+	if _, err := DeleteBudget(db, "monthly", read.UpdatedAt); !errors.Is(err, ErrModified) {
+		t.Errorf("Expected ErrModified for a stale delete, got %v", err)
+	}
+
+	doc, err := Export(db)
+	if err != nil || doc.Budgets[0].LimitCost != 250 {
+		t.Fatalf("Expected the first write to be kept, got %+v, %v", doc.Budgets, err)
+	}
+	if len(doc.Tokens) != 1 || doc.Tokens[0].Secret != Redacted {
+		t.Errorf("Expected other objects to be untouched, got %+v", doc.Tokens)
+	}
+
+	invalid := changed
+	invalid.PricingModel = "missing"
+	var verr *ValidationError
+	if err := CheckBudget(db, invalid); !errors.As(err, &verr) {
+		t.Errorf("Expected a validation error for an unknown pricing model, got %v", err)
+	}
+	if _, err := SaveBudget(db, invalid, time.Time{}); !errors.As(err, &verr) {
+		t.Errorf("Expected SaveBudget to validate, got %v", err)
+	}
+
+	if deleted, err := DeleteBudget(db, "monthly", saved.UpdatedAt); err != nil || !deleted {
+		t.Errorf("Expected the budget to be deleted, got %v, %v", deleted, err)
+	}
+	if deleted, err := DeleteBudget(db, "monthly", time.Time{}); err != nil || deleted {
+		t.Errorf("Expected nothing left to delete, got %v, %v", deleted, err)
+	}
+}
+
+func TestSaveAlertRule(t *testing.T) {
+	db := newTestDB(t, "test_config_alert_objects.db")
+
+	rule := AlertRule{Name: "failures", Metric: "ingest_failures", Comparison: ">", Threshold: 10, WindowMinutes: 60, Enabled: true}
+	saved, err := SaveAlertRule(db, rule, time.Time{})
+	if err != nil || saved.UpdatedAt.IsZero() {
+		t.Fatalf("SaveAlertRule failed: %+v, %v", saved, err)
+	}
+
+	// Saving an unchanged rule keeps its modification time
+	again, err := SaveAlertRule(db, rule, saved.UpdatedAt)
+	if err != nil || !again.UpdatedAt.Equal(saved.UpdatedAt) {
+		t.Errorf("Expected an unchanged rule to keep updated_at, got %+v, %v", again, err)
+	}
+
+	// A precondition on a rule that does not exist fails
+	other := rule
+	other.Name = "other"
+	if _, err := SaveAlertRule(db, other, saved.UpdatedAt); !errors.Is(err, ErrModified) {
+		t.Errorf("Expected ErrModified for a missing rule, got %v", err)
+	}
+
+	rules, err := ListAlertRules(db)
+	if err != nil || len(rules) != 1 || rules[0].Name != "failures" {
+		t.Errorf("Expected one rule, got %+v, %v", rules, err)
+	}
+}
+
+func TestUpdateIsAllOrNothing(t *testing.T) {
+	db := newTestDB(t, "test_config_update.db")
+	if err := Import(db, sampleDocument()); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	cs, err := Update(db, func(doc *Document) error {
+		doc.Tenants = append(doc.Tenants, Tenant{Name: "acme"})
+		doc.Tokens = append(doc.Tokens, Token{Name: "acme", Scopes: []string{"ingest"}, Secret: "t0ken", Tenant: "acme"})
+		return nil
+	}, true)
+	if err != nil || cs.Created != 2 {
+		t.Fatalf("Expected a dry run creating two objects, got %+v, %v", cs, err)
+	}
+	if doc, _ := Export(db); len(doc.Tenants) != 0 {
+		t.Fatalf("Expected the dry run to store nothing, got %+v", doc.Tenants)
+	}
+
+	// The invalid budget rejects the tenant added with it
+	_, err = Update(db, func(doc *Document) error {
+		doc.Tenants = append(doc.Tenants, Tenant{Name: "acme"})
+		doc.Budgets = append(doc.Budgets, Budget{Name: "acme", Period: "weekly", LimitBytes: 1, Tenant: "acme"})
+		return nil
+	}, false)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	if doc, _ := Export(db); len(doc.Tenants) != 0 {
+		t.Errorf("Expected nothing stored after a failed update, got %+v", doc.Tenants)
+	}
+
+	if _, err := Update(db, func(*Document) error { return ErrExists }, false); !errors.Is(err, ErrExists) {
+		t.Errorf("Expected the change's error, got %v", err)
+	}
+}
+
+func TestRotateToken(t *testing.T) {
+	db := newTestDB(t, "test_config_rotate.db")
+	doc := sampleDocument()
+	doc.Tokens = append(doc.Tokens, Token{Name: "vaulted", Scopes: []string{"ingest"}, Secret: "${vault:secret/data/lpe#ingest}"})
+	if err := Import(db, doc); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	at := time.Date(2025, 9, 20, 12, 0, 0, 0, time.UTC)
+	if found, err := RotateToken(db, "pipeline", "n3w", at.Add(time.Hour)); err != nil || !found {
+		t.Fatalf("RotateToken failed: %v, %v", found, err)
+	}
+	if found, err := RotateToken(db, "missing", "x", at); err != nil || found {
+		t.Errorf("Expected a missing token to be reported, got %v, %v", found, err)
+	}
+	if _, err := RotateToken(db, "vaulted", "x", at); !errors.Is(err, ErrNotRotatable) {
+		t.Errorf("Expected ErrNotRotatable for a secret reference, got %v", err)
+	}
+
+	stored, err := load(db)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	token := stored.Tokens[0]
+	if !token.Accepts("n3w", at) || !token.Accepts("s3cret", at) {
+		t.Errorf("Expected both secrets to be accepted during the grace period, got %+v", token)
+	}
+	if token.Accepts("s3cret", at.Add(time.Hour)) || !token.Accepts("n3w", at.Add(time.Hour)) || token.Accepts("", at) {
+		t.Errorf("Expected only the new secret to be accepted after the grace period")
+	}
+
+	// Re-importing an export keeps both secrets
+	exported, err := Export(db)
+	if err != nil || exported.Tokens[0].PreviousSecret != Redacted {
+		t.Fatalf("Expected the previous secret to be redacted, got %+v, %v", exported.Tokens, err)
+	}
+	if err := Import(db, exported); err != nil {
+		t.Fatalf("Re-import failed: %v", err)
+	}
+	if stored, _ := load(db); stored.Tokens[0].PreviousSecret != "s3cret" || stored.Tokens[0].Secret != "n3w" {
+		t.Errorf("Expected the secrets to survive a round trip, got %+v", stored.Tokens[0])
+	}
+}
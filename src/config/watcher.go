@@ -0,0 +1,68 @@
+package config
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/secrets"
+)
+
+// Watcher serves the stored configuration from memory for hot paths such as
+// ingest, reloading it from the database at most once per interval. Changes
+// applied through Import or Apply therefore take effect within one interval.
+// Secret references in the document are resolved on every reload, so a
+// secret rotated in its store is picked up the same way.
+type Watcher struct {
+	db       *database.SQLiteController
+	interval time.Duration
+	resolver *secrets.Resolver
+
+	mu     sync.Mutex
+	doc    Document
+	loaded time.Time
+	err    error
+}
+
+// NewWatcher creates a watcher over db's configuration.
+//
+// Parameters:
+//   - db: Database controller holding the configuration
+//   - interval: Maximum age of the cached document
+//   - resolver: Resolver for secret references, or nil to leave them as written
+//
+// Returns:
+//   - *Watcher: Watcher that loads the configuration on first use
+func NewWatcher(db *database.SQLiteController, interval time.Duration, resolver *secrets.Resolver) *Watcher {
+	return &Watcher{db: db, interval: interval, resolver: resolver, doc: NewDocument()}
+}
+
+// Current returns the cached configuration, reloading it if it is older than
+// the interval. If reloading fails, including when a secret reference cannot
+// be resolved, the last successfully loaded document (or the defaults) is
+// returned and the reload is retried after the next interval. Token secrets
+// are not redacted.
+func (w *Watcher) Current() Document {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if time.Since(w.loaded) >= w.interval {
+		doc, err := load(w.db)
+		if err == nil && w.resolver != nil {
+			err = ResolveReferences(context.Background(), &doc, w.resolver)
+		}
+		if err == nil {
+			w.doc = doc
+		}
+		w.err = err
+		w.loaded = time.Now()
+	}
+	return w.doc
+}
+
+// Err returns the error from the most recent reload, or nil if it succeeded.
+func (w *Watcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
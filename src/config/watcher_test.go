@@ -0,0 +1,70 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/secrets"
+)
+
+func TestWatcherReloadsAfterInterval(t *testing.T) {
+	db := newTestDB(t, "test_config_watcher.db")
+
+	w := NewWatcher(db, time.Hour, nil)
+	if w.Current().Sampling.EveryN != 0 {
+		t.Fatal("Expected sampling to be disabled by default")
+	}
+
+	doc := NewDocument()
+	doc.Sampling.EveryN = 10
+	if err := Import(db, doc); err != nil {
+		t.Fatalf("Failed to import config: %v", err)
+	}
+	// Within the interval the cached document is served
+	if w.Current().Sampling.EveryN != 0 {
+		t.Error("Expected the cached document before the interval elapsed")
+	}
+
+	w = NewWatcher(db, 0, nil)
+	if w.Current().Sampling.EveryN != 10 {
+		t.Error("Expected the stored document after reloading")
+	}
+}
+
+func TestWatcherResolvesSecretReferences(t *testing.T) {
+	db := newTestDB(t, "test_config_watcher_secrets.db")
+
+	env := map[string]string{"INGEST_TOKEN": "first"}
+	resolver := secrets.NewResolver(func(k string) string { return env[k] })
+
+	doc := NewDocument()
+	doc.Tokens = []Token{{Name: "ingest", Scopes: []string{"ingest"}, Secret: "${env:INGEST_TOKEN}"}}
+	if err := Import(db, doc); err != nil {
+		t.Fatalf("Failed to import config: %v", err)
+	}
+
+	w := NewWatcher(db, 0, resolver)
+	if got := w.Current().Tokens[0].Secret; got != "first" {
+		t.Errorf("Expected the reference to resolve, got %q", got)
+	}
+
+	// Rotated secrets are picked up on reload
+	env["INGEST_TOKEN"] = "second"
+	if got := w.Current().Tokens[0].Secret; got != "second" {
+		t.Errorf("Expected the rotated secret, got %q", got)
+	}
+
+	// An unresolvable reference keeps the last good document
+	delete(env, "INGEST_TOKEN")
+	if got := w.Current().Tokens[0].Secret; got != "second" || w.Err() == nil {
+		t.Errorf("Expected the last good secret and an error, got %q, %v", got, w.Err())
+	}
+
+	exported, err := Export(db)
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if exported.Tokens[0].Secret != "${env:INGEST_TOKEN}" {
+		t.Errorf("Expected references to be exported as written, got %q", exported.Tokens[0].Secret)
+	}
+}
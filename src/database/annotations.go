@@ -0,0 +1,114 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// createAnnotationsTable holds the DDL for chart annotations: periods of
+// interest, such as Cloudflare incidents, drawn over the time series so a
+// gap in delivery can be told apart from an outage at the source.
+const createAnnotationsTable = `CREATE TABLE IF NOT EXISTS annotations (
+	source TEXT NOT NULL,
+	external_id TEXT NOT NULL,
+	title TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT '',
+	url TEXT NOT NULL DEFAULT '',
+	started_at DATETIME NOT NULL,
+	ended_at DATETIME,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (source, external_id)
+);
+CREATE INDEX IF NOT EXISTS idx_annotations_started_at ON annotations(started_at);`
+
+// AnnotationCloudflareStatus is the source of annotations created from
+// Cloudflare status page incidents.
+const AnnotationCloudflareStatus = "cloudflare_status"
+
+// Annotation marks a period on the time series charts.
+type Annotation struct {
+	Source     string     `json:"source"`      // Where the annotation came from, e.g. AnnotationCloudflareStatus
+	ExternalID string     `json:"external_id"` // Identifier within the source, e.g. the incident ID
+	Title      string     `json:"title"`       // Short description shown on the chart
+	Status     string     `json:"status"`      // Source-specific status, e.g. "investigating" or "resolved"
+	URL        string     `json:"url"`         // Where to read more
+	StartedAt  time.Time  `json:"started_at"`  // Start of the period, UTC
+	EndedAt    *time.Time `json:"ended_at"`    // End of the period, or nil while ongoing
+}
+
+// UpsertAnnotations stores annotations, replacing any previously stored
+// annotation with the same source and external ID.
+//
+// Parameters:
+//   - annotations: Annotations to store
+//
+// Returns:
+//   - error: Any error encountered; on error nothing is stored
+func (c *SQLiteController) UpsertAnnotations(annotations []Annotation) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		c.logger.Error("Failed to begin annotations transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	now := c.now().UTC()
+	for _, a := range annotations {
+		var ended any
+		if a.EndedAt != nil {
+			ended = a.EndedAt.UTC()
+		}
+		_, err := tx.Exec(`INSERT INTO annotations (source, external_id, title, status, url, started_at, ended_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(source, external_id) DO UPDATE SET title = excluded.title, status = excluded.status, url = excluded.url,
+			started_at = excluded.started_at, ended_at = excluded.ended_at, updated_at = excluded.updated_at`,
+			a.Source, a.ExternalID, a.Title, a.Status, a.URL, a.StartedAt.UTC(), ended, now)
+		if err != nil {
+			c.logger.Error("Failed to store annotation", "error", err, "source", a.Source, "id", a.ExternalID)
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.logger.Error("Failed to commit annotations", "error", err)
+		return err
+	}
+	return nil
+}
+
+// QueryAnnotations returns the annotations overlapping [start, end),
+// including ongoing ones that started before end, ordered by start.
+//
+// Parameters:
+//   - start: Start time (inclusive)
+//   - end: End time (exclusive)
+//
+// Returns:
+//   - []Annotation: Overlapping annotations
+//   - error: Any error encountered during the query
+func (c *SQLiteController) QueryAnnotations(start, end time.Time) ([]Annotation, error) {
+	rows, err := c.db.Query(`SELECT source, external_id, title, status, url, started_at, ended_at FROM annotations
+		WHERE started_at < ? AND (ended_at IS NULL OR ended_at >= ?) ORDER BY started_at, source, external_id`,
+		end.UTC(), start.UTC())
+	if err != nil {
+		c.logger.Error("Failed to query annotations", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Annotation{}
+	for rows.Next() {
+		var (
+			a     Annotation
+			ended sql.NullTime
+		)
+		if err := rows.Scan(&a.Source, &a.ExternalID, &a.Title, &a.Status, &a.URL, &a.StartedAt, &ended); err != nil {
+			c.logger.Error("Failed to scan annotation row", "error", err)
+			return nil, err
+		}
+		a.StartedAt = a.StartedAt.UTC()
+		if ended.Valid {
+			t := ended.Time.UTC()
+			a.EndedAt = &t
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
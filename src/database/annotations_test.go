@@ -0,0 +1,51 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAnnotations(t *testing.T) {
+	tempFile := "test_annotations.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	start := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	resolved := start.Add(2 * time.Hour)
+	err = controller.UpsertAnnotations([]Annotation{
+		{Source: AnnotationCloudflareStatus, ExternalID: "old", Title: "Delayed Logpush delivery", StartedAt: start, EndedAt: &resolved},
+		{Source: AnnotationCloudflareStatus, ExternalID: "new", Title: "Logpush errors", Status: "investigating", StartedAt: start.Add(24 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to store annotations: %v", err)
+	}
+
+	// A later poll records the incident as resolved
+	ended := start.Add(26 * time.Hour)
+	err = controller.UpsertAnnotations([]Annotation{
+		{Source: AnnotationCloudflareStatus, ExternalID: "new", Title: "Logpush errors", Status: "resolved", StartedAt: start.Add(24 * time.Hour), EndedAt: &ended},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update annotation: %v", err)
+	}
+
+	got, err := controller.QueryAnnotations(start.Add(time.Hour), start.Add(25*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query annotations: %v", err)
+	}
+	if len(got) != 2 || got[0].ExternalID != "old" || got[1].Status != "resolved" || got[1].EndedAt == nil || !got[1].EndedAt.Equal(ended) {
+		t.Fatalf("Expected both annotations with the update applied, got %+v", got)
+	}
+
+	if got, err := controller.QueryAnnotations(start.Add(3*time.Hour), start.Add(4*time.Hour)); err != nil || len(got) != 0 {
+		t.Errorf("Expected nothing between the incidents, got %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Backup writes a consistent copy of the database to path with VACUUM INTO.
+// The copy is taken inside a read transaction, so ingestion can continue
+// while it is written.
+//
+// Parameters:
+//   - path: Destination file; it must not already exist
+//
+// Returns:
+//   - error: Any error encountered while writing the copy
+func (c *SQLiteController) Backup(path string) error {
+	if _, err := c.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		c.logger.Error("Failed to back up database", "error", err, "path", path)
+		return err
+	}
+	c.logger.Info("Database backed up", "path", path)
+	return nil
+}
+
+// Snapshot returns a consistent copy of the database file. The copy is
+// staged in a temporary directory that is removed before returning.
+//
+// Returns:
+//   - []byte: Contents of a standalone SQLite database file
+//   - error: Any error encountered while copying
+func (c *SQLiteController) Snapshot() ([]byte, error) {
+	dir, err := os.MkdirTemp("", "logpush-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "backup.db")
+	if err := c.Backup(path); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
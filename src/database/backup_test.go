@@ -0,0 +1,42 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestSnapshotIsStandaloneDatabase(t *testing.T) {
+	tempFile := "test_backup.db"
+	restoredFile := "test_backup_restored.db"
+	defer os.Remove(tempFile)
+	defer os.Remove(restoredFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	if err := controller.InsertLogSize(1234); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	data, err := controller.Snapshot()
+	if err != nil {
+		t.Fatalf("Failed to snapshot: %v", err)
+	}
+	if err := os.WriteFile(restoredFile, data, 0o600); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	restored, err := NewSQLiteController(restoredFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to open snapshot: %v", err)
+	}
+	defer restored.Close()
+	logs, err := restored.GetAll()
+	if err != nil || len(logs) != 1 || logs[0].Filesize != 1234 {
+		t.Errorf("Expected the snapshot to contain the inserted record, got %+v, %v", logs, err)
+	}
+}
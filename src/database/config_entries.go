@@ -0,0 +1,114 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// createConfigEntriesTable holds the DDL for declarative configuration
+// objects (pricing models, budgets, alert rules, tokens, retention). Each
+// object is stored as a JSON body keyed by kind and name.
+const createConfigEntriesTable = `CREATE TABLE IF NOT EXISTS config_entries (
+	kind TEXT NOT NULL,
+	name TEXT NOT NULL,
+	body TEXT NOT NULL,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (kind, name)
+);`
+
+// ConfigEntry is a single stored configuration object.
+type ConfigEntry struct {
+	Kind      string          // Object kind, e.g. "budget"
+	Name      string          // Object name, unique within its kind
+	Body      json.RawMessage // JSON encoding of the object
+	UpdatedAt time.Time       // When the body last changed
+}
+
+// ListConfigEntries returns every stored configuration object ordered by kind
+// and name.
+//
+// Returns:
+//   - []ConfigEntry: Stored configuration objects
+//   - error: Any error encountered during the query
+func (c *SQLiteController) ListConfigEntries() ([]ConfigEntry, error) {
+	rows, err := c.db.Query(`SELECT kind, name, body, updated_at FROM config_entries ORDER BY kind, name`)
+	if err != nil {
+		c.logger.Error("Failed to query config entries", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ConfigEntry
+	for rows.Next() {
+		var (
+			e    ConfigEntry
+			body string
+		)
+		if err := rows.Scan(&e.Kind, &e.Name, &body, &e.UpdatedAt); err != nil {
+			c.logger.Error("Failed to scan config entry row", "error", err)
+			return nil, err
+		}
+		e.Body = json.RawMessage(body)
+		e.UpdatedAt = e.UpdatedAt.UTC()
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// ReplaceConfigEntries makes the stored configuration match entries in a
+// single transaction. Objects that are not in entries are deleted, and only
+// objects whose body changed are rewritten, so UpdatedAt reflects real edits.
+//
+// Parameters:
+//   - entries: The complete desired set of configuration objects
+//
+// Returns:
+//   - error: Any error encountered; on error nothing is changed
+func (c *SQLiteController) ReplaceConfigEntries(entries []ConfigEntry) error {
+	current, err := c.ListConfigEntries()
+	if err != nil {
+		return err
+	}
+	type key struct{ kind, name string }
+	existing := make(map[key]json.RawMessage, len(current))
+	for _, e := range current {
+		existing[key{e.Kind, e.Name}] = e.Body
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		c.logger.Error("Failed to begin config transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	now := c.now().UTC()
+	for _, e := range entries {
+		k := key{e.Kind, e.Name}
+		body, found := existing[k]
+		delete(existing, k)
+		if found && bytes.Equal(body, e.Body) {
+			continue
+		}
+		_, err := tx.Exec(`INSERT INTO config_entries (kind, name, body, updated_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(kind, name) DO UPDATE SET body = excluded.body, updated_at = excluded.updated_at`,
+			e.Kind, e.Name, string(e.Body), now)
+		if err != nil {
+			c.logger.Error("Failed to write config entry", "error", err, "kind", e.Kind, "name", e.Name)
+			return err
+		}
+	}
+	for k := range existing {
+		if _, err := tx.Exec(`DELETE FROM config_entries WHERE kind = ? AND name = ?`, k.kind, k.name); err != nil {
+			c.logger.Error("Failed to delete config entry", "error", err, "kind", k.kind, "name", k.name)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.logger.Error("Failed to commit config entries", "error", err)
+		return err
+	}
+	c.logger.Info("Configuration replaced", "entries", len(entries))
+	return nil
+}
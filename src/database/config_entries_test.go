@@ -0,0 +1,55 @@
+package database
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestReplaceConfigEntries(t *testing.T) {
+	tempFile := "test_config_entries.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	first := []ConfigEntry{
+		{Kind: "budget", Name: "monthly", Body: json.RawMessage(`{"limit_bytes":1}`)},
+		{Kind: "budget", Name: "daily", Body: json.RawMessage(`{"limit_bytes":2}`)},
+	}
+	if err := controller.ReplaceConfigEntries(first); err != nil {
+		t.Fatalf("Failed to replace config entries: %v", err)
+	}
+	entries, err := controller.ListConfigEntries()
+	if err != nil {
+		t.Fatalf("Failed to list config entries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name != "daily" {
+		t.Fatalf("Expected 2 entries ordered by name, got %+v", entries)
+	}
+	unchangedAt := entries[1].UpdatedAt
+
+	// Drop "daily", keep "monthly" unchanged and add a new kind
+	second := []ConfigEntry{
+		{Kind: "budget", Name: "monthly", Body: json.RawMessage(`{"limit_bytes":1}`)},
+		{Kind: "retention", Name: "default", Body: json.RawMessage(`{"raw_days":30}`)},
+	}
+	if err := controller.ReplaceConfigEntries(second); err != nil {
+		t.Fatalf("Failed to replace config entries: %v", err)
+	}
+	entries, err = controller.ListConfigEntries()
+	if err != nil {
+		t.Fatalf("Failed to list config entries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name != "monthly" || entries[1].Kind != "retention" {
+		t.Fatalf("Unexpected entries after replace: %+v", entries)
+	}
+	if !entries[0].UpdatedAt.Equal(unchangedAt) {
+		t.Errorf("Expected unchanged entry to keep UpdatedAt %v, got %v", unchangedAt, entries[0].UpdatedAt)
+	}
+}
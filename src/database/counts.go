@@ -0,0 +1,36 @@
+package database
+
+import "time"
+
+// LogCount is the number and size of the log records in a range, without
+// the records themselves.
+type LogCount struct {
+	Batches int64 `json:"batches"` // Number of stored log batches
+	Records int64 `json:"records"` // Sum of log lines across the batches
+	Bytes   int64 `json:"bytes"`   // Sum of batch sizes in bytes
+}
+
+// CountByTimeRange counts the log records with timestamps in [start, end)
+// using aggregate SQL, so no rows are transferred. A zero start or end
+// leaves that side of the range open.
+//
+// Parameters:
+//   - start: Start time (inclusive), or zero for no lower bound
+//   - end: End time (exclusive), or zero for no upper bound
+//
+// Returns:
+//   - LogCount: Batches, records and bytes in the range
+//   - error: Any error encountered during the query
+func (c *SQLiteController) CountByTimeRange(start, end time.Time) (LogCount, error) {
+	if err := c.checkAttached(start); err != nil {
+		return LogCount{}, err
+	}
+	c.logger.Info("Counting log sizes by time range", "start", start, "end", end)
+	where, args := c.rangeFilter(start, end)
+	var count LogCount
+	if err := c.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(record_count), 0), COALESCE(SUM(filesize), 0) FROM log_sizes`+where, args...).Scan(&count.Batches, &count.Records, &count.Bytes); err != nil {
+		c.logger.Error("Failed to count log sizes", "error", err)
+		return LogCount{}, err
+	}
+	return count, nil
+}
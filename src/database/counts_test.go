@@ -0,0 +1,62 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCountByTimeRange(t *testing.T) {
+	tempFile := "test_counts.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now().UTC()
+	old := LogSize{Timestamp: now.Add(-48 * time.Hour), Filesize: 1000, RecordCount: 10}
+	recent := LogSize{Timestamp: now.Add(-time.Hour), Filesize: 200, RecordCount: 2}
+	if err := db.InsertLog(old); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+	if err := db.InsertLog(recent); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+	if err := db.ForTenant("acme").InsertLog(recent); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+
+	all, err := db.CountByTimeRange(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	if all != (LogCount{Batches: 3, Records: 14, Bytes: 1400}) {
+		t.Errorf("Unexpected totals for the whole history: %+v", all)
+	}
+
+	day, err := db.CountByTimeRange(now.Add(-24*time.Hour), now)
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	if day != (LogCount{Batches: 2, Records: 4, Bytes: 400}) {
+		t.Errorf("Unexpected totals for the last day: %+v", day)
+	}
+
+	tenant, err := db.ForTenant("acme").CountByTimeRange(time.Time{}, now)
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	if tenant != (LogCount{Batches: 1, Records: 2, Bytes: 200}) {
+		t.Errorf("Unexpected tenant totals: %+v", tenant)
+	}
+
+	empty, err := db.CountByTimeRange(now.Add(time.Hour), now.Add(2*time.Hour))
+	if err != nil || empty != (LogCount{}) {
+		t.Errorf("Expected zero totals for an empty range, got %+v, %v", empty, err)
+	}
+}
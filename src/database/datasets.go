@@ -0,0 +1,56 @@
+package database
+
+import "time"
+
+// DatasetUsage summarizes the log records stored for one Logpush dataset.
+type DatasetUsage struct {
+	Dataset     string `json:"dataset"`      // Dataset name; empty for batches ingested without one
+	Records     int64  `json:"records"`      // Number of stored log batches
+	TotalSize   int64  `json:"total_size"`   // Sum of batch sizes in bytes
+	RecordCount int64  `json:"record_count"` // Sum of log lines across the batches
+}
+
+// QueryDatasetUsage returns the usage of every dataset with records in
+// [start, end), largest first. A zero start or end leaves that side of the
+// range open. On a tenant-scoped controller only the tenant's records are
+// counted.
+//
+// Parameters:
+//   - start: Start time (inclusive), or zero for no lower bound
+//   - end: End time (exclusive), or zero for no upper bound
+//
+// Returns:
+//   - []DatasetUsage: Usage per dataset within the range
+//   - error: Any error encountered during the query
+func (c *SQLiteController) QueryDatasetUsage(start, end time.Time) ([]DatasetUsage, error) {
+	if err := c.checkAttached(start); err != nil {
+		return nil, err
+	}
+	query := `SELECT dataset, COUNT(*), COALESCE(SUM(filesize), 0), COALESCE(SUM(record_count), 0) FROM log_sizes WHERE 1 = 1`
+	var args []any
+	if !start.IsZero() {
+		query += ` AND timestamp >= ?`
+		args = append(args, start.UTC())
+	}
+	if !end.IsZero() {
+		query += ` AND timestamp < ?`
+		args = append(args, end.UTC())
+	}
+	filter, filterArgs := c.tenantFilter()
+	rows, err := c.db.Query(query+filter+` GROUP BY dataset ORDER BY SUM(filesize) DESC, dataset`, append(args, filterArgs...)...)
+	if err != nil {
+		c.logger.Error("Failed to query dataset usage", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []DatasetUsage{}
+	for rows.Next() {
+		var u DatasetUsage
+		if err := rows.Scan(&u.Dataset, &u.Records, &u.TotalSize, &u.RecordCount); err != nil {
+			c.logger.Error("Failed to scan dataset usage row", "error", err)
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestForDatasetScopesLogRecords(t *testing.T) {
+	tempFile := "test_datasets.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	at := time.Date(2025, 9, 15, 10, 0, 30, 0, time.UTC)
+	for _, l := range []LogSize{
+		{Timestamp: at, Filesize: 100, RecordCount: 2, Dataset: "http_requests"},
+		{Timestamp: at.Add(10 * time.Second), Filesize: 50, RecordCount: 1, Dataset: "http_requests", Tenant: "acme"},
+		{Timestamp: at, Filesize: 300, RecordCount: 3, Dataset: "firewall_events"},
+		{Timestamp: at, Filesize: 7},
+	} {
+		if err := controller.InsertLog(l); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+
+	http := controller.ForDataset("http_requests")
+	check := func(name string, c *SQLiteController, batches int, bytes int64) {
+		t.Helper()
+		logs, err := c.QueryByTimeRange(at.Add(-time.Minute), at.Add(time.Minute))
+		if err != nil {
+			t.Fatalf("%s: failed to query range: %v", name, err)
+		}
+		var sum int64
+		for _, l := range logs {
+			sum += l.Filesize
+		}
+		if len(logs) != batches || sum != bytes {
+			t.Errorf("%s: expected %d records of %d bytes, got %+v", name, batches, bytes, logs)
+		}
+		count, err := c.CountByTimeRange(time.Time{}, time.Time{})
+		if err != nil || count.Batches != int64(batches) || count.Bytes != bytes {
+			t.Errorf("%s: expected the count to match, got %+v, %v", name, count, err)
+		}
+		minutes, err := c.QueryMinuteAggregates(at.Add(-time.Minute), at.Add(time.Minute))
+		if err != nil || len(minutes) != 1 || minutes[0].TotalSize != bytes {
+			t.Errorf("%s: expected one minute of %d bytes, got %+v, %v", name, bytes, minutes, err)
+		}
+	}
+	check("http_requests", http, 2, 150)
+	check("acme http_requests", controller.ForTenant("acme").ForDataset("http_requests"), 1, 50)
+
+	// The SQL path filters like the recent buffer
+	controller.SetRecentBufferSize(0)
+	check("http_requests from SQL", http, 2, 150)
+	since, err := controller.ForDataset("firewall_events").QuerySince(0, 10)
+	if err != nil || len(since) != 1 || since[0].Dataset != "firewall_events" {
+		t.Errorf("Expected the firewall_events record only, got %+v, %v", since, err)
+	}
+
+	usage, err := controller.QueryDatasetUsage(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to query dataset usage: %v", err)
+	}
+	want := []DatasetUsage{
+		{Dataset: "firewall_events", Records: 1, TotalSize: 300, RecordCount: 3},
+		{Dataset: "http_requests", Records: 2, TotalSize: 150, RecordCount: 3},
+		{Dataset: "", Records: 1, TotalSize: 7},
+	}
+	if len(usage) != len(want) {
+		t.Fatalf("Expected %d datasets, got %+v", len(want), usage)
+	}
+	for i := range want {
+		if usage[i] != want[i] {
+			t.Errorf("Dataset %d: expected %+v, got %+v", i, want[i], usage[i])
+		}
+	}
+	acme, err := controller.ForTenant("acme").QueryDatasetUsage(time.Time{}, time.Time{})
+	if err != nil || len(acme) != 1 || acme[0].TotalSize != 50 {
+		t.Errorf("Expected acme's http_requests usage only, got %+v, %v", acme, err)
+	}
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// summarizeRangeQuery counts rows and bytes in a [start, end) timestamp range.
+const summarizeRangeQuery = `SELECT COUNT(*), COALESCE(SUM(filesize), 0) FROM log_sizes WHERE timestamp >= ? AND timestamp < ?`
+
+// DeletionSummary describes the log records affected by a delete, either
+// planned (dry run) or performed.
+type DeletionSummary struct {
+	Rows    int64 `json:"rows"`               // Number of log_sizes rows
+	Bytes   int64 `json:"bytes"`              // Sum of their filesize values
+	TrashID int64 `json:"trash_id,omitempty"` // Trash batch holding the deleted rows, if any
+}
+
+// SummarizeTimeRange counts the log records in [start, end) without changing
+// anything. It reports exactly what DeleteByTimeRange would remove, so on a
+// scoped controller only the records in scope are counted.
+//
+// Parameters:
+//   - start: Start time (inclusive)
+//   - end: End time (exclusive)
+//
+// Returns:
+//   - DeletionSummary: Row count and byte total in the range
+//   - error: Any error encountered during the query
+func (c *SQLiteController) SummarizeTimeRange(start, end time.Time) (DeletionSummary, error) {
+	if err := c.checkAttached(start); err != nil {
+		return DeletionSummary{}, err
+	}
+	var s DeletionSummary
+	filter, args := c.tenantFilter()
+	err := c.db.QueryRow(summarizeRangeQuery+filter, append([]any{start.UTC(), end.UTC()}, args...)...).Scan(&s.Rows, &s.Bytes)
+	if err != nil {
+		c.logger.Error("Failed to summarize time range", "error", err, "start", start, "end", end)
+	}
+	return s, err
+}
+
+// DeleteByTimeRange deletes the log records in [start, end) together with the
+// minute aggregates whose minute starts inside the range. The records are
+// moved into a trash batch first, so the delete can be undone with
+// RestoreTrash until the batch is purged. On a scoped controller only the
+// records in scope are deleted, and their share is subtracted from the
+// minute aggregates, which cover every tenant and dataset.
+//
+// Parameters:
+//   - start: Start time (inclusive)
+//   - end: End time (exclusive)
+//
+// Returns:
+//   - DeletionSummary: Rows and bytes removed from log_sizes
+//   - error: Any error encountered; on error nothing is deleted
+func (c *SQLiteController) DeleteByTimeRange(start, end time.Time) (DeletionSummary, error) {
+	if err := c.checkAttached(start); err != nil {
+		return DeletionSummary{}, err
+	}
+	c.logger.Info("Deleting log sizes by time range", "start", start, "end", end)
+	start, end = start.UTC(), end.UTC()
+	tx, err := c.db.Begin()
+	if err != nil {
+		c.logger.Error("Failed to begin delete transaction", "error", err)
+		return DeletionSummary{}, err
+	}
+	defer tx.Rollback()
+
+	filter, filterArgs := c.tenantFilter()
+	args := append([]any{start, end}, filterArgs...)
+	var s DeletionSummary
+	err = tx.QueryRow(summarizeRangeQuery+filter, args...).Scan(&s.Rows, &s.Bytes)
+	if err != nil {
+		c.logger.Error("Failed to summarize time range", "error", err)
+		return DeletionSummary{}, err
+	}
+	if s.Rows > 0 {
+		if s.TrashID, err = moveRangeToTrash(tx, filter, args, c.now(), s); err != nil {
+			c.logger.Error("Failed to move log sizes to trash", "error", err)
+			return DeletionSummary{}, err
+		}
+	}
+	if c.scoped() {
+		err = subtractMinuteAggregates(tx, filter, args)
+	} else {
+		_, err = tx.Exec(`DELETE FROM minute_aggregates WHERE minute >= ? AND minute < ?`, start, end)
+	}
+	if err != nil {
+		c.logger.Error("Failed to delete minute aggregates", "error", err)
+		return DeletionSummary{}, err
+	}
+	for _, table := range c.logSizeTables() {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE timestamp >= ? AND timestamp < ?`+filter, args...); err != nil {
+			c.logger.Error("Failed to delete log sizes", "error", err, "table", table)
+			return DeletionSummary{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.logger.Error("Failed to commit delete", "error", err)
+		return DeletionSummary{}, err
+	}
+	c.recent.invalidate()
+	c.logger.Info("Log sizes deleted", "rows", s.Rows, "bytes", s.Bytes, "trash_id", s.TrashID)
+	return s, nil
+}
+
+// DeleteOlderThan deletes every log record timestamped before cutoff, as
+// DeleteByTimeRange does from the Unix epoch, so expired records go to the
+// trash and stay restorable until it is purged. Retention pruning uses it.
+//
+// Parameters:
+//   - cutoff: Records before this time are deleted
+//
+// Returns:
+//   - DeletionSummary: Rows and bytes removed from log_sizes
+//   - error: Any error encountered; on error nothing is deleted
+func (c *SQLiteController) DeleteOlderThan(cutoff time.Time) (DeletionSummary, error) {
+	return c.DeleteByTimeRange(time.Unix(0, 0), cutoff)
+}
+
+// subtractMinuteAggregates takes the log records matching the range and
+// filter out of the minute aggregates within tx, removing buckets left
+// without batches. RestoreTrash adds them back.
+func subtractMinuteAggregates(tx *sql.Tx, filter string, args []any) error {
+	rows, err := tx.Query(`SELECT timestamp, filesize, record_count FROM log_sizes WHERE timestamp >= ? AND timestamp < ?`+filter, args...)
+	if err != nil {
+		return err
+	}
+	type removed struct {
+		minute            time.Time
+		filesize, records int64
+	}
+	var batches []removed
+	for rows.Next() {
+		var r removed
+		if err := rows.Scan(&r.minute, &r.filesize, &r.records); err != nil {
+			rows.Close()
+			return err
+		}
+		r.minute = r.minute.UTC().Truncate(time.Minute)
+		batches = append(batches, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, r := range batches {
+		if _, err := tx.Exec(`UPDATE minute_aggregates SET batches = batches - 1, records = records - ?, total_size = total_size - ? WHERE minute = ?`,
+			r.records, r.filesize, r.minute); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`DELETE FROM minute_aggregates WHERE batches <= 0`)
+	return err
+}
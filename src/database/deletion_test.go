@@ -0,0 +1,139 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDeleteByTimeRange(t *testing.T) {
+	tempFile := "test_deletion.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, size := range []int64{100, 200, 300} {
+		if err := controller.InsertLog(LogSize{Timestamp: base.Add(time.Duration(i) * time.Hour), Filesize: size}); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+	start, end := base, base.Add(2*time.Hour)
+
+	planned, err := controller.SummarizeTimeRange(start, end)
+	if err != nil {
+		t.Fatalf("Failed to summarize range: %v", err)
+	}
+	if planned.Rows != 2 || planned.Bytes != 300 {
+		t.Errorf("Expected 2 rows / 300 bytes, got %+v", planned)
+	}
+
+	// Summarizing must not delete anything
+	if logs, _ := controller.GetAll(); len(logs) != 3 {
+		t.Fatalf("Expected 3 rows after summary, got %d", len(logs))
+	}
+
+	deleted, err := controller.DeleteByTimeRange(start, end)
+	if err != nil {
+		t.Fatalf("Failed to delete range: %v", err)
+	}
+	if deleted.Rows != planned.Rows || deleted.Bytes != planned.Bytes || deleted.TrashID == 0 {
+		t.Errorf("Expected delete to match summary %+v and fill the trash, got %+v", planned, deleted)
+	}
+	logs, _ := controller.GetAll()
+	if len(logs) != 1 || logs[0].Filesize != 300 {
+		t.Errorf("Expected only the 300 byte record to remain, got %+v", logs)
+	}
+	aggregates, _ := controller.QueryMinuteAggregates(base.Add(-time.Hour), base.Add(3*time.Hour))
+	if len(aggregates) != 1 {
+		t.Errorf("Expected minute aggregates in the range to be deleted, got %+v", aggregates)
+	}
+}
+
+func TestDeleteOlderThan(t *testing.T) {
+	tempFile := "test_delete_older_than.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, size := range []int64{100, 200, 300} {
+		if err := controller.InsertLog(LogSize{Timestamp: base.AddDate(0, 0, 30*i), Filesize: size}); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+
+	deleted, err := controller.DeleteOlderThan(base.AddDate(0, 0, 30))
+	if err != nil {
+		t.Fatalf("DeleteOlderThan failed: %v", err)
+	}
+	if deleted.Rows != 1 || deleted.Bytes != 100 || deleted.TrashID == 0 {
+		t.Errorf("Expected the oldest record moved to the trash, got %+v", deleted)
+	}
+	if logs, _ := controller.GetAll(); len(logs) != 2 {
+		t.Errorf("Expected 2 records left, got %d", len(logs))
+	}
+	if deleted, _ := controller.DeleteOlderThan(base); deleted.Rows != 0 || deleted.TrashID != 0 {
+		t.Errorf("Expected nothing to delete before the first record, got %+v", deleted)
+	}
+}
+
+func TestDeleteByTimeRangeScoped(t *testing.T) {
+	tempFile := "test_deletion_scoped.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, l := range []LogSize{
+		{Timestamp: base, Filesize: 100, RecordCount: 1, Dataset: "http_requests"},
+		{Timestamp: base, Filesize: 200, RecordCount: 2, Dataset: "firewall_events"},
+		{Timestamp: base.Add(time.Hour), Filesize: 300, RecordCount: 3, Dataset: "http_requests"},
+	} {
+		if err := controller.InsertLog(l); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+	start, end := base, base.Add(2*time.Hour)
+	http := controller.ForDataset("http_requests")
+
+	if planned, err := http.SummarizeTimeRange(start, end); err != nil || planned.Rows != 2 || planned.Bytes != 400 {
+		t.Errorf("Expected the two http_requests records planned, got %+v (%v)", planned, err)
+	}
+	deleted, err := http.DeleteByTimeRange(start, end)
+	if err != nil || deleted.Rows != 2 || deleted.Bytes != 400 || deleted.TrashID == 0 {
+		t.Fatalf("Expected the two http_requests records deleted, got %+v (%v)", deleted, err)
+	}
+	if logs, _ := controller.GetAll(); len(logs) != 1 || logs[0].Dataset != "firewall_events" {
+		t.Errorf("Expected only the firewall_events record to remain, got %+v", logs)
+	}
+
+	// Only the deleted records' share leaves the minute aggregates
+	aggregates, _ := controller.QueryMinuteAggregates(base.Add(-time.Hour), base.Add(3*time.Hour))
+	if len(aggregates) != 1 || aggregates[0].Batches != 1 || aggregates[0].TotalSize != 200 || aggregates[0].Records != 2 {
+		t.Errorf("Expected the firewall_events minute only, got %+v", aggregates)
+	}
+
+	if _, ok, err := controller.RestoreTrash(deleted.TrashID); !ok || err != nil {
+		t.Fatalf("Failed to restore the purge: %v", err)
+	}
+	if logs, _ := controller.GetAll(); len(logs) != 3 {
+		t.Errorf("Expected every record back after the restore, got %d", len(logs))
+	}
+}
@@ -0,0 +1,369 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// createDimensionRollupsTable holds the DDL for hourly record counts and bytes
+// per dimension value, filled by the optional dataset parsers at ingest.
+const createDimensionRollupsTable = `CREATE TABLE IF NOT EXISTS dimension_rollups (
+	hour DATETIME NOT NULL,
+	tenant TEXT NOT NULL DEFAULT '',
+	dataset TEXT NOT NULL,
+	zone TEXT NOT NULL DEFAULT '',
+	labels TEXT NOT NULL DEFAULT '',
+	dimension TEXT NOT NULL,
+	value TEXT NOT NULL,
+	records INTEGER NOT NULL,
+	bytes INTEGER NOT NULL,
+	PRIMARY KEY (hour, tenant, dataset, zone, labels, dimension, value)
+);
+CREATE INDEX IF NOT EXISTS idx_dimension_rollups_dimension ON dimension_rollups(dimension, hour);`
+
+// migrateDimensionRollups rebuilds a dimension_rollups table created before
+// the rollups were scoped by tenant, zone and labels. The columns are part of
+// the primary key, so they cannot be added in place; existing counts are
+// copied under the default (empty) scope. Tables that already have the
+// columns are left untouched, which makes the function safe to run on every
+// startup.
+//
+// Parameters:
+//   - db: Database whose dimension_rollups table has already been created
+//
+// Returns:
+//   - error: Any error encountered while inspecting or rebuilding the table
+func migrateDimensionRollups(db *sql.DB) error {
+	var scoped int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('dimension_rollups') WHERE name = 'zone'`).Scan(&scoped); err != nil {
+		return err
+	}
+	if scoped > 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range []string{
+		`ALTER TABLE dimension_rollups RENAME TO dimension_rollups_unscoped`,
+		`DROP INDEX IF EXISTS idx_dimension_rollups_dimension`,
+		createDimensionRollupsTable,
+		`INSERT INTO dimension_rollups (hour, dataset, dimension, value, records, bytes)
+			SELECT hour, dataset, dimension, value, records, bytes FROM dimension_rollups_unscoped`,
+		`DROP TABLE dimension_rollups_unscoped`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("rebuild dimension_rollups: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DimensionCount is the number and size of records sharing one dimension
+// value, either within a batch (when recording) or over a window (when
+// querying).
+type DimensionCount struct {
+	Dataset   string `json:"dataset"`   // Dataset the records belong to
+	Dimension string `json:"dimension"` // Dimension name, e.g. "status_class"
+	Value     string `json:"value"`     // Dimension value, e.g. "5xx"
+	Records   int64  `json:"records"`   // Number of records
+	Bytes     int64  `json:"bytes"`     // Total size of the records in bytes
+}
+
+// AddDimensionCounts adds a batch's dimension counts to the rollup for the
+// hour containing at. Unlike log records, which carry their own zone and
+// labels, the counts are stored under c's tenant, zone and labels, so
+// record the batch through a controller scoped to them.
+//
+// Parameters:
+//   - at: When the batch was received
+//   - counts: Per-value counts for the batch
+//
+// Returns:
+//   - error: Any error encountered; on error nothing is added
+func (c *SQLiteController) AddDimensionCounts(at time.Time, counts []DimensionCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	tx, err := c.db.Begin()
+	if err != nil {
+		c.logger.Error("Failed to begin dimension transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	hour := at.UTC().Truncate(time.Hour)
+	labels := encodeLabels(c.labels)
+	for _, d := range counts {
+		_, err := tx.Exec(`INSERT INTO dimension_rollups (hour, tenant, dataset, zone, labels, dimension, value, records, bytes) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(hour, tenant, dataset, zone, labels, dimension, value) DO UPDATE SET records = records + excluded.records, bytes = bytes + excluded.bytes`,
+			hour, c.tenant, d.Dataset, c.zone, labels, d.Dimension, d.Value, d.Records, d.Bytes)
+		if err != nil {
+			c.logger.Error("Failed to add dimension counts", "error", err, "dimension", d.Dimension)
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.logger.Error("Failed to commit dimension counts", "error", err)
+		return err
+	}
+	return nil
+}
+
+// QueryDimension totals one dimension's values over hours in [start, end),
+// largest byte volume first. On a scoped controller only the counts in
+// scope are totalled.
+//
+// Parameters:
+//   - dimension: Dimension name
+//   - start: Start time (inclusive, truncated to the hour)
+//   - end: End time (exclusive)
+//
+// Returns:
+//   - []DimensionCount: Totals per dataset and value
+//   - error: Any error encountered during the query
+func (c *SQLiteController) QueryDimension(dimension string, start, end time.Time) ([]DimensionCount, error) {
+	filter, args := c.tenantFilter()
+	rows, err := c.db.Query(`SELECT dataset, value, SUM(records), SUM(bytes) FROM dimension_rollups
+		WHERE dimension = ? AND hour >= ? AND hour < ?`+filter+`
+		GROUP BY dataset, value ORDER BY SUM(bytes) DESC, dataset, value`,
+		append([]any{dimension, start.UTC().Truncate(time.Hour), end.UTC()}, args...)...)
+	if err != nil {
+		c.logger.Error("Failed to query dimension rollups", "error", err, "dimension", dimension)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []DimensionCount{}
+	for rows.Next() {
+		d := DimensionCount{Dimension: dimension}
+		if err := rows.Scan(&d.Dataset, &d.Value, &d.Records, &d.Bytes); err != nil {
+			c.logger.Error("Failed to scan dimension row", "error", err)
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// QueryDimensionTotals totals every dimension's values over hours in
+// [start, end), largest byte volume first. On a scoped controller only the
+// counts in scope are totalled.
+//
+// Parameters:
+//   - start: Start time (inclusive, truncated to the hour)
+//   - end: End time (exclusive)
+//
+// Returns:
+//   - []DimensionCount: Totals per dataset, dimension and value
+//   - error: Any error encountered during the query
+func (c *SQLiteController) QueryDimensionTotals(start, end time.Time) ([]DimensionCount, error) {
+	filter, args := c.tenantFilter()
+	rows, err := c.db.Query(`SELECT dataset, dimension, value, SUM(records), SUM(bytes) FROM dimension_rollups
+		WHERE hour >= ? AND hour < ?`+filter+`
+		GROUP BY dataset, dimension, value ORDER BY SUM(bytes) DESC, dataset, dimension, value`,
+		append([]any{start.UTC().Truncate(time.Hour), end.UTC()}, args...)...)
+	if err != nil {
+		c.logger.Error("Failed to query dimension rollups", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []DimensionCount{}
+	for rows.Next() {
+		var d DimensionCount
+		if err := rows.Scan(&d.Dataset, &d.Dimension, &d.Value, &d.Records, &d.Bytes); err != nil {
+			c.logger.Error("Failed to scan dimension row", "error", err)
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// DimensionHour is one hour's total for one value of a dimension.
+type DimensionHour struct {
+	Hour    time.Time // Start of the hour, UTC
+	Value   string    // Dimension value, e.g. "5xx"
+	Records int64     // Number of records
+	Bytes   int64     // Total size of the records in bytes
+}
+
+// QueryDimensionHours returns one dimension's hourly totals per value over
+// hours in [start, end), oldest first, summed across datasets unless one is
+// given. Hours without records of a value are omitted.
+//
+// Parameters:
+//   - dimension: Dimension name
+//   - dataset: Dataset to read; empty for all
+//   - start: Start time (inclusive, truncated to the hour)
+//   - end: End time (exclusive)
+//
+// Returns:
+//   - []DimensionHour: Totals per hour and value
+//   - error: Any error encountered during the query
+func (c *SQLiteController) QueryDimensionHours(dimension, dataset string, start, end time.Time) ([]DimensionHour, error) {
+	query := `SELECT hour, value, SUM(records), SUM(bytes) FROM dimension_rollups WHERE dimension = ? AND hour >= ? AND hour < ?`
+	args := []any{dimension, start.UTC().Truncate(time.Hour), end.UTC()}
+	if dataset != "" {
+		query += ` AND dataset = ?`
+		args = append(args, dataset)
+	}
+	filter, filterArgs := c.tenantFilter()
+	rows, err := c.db.Query(query+filter+` GROUP BY hour, value ORDER BY hour, value`, append(args, filterArgs...)...)
+	if err != nil {
+		c.logger.Error("Failed to query dimension hours", "error", err, "dimension", dimension)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []DimensionHour{}
+	for rows.Next() {
+		var d DimensionHour
+		if err := rows.Scan(&d.Hour, &d.Value, &d.Records, &d.Bytes); err != nil {
+			c.logger.Error("Failed to scan dimension hour row", "error", err)
+			return nil, err
+		}
+		d.Hour = d.Hour.UTC()
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// Group-by keys accepted by QueryDimensionGroups, with the rollup expression
+// each one selects. Time keys are formatted as RFC 3339 bucket starts.
+const (
+	DimensionGroupDataset = "dataset"
+	DimensionGroupValue   = "value"
+	DimensionGroupHour    = "hour"
+	DimensionGroupDay     = "day"
+)
+
+var dimensionGroupColumns = map[string]string{
+	DimensionGroupDataset: "dataset",
+	DimensionGroupValue:   "value",
+	DimensionGroupHour:    "strftime('%Y-%m-%dT%H:00:00Z', hour)",
+	DimensionGroupDay:     "strftime('%Y-%m-%dT00:00:00Z', hour)",
+}
+
+// DimensionQuery selects and groups rollup rows of one dimension.
+type DimensionQuery struct {
+	Dimension string    // Dimension name
+	Datasets  []string  // Datasets to read; empty for all
+	Values    []string  // Values to read; empty for all
+	Start     time.Time // Start time (inclusive, truncated to the hour)
+	End       time.Time // End time (exclusive)
+	GroupBy   []string  // Keys to group by, in output order; at most one of hour and day
+	Limit     int       // Maximum number of groups; 0 for no limit
+}
+
+// DimensionGroup is the total of one group of a DimensionQuery. Keys that
+// were not grouped by are empty.
+type DimensionGroup struct {
+	Dataset string `json:"dataset,omitempty"` // Dataset, when grouped by dataset
+	Value   string `json:"value,omitempty"`   // Dimension value, when grouped by value
+	Bucket  string `json:"bucket,omitempty"`  // Start of the hour or day, RFC 3339 UTC, when grouped by time
+	Records int64  `json:"records"`           // Number of records
+	Bytes   int64  `json:"bytes"`             // Total size of the records in bytes
+}
+
+// ValidDimensionGroupBy reports whether keys can be passed as
+// DimensionQuery.GroupBy: known keys, none repeated, and at most one of
+// hour and day.
+func ValidDimensionGroupBy(keys []string) bool {
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if _, ok := dimensionGroupColumns[k]; !ok || seen[k] {
+			return false
+		}
+		seen[k] = true
+	}
+	return !(seen[DimensionGroupHour] && seen[DimensionGroupDay])
+}
+
+// QueryDimensionGroups totals one dimension's rollups over hours in
+// [q.Start, q.End), filtered by dataset and value and grouped by any of
+// dataset, value and hour or day. Groups are ordered by time bucket, then
+// largest byte volume first. Without group keys a single total is returned.
+//
+// Parameters:
+//   - q: Dimension, filters, window and grouping; GroupBy must satisfy
+//     ValidDimensionGroupBy
+//
+// Returns:
+//   - []DimensionGroup: Totals per group
+//   - error: Any error encountered during the query
+func (c *SQLiteController) QueryDimensionGroups(q DimensionQuery) ([]DimensionGroup, error) {
+	if !ValidDimensionGroupBy(q.GroupBy) {
+		return nil, fmt.Errorf("invalid dimension group-by %q", q.GroupBy)
+	}
+	where := []string{"dimension = ?", "hour >= ?", "hour < ?"}
+	args := []any{q.Dimension, q.Start.UTC().Truncate(time.Hour), q.End.UTC()}
+	for _, filter := range []struct {
+		column string
+		values []string
+	}{{"dataset", q.Datasets}, {"value", q.Values}} {
+		if len(filter.values) == 0 {
+			continue
+		}
+		where = append(where, filter.column+" IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(filter.values)), ", ")+")")
+		for _, v := range filter.values {
+			args = append(args, v)
+		}
+	}
+
+	// Each group key selects its expression; keys not grouped by select ''
+	grouped := make(map[string]string, len(q.GroupBy))
+	var groupBy, orderBy []string
+	for _, k := range q.GroupBy {
+		grouped[k] = dimensionGroupColumns[k]
+		groupBy = append(groupBy, dimensionGroupColumns[k])
+		if k == DimensionGroupHour || k == DimensionGroupDay {
+			orderBy = append(orderBy, dimensionGroupColumns[k])
+		}
+	}
+	orderBy = append(orderBy, "SUM(bytes) DESC")
+	selects := make([]string, 0, 3)
+	for _, k := range []string{DimensionGroupDataset, DimensionGroupValue, DimensionGroupHour} {
+		expr, ok := grouped[k]
+		if k == DimensionGroupHour && !ok {
+			expr, ok = grouped[DimensionGroupDay]
+		}
+		if !ok {
+			expr = "''"
+		} else if k != DimensionGroupHour {
+			orderBy = append(orderBy, expr)
+		}
+		selects = append(selects, expr)
+	}
+
+	filter, filterArgs := c.tenantFilter()
+	args = append(args, filterArgs...)
+	query := `SELECT ` + strings.Join(selects, ", ") + `, COALESCE(SUM(records), 0), COALESCE(SUM(bytes), 0) FROM dimension_rollups WHERE ` + strings.Join(where, " AND ") + filter
+	if len(groupBy) > 0 {
+		query += ` GROUP BY ` + strings.Join(groupBy, ", ")
+	}
+	query += ` ORDER BY ` + strings.Join(orderBy, ", ")
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		c.logger.Error("Failed to query dimension groups", "error", err, "dimension", q.Dimension)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []DimensionGroup{}
+	for rows.Next() {
+		var g DimensionGroup
+		if err := rows.Scan(&g.Dataset, &g.Value, &g.Bucket, &g.Records, &g.Bytes); err != nil {
+			c.logger.Error("Failed to scan dimension group row", "error", err)
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,208 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAddDimensionCounts(t *testing.T) {
+	tempFile := "test_dimensions.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	hour := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	batch := []DimensionCount{
+		{Dataset: "http_requests", Dimension: "status_class", Value: "2xx", Records: 10, Bytes: 1000},
+		{Dataset: "http_requests", Dimension: "status_class", Value: "5xx", Records: 5, Bytes: 2000},
+	}
+	// Two batches in the same hour accumulate; the third falls in the next hour
+	for _, at := range []time.Time{hour.Add(5 * time.Minute), hour.Add(50 * time.Minute), hour.Add(70 * time.Minute)} {
+		if err := controller.AddDimensionCounts(at, batch); err != nil {
+			t.Fatalf("Failed to add dimension counts: %v", err)
+		}
+	}
+
+	values, err := controller.QueryDimension("status_class", hour, hour.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query dimension: %v", err)
+	}
+	if len(values) != 2 {
+		t.Fatalf("Expected 2 values, got %+v", values)
+	}
+	if values[0].Value != "5xx" || values[0].Records != 10 || values[0].Bytes != 4000 {
+		t.Errorf("Expected 5xx first with two batches summed, got %+v", values[0])
+	}
+
+	values, _ = controller.QueryDimension("action", hour, hour.Add(2*time.Hour))
+	if len(values) != 0 {
+		t.Errorf("Expected no values for an unrecorded dimension, got %+v", values)
+	}
+
+	controller.AddDimensionCounts(hour, []DimensionCount{{Dataset: "firewall_events", Dimension: "action", Value: "block", Records: 1, Bytes: 100}})
+	totals, err := controller.QueryDimensionTotals(hour, hour.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query dimension totals: %v", err)
+	}
+	if len(totals) != 3 || totals[0].Dimension != "status_class" || totals[0].Bytes != 6000 || totals[2].Dimension != "action" {
+		t.Errorf("Expected every dimension totalled, largest first, got %+v", totals)
+	}
+
+	controller.AddDimensionCounts(hour, []DimensionCount{{Dataset: "other", Dimension: "status_class", Value: "2xx", Records: 1, Bytes: 10}})
+	hours, err := controller.QueryDimensionHours("status_class", "", hour, hour.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query dimension hours: %v", err)
+	}
+	if len(hours) != 4 || !hours[0].Hour.Equal(hour) || hours[0].Value != "2xx" || hours[0].Bytes != 2010 || !hours[3].Hour.Equal(hour.Add(time.Hour)) {
+		t.Errorf("Expected two values in each of two hours, summed across datasets, got %+v", hours)
+	}
+	if hours, _ := controller.QueryDimensionHours("status_class", "http_requests", hour, hour.Add(time.Hour)); len(hours) != 2 || hours[0].Bytes != 2000 {
+		t.Errorf("Expected the http_requests values of one hour, got %+v", hours)
+	}
+}
+
+func TestDimensionCountsScoped(t *testing.T) {
+	tempFile := "test_dimensions_scoped.db"
+	defer os.Remove(tempFile)
+
+	// A rollup table from before the counts were scoped
+	legacy, err := sql.Open("sqlite3", tempFile)
+	if err != nil {
+		t.Fatalf("Failed to open legacy database: %v", err)
+	}
+	_, err = legacy.Exec(`CREATE TABLE dimension_rollups (hour DATETIME NOT NULL, dataset TEXT NOT NULL, dimension TEXT NOT NULL, value TEXT NOT NULL,
+			records INTEGER NOT NULL, bytes INTEGER NOT NULL, PRIMARY KEY (hour, dataset, dimension, value));
+		CREATE INDEX idx_dimension_rollups_dimension ON dimension_rollups(dimension, hour);
+		INSERT INTO dimension_rollups VALUES ('2025-09-15 10:00:00+00:00', 'http_requests', 'status_class', '2xx', 10, 1000);`)
+	legacy.Close()
+	if err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to open legacy database with controller: %v", err)
+	}
+	defer controller.Close()
+
+	hour := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	batch := []DimensionCount{{Dataset: "http_requests", Dimension: "status_class", Value: "2xx", Records: 1, Bytes: 100}}
+	if err := controller.ForTenant("acme").ForZone("example.com").ForLabels(map[string]string{"env": "prod"}).AddDimensionCounts(hour, batch); err != nil {
+		t.Fatalf("Failed to add scoped dimension counts: %v", err)
+	}
+	controller.ForTenant("acme").ForZone("other.com").AddDimensionCounts(hour, batch)
+
+	for _, c := range []struct {
+		name  string
+		store *SQLiteController
+		bytes int64
+	}{
+		{"unscoped", controller, 1200},
+		{"tenant", controller.ForTenant("acme"), 200},
+		{"zone", controller.ForTenant("acme").ForZone("example.com"), 100},
+		{"labels", controller.ForLabels(map[string]string{"env": "prod"}), 100},
+		{"dataset", controller.ForDataset("firewall_events"), 0},
+	} {
+		values, err := c.store.QueryDimension("status_class", hour, hour.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("%s: failed to query dimension: %v", c.name, err)
+		}
+		var bytes int64
+		for _, v := range values {
+			bytes += v.Bytes
+		}
+		if bytes != c.bytes {
+			t.Errorf("%s: expected %d bytes, got %+v", c.name, c.bytes, values)
+		}
+	}
+	if hours, _ := controller.ForZone("example.com").QueryDimensionHours("status_class", "", hour, hour.Add(time.Hour)); len(hours) != 1 || hours[0].Bytes != 100 {
+		t.Errorf("Expected the example.com hour only, got %+v", hours)
+	}
+	groups, err := controller.ForZone("other.com").QueryDimensionGroups(DimensionQuery{Dimension: "status_class", Start: hour, End: hour.Add(time.Hour)})
+	if err != nil || len(groups) != 1 || groups[0].Bytes != 100 {
+		t.Errorf("Expected the other.com total only, got %+v (%v)", groups, err)
+	}
+}
+
+func TestQueryDimensionGroups(t *testing.T) {
+	tempFile := "test_dimension_groups.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	day := time.Date(2025, 9, 15, 0, 0, 0, 0, time.UTC)
+	controller.AddDimensionCounts(day.Add(10*time.Hour), []DimensionCount{
+		{Dataset: "http_requests", Dimension: "colo", Value: "FRA", Records: 10, Bytes: 1000},
+		{Dataset: "http_requests", Dimension: "colo", Value: "AMS", Records: 5, Bytes: 3000},
+	})
+	controller.AddDimensionCounts(day.Add(11*time.Hour), []DimensionCount{
+		{Dataset: "http_requests", Dimension: "colo", Value: "FRA", Records: 1, Bytes: 100},
+		{Dataset: "firewall_events", Dimension: "colo", Value: "FRA", Records: 2, Bytes: 50},
+	})
+	controller.AddDimensionCounts(day.Add(26*time.Hour), []DimensionCount{
+		{Dataset: "http_requests", Dimension: "colo", Value: "FRA", Records: 7, Bytes: 700},
+	})
+	q := DimensionQuery{Dimension: "colo", Start: day, End: day.Add(48 * time.Hour)}
+
+	q.GroupBy = []string{"value"}
+	groups, err := controller.QueryDimensionGroups(q)
+	if err != nil {
+		t.Fatalf("Failed to query dimension groups: %v", err)
+	}
+	want := []DimensionGroup{{Value: "AMS", Records: 5, Bytes: 3000}, {Value: "FRA", Records: 20, Bytes: 1850}}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("Grouped by value = %+v, want %+v", groups, want)
+	}
+
+	q.GroupBy = []string{"day", "value"}
+	q.Datasets = []string{"http_requests"}
+	groups, _ = controller.QueryDimensionGroups(q)
+	want = []DimensionGroup{
+		{Value: "AMS", Bucket: "2025-09-15T00:00:00Z", Records: 5, Bytes: 3000},
+		{Value: "FRA", Bucket: "2025-09-15T00:00:00Z", Records: 11, Bytes: 1100},
+		{Value: "FRA", Bucket: "2025-09-16T00:00:00Z", Records: 7, Bytes: 700},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("Grouped by day and value = %+v, want %+v", groups, want)
+	}
+
+	q.GroupBy = []string{"hour", "dataset"}
+	q.Datasets = nil
+	q.Values = []string{"FRA"}
+	q.Limit = 2
+	groups, _ = controller.QueryDimensionGroups(q)
+	want = []DimensionGroup{
+		{Dataset: "http_requests", Bucket: "2025-09-15T10:00:00Z", Records: 10, Bytes: 1000},
+		{Dataset: "http_requests", Bucket: "2025-09-15T11:00:00Z", Records: 1, Bytes: 100},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("Grouped by hour and dataset = %+v, want %+v", groups, want)
+	}
+
+	q = DimensionQuery{Dimension: "colo", Start: day, End: day.Add(48 * time.Hour)}
+	if groups, _ := controller.QueryDimensionGroups(q); len(groups) != 1 || groups[0].Records != 25 || groups[0].Bytes != 4850 {
+		t.Errorf("Expected a single total without group keys, got %+v", groups)
+	}
+
+	for _, keys := range [][]string{{"zone"}, {"value", "value"}, {"hour", "day"}} {
+		q.GroupBy = keys
+		if _, err := controller.QueryDimensionGroups(q); err == nil {
+			t.Errorf("Expected group-by %q to be rejected", keys)
+		}
+	}
+}
@@ -78,13 +78,17 @@
 // log data analysis:
 //
 //	Table: log_sizes
-//	┌─────────────┬──────────────┬─────────────────────────────────┐
-//	│ Column      │ Type         │ Description                     │
-//	├─────────────┼──────────────┼─────────────────────────────────┤
-//	│ id          │ INTEGER      │ Primary key (auto-increment)    │
-//	│ timestamp   │ DATETIME     │ When the log was recorded       │
-//	│ filesize    │ INTEGER      │ Size of log data in bytes       │
-//	└─────────────┴──────────────┴─────────────────────────────────┘
+//	┌─────────────────┬──────────────┬─────────────────────────────────┐
+//	│ Column          │ Type         │ Description                     │
+//	├─────────────────┼──────────────┼─────────────────────────────────┤
+//	│ id              │ INTEGER      │ Primary key (auto-increment)    │
+//	│ timestamp       │ DATETIME     │ When the log was recorded       │
+//	│ filesize        │ INTEGER      │ Size of log data in bytes       │
+//	│ record_count    │ INTEGER      │ Records (lines) in the batch    │
+//	│ min_record_size │ INTEGER      │ Smallest record in bytes        │
+//	│ max_record_size │ INTEGER      │ Largest record in bytes         │
+//	│ avg_record_size │ REAL         │ Average record size in bytes    │
+//	└─────────────────┴──────────────┴─────────────────────────────────┘
 //
 //	Index: idx_timestamp on (timestamp)
 //	- Optimizes time-range queries for analytics
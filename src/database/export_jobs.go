@@ -0,0 +1,200 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// createExportJobsTable holds the DDL for exports built in the background.
+// The exported file itself is written to disk; the row records where, and
+// the manifest figures of the finished export.
+const createExportJobsTable = `CREATE TABLE IF NOT EXISTS export_jobs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	status TEXT NOT NULL DEFAULT 'pending',
+	range_start DATETIME NOT NULL,
+	range_end DATETIME NOT NULL,
+	file TEXT NOT NULL DEFAULT '',
+	encrypted INTEGER NOT NULL DEFAULT 0,
+	rows INTEGER NOT NULL DEFAULT 0,
+	bytes INTEGER NOT NULL DEFAULT 0,
+	sha256 TEXT NOT NULL DEFAULT '',
+	error TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	completed_at DATETIME
+);`
+
+// Export job states.
+const (
+	ExportPending  = "pending"  // Waiting for an earlier export to finish
+	ExportRunning  = "running"  // Being built
+	ExportComplete = "complete" // Written to File and ready for download
+	ExportFailed   = "failed"   // Given up on; Error says why
+)
+
+// ExportJob is a CSV export of a time range built in the background.
+type ExportJob struct {
+	ID          int64      `json:"id"`           // Job identifier
+	Status      string     `json:"status"`       // ExportPending, ExportRunning, ExportComplete or ExportFailed
+	Start       time.Time  `json:"start"`        // Range start (inclusive), UTC
+	End         time.Time  `json:"end"`          // Range end (exclusive), UTC
+	File        string     `json:"-"`            // Path of the finished file on disk
+	Encrypted   bool       `json:"encrypted"`    // Whether the file is sealed with the encryption key
+	Rows        int64      `json:"rows"`         // Data rows in the finished export
+	Bytes       int64      `json:"bytes"`        // Length of the CSV before any encryption
+	SHA256      string     `json:"sha256"`       // Hex SHA-256 of the CSV before any encryption
+	Error       string     `json:"error"`        // Why a failed export failed
+	CreatedAt   time.Time  `json:"created_at"`   // When the export was requested
+	CompletedAt *time.Time `json:"completed_at"` // When the export completed or failed
+}
+
+// exportJobColumns lists the columns read by scanExportJob.
+const exportJobColumns = `id, status, range_start, range_end, file, encrypted, rows, bytes, sha256, error, created_at, completed_at`
+
+// scanExportJob reads a single row selected with exportJobColumns.
+func scanExportJob(row rowScanner) (ExportJob, error) {
+	var (
+		j         ExportJob
+		completed sql.NullTime
+	)
+	err := row.Scan(&j.ID, &j.Status, &j.Start, &j.End, &j.File, &j.Encrypted, &j.Rows, &j.Bytes, &j.SHA256, &j.Error, &j.CreatedAt, &completed)
+	if err != nil {
+		return j, err
+	}
+	j.Start, j.End, j.CreatedAt = j.Start.UTC(), j.End.UTC(), j.CreatedAt.UTC()
+	if completed.Valid {
+		t := completed.Time.UTC()
+		j.CompletedAt = &t
+	}
+	return j, nil
+}
+
+// CreateExportJob records a pending export of [start, end).
+//
+// Parameters:
+//   - start: Range start (inclusive)
+//   - end: Range end (exclusive)
+//
+// Returns:
+//   - ExportJob: The new job
+//   - error: Any error encountered during the write
+func (c *SQLiteController) CreateExportJob(start, end time.Time) (ExportJob, error) {
+	res, err := c.db.Exec(`INSERT INTO export_jobs (status, range_start, range_end, created_at) VALUES (?, ?, ?, ?)`,
+		ExportPending, start.UTC(), end.UTC(), c.now().UTC())
+	if err != nil {
+		c.logger.Error("Failed to create export job", "error", err)
+		return ExportJob{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return ExportJob{}, err
+	}
+	job, _, err := c.GetExportJob(id)
+	return job, err
+}
+
+// GetExportJob loads an export job by ID.
+//
+// Parameters:
+//   - id: Job identifier
+//
+// Returns:
+//   - ExportJob: The job, if found
+//   - bool: Whether a job with that ID exists
+//   - error: Any error encountered during the query
+func (c *SQLiteController) GetExportJob(id int64) (ExportJob, bool, error) {
+	j, err := scanExportJob(c.db.QueryRow(`SELECT `+exportJobColumns+` FROM export_jobs WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ExportJob{}, false, nil
+	}
+	if err != nil {
+		c.logger.Error("Failed to query export job", "error", err, "id", id)
+		return ExportJob{}, false, err
+	}
+	return j, true, nil
+}
+
+// StartExportJob marks a pending export job as running.
+//
+// Parameters:
+//   - id: Job identifier
+//
+// Returns:
+//   - error: Any error encountered during the write
+func (c *SQLiteController) StartExportJob(id int64) error {
+	_, err := c.db.Exec(`UPDATE export_jobs SET status = ? WHERE id = ?`, ExportRunning, id)
+	if err != nil {
+		c.logger.Error("Failed to start export job", "error", err, "id", id)
+	}
+	return err
+}
+
+// CompleteExportJob records the finished file of an export job.
+//
+// Parameters:
+//   - job: Job with ID, File, Encrypted, Rows, Bytes and SHA256 set
+//
+// Returns:
+//   - error: Any error encountered during the write
+func (c *SQLiteController) CompleteExportJob(job ExportJob) error {
+	_, err := c.db.Exec(`UPDATE export_jobs SET status = ?, file = ?, encrypted = ?, rows = ?, bytes = ?, sha256 = ?, completed_at = ? WHERE id = ?`,
+		ExportComplete, job.File, job.Encrypted, job.Rows, job.Bytes, job.SHA256, c.now().UTC(), job.ID)
+	if err != nil {
+		c.logger.Error("Failed to complete export job", "error", err, "id", job.ID)
+	}
+	return err
+}
+
+// FailExportJob records why an export job failed.
+//
+// Parameters:
+//   - id: Job identifier
+//   - reason: Error reported to the caller
+//
+// Returns:
+//   - error: Any error encountered during the write
+func (c *SQLiteController) FailExportJob(id int64, reason string) error {
+	_, err := c.db.Exec(`UPDATE export_jobs SET status = ?, error = ?, completed_at = ? WHERE id = ?`,
+		ExportFailed, reason, c.now().UTC(), id)
+	if err != nil {
+		c.logger.Error("Failed to record export job failure", "error", err, "id", id)
+	}
+	return err
+}
+
+// FailUnfinishedExportJobs marks every pending or running export job as
+// failed, for jobs a previous process was stopped in the middle of.
+//
+// Parameters:
+//   - reason: Error reported to the caller
+//
+// Returns:
+//   - int64: Number of jobs marked failed
+//   - error: Any error encountered during the write
+func (c *SQLiteController) FailUnfinishedExportJobs(reason string) (int64, error) {
+	res, err := c.db.Exec(`UPDATE export_jobs SET status = ?, error = ?, completed_at = ? WHERE status IN (?, ?)`,
+		ExportFailed, reason, c.now().UTC(), ExportPending, ExportRunning)
+	if err != nil {
+		c.logger.Error("Failed to fail unfinished export jobs", "error", err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteExportJob removes an export job. The caller removes its file.
+//
+// Parameters:
+//   - id: Job identifier
+//
+// Returns:
+//   - bool: Whether a job was deleted
+//   - error: Any error encountered during the delete
+func (c *SQLiteController) DeleteExportJob(id int64) (bool, error) {
+	res, err := c.db.Exec(`DELETE FROM export_jobs WHERE id = ?`, id)
+	if err != nil {
+		c.logger.Error("Failed to delete export job", "error", err, "id", id)
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestExportJobLifecycle(t *testing.T) {
+	tempFile := "test_export_jobs.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	job, err := controller.CreateExportJob(start, start.AddDate(0, 1, 0))
+	if err != nil || job.Status != ExportPending || !job.Start.Equal(start) || job.CompletedAt != nil {
+		t.Fatalf("Expected a pending job, got %+v, %v", job, err)
+	}
+
+	if err := controller.StartExportJob(job.ID); err != nil {
+		t.Fatalf("StartExportJob failed: %v", err)
+	}
+	job.File, job.Rows, job.Bytes, job.SHA256, job.Encrypted = "/tmp/export-1.csv", 10, 512, "abc", true
+	if err := controller.CompleteExportJob(job); err != nil {
+		t.Fatalf("CompleteExportJob failed: %v", err)
+	}
+	done, found, err := controller.GetExportJob(job.ID)
+	if err != nil || !found || done.Status != ExportComplete || done.File != job.File || done.Rows != 10 || !done.Encrypted || done.CompletedAt == nil {
+		t.Errorf("Expected the completed job, got %+v, %v, %v", done, found, err)
+	}
+
+	// Jobs a stopped process left unfinished are failed; finished ones are kept
+	other, _ := controller.CreateExportJob(start, start.Add(time.Hour))
+	if n, err := controller.FailUnfinishedExportJobs("interrupted"); err != nil || n != 1 {
+		t.Errorf("Expected one unfinished job, got %d, %v", n, err)
+	}
+	if failed, _, _ := controller.GetExportJob(other.ID); failed.Status != ExportFailed || failed.Error != "interrupted" {
+		t.Errorf("Expected the job to be failed, got %+v", failed)
+	}
+
+	if deleted, err := controller.DeleteExportJob(job.ID); err != nil || !deleted {
+		t.Errorf("Expected the job to be deleted, got %v, %v", deleted, err)
+	}
+	if _, found, _ := controller.GetExportJob(job.ID); found {
+		t.Error("Expected the deleted job to be gone")
+	}
+}
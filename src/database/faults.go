@@ -0,0 +1,230 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Faults describes failures injected into database calls, so retry,
+// buffering and alerting code can be exercised against a misbehaving
+// database in automated tests. The zero value injects nothing.
+type Faults struct {
+	ErrorRate float64       `json:"error_rate"` // Fraction of calls failing with ErrInjectedFault
+	BusyRate  float64       `json:"busy_rate"`  // Fraction of calls failing as if the database were locked
+	Latency   time.Duration `json:"latency"`    // Delay added before every call
+}
+
+// ErrInjectedFault is returned by database calls failed by Faults.ErrorRate.
+var ErrInjectedFault = errors.New("database: injected fault")
+
+// ParseFaults parses a fault specification such as
+// "error_rate=0.05,busy_rate=0.1,latency=20ms", as accepted by
+// LPE_DB_FAULTS. Rates are fractions between 0 and 1.
+//
+// Parameters:
+//   - spec: Comma-separated key=value pairs; empty injects nothing
+//
+// Returns:
+//   - Faults: Parsed specification
+//   - error: If a key is unknown or a value is out of range
+func ParseFaults(spec string) (Faults, error) {
+	var f Faults
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Faults{}, fmt.Errorf("fault %q must be key=value", part)
+		}
+		var err error
+		switch strings.TrimSpace(key) {
+		case "error_rate":
+			f.ErrorRate, err = parseRate(value)
+		case "busy_rate":
+			f.BusyRate, err = parseRate(value)
+		case "latency":
+			f.Latency, err = time.ParseDuration(strings.TrimSpace(value))
+			if err == nil && f.Latency < 0 {
+				err = errors.New("must not be negative")
+			}
+		default:
+			return Faults{}, fmt.Errorf("unknown fault %q (use error_rate, busy_rate or latency)", key)
+		}
+		if err != nil {
+			return Faults{}, fmt.Errorf("invalid %s: %v", key, err)
+		}
+	}
+	return f, nil
+}
+
+// parseRate parses a fraction between 0 and 1.
+func parseRate(s string) (float64, error) {
+	r, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || r < 0 || r > 1 {
+		return 0, errors.New("must be a number between 0 and 1")
+	}
+	return r, nil
+}
+
+// IsBusy reports whether err is SQLite's "database is locked" error,
+// whether real or injected by Faults.BusyRate.
+func IsBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)
+}
+
+// SetFaults starts injecting failures into every database call made by
+// this controller and its ForTenant copies, replacing any faults set
+// before. Pass the zero Faults to stop.
+//
+// Parameters:
+//   - f: Failures to inject
+func (c *SQLiteController) SetFaults(f Faults) {
+	c.faults.set(f)
+	if f != (Faults{}) {
+		c.logger.Warn("Injecting database faults", "error_rate", f.ErrorRate, "busy_rate", f.BusyRate, "latency", f.Latency)
+	}
+}
+
+// faultState holds the faults injected into a controller's connections.
+type faultState struct {
+	current atomic.Pointer[Faults] // nil when no faults are injected
+}
+
+// set replaces the injected faults.
+func (s *faultState) set(f Faults) {
+	if f == (Faults{}) {
+		s.current.Store(nil)
+		return
+	}
+	s.current.Store(&f)
+}
+
+// inject delays and fails a call as configured.
+func (s *faultState) inject(ctx context.Context) error {
+	f := s.current.Load()
+	if f == nil {
+		return nil
+	}
+	if f.Latency > 0 {
+		t := time.NewTimer(f.Latency)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	if f.BusyRate > 0 && rand.Float64() < f.BusyRate {
+		return sqlite3.Error{Code: sqlite3.ErrBusy}
+	}
+	if f.ErrorRate > 0 && rand.Float64() < f.ErrorRate {
+		return ErrInjectedFault
+	}
+	return nil
+}
+
+// faultConnector opens SQLite connections that consult a faultState before
+// every call. Without faults set it only adds an atomic load per call. Its
+// connections also attach the month shards (see ShardByMonth).
+type faultConnector struct {
+	dsn    string
+	driver *sqlite3.SQLiteDriver
+	faults *faultState
+	shards *shardState
+}
+
+func (c *faultConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &faultConn{conn: conn.(*sqlite3.SQLiteConn), faults: c.faults, shards: c.shards}, nil
+}
+
+func (c *faultConnector) Driver() driver.Driver { return c.driver }
+
+// faultConn wraps a SQLite connection, injecting faults into statements,
+// transactions and pings, and attaching the month shards before them.
+type faultConn struct {
+	conn   *sqlite3.SQLiteConn
+	faults *faultState
+	shards *shardState
+
+	inTx            bool     // Whether a transaction is open
+	shardGeneration uint64   // Generation of the shards attached
+	attached        []string // Months attached
+}
+
+func (c *faultConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *faultConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.faults.inject(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.syncShards(ctx); err != nil {
+		return nil, err
+	}
+	return c.conn.PrepareContext(ctx, query)
+}
+
+func (c *faultConn) Close() error { return c.conn.Close() }
+
+func (c *faultConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *faultConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.faults.inject(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.syncShards(ctx); err != nil {
+		return nil, err
+	}
+	tx, err := c.conn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.inTx = true
+	return &shardTx{Tx: tx, conn: c}, nil
+}
+
+func (c *faultConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.faults.inject(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.syncShards(ctx); err != nil {
+		return nil, err
+	}
+	return c.conn.ExecContext(ctx, query, args)
+}
+
+func (c *faultConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.faults.inject(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.syncShards(ctx); err != nil {
+		return nil, err
+	}
+	return c.conn.QueryContext(ctx, query, args)
+}
+
+func (c *faultConn) Ping(ctx context.Context) error {
+	if err := c.faults.inject(ctx); err != nil {
+		return err
+	}
+	return c.conn.Ping(ctx)
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseFaults(t *testing.T) {
+	f, err := ParseFaults("error_rate=0.05, busy_rate=1,latency=20ms")
+	if err != nil {
+		t.Fatalf("ParseFaults failed: %v", err)
+	}
+	if f != (Faults{ErrorRate: 0.05, BusyRate: 1, Latency: 20 * time.Millisecond}) {
+		t.Errorf("Unexpected faults %+v", f)
+	}
+	if f, err := ParseFaults(""); err != nil || f != (Faults{}) {
+		t.Errorf("Expected no faults for an empty spec, got %+v, %v", f, err)
+	}
+	for _, spec := range []string{"error_rate=2", "busy_rate=-0.1", "latency=-1s", "latency=soon", "drop_rate=0.1", "error_rate"} {
+		if _, err := ParseFaults(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestSetFaults(t *testing.T) {
+	tempFile := "test_faults.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	controller.SetFaults(Faults{ErrorRate: 1})
+	if err := controller.InsertLogSize(1024); !errors.Is(err, ErrInjectedFault) {
+		t.Errorf("Expected an injected fault, got %v", err)
+	}
+	if err := controller.ForTenant("acme").Ping(); !errors.Is(err, ErrInjectedFault) {
+		t.Errorf("Expected tenant copies to share faults, got %v", err)
+	}
+
+	controller.SetFaults(Faults{BusyRate: 1})
+	if _, err := controller.GetAll(); !IsBusy(err) {
+		t.Errorf("Expected a busy error, got %v", err)
+	}
+
+	controller.SetFaults(Faults{Latency: 30 * time.Millisecond})
+	start := time.Now()
+	if err := controller.Ping(); err != nil {
+		t.Errorf("Ping failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected at least 30ms of latency, took %v", elapsed)
+	}
+
+	controller.SetFaults(Faults{})
+	if err := controller.InsertLogSize(1024); err != nil {
+		t.Errorf("Expected inserts to succeed once faults are cleared, got %v", err)
+	}
+	logs, err := controller.GetAll()
+	if err != nil || len(logs) != 1 {
+		t.Errorf("Expected only the insert made without faults to be stored, got %d logs, %v", len(logs), err)
+	}
+	if IsBusy(ErrInjectedFault) || IsBusy(nil) {
+		t.Error("Expected IsBusy to only match locked database errors")
+	}
+}
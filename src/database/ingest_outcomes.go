@@ -0,0 +1,63 @@
+package database
+
+import (
+	"time"
+)
+
+// createIngestOutcomesTable holds the DDL for hourly ingest success/failure
+// counters used to compute availability SLOs.
+const createIngestOutcomesTable = `CREATE TABLE IF NOT EXISTS ingest_outcomes (
+	hour DATETIME PRIMARY KEY,
+	successes INTEGER NOT NULL DEFAULT 0,
+	failures INTEGER NOT NULL DEFAULT 0
+);`
+
+// IngestOutcomes holds success and failure counts for ingest requests.
+type IngestOutcomes struct {
+	Successes int64 // Requests that were accepted and stored
+	Failures  int64 // Requests that failed because of a server-side error
+}
+
+// RecordIngestOutcome increments the success or failure counter for the
+// current hour. Only server-side failures should be recorded as failures;
+// client errors such as empty bodies do not count against availability.
+//
+// Parameters:
+//   - success: Whether the ingest request was handled successfully
+//
+// Returns:
+//   - error: Any error encountered while updating the counter
+func (c *SQLiteController) RecordIngestOutcome(success bool) error {
+	column := "failures"
+	if success {
+		column = "successes"
+	}
+	hour := c.now().UTC().Truncate(time.Hour)
+	_, err := c.db.Exec(`INSERT INTO ingest_outcomes (hour, `+column+`) VALUES (?, 1)
+		ON CONFLICT(hour) DO UPDATE SET `+column+` = `+column+` + 1`, hour)
+	if err != nil {
+		c.logger.Error("Failed to record ingest outcome", "error", err, "success", success)
+		return err
+	}
+	return nil
+}
+
+// IngestOutcomesSince sums ingest outcomes for every hour starting at or after
+// since.
+//
+// Parameters:
+//   - since: Start of the window (inclusive, truncated to the hour)
+//
+// Returns:
+//   - IngestOutcomes: Summed success and failure counts
+//   - error: Any error encountered during the query
+func (c *SQLiteController) IngestOutcomesSince(since time.Time) (IngestOutcomes, error) {
+	var out IngestOutcomes
+	err := c.db.QueryRow(`SELECT COALESCE(SUM(successes), 0), COALESCE(SUM(failures), 0) FROM ingest_outcomes WHERE hour >= ?`,
+		since.UTC().Truncate(time.Hour)).Scan(&out.Successes, &out.Failures)
+	if err != nil {
+		c.logger.Error("Failed to query ingest outcomes", "error", err, "since", since)
+		return IngestOutcomes{}, err
+	}
+	return out, nil
+}
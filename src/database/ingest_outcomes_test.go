@@ -0,0 +1,42 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestIngestOutcomes(t *testing.T) {
+	tempFile := "test_ingest_outcomes.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	for _, success := range []bool{true, true, true, false} {
+		if err := controller.RecordIngestOutcome(success); err != nil {
+			t.Fatalf("Failed to record ingest outcome: %v", err)
+		}
+	}
+
+	outcomes, err := controller.IngestOutcomesSince(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query ingest outcomes: %v", err)
+	}
+	if outcomes.Successes != 3 || outcomes.Failures != 1 {
+		t.Errorf("Expected 3 successes and 1 failure, got %+v", outcomes)
+	}
+
+	future, err := controller.IngestOutcomesSince(time.Now().Add(2 * time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query ingest outcomes: %v", err)
+	}
+	if future.Successes != 0 || future.Failures != 0 {
+		t.Errorf("Expected no outcomes in the future window, got %+v", future)
+	}
+}
@@ -0,0 +1,237 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// createIntegrityIssuesTable holds the DDL for discrepancies found by
+// CheckIntegrity. Rows are kept as an audit trail even after repair.
+const createIntegrityIssuesTable = `CREATE TABLE IF NOT EXISTS integrity_issues (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	detected_at DATETIME NOT NULL,
+	check_name TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	expected TEXT NOT NULL,
+	actual TEXT NOT NULL,
+	repaired INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_integrity_issues_detected_at ON integrity_issues(detected_at);`
+
+// CheckMinuteRollup is the name of the check comparing minute_aggregates
+// with the raw log_sizes rows.
+const CheckMinuteRollup = "minute_rollup"
+
+// IntegrityIssue is a single discrepancy between derived and raw data.
+type IntegrityIssue struct {
+	ID         int64     `json:"id"`          // Unique identifier
+	DetectedAt time.Time `json:"detected_at"` // When the check found the discrepancy
+	Check      string    `json:"check"`       // Check that found it, e.g. "minute_rollup"
+	Subject    string    `json:"subject"`     // What is inconsistent, e.g. the minute bucket
+	Expected   string    `json:"expected"`    // Value derived from raw data
+	Actual     string    `json:"actual"`      // Value found in the derived table
+	Repaired   bool      `json:"repaired"`    // Whether the derived value was rewritten
+}
+
+// IntegrityRun summarizes one CheckIntegrity pass.
+type IntegrityRun struct {
+	CheckedAt time.Time        `json:"checked_at"` // When the run started
+	Checks    []string         `json:"checks"`     // Checks that were executed
+	Repair    bool             `json:"repair"`     // Whether discrepancies were repaired
+	Issues    []IntegrityIssue `json:"issues"`     // Discrepancies found in this run
+}
+
+// minuteTotals are the per-minute values kept in minute_aggregates.
+type minuteTotals struct {
+	batches, records, totalSize int64
+}
+
+func (m minuteTotals) String() string {
+	return fmt.Sprintf("batches=%d records=%d total_size=%d", m.batches, m.records, m.totalSize)
+}
+
+// CheckIntegrity cross-checks derived tables against the raw log_sizes rows
+// and records every discrepancy in integrity_issues. With repair set, derived
+// rows are rewritten from the raw data in the same transaction, so drift left
+// by crashes or manual edits is corrected.
+//
+// Currently the per-minute rollup is checked over the part of
+// MinuteAggregateWindow that has not been pruned.
+//
+// Parameters:
+//   - repair: Whether to rewrite inconsistent derived rows
+//
+// Returns:
+//   - IntegrityRun: The checks run and the issues found
+//   - error: Any error encountered; on error nothing is recorded or repaired
+func (c *SQLiteController) CheckIntegrity(repair bool) (IntegrityRun, error) {
+	run := IntegrityRun{
+		CheckedAt: c.now().UTC(),
+		Checks:    []string{CheckMinuteRollup},
+		Repair:    repair,
+		Issues:    []IntegrityIssue{},
+	}
+	c.logger.Info("Checking data integrity", "repair", repair)
+
+	// Reading raw and derived rows in one transaction gives a consistent
+	// snapshot, so concurrent ingestion is not reported as drift
+	tx, err := c.db.Begin()
+	if err != nil {
+		c.logger.Error("Failed to begin integrity transaction", "error", err)
+		return IntegrityRun{}, err
+	}
+	defer tx.Rollback()
+
+	issues, err := checkMinuteRollup(tx, run.CheckedAt, repair)
+	if err != nil {
+		c.logger.Error("Failed to check minute rollup", "error", err)
+		return IntegrityRun{}, err
+	}
+
+	for _, issue := range issues {
+		issue.DetectedAt = run.CheckedAt
+		res, err := tx.Exec(`INSERT INTO integrity_issues (detected_at, check_name, subject, expected, actual, repaired) VALUES (?, ?, ?, ?, ?, ?)`,
+			issue.DetectedAt, issue.Check, issue.Subject, issue.Expected, issue.Actual, issue.Repaired)
+		if err != nil {
+			c.logger.Error("Failed to record integrity issue", "error", err)
+			return IntegrityRun{}, err
+		}
+		issue.ID, _ = res.LastInsertId()
+		run.Issues = append(run.Issues, issue)
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.logger.Error("Failed to commit integrity check", "error", err)
+		return IntegrityRun{}, err
+	}
+	if len(run.Issues) > 0 {
+		c.logger.Warn("Integrity check found discrepancies", "issues", len(run.Issues), "repaired", repair)
+	} else {
+		c.logger.Info("Integrity check passed")
+	}
+	return run, nil
+}
+
+// checkMinuteRollup compares minute_aggregates with totals computed from
+// log_sizes for every minute that cannot have been pruned yet.
+func checkMinuteRollup(tx *sql.Tx, now time.Time, repair bool) ([]IntegrityIssue, error) {
+	since := now.Add(-MinuteAggregateWindow).Truncate(time.Minute).Add(time.Minute)
+
+	expected := make(map[int64]minuteTotals)
+	rows, err := tx.Query(`SELECT timestamp, filesize, record_count FROM log_sizes WHERE timestamp >= ?`, since)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var (
+			ts                time.Time
+			filesize, records int64
+		)
+		if err := rows.Scan(&ts, &filesize, &records); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		key := ts.UTC().Truncate(time.Minute).Unix()
+		t := expected[key]
+		t.batches++
+		t.records += records
+		t.totalSize += filesize
+		expected[key] = t
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	actual := make(map[int64]minuteTotals)
+	rows, err = tx.Query(`SELECT minute, batches, records, total_size FROM minute_aggregates WHERE minute >= ?`, since)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var (
+			minute time.Time
+			t      minuteTotals
+		)
+		if err := rows.Scan(&minute, &t.batches, &t.records, &t.totalSize); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		actual[minute.UTC().Unix()] = t
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var issues []IntegrityIssue
+	record := func(key int64, want, got minuteTotals) error {
+		minute := time.Unix(key, 0).UTC()
+		issues = append(issues, IntegrityIssue{
+			Check:    CheckMinuteRollup,
+			Subject:  minute.Format(time.RFC3339),
+			Expected: want.String(),
+			Actual:   got.String(),
+			Repaired: repair,
+		})
+		if !repair {
+			return nil
+		}
+		if want == (minuteTotals{}) {
+			_, err := tx.Exec(`DELETE FROM minute_aggregates WHERE minute = ?`, minute)
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO minute_aggregates (minute, batches, records, total_size) VALUES (?, ?, ?, ?)
+			ON CONFLICT(minute) DO UPDATE SET batches = excluded.batches, records = excluded.records, total_size = excluded.total_size`,
+			minute, want.batches, want.records, want.totalSize)
+		return err
+	}
+
+	for key, want := range expected {
+		if got := actual[key]; got != want {
+			if err := record(key, want, got); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for key, got := range actual {
+		if _, ok := expected[key]; !ok && got != (minuteTotals{}) {
+			if err := record(key, minuteTotals{}, got); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Subject < issues[j].Subject })
+	return issues, nil
+}
+
+// ListIntegrityIssues returns the most recently detected integrity issues,
+// newest first.
+//
+// Parameters:
+//   - limit: Maximum number of issues to return
+//
+// Returns:
+//   - []IntegrityIssue: Recorded issues
+//   - error: Any error encountered during the query
+func (c *SQLiteController) ListIntegrityIssues(limit int) ([]IntegrityIssue, error) {
+	rows, err := c.db.Query(`SELECT id, detected_at, check_name, subject, expected, actual, repaired FROM integrity_issues ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		c.logger.Error("Failed to query integrity issues", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []IntegrityIssue{}
+	for rows.Next() {
+		var issue IntegrityIssue
+		if err := rows.Scan(&issue.ID, &issue.DetectedAt, &issue.Check, &issue.Subject, &issue.Expected, &issue.Actual, &issue.Repaired); err != nil {
+			c.logger.Error("Failed to scan integrity issue row", "error", err)
+			return nil, err
+		}
+		issue.DetectedAt = issue.DetectedAt.UTC()
+		out = append(out, issue)
+	}
+	return out, rows.Err()
+}
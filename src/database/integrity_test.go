@@ -0,0 +1,86 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCheckIntegrityRepairsMinuteRollup(t *testing.T) {
+	tempFile := "test_integrity.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	minute := time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+	for _, size := range []int64{100, 200} {
+		if err := controller.InsertLog(LogSize{Timestamp: minute.Add(10 * time.Second), Filesize: size, RecordCount: 2}); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+
+	run, err := controller.CheckIntegrity(true)
+	if err != nil {
+		t.Fatalf("Failed to check integrity: %v", err)
+	}
+	if len(run.Issues) != 0 {
+		t.Fatalf("Expected consistent data after ingest, got %+v", run.Issues)
+	}
+
+	// Simulate drift: a lost rollup update and an orphaned bucket
+	if _, err := controller.db.Exec(`UPDATE minute_aggregates SET batches = 1, total_size = 100 WHERE minute = ?`, minute); err != nil {
+		t.Fatalf("Failed to corrupt rollup: %v", err)
+	}
+	if _, err := controller.db.Exec(`INSERT INTO minute_aggregates (minute, batches, records, total_size) VALUES (?, 3, 3, 999)`, minute.Add(5*time.Minute)); err != nil {
+		t.Fatalf("Failed to insert orphaned rollup: %v", err)
+	}
+
+	run, err = controller.CheckIntegrity(false)
+	if err != nil {
+		t.Fatalf("Failed to check integrity: %v", err)
+	}
+	if len(run.Issues) != 2 || run.Issues[0].Repaired {
+		t.Fatalf("Expected 2 unrepaired issues, got %+v", run.Issues)
+	}
+	if run.Issues[0].Expected != "batches=2 records=4 total_size=300" {
+		t.Errorf("Unexpected expected value %q", run.Issues[0].Expected)
+	}
+
+	run, err = controller.CheckIntegrity(true)
+	if err != nil {
+		t.Fatalf("Failed to check integrity: %v", err)
+	}
+	if len(run.Issues) != 2 || !run.Issues[0].Repaired {
+		t.Fatalf("Expected 2 repaired issues, got %+v", run.Issues)
+	}
+
+	aggregates, err := controller.QueryMinuteAggregates(minute, minute.Add(10*time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to query minute aggregates: %v", err)
+	}
+	if len(aggregates) != 1 || aggregates[0].Batches != 2 || aggregates[0].TotalSize != 300 {
+		t.Errorf("Expected rollup rebuilt from raw data, got %+v", aggregates)
+	}
+
+	run, err = controller.CheckIntegrity(true)
+	if err != nil {
+		t.Fatalf("Failed to check integrity: %v", err)
+	}
+	if len(run.Issues) != 0 {
+		t.Errorf("Expected no issues after repair, got %+v", run.Issues)
+	}
+
+	issues, err := controller.ListIntegrityIssues(10)
+	if err != nil {
+		t.Fatalf("Failed to list integrity issues: %v", err)
+	}
+	if len(issues) != 4 || !issues[0].Repaired || issues[3].Repaired {
+		t.Errorf("Expected 4 recorded issues, newest first, got %+v", issues)
+	}
+}
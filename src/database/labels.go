@@ -0,0 +1,281 @@
+package database
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// encodeLabels stores labels as comma-separated key=value pairs ordered by
+// key, e.g. "env=prod,team=edge", or "" without labels. Keys and values
+// cannot contain commas or equals signs (see ingest.ValidLabel), so a label
+// is matched by searching for ",key=value," in "," + labels + ",".
+func encodeLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// decodeLabels reverses encodeLabels, returning nil for "".
+func decodeLabels(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if k, v, ok := strings.Cut(pair, "="); ok {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+// ForLabels returns a controller whose log record queries only return
+// records carrying every one of labels, in addition to any tenant, dataset
+// or zone scope of c. Like ForZone, it shares the connection with c and does
+// not change inserts.
+//
+// Parameters:
+//   - labels: Labels to require by key; empty returns c's scope unchanged
+//
+// Returns:
+//   - *SQLiteController: Scoped controller
+func (c *SQLiteController) ForLabels(labels map[string]string) *SQLiteController {
+	if len(labels) == 0 {
+		return c
+	}
+	scoped := *c
+	scoped.labels = make(map[string]string, len(c.labels)+len(labels))
+	for k, v := range c.labels {
+		scoped.labels[k] = v
+	}
+	for k, v := range labels {
+		scoped.labels[k] = v
+	}
+	scoped.logger = c.logger.With("labels", encodeLabels(scoped.labels))
+	return &scoped
+}
+
+// Labels returns the labels the controller's records must carry, or nil if
+// it sees records regardless of labels. The map must not be modified.
+func (c *SQLiteController) Labels() map[string]string {
+	return c.labels
+}
+
+// labelFilter returns a condition (starting with " AND") restricting
+// log_sizes rows to those carrying every label of the controller, and its
+// arguments, in key order.
+func (c *SQLiteController) labelFilter() (string, []any) {
+	var filter string
+	var args []any
+	for _, pair := range strings.Split(encodeLabels(c.labels), ",") {
+		if pair == "" {
+			continue
+		}
+		filter += " AND instr(',' || labels || ',', ?) > 0"
+		args = append(args, ","+pair+",")
+	}
+	return filter, args
+}
+
+// hasLabels reports whether l carries every one of labels.
+func hasLabels(l LogSize, labels map[string]string) bool {
+	for k, v := range labels {
+		if got, ok := l.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// LabelUsage summarizes the log records stored with one label.
+type LabelUsage struct {
+	Key         string `json:"key"`          // Label key
+	Value       string `json:"value"`        // Label value
+	Records     int64  `json:"records"`      // Number of stored log batches
+	TotalSize   int64  `json:"total_size"`   // Sum of batch sizes in bytes
+	RecordCount int64  `json:"record_count"` // Sum of log lines across the batches
+}
+
+// QueryLabelUsage returns the usage of every label on records in
+// [start, end), ordered by key, then largest first. A zero start or end
+// leaves that side of the range open. A batch with several labels counts
+// towards each of them; batches without labels are not listed. On a scoped
+// controller only the records in scope are counted.
+//
+// Parameters:
+//   - start: Start time (inclusive), or zero for no lower bound
+//   - end: End time (exclusive), or zero for no upper bound
+//
+// Returns:
+//   - []LabelUsage: Usage per label key and value within the range
+//   - error: Any error encountered during the query
+func (c *SQLiteController) QueryLabelUsage(start, end time.Time) ([]LabelUsage, error) {
+	if err := c.checkAttached(start); err != nil {
+		return nil, err
+	}
+	query := `SELECT labels, COUNT(*), COALESCE(SUM(filesize), 0), COALESCE(SUM(record_count), 0) FROM log_sizes WHERE labels != ''`
+	var args []any
+	if !start.IsZero() {
+		query += ` AND timestamp >= ?`
+		args = append(args, start.UTC())
+	}
+	if !end.IsZero() {
+		query += ` AND timestamp < ?`
+		args = append(args, end.UTC())
+	}
+	filter, filterArgs := c.tenantFilter()
+	rows, err := c.db.Query(query+filter+` GROUP BY labels`, append(args, filterArgs...)...)
+	if err != nil {
+		c.logger.Error("Failed to query label usage", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Batches are grouped by their whole label set, then split per label
+	type key struct{ key, value string }
+	totals := make(map[key]*LabelUsage)
+	for rows.Next() {
+		var labels string
+		var u LabelUsage
+		if err := rows.Scan(&labels, &u.Records, &u.TotalSize, &u.RecordCount); err != nil {
+			c.logger.Error("Failed to scan label usage row", "error", err)
+			return nil, err
+		}
+		for k, v := range decodeLabels(labels) {
+			t, ok := totals[key{k, v}]
+			if !ok {
+				t = &LabelUsage{Key: k, Value: v}
+				totals[key{k, v}] = t
+			}
+			t.Records += u.Records
+			t.TotalSize += u.TotalSize
+			t.RecordCount += u.RecordCount
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]LabelUsage, 0, len(totals))
+	for _, t := range totals {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Key != out[j].Key {
+			return out[i].Key < out[j].Key
+		}
+		if out[i].TotalSize != out[j].TotalSize {
+			return out[i].TotalSize > out[j].TotalSize
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out, nil
+}
+
+// labelMatch returns a condition (starting with " AND") matching log_sizes
+// rows carrying key=value, or key with any value when value is empty, and
+// its argument.
+func labelMatch(key, value string) (string, any) {
+	if value == "" {
+		return " AND instr(',' || labels, ?) > 0", "," + key + "="
+	}
+	return " AND instr(',' || labels || ',', ?) > 0", "," + key + "=" + value + ","
+}
+
+// CountLabel returns how many stored log records carry a label, as
+// RemoveLabel would change them. On a scoped controller only the records
+// in scope are counted.
+//
+// Parameters:
+//   - key: Label key
+//   - value: Label value, or "" for every value of key
+//
+// Returns:
+//   - int64: Matching records
+//   - error: Any error encountered during the query
+func (c *SQLiteController) CountLabel(key, value string) (int64, error) {
+	match, arg := labelMatch(key, value)
+	filter, args := c.tenantFilter()
+	var total int64
+	for _, table := range c.logSizeTables() {
+		var n int64
+		err := c.db.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE 1 = 1`+match+filter, append([]any{arg}, args...)...).Scan(&n)
+		if err != nil {
+			c.logger.Error("Failed to count labelled log sizes", "error", err, "table", table)
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// RemoveLabel strips a label from the stored log records, leaving the
+// records and their other labels in place, e.g. to clean up a key that
+// carried unbounded values. Unlike deletes it does not go through the
+// trash and cannot be undone. On a scoped controller only the records in
+// scope are changed.
+//
+// Parameters:
+//   - key: Label key
+//   - value: Label value, or "" to remove key whatever its value
+//
+// Returns:
+//   - int64: Records changed
+//   - error: Any error encountered; on error nothing is changed
+func (c *SQLiteController) RemoveLabel(key, value string) (int64, error) {
+	c.logger.Info("Removing label from log sizes", "key", key, "value", value)
+	match, arg := labelMatch(key, value)
+	filter, args := c.tenantFilter()
+	tx, err := c.db.Begin()
+	if err != nil {
+		c.logger.Error("Failed to begin label removal", "error", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var changed int64
+	for _, table := range c.logSizeTables() {
+		// Read the matching label sets first; rows cannot be updated while
+		// the query over them is open
+		rows, err := tx.Query(`SELECT id, labels FROM `+table+` WHERE 1 = 1`+match+filter, append([]any{arg}, args...)...)
+		if err != nil {
+			c.logger.Error("Failed to query labelled log sizes", "error", err, "table", table)
+			return 0, err
+		}
+		updates := make(map[int64]string)
+		for rows.Next() {
+			var id int64
+			var labels string
+			if err := rows.Scan(&id, &labels); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			decoded := decodeLabels(labels)
+			delete(decoded, key)
+			updates[id] = encodeLabels(decoded)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		for id, labels := range updates {
+			if _, err := tx.Exec(`UPDATE `+table+` SET labels = ? WHERE id = ?`, labels, id); err != nil {
+				c.logger.Error("Failed to update log size labels", "error", err, "table", table)
+				return 0, err
+			}
+		}
+		changed += int64(len(updates))
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.logger.Error("Failed to commit label removal", "error", err)
+		return 0, err
+	}
+	c.recent.invalidate()
+	c.logger.Info("Label removed from log sizes", "key", key, "value", value, "records", changed)
+	return changed, nil
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestForLabelsScopesLogRecords(t *testing.T) {
+	tempFile := "test_labels.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	at := time.Date(2025, 9, 15, 10, 0, 30, 0, time.UTC)
+	for _, l := range []LogSize{
+		{Timestamp: at, Filesize: 100, RecordCount: 2, Labels: map[string]string{"env": "prod", "team": "edge"}},
+		{Timestamp: at.Add(10 * time.Second), Filesize: 50, RecordCount: 1, Labels: map[string]string{"env": "prod"}},
+		{Timestamp: at, Filesize: 300, RecordCount: 3, Labels: map[string]string{"env": "staging", "team": "edge"}},
+		// A value that contains another as a prefix must not match it
+		{Timestamp: at, Filesize: 20, RecordCount: 1, Labels: map[string]string{"env": "prod-eu"}},
+		{Timestamp: at, Filesize: 7},
+	} {
+		if err := controller.InsertLog(l); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+
+	check := func(name string, c *SQLiteController, batches int, bytes int64) {
+		t.Helper()
+		logs, err := c.QueryByTimeRange(at.Add(-time.Minute), at.Add(time.Minute))
+		if err != nil {
+			t.Fatalf("%s: failed to query range: %v", name, err)
+		}
+		var sum int64
+		for _, l := range logs {
+			sum += l.Filesize
+		}
+		if len(logs) != batches || sum != bytes {
+			t.Errorf("%s: expected %d records of %d bytes, got %+v", name, batches, bytes, logs)
+		}
+		count, err := c.CountByTimeRange(time.Time{}, time.Time{})
+		if err != nil || count.Batches != int64(batches) || count.Bytes != bytes {
+			t.Errorf("%s: expected the count to match, got %+v, %v", name, count, err)
+		}
+	}
+	check("env=prod", controller.ForLabels(map[string]string{"env": "prod"}), 2, 150)
+	check("env=prod team=edge", controller.ForLabels(map[string]string{"env": "prod"}).ForLabels(map[string]string{"team": "edge"}), 1, 100)
+	check("no labels", controller.ForLabels(nil), 5, 477)
+
+	// The SQL path filters like the recent buffer
+	controller.SetRecentBufferSize(0)
+	check("env=prod from SQL", controller.ForLabels(map[string]string{"env": "prod"}), 2, 150)
+	check("team=edge from SQL", controller.ForLabels(map[string]string{"team": "edge"}), 2, 400)
+	since, err := controller.ForLabels(map[string]string{"env": "staging"}).QuerySince(0, 10)
+	if err != nil || len(since) != 1 || !reflect.DeepEqual(since[0].Labels, map[string]string{"env": "staging", "team": "edge"}) {
+		t.Errorf("Expected the staging record with its labels, got %+v, %v", since, err)
+	}
+
+	usage, err := controller.QueryLabelUsage(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to query label usage: %v", err)
+	}
+	want := []LabelUsage{
+		{Key: "env", Value: "staging", Records: 1, TotalSize: 300, RecordCount: 3},
+		{Key: "env", Value: "prod", Records: 2, TotalSize: 150, RecordCount: 3},
+		{Key: "env", Value: "prod-eu", Records: 1, TotalSize: 20, RecordCount: 1},
+		{Key: "team", Value: "edge", Records: 2, TotalSize: 400, RecordCount: 5},
+	}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("QueryLabelUsage() =\n%+v\nwant\n%+v", usage, want)
+	}
+}
+
+func TestEncodeLabels(t *testing.T) {
+	labels := map[string]string{"team": "edge", "env": "prod"}
+	encoded := encodeLabels(labels)
+	if encoded != "env=prod,team=edge" {
+		t.Errorf("encodeLabels() = %q, want keys in order", encoded)
+	}
+	if !reflect.DeepEqual(decodeLabels(encoded), labels) {
+		t.Errorf("decodeLabels(%q) = %v, want %v", encoded, decodeLabels(encoded), labels)
+	}
+	if encodeLabels(nil) != "" || decodeLabels("") != nil {
+		t.Error("Expected no labels to encode as the empty string and back")
+	}
+}
+
+func TestRemoveLabel(t *testing.T) {
+	tempFile := "test_remove_label.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	at := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	if err := controller.InsertLogs([]LogSize{
+		{Timestamp: at, Filesize: 1, Labels: map[string]string{"env": "prod", "request": "a1"}},
+		{Timestamp: at, Filesize: 2, Labels: map[string]string{"request": "b2"}},
+		{Timestamp: at, Filesize: 3, Labels: map[string]string{"env": "prod"}},
+		{Timestamp: at, Filesize: 4, Labels: map[string]string{"env": "dev"}, Tenant: "acme"},
+	}); err != nil {
+		t.Fatalf("Failed to insert logs: %v", err)
+	}
+
+	if n, err := controller.CountLabel("request", ""); err != nil || n != 2 {
+		t.Errorf("Expected 2 records with a request label, got %d (%v)", n, err)
+	}
+	if n, err := controller.RemoveLabel("request", ""); err != nil || n != 2 {
+		t.Fatalf("Expected 2 records changed, got %d (%v)", n, err)
+	}
+
+	// Scoped controllers only change their own records
+	if n, err := controller.ForTenant("acme").RemoveLabel("env", "prod"); err != nil || n != 0 {
+		t.Errorf("Expected no tenant record with env=prod, got %d (%v)", n, err)
+	}
+	if n, err := controller.RemoveLabel("env", "dev"); err != nil || n != 1 {
+		t.Errorf("Expected 1 record with env=dev, got %d (%v)", n, err)
+	}
+
+	logs, err := controller.QueryByTimeRange(at, at.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to query range: %v", err)
+	}
+	want := map[int64]map[string]string{1: {"env": "prod"}, 2: nil, 3: {"env": "prod"}, 4: nil}
+	for _, l := range logs {
+		if !reflect.DeepEqual(l.Labels, want[l.Filesize]) {
+			t.Errorf("Expected record %d to carry %v, got %v", l.Filesize, want[l.Filesize], l.Labels)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrLocked is returned by LockDatabase when another process holds the
+// database's lock.
+var ErrLocked = errors.New("database is in use by another LogpushEstimator process")
+
+// FileLock is an advisory lock on a database, held until Release or until
+// the process exits.
+type FileLock struct {
+	file     *os.File
+	previous string // Process ID a previous holder left behind, see PreviousHolder
+}
+
+// LockDatabase takes an exclusive advisory lock on the database at path,
+// through the file path+".lock", which is created if needed and records the
+// holder's process ID. Two estimators writing to one SQLite file double
+// count records and can corrupt the derived tables, so a second process is
+// refused instead of waiting.
+//
+// The lock is released by the operating system when the process exits,
+// however it exits, so a stale lock file never blocks a restart. On
+// platforms without file locking it always succeeds.
+//
+// Parameters:
+//   - path: Database file path
+//
+// Returns:
+//   - *FileLock: Held lock
+//   - error: ErrLocked, wrapped with the lock file and holder's process ID,
+//     when another process holds the lock
+func LockDatabase(path string) (*FileLock, error) {
+	lockPath := path + ".lock"
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		holder, _ := os.ReadFile(lockPath)
+		f.Close()
+		if errors.Is(err, errWouldBlock) {
+			if pid := strings.TrimSpace(string(holder)); pid != "" {
+				return nil, fmt.Errorf("%w: %s is held by process %s", ErrLocked, lockPath, pid)
+			}
+			return nil, fmt.Errorf("%w: %s is held", ErrLocked, lockPath)
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", lockPath, err)
+	}
+
+	// Release clears the process ID, so one still recorded belongs to a
+	// holder that exited without releasing the lock
+	previous, _ := io.ReadAll(f)
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &FileLock{file: f, previous: strings.TrimSpace(string(previous))}, nil
+}
+
+// PreviousHolder returns the process ID of the previous holder when it
+// exited without releasing the lock, i.e. it crashed or was killed, and an
+// empty string after a clean shutdown or on first use.
+//
+// Returns:
+//   - string: Previous holder's process ID, or empty
+func (l *FileLock) PreviousHolder() string {
+	if l == nil {
+		return ""
+	}
+	return l.previous
+}
+
+// Release releases the lock. The lock file is left in place, since removing
+// it could let two processes lock different files of the same name.
+//
+// Returns:
+//   - error: Any error encountered while unlocking
+func (l *FileLock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	l.file.Truncate(0)
+	err := unlockFile(l.file)
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	l.file = nil
+	return err
+}
@@ -0,0 +1,17 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package database
+
+import (
+	"errors"
+	"os"
+)
+
+// errWouldBlock is never returned on platforms without file locking.
+var errWouldBlock = errors.New("lock is held")
+
+// lockFile does nothing on platforms without file locking.
+func lockFile(*os.File) error { return nil }
+
+// unlockFile does nothing on platforms without file locking.
+func unlockFile(*os.File) error { return nil }
@@ -0,0 +1,55 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows
+
+package database
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLockDatabase(t *testing.T) {
+	tempFile := "test_lock.db"
+	defer os.Remove(tempFile + ".lock")
+
+	lock, err := LockDatabase(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to lock database: %v", err)
+	}
+
+	_, err = LockDatabase(tempFile)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("Expected ErrLocked for a second lock, got %v", err)
+	}
+	if !strings.Contains(err.Error(), strconv.Itoa(os.Getpid())) {
+		t.Errorf("Expected the error to name the holder, got %v", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Errorf("Second Release failed: %v", err)
+	}
+
+	lock, err = LockDatabase(tempFile)
+	if err != nil {
+		t.Fatalf("Expected the lock to be free after Release, got %v", err)
+	}
+	if pid := lock.PreviousHolder(); pid != "" {
+		t.Errorf("Expected no previous holder after a clean release, got %q", pid)
+	}
+
+	// A holder that exits without releasing leaves its process ID behind
+	lock.file.Close()
+	lock, err = LockDatabase(tempFile)
+	if err != nil {
+		t.Fatalf("Expected the lock to be free once its file is closed, got %v", err)
+	}
+	if pid := lock.PreviousHolder(); pid != strconv.Itoa(os.Getpid()) {
+		t.Errorf("Expected the unreleased holder's process ID, got %q", pid)
+	}
+	lock.Release()
+}
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// createLogpushJobsTable holds the DDL for Cloudflare Logpush jobs created
+// or tracked by this instance. Destinations are stored with credentials
+// redacted. The last_* columns and error_message mirror the job's status at
+// Cloudflare as of health_checked_at.
+const createLogpushJobsTable = `CREATE TABLE IF NOT EXISTS logpush_jobs (
+	id INTEGER PRIMARY KEY,
+	scope TEXT NOT NULL,
+	scope_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	dataset TEXT NOT NULL,
+	destination TEXT NOT NULL,
+	enabled INTEGER NOT NULL,
+	created_at DATETIME NOT NULL,
+	last_complete DATETIME,
+	last_error DATETIME,
+	error_message TEXT NOT NULL DEFAULT '',
+	health_checked_at DATETIME
+);`
+
+// LogpushJob is a Cloudflare Logpush job tracked by this instance.
+type LogpushJob struct {
+	ID          int64     `json:"id"`          // Cloudflare job identifier
+	Scope       string    `json:"scope"`       // "zones" or "accounts"
+	ScopeID     string    `json:"scope_id"`    // Zone or account identifier
+	Name        string    `json:"name"`        // Job name
+	Dataset     string    `json:"dataset"`     // Dataset the job pushes
+	Destination string    `json:"destination"` // Destination with credentials redacted
+	Enabled     bool      `json:"enabled"`     // Whether the job was enabled when last seen
+	CreatedAt   time.Time `json:"created_at"`  // When the job was recorded here
+
+	LastComplete    *time.Time `json:"last_complete"`     // Last successful push reported by Cloudflare
+	LastError       *time.Time `json:"last_error"`        // Last failed push reported by Cloudflare
+	ErrorMessage    string     `json:"error_message"`     // Message for the last failure
+	HealthCheckedAt *time.Time `json:"health_checked_at"` // When the status was last synced; nil if never
+}
+
+// Job health states reported by LogpushJob.Health.
+const (
+	JobHealthUnknown  = "unknown"  // Never synced
+	JobHealthDisabled = "disabled" // Job is not pushing
+	JobHealthPending  = "pending"  // Enabled, but no push has completed or failed yet
+	JobHealthHealthy  = "healthy"  // Last push completed
+	JobHealthFailing  = "failing"  // Last push failed
+)
+
+// Health classifies the job from its last synced status. A job is failing
+// when its most recent push attempt, successful or not, was a failure.
+func (j LogpushJob) Health() string {
+	switch {
+	case j.HealthCheckedAt == nil:
+		return JobHealthUnknown
+	case !j.Enabled:
+		return JobHealthDisabled
+	case j.LastError != nil && (j.LastComplete == nil || j.LastError.After(*j.LastComplete)):
+		return JobHealthFailing
+	case j.LastComplete != nil:
+		return JobHealthHealthy
+	default:
+		return JobHealthPending
+	}
+}
+
+// SaveLogpushJob records a job, replacing any stored job with the same ID.
+//
+// Parameters:
+//   - job: Job to record; CreatedAt defaults to now
+//
+// Returns:
+//   - error: Any error encountered while writing
+func (c *SQLiteController) SaveLogpushJob(job LogpushJob) error {
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = c.now()
+	}
+	_, err := c.db.Exec(`INSERT INTO logpush_jobs (id, scope, scope_id, name, dataset, destination, enabled, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET scope = excluded.scope, scope_id = excluded.scope_id, name = excluded.name,
+			dataset = excluded.dataset, destination = excluded.destination, enabled = excluded.enabled`,
+		job.ID, job.Scope, job.ScopeID, job.Name, job.Dataset, job.Destination, job.Enabled, job.CreatedAt.UTC())
+	if err != nil {
+		c.logger.Error("Failed to save logpush job", "error", err, "id", job.ID)
+		return err
+	}
+	c.logger.Info("Logpush job saved", "id", job.ID, "dataset", job.Dataset)
+	return nil
+}
+
+// ListLogpushJobs returns every tracked job ordered by ID.
+//
+// Returns:
+//   - []LogpushJob: Tracked jobs
+//   - error: Any error encountered during the query
+func (c *SQLiteController) ListLogpushJobs() ([]LogpushJob, error) {
+	rows, err := c.db.Query(`SELECT ` + logpushJobColumns + ` FROM logpush_jobs ORDER BY id`)
+	if err != nil {
+		c.logger.Error("Failed to query logpush jobs", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []LogpushJob{}
+	for rows.Next() {
+		j, err := scanLogpushJob(rows)
+		if err != nil {
+			c.logger.Error("Failed to scan logpush job row", "error", err)
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+// GetLogpushJob returns the tracked job with the given ID.
+//
+// Parameters:
+//   - id: Cloudflare job identifier
+//
+// Returns:
+//   - LogpushJob: The job, if found
+//   - bool: Whether the job is tracked
+//   - error: Any error encountered during the query
+func (c *SQLiteController) GetLogpushJob(id int64) (LogpushJob, bool, error) {
+	j, err := scanLogpushJob(c.db.QueryRow(`SELECT `+logpushJobColumns+` FROM logpush_jobs WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return LogpushJob{}, false, nil
+	}
+	if err != nil {
+		c.logger.Error("Failed to query logpush job", "error", err, "id", id)
+		return LogpushJob{}, false, err
+	}
+	return j, true, nil
+}
+
+// UpdateLogpushJobHealth stores the status Cloudflare reports for a tracked
+// job and stamps health_checked_at with the current time.
+//
+// Parameters:
+//   - job: Job ID plus its Enabled, LastComplete, LastError and ErrorMessage
+//
+// Returns:
+//   - bool: Whether the job is tracked
+//   - error: Any error encountered while writing
+func (c *SQLiteController) UpdateLogpushJobHealth(job LogpushJob) (bool, error) {
+	res, err := c.db.Exec(`UPDATE logpush_jobs SET enabled = ?, last_complete = ?, last_error = ?, error_message = ?, health_checked_at = ? WHERE id = ?`,
+		job.Enabled, utcOrNil(job.LastComplete), utcOrNil(job.LastError), job.ErrorMessage, c.now().UTC(), job.ID)
+	if err != nil {
+		c.logger.Error("Failed to update logpush job health", "error", err, "id", job.ID)
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// logpushJobColumns is the column list scanLogpushJob expects.
+const logpushJobColumns = `id, scope, scope_id, name, dataset, destination, enabled, created_at, last_complete, last_error, error_message, health_checked_at`
+
+// scanLogpushJob reads one logpush_jobs row selected with logpushJobColumns.
+func scanLogpushJob(row interface{ Scan(...any) error }) (LogpushJob, error) {
+	var (
+		j                                  LogpushJob
+		lastComplete, lastError, checkedAt sql.NullTime
+	)
+	if err := row.Scan(&j.ID, &j.Scope, &j.ScopeID, &j.Name, &j.Dataset, &j.Destination, &j.Enabled, &j.CreatedAt,
+		&lastComplete, &lastError, &j.ErrorMessage, &checkedAt); err != nil {
+		return LogpushJob{}, err
+	}
+	j.CreatedAt = j.CreatedAt.UTC()
+	j.LastComplete = nullTimePtr(lastComplete)
+	j.LastError = nullTimePtr(lastError)
+	j.HealthCheckedAt = nullTimePtr(checkedAt)
+	return j, nil
+}
+
+// nullTimePtr converts a nullable column to a UTC *time.Time.
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := t.Time.UTC()
+	return &utc
+}
+
+// utcOrNil prepares an optional time for storage.
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
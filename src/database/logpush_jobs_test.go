@@ -0,0 +1,106 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSaveLogpushJob(t *testing.T) {
+	tempFile := "test_logpush_jobs.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	job := LogpushJob{ID: 7, Scope: "zones", ScopeID: "zone-a", Name: "estimator", Dataset: "http_requests", Destination: "https://example.com/ingest", Enabled: true}
+	if err := controller.SaveLogpushJob(job); err != nil {
+		t.Fatalf("Failed to save job: %v", err)
+	}
+	job.Enabled = false
+	if err := controller.SaveLogpushJob(job); err != nil {
+		t.Fatalf("Failed to update job: %v", err)
+	}
+
+	jobs, err := controller.ListLogpushJobs()
+	if err != nil {
+		t.Fatalf("Failed to list jobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != 7 || jobs[0].Enabled || jobs[0].CreatedAt.IsZero() {
+		t.Errorf("Expected one updated job, got %+v", jobs)
+	}
+}
+
+func TestUpdateLogpushJobHealth(t *testing.T) {
+	tempFile := "test_logpush_job_health.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	job := LogpushJob{ID: 7, Scope: "zones", ScopeID: "zone-a", Name: "estimator", Dataset: "http_requests", Destination: "https://example.com/ingest", Enabled: true}
+	if err := controller.SaveLogpushJob(job); err != nil {
+		t.Fatalf("Failed to save job: %v", err)
+	}
+	stored, found, err := controller.GetLogpushJob(7)
+	if err != nil || !found {
+		t.Fatalf("Expected the job to be found, got %v %v", found, err)
+	}
+	if stored.Health() != JobHealthUnknown {
+		t.Errorf("Expected an unsynced job to be unknown, got %s", stored.Health())
+	}
+
+	complete := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	failed := complete.Add(5 * time.Minute)
+	job.LastComplete = &complete
+	job.LastError = &failed
+	job.ErrorMessage = "destination returned 401"
+	if ok, err := controller.UpdateLogpushJobHealth(job); err != nil || !ok {
+		t.Fatalf("Failed to update health: %v %v", ok, err)
+	}
+
+	stored, _, _ = controller.GetLogpushJob(7)
+	if stored.HealthCheckedAt == nil || stored.LastError == nil || !stored.LastError.Equal(failed) || stored.ErrorMessage != job.ErrorMessage {
+		t.Errorf("Expected the synced status to be stored, got %+v", stored)
+	}
+	if stored.Health() != JobHealthFailing {
+		t.Errorf("Expected a job whose last push failed to be failing, got %s", stored.Health())
+	}
+
+	if ok, _ := controller.UpdateLogpushJobHealth(LogpushJob{ID: 8}); ok {
+		t.Error("Expected updating an untracked job to report not found")
+	}
+	if _, found, _ := controller.GetLogpushJob(8); found {
+		t.Error("Expected an untracked job to be missing")
+	}
+}
+
+func TestLogpushJobHealth(t *testing.T) {
+	checked := time.Now()
+	earlier, later := checked.Add(-time.Hour), checked.Add(-time.Minute)
+	tests := []struct {
+		name string
+		job  LogpushJob
+		want string
+	}{
+		{"disabled", LogpushJob{HealthCheckedAt: &checked}, JobHealthDisabled},
+		{"pending", LogpushJob{Enabled: true, HealthCheckedAt: &checked}, JobHealthPending},
+		{"healthy", LogpushJob{Enabled: true, HealthCheckedAt: &checked, LastComplete: &later, LastError: &earlier}, JobHealthHealthy},
+		{"failing", LogpushJob{Enabled: true, HealthCheckedAt: &checked, LastComplete: &earlier, LastError: &later}, JobHealthFailing},
+		{"never completed", LogpushJob{Enabled: true, HealthCheckedAt: &checked, LastError: &later}, JobHealthFailing},
+	}
+	for _, tt := range tests {
+		if got := tt.job.Health(); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
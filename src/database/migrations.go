@@ -0,0 +1,182 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// columnDef describes a column that was added to an existing table after the
+// initial schema shipped.
+type columnDef struct {
+	name       string // Column name
+	definition string // Type and constraints used in ALTER TABLE ... ADD COLUMN
+}
+
+// logSizeColumns lists the log_sizes columns introduced after the original
+// (id, timestamp, filesize) schema. They are appended on startup so that
+// databases created by older versions keep working without manual migration.
+var logSizeColumns = []columnDef{
+	{"record_count", "INTEGER NOT NULL DEFAULT 0"},
+	{"min_record_size", "INTEGER NOT NULL DEFAULT 0"},
+	{"max_record_size", "INTEGER NOT NULL DEFAULT 0"},
+	{"avg_record_size", "REAL NOT NULL DEFAULT 0"},
+	{"tenant", "TEXT NOT NULL DEFAULT ''"},
+	{"dataset", "TEXT NOT NULL DEFAULT ''"},
+	{"uncompressed_size", "INTEGER NOT NULL DEFAULT 0"},
+	{"content_encoding", "TEXT NOT NULL DEFAULT ''"},
+	{"zone", "TEXT NOT NULL DEFAULT ''"},
+	{"labels", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// deletedLogSizeColumns lists the trash columns introduced after the trash
+// table, mirroring logSizeColumns so restored records keep their values.
+var deletedLogSizeColumns = []columnDef{
+	{"tenant", "TEXT NOT NULL DEFAULT ''"},
+	{"dataset", "TEXT NOT NULL DEFAULT ''"},
+	{"uncompressed_size", "INTEGER NOT NULL DEFAULT 0"},
+	{"content_encoding", "TEXT NOT NULL DEFAULT ''"},
+	{"zone", "TEXT NOT NULL DEFAULT ''"},
+	{"labels", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// preferencesColumns lists the preferences columns introduced after the
+// preferences table.
+var preferencesColumns = []columnDef{
+	{"locale", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// tableDef is a table created alongside log_sizes on startup.
+type tableDef struct {
+	name string // Table name, used for logging
+	ddl  string // CREATE TABLE IF NOT EXISTS statement (plus any indexes)
+}
+
+// auxiliaryTables lists the supporting tables created after log_sizes. Each
+// DDL statement must be idempotent.
+var auxiliaryTables = []tableDef{
+	{"minute_aggregates", createMinuteAggregatesTable},
+	{"ingest_outcomes", createIngestOutcomesTable},
+	{"preferences", createPreferencesTable},
+	{"views", createViewsTable},
+	{"config_entries", createConfigEntriesTable},
+	{"integrity_issues", createIntegrityIssuesTable},
+	{"trash", createTrashTables},
+	{"zone_traffic", createZoneTrafficTable},
+	{"logpush_jobs", createLogpushJobsTable},
+	{"dimension_rollups", createDimensionRollupsTable},
+	{"payload_samples", createPayloadSamplesTable},
+	{"redaction_audit", createRedactionAuditTable},
+	{"outbox", createOutboxTable},
+	{"scenarios", createScenariosTable},
+	{"export_jobs", createExportJobsTable},
+	{"annotations", createAnnotationsTable},
+	{"relay_queue", createRelayQueueTable},
+	{"relay_forwards", createRelayForwardsTable},
+}
+
+// addMissingColumns adds every column in columns that is not yet present in
+// table. Existing columns are left untouched, which makes the function safe to
+// run on every startup.
+func addMissingColumns(db *sql.DB, table string, columns []columnDef) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	for _, col := range columns {
+		if existing[col.name] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, col.name, col.definition)); err != nil {
+			return fmt.Errorf("add column %s.%s: %w", table, col.name, err)
+		}
+	}
+	return nil
+}
+
+// columnRef names a single column of a table.
+type columnRef struct {
+	table  string
+	column string
+}
+
+// utcColumns lists the timestamp columns used in range comparisons. SQLite
+// compares DATETIME values as text, so every row must use the same offset for
+// ordering to be correct.
+var utcColumns = []columnRef{
+	{"log_sizes", "timestamp"},
+	{"minute_aggregates", "minute"},
+	{"ingest_outcomes", "hour"},
+}
+
+// utcSuffix is how the SQLite driver serializes a UTC offset.
+const utcSuffix = "+00:00"
+
+// normalizeToUTC rewrites values of table.column that were stored with a
+// non-UTC offset (by versions that used local time) as the same instant in
+// UTC. It returns the number of rows rewritten and is a no-op once every row
+// is in UTC.
+func normalizeToUTC(db *sql.DB, table, column string) (int64, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT rowid, %s FROM %s WHERE %s NOT LIKE '%%%s'", column, table, column, utcSuffix))
+	if err != nil {
+		return 0, err
+	}
+	type pending struct {
+		rowid int64
+		ts    time.Time
+	}
+	var updates []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.rowid, &p.ts); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		updates = append(updates, p)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+	if len(updates) == 0 {
+		return 0, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for _, p := range updates {
+		if _, err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE rowid = ?", table, column), p.ts.UTC(), p.rowid); err != nil {
+			return 0, fmt.Errorf("normalize %s.%s: %w", table, column, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int64(len(updates)), nil
+}
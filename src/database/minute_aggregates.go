@@ -0,0 +1,118 @@
+package database
+
+import (
+	"time"
+)
+
+// MinuteAggregateWindow is how long per-minute aggregates are retained.
+// Long-term analysis uses the raw log_sizes table; the minute table only
+// backs high-resolution "last hour" charts and burst detection.
+const MinuteAggregateWindow = 48 * time.Hour
+
+// createMinuteAggregatesTable holds the DDL for the rolling per-minute table.
+const createMinuteAggregatesTable = `CREATE TABLE IF NOT EXISTS minute_aggregates (
+	minute DATETIME PRIMARY KEY,
+	batches INTEGER NOT NULL DEFAULT 0,
+	records INTEGER NOT NULL DEFAULT 0,
+	total_size INTEGER NOT NULL DEFAULT 0
+);`
+
+// upsertMinuteAggregate adds a batch to the bucket for its minute.
+const upsertMinuteAggregate = `INSERT INTO minute_aggregates (minute, batches, records, total_size) VALUES (?, 1, ?, ?)
+	ON CONFLICT(minute) DO UPDATE SET
+		batches = batches + 1,
+		records = records + excluded.records,
+		total_size = total_size + excluded.total_size`
+
+// MinuteAggregate is the per-minute rollup of ingested batches.
+type MinuteAggregate struct {
+	Minute    time.Time // Start of the minute bucket
+	Batches   int64     // Number of batches received during the minute
+	Records   int64     // Number of records received during the minute
+	TotalSize int64     // Sum of batch sizes in bytes
+}
+
+// QueryMinuteAggregates returns the minute buckets in [start, end) ordered by
+// time. Minutes without any ingested batches are not stored and therefore not
+// returned. The rollup table covers all tenants and datasets, so a scoped
+// controller computes the buckets from its raw records instead.
+//
+// Parameters:
+//   - start: Start time (inclusive)
+//   - end: End time (exclusive)
+//
+// Returns:
+//   - []MinuteAggregate: Buckets ordered by minute
+//   - error: Any error encountered during the query
+func (c *SQLiteController) QueryMinuteAggregates(start, end time.Time) ([]MinuteAggregate, error) {
+	c.logger.Info("Querying minute aggregates", "start", start, "end", end)
+	if c.scoped() {
+		return c.queryScopedMinuteAggregates(start, end)
+	}
+	rows, err := c.db.Query(`SELECT minute, batches, records, total_size FROM minute_aggregates WHERE minute >= ? AND minute < ? ORDER BY minute`,
+		start.UTC().Truncate(time.Minute), end.UTC())
+	if err != nil {
+		c.logger.Error("Failed to query minute aggregates", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var out []MinuteAggregate
+	for rows.Next() {
+		var m MinuteAggregate
+		if err := rows.Scan(&m.Minute, &m.Batches, &m.Records, &m.TotalSize); err != nil {
+			c.logger.Error("Failed to scan minute aggregate row", "error", err)
+			return nil, err
+		}
+		m.Minute = m.Minute.UTC()
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// queryScopedMinuteAggregates groups the log records of the controller's
+// tenant and dataset in [start, end) into minute buckets.
+func (c *SQLiteController) queryScopedMinuteAggregates(start, end time.Time) ([]MinuteAggregate, error) {
+	if err := c.checkAttached(start); err != nil {
+		return nil, err
+	}
+	filter, args := c.tenantFilter()
+	rows, err := c.db.Query(`SELECT strftime('%Y-%m-%d %H:%M:00', timestamp) AS minute, COUNT(*), SUM(record_count), SUM(filesize)
+		FROM log_sizes WHERE timestamp >= ? AND timestamp < ?`+filter+` GROUP BY minute ORDER BY minute`,
+		append([]any{start.UTC().Truncate(time.Minute), end.UTC()}, args...)...)
+	if err != nil {
+		c.logger.Error("Failed to query scoped minute aggregates", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var out []MinuteAggregate
+	for rows.Next() {
+		var (
+			m      MinuteAggregate
+			minute string
+		)
+		if err := rows.Scan(&minute, &m.Batches, &m.Records, &m.TotalSize); err != nil {
+			c.logger.Error("Failed to scan scoped minute aggregate row", "error", err)
+			return nil, err
+		}
+		if m.Minute, err = time.Parse(time.DateTime, minute); err != nil {
+			c.logger.Error("Failed to parse scoped minute", "error", err, "minute", minute)
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// PruneMinuteAggregates deletes minute buckets that started before cutoff and
+// returns the number of rows removed. It is run periodically to keep the table
+// bounded to MinuteAggregateWindow.
+func (c *SQLiteController) PruneMinuteAggregates(cutoff time.Time) (int64, error) {
+	res, err := c.db.Exec(`DELETE FROM minute_aggregates WHERE minute < ?`, cutoff.UTC())
+	if err != nil {
+		c.logger.Error("Failed to prune minute aggregates", "error", err, "cutoff", cutoff)
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	c.logger.Info("Pruned minute aggregates", "cutoff", cutoff, "deleted", n)
+	return n, nil
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMinuteAggregates(t *testing.T) {
+	tempFile := "test_minute_aggregates.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	minute := time.Now().Truncate(time.Minute).Add(-5 * time.Minute)
+	entries := []LogSize{
+		{Timestamp: minute.Add(5 * time.Second), Filesize: 100, RecordCount: 2},
+		{Timestamp: minute.Add(50 * time.Second), Filesize: 300, RecordCount: 3},
+		{Timestamp: minute.Add(time.Minute), Filesize: 50, RecordCount: 1},
+	}
+	for _, e := range entries {
+		if err := controller.InsertLog(e); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+
+	aggregates, err := controller.QueryMinuteAggregates(minute.Add(-time.Hour), time.Now())
+	if err != nil {
+		t.Fatalf("Failed to query minute aggregates: %v", err)
+	}
+	if len(aggregates) != 2 {
+		t.Fatalf("Expected 2 minute buckets, got %d", len(aggregates))
+	}
+	if aggregates[0].Batches != 2 || aggregates[0].Records != 5 || aggregates[0].TotalSize != 400 {
+		t.Errorf("Unexpected first bucket: %+v", aggregates[0])
+	}
+
+	deleted, err := controller.PruneMinuteAggregates(minute.Add(30 * time.Second))
+	if err != nil {
+		t.Fatalf("Failed to prune minute aggregates: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 pruned bucket, got %d", deleted)
+	}
+
+	// Pruning the rollup must never touch raw records
+	logSizes, err := controller.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to query log sizes: %v", err)
+	}
+	if len(logSizes) != len(entries) {
+		t.Errorf("Expected %d raw records after pruning, got %d", len(entries), len(logSizes))
+	}
+}
@@ -0,0 +1,234 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// createOutboxTable holds the DDL for outbound notifications waiting to be
+// delivered, delivered, or given up on. Rows are written in the same
+// process that publishes the event and removed by PruneOutbox once
+// delivered, so a notification queued before a restart is still sent.
+const createOutboxTable = `CREATE TABLE IF NOT EXISTS outbox (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	destination TEXT NOT NULL,
+	url TEXT NOT NULL,
+	topic TEXT NOT NULL,
+	payload BLOB NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at DATETIME NOT NULL,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	delivered_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON outbox(status, next_attempt_at);`
+
+// Outbox message states.
+const (
+	OutboxPending   = "pending"   // Waiting for its next delivery attempt
+	OutboxDelivered = "delivered" // Accepted by the destination
+	OutboxDead      = "dead"      // Given up on; kept for inspection and manual retry
+)
+
+// OutboxMessage is a notification queued for delivery to a webhook.
+type OutboxMessage struct {
+	ID            int64           `json:"id"`              // Message identifier, sent as the delivery ID
+	Destination   string          `json:"destination"`     // Name of the webhook the message is for
+	URL           string          `json:"url"`             // URL the message is POSTed to
+	Topic         string          `json:"topic"`           // Event topic
+	Payload       json.RawMessage `json:"payload"`         // JSON request body
+	Status        string          `json:"status"`          // OutboxPending, OutboxDelivered or OutboxDead
+	Attempts      int             `json:"attempts"`        // Delivery attempts made
+	NextAttemptAt time.Time       `json:"next_attempt_at"` // When a pending message is next tried
+	LastError     string          `json:"last_error"`      // Why the last attempt failed
+	CreatedAt     time.Time       `json:"created_at"`      // When the message was queued
+	DeliveredAt   *time.Time      `json:"delivered_at"`    // When the destination accepted it
+}
+
+// outboxColumns lists the columns read by scanOutboxMessage.
+const outboxColumns = `id, destination, url, topic, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at`
+
+// EnqueueOutbox queues messages for immediate delivery, in one
+// transaction.
+//
+// Parameters:
+//   - msgs: Messages with Destination, URL, Topic and Payload set
+//
+// Returns:
+//   - error: Any error encountered; on error nothing is queued
+func (c *SQLiteController) EnqueueOutbox(msgs []OutboxMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	now := c.now().UTC()
+	tx, err := c.db.Begin()
+	if err != nil {
+		c.logger.Error("Failed to begin outbox transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+	for _, m := range msgs {
+		if _, err := tx.Exec(`INSERT INTO outbox (destination, url, topic, payload, status, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			m.Destination, m.URL, m.Topic, []byte(m.Payload), OutboxPending, now, now); err != nil {
+			c.logger.Error("Failed to queue outbox message", "error", err, "destination", m.Destination, "topic", m.Topic)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DueOutbox returns pending messages whose next attempt is due, oldest
+// first.
+//
+// Parameters:
+//   - now: Messages due at or before this time are returned
+//   - limit: Maximum number of messages
+//
+// Returns:
+//   - []OutboxMessage: Due messages
+//   - error: Any error encountered during the query
+func (c *SQLiteController) DueOutbox(now time.Time, limit int) ([]OutboxMessage, error) {
+	return c.queryOutbox(`SELECT `+outboxColumns+` FROM outbox WHERE status = ? AND next_attempt_at <= ? ORDER BY id LIMIT ?`,
+		OutboxPending, now.UTC(), limit)
+}
+
+// ListOutbox returns the most recently queued messages, newest first.
+//
+// Parameters:
+//   - status: Only messages in this state; empty lists all
+//   - limit: Maximum number of messages
+//
+// Returns:
+//   - []OutboxMessage: Messages
+//   - error: Any error encountered during the query
+func (c *SQLiteController) ListOutbox(status string, limit int) ([]OutboxMessage, error) {
+	if status == "" {
+		return c.queryOutbox(`SELECT `+outboxColumns+` FROM outbox ORDER BY id DESC LIMIT ?`, limit)
+	}
+	return c.queryOutbox(`SELECT `+outboxColumns+` FROM outbox WHERE status = ? ORDER BY id DESC LIMIT ?`, status, limit)
+}
+
+// queryOutbox runs a SELECT of outboxColumns.
+func (c *SQLiteController) queryOutbox(query string, args ...any) ([]OutboxMessage, error) {
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		c.logger.Error("Failed to query outbox", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []OutboxMessage{}
+	for rows.Next() {
+		var (
+			m           OutboxMessage
+			payload     []byte
+			deliveredAt sql.NullTime
+		)
+		if err := rows.Scan(&m.ID, &m.Destination, &m.URL, &m.Topic, &payload, &m.Status, &m.Attempts,
+			&m.NextAttemptAt, &m.LastError, &m.CreatedAt, &deliveredAt); err != nil {
+			c.logger.Error("Failed to scan outbox row", "error", err)
+			return nil, err
+		}
+		m.Payload = json.RawMessage(payload)
+		m.NextAttemptAt = m.NextAttemptAt.UTC()
+		m.CreatedAt = m.CreatedAt.UTC()
+		m.DeliveredAt = nullTimePtr(deliveredAt)
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// CountOutbox returns the number of messages in each state.
+//
+// Returns:
+//   - map[string]int64: Message count per state, including empty states
+//   - error: Any error encountered during the query
+func (c *SQLiteController) CountOutbox() (map[string]int64, error) {
+	counts := map[string]int64{OutboxPending: 0, OutboxDelivered: 0, OutboxDead: 0}
+	rows, err := c.db.Query(`SELECT status, COUNT(*) FROM outbox GROUP BY status`)
+	if err != nil {
+		c.logger.Error("Failed to count outbox messages", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var n int64
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+// MarkOutboxDelivered records that the destination accepted a message.
+//
+// Parameters:
+//   - id: Message identifier
+//
+// Returns:
+//   - error: Any error encountered while writing
+func (c *SQLiteController) MarkOutboxDelivered(id int64) error {
+	_, err := c.db.Exec(`UPDATE outbox SET status = ?, attempts = attempts + 1, last_error = '', delivered_at = ? WHERE id = ?`,
+		OutboxDelivered, c.now().UTC(), id)
+	if err != nil {
+		c.logger.Error("Failed to mark outbox message delivered", "error", err, "id", id)
+	}
+	return err
+}
+
+// MarkOutboxFailed records a failed delivery attempt, scheduling the next
+// one or moving the message to the dead letters.
+//
+// Parameters:
+//   - id: Message identifier
+//   - reason: Why the attempt failed
+//   - retryAt: When to try again; the zero time dead-letters the message
+//
+// Returns:
+//   - error: Any error encountered while writing
+func (c *SQLiteController) MarkOutboxFailed(id int64, reason string, retryAt time.Time) error {
+	status, next := OutboxPending, retryAt.UTC()
+	if retryAt.IsZero() {
+		status, next = OutboxDead, c.now().UTC()
+	}
+	_, err := c.db.Exec(`UPDATE outbox SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?`,
+		status, reason, next, id)
+	if err != nil {
+		c.logger.Error("Failed to record outbox failure", "error", err, "id", id)
+	}
+	return err
+}
+
+// RetryOutbox makes a dead-lettered or pending message due immediately,
+// with its attempts reset.
+//
+// Parameters:
+//   - id: Message identifier
+//
+// Returns:
+//   - bool: Whether an undelivered message with this ID exists
+//   - error: Any error encountered while writing
+func (c *SQLiteController) RetryOutbox(id int64) (bool, error) {
+	res, err := c.db.Exec(`UPDATE outbox SET status = ?, attempts = 0, next_attempt_at = ? WHERE id = ? AND status != ?`,
+		OutboxPending, c.now().UTC(), id, OutboxDelivered)
+	if err != nil {
+		c.logger.Error("Failed to retry outbox message", "error", err, "id", id)
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// PruneOutbox deletes delivered messages queued before cutoff and returns
+// the number removed. Dead letters are kept until retried.
+func (c *SQLiteController) PruneOutbox(cutoff time.Time) (int64, error) {
+	res, err := c.db.Exec(`DELETE FROM outbox WHERE status = ? AND created_at < ?`, OutboxDelivered, cutoff.UTC())
+	if err != nil {
+		c.logger.Error("Failed to prune outbox", "error", err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
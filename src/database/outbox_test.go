@@ -0,0 +1,68 @@
+package database
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestOutboxLifecycle(t *testing.T) {
+	tempFile := "test_outbox.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	err = controller.EnqueueOutbox([]OutboxMessage{
+		{Destination: "a", URL: "https://example.com/a", Topic: "alert.fired", Payload: json.RawMessage(`{"n":1}`)},
+		{Destination: "b", URL: "https://example.com/b", Topic: "alert.fired", Payload: json.RawMessage(`{"n":2}`)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+
+	now := time.Now()
+	due, err := controller.DueOutbox(now, 10)
+	if err != nil || len(due) != 2 || string(due[0].Payload) != `{"n":1}` || due[0].Status != OutboxPending {
+		t.Fatalf("Expected both messages due, oldest first, got %+v, %v", due, err)
+	}
+
+	if err := controller.MarkOutboxDelivered(due[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := controller.MarkOutboxFailed(due[1].ID, "503", now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if due, _ := controller.DueOutbox(now, 10); len(due) != 0 {
+		t.Errorf("Expected nothing due before the retry time, got %+v", due)
+	}
+	if due, _ := controller.DueOutbox(now.Add(time.Minute), 10); len(due) != 1 || due[0].Attempts != 1 || due[0].LastError != "503" {
+		t.Errorf("Expected the failed message due at its retry time, got %+v", due)
+	}
+
+	if err := controller.MarkOutboxFailed(due[1].ID, "400", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	counts, err := controller.CountOutbox()
+	if err != nil || counts[OutboxDelivered] != 1 || counts[OutboxDead] != 1 || counts[OutboxPending] != 0 {
+		t.Errorf("Unexpected counts %v, %v", counts, err)
+	}
+	if ok, _ := controller.RetryOutbox(due[0].ID); ok {
+		t.Error("Expected a delivered message not to be retried")
+	}
+
+	// Delivered messages are pruned; dead letters are kept
+	removed, err := controller.PruneOutbox(now.Add(time.Hour))
+	if err != nil || removed != 1 {
+		t.Errorf("Expected 1 delivered message pruned, got %d, %v", removed, err)
+	}
+	if all, _ := controller.ListOutbox("", 10); len(all) != 1 || all[0].Status != OutboxDead {
+		t.Errorf("Expected only the dead letter left, got %+v", all)
+	}
+}
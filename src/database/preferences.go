@@ -0,0 +1,107 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// createPreferencesTable holds the DDL for per-user dashboard preferences.
+const createPreferencesTable = `CREATE TABLE IF NOT EXISTS preferences (
+	token TEXT PRIMARY KEY,
+	timezone TEXT NOT NULL DEFAULT '',
+	default_range_hours INTEGER NOT NULL DEFAULT 24,
+	favorite_datasets TEXT NOT NULL DEFAULT '[]',
+	units TEXT NOT NULL DEFAULT '',
+	locale TEXT NOT NULL DEFAULT '',
+	updated_at DATETIME NOT NULL
+);`
+
+// Preferences holds dashboard defaults for a single user or browser token.
+type Preferences struct {
+	Token             string    `json:"-"`                   // Opaque user or browser token (never serialized)
+	Timezone          string    `json:"timezone"`            // IANA time zone name; empty means the browser's zone
+	DefaultRangeHours int       `json:"default_range_hours"` // Time range selected when the dashboard loads
+	FavoriteDatasets  []string  `json:"favorite_datasets"`   // Datasets pinned by the user
+	Units             string    `json:"units"`               // "binary" (KiB, MiB) or "decimal" (KB, MB); empty follows the instance's units
+	Locale            string    `json:"locale"`              // Language of server-rendered pages, such as "de"; empty follows Accept-Language
+	UpdatedAt         time.Time `json:"updated_at"`          // When the preferences were last saved
+}
+
+// DefaultPreferences returns the preferences used when a token has not saved
+// any of its own.
+func DefaultPreferences() Preferences {
+	return Preferences{
+		DefaultRangeHours: 24,
+		FavoriteDatasets:  []string{},
+	}
+}
+
+// GetPreferences loads the preferences saved for token. If none exist the
+// defaults are returned with found set to false.
+//
+// Parameters:
+//   - token: User or browser token
+//
+// Returns:
+//   - Preferences: Saved or default preferences
+//   - bool: Whether preferences were found for the token
+//   - error: Any error encountered during the query
+func (c *SQLiteController) GetPreferences(token string) (Preferences, bool, error) {
+	p := DefaultPreferences()
+	p.Token = token
+
+	var favorites string
+	err := c.db.QueryRow(`SELECT timezone, default_range_hours, favorite_datasets, units, locale, updated_at FROM preferences WHERE token = ?`, token).
+		Scan(&p.Timezone, &p.DefaultRangeHours, &favorites, &p.Units, &p.Locale, &p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, false, nil
+	}
+	if err != nil {
+		c.logger.Error("Failed to query preferences", "error", err)
+		return p, false, err
+	}
+	p.UpdatedAt = p.UpdatedAt.UTC()
+	if err := json.Unmarshal([]byte(favorites), &p.FavoriteDatasets); err != nil {
+		c.logger.Error("Failed to decode favorite datasets", "error", err)
+		return p, false, err
+	}
+	return p, true, nil
+}
+
+// SavePreferences creates or replaces the preferences for p.Token and sets
+// p.UpdatedAt to the current time.
+//
+// Parameters:
+//   - p: Preferences to store
+//
+// Returns:
+//   - Preferences: The stored preferences including the new UpdatedAt
+//   - error: Any error encountered during the write
+func (c *SQLiteController) SavePreferences(p Preferences) (Preferences, error) {
+	if p.FavoriteDatasets == nil {
+		p.FavoriteDatasets = []string{}
+	}
+	favorites, err := json.Marshal(p.FavoriteDatasets)
+	if err != nil {
+		return p, err
+	}
+	p.UpdatedAt = c.now().UTC()
+
+	_, err = c.db.Exec(`INSERT INTO preferences (token, timezone, default_range_hours, favorite_datasets, units, locale, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(token) DO UPDATE SET
+			timezone = excluded.timezone,
+			default_range_hours = excluded.default_range_hours,
+			favorite_datasets = excluded.favorite_datasets,
+			units = excluded.units,
+			locale = excluded.locale,
+			updated_at = excluded.updated_at`,
+		p.Token, p.Timezone, p.DefaultRangeHours, string(favorites), p.Units, p.Locale, p.UpdatedAt)
+	if err != nil {
+		c.logger.Error("Failed to save preferences", "error", err)
+		return p, err
+	}
+	c.logger.Info("Preferences saved")
+	return p, nil
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestPreferencesRoundTrip(t *testing.T) {
+	tempFile := "test_preferences.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	prefs, found, err := controller.GetPreferences("abc")
+	if err != nil {
+		t.Fatalf("Failed to get preferences: %v", err)
+	}
+	if found || prefs.DefaultRangeHours != 24 || prefs.Units != "" {
+		t.Errorf("Expected defaults for unknown token, got %+v (found=%v)", prefs, found)
+	}
+
+	prefs.Timezone = "Europe/Berlin"
+	prefs.DefaultRangeHours = 168
+	prefs.FavoriteDatasets = []string{"http_requests", "firewall_events"}
+	prefs.Units = "decimal"
+	prefs.Locale = "fr"
+	if _, err := controller.SavePreferences(prefs); err != nil {
+		t.Fatalf("Failed to save preferences: %v", err)
+	}
+
+	// Saving again must update rather than duplicate
+	prefs.DefaultRangeHours = 6
+	if _, err := controller.SavePreferences(prefs); err != nil {
+		t.Fatalf("Failed to update preferences: %v", err)
+	}
+
+	got, found, err := controller.GetPreferences("abc")
+	if err != nil {
+		t.Fatalf("Failed to get preferences: %v", err)
+	}
+	if !found {
+		t.Fatal("Expected saved preferences to be found")
+	}
+	if got.Timezone != "Europe/Berlin" || got.DefaultRangeHours != 6 || got.Units != "decimal" || got.Locale != "fr" || len(got.FavoriteDatasets) != 2 {
+		t.Errorf("Unexpected preferences after round trip: %+v", got)
+	}
+	if got.UpdatedAt.IsZero() {
+		t.Error("Expected UpdatedAt to be set")
+	}
+}
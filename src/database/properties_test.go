@@ -0,0 +1,135 @@
+package database
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// batchSample is a random set of batches spread over six hours before
+// propertyEnd, split across three tenants.
+type batchSample []LogSize
+
+// propertyEnd is the exclusive end of the window batchSample draws from.
+var propertyEnd = time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+// Generate implements quick.Generator.
+func (batchSample) Generate(r *rand.Rand, size int) reflect.Value {
+	tenants := []string{"", "acme", "globex"}
+	batches := make(batchSample, r.Intn(size+1))
+	for i := range batches {
+		batches[i] = LogSize{
+			// Whole seconds, so many batches share a minute.
+			Timestamp:   propertyEnd.Add(-time.Duration(r.Intn(6*60*60)+1) * time.Second),
+			Filesize:    r.Int63n(1 << 30),
+			RecordCount: r.Int63n(10000),
+			Tenant:      tenants[r.Intn(len(tenants))],
+		}
+	}
+	return reflect.ValueOf(batches)
+}
+
+// rawMinuteAggregates groups batches of tenant (all tenants when empty)
+// into minute buckets the way the rollup should.
+func rawMinuteAggregates(batches []LogSize, tenant string) map[time.Time]MinuteAggregate {
+	out := make(map[time.Time]MinuteAggregate)
+	for _, b := range batches {
+		if tenant != "" && b.Tenant != tenant {
+			continue
+		}
+		minute := b.Timestamp.UTC().Truncate(time.Minute)
+		m := out[minute]
+		m.Minute = minute
+		m.Batches++
+		m.Records += b.RecordCount
+		m.TotalSize += b.Filesize
+		out[minute] = m
+	}
+	return out
+}
+
+// TestPropertyRollupsMatchRawAggregation inserts random batches and checks
+// the per-minute rollup, the tenant-scoped minute buckets, range counts and
+// tenant usage all agree with aggregating the raw batches.
+func TestPropertyRollupsMatchRawAggregation(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	dir := t.TempDir()
+	seed := time.Now().UnixNano()
+	t.Logf("property seed %d", seed)
+	run := 0
+
+	property := func(batches batchSample) bool {
+		run++
+		controller, err := NewSQLiteController(filepath.Join(dir, fmt.Sprintf("run%d.db", run)), logger)
+		if err != nil {
+			t.Fatalf("Failed to create SQLiteController: %v", err)
+		}
+		defer controller.Close()
+		for _, b := range batches {
+			if err := controller.InsertLog(b); err != nil {
+				t.Fatalf("Failed to insert log: %v", err)
+			}
+		}
+		start := propertyEnd.Add(-7 * time.Hour)
+
+		for _, tenant := range []string{"", "acme", "globex"} {
+			want := rawMinuteAggregates(batches, tenant)
+			got, err := controller.ForTenant(tenant).QueryMinuteAggregates(start, propertyEnd)
+			if err != nil {
+				t.Fatalf("Failed to query minute aggregates: %v", err)
+			}
+			if len(got) != len(want) {
+				t.Logf("tenant %q: %d minute buckets, raw %d", tenant, len(got), len(want))
+				return false
+			}
+			for _, m := range got {
+				if want[m.Minute] != m {
+					t.Logf("tenant %q: bucket %+v, raw %+v", tenant, m, want[m.Minute])
+					return false
+				}
+			}
+		}
+
+		var wantCount LogCount
+		for _, b := range batches {
+			wantCount.Batches++
+			wantCount.Records += b.RecordCount
+			wantCount.Bytes += b.Filesize
+		}
+		count, err := controller.CountByTimeRange(start, propertyEnd)
+		if err != nil {
+			t.Fatalf("Failed to count: %v", err)
+		}
+		if count != wantCount {
+			t.Logf("count %+v, raw %+v", count, wantCount)
+			return false
+		}
+
+		usage, err := controller.QueryTenantUsage(start, propertyEnd)
+		if err != nil {
+			t.Fatalf("Failed to query tenant usage: %v", err)
+		}
+		var usageCount LogCount
+		for _, u := range usage {
+			usageCount.Batches += u.Records
+			usageCount.Records += u.RecordCount
+			usageCount.Bytes += u.TotalSize
+		}
+		if usageCount != wantCount {
+			t.Logf("tenant usage totals %+v, raw %+v", usageCount, wantCount)
+			return false
+		}
+		return true
+	}
+
+	cfg := &quick.Config{MaxCount: 25, Rand: rand.New(rand.NewSource(seed))}
+	if err := quick.Check(property, cfg); err != nil {
+		t.Error(err)
+	}
+}
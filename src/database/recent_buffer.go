@@ -0,0 +1,223 @@
+package database
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultRecentBufferSize is the number of most recently ingested log
+// records each controller keeps in memory.
+const DefaultRecentBufferSize = 10000
+
+// recentBuffer is a ring buffer of the most recently inserted log records.
+// It lets QueryByTimeRange and QuerySince answer the requests dashboards and
+// live feeds make most often, for the latest records, without reading the
+// database.
+//
+// The buffer only answers a query when it provably holds every matching
+// record: it tracks the highest ID and latest timestamp of the stored records
+// it does not hold, and defers to the database whenever a query could reach
+// them. Operations that remove or re-insert records outside InsertLog
+// invalidate it, and it is primed again from the database on the next read.
+// Only writes made through the same controller (or its ForTenant copies) are
+// seen, so a database shared with another process must disable the buffer.
+type recentBuffer struct {
+	writeMu sync.Mutex // Held across an insert and its add so records enter in ID order
+
+	mu       sync.RWMutex
+	capacity int
+	records  []LogSize // Ring storage in ID order starting at head
+	head     int       // Index of the oldest record
+	count    int       // Number of records held
+	primed   bool      // Whether the contents reflect the database
+
+	hasOlder     bool      // Whether stored records exist that the buffer does not hold
+	olderMaxID   int64     // Highest ID of those records
+	olderMaxTime time.Time // Latest timestamp of those records
+}
+
+// newRecentBuffer creates an empty, unprimed buffer holding up to capacity
+// records; a capacity of 0 disables it.
+func newRecentBuffer(capacity int) *recentBuffer {
+	return &recentBuffer{capacity: capacity, records: make([]LogSize, capacity)}
+}
+
+// at returns the i-th oldest record held. The caller holds mu.
+func (b *recentBuffer) at(i int) LogSize {
+	return b.records[(b.head+i)%b.capacity]
+}
+
+// add appends a record just committed by InsertLog, evicting the oldest
+// record when full. The caller holds writeMu. Records added while the buffer
+// is unprimed are read from the database when it is primed.
+func (b *recentBuffer) add(l LogSize) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.primed || b.capacity == 0 {
+		return
+	}
+	if b.count < b.capacity {
+		b.records[(b.head+b.count)%b.capacity] = l
+		b.count++
+		return
+	}
+	evicted := b.records[b.head]
+	b.hasOlder = true
+	b.olderMaxID = max(b.olderMaxID, evicted.ID)
+	if evicted.Timestamp.After(b.olderMaxTime) {
+		b.olderMaxTime = evicted.Timestamp
+	}
+	b.records[b.head] = l
+	b.head = (b.head + 1) % b.capacity
+}
+
+// invalidate discards the contents after records were deleted or inserted
+// outside InsertLog.
+func (b *recentBuffer) invalidate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reset()
+}
+
+// reset empties the buffer and marks it unprimed. The caller holds mu.
+func (b *recentBuffer) reset() {
+	b.head, b.count, b.primed = 0, 0, false
+	b.hasOlder, b.olderMaxID, b.olderMaxTime = false, 0, time.Time{}
+}
+
+// resize changes the capacity and invalidates the buffer.
+func (b *recentBuffer) resize(capacity int) {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.capacity = capacity
+	b.records = make([]LogSize, capacity)
+	b.reset()
+}
+
+// prime loads the most recent records from db if the buffer is unprimed.
+// Inserts are held off meanwhile so none is both loaded and added.
+func (b *recentBuffer) prime(db *sql.DB) error {
+	b.mu.RLock()
+	ready := b.primed || b.capacity == 0
+	b.mu.RUnlock()
+	if ready {
+		return nil
+	}
+
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.primed || b.capacity == 0 {
+		return nil
+	}
+
+	rows, err := db.Query(`SELECT `+logSizeSelectColumns+` FROM log_sizes ORDER BY id DESC LIMIT ?`, b.capacity)
+	if err != nil {
+		return err
+	}
+	var latest []LogSize
+	for rows.Next() {
+		l, err := scanLogSize(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		latest = append(latest, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	b.reset()
+	for i := len(latest) - 1; i >= 0; i-- {
+		b.records[b.count] = latest[i]
+		b.count++
+	}
+	if b.count == b.capacity {
+		oldest := latest[len(latest)-1].ID
+		var ts time.Time
+		err := db.QueryRow(`SELECT timestamp FROM log_sizes WHERE id < ? ORDER BY timestamp DESC LIMIT 1`, oldest).Scan(&ts)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return err
+		default:
+			b.hasOlder, b.olderMaxID, b.olderMaxTime = true, oldest-1, ts.UTC()
+		}
+	}
+	b.primed = true
+	return nil
+}
+
+// queryRange returns the held records of tenant, dataset and zone (all
+// when empty) carrying labels, with timestamps in [start, end), ordered by
+// timestamp, and whether the buffer could answer.
+func (b *recentBuffer) queryRange(start, end time.Time, tenant, dataset, zone string, labels map[string]string) ([]LogSize, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if !b.primed || (b.hasOlder && !b.olderMaxTime.Before(start)) {
+		return nil, false
+	}
+	var out []LogSize
+	for i := 0; i < b.count; i++ {
+		l := b.at(i)
+		if !l.Timestamp.Before(start) && l.Timestamp.Before(end) && matchesScope(l, tenant, dataset, zone, labels) {
+			out = append(out, l)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Timestamp.Before(out[j].Timestamp) })
+	return out, true
+}
+
+// querySince returns up to limit held records of tenant, dataset and zone
+// carrying labels with an ID greater than id, ordered by ID, and whether the
+// buffer could answer.
+func (b *recentBuffer) querySince(id int64, limit int, tenant, dataset, zone string, labels map[string]string) ([]LogSize, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if !b.primed || (b.hasOlder && b.olderMaxID > id) {
+		return nil, false
+	}
+	var out []LogSize
+	for i := 0; i < b.count && len(out) < limit; i++ {
+		l := b.at(i)
+		if l.ID > id && matchesScope(l, tenant, dataset, zone, labels) {
+			out = append(out, l)
+		}
+	}
+	return out, true
+}
+
+// matchesScope reports whether l belongs to tenant, dataset and zone, each
+// of which matches everything when empty, and carries labels.
+func matchesScope(l LogSize, tenant, dataset, zone string, labels map[string]string) bool {
+	return (tenant == "" || l.Tenant == tenant) && (dataset == "" || l.Dataset == dataset) && (zone == "" || l.Zone == zone) &&
+		hasLabels(l, labels)
+}
+
+// SetRecentBufferSize changes how many recent records are kept in memory
+// for QueryByTimeRange and QuerySince. A size of 0 disables the buffer, which
+// is required when other processes write to the same database file.
+//
+// Parameters:
+//   - size: Number of records to keep (default DefaultRecentBufferSize)
+func (c *SQLiteController) SetRecentBufferSize(size int) {
+	c.recent.resize(max(size, 0))
+	c.logger.Info("Recent record buffer resized", "size", size)
+}
+
+// recentReady primes the recent buffer if needed and reports whether it can
+// be consulted. Priming failures fall back to the database.
+func (c *SQLiteController) recentReady() bool {
+	if err := c.recent.prime(c.db); err != nil {
+		c.logger.Warn("Failed to prime recent record buffer", "error", err)
+		return false
+	}
+	return true
+}
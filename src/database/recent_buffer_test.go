@@ -0,0 +1,105 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRecentBufferServesLatestRecords(t *testing.T) {
+	tempFile := "test_recent_buffer.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+	controller.SetRecentBufferSize(3)
+
+	base := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		entry := LogSize{Timestamp: base.Add(time.Duration(i) * time.Minute), Filesize: int64(100 + i)}
+		if i == 4 {
+			entry.Tenant = "acme"
+		}
+		if err := controller.InsertLog(entry); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+
+	// The buffer was primed by the first read, so compare it with the database
+	if _, ok := controller.recent.queryRange(base, base.Add(time.Hour), "", "", "", nil); ok {
+		t.Fatal("Expected an unprimed buffer before the first read")
+	}
+	logs, err := controller.QueryByTimeRange(base.Add(2*time.Minute), base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query range: %v", err)
+	}
+	if len(logs) != 3 || logs[0].Filesize != 102 || logs[2].Tenant != "acme" {
+		t.Errorf("Expected the last three records, got %+v", logs)
+	}
+	if _, ok := controller.recent.queryRange(base.Add(2*time.Minute), base.Add(time.Hour), "", "", "", nil); !ok {
+		t.Error("Expected the buffer to cover the last three minutes")
+	}
+
+	// Ranges reaching evicted records fall back to the database
+	if _, ok := controller.recent.queryRange(base, base.Add(time.Hour), "", "", "", nil); ok {
+		t.Error("Expected the buffer to refuse a range covering evicted records")
+	}
+	logs, err = controller.QueryByTimeRange(base, base.Add(time.Hour))
+	if err != nil || len(logs) != 5 {
+		t.Errorf("Expected all 5 records from the database, got %d (%v)", len(logs), err)
+	}
+
+	// A new insert evicts the oldest held record
+	if err := controller.InsertLog(LogSize{Timestamp: base.Add(5 * time.Minute), Filesize: 105}); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+	since, ok := controller.recent.querySince(3, 10, "", "", "", nil)
+	if !ok || len(since) != 3 || since[0].ID != 4 || since[2].Filesize != 105 {
+		t.Errorf("Expected records 4-6 from the buffer, got %+v (%v)", since, ok)
+	}
+	if _, ok := controller.recent.querySince(2, 10, "", "", "", nil); ok {
+		t.Error("Expected the buffer to refuse a cursor before its oldest record")
+	}
+	scoped, err := controller.ForTenant("acme").QuerySince(0, 10)
+	if err != nil || len(scoped) != 1 || scoped[0].ID != 5 {
+		t.Errorf("Expected acme's record only, got %+v (%v)", scoped, err)
+	}
+
+	// Deleting records must not leave them visible in memory
+	if _, err := controller.DeleteByTimeRange(base.Add(4*time.Minute), base.Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to delete range: %v", err)
+	}
+	since, err = controller.QuerySince(3, 10)
+	if err != nil || len(since) != 1 || since[0].ID != 4 {
+		t.Errorf("Expected only record 4 after the delete, got %+v (%v)", since, err)
+	}
+}
+
+func TestRecentBufferDisabled(t *testing.T) {
+	tempFile := "test_recent_buffer_disabled.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+	controller.SetRecentBufferSize(0)
+
+	if err := controller.InsertLogSize(100); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+	logs, err := controller.QuerySince(0, 10)
+	if err != nil || len(logs) != 1 {
+		t.Errorf("Expected the record from the database, got %+v (%v)", logs, err)
+	}
+	if _, ok := controller.recent.querySince(0, 10, "", "", "", nil); ok {
+		t.Error("Expected a disabled buffer never to answer")
+	}
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Recovery reports the scan run at startup by Recover. After a clean
+// shutdown nothing is checked; after an unclean one the database file is
+// checked, derived counters are rebuilt from the raw records, and the
+// batches waiting in the relay queue and webhook outbox, which are retried
+// as soon as the background jobs start, are counted.
+type Recovery struct {
+	CheckedAt     time.Time     `json:"checked_at"`            // When the scan ran
+	Unclean       bool          `json:"unclean"`               // Whether the previous process exited without shutting down cleanly
+	Signs         []string      `json:"signs"`                 // What showed the shutdown was unclean; empty after a clean one
+	QuickCheck    []string      `json:"quick_check,omitempty"` // Problems reported by SQLite's quick_check; empty when the file is intact
+	Integrity     *IntegrityRun `json:"integrity,omitempty"`   // Rollup check, repaired unless read-only; nil after a clean shutdown
+	RelayQueued   int64         `json:"relay_queued"`          // Batches waiting to be relayed upstream
+	OutboxPending int64         `json:"outbox_pending"`        // Webhook notifications waiting to be delivered
+	Error         string        `json:"error,omitempty"`       // Why the scan stopped early, if it did
+}
+
+// recoveryState holds the last Recovery, shared with ForTenant copies.
+type recoveryState struct {
+	mu   sync.Mutex
+	last *Recovery
+}
+
+// ShutdownSigns inspects a database and its lock before the database is
+// opened, and describes any sign that the process that last used it did
+// not shut down cleanly: the process ID it left in the lock file, a
+// write-ahead log that was never checkpointed, or a rollback journal left
+// by an interrupted transaction. SQLite replays or rolls back the journals
+// when the database is opened, so they must be looked at first.
+//
+// Parameters:
+//   - path: Database file path
+//   - lock: Lock just taken on the database with LockDatabase
+//
+// Returns:
+//   - []string: Signs of an unclean shutdown, empty after a clean one
+func ShutdownSigns(path string, lock *FileLock) []string {
+	signs := []string{}
+	if pid := lock.PreviousHolder(); pid != "" {
+		signs = append(signs, fmt.Sprintf("process %s did not release %s.lock", pid, path))
+	}
+	if info, err := os.Stat(path + "-wal"); err == nil && info.Size() > 0 {
+		signs = append(signs, fmt.Sprintf("write-ahead log %s-wal holds %d bytes that were not checkpointed", path, info.Size()))
+	}
+	if info, err := os.Stat(path + "-journal"); err == nil && info.Size() > 0 {
+		signs = append(signs, fmt.Sprintf("rollback journal %s-journal was left by an interrupted transaction", path))
+	}
+	return signs
+}
+
+// Recover runs the startup recovery scan and keeps its report for
+// LastRecovery. With no signs of an unclean shutdown it only records that
+// the shutdown was clean. Otherwise it runs SQLite's quick_check, rebuilds
+// the per-minute rollup from the raw records through CheckIntegrity, and
+// counts the relay and outbox batches the background jobs will replay.
+//
+// Parameters:
+//   - signs: Signs of an unclean shutdown from ShutdownSigns
+//   - repair: Whether to rewrite drifted rollups; false only reports them,
+//     for read-only instances
+//
+// Returns:
+//   - Recovery: The scan's report, also when it failed part way
+//   - error: Any error encountered while running the checks
+func (c *SQLiteController) Recover(signs []string, repair bool) (Recovery, error) {
+	rec := Recovery{CheckedAt: c.now().UTC(), Unclean: len(signs) > 0, Signs: append([]string{}, signs...)}
+	err := c.recover(&rec, repair)
+	if err != nil {
+		rec.Error = err.Error()
+		c.logger.Error("Recovery scan failed", "error", err)
+	}
+	c.recovery.mu.Lock()
+	c.recovery.last = &rec
+	c.recovery.mu.Unlock()
+	return rec, err
+}
+
+// recover fills in rec after an unclean shutdown.
+func (c *SQLiteController) recover(rec *Recovery, repair bool) error {
+	if !rec.Unclean {
+		c.logger.Info("Previous shutdown was clean; no recovery needed")
+		return nil
+	}
+	c.logger.Warn("Previous shutdown was unclean; running recovery scan", "signs", rec.Signs)
+
+	rows, err := c.db.Query(`PRAGMA quick_check`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			rows.Close()
+			return err
+		}
+		if result != "ok" {
+			rec.QuickCheck = append(rec.QuickCheck, result)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(rec.QuickCheck) > 0 {
+		c.logger.Error("Database file is damaged; restore it from a backup", "problems", rec.QuickCheck)
+	}
+
+	// Rollup counters are written in the same transaction as their
+	// records, so drift here comes from edits made while the process was
+	// down; rebuilding covers it either way
+	run, err := c.CheckIntegrity(repair)
+	if err != nil {
+		return err
+	}
+	rec.Integrity = &run
+
+	queue, err := c.RelayQueueSize()
+	if err != nil {
+		return err
+	}
+	outbox, err := c.CountOutbox()
+	if err != nil {
+		return err
+	}
+	rec.RelayQueued, rec.OutboxPending = queue.Batches, outbox[OutboxPending]
+
+	c.logger.Warn("Recovery scan finished", "damaged", len(rec.QuickCheck) > 0, "rollup_repairs", len(run.Issues),
+		"relay_queued", rec.RelayQueued, "outbox_pending", rec.OutboxPending)
+	return nil
+}
+
+// LastRecovery returns the report of the last Recover call.
+//
+// Returns:
+//   - Recovery: The report
+//   - bool: False when Recover has not run
+func (c *SQLiteController) LastRecovery() (Recovery, bool) {
+	c.recovery.mu.Lock()
+	defer c.recovery.mu.Unlock()
+	if c.recovery.last == nil {
+		return Recovery{}, false
+	}
+	return *c.recovery.last, true
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logpush.db")
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// A clean start shows no signs and checks nothing
+	lock, err := LockDatabase(path)
+	if err != nil {
+		t.Fatalf("Failed to lock database: %v", err)
+	}
+	if signs := ShutdownSigns(path, lock); len(signs) != 0 {
+		t.Errorf("Expected no signs on first use, got %q", signs)
+	}
+	db, err := NewSQLiteController(path, logger)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	if _, ok := db.LastRecovery(); ok {
+		t.Error("Expected no recovery report before Recover")
+	}
+	if rec, err := db.Recover(nil, true); err != nil || rec.Unclean || rec.Integrity != nil || rec.Signs == nil {
+		t.Errorf("Expected a clean report, got %+v, %v", rec, err)
+	}
+
+	minute := time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+	if err := db.InsertLog(LogSize{Timestamp: minute, Filesize: 100, RecordCount: 2}); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+	if _, err := db.EnqueueRelay(RelayBatch{Body: []byte("queued"), LastError: "timeout"}, time.Now()); err != nil {
+		t.Fatalf("Failed to queue relay batch: %v", err)
+	}
+
+	// A process that exits without releasing its lock, with a rollup that
+	// fell behind its records and a journal left beside the database
+	if _, err := db.db.Exec(`UPDATE minute_aggregates SET batches = 0, total_size = 0`); err != nil {
+		t.Fatalf("Failed to corrupt rollup: %v", err)
+	}
+	lock.file.Close()
+	if err := os.WriteFile(path+"-journal", []byte("journal"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path + "-journal")
+	lock, err = LockDatabase(path)
+	if err != nil {
+		t.Fatalf("Failed to lock database: %v", err)
+	}
+	defer lock.Release()
+	signs := ShutdownSigns(path, lock)
+	if len(signs) != 2 {
+		t.Fatalf("Expected the unreleased lock and the journal as signs, got %q", signs)
+	}
+
+	rec, err := db.Recover(signs, true)
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if !rec.Unclean || len(rec.QuickCheck) != 0 || rec.Integrity == nil || len(rec.Integrity.Issues) != 1 ||
+		!rec.Integrity.Issues[0].Repaired || rec.RelayQueued != 1 || rec.OutboxPending != 0 {
+		t.Errorf("Unexpected recovery report %+v", rec)
+	}
+	if last, ok := db.LastRecovery(); !ok || len(last.Signs) != 2 {
+		t.Errorf("Expected the report kept, got %+v", last)
+	}
+	if run, err := db.CheckIntegrity(false); err != nil || len(run.Issues) != 0 {
+		t.Errorf("Expected the rollup rebuilt, got %+v, %v", run.Issues, err)
+	}
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"time"
+)
+
+// createRelayForwardsTable holds the DDL for hourly counts and latencies of
+// the requests relay mode sends upstream, per destination and status code.
+// Status 0 counts requests that got no response.
+const createRelayForwardsTable = `CREATE TABLE IF NOT EXISTS relay_forwards (
+	hour DATETIME NOT NULL,
+	destination TEXT NOT NULL,
+	status INTEGER NOT NULL,
+	requests INTEGER NOT NULL DEFAULT 0,
+	latency_ms_sum REAL NOT NULL DEFAULT 0,
+	latency_ms_max REAL NOT NULL DEFAULT 0,
+	PRIMARY KEY (hour, destination, status)
+);`
+
+// RelayForwardHour is the forwarding requests to one destination that got
+// one status code in one hour.
+type RelayForwardHour struct {
+	Hour         time.Time // Start of the hour, UTC
+	Destination  string    // Upstream host
+	Status       int       // HTTP status code; 0 when no response was received
+	Requests     int64     // Forwarding requests
+	LatencyMsSum float64   // Sum of their latencies in milliseconds
+	LatencyMsMax float64   // Slowest of them in milliseconds
+}
+
+// RecordRelayForward adds one forwarding request to the current hour's
+// counters for its destination and status.
+//
+// Parameters:
+//   - destination: Upstream host
+//   - status: HTTP status code, or 0 when the upstream did not respond
+//   - latency: Time from sending the request to reading the response
+//
+// Returns:
+//   - error: Any error encountered while updating the counters
+func (c *SQLiteController) RecordRelayForward(destination string, status int, latency time.Duration) error {
+	hour := c.now().UTC().Truncate(time.Hour)
+	ms := float64(latency) / float64(time.Millisecond)
+	_, err := c.db.Exec(`INSERT INTO relay_forwards (hour, destination, status, requests, latency_ms_sum, latency_ms_max)
+		VALUES (?, ?, ?, 1, ?, ?)
+		ON CONFLICT(hour, destination, status) DO UPDATE SET requests = requests + 1,
+			latency_ms_sum = latency_ms_sum + excluded.latency_ms_sum,
+			latency_ms_max = MAX(latency_ms_max, excluded.latency_ms_max)`,
+		hour, destination, status, ms, ms)
+	if err != nil {
+		c.logger.Error("Failed to record relay forward", "error", err, "destination", destination, "status", status)
+	}
+	return err
+}
+
+// RelayForwardsByTimeRange returns the hourly forwarding counters of the
+// hours starting in [start, end).
+//
+// Parameters:
+//   - start: Start time (inclusive, truncated to the hour)
+//   - end: End time (exclusive)
+//
+// Returns:
+//   - []RelayForwardHour: Counters ordered by destination, hour and status
+//   - error: Any error encountered during the query
+func (c *SQLiteController) RelayForwardsByTimeRange(start, end time.Time) ([]RelayForwardHour, error) {
+	rows, err := c.db.Query(`SELECT hour, destination, status, requests, latency_ms_sum, latency_ms_max
+		FROM relay_forwards WHERE hour >= ? AND hour < ? ORDER BY destination, hour, status`,
+		start.UTC().Truncate(time.Hour), end.UTC())
+	if err != nil {
+		c.logger.Error("Failed to query relay forwards", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []RelayForwardHour{}
+	for rows.Next() {
+		var h RelayForwardHour
+		if err := rows.Scan(&h.Hour, &h.Destination, &h.Status, &h.Requests, &h.LatencyMsSum, &h.LatencyMsMax); err != nil {
+			c.logger.Error("Failed to scan relay forward row", "error", err)
+			return nil, err
+		}
+		h.Hour = h.Hour.UTC()
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/clock"
+)
+
+func TestRelayForwards(t *testing.T) {
+	tempFile := "test_relay_forwards.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	hour := time.Date(2025, 9, 15, 12, 0, 0, 0, time.UTC)
+	now := hour.Add(10 * time.Minute)
+	controller.SetClock(clock.Func(func() time.Time { return now }))
+
+	for _, f := range []struct {
+		destination string
+		status      int
+		latency     time.Duration
+	}{
+		{"hec.example.com", 200, 40 * time.Millisecond},
+		{"hec.example.com", 200, 100 * time.Millisecond},
+		{"hec.example.com", 0, 30 * time.Second},
+		{"bucket.s3.amazonaws.com", 200, 5 * time.Millisecond},
+	} {
+		if err := controller.RecordRelayForward(f.destination, f.status, f.latency); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = hour.Add(time.Hour)
+	controller.RecordRelayForward("hec.example.com", 503, time.Millisecond)
+
+	rows, err := controller.RelayForwardsByTimeRange(hour.Add(30*time.Minute), hour.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("RelayForwardsByTimeRange failed: %v", err)
+	}
+	want := []RelayForwardHour{
+		{Hour: hour, Destination: "bucket.s3.amazonaws.com", Status: 200, Requests: 1, LatencyMsSum: 5, LatencyMsMax: 5},
+		{Hour: hour, Destination: "hec.example.com", Status: 0, Requests: 1, LatencyMsSum: 30000, LatencyMsMax: 30000},
+		{Hour: hour, Destination: "hec.example.com", Status: 200, Requests: 2, LatencyMsSum: 140, LatencyMsMax: 100},
+		{Hour: hour.Add(time.Hour), Destination: "hec.example.com", Status: 503, Requests: 1, LatencyMsSum: 1, LatencyMsMax: 1},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("Expected %d rows, got %+v", len(want), rows)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("Row %d: expected %+v, got %+v", i, want[i], rows[i])
+		}
+	}
+	if rows, _ := controller.RelayForwardsByTimeRange(hour, hour.Add(time.Hour)); len(rows) != 3 {
+		t.Errorf("Expected the end hour excluded, got %+v", rows)
+	}
+}
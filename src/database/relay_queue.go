@@ -0,0 +1,212 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// createRelayQueueTable holds the DDL for batches that could not be relayed
+// to the upstream destination and wait to be retried, and for hourly counts
+// of relay outcomes. Batches are removed once the upstream accepts or
+// refuses them, so the queue only holds undelivered bodies.
+const createRelayQueueTable = `CREATE TABLE IF NOT EXISTS relay_queue (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	content_type TEXT NOT NULL DEFAULT '',
+	content_encoding TEXT NOT NULL DEFAULT '',
+	body BLOB NOT NULL,
+	size INTEGER NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at DATETIME NOT NULL,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_relay_queue_due ON relay_queue(next_attempt_at);
+CREATE TABLE IF NOT EXISTS relay_outcomes (
+	hour DATETIME NOT NULL,
+	outcome TEXT NOT NULL,
+	count INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (hour, outcome)
+);`
+
+// RelayBatch is a batch waiting to be relayed to the upstream destination.
+type RelayBatch struct {
+	ID              int64     `json:"id"`               // Queue entry identifier
+	ContentType     string    `json:"content_type"`     // Content-Type the batch was received with
+	ContentEncoding string    `json:"content_encoding"` // Content-Encoding the batch was received with
+	Body            []byte    `json:"-"`                // Batch as received
+	Size            int64     `json:"size"`             // Length of Body in bytes
+	Attempts        int       `json:"attempts"`         // Forwarding attempts made, including the one that queued it
+	NextAttemptAt   time.Time `json:"next_attempt_at"`  // When the batch is next tried
+	LastError       string    `json:"last_error"`       // Why the last attempt failed
+	CreatedAt       time.Time `json:"created_at"`       // When the batch was queued
+}
+
+// RelayQueueSize describes the batches waiting in the relay queue.
+type RelayQueueSize struct {
+	Batches int64      `json:"batches"`          // Queued batches
+	Bytes   int64      `json:"bytes"`            // Sum of their sizes
+	Oldest  *time.Time `json:"oldest,omitempty"` // When the oldest was queued; nil when the queue is empty
+}
+
+// EnqueueRelay queues a batch the upstream did not accept, to be retried at
+// retryAt.
+//
+// Parameters:
+//   - b: Batch with ContentType, ContentEncoding, Body and LastError set
+//   - retryAt: When to try again
+//
+// Returns:
+//   - int64: Queue entry identifier
+//   - error: Any error encountered while writing
+func (c *SQLiteController) EnqueueRelay(b RelayBatch, retryAt time.Time) (int64, error) {
+	res, err := c.db.Exec(`INSERT INTO relay_queue (content_type, content_encoding, body, size, attempts, next_attempt_at, last_error, created_at)
+		VALUES (?, ?, ?, ?, 1, ?, ?, ?)`,
+		b.ContentType, b.ContentEncoding, b.Body, len(b.Body), retryAt.UTC(), b.LastError, c.now().UTC())
+	if err != nil {
+		c.logger.Error("Failed to queue relay batch", "error", err, "size", len(b.Body))
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// DueRelay returns queued batches whose next attempt is due, oldest first.
+//
+// Parameters:
+//   - now: Batches due at or before this time are returned
+//   - limit: Maximum number of batches
+//
+// Returns:
+//   - []RelayBatch: Due batches, with their bodies
+//   - error: Any error encountered during the query
+func (c *SQLiteController) DueRelay(now time.Time, limit int) ([]RelayBatch, error) {
+	rows, err := c.db.Query(`SELECT id, content_type, content_encoding, body, size, attempts, next_attempt_at, last_error, created_at
+		FROM relay_queue WHERE next_attempt_at <= ? ORDER BY id LIMIT ?`, now.UTC(), limit)
+	if err != nil {
+		c.logger.Error("Failed to query relay queue", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []RelayBatch{}
+	for rows.Next() {
+		var b RelayBatch
+		if err := rows.Scan(&b.ID, &b.ContentType, &b.ContentEncoding, &b.Body, &b.Size, &b.Attempts,
+			&b.NextAttemptAt, &b.LastError, &b.CreatedAt); err != nil {
+			c.logger.Error("Failed to scan relay queue row", "error", err)
+			return nil, err
+		}
+		b.NextAttemptAt = b.NextAttemptAt.UTC()
+		b.CreatedAt = b.CreatedAt.UTC()
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// DeleteRelay removes a batch from the relay queue once the upstream has
+// accepted or refused it.
+//
+// Parameters:
+//   - id: Queue entry identifier
+//
+// Returns:
+//   - error: Any error encountered while writing
+func (c *SQLiteController) DeleteRelay(id int64) error {
+	_, err := c.db.Exec(`DELETE FROM relay_queue WHERE id = ?`, id)
+	if err != nil {
+		c.logger.Error("Failed to remove relay batch", "error", err, "id", id)
+	}
+	return err
+}
+
+// RescheduleRelay records a failed retry of a queued batch and when to try
+// again.
+//
+// Parameters:
+//   - id: Queue entry identifier
+//   - reason: Why the attempt failed
+//   - retryAt: When to try again
+//
+// Returns:
+//   - error: Any error encountered while writing
+func (c *SQLiteController) RescheduleRelay(id int64, reason string, retryAt time.Time) error {
+	_, err := c.db.Exec(`UPDATE relay_queue SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?`,
+		reason, retryAt.UTC(), id)
+	if err != nil {
+		c.logger.Error("Failed to reschedule relay batch", "error", err, "id", id)
+	}
+	return err
+}
+
+// RelayQueueSize returns the number and total size of the queued batches.
+//
+// Returns:
+//   - RelayQueueSize: Batches, bytes and the oldest batch's age
+//   - error: Any error encountered during the query
+func (c *SQLiteController) RelayQueueSize() (RelayQueueSize, error) {
+	var (
+		s      RelayQueueSize
+		oldest sql.NullString
+	)
+	err := c.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(size), 0), strftime('%Y-%m-%d %H:%M:%S', MIN(created_at)) FROM relay_queue`).
+		Scan(&s.Batches, &s.Bytes, &oldest)
+	if err != nil {
+		c.logger.Error("Failed to measure relay queue", "error", err)
+		return RelayQueueSize{}, err
+	}
+	if oldest.Valid {
+		t, err := time.Parse(time.DateTime, oldest.String)
+		if err != nil {
+			return RelayQueueSize{}, err
+		}
+		s.Oldest = &t
+	}
+	return s, nil
+}
+
+// RecordRelayOutcome increments the counter of an outcome of relaying a
+// batch for the current hour.
+//
+// Parameters:
+//   - outcome: Outcome name, e.g. "delivered" or "queued"
+//
+// Returns:
+//   - error: Any error encountered while updating the counter
+func (c *SQLiteController) RecordRelayOutcome(outcome string) error {
+	hour := c.now().UTC().Truncate(time.Hour)
+	_, err := c.db.Exec(`INSERT INTO relay_outcomes (hour, outcome, count) VALUES (?, ?, 1)
+		ON CONFLICT(hour, outcome) DO UPDATE SET count = count + 1`, hour, outcome)
+	if err != nil {
+		c.logger.Error("Failed to record relay outcome", "error", err, "outcome", outcome)
+	}
+	return err
+}
+
+// RelayOutcomesSince sums relay outcomes for every hour starting at or
+// after since.
+//
+// Parameters:
+//   - since: Start of the window (inclusive, truncated to the hour)
+//
+// Returns:
+//   - map[string]int64: Count per outcome; outcomes that did not occur are absent
+//   - error: Any error encountered during the query
+func (c *SQLiteController) RelayOutcomesSince(since time.Time) (map[string]int64, error) {
+	rows, err := c.db.Query(`SELECT outcome, SUM(count) FROM relay_outcomes WHERE hour >= ? GROUP BY outcome`,
+		since.UTC().Truncate(time.Hour))
+	if err != nil {
+		c.logger.Error("Failed to query relay outcomes", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]int64)
+	for rows.Next() {
+		var (
+			outcome string
+			n       int64
+		)
+		if err := rows.Scan(&outcome, &n); err != nil {
+			return nil, err
+		}
+		out[outcome] = n
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/clock"
+)
+
+func TestRelayQueueLifecycle(t *testing.T) {
+	tempFile := "test_relay_queue.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	now := time.Date(2025, 9, 15, 12, 30, 0, 0, time.UTC)
+	controller.SetClock(clock.Func(func() time.Time { return now }))
+
+	if size, err := controller.RelayQueueSize(); err != nil || size.Batches != 0 || size.Oldest != nil {
+		t.Errorf("Expected an empty queue, got %+v, %v", size, err)
+	}
+	first, err := controller.EnqueueRelay(RelayBatch{ContentEncoding: "gzip", Body: []byte("first"), LastError: "503"}, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	if _, err := controller.EnqueueRelay(RelayBatch{Body: []byte("second"), LastError: "503"}, now.Add(time.Minute)); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	size, err := controller.RelayQueueSize()
+	if err != nil || size.Batches != 2 || size.Bytes != 11 || size.Oldest == nil || !size.Oldest.Equal(now) {
+		t.Errorf("Unexpected queue size %+v, %v", size, err)
+	}
+
+	if due, _ := controller.DueRelay(now, 10); len(due) != 0 {
+		t.Errorf("Expected nothing due before the retry time, got %+v", due)
+	}
+	due, err := controller.DueRelay(now.Add(time.Minute), 10)
+	if err != nil || len(due) != 2 || string(due[0].Body) != "first" || due[0].ContentEncoding != "gzip" || due[0].Attempts != 1 {
+		t.Fatalf("Expected both batches due, oldest first, got %+v, %v", due, err)
+	}
+
+	if err := controller.RescheduleRelay(first, "timeout", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := controller.DeleteRelay(due[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if due, _ := controller.DueRelay(now.Add(time.Hour), 10); len(due) != 1 || due[0].Attempts != 2 || due[0].LastError != "timeout" {
+		t.Errorf("Expected the rescheduled batch due at its retry time, got %+v", due)
+	}
+
+	for _, outcome := range []string{"queued", "queued", "retried"} {
+		if err := controller.RecordRelayOutcome(outcome); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = now.Add(2 * time.Hour)
+	controller.RecordRelayOutcome("delivered")
+	if outcomes, err := controller.RelayOutcomesSince(now.Add(-3 * time.Hour)); err != nil || outcomes["queued"] != 2 || outcomes["retried"] != 1 || outcomes["delivered"] != 1 {
+		t.Errorf("Unexpected outcomes %v, %v", outcomes, err)
+	}
+	if outcomes, _ := controller.RelayOutcomesSince(now); len(outcomes) != 1 {
+		t.Errorf("Expected only the last hour's outcome, got %v", outcomes)
+	}
+}
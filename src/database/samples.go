@@ -0,0 +1,161 @@
+package database
+
+import "time"
+
+// createPayloadSamplesTable holds the DDL for redacted excerpts of ingested
+// batches. Only the most recent samples are kept.
+const createPayloadSamplesTable = `CREATE TABLE IF NOT EXISTS payload_samples (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	received_at DATETIME NOT NULL,
+	dataset TEXT NOT NULL,
+	batch_bytes INTEGER NOT NULL,
+	record_count INTEGER NOT NULL,
+	content TEXT NOT NULL,
+	redacted_fields INTEGER NOT NULL,
+	truncated INTEGER NOT NULL
+);`
+
+// createRedactionAuditTable holds the DDL for the record of what redaction
+// rules removed from stored samples. Entries outlive the samples they
+// describe, so the audit covers samples that have since been trimmed.
+const createRedactionAuditTable = `CREATE TABLE IF NOT EXISTS redaction_audit (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	sample_id INTEGER NOT NULL,
+	redacted_at DATETIME NOT NULL,
+	dataset TEXT NOT NULL,
+	rule TEXT NOT NULL,
+	count INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_redaction_audit_redacted_at ON redaction_audit(redacted_at);`
+
+// PayloadSample is a redacted excerpt of one ingested batch.
+type PayloadSample struct {
+	ID             int64     `json:"id"`              // Sample identifier
+	ReceivedAt     time.Time `json:"received_at"`     // When the batch was received
+	Dataset        string    `json:"dataset"`         // Dataset named by the ingest request, if any
+	BatchBytes     int64     `json:"batch_bytes"`     // Size of the whole batch
+	RecordCount    int64     `json:"record_count"`    // Records in the whole batch
+	Content        string    `json:"content"`         // First record with sensitive fields redacted
+	RedactedFields int       `json:"redacted_fields"` // Number of values redacted from Content
+	Truncated      bool      `json:"truncated"`       // Whether Content was cut to the size limit
+
+	// Redactions holds per-rule counts written to the redaction audit on
+	// insert. ListSamples does not populate it.
+	Redactions map[string]int `json:"-"`
+}
+
+// RedactionTotal summarises what one redaction rule removed from samples.
+type RedactionTotal struct {
+	Rule       string `json:"rule"`       // "field:<name>" or "pattern:<name>"
+	Redactions int64  `json:"redactions"` // Values or matches redacted
+	Samples    int64  `json:"samples"`    // Samples the rule redacted something from
+}
+
+// InsertSample stores a sample and deletes all but the keep most recent.
+// The sample's Redactions are recorded in the redaction audit in the same
+// transaction, so a sample is never stored without its audit entries.
+//
+// Parameters:
+//   - s: Sample to store; ReceivedAt defaults to now
+//   - keep: Number of samples to retain
+//
+// Returns:
+//   - error: Any error encountered; on error nothing is changed
+func (c *SQLiteController) InsertSample(s PayloadSample, keep int) error {
+	if s.ReceivedAt.IsZero() {
+		s.ReceivedAt = c.now()
+	}
+	tx, err := c.db.Begin()
+	if err != nil {
+		c.logger.Error("Failed to begin sample transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO payload_samples (received_at, dataset, batch_bytes, record_count, content, redacted_fields, truncated) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		s.ReceivedAt.UTC(), s.Dataset, s.BatchBytes, s.RecordCount, s.Content, s.RedactedFields, s.Truncated)
+	if err != nil {
+		c.logger.Error("Failed to insert sample", "error", err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for rule, count := range s.Redactions {
+		if _, err := tx.Exec(`INSERT INTO redaction_audit (sample_id, redacted_at, dataset, rule, count) VALUES (?, ?, ?, ?, ?)`,
+			id, s.ReceivedAt.UTC(), s.Dataset, rule, count); err != nil {
+			c.logger.Error("Failed to record redaction audit", "error", err, "rule", rule)
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM payload_samples WHERE id NOT IN (SELECT id FROM payload_samples ORDER BY id DESC LIMIT ?)`, keep); err != nil {
+		c.logger.Error("Failed to trim samples", "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		c.logger.Error("Failed to commit sample", "error", err)
+		return err
+	}
+	return nil
+}
+
+// ListSamples returns the most recent samples, newest first.
+//
+// Parameters:
+//   - limit: Maximum number of samples to return
+//
+// Returns:
+//   - []PayloadSample: Stored samples
+//   - error: Any error encountered during the query
+func (c *SQLiteController) ListSamples(limit int) ([]PayloadSample, error) {
+	rows, err := c.db.Query(`SELECT id, received_at, dataset, batch_bytes, record_count, content, redacted_fields, truncated
+		FROM payload_samples ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		c.logger.Error("Failed to query samples", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []PayloadSample{}
+	for rows.Next() {
+		var s PayloadSample
+		if err := rows.Scan(&s.ID, &s.ReceivedAt, &s.Dataset, &s.BatchBytes, &s.RecordCount, &s.Content, &s.RedactedFields, &s.Truncated); err != nil {
+			c.logger.Error("Failed to scan sample row", "error", err)
+			return nil, err
+		}
+		s.ReceivedAt = s.ReceivedAt.UTC()
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// QueryRedactionAudit totals the redaction audit per rule within a time
+// range, most frequent rule first.
+//
+// Parameters:
+//   - start: Start of the range (inclusive)
+//   - end: End of the range (exclusive)
+//
+// Returns:
+//   - []RedactionTotal: One entry per rule that redacted something
+//   - error: Any error encountered during the query
+func (c *SQLiteController) QueryRedactionAudit(start, end time.Time) ([]RedactionTotal, error) {
+	rows, err := c.db.Query(`SELECT rule, SUM(count), COUNT(DISTINCT sample_id) FROM redaction_audit
+		WHERE redacted_at >= ? AND redacted_at < ?
+		GROUP BY rule ORDER BY SUM(count) DESC, rule`, start.UTC(), end.UTC())
+	if err != nil {
+		c.logger.Error("Failed to query redaction audit", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []RedactionTotal{}
+	for rows.Next() {
+		var t RedactionTotal
+		if err := rows.Scan(&t.Rule, &t.Redactions, &t.Samples); err != nil {
+			c.logger.Error("Failed to scan redaction audit row", "error", err)
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
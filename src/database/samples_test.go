@@ -0,0 +1,73 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestInsertSampleKeepsMostRecent(t *testing.T) {
+	tempFile := "test_samples.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	for i, content := range []string{`{"n":1}`, `{"n":2}`, `{"n":3}`} {
+		err := controller.InsertSample(PayloadSample{Dataset: "http_requests", BatchBytes: int64(100 * (i + 1)), RecordCount: 1, Content: content}, 2)
+		if err != nil {
+			t.Fatalf("Failed to insert sample: %v", err)
+		}
+	}
+
+	samples, err := controller.ListSamples(10)
+	if err != nil {
+		t.Fatalf("Failed to list samples: %v", err)
+	}
+	if len(samples) != 2 || samples[0].Content != `{"n":3}` || samples[1].Content != `{"n":2}` {
+		t.Errorf("Expected the two newest samples, newest first, got %+v", samples)
+	}
+	if samples[0].ReceivedAt.IsZero() || samples[0].BatchBytes != 300 {
+		t.Errorf("Unexpected sample fields %+v", samples[0])
+	}
+}
+
+func TestRedactionAuditOutlivesSamples(t *testing.T) {
+	tempFile := "test_redaction_audit.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	samples := []map[string]int{
+		{"field:ClientIP": 1, "pattern:email": 2},
+		{"pattern:email": 1},
+	}
+	for _, redactions := range samples {
+		if err := controller.InsertSample(PayloadSample{Content: `{}`, Redactions: redactions}, 1); err != nil {
+			t.Fatalf("Failed to insert sample: %v", err)
+		}
+	}
+
+	now := time.Now()
+	totals, err := controller.QueryRedactionAudit(now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query redaction audit: %v", err)
+	}
+	want := []RedactionTotal{
+		{Rule: "pattern:email", Redactions: 3, Samples: 2},
+		{Rule: "field:ClientIP", Redactions: 1, Samples: 1},
+	}
+	if len(totals) != len(want) || totals[0] != want[0] || totals[1] != want[1] {
+		t.Errorf("Expected %+v, got %+v", want, totals)
+	}
+}
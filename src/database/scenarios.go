@@ -0,0 +1,166 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// createScenariosTable holds the DDL for saved estimation scenarios. Fields
+// are stored as JSON text.
+const createScenariosTable = `CREATE TABLE IF NOT EXISTS scenarios (
+	name TEXT PRIMARY KEY,
+	description TEXT NOT NULL DEFAULT '',
+	dataset TEXT NOT NULL DEFAULT '',
+	fields TEXT NOT NULL DEFAULT '[]',
+	sample_rate REAL NOT NULL DEFAULT 1,
+	destination TEXT NOT NULL DEFAULT '',
+	retention_days INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);`
+
+// Scenario is a named proposal for what to ship through Logpush and where,
+// such as "current", "trimmed fields" or "sampled 10%". Scenarios are
+// compared against each other using the observed volume.
+type Scenario struct {
+	Name          string    `json:"name"`           // Unique, URL-safe scenario name
+	Description   string    `json:"description"`    // Free-form description
+	Dataset       string    `json:"dataset"`        // Dataset whose samples size the fields; empty for any
+	Fields        []string  `json:"fields"`         // Fields kept in each record; empty keeps every field
+	SampleRate    float64   `json:"sample_rate"`    // Fraction of records shipped, in (0, 1]
+	Destination   string    `json:"destination"`    // Pricing model name; empty for the default model
+	RetentionDays int       `json:"retention_days"` // Days the destination keeps data; 0 when not modelled
+	CreatedAt     time.Time `json:"created_at"`     // When the scenario was first saved
+	UpdatedAt     time.Time `json:"updated_at"`     // When the scenario was last saved
+}
+
+// scenarioSelectColumns is the column list scanned by scanScenario.
+const scenarioSelectColumns = `name, description, dataset, fields, sample_rate, destination, retention_days, created_at, updated_at`
+
+// scanScenario reads a single row selected with scenarioSelectColumns.
+func scanScenario(row rowScanner) (Scenario, error) {
+	var (
+		s      Scenario
+		fields string
+	)
+	if err := row.Scan(&s.Name, &s.Description, &s.Dataset, &fields, &s.SampleRate, &s.Destination, &s.RetentionDays, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return s, err
+	}
+	s.CreatedAt, s.UpdatedAt = s.CreatedAt.UTC(), s.UpdatedAt.UTC()
+	if err := json.Unmarshal([]byte(fields), &s.Fields); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// ListScenarios returns every saved scenario ordered by name.
+//
+// Returns:
+//   - []Scenario: Saved scenarios ordered by name
+//   - error: Any error encountered during the query
+func (c *SQLiteController) ListScenarios() ([]Scenario, error) {
+	c.logger.Info("Querying scenarios")
+	rows, err := c.db.Query(`SELECT ` + scenarioSelectColumns + ` FROM scenarios ORDER BY name`)
+	if err != nil {
+		c.logger.Error("Failed to query scenarios", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Scenario{}
+	for rows.Next() {
+		s, err := scanScenario(rows)
+		if err != nil {
+			c.logger.Error("Failed to scan scenario row", "error", err)
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// GetScenario loads a saved scenario by name.
+//
+// Parameters:
+//   - name: Scenario name
+//
+// Returns:
+//   - Scenario: The saved scenario, if found
+//   - bool: Whether a scenario with that name exists
+//   - error: Any error encountered during the query
+func (c *SQLiteController) GetScenario(name string) (Scenario, bool, error) {
+	s, err := scanScenario(c.db.QueryRow(`SELECT `+scenarioSelectColumns+` FROM scenarios WHERE name = ?`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Scenario{}, false, nil
+	}
+	if err != nil {
+		c.logger.Error("Failed to query scenario", "error", err, "name", name)
+		return Scenario{}, false, err
+	}
+	return s, true, nil
+}
+
+// SaveScenario creates or replaces the scenario named s.Name. CreatedAt is
+// kept from an existing scenario and UpdatedAt is set to the current time.
+//
+// Parameters:
+//   - s: Scenario to store
+//
+// Returns:
+//   - Scenario: The stored scenario including timestamps
+//   - error: Any error encountered during the write
+func (c *SQLiteController) SaveScenario(s Scenario) (Scenario, error) {
+	if s.Fields == nil {
+		s.Fields = []string{}
+	}
+	fields, err := json.Marshal(s.Fields)
+	if err != nil {
+		return s, err
+	}
+	now := c.now().UTC()
+
+	_, err = c.db.Exec(`INSERT INTO scenarios (name, description, dataset, fields, sample_rate, destination, retention_days, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			description = excluded.description,
+			dataset = excluded.dataset,
+			fields = excluded.fields,
+			sample_rate = excluded.sample_rate,
+			destination = excluded.destination,
+			retention_days = excluded.retention_days,
+			updated_at = excluded.updated_at`,
+		s.Name, s.Description, s.Dataset, string(fields), s.SampleRate, s.Destination, s.RetentionDays, now, now)
+	if err != nil {
+		c.logger.Error("Failed to save scenario", "error", err, "name", s.Name)
+		return s, err
+	}
+
+	saved, _, err := c.GetScenario(s.Name)
+	if err != nil {
+		return s, err
+	}
+	c.logger.Info("Scenario stored", "name", s.Name)
+	return saved, nil
+}
+
+// DeleteScenario removes the scenario with the given name.
+//
+// Parameters:
+//   - name: Scenario name
+//
+// Returns:
+//   - bool: Whether a scenario was deleted
+//   - error: Any error encountered during the delete
+func (c *SQLiteController) DeleteScenario(name string) (bool, error) {
+	res, err := c.db.Exec(`DELETE FROM scenarios WHERE name = ?`, name)
+	if err != nil {
+		c.logger.Error("Failed to delete scenario", "error", err, "name", name)
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	c.logger.Info("Scenario deleted", "name", name, "deleted", n > 0)
+	return n > 0, nil
+}
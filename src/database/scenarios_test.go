@@ -0,0 +1,69 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestScenariosCRUD(t *testing.T) {
+	tempFile := "test_scenarios.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	if _, found, err := controller.GetScenario("missing"); err != nil || found {
+		t.Fatalf("Expected missing scenario to be not found, got found=%v err=%v", found, err)
+	}
+
+	saved, err := controller.SaveScenario(Scenario{
+		Name:          "trimmed",
+		Dataset:       "http_requests",
+		Fields:        []string{"ClientIP", "EdgeResponseStatus"},
+		SampleRate:    0.1,
+		Destination:   "r2",
+		RetentionDays: 30,
+	})
+	if err != nil {
+		t.Fatalf("Failed to save scenario: %v", err)
+	}
+	if saved.CreatedAt.IsZero() || len(saved.Fields) != 2 || saved.SampleRate != 0.1 || saved.RetentionDays != 30 {
+		t.Errorf("Unexpected saved scenario: %+v", saved)
+	}
+
+	// Replacing keeps the original creation time
+	if _, err := controller.SaveScenario(Scenario{Name: "trimmed", SampleRate: 1}); err != nil {
+		t.Fatalf("Failed to replace scenario: %v", err)
+	}
+	if _, err := controller.SaveScenario(Scenario{Name: "current", SampleRate: 1}); err != nil {
+		t.Fatalf("Failed to save second scenario: %v", err)
+	}
+
+	scenarios, err := controller.ListScenarios()
+	if err != nil {
+		t.Fatalf("Failed to list scenarios: %v", err)
+	}
+	if len(scenarios) != 2 || scenarios[0].Name != "current" || scenarios[1].Name != "trimmed" {
+		t.Fatalf("Expected scenarios ordered by name, got %+v", scenarios)
+	}
+	replaced := scenarios[1]
+	if len(replaced.Fields) != 0 || replaced.Dataset != "" || replaced.SampleRate != 1 {
+		t.Errorf("Expected replaced scenario to drop its fields, got %+v", replaced)
+	}
+	if !replaced.CreatedAt.Equal(saved.CreatedAt) {
+		t.Errorf("Expected CreatedAt %v to be preserved, got %v", saved.CreatedAt, replaced.CreatedAt)
+	}
+
+	deleted, err := controller.DeleteScenario("current")
+	if err != nil || !deleted {
+		t.Fatalf("Expected scenario to be deleted, got deleted=%v err=%v", deleted, err)
+	}
+	if deleted, _ := controller.DeleteScenario("current"); deleted {
+		t.Error("Expected second delete to report nothing deleted")
+	}
+}
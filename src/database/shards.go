@@ -0,0 +1,430 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Monthly sharding keeps each closed month's log records in a SQLite file of
+// its own next to the main database, e.g. logpush-2025-09.db beside
+// logpush.db. The main database holds the current month and every other
+// table. Each connection attaches the shard files and reads log_sizes
+// through a temporary view, the UNION ALL of the main table and the shards'
+// tables, which shadows the main table. The view cannot be written to: new
+// records are inserted into main.log_sizes, RotateShards moves them into
+// their month's shard once it has closed, and deletions run against each
+// table in logSizeTables. A closed month's file only changes when records are
+// deleted, so backups can skip it after copying it once, and it can be
+// moved to cold storage: its records are no longer read, and are read again
+// once it is moved back. Beyond MaxAttachedShards, the oldest shard files
+// stay in place but are not attached, and queries over their months are
+// refused with ErrShardDetached.
+
+// MaxAttachedShards is the most shards read at once. SQLite attaches at
+// most 10 databases to a connection, so older shards are left detached.
+// Queries reaching back into a detached shard's month fail with
+// ErrShardDetached rather than leave its records out; move such shards to
+// cold storage, or archive them.
+const MaxAttachedShards = 10
+
+// ErrShardDetached is returned by queries and deletions of log records whose
+// range reaches back into the month of a shard file that is present but
+// not attached, since its records would silently be missing.
+var ErrShardDetached = errors.New("range includes months whose shard files are not attached")
+
+// shardMonth is the layout of a shard's month, as in its file name.
+const shardMonth = "2006-01"
+
+// Shard describes the file holding one month of log records.
+type Shard struct {
+	Month    string `json:"month"`    // Month the shard holds, e.g. "2025-09"
+	Path     string `json:"path"`     // File path
+	Bytes    int64  `json:"bytes"`    // File size
+	Attached bool   `json:"attached"` // Whether its records are read; only the newest MaxAttachedShards are
+}
+
+// shardState tracks the shard files of a controller's database. It is
+// shared by the controller, its ForTenant copies and the connector, whose
+// connections attach the shards whenever the generation changes.
+type shardState struct {
+	path string // Main database file
+
+	mu         sync.RWMutex
+	enabled    bool
+	months     []string // Months with a shard file, oldest first
+	generation uint64   // Incremented whenever months changes
+}
+
+// file returns the path of a month's shard file.
+func (s *shardState) file(month string) string {
+	ext := filepath.Ext(s.path)
+	return strings.TrimSuffix(s.path, ext) + "-" + month + ext
+}
+
+// shardSchema returns the name a month's shard is attached under.
+func shardSchema(month string) string {
+	return "shard_" + strings.ReplaceAll(month, "-", "_")
+}
+
+// attached returns the months read, the newest MaxAttachedShards.
+func (s *shardState) attached() []string {
+	return s.months[max(0, len(s.months)-MaxAttachedShards):]
+}
+
+// snapshot returns the generation and the months connections attach.
+func (s *shardState) snapshot() (uint64, []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.generation, slices.Clone(s.attached())
+}
+
+// isEnabled reports whether the database is sharded.
+func (s *shardState) isEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.enabled
+}
+
+// setMonths replaces the known months, bumping the generation on change.
+func (s *shardState) setMonths(months []string) {
+	slices.Sort(months)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.Equal(s.months, months) {
+		s.months = months
+		s.generation++
+	}
+}
+
+// discover lists the shard files present, so files moved to or back from
+// cold storage are noticed.
+func (s *shardState) discover() error {
+	ext := filepath.Ext(s.path)
+	matches, err := filepath.Glob(strings.TrimSuffix(s.path, ext) + "-*" + ext)
+	if err != nil {
+		return err
+	}
+	months := []string{}
+	for _, m := range matches {
+		month := strings.TrimSuffix(strings.TrimPrefix(m, strings.TrimSuffix(s.path, ext)+"-"), ext)
+		if t, err := time.Parse(shardMonth, month); err == nil && t.Format(shardMonth) == month {
+			months = append(months, month)
+		}
+	}
+	s.setMonths(months)
+	return nil
+}
+
+// ShardByMonth stores each closed month's log records in a shard file of
+// its own next to the database, and moves the records of past months out of
+// the main database straight away. Call RotateShards periodically
+// afterwards, so each month is moved once it closes. Records in a shard
+// file are read for as long as it stays in place and is among the newest
+// MaxAttachedShards. A controller opened with OpenReadOnly only reads the
+// shard files already present and moves nothing.
+//
+// Returns:
+//   - error: If the database is not a plain file, or moving records failed;
+//     sharding stays enabled, and RotateShards retries the move
+func (c *SQLiteController) ShardByMonth() error {
+	if c.shards.path == ":memory:" || strings.HasPrefix(c.shards.path, "file:") || strings.Contains(c.shards.path, "?") {
+		return fmt.Errorf("monthly shards need a plain database file path, got %q", c.shards.path)
+	}
+	c.shards.mu.Lock()
+	c.shards.enabled = true
+	c.shards.mu.Unlock()
+	c.logger.Info("Sharding log records by month", "path", c.shards.path)
+	if c.readOnly {
+		// The writer rotates the shards; only read the ones it made
+		return c.shards.discover()
+	}
+	_, err := c.RotateShards()
+	return err
+}
+
+// ShardedByMonth reports whether ShardByMonth was called.
+func (c *SQLiteController) ShardedByMonth() bool {
+	return c.shards.isEnabled()
+}
+
+// RotateShards moves the log records of every month before the current one
+// from the main database into their month's shard, creating it if needed.
+// Records of a month older than the newest MaxAttachedShards shards stay in
+// the main database, so they are still read. It does nothing unless
+// ShardByMonth was called.
+//
+// Returns:
+//   - int64: Records moved
+//   - error: Any error encountered; months already moved stay moved
+func (c *SQLiteController) RotateShards() (int64, error) {
+	if !c.shards.isEnabled() {
+		return 0, nil
+	}
+	if err := c.shards.discover(); err != nil {
+		c.logger.Error("Failed to list shard files", "error", err)
+		return 0, err
+	}
+
+	now := c.now().UTC()
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	rows, err := c.db.Query(`SELECT DISTINCT strftime('%Y-%m', timestamp) FROM main.log_sizes WHERE timestamp < ?`, current)
+	if err != nil {
+		c.logger.Error("Failed to find months to shard", "error", err)
+		return 0, err
+	}
+	var months []string
+	for rows.Next() {
+		var month string
+		if err := rows.Scan(&month); err != nil {
+			rows.Close()
+			c.logger.Error("Failed to scan shard month", "error", err)
+			return 0, err
+		}
+		months = append(months, month)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var moved int64
+	for _, month := range months {
+		n, err := c.moveToShard(month)
+		if err != nil {
+			c.logger.Error("Failed to move records to shard", "error", err, "month", month)
+			return moved, err
+		}
+		moved += n
+	}
+	if moved > 0 {
+		c.logger.Info("Moved log records to monthly shards", "records", moved, "months", len(months))
+	}
+	return moved, nil
+}
+
+// moveToShard moves one month's records from the main database into its
+// shard. The records keep their IDs, which main.log_sizes never reuses.
+func (c *SQLiteController) moveToShard(month string) (int64, error) {
+	start, err := time.Parse(shardMonth, month)
+	if err != nil {
+		return 0, err
+	}
+	c.shards.mu.RLock()
+	months := append(slices.Clone(c.shards.months), month)
+	c.shards.mu.RUnlock()
+	slices.Sort(months)
+	months = slices.Compact(months)
+	if !slices.Contains(months[max(0, len(months)-MaxAttachedShards):], month) {
+		c.logger.Warn("Keeping records in the main database; their month is older than every attached shard", "month", month)
+		return 0, nil
+	}
+	// Connections attach the new shard, creating its file, before their
+	// next statement
+	c.shards.setMonths(months)
+
+	end := start.AddDate(0, 1, 0)
+	tx, err := c.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	// A crash between the shard's commit and the main database's (each file
+	// commits separately in WAL mode) leaves the records in both, so copies
+	// replace what a previous attempt wrote
+	res, err := tx.Exec(`INSERT OR REPLACE INTO `+shardSchema(month)+`.log_sizes (`+logSizeSelectColumns+`)
+		SELECT `+logSizeSelectColumns+` FROM main.log_sizes WHERE timestamp >= ? AND timestamp < ?`, start, end)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM main.log_sizes WHERE timestamp >= ? AND timestamp < ?`, start, end); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ReadableSince returns the time from which every log record is read: the
+// start of the month after the newest shard file left detached beyond
+// MaxAttachedShards. It is zero when every shard file present is attached.
+func (c *SQLiteController) ReadableSince() time.Time {
+	c.shards.mu.RLock()
+	defer c.shards.mu.RUnlock()
+	detached := len(c.shards.months) - MaxAttachedShards
+	if detached <= 0 {
+		return time.Time{}
+	}
+	month, err := time.Parse(shardMonth, c.shards.months[detached-1])
+	if err != nil {
+		return time.Time{}
+	}
+	return month.AddDate(0, 1, 0)
+}
+
+// checkAttached refuses a range of log records starting at start, or all
+// of them when start is zero, that reaches before ReadableSince.
+func (c *SQLiteController) checkAttached(start time.Time) error {
+	since := c.ReadableSince()
+	if since.IsZero() || !start.Before(since) {
+		return nil
+	}
+	return fmt.Errorf("%w: records before %s are not read; query from %s, or move the older shard files to cold storage",
+		ErrShardDetached, since.Format(shardMonth), since.Format(time.RFC3339))
+}
+
+// logSizeTables returns the tables holding log records: the main
+// database's and those of the attached shards.
+func (c *SQLiteController) logSizeTables() []string {
+	_, months := c.shards.snapshot()
+	tables := []string{"main.log_sizes"}
+	for _, month := range months {
+		tables = append(tables, shardSchema(month)+".log_sizes")
+	}
+	return tables
+}
+
+// Shards lists the shard files, oldest first; empty unless ShardByMonth was
+// called.
+//
+// Returns:
+//   - []Shard: Shard files and whether their records are read
+//   - error: If the files could not be listed
+func (c *SQLiteController) Shards() ([]Shard, error) {
+	if !c.shards.isEnabled() {
+		return []Shard{}, nil
+	}
+	if err := c.shards.discover(); err != nil {
+		return nil, err
+	}
+	c.shards.mu.RLock()
+	months, attached := slices.Clone(c.shards.months), c.shards.attached()
+	c.shards.mu.RUnlock()
+	out := make([]Shard, 0, len(months))
+	for _, month := range months {
+		s := Shard{Month: month, Path: c.shards.file(month), Attached: slices.Contains(attached, month)}
+		if info, err := os.Stat(s.Path); err == nil {
+			s.Bytes = info.Size()
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// syncShards attaches the shards registered since the connection last
+// synced and rebuilds the temporary log_sizes view over them. It is skipped
+// inside transactions, which keep the shards they began with.
+func (c *faultConn) syncShards(ctx context.Context) error {
+	if c.shards == nil || c.inTx {
+		return nil
+	}
+	generation, months := c.shards.snapshot()
+	if generation == c.shardGeneration {
+		return nil
+	}
+	exec := func(query string, args ...driver.NamedValue) error {
+		_, err := c.conn.ExecContext(ctx, query, args)
+		return err
+	}
+
+	if err := exec(`DROP VIEW IF EXISTS temp.log_sizes`); err != nil {
+		return err
+	}
+	for len(c.attached) > 0 {
+		if err := exec(`DETACH DATABASE ` + shardSchema(c.attached[0])); err != nil {
+			return err
+		}
+		c.attached = c.attached[1:]
+	}
+
+	selects := []string{`SELECT ` + logSizeSelectColumns + ` FROM main.log_sizes`}
+	for _, month := range months {
+		schema := shardSchema(month)
+		if err := exec(`ATTACH DATABASE ? AS `+schema, driver.NamedValue{Ordinal: 1, Value: c.shards.file(month)}); err != nil {
+			return fmt.Errorf("attaching shard %s: %w", month, err)
+		}
+		c.attached = append(c.attached, month)
+		if err := c.migrateShard(ctx, schema); err != nil {
+			return fmt.Errorf("migrating shard %s: %w", month, err)
+		}
+		selects = append(selects, `SELECT `+logSizeSelectColumns+` FROM `+schema+`.log_sizes`)
+	}
+	if len(months) > 0 {
+		if err := exec(`CREATE TEMP VIEW log_sizes AS ` + strings.Join(selects, " UNION ALL ")); err != nil {
+			return err
+		}
+	}
+	c.shardGeneration = generation
+	return nil
+}
+
+// migrateShard creates an attached shard's table, or adds the columns
+// introduced since its file was written.
+func (c *faultConn) migrateShard(ctx context.Context, schema string) error {
+	columns := []string{"id INTEGER PRIMARY KEY", "timestamp DATETIME NOT NULL", "filesize INTEGER NOT NULL"}
+	for _, col := range logSizeColumns {
+		columns = append(columns, col.name+" "+col.definition)
+	}
+	_, err := c.conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+schema+`.log_sizes (`+strings.Join(columns, ", ")+`);
+		CREATE INDEX IF NOT EXISTS `+schema+`.idx_log_sizes_timestamp ON log_sizes(timestamp);
+		CREATE INDEX IF NOT EXISTS `+schema+`.idx_log_sizes_tenant_timestamp ON log_sizes(tenant, timestamp);`, nil)
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.conn.QueryContext(ctx, `PRAGMA `+schema+`.table_info(log_sizes)`, nil)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	dest := make([]driver.Value, len(rows.Columns()))
+	for {
+		if err := rows.Next(dest); err != nil {
+			rows.Close()
+			if !errors.Is(err, io.EOF) {
+				return err
+			}
+			break
+		}
+		switch name := dest[1].(type) {
+		case string:
+			existing[name] = true
+		case []byte:
+			existing[string(name)] = true
+		}
+	}
+	for _, col := range logSizeColumns {
+		if existing[col.name] {
+			continue
+		}
+		if _, err := c.conn.ExecContext(ctx, `ALTER TABLE `+schema+`.log_sizes ADD COLUMN `+col.name+` `+col.definition, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shardTx ends a connection's transaction, letting it sync its shards again.
+type shardTx struct {
+	driver.Tx
+	conn *faultConn
+}
+
+func (t *shardTx) Commit() error {
+	t.conn.inTx = false
+	return t.Tx.Commit()
+}
+
+func (t *shardTx) Rollback() error {
+	t.conn.inTx = false
+	return t.Tx.Rollback()
+}
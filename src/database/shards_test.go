@@ -0,0 +1,177 @@
+package database
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/clock"
+)
+
+func TestShardByMonth(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logpush.db")
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := NewSQLiteController(path, logger)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	now := time.Date(2025, 11, 10, 12, 0, 0, 0, time.UTC)
+	db.SetClock(clock.Func(func() time.Time { return now }))
+
+	sep := time.Date(2025, 9, 15, 8, 0, 0, 0, time.UTC)
+	oct := time.Date(2025, 10, 31, 23, 59, 0, 0, time.UTC)
+	for _, l := range []LogSize{
+		{Timestamp: sep, Filesize: 100, RecordCount: 1},
+		{Timestamp: sep.Add(time.Hour), Filesize: 200, RecordCount: 2},
+		{Timestamp: oct, Filesize: 400, RecordCount: 4},
+		{Timestamp: now.Add(-time.Hour), Filesize: 800, RecordCount: 8},
+	} {
+		if err := db.InsertLog(l); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+	if err := db.ForTenant("acme").InsertLog(LogSize{Timestamp: sep, Filesize: 7}); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+
+	if err := db.ShardByMonth(); err != nil {
+		t.Fatalf("ShardByMonth failed: %v", err)
+	}
+	shards, err := db.Shards()
+	if err != nil || len(shards) != 2 || shards[0].Month != "2025-09" || shards[1].Path != filepath.Join(dir, "logpush-2025-10.db") ||
+		!shards[0].Attached || shards[0].Bytes == 0 {
+		t.Fatalf("Expected September and October shards, got %+v, %v", shards, err)
+	}
+	var inMain int
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM main.log_sizes`).Scan(&inMain); err != nil || inMain != 1 {
+		t.Errorf("Expected only November's record left in the main database, got %d, %v", inMain, err)
+	}
+
+	// Records are read across the shards as before, IDs and tenants intact
+	stats, err := db.StatsByTimeRange(time.Time{}, time.Time{})
+	if err != nil || stats.Batches != 5 || stats.Bytes != 1507 || stats.Records != 15 {
+		t.Errorf("Expected every record read, got %+v, %v", stats, err)
+	}
+	since, err := db.QuerySince(2, 10)
+	if err != nil || len(since) != 3 || since[0].ID != 3 || since[0].Filesize != 400 {
+		t.Errorf("Expected records after ID 2 in order, got %+v, %v", since, err)
+	}
+	if tenant, err := db.ForTenant("acme").StatsByTimeRange(time.Time{}, time.Time{}); err != nil || tenant.Batches != 1 {
+		t.Errorf("Expected the tenant's sharded record, got %+v, %v", tenant, err)
+	}
+
+	// New records go to the main database with fresh IDs
+	if err := db.InsertLog(LogSize{Timestamp: now, Filesize: 1600}); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+	if latest, err := db.QuerySince(5, 10); err != nil || len(latest) != 1 || latest[0].ID != 6 {
+		t.Errorf("Expected the new record with ID 6, got %+v, %v", latest, err)
+	}
+
+	// Deleting reaches into the shards; restored records return to their
+	// shard with the next rotation
+	deleted, err := db.DeleteByTimeRange(sep, sep.Add(time.Minute))
+	if err != nil || deleted.Rows != 2 {
+		t.Fatalf("Expected two September records deleted, got %+v, %v", deleted, err)
+	}
+	if stats, _ := db.StatsByTimeRange(time.Time{}, time.Time{}); stats.Batches != 4 {
+		t.Errorf("Expected 4 records after deleting, got %d", stats.Batches)
+	}
+	if _, ok, err := db.RestoreTrash(deleted.TrashID); err != nil || !ok {
+		t.Fatalf("RestoreTrash failed: %v", err)
+	}
+	if moved, err := db.RotateShards(); err != nil || moved != 2 {
+		t.Errorf("Expected the restored records moved back, got %d, %v", moved, err)
+	}
+
+	// A month moved to cold storage is not read until it is moved back
+	cold := filepath.Join(t.TempDir(), "logpush-2025-09.db")
+	if err := os.Rename(shards[0].Path, cold); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.RotateShards(); err != nil {
+		t.Fatalf("RotateShards failed: %v", err)
+	}
+	if stats, _ := db.StatsByTimeRange(time.Time{}, time.Time{}); stats.Batches != 3 {
+		t.Errorf("Expected September unread while in cold storage, got %d records", stats.Batches)
+	}
+	if err := os.Rename(cold, shards[0].Path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.RotateShards(); err != nil {
+		t.Fatalf("RotateShards failed: %v", err)
+	}
+	if stats, _ := db.StatsByTimeRange(time.Time{}, time.Time{}); stats.Batches != 6 {
+		t.Errorf("Expected September read again, got %d records", stats.Batches)
+	}
+
+	if err := (&SQLiteController{shards: &shardState{path: ":memory:"}}).ShardByMonth(); err == nil {
+		t.Error("Expected an in-memory database to be refused")
+	}
+}
+
+func TestShardsBeyondMaxAttached(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logpush.db")
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := NewSQLiteController(path, logger)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	db.SetClock(clock.Func(func() time.Time { return now }))
+
+	// One record in each month of 2024: two more shards than are attached
+	for month := 1; month <= 12; month++ {
+		if err := db.InsertLog(LogSize{Timestamp: time.Date(2024, time.Month(month), 15, 0, 0, 0, 0, time.UTC), Filesize: 100}); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+	if err := db.ShardByMonth(); err != nil {
+		t.Fatalf("ShardByMonth failed: %v", err)
+	}
+	shards, err := db.Shards()
+	if err != nil || len(shards) != 12 || shards[1].Attached || !shards[2].Attached {
+		t.Fatalf("Expected January and February 2024 detached, got %+v, %v", shards, err)
+	}
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	if since := db.ReadableSince(); !since.Equal(march) {
+		t.Errorf("Expected records read from March 2024, got %v", since)
+	}
+
+	// Ranges reaching into the detached months are refused, not undercounted
+	if _, err := db.StatsByTimeRange(time.Time{}, now); !errors.Is(err, ErrShardDetached) {
+		t.Errorf("Expected ErrShardDetached for all records, got %v", err)
+	}
+	if _, err := db.QueryByTimeRange(march.Add(-time.Hour), now); !errors.Is(err, ErrShardDetached) {
+		t.Errorf("Expected ErrShardDetached for a range from February, got %v", err)
+	}
+	if _, err := db.GetAll(); !errors.Is(err, ErrShardDetached) {
+		t.Errorf("Expected ErrShardDetached from GetAll, got %v", err)
+	}
+	if _, err := db.DeleteByTimeRange(time.Time{}, now); !errors.Is(err, ErrShardDetached) {
+		t.Errorf("Expected ErrShardDetached for a deletion, got %v", err)
+	}
+	stats, err := db.StatsByTimeRange(march, now)
+	if err != nil || stats.Batches != 10 {
+		t.Errorf("Expected the ten attached months read, got %+v, %v", stats, err)
+	}
+
+	// Once the oldest shards are moved away, every remaining record is read
+	for _, s := range shards[:2] {
+		if err := os.Rename(s.Path, s.Path+".cold"); err != nil {
+			t.Fatalf("Failed to move shard: %v", err)
+		}
+	}
+	if _, err := db.Shards(); err != nil {
+		t.Fatalf("Shards failed: %v", err)
+	}
+	if stats, err := db.StatsByTimeRange(time.Time{}, now); err != nil || stats.Batches != 10 {
+		t.Errorf("Expected every remaining record read, got %+v, %v", stats, err)
+	}
+}
@@ -37,28 +37,79 @@
 //	CREATE TABLE log_sizes (
 //		id INTEGER PRIMARY KEY AUTOINCREMENT,
 //		timestamp DATETIME NOT NULL,
-//		filesize INTEGER NOT NULL
+//		filesize INTEGER NOT NULL,
+//		record_count INTEGER NOT NULL DEFAULT 0,
+//		min_record_size INTEGER NOT NULL DEFAULT 0,
+//		max_record_size INTEGER NOT NULL DEFAULT 0,
+//		avg_record_size REAL NOT NULL DEFAULT 0
 //	);
 //
 // An index on the timestamp column is automatically created for efficient
-// time-range queries.
+// time-range queries. Columns added after the original schema are applied
+// to existing databases on startup.
+//
+// # Time Handling
+//
+// All timestamps are stored in UTC. Query bounds are converted to UTC before
+// comparison and scanned timestamps are returned in UTC, so bucketing does
+// not depend on the server's local time zone. Rows written in local time by
+// older versions are rewritten to UTC on startup.
 package database
 
 import (
 	"database/sql"
 	"log/slog"
+	"net/url"
 	"os"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
+	"github.com/melatonein5/LogpushEstimator/src/clock"
 )
 
 // LogSize represents a single log size record with timestamp.
 // This struct maps directly to the log_sizes table in the database.
 type LogSize struct {
-	ID        int64     // Unique identifier (auto-increment primary key)
-	Timestamp time.Time // When the log was recorded
-	Filesize  int64     // Size of the log data in bytes
+	ID            int64     // Unique identifier (auto-increment primary key)
+	Timestamp     time.Time // When the log was recorded
+	Filesize      int64     // Size of the log data in bytes, as received (compressed when Encoding is set)
+	RecordCount   int64     // Number of records (lines) in the batch
+	MinRecordSize int64     // Smallest record in the batch in bytes
+	MaxRecordSize int64     // Largest record in the batch in bytes
+	AvgRecordSize float64   // Average record size in the batch in bytes
+	Tenant        string    // Tenant the batch was ingested for; empty for the default tenant
+	Dataset       string    // Logpush dataset of the batch, e.g. "http_requests"; empty if not named at ingestion
+
+	UncompressedSize int64  // Size of the log data after decompression; equals Filesize for uncompressed batches
+	Encoding         string // Content-Encoding the batch was received with, e.g. "gzip"; empty if uncompressed
+	Zone             string // Cloudflare zone the batch was pushed for, e.g. "example.com"; empty if not named at ingestion
+
+	Labels map[string]string // Labels sent with the batch, e.g. {"env": "prod"}; nil without labels
+}
+
+// logSizeSelectColumns is the column list shared by every query that scans
+// rows into a LogSize via scanLogSize.
+const logSizeSelectColumns = `id, timestamp, filesize, record_count, min_record_size, max_record_size, avg_record_size, tenant, dataset, uncompressed_size, content_encoding, zone, labels`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanLogSize reads a single row selected with logSizeSelectColumns.
+// Records stored before sizes were tracked uncompressed report Filesize as
+// their uncompressed size.
+func scanLogSize(row rowScanner) (LogSize, error) {
+	var l LogSize
+	var labels string
+	err := row.Scan(&l.ID, &l.Timestamp, &l.Filesize, &l.RecordCount, &l.MinRecordSize, &l.MaxRecordSize, &l.AvgRecordSize, &l.Tenant, &l.Dataset,
+		&l.UncompressedSize, &l.Encoding, &l.Zone, &labels)
+	l.Timestamp = l.Timestamp.UTC()
+	l.Labels = decodeLabels(labels)
+	if l.UncompressedSize == 0 {
+		l.UncompressedSize = l.Filesize
+	}
+	return l, err
 }
 
 // SQLiteController provides database operations for log size tracking.
@@ -66,8 +117,18 @@ type LogSize struct {
 // inserting and querying log size records with proper error handling
 // and structured logging.
 type SQLiteController struct {
-	db     *sql.DB      // SQLite database connection
-	logger *slog.Logger // Structured logger for database operations
+	db       *sql.DB           // SQLite database connection
+	logger   *slog.Logger      // Structured logger for database operations
+	tenant   string            // Tenant log record queries are scoped to; empty for all tenants
+	dataset  string            // Dataset log record queries are scoped to; empty for all datasets
+	zone     string            // Zone log record queries are scoped to; empty for all zones
+	labels   map[string]string // Labels log record queries require; nil for any labels
+	recent   *recentBuffer     // Most recently inserted records, shared with ForTenant copies
+	clock    *clock.Source     // Source of the current time, shared with ForTenant copies
+	faults   *faultState       // Injected failures (see SetFaults), shared with ForTenant copies
+	shards   *shardState       // Month shard files (see ShardByMonth), shared with ForTenant copies
+	recovery *recoveryState    // Startup recovery report (see Recover), shared with ForTenant copies
+	readOnly bool              // Opened with OpenReadOnly
 }
 
 // NewSQLiteController creates a new database controller and initializes the database.
@@ -85,6 +146,7 @@ type SQLiteController struct {
 // The function ensures the database schema is properly set up with:
 //   - log_sizes table for storing log records
 //   - timestamp index for efficient time-range queries
+//   - auxiliary tables (rollups, tracking) listed in auxiliaryTables
 func NewSQLiteController(path string, logger *slog.Logger) (*SQLiteController, error) {
 	if path == "" {
 		path = "logpush.db"
@@ -95,14 +157,11 @@ func NewSQLiteController(path string, logger *slog.Logger) (*SQLiteController, e
 	}
 
 	logger.Info("Opening SQLite database", "path", path)
-	db, err := sql.Open("sqlite3", path)
-	if err != nil {
-		logger.Error("Failed to open SQLite database", "error", err, "path", path)
-		return nil, err
-	}
+	faults, shards := &faultState{}, &shardState{path: path}
+	db := sql.OpenDB(&faultConnector{dsn: path, driver: &sqlite3.SQLiteDriver{}, faults: faults, shards: shards})
 
 	logger.Info("Creating log_sizes table if not exists")
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS log_sizes (
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS log_sizes (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		timestamp DATETIME NOT NULL,
 		filesize INTEGER NOT NULL
@@ -121,11 +180,213 @@ func NewSQLiteController(path string, logger *slog.Logger) (*SQLiteController, e
 		return nil, err
 	}
 
+	logger.Info("Applying log_sizes column migrations")
+	if err := addMissingColumns(db, "log_sizes", logSizeColumns); err != nil {
+		logger.Error("Failed to migrate log_sizes table", "error", err)
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_log_sizes_tenant_timestamp ON log_sizes(tenant, timestamp);`); err != nil {
+		logger.Error("Failed to create tenant index", "error", err)
+		db.Close()
+		return nil, err
+	}
+
+	for _, table := range auxiliaryTables {
+		logger.Info("Creating table if not exists", "table", table.name)
+		if _, err := db.Exec(table.ddl); err != nil {
+			logger.Error("Failed to create table", "table", table.name, "error", err)
+			db.Close()
+			return nil, err
+		}
+	}
+	if err := addMissingColumns(db, "deleted_log_sizes", deletedLogSizeColumns); err != nil {
+		logger.Error("Failed to migrate deleted_log_sizes table", "error", err)
+		db.Close()
+		return nil, err
+	}
+	if err := addMissingColumns(db, "preferences", preferencesColumns); err != nil {
+		logger.Error("Failed to migrate preferences table", "error", err)
+		db.Close()
+		return nil, err
+	}
+	if err := migrateDimensionRollups(db); err != nil {
+		logger.Error("Failed to migrate dimension_rollups table", "error", err)
+		db.Close()
+		return nil, err
+	}
+
+	logger.Info("Normalizing stored timestamps to UTC")
+	for _, col := range utcColumns {
+		n, err := normalizeToUTC(db, col.table, col.column)
+		if err != nil {
+			logger.Error("Failed to normalize timestamps", "table", col.table, "error", err)
+			db.Close()
+			return nil, err
+		}
+		if n > 0 {
+			logger.Info("Converted timestamps to UTC", "table", col.table, "rows", n)
+		}
+	}
+
 	logger.Info("SQLite database setup completed successfully")
-	return &SQLiteController{db: db, logger: logger}, nil
+	return &SQLiteController{db: db, logger: logger, recent: newRecentBuffer(DefaultRecentBufferSize), clock: &clock.Source{}, faults: faults, shards: shards, recovery: &recoveryState{}}, nil
 }
 
-// InsertLogSize inserts a new log size record with the current timestamp.
+// OpenReadOnly opens an existing database without writing to it, for an
+// instance serving a copy of the database that another process keeps up
+// to date. The file is opened in SQLite's read-only mode, and no tables are
+// created, migrated or normalized, so the schema must be that of a database
+// NewSQLiteController has already opened.
+//
+// Parameters:
+//   - path: Database file path; it must exist
+//   - logger: Logger for database operations. If nil, creates a default logger
+//
+// Returns:
+//   - *SQLiteController: Database controller whose writes fail
+//   - error: When the file is missing or cannot be opened
+func OpenReadOnly(path string, logger *slog.Logger) (*SQLiteController, error) {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Opening SQLite database read-only", "path", path)
+	dsn := (&url.URL{Scheme: "file", Path: path, RawQuery: "mode=ro"}).String()
+	faults, shards := &faultState{}, &shardState{path: path}
+	db := sql.OpenDB(&faultConnector{dsn: dsn, driver: &sqlite3.SQLiteDriver{}, faults: faults, shards: shards})
+	if err := db.Ping(); err != nil {
+		logger.Error("Failed to open SQLite database read-only", "error", err)
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteController{db: db, logger: logger, recent: newRecentBuffer(DefaultRecentBufferSize), clock: &clock.Source{}, faults: faults, shards: shards, recovery: &recoveryState{}, readOnly: true}, nil
+}
+
+// SetClock replaces the source of the current time used to stamp new
+// records and compute cutoffs relative to now, for this controller and its
+// ForTenant copies.
+//
+// Parameters:
+//   - clk: Clock to read, or nil for the system time
+func (c *SQLiteController) SetClock(clk clock.Clock) {
+	c.clock.Set(clk)
+}
+
+// now returns the current time from the controller's clock.
+func (c *SQLiteController) now() time.Time {
+	return c.clock.Now()
+}
+
+// ForTenant returns a controller scoped to tenant. It shares the connection
+// with c, so only the controller returned by NewSQLiteController should be
+// closed. A scoped controller stores new log records under the tenant, and
+// log record queries (QueryByTimeRange, QuerySince, GetAll and
+// QueryMinuteAggregates) only return that tenant's records. Other tables are
+// shared by all tenants.
+//
+// Parameters:
+//   - tenant: Tenant name; empty returns an unscoped controller
+//
+// Returns:
+//   - *SQLiteController: Scoped controller
+func (c *SQLiteController) ForTenant(tenant string) *SQLiteController {
+	scoped := *c
+	scoped.tenant = tenant
+	if tenant != "" {
+		scoped.logger = c.logger.With("tenant", tenant)
+	}
+	return &scoped
+}
+
+// Tenant returns the tenant the controller is scoped to, or empty if it sees
+// every tenant.
+func (c *SQLiteController) Tenant() string {
+	return c.tenant
+}
+
+// ForDataset returns a controller whose log record queries only return
+// records of dataset, in addition to any tenant scope of c. Like ForTenant,
+// it shares the connection with c. Records are stored under the dataset
+// they name, so a dataset-scoped controller does not change inserts.
+//
+// Parameters:
+//   - dataset: Logpush dataset name; empty returns c's scope unchanged
+//
+// Returns:
+//   - *SQLiteController: Scoped controller
+func (c *SQLiteController) ForDataset(dataset string) *SQLiteController {
+	scoped := *c
+	scoped.dataset = dataset
+	if dataset != "" {
+		scoped.logger = c.logger.With("dataset", dataset)
+	}
+	return &scoped
+}
+
+// Dataset returns the dataset the controller is scoped to, or empty if it
+// sees every dataset.
+func (c *SQLiteController) Dataset() string {
+	return c.dataset
+}
+
+// ForZone returns a controller whose log record queries only return records
+// pushed for zone, in addition to any tenant or dataset scope of c. Like
+// ForDataset, it shares the connection with c and does not change inserts.
+//
+// Parameters:
+//   - zone: Zone name or ID; empty returns c's scope unchanged
+//
+// Returns:
+//   - *SQLiteController: Scoped controller
+func (c *SQLiteController) ForZone(zone string) *SQLiteController {
+	scoped := *c
+	scoped.zone = zone
+	if zone != "" {
+		scoped.logger = c.logger.With("zone", zone)
+	}
+	return &scoped
+}
+
+// Zone returns the zone the controller is scoped to, or empty if it sees
+// every zone.
+func (c *SQLiteController) Zone() string {
+	return c.zone
+}
+
+// scoped reports whether log record queries are filtered to a tenant,
+// dataset, zone or labels.
+func (c *SQLiteController) scoped() bool {
+	return c.tenant != "" || c.dataset != "" || c.zone != "" || len(c.labels) > 0
+}
+
+// tenantFilter returns a condition (starting with " AND") restricting
+// log_sizes rows to the controller's tenant, dataset, zone and labels, and
+// its arguments.
+func (c *SQLiteController) tenantFilter() (string, []any) {
+	var filter string
+	var args []any
+	if c.tenant != "" {
+		filter += " AND tenant = ?"
+		args = append(args, c.tenant)
+	}
+	if c.dataset != "" {
+		filter += " AND dataset = ?"
+		args = append(args, c.dataset)
+	}
+	if c.zone != "" {
+		filter += " AND zone = ?"
+		args = append(args, c.zone)
+	}
+	labelFilter, labelArgs := c.labelFilter()
+	return filter + labelFilter, append(args, labelArgs...)
+}
+
+// InsertLogSize inserts a new log size record stamped with the current time
+// of the controller's clock (see SetClock).
 // This is the primary method for recording log data sizes as they are received.
 //
 // Parameters:
@@ -136,13 +397,97 @@ func NewSQLiteController(path string, logger *slog.Logger) (*SQLiteController, e
 //
 // The function automatically uses the current time as the timestamp for the record.
 func (c *SQLiteController) InsertLogSize(filesize int64) error {
-	c.logger.Info("Inserting log size", "filesize", filesize)
-	_, err := c.db.Exec(`INSERT INTO log_sizes (timestamp, filesize) VALUES (?, ?)`, time.Now(), filesize)
+	return c.InsertLog(LogSize{Filesize: filesize})
+}
+
+// InsertLog inserts a complete log record, including per-record statistics.
+// The ID field is ignored; a zero Timestamp is replaced with the current time,
+// and the timestamp is stored in UTC. On a scoped controller the record is
+// stored under its tenant, overriding entry.Tenant. The matching
+// minute_aggregates bucket is updated in the same transaction, and the record
+// is added to the in-memory buffer of recent records once committed.
+//
+// Parameters:
+//   - entry: Log record to store
+//
+// Returns:
+//   - error: Any error encountered during database insertion
+func (c *SQLiteController) InsertLog(entry LogSize) error {
+	return c.InsertLogs([]LogSize{entry})
+}
+
+// InsertLogs inserts several log records in one transaction, as InsertLog
+// does for one. Committing once per group rather than once per record is
+// what lets a WriteBuffer keep up with bursts of batches.
+//
+// Parameters:
+//   - entries: Log records to store, in insertion order
+//
+// Returns:
+//   - error: Any error encountered during database insertion; on error
+//     none of the records is stored
+func (c *SQLiteController) InsertLogs(entries []LogSize) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	entries = append([]LogSize(nil), entries...)
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Timestamp.IsZero() {
+			entry.Timestamp = c.now()
+		}
+		entry.Timestamp = entry.Timestamp.UTC()
+		if c.tenant != "" {
+			entry.Tenant = c.tenant
+		}
+		if entry.UncompressedSize == 0 {
+			entry.UncompressedSize = entry.Filesize
+		}
+		c.logger.Info("Inserting log size", "filesize", entry.Filesize, "record_count", entry.RecordCount)
+	}
+	c.recent.writeMu.Lock()
+	defer c.recent.writeMu.Unlock()
+	tx, err := c.db.Begin()
 	if err != nil {
-		c.logger.Error("Failed to insert log size", "error", err, "filesize", filesize)
+		c.logger.Error("Failed to begin insert transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	for i := range entries {
+		entry := &entries[i]
+		// New records go to the main database even when log_sizes is the
+		// view over the month shards; RotateShards moves them once their
+		// month closes
+		res, err := tx.Exec(`INSERT INTO main.log_sizes (timestamp, filesize, record_count, min_record_size, max_record_size, avg_record_size, tenant, dataset, uncompressed_size, content_encoding, zone, labels)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			entry.Timestamp, entry.Filesize, entry.RecordCount, entry.MinRecordSize, entry.MaxRecordSize, entry.AvgRecordSize, entry.Tenant, entry.Dataset,
+			entry.UncompressedSize, entry.Encoding, entry.Zone, encodeLabels(entry.Labels))
+		if err != nil {
+			c.logger.Error("Failed to insert log size", "error", err, "filesize", entry.Filesize)
+			return err
+		}
+		if entry.ID, err = res.LastInsertId(); err != nil {
+			c.logger.Error("Failed to read inserted log size ID", "error", err)
+			return err
+		}
+
+		// Keep the rolling per-minute rollup in step with the raw table
+		_, err = tx.Exec(upsertMinuteAggregate, entry.Timestamp.Truncate(time.Minute), entry.RecordCount, entry.Filesize)
+		if err != nil {
+			c.logger.Error("Failed to update minute aggregate", "error", err, "filesize", entry.Filesize)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.logger.Error("Failed to commit log size insert", "error", err)
 		return err
 	}
-	c.logger.Info("Log size inserted successfully", "filesize", filesize)
+	for _, entry := range entries {
+		c.recent.add(entry)
+		c.logger.Info("Log size inserted successfully", "filesize", entry.Filesize)
+	}
 	return nil
 }
 
@@ -157,10 +502,23 @@ func (c *SQLiteController) InsertLogSize(filesize int64) error {
 //   - []LogSize: Slice of log size records ordered by timestamp
 //   - error: Any error encountered during the query
 //
-// The results are automatically sorted by timestamp in ascending order.
+// The results are automatically sorted by timestamp in ascending order. The
+// bounds may be in any time zone; returned timestamps are in UTC. Ranges that
+// only cover recently inserted records are answered from memory.
 func (c *SQLiteController) QueryByTimeRange(start, end time.Time) ([]LogSize, error) {
+	if err := c.checkAttached(start); err != nil {
+		return nil, err
+	}
 	c.logger.Info("Querying log sizes by time range", "start", start, "end", end)
-	rows, err := c.db.Query(`SELECT id, timestamp, filesize FROM log_sizes WHERE timestamp >= ? AND timestamp < ? ORDER BY timestamp`, start, end)
+	if c.recentReady() {
+		if out, ok := c.recent.queryRange(start.UTC(), end.UTC(), c.tenant, c.dataset, c.zone, c.labels); ok {
+			c.logger.Info("Query served from recent records", "start", start, "end", end, "count", len(out))
+			return out, nil
+		}
+	}
+	filter, args := c.tenantFilter()
+	rows, err := c.db.Query(`SELECT `+logSizeSelectColumns+` FROM log_sizes WHERE timestamp >= ? AND timestamp < ?`+filter+` ORDER BY timestamp`,
+		append([]any{start.UTC(), end.UTC()}, args...)...)
 	if err != nil {
 		c.logger.Error("Failed to query log sizes by time range", "error", err, "start", start, "end", end)
 		return nil, err
@@ -168,8 +526,7 @@ func (c *SQLiteController) QueryByTimeRange(start, end time.Time) ([]LogSize, er
 	defer rows.Close()
 	var out []LogSize
 	for rows.Next() {
-		var l LogSize
-		err := rows.Scan(&l.ID, &l.Timestamp, &l.Filesize)
+		l, err := scanLogSize(rows)
 		if err != nil {
 			c.logger.Error("Failed to scan log size row", "error", err)
 			return nil, err
@@ -180,6 +537,161 @@ func (c *SQLiteController) QueryByTimeRange(start, end time.Time) ([]LogSize, er
 	return out, nil
 }
 
+// QueryByTimeRangePage returns one page of the log size records within a
+// time range, so listings of large ranges need not load every record.
+// Records are ordered by timestamp, then ID, so pages neither overlap nor
+// skip records that share a timestamp while the range is unchanged.
+//
+// Parameters:
+//   - start: Start time (inclusive)
+//   - end: End time (exclusive)
+//   - limit: Maximum number of records to return
+//   - offset: Number of records in the range to skip
+//
+// Returns:
+//   - []LogSize: The page of log size records
+//   - error: Any error encountered during the query
+//
+// Use CountByTimeRange for the number of records in the whole range.
+func (c *SQLiteController) QueryByTimeRangePage(start, end time.Time, limit, offset int) ([]LogSize, error) {
+	if err := c.checkAttached(start); err != nil {
+		return nil, err
+	}
+	c.logger.Info("Querying page of log sizes by time range", "start", start, "end", end, "limit", limit, "offset", offset)
+	filter, args := c.tenantFilter()
+	rows, err := c.db.Query(`SELECT `+logSizeSelectColumns+` FROM log_sizes WHERE timestamp >= ? AND timestamp < ?`+filter+` ORDER BY timestamp, id LIMIT ? OFFSET ?`,
+		append(append([]any{start.UTC(), end.UTC()}, args...), limit, offset)...)
+	if err != nil {
+		c.logger.Error("Failed to query page of log sizes", "error", err, "start", start, "end", end)
+		return nil, err
+	}
+	defer rows.Close()
+	var out []LogSize
+	for rows.Next() {
+		l, err := scanLogSize(rows)
+		if err != nil {
+			c.logger.Error("Failed to scan log size row", "error", err)
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	c.logger.Info("Page query completed successfully", "start", start, "end", end, "count", len(out))
+	return out, rows.Err()
+}
+
+// eachPageSize is how many records EachByTimeRange reads per query.
+const eachPageSize = 1000
+
+// EachByTimeRange calls fn with every log size record within a time range,
+// ordered by timestamp, then ID, without loading the range into memory.
+// Records are read a page at a time and no query is open while fn runs, so
+// a slow consumer, such as a client downloading an export, does not hold a
+// read lock that would stall ingestion.
+//
+// Parameters:
+//   - start: Start time (inclusive)
+//   - end: End time (exclusive)
+//   - fn: Called with each record; a non-nil error stops the iteration
+//
+// Returns:
+//   - error: The error returned by fn, or any error encountered during a query
+func (c *SQLiteController) EachByTimeRange(start, end time.Time, fn func(LogSize) error) error {
+	if err := c.checkAttached(start); err != nil {
+		return err
+	}
+	c.logger.Info("Iterating log sizes by time range", "start", start, "end", end)
+	filter, args := c.tenantFilter()
+	query := `SELECT ` + logSizeSelectColumns + ` FROM log_sizes WHERE timestamp >= ? AND timestamp < ?` + filter +
+		` AND (timestamp > ? OR (timestamp = ? AND id > ?)) ORDER BY timestamp, id LIMIT ?`
+
+	// Keyset pagination: each page starts after the last record of the
+	// previous one, so records inserted meanwhile neither shift nor repeat it
+	after, afterID := start.UTC(), int64(-1)
+	count := 0
+	for {
+		page, err := c.queryLogSizes(query, append(append([]any{start.UTC(), end.UTC()}, args...), after, after, afterID, eachPageSize)...)
+		if err != nil {
+			c.logger.Error("Failed to iterate log sizes by time range", "error", err, "start", start, "end", end)
+			return err
+		}
+		for _, l := range page {
+			if err := fn(l); err != nil {
+				return err
+			}
+		}
+		count += len(page)
+		if len(page) < eachPageSize {
+			c.logger.Info("Iteration completed successfully", "start", start, "end", end, "count", count)
+			return nil
+		}
+		last := page[len(page)-1]
+		after, afterID = last.Timestamp, last.ID
+	}
+}
+
+// queryLogSizes runs a query selecting logSizeSelectColumns and returns
+// every row.
+func (c *SQLiteController) queryLogSizes(query string, args ...any) ([]LogSize, error) {
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []LogSize
+	for rows.Next() {
+		l, err := scanLogSize(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+// QuerySince returns up to limit log size records whose ID is greater than
+// id, ordered by ID. IDs come from an AUTOINCREMENT column and are never
+// reused, so consumers can tail new records by passing the last ID they saw
+// without the duplicates or gaps that timestamp cursors suffer when several
+// records share a timestamp.
+//
+// Parameters:
+//   - id: Cursor; only records with a greater ID are returned (0 for all)
+//   - limit: Maximum number of records to return
+//
+// Returns:
+//   - []LogSize: Slice of log size records ordered by ID
+//   - error: Any error encountered during the query
+//
+// Cursors within the recently inserted records are answered from memory.
+func (c *SQLiteController) QuerySince(id int64, limit int) ([]LogSize, error) {
+	c.logger.Info("Querying log sizes since ID", "id", id, "limit", limit)
+	if c.recentReady() {
+		if out, ok := c.recent.querySince(id, limit, c.tenant, c.dataset, c.zone, c.labels); ok {
+			c.logger.Info("Query since served from recent records", "id", id, "count", len(out))
+			return out, nil
+		}
+	}
+	filter, args := c.tenantFilter()
+	rows, err := c.db.Query(`SELECT `+logSizeSelectColumns+` FROM log_sizes WHERE id > ?`+filter+` ORDER BY id LIMIT ?`,
+		append(append([]any{id}, args...), limit)...)
+	if err != nil {
+		c.logger.Error("Failed to query log sizes since ID", "error", err, "id", id)
+		return nil, err
+	}
+	defer rows.Close()
+	var out []LogSize
+	for rows.Next() {
+		l, err := scanLogSize(rows)
+		if err != nil {
+			c.logger.Error("Failed to scan log size row", "error", err)
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	c.logger.Info("Query since completed successfully", "id", id, "count", len(out))
+	return out, rows.Err()
+}
+
 // GetAll returns all log size records from the database.
 // This method retrieves every record in the log_sizes table, ordered by ID.
 // Use with caution on large datasets as it loads all records into memory.
@@ -190,8 +702,12 @@ func (c *SQLiteController) QueryByTimeRange(start, end time.Time) ([]LogSize, er
 //
 // For large datasets, consider using QueryByTimeRange instead to limit results.
 func (c *SQLiteController) GetAll() ([]LogSize, error) {
+	if err := c.checkAttached(time.Time{}); err != nil {
+		return nil, err
+	}
 	c.logger.Info("Querying all log sizes")
-	rows, err := c.db.Query(`SELECT id, timestamp, filesize FROM log_sizes ORDER BY id`)
+	filter, args := c.tenantFilter()
+	rows, err := c.db.Query(`SELECT `+logSizeSelectColumns+` FROM log_sizes WHERE 1 = 1`+filter+` ORDER BY id`, args...)
 	if err != nil {
 		c.logger.Error("Failed to query all log sizes", "error", err)
 		return nil, err
@@ -199,8 +715,7 @@ func (c *SQLiteController) GetAll() ([]LogSize, error) {
 	defer rows.Close()
 	var out []LogSize
 	for rows.Next() {
-		var l LogSize
-		err := rows.Scan(&l.ID, &l.Timestamp, &l.Filesize)
+		l, err := scanLogSize(rows)
 		if err != nil {
 			c.logger.Error("Failed to scan log size row", "error", err)
 			return nil, err
@@ -235,3 +750,18 @@ func (c *SQLiteController) Close() error {
 	}
 	return err
 }
+
+// Ping checks that the database can be read, by reading a row of the
+// log_sizes table. Health checks and the service watchdog use it to detect
+// a database that has become unreadable.
+//
+// Returns:
+//   - error: Any error encountered while reading
+func (c *SQLiteController) Ping() error {
+	var one int
+	err := c.db.QueryRow(`SELECT 1 FROM log_sizes LIMIT 1`).Scan(&one)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
@@ -1,11 +1,16 @@
 package database
 
 import (
+	"database/sql"
+	"errors"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/clock"
 )
 
 func TestNewSQLiteController(t *testing.T) {
@@ -30,6 +35,44 @@ func TestNewSQLiteController(t *testing.T) {
 	}
 }
 
+func TestOpenReadOnly(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	path := filepath.Join(t.TempDir(), "logpush.db")
+	if _, err := OpenReadOnly(path, logger); err == nil {
+		t.Fatal("Expected an error for a missing database")
+	}
+
+	writer, err := NewSQLiteController(path, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer writer.Close()
+	if err := writer.InsertLogSize(100); err != nil {
+		t.Fatalf("InsertLogSize failed: %v", err)
+	}
+
+	reader, err := OpenReadOnly(path, logger)
+	if err != nil {
+		t.Fatalf("OpenReadOnly failed: %v", err)
+	}
+	defer reader.Close()
+	logs, err := reader.GetAll()
+	if err != nil || len(logs) != 1 || logs[0].Filesize != 100 {
+		t.Errorf("Expected the writer's record, got %v %v", logs, err)
+	}
+	if err := reader.InsertLogSize(200); err == nil {
+		t.Error("Expected writes to a read-only database to fail")
+	}
+
+	// Records the writer adds later are read
+	if err := writer.InsertLogSize(300); err != nil {
+		t.Fatalf("InsertLogSize failed: %v", err)
+	}
+	if logs, err := reader.GetAll(); err != nil || len(logs) != 2 {
+		t.Errorf("Expected both of the writer's records, got %v %v", logs, err)
+	}
+}
+
 func TestNewSQLiteControllerDefaultPath(t *testing.T) {
 	// Test with default path
 	defer os.Remove("logpush.db")
@@ -134,6 +177,38 @@ func TestInsertLogSizeZero(t *testing.T) {
 	}
 }
 
+func TestInsertLogUncompressedSize(t *testing.T) {
+	tempFile := "test_insert_uncompressed.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	if err := controller.InsertLog(LogSize{Filesize: 100, UncompressedSize: 1000, Encoding: "gzip"}); err != nil {
+		t.Fatalf("Failed to insert gzip batch: %v", err)
+	}
+	if err := controller.InsertLogSize(200); err != nil {
+		t.Fatalf("Failed to insert log size: %v", err)
+	}
+
+	// Read from SQL rather than the recent buffer
+	controller.SetRecentBufferSize(0)
+	logSizes, err := controller.GetAll()
+	if err != nil || len(logSizes) != 2 {
+		t.Fatalf("Expected 2 log sizes, got %d (%v)", len(logSizes), err)
+	}
+	if l := logSizes[0]; l.UncompressedSize != 1000 || l.Encoding != "gzip" {
+		t.Errorf("Expected the gzip batch's decompressed size, got %+v", l)
+	}
+	if l := logSizes[1]; l.UncompressedSize != 200 || l.Encoding != "" {
+		t.Errorf("Expected an uncompressed batch to default to its filesize, got %+v", l)
+	}
+}
+
 func TestGetAll(t *testing.T) {
 	tempFile := "test_get_all.db"
 	defer os.Remove(tempFile)
@@ -185,7 +260,8 @@ func TestQueryByTimeRange(t *testing.T) {
 	defer controller.Close()
 
 	// Insert some log sizes
-	baseTime := time.Now().Add(-2 * time.Hour)
+	// Rows are stored in UTC; raw inserts bypass InsertLog so convert here
+	baseTime := time.Now().UTC().Add(-2 * time.Hour)
 
 	// Insert logs at different times using direct SQL to control timestamps
 	_, err = controller.db.Exec(`INSERT INTO log_sizes (timestamp, filesize) VALUES (?, ?)`,
@@ -249,6 +325,105 @@ func TestQueryByTimeRangeEmpty(t *testing.T) {
 	}
 }
 
+func TestQueryByTimeRangePage(t *testing.T) {
+	tempFile := "test_query_page.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	// Records sharing a timestamp are paged in ID order
+	ts := time.Now().UTC().Truncate(time.Second)
+	for i := int64(1); i <= 5; i++ {
+		if err := controller.InsertLog(LogSize{Timestamp: ts.Add(time.Duration(i/3) * time.Minute), Filesize: i * 100}); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+
+	start, end := ts.Add(-time.Hour), ts.Add(time.Hour)
+	var sizes []int64
+	for offset := 0; offset < 6; offset += 2 {
+		page, err := controller.QueryByTimeRangePage(start, end, 2, offset)
+		if err != nil {
+			t.Fatalf("Failed to query page: %v", err)
+		}
+		for _, l := range page {
+			sizes = append(sizes, l.Filesize)
+		}
+	}
+	if len(sizes) != 5 || sizes[0] != 100 || sizes[1] != 200 || sizes[2] != 300 || sizes[4] != 500 {
+		t.Errorf("Expected every record once in order, got %v", sizes)
+	}
+
+	if err := controller.ForTenant("acme").InsertLog(LogSize{Timestamp: ts, Filesize: 999}); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+	tenant, err := controller.ForTenant("acme").QueryByTimeRangePage(start, end, 10, 0)
+	if err != nil || len(tenant) != 1 || tenant[0].Filesize != 999 {
+		t.Errorf("Expected only the tenant's record, got %v, %v", tenant, err)
+	}
+
+	past, err := controller.QueryByTimeRangePage(start, end, 10, 100)
+	if err != nil || len(past) != 0 {
+		t.Errorf("Expected an empty page past the end, got %v, %v", past, err)
+	}
+}
+
+func TestEachByTimeRange(t *testing.T) {
+	tempFile := "test_each_range.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	// More records than a page, most sharing a timestamp with others, so
+	// pages must continue by ID within a timestamp
+	ts := time.Now().UTC().Truncate(time.Second)
+	entries := make([]LogSize, eachPageSize+5)
+	for i := range entries {
+		entries[i] = LogSize{Timestamp: ts.Add(time.Duration(i/300) * time.Second), Filesize: int64(i)}
+	}
+	if err := controller.InsertLogs(entries); err != nil {
+		t.Fatalf("Failed to insert logs: %v", err)
+	}
+	if err := controller.InsertLog(LogSize{Timestamp: ts.Add(time.Hour), Filesize: -1}); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+
+	var seen []int64
+	err = controller.EachByTimeRange(ts, ts.Add(time.Minute), func(l LogSize) error {
+		seen = append(seen, l.Filesize)
+		return nil
+	})
+	if err != nil || len(seen) != len(entries) {
+		t.Fatalf("Expected %d records, got %d (%v)", len(entries), len(seen), err)
+	}
+	for i, size := range seen {
+		if size != int64(i) {
+			t.Fatalf("Expected records in order, got %d at %d", size, i)
+		}
+	}
+
+	// An error from fn stops the iteration and is returned
+	stop := errors.New("stop")
+	calls := 0
+	err = controller.EachByTimeRange(ts, ts.Add(time.Minute), func(LogSize) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected the iteration to stop at the first error, got %v after %d calls", err, calls)
+	}
+}
+
 func TestClose(t *testing.T) {
 	tempFile := "test_close.db"
 	defer os.Remove(tempFile)
@@ -272,6 +447,79 @@ func TestClose(t *testing.T) {
 	}
 }
 
+func TestPing(t *testing.T) {
+	tempFile := "test_ping.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+
+	if err := controller.Ping(); err != nil {
+		t.Errorf("Ping of an empty database failed: %v", err)
+	}
+	controller.Close()
+	if err := controller.Ping(); err == nil {
+		t.Error("Expected Ping to fail after Close")
+	}
+}
+
+func TestSetClockAcrossDST(t *testing.T) {
+	tempFile := "test_set_clock.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone data unavailable: %v", err)
+	}
+	// 01:30 EST, half an hour before clocks in New York jump to 03:00 EDT.
+	current := time.Date(2024, time.March, 10, 1, 30, 0, 0, newYork)
+	controller.SetClock(clock.Func(func() time.Time { return current }))
+
+	if err := controller.InsertLogSize(1024); err != nil {
+		t.Fatalf("Failed to insert log size: %v", err)
+	}
+	current = current.Add(time.Hour) // 03:30 EDT
+	if err := controller.ForTenant("acme").InsertLogSize(2048); err != nil {
+		t.Fatalf("Failed to insert log size: %v", err)
+	}
+
+	logs, err := controller.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+	want := []time.Time{
+		time.Date(2024, time.March, 10, 6, 30, 0, 0, time.UTC),
+		time.Date(2024, time.March, 10, 7, 30, 0, 0, time.UTC),
+	}
+	if len(logs) != len(want) {
+		t.Fatalf("Expected %d logs, got %d", len(want), len(logs))
+	}
+	for i, l := range logs {
+		if !l.Timestamp.Equal(want[i]) || l.Timestamp.Location() != time.UTC {
+			t.Errorf("Log %d: expected timestamp %v, got %v", i, want[i], l.Timestamp)
+		}
+	}
+
+	controller.SetClock(nil)
+	if err := controller.InsertLogSize(4096); err != nil {
+		t.Fatalf("Failed to insert log size: %v", err)
+	}
+	logs, _ = controller.GetAll()
+	if got := logs[len(logs)-1].Timestamp; time.Since(got) > time.Minute {
+		t.Errorf("Expected the system time after SetClock(nil), got %v", got)
+	}
+}
+
 func TestConcurrentInserts(t *testing.T) {
 	tempFile := "test_concurrent.db"
 	defer os.Remove(tempFile)
@@ -327,3 +575,164 @@ func TestConcurrentInserts(t *testing.T) {
 		t.Errorf("Expected %d log sizes after concurrent inserts, got %d", expectedCount, len(logSizes))
 	}
 }
+
+func TestInsertLogWithRecordStats(t *testing.T) {
+	tempFile := "test_insert_records.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	entry := LogSize{Filesize: 300, RecordCount: 3, MinRecordSize: 50, MaxRecordSize: 150, AvgRecordSize: 99.5}
+	if err := controller.InsertLog(entry); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+
+	logSizes, err := controller.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to query log sizes: %v", err)
+	}
+	if len(logSizes) != 1 {
+		t.Fatalf("Expected 1 log size, got %d", len(logSizes))
+	}
+
+	got := logSizes[0]
+	if got.RecordCount != 3 || got.MinRecordSize != 50 || got.MaxRecordSize != 150 || got.AvgRecordSize != 99.5 {
+		t.Errorf("Record stats not persisted correctly: %+v", got)
+	}
+}
+
+func TestMigrateLegacySchema(t *testing.T) {
+	tempFile := "test_migrate_legacy.db"
+	defer os.Remove(tempFile)
+
+	// Create a database using the original three-column schema
+	legacy, err := sql.Open("sqlite3", tempFile)
+	if err != nil {
+		t.Fatalf("Failed to open legacy database: %v", err)
+	}
+	_, err = legacy.Exec(`CREATE TABLE log_sizes (id INTEGER PRIMARY KEY AUTOINCREMENT, timestamp DATETIME NOT NULL, filesize INTEGER NOT NULL);
+		INSERT INTO log_sizes (timestamp, filesize) VALUES (CURRENT_TIMESTAMP, 42);`)
+	legacy.Close()
+	if err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to open legacy database with controller: %v", err)
+	}
+	defer controller.Close()
+
+	logSizes, err := controller.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to query migrated database: %v", err)
+	}
+	if len(logSizes) != 1 || logSizes[0].Filesize != 42 || logSizes[0].RecordCount != 0 {
+		t.Errorf("Unexpected rows after migration: %+v", logSizes)
+	}
+}
+
+func TestNormalizeLocalTimestampsToUTC(t *testing.T) {
+	tempFile := "test_normalize_utc.db"
+	defer os.Remove(tempFile)
+
+	// Rows written in local time by older versions carry a non-UTC offset
+	legacy, err := sql.Open("sqlite3", tempFile)
+	if err != nil {
+		t.Fatalf("Failed to open legacy database: %v", err)
+	}
+	_, err = legacy.Exec(`CREATE TABLE log_sizes (id INTEGER PRIMARY KEY AUTOINCREMENT, timestamp DATETIME NOT NULL, filesize INTEGER NOT NULL);
+		INSERT INTO log_sizes (timestamp, filesize) VALUES ('2024-01-01 14:30:00+02:00', 1);
+		INSERT INTO log_sizes (timestamp, filesize) VALUES ('2024-01-01 12:45:00+00:00', 2);`)
+	legacy.Close()
+	if err != nil {
+		t.Fatalf("Failed to create legacy rows: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to open legacy database with controller: %v", err)
+	}
+	defer controller.Close()
+
+	// Both rows fall in the 12:00 UTC hour once normalized
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	logs, err := controller.QueryByTimeRange(start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query normalized rows: %v", err)
+	}
+	if len(logs) != 2 || logs[0].Filesize != 1 {
+		t.Fatalf("Expected both rows in UTC order, got %+v", logs)
+	}
+	if logs[0].Timestamp.Location() != time.UTC || logs[0].Timestamp.Hour() != 12 {
+		t.Errorf("Expected 12:30 UTC, got %v", logs[0].Timestamp)
+	}
+
+	// New records in another zone are stored as the same instant in UTC
+	zone := time.FixedZone("UTC-5", -5*60*60)
+	if err := controller.InsertLog(LogSize{Timestamp: time.Date(2024, 1, 1, 7, 50, 0, 0, zone), Filesize: 3}); err != nil {
+		t.Fatalf("Failed to insert zoned record: %v", err)
+	}
+	logs, _ = controller.QueryByTimeRange(start.In(zone), start.Add(time.Hour).In(zone))
+	if len(logs) != 3 || logs[2].Filesize != 3 {
+		t.Errorf("Expected zoned insert and zoned bounds to match UTC rows, got %+v", logs)
+	}
+}
+
+func TestQuerySince(t *testing.T) {
+	tempFile := "test_query_since.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	// Records sharing a timestamp are still distinguished by ID
+	ts := time.Now().UTC().Truncate(time.Second)
+	for i := int64(1); i <= 5; i++ {
+		if err := controller.InsertLog(LogSize{Timestamp: ts, Filesize: i * 100}); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+
+	first, err := controller.QuerySince(0, 3)
+	if err != nil {
+		t.Fatalf("Failed to query since: %v", err)
+	}
+	if len(first) != 3 || first[0].Filesize != 100 || first[2].Filesize != 300 {
+		t.Fatalf("Expected first three records, got %+v", first)
+	}
+
+	rest, err := controller.QuerySince(first[2].ID, 10)
+	if err != nil {
+		t.Fatalf("Failed to query since: %v", err)
+	}
+	if len(rest) != 2 || rest[0].Filesize != 400 || rest[1].Filesize != 500 {
+		t.Fatalf("Expected remaining two records, got %+v", rest)
+	}
+
+	// A deleted tail must not cause IDs to be reused
+	if _, err := controller.db.Exec(`DELETE FROM log_sizes WHERE id = ?`, rest[1].ID); err != nil {
+		t.Fatalf("Failed to delete record: %v", err)
+	}
+	if err := controller.InsertLogSize(600); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+	next, err := controller.QuerySince(rest[1].ID, 10)
+	if err != nil {
+		t.Fatalf("Failed to query since: %v", err)
+	}
+	if len(next) != 1 || next[0].Filesize != 600 {
+		t.Fatalf("Expected only the new record after the cursor, got %+v", next)
+	}
+}
@@ -0,0 +1,194 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LogStats summarizes the log records in a range, computed in SQL.
+type LogStats struct {
+	Batches           int64     // Number of stored log batches
+	Records           int64     // Sum of records (log lines) across the batches
+	Bytes             int64     // Sum of batch sizes as received
+	UncompressedBytes int64     // Sum of batch sizes after decompression
+	AvgSize           float64   // Average batch size as received
+	MinSize           int64     // Smallest batch as received
+	MaxSize           int64     // Largest batch as received
+	AvgRecordSize     float64   // Mean record size weighted by record count; 0 without records
+	Last              time.Time // Timestamp of the newest batch, to the second; zero when there are none
+}
+
+// TimeBucket is the number and size of the log records in one time bucket.
+type TimeBucket struct {
+	Start   time.Time // Start of the bucket, UTC
+	Batches int64     // Number of stored log batches
+	Records int64     // Sum of records (log lines) across the batches
+	Bytes   int64     // Sum of batch sizes as received
+}
+
+// rangeFilter returns the WHERE conditions selecting [start, end) within
+// the controller's scope, leaving a side open when its bound is zero.
+func (c *SQLiteController) rangeFilter(start, end time.Time) (string, []any) {
+	where := ` WHERE 1 = 1`
+	var args []any
+	if !start.IsZero() {
+		where += ` AND timestamp >= ?`
+		args = append(args, start.UTC())
+	}
+	if !end.IsZero() {
+		where += ` AND timestamp < ?`
+		args = append(args, end.UTC())
+	}
+	filter, filterArgs := c.tenantFilter()
+	return where + filter, append(args, filterArgs...)
+}
+
+// StatsByTimeRange computes the totals, average, extremes and newest
+// timestamp of the log records in [start, end) with aggregate SQL, so no
+// rows are transferred. A zero start or end leaves that side of the range
+// open.
+//
+// Parameters:
+//   - start: Start time (inclusive), or zero for no lower bound
+//   - end: End time (exclusive), or zero for no upper bound
+//
+// Returns:
+//   - LogStats: Summary of the range; zero when it holds no records
+//   - error: Any error encountered during the query
+//
+// Records stored before uncompressed sizes were recorded count their
+// received size as uncompressed. Batches stored before records were
+// counted contribute no records.
+func (c *SQLiteController) StatsByTimeRange(start, end time.Time) (LogStats, error) {
+	if err := c.checkAttached(start); err != nil {
+		return LogStats{}, err
+	}
+	c.logger.Info("Computing log size statistics by time range", "start", start, "end", end)
+	where, args := c.rangeFilter(start, end)
+	var (
+		s           LogStats
+		avg         sql.NullFloat64
+		recordBytes float64
+		last        sql.NullString
+	)
+	err := c.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(record_count), 0), COALESCE(SUM(filesize), 0),
+		COALESCE(SUM(CASE WHEN uncompressed_size > 0 THEN uncompressed_size ELSE filesize END), 0),
+		AVG(filesize), COALESCE(MIN(filesize), 0), COALESCE(MAX(filesize), 0),
+		COALESCE(SUM(avg_record_size * record_count), 0),
+		strftime('%Y-%m-%d %H:%M:%S', MAX(timestamp))
+		FROM log_sizes`+where, args...).Scan(&s.Batches, &s.Records, &s.Bytes, &s.UncompressedBytes, &avg, &s.MinSize, &s.MaxSize,
+		&recordBytes, &last)
+	if err != nil {
+		c.logger.Error("Failed to compute log size statistics", "error", err)
+		return LogStats{}, err
+	}
+	s.AvgSize = avg.Float64
+	if s.Records > 0 {
+		s.AvgRecordSize = recordBytes / float64(s.Records)
+	}
+	if last.Valid {
+		if s.Last, err = time.Parse(time.DateTime, last.String); err != nil {
+			c.logger.Error("Failed to parse newest timestamp", "error", err, "timestamp", last.String)
+			return LogStats{}, err
+		}
+	}
+	return s, nil
+}
+
+// BucketsByTimeRange sums the log records in [start, end) into buckets of
+// a fixed length with GROUP BY, so no rows are transferred. Buckets are
+// aligned to the Unix epoch, so hours and days start on UTC boundaries. A
+// zero start or end leaves that side of the range open.
+//
+// Parameters:
+//   - start: Start time (inclusive), or zero for no lower bound
+//   - end: End time (exclusive), or zero for no upper bound
+//   - bucket: Bucket length; a whole number of seconds, e.g. time.Hour
+//
+// Returns:
+//   - []TimeBucket: One bucket per interval holding records, oldest first
+//   - error: Any error encountered during the query
+func (c *SQLiteController) BucketsByTimeRange(start, end time.Time, bucket time.Duration) ([]TimeBucket, error) {
+	if err := c.checkAttached(start); err != nil {
+		return nil, err
+	}
+	c.logger.Info("Bucketing log sizes by time range", "start", start, "end", end, "bucket", bucket)
+	seconds := int64(bucket / time.Second)
+	if seconds <= 0 || bucket%time.Second != 0 {
+		return nil, fmt.Errorf("bucket must be a whole number of seconds, got %s", bucket)
+	}
+	where, args := c.rangeFilter(start, end)
+	rows, err := c.db.Query(`SELECT CAST(strftime('%s', timestamp) AS INTEGER) / ? * ? AS bucket, COUNT(*), SUM(record_count), SUM(filesize)
+		FROM log_sizes`+where+` GROUP BY bucket ORDER BY bucket`, append([]any{seconds, seconds}, args...)...)
+	if err != nil {
+		c.logger.Error("Failed to bucket log sizes", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []TimeBucket{}
+	for rows.Next() {
+		var (
+			b    TimeBucket
+			unix int64
+		)
+		if err := rows.Scan(&unix, &b.Batches, &b.Records, &b.Bytes); err != nil {
+			c.logger.Error("Failed to scan time bucket row", "error", err)
+			return nil, err
+		}
+		b.Start = time.Unix(unix, 0).UTC()
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// CountBySize counts the log records in [start, end) whose received size
+// falls in each of the ranges delimited by bounds, in one aggregate query.
+// A zero start or end leaves that side of the range open.
+//
+// Parameters:
+//   - start: Start time (inclusive), or zero for no lower bound
+//   - end: End time (exclusive), or zero for no upper bound
+//   - bounds: Ascending range boundaries in bytes
+//
+// Returns:
+//   - []int64: len(bounds)+1 counts: below bounds[0], each [bounds[i-1],
+//     bounds[i]), and at or above the last bound
+//   - error: Any error encountered during the query
+func (c *SQLiteController) CountBySize(start, end time.Time, bounds []float64) ([]int64, error) {
+	if err := c.checkAttached(start); err != nil {
+		return nil, err
+	}
+	c.logger.Info("Counting log sizes by size range", "start", start, "end", end, "ranges", len(bounds)+1)
+	where, args := c.rangeFilter(start, end)
+	sums := make([]string, 0, len(bounds)+1)
+	var boundArgs []any
+	for i, b := range bounds {
+		if i == 0 {
+			sums = append(sums, `COALESCE(SUM(filesize < ?), 0)`)
+			boundArgs = append(boundArgs, b)
+			continue
+		}
+		sums = append(sums, `COALESCE(SUM(filesize >= ? AND filesize < ?), 0)`)
+		boundArgs = append(boundArgs, bounds[i-1], b)
+	}
+	if len(bounds) == 0 {
+		sums = append(sums, `COUNT(*)`)
+	} else {
+		sums = append(sums, `COALESCE(SUM(filesize >= ?), 0)`)
+		boundArgs = append(boundArgs, bounds[len(bounds)-1])
+	}
+
+	counts := make([]int64, len(sums))
+	dest := make([]any, len(counts))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+	err := c.db.QueryRow(`SELECT `+strings.Join(sums, ", ")+` FROM log_sizes`+where, append(boundArgs, args...)...).Scan(dest...)
+	if err != nil {
+		c.logger.Error("Failed to count log sizes by size range", "error", err)
+		return nil, err
+	}
+	return counts, nil
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAggregateStatsInSQL(t *testing.T) {
+	tempFile := "test_stats.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	hour := time.Date(2025, 9, 15, 12, 0, 0, 0, time.UTC)
+	for _, l := range []LogSize{
+		{Timestamp: hour.Add(5 * time.Minute), Filesize: 500, RecordCount: 10, AvgRecordSize: 50},
+		{Timestamp: hour.Add(59*time.Minute + 59*time.Second + 500*time.Millisecond), Filesize: 1500, UncompressedSize: 6000, RecordCount: 30, AvgRecordSize: 200},
+		// Stored before records were counted
+		{Timestamp: hour.Add(2*time.Hour + 30*time.Second), Filesize: 20000},
+	} {
+		if err := db.InsertLog(l); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+	if err := db.ForTenant("acme").InsertLog(LogSize{Timestamp: hour, Filesize: 7}); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+
+	stats, err := db.StatsByTimeRange(hour.Add(time.Minute), time.Time{})
+	if err != nil {
+		t.Fatalf("StatsByTimeRange failed: %v", err)
+	}
+	want := LogStats{Batches: 3, Records: 40, Bytes: 22000, UncompressedBytes: 26500, AvgSize: 22000.0 / 3, MinSize: 500, MaxSize: 20000,
+		AvgRecordSize: 162.5, Last: hour.Add(2*time.Hour + 30*time.Second)}
+	if stats != want {
+		t.Errorf("Expected %+v, got %+v", want, stats)
+	}
+	if empty, err := db.StatsByTimeRange(hour.Add(-time.Hour), hour); err != nil || empty != (LogStats{}) {
+		t.Errorf("Expected zero statistics for an empty range, got %+v, %v", empty, err)
+	}
+	if tenant, err := db.ForTenant("acme").StatsByTimeRange(time.Time{}, time.Time{}); err != nil || tenant.Batches != 1 || tenant.Bytes != 7 {
+		t.Errorf("Expected only the tenant's record, got %+v, %v", tenant, err)
+	}
+
+	buckets, err := db.BucketsByTimeRange(hour.Add(time.Minute), hour.Add(24*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("BucketsByTimeRange failed: %v", err)
+	}
+	if len(buckets) != 2 || buckets[0] != (TimeBucket{Start: hour, Batches: 2, Records: 40, Bytes: 2000}) ||
+		buckets[1] != (TimeBucket{Start: hour.Add(2 * time.Hour), Batches: 1, Bytes: 20000}) {
+		t.Errorf("Unexpected hourly buckets %+v", buckets)
+	}
+	if days, err := db.BucketsByTimeRange(time.Time{}, time.Time{}, 24*time.Hour); err != nil || len(days) != 1 || days[0].Batches != 4 {
+		t.Errorf("Expected every record in one day, got %+v, %v", days, err)
+	}
+	if _, err := db.BucketsByTimeRange(time.Time{}, time.Time{}, time.Millisecond); err == nil {
+		t.Error("Expected an error for a bucket shorter than a second")
+	}
+
+	counts, err := db.CountBySize(time.Time{}, time.Time{}, []float64{1000, 10000})
+	if err != nil {
+		t.Fatalf("CountBySize failed: %v", err)
+	}
+	if len(counts) != 3 || counts[0] != 2 || counts[1] != 1 || counts[2] != 1 {
+		t.Errorf("Expected 2, 1 and 1 records per size range, got %v", counts)
+	}
+	if counts, err := db.CountBySize(hour.Add(-time.Hour), hour, []float64{1000}); err != nil || counts[0] != 0 || counts[1] != 0 {
+		t.Errorf("Expected zero counts for an empty range, got %v, %v", counts, err)
+	}
+}
@@ -0,0 +1,125 @@
+package database
+
+import "time"
+
+// TenantUsage summarizes the log records stored for one tenant.
+type TenantUsage struct {
+	Tenant      string `json:"tenant"`       // Tenant name; empty for the default tenant
+	Records     int64  `json:"records"`      // Number of stored log batches
+	TotalSize   int64  `json:"total_size"`   // Sum of batch sizes in bytes
+	RecordCount int64  `json:"record_count"` // Sum of log lines across the batches
+}
+
+// ListTenants returns every tenant with stored log records, ordered by name.
+// Records ingested without a tenant are reported under the empty name.
+//
+// Returns:
+//   - []TenantUsage: Stored usage per tenant
+//   - error: Any error encountered during the query
+func (c *SQLiteController) ListTenants() ([]TenantUsage, error) {
+	return c.queryTenantUsage(`SELECT tenant, COUNT(*), COALESCE(SUM(filesize), 0), COALESCE(SUM(record_count), 0)
+		FROM log_sizes GROUP BY tenant ORDER BY tenant`)
+}
+
+// QueryTenantUsage returns the usage of every tenant with records in
+// [start, end), ordered by name.
+//
+// Parameters:
+//   - start: Start time (inclusive)
+//   - end: End time (exclusive)
+//
+// Returns:
+//   - []TenantUsage: Usage per tenant within the range
+//   - error: Any error encountered during the query
+func (c *SQLiteController) QueryTenantUsage(start, end time.Time) ([]TenantUsage, error) {
+	if err := c.checkAttached(start); err != nil {
+		return nil, err
+	}
+	return c.queryTenantUsage(`SELECT tenant, COUNT(*), COALESCE(SUM(filesize), 0), COALESCE(SUM(record_count), 0)
+		FROM log_sizes WHERE timestamp >= ? AND timestamp < ? GROUP BY tenant ORDER BY tenant`, start.UTC(), end.UTC())
+}
+
+// TenantHourUsage is a tenant's usage within one hour.
+type TenantHourUsage struct {
+	TenantUsage
+	Hour time.Time `json:"hour"` // Start of the hour, UTC
+}
+
+// QueryTenantHourlyUsage returns the usage of every tenant in each hour of
+// [start, end) in which it has records, ordered by tenant and hour.
+//
+// Parameters:
+//   - start: Start time (inclusive)
+//   - end: End time (exclusive)
+//
+// Returns:
+//   - []TenantHourUsage: Usage per tenant and hour within the range
+//   - error: Any error encountered during the query
+func (c *SQLiteController) QueryTenantHourlyUsage(start, end time.Time) ([]TenantHourUsage, error) {
+	if err := c.checkAttached(start); err != nil {
+		return nil, err
+	}
+	rows, err := c.db.Query(`SELECT tenant, strftime('%Y-%m-%d %H:00:00', timestamp) AS hour, COUNT(*), SUM(filesize), SUM(record_count)
+		FROM log_sizes WHERE timestamp >= ? AND timestamp < ? GROUP BY tenant, hour ORDER BY tenant, hour`, start.UTC(), end.UTC())
+	if err != nil {
+		c.logger.Error("Failed to query hourly tenant usage", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []TenantHourUsage{}
+	for rows.Next() {
+		var (
+			u    TenantHourUsage
+			hour string
+		)
+		if err := rows.Scan(&u.Tenant, &hour, &u.Records, &u.TotalSize, &u.RecordCount); err != nil {
+			c.logger.Error("Failed to scan hourly tenant row", "error", err)
+			return nil, err
+		}
+		if u.Hour, err = time.Parse(time.DateTime, hour); err != nil {
+			c.logger.Error("Failed to parse tenant hour", "error", err, "hour", hour)
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// queryTenantUsage runs a query selecting tenant, batches, bytes and
+// records per tenant.
+func (c *SQLiteController) queryTenantUsage(query string, args ...any) ([]TenantUsage, error) {
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		c.logger.Error("Failed to query tenant usage", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []TenantUsage{}
+	for rows.Next() {
+		var u TenantUsage
+		if err := rows.Scan(&u.Tenant, &u.Records, &u.TotalSize, &u.RecordCount); err != nil {
+			c.logger.Error("Failed to scan tenant row", "error", err)
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// TenantExists reports whether any log records are stored for tenant.
+//
+// Parameters:
+//   - tenant: Tenant name
+//
+// Returns:
+//   - bool: Whether the tenant has stored records
+//   - error: Any error encountered during the query
+func (c *SQLiteController) TenantExists(tenant string) (bool, error) {
+	var exists bool
+	err := c.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM log_sizes WHERE tenant = ?)`, tenant).Scan(&exists)
+	if err != nil {
+		c.logger.Error("Failed to check tenant", "error", err, "tenant", tenant)
+		return false, err
+	}
+	return exists, nil
+}
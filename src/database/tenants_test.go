@@ -0,0 +1,106 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestForTenantScopesLogRecords(t *testing.T) {
+	tempFile := "test_tenants.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	acme := controller.ForTenant("acme")
+	globex := controller.ForTenant("globex")
+	at := time.Date(2025, 9, 15, 10, 0, 30, 0, time.UTC)
+
+	if err := acme.InsertLog(LogSize{Timestamp: at, Filesize: 100, RecordCount: 2}); err != nil {
+		t.Fatalf("Failed to insert acme log: %v", err)
+	}
+	if err := acme.InsertLog(LogSize{Timestamp: at.Add(10 * time.Second), Filesize: 50, RecordCount: 1}); err != nil {
+		t.Fatalf("Failed to insert acme log: %v", err)
+	}
+	// The scope overrides any tenant set on the entry
+	if err := globex.InsertLog(LogSize{Timestamp: at, Filesize: 300, Tenant: "acme"}); err != nil {
+		t.Fatalf("Failed to insert globex log: %v", err)
+	}
+	if err := controller.InsertLog(LogSize{Timestamp: at, Filesize: 7}); err != nil {
+		t.Fatalf("Failed to insert default log: %v", err)
+	}
+
+	logs, err := acme.QueryByTimeRange(at.Add(-time.Minute), at.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to query acme range: %v", err)
+	}
+	if len(logs) != 2 || logs[0].Tenant != "acme" || logs[1].Tenant != "acme" {
+		t.Errorf("Expected acme's two records, got %+v", logs)
+	}
+
+	all, err := controller.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get all logs: %v", err)
+	}
+	if len(all) != 4 {
+		t.Errorf("Expected unscoped controller to see all 4 records, got %d", len(all))
+	}
+
+	since, err := globex.QuerySince(0, 10)
+	if err != nil {
+		t.Fatalf("Failed to query globex since: %v", err)
+	}
+	if len(since) != 1 || since[0].Filesize != 300 {
+		t.Errorf("Expected globex's record only, got %+v", since)
+	}
+
+	minutes, err := acme.QueryMinuteAggregates(at.Add(-time.Hour), at.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query acme minutes: %v", err)
+	}
+	if len(minutes) != 1 || minutes[0].Batches != 2 || minutes[0].Records != 3 || minutes[0].TotalSize != 150 ||
+		!minutes[0].Minute.Equal(at.Truncate(time.Minute)) {
+		t.Errorf("Expected one acme minute of 2 batches, got %+v", minutes)
+	}
+
+	tenants, err := controller.ListTenants()
+	if err != nil {
+		t.Fatalf("Failed to list tenants: %v", err)
+	}
+	if len(tenants) != 3 || tenants[0].Tenant != "" || tenants[1].Tenant != "acme" || tenants[1].TotalSize != 150 || tenants[2].Records != 1 {
+		t.Errorf("Unexpected tenant usage: %+v", tenants)
+	}
+
+	usage, err := controller.QueryTenantUsage(at, at.Add(5*time.Second))
+	if err != nil {
+		t.Fatalf("Failed to query tenant usage: %v", err)
+	}
+	if len(usage) != 3 || usage[1].Records != 1 || usage[1].RecordCount != 2 || usage[1].TotalSize != 100 {
+		t.Errorf("Expected only acme's first batch in range, got %+v", usage)
+	}
+
+	if err := acme.InsertLog(LogSize{Timestamp: at.Add(time.Hour), Filesize: 20, RecordCount: 4}); err != nil {
+		t.Fatalf("Failed to insert acme log: %v", err)
+	}
+	hourly, err := controller.QueryTenantHourlyUsage(at.Add(-time.Hour), at.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query hourly usage: %v", err)
+	}
+	if len(hourly) != 4 || hourly[1].Tenant != "acme" || hourly[1].Records != 2 || hourly[1].TotalSize != 150 ||
+		!hourly[1].Hour.Equal(at.Truncate(time.Hour)) || !hourly[2].Hour.Equal(at.Truncate(time.Hour).Add(time.Hour)) {
+		t.Errorf("Expected acme's usage in two hours, got %+v", hourly)
+	}
+
+	if ok, err := controller.TenantExists("acme"); err != nil || !ok {
+		t.Errorf("Expected acme to exist, got %v (%v)", ok, err)
+	}
+	if ok, err := controller.TenantExists("initech"); err != nil || ok {
+		t.Errorf("Expected initech not to exist, got %v (%v)", ok, err)
+	}
+}
@@ -0,0 +1,236 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// createTrashTables holds the DDL for deleted log records. Each delete that
+// removes rows creates one trash_batches row; the removed rows are kept in
+// deleted_log_sizes under that batch, with their original IDs, until the
+// batch is restored or purged.
+const createTrashTables = `CREATE TABLE IF NOT EXISTS trash_batches (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	deleted_at DATETIME NOT NULL,
+	range_start DATETIME NOT NULL,
+	range_end DATETIME NOT NULL,
+	row_count INTEGER NOT NULL,
+	byte_count INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS deleted_log_sizes (
+	id INTEGER PRIMARY KEY,
+	batch_id INTEGER NOT NULL,
+	timestamp DATETIME NOT NULL,
+	filesize INTEGER NOT NULL,
+	record_count INTEGER NOT NULL DEFAULT 0,
+	min_record_size INTEGER NOT NULL DEFAULT 0,
+	max_record_size INTEGER NOT NULL DEFAULT 0,
+	avg_record_size REAL NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_deleted_log_sizes_batch ON deleted_log_sizes(batch_id);`
+
+// DefaultTrashRetention is how long deleted records stay restorable when no
+// retention is configured.
+const DefaultTrashRetention = 7 * 24 * time.Hour
+
+// TrashBatch describes the records removed by one delete.
+type TrashBatch struct {
+	ID        int64     `json:"id"`         // Batch identifier, used to restore
+	DeletedAt time.Time `json:"deleted_at"` // When the delete ran
+	Start     time.Time `json:"start"`      // Deleted range start (inclusive)
+	End       time.Time `json:"end"`        // Deleted range end (exclusive)
+	Rows      int64     `json:"rows"`       // Number of records in the batch
+	Bytes     int64     `json:"bytes"`      // Sum of their filesize values
+}
+
+// trashColumns are the log_sizes columns copied to and from the trash
+// alongside the original ID.
+const trashColumns = `timestamp, filesize, record_count, min_record_size, max_record_size, avg_record_size, tenant, dataset, uncompressed_size, content_encoding, zone, labels`
+
+// moveRangeToTrash copies the log records in the [start, end) range that
+// match filter into a new trash batch deleted at deletedAt within tx and
+// returns the batch ID. args holds start, end and the filter's arguments.
+// The caller deletes the originals.
+func moveRangeToTrash(tx *sql.Tx, filter string, args []any, deletedAt time.Time, s DeletionSummary) (int64, error) {
+	res, err := tx.Exec(`INSERT INTO trash_batches (deleted_at, range_start, range_end, row_count, byte_count) VALUES (?, ?, ?, ?, ?)`,
+		deletedAt.UTC(), args[0], args[1], s.Rows, s.Bytes)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(`INSERT INTO deleted_log_sizes (id, batch_id, `+trashColumns+`)
+		SELECT id, ?, `+trashColumns+` FROM log_sizes WHERE timestamp >= ? AND timestamp < ?`+filter, append([]any{id}, args...)...)
+	return id, err
+}
+
+// ListTrash returns the restorable delete batches, newest first.
+//
+// Returns:
+//   - []TrashBatch: Batches currently in the trash
+//   - error: Any error encountered during the query
+func (c *SQLiteController) ListTrash() ([]TrashBatch, error) {
+	rows, err := c.db.Query(`SELECT id, deleted_at, range_start, range_end, row_count, byte_count FROM trash_batches ORDER BY id DESC`)
+	if err != nil {
+		c.logger.Error("Failed to query trash", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []TrashBatch{}
+	for rows.Next() {
+		var b TrashBatch
+		if err := rows.Scan(&b.ID, &b.DeletedAt, &b.Start, &b.End, &b.Rows, &b.Bytes); err != nil {
+			c.logger.Error("Failed to scan trash batch row", "error", err)
+			return nil, err
+		}
+		b.DeletedAt, b.Start, b.End = b.DeletedAt.UTC(), b.Start.UTC(), b.End.UTC()
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// RestoreTrash moves the records of a trash batch back into log_sizes with
+// their original IDs and rebuilds the minute aggregates for restored records
+// that fall inside MinuteAggregateWindow. The batch is removed from the trash.
+//
+// Parameters:
+//   - id: Trash batch to restore
+//
+// Returns:
+//   - DeletionSummary: Rows and bytes restored
+//   - bool: Whether the batch exists
+//   - error: Any error encountered; on error nothing is restored
+func (c *SQLiteController) RestoreTrash(id int64) (DeletionSummary, bool, error) {
+	c.logger.Info("Restoring trash batch", "id", id)
+	tx, err := c.db.Begin()
+	if err != nil {
+		c.logger.Error("Failed to begin restore transaction", "error", err)
+		return DeletionSummary{}, false, err
+	}
+	defer tx.Rollback()
+
+	var s DeletionSummary
+	err = tx.QueryRow(`SELECT row_count, byte_count FROM trash_batches WHERE id = ?`, id).Scan(&s.Rows, &s.Bytes)
+	if err == sql.ErrNoRows {
+		return DeletionSummary{}, false, nil
+	}
+	if err != nil {
+		c.logger.Error("Failed to load trash batch", "error", err, "id", id)
+		return DeletionSummary{}, false, err
+	}
+
+	// Restored records of closed months return to their shard with the next
+	// RotateShards
+	if _, err := tx.Exec(`INSERT INTO main.log_sizes (id, `+trashColumns+`)
+		SELECT id, `+trashColumns+` FROM deleted_log_sizes WHERE batch_id = ?`, id); err != nil {
+		c.logger.Error("Failed to restore log sizes", "error", err, "id", id)
+		return DeletionSummary{}, false, err
+	}
+
+	// Records recent enough to have minute aggregates get them back
+	cutoff := c.now().UTC().Add(-MinuteAggregateWindow)
+	rows, err := tx.Query(`SELECT timestamp, filesize, record_count FROM deleted_log_sizes WHERE batch_id = ? AND timestamp >= ?`, id, cutoff)
+	if err != nil {
+		c.logger.Error("Failed to query restored records", "error", err, "id", id)
+		return DeletionSummary{}, false, err
+	}
+	type restored struct {
+		minute            time.Time
+		filesize, records int64
+	}
+	var recent []restored
+	for rows.Next() {
+		var r restored
+		if err := rows.Scan(&r.minute, &r.filesize, &r.records); err != nil {
+			rows.Close()
+			c.logger.Error("Failed to scan restored record", "error", err)
+			return DeletionSummary{}, false, err
+		}
+		r.minute = r.minute.UTC().Truncate(time.Minute)
+		recent = append(recent, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return DeletionSummary{}, false, err
+	}
+	for _, r := range recent {
+		if _, err := tx.Exec(upsertMinuteAggregate, r.minute, r.records, r.filesize); err != nil {
+			c.logger.Error("Failed to rebuild minute aggregate", "error", err)
+			return DeletionSummary{}, false, err
+		}
+	}
+
+	if err := deleteTrashBatch(tx, id); err != nil {
+		c.logger.Error("Failed to remove restored trash batch", "error", err, "id", id)
+		return DeletionSummary{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		c.logger.Error("Failed to commit restore", "error", err)
+		return DeletionSummary{}, false, err
+	}
+	c.recent.invalidate()
+	c.logger.Info("Trash batch restored", "id", id, "rows", s.Rows, "bytes", s.Bytes)
+	return s, true, nil
+}
+
+// PurgeTrash permanently removes trash batches deleted before cutoff.
+//
+// Parameters:
+//   - cutoff: Batches deleted before this time are purged
+//
+// Returns:
+//   - int64: Number of batches purged
+//   - error: Any error encountered; on error nothing is purged
+func (c *SQLiteController) PurgeTrash(cutoff time.Time) (int64, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		c.logger.Error("Failed to begin trash purge", "error", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id FROM trash_batches WHERE deleted_at < ?`, cutoff.UTC())
+	if err != nil {
+		c.logger.Error("Failed to query expired trash", "error", err)
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := deleteTrashBatch(tx, id); err != nil {
+			c.logger.Error("Failed to purge trash batch", "error", err, "id", id)
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.logger.Error("Failed to commit trash purge", "error", err)
+		return 0, err
+	}
+	if len(ids) > 0 {
+		c.logger.Info("Purged expired trash", "cutoff", cutoff, "batches", len(ids))
+	}
+	return int64(len(ids)), nil
+}
+
+// deleteTrashBatch removes a batch and its records from the trash within tx.
+func deleteTrashBatch(tx *sql.Tx, id int64) error {
+	if _, err := tx.Exec(`DELETE FROM deleted_log_sizes WHERE batch_id = ?`, id); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM trash_batches WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTrashRestore(t *testing.T) {
+	tempFile := "test_trash_restore.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	base := time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+	for i, size := range []int64{100, 200, 300} {
+		if err := controller.InsertLog(LogSize{Timestamp: base.Add(time.Duration(i) * time.Minute), Filesize: size, RecordCount: 1}); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+	before, _ := controller.GetAll()
+
+	deleted, err := controller.DeleteByTimeRange(base, base.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to delete range: %v", err)
+	}
+
+	trash, err := controller.ListTrash()
+	if err != nil {
+		t.Fatalf("Failed to list trash: %v", err)
+	}
+	if len(trash) != 1 || trash[0].ID != deleted.TrashID || trash[0].Rows != 2 || trash[0].Bytes != 300 {
+		t.Fatalf("Expected one trash batch of 2 rows, got %+v", trash)
+	}
+
+	restored, found, err := controller.RestoreTrash(deleted.TrashID)
+	if err != nil || !found {
+		t.Fatalf("Failed to restore trash: found=%v err=%v", found, err)
+	}
+	if restored.Rows != 2 || restored.Bytes != 300 {
+		t.Errorf("Unexpected restore summary %+v", restored)
+	}
+
+	after, _ := controller.GetAll()
+	if len(after) != len(before) {
+		t.Fatalf("Expected %d rows after restore, got %d", len(before), len(after))
+	}
+	for i := range before {
+		if after[i].ID != before[i].ID || after[i].Filesize != before[i].Filesize {
+			t.Errorf("Row %d: expected %+v, got %+v", i, before[i], after[i])
+		}
+	}
+
+	aggregates, _ := controller.QueryMinuteAggregates(base, base.Add(3*time.Minute))
+	if len(aggregates) != 3 {
+		t.Errorf("Expected minute aggregates rebuilt for restored rows, got %+v", aggregates)
+	}
+	if trash, _ := controller.ListTrash(); len(trash) != 0 {
+		t.Errorf("Expected empty trash after restore, got %+v", trash)
+	}
+	if _, found, _ := controller.RestoreTrash(deleted.TrashID); found {
+		t.Errorf("Expected restored batch to be gone")
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	tempFile := "test_trash_purge.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	if err := controller.InsertLogSize(100); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+	now := time.Now().UTC()
+	deleted, err := controller.DeleteByTimeRange(now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to delete range: %v", err)
+	}
+
+	if n, err := controller.PurgeTrash(now.Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("Expected nothing purged before expiry, got %d (%v)", n, err)
+	}
+	if n, err := controller.PurgeTrash(now.Add(time.Hour)); err != nil || n != 1 {
+		t.Fatalf("Expected 1 batch purged, got %d (%v)", n, err)
+	}
+	if _, found, _ := controller.RestoreTrash(deleted.TrashID); found {
+		t.Errorf("Expected purged batch to be unrestorable")
+	}
+	var remaining int
+	controller.db.QueryRow(`SELECT COUNT(*) FROM deleted_log_sizes`).Scan(&remaining)
+	if remaining != 0 {
+		t.Errorf("Expected purged records to be removed, %d remain", remaining)
+	}
+}
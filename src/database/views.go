@@ -0,0 +1,197 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// createViewsTable holds the DDL for saved dashboard views. Datasets and
+// filters are stored as JSON text.
+const createViewsTable = `CREATE TABLE IF NOT EXISTS views (
+	name TEXT PRIMARY KEY,
+	description TEXT NOT NULL DEFAULT '',
+	range_hours INTEGER NOT NULL DEFAULT 0,
+	start_time DATETIME,
+	end_time DATETIME,
+	interval TEXT NOT NULL DEFAULT '',
+	datasets TEXT NOT NULL DEFAULT '[]',
+	filters TEXT NOT NULL DEFAULT '{}',
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);`
+
+// SavedView is a named query definition that can be rendered as its own
+// dashboard page. A view uses either a relative range (RangeHours) or a fixed
+// Start/End window.
+type SavedView struct {
+	Name        string            `json:"name"`            // Unique, URL-safe view name
+	Description string            `json:"description"`     // Free-form description shown on the page
+	RangeHours  int               `json:"range_hours"`     // Relative range in hours; 0 when Start/End are set
+	Start       *time.Time        `json:"start,omitempty"` // Fixed window start (inclusive)
+	End         *time.Time        `json:"end,omitempty"`   // Fixed window end (exclusive)
+	Interval    string            `json:"interval"`        // Chart resolution: "", "minute" or "hour"
+	Datasets    []string          `json:"datasets"`        // Datasets included in the view
+	Filters     map[string]string `json:"filters"`         // Additional query filters
+	CreatedAt   time.Time         `json:"created_at"`      // When the view was first saved
+	UpdatedAt   time.Time         `json:"updated_at"`      // When the view was last saved
+}
+
+// viewSelectColumns is the column list scanned by scanView.
+const viewSelectColumns = `name, description, range_hours, start_time, end_time, interval, datasets, filters, created_at, updated_at`
+
+// scanView reads a single row selected with viewSelectColumns.
+func scanView(row rowScanner) (SavedView, error) {
+	var (
+		v                 SavedView
+		start, end        sql.NullTime
+		datasets, filters string
+	)
+	if err := row.Scan(&v.Name, &v.Description, &v.RangeHours, &start, &end, &v.Interval, &datasets, &filters, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		return v, err
+	}
+	if start.Valid {
+		t := start.Time.UTC()
+		v.Start = &t
+	}
+	if end.Valid {
+		t := end.Time.UTC()
+		v.End = &t
+	}
+	v.CreatedAt, v.UpdatedAt = v.CreatedAt.UTC(), v.UpdatedAt.UTC()
+	if err := json.Unmarshal([]byte(datasets), &v.Datasets); err != nil {
+		return v, err
+	}
+	if err := json.Unmarshal([]byte(filters), &v.Filters); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// ListViews returns every saved view ordered by name.
+//
+// Returns:
+//   - []SavedView: Saved views ordered by name
+//   - error: Any error encountered during the query
+func (c *SQLiteController) ListViews() ([]SavedView, error) {
+	c.logger.Info("Querying saved views")
+	rows, err := c.db.Query(`SELECT ` + viewSelectColumns + ` FROM views ORDER BY name`)
+	if err != nil {
+		c.logger.Error("Failed to query saved views", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []SavedView{}
+	for rows.Next() {
+		v, err := scanView(rows)
+		if err != nil {
+			c.logger.Error("Failed to scan saved view row", "error", err)
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// GetView loads a saved view by name.
+//
+// Parameters:
+//   - name: View name
+//
+// Returns:
+//   - SavedView: The saved view, if found
+//   - bool: Whether a view with that name exists
+//   - error: Any error encountered during the query
+func (c *SQLiteController) GetView(name string) (SavedView, bool, error) {
+	v, err := scanView(c.db.QueryRow(`SELECT `+viewSelectColumns+` FROM views WHERE name = ?`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return SavedView{}, false, nil
+	}
+	if err != nil {
+		c.logger.Error("Failed to query saved view", "error", err, "name", name)
+		return SavedView{}, false, err
+	}
+	return v, true, nil
+}
+
+// SaveView creates or replaces the view named v.Name. CreatedAt is kept from
+// an existing view and UpdatedAt is set to the current time.
+//
+// Parameters:
+//   - v: View to store
+//
+// Returns:
+//   - SavedView: The stored view including timestamps
+//   - error: Any error encountered during the write
+func (c *SQLiteController) SaveView(v SavedView) (SavedView, error) {
+	if v.Datasets == nil {
+		v.Datasets = []string{}
+	}
+	if v.Filters == nil {
+		v.Filters = map[string]string{}
+	}
+	datasets, err := json.Marshal(v.Datasets)
+	if err != nil {
+		return v, err
+	}
+	filters, err := json.Marshal(v.Filters)
+	if err != nil {
+		return v, err
+	}
+	now := c.now().UTC()
+	v.UpdatedAt = now
+	if v.Start != nil {
+		start := v.Start.UTC()
+		v.Start = &start
+	}
+	if v.End != nil {
+		end := v.End.UTC()
+		v.End = &end
+	}
+
+	_, err = c.db.Exec(`INSERT INTO views (name, description, range_hours, start_time, end_time, interval, datasets, filters, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			description = excluded.description,
+			range_hours = excluded.range_hours,
+			start_time = excluded.start_time,
+			end_time = excluded.end_time,
+			interval = excluded.interval,
+			datasets = excluded.datasets,
+			filters = excluded.filters,
+			updated_at = excluded.updated_at`,
+		v.Name, v.Description, v.RangeHours, v.Start, v.End, v.Interval, string(datasets), string(filters), now, now)
+	if err != nil {
+		c.logger.Error("Failed to save view", "error", err, "name", v.Name)
+		return v, err
+	}
+
+	saved, _, err := c.GetView(v.Name)
+	if err != nil {
+		return v, err
+	}
+	c.logger.Info("Saved view stored", "name", v.Name)
+	return saved, nil
+}
+
+// DeleteView removes the view with the given name.
+//
+// Parameters:
+//   - name: View name
+//
+// Returns:
+//   - bool: Whether a view was deleted
+//   - error: Any error encountered during the delete
+func (c *SQLiteController) DeleteView(name string) (bool, error) {
+	res, err := c.db.Exec(`DELETE FROM views WHERE name = ?`, name)
+	if err != nil {
+		c.logger.Error("Failed to delete view", "error", err, "name", name)
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	c.logger.Info("Saved view deleted", "name", name, "deleted", n > 0)
+	return n > 0, nil
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSavedViewsCRUD(t *testing.T) {
+	tempFile := "test_views.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	if _, found, err := controller.GetView("missing"); err != nil || found {
+		t.Fatalf("Expected missing view to be not found, got found=%v err=%v", found, err)
+	}
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(6 * time.Hour)
+	saved, err := controller.SaveView(SavedView{
+		Name:     "incident-42",
+		Start:    &start,
+		End:      &end,
+		Interval: "minute",
+		Datasets: []string{"http_requests"},
+		Filters:  map[string]string{"status": "5xx"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to save view: %v", err)
+	}
+	if saved.CreatedAt.IsZero() || saved.Start == nil || !saved.Start.Equal(start) {
+		t.Errorf("Unexpected saved view: %+v", saved)
+	}
+
+	// Replacing keeps the original creation time
+	if _, err := controller.SaveView(SavedView{Name: "incident-42", RangeHours: 24}); err != nil {
+		t.Fatalf("Failed to replace view: %v", err)
+	}
+	if _, err := controller.SaveView(SavedView{Name: "daily", RangeHours: 24}); err != nil {
+		t.Fatalf("Failed to save second view: %v", err)
+	}
+
+	views, err := controller.ListViews()
+	if err != nil {
+		t.Fatalf("Failed to list views: %v", err)
+	}
+	if len(views) != 2 || views[0].Name != "daily" || views[1].Name != "incident-42" {
+		t.Fatalf("Expected views ordered by name, got %+v", views)
+	}
+	replaced := views[1]
+	if replaced.Start != nil || replaced.RangeHours != 24 || len(replaced.Filters) != 0 {
+		t.Errorf("Expected replaced view to drop the fixed window, got %+v", replaced)
+	}
+	if !replaced.CreatedAt.Equal(saved.CreatedAt) {
+		t.Errorf("Expected CreatedAt %v to be preserved, got %v", saved.CreatedAt, replaced.CreatedAt)
+	}
+
+	deleted, err := controller.DeleteView("daily")
+	if err != nil || !deleted {
+		t.Fatalf("Expected view to be deleted, got deleted=%v err=%v", deleted, err)
+	}
+	if deleted, _ := controller.DeleteView("daily"); deleted {
+		t.Error("Expected second delete to report nothing deleted")
+	}
+}
@@ -0,0 +1,224 @@
+package database
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults for WriteBufferConfig.
+const (
+	DefaultWriteBufferSize    = 10000
+	DefaultWriteFlushSize     = 100
+	DefaultWriteFlushInterval = 250 * time.Millisecond
+)
+
+// maxTuneFactor bounds auto-tuning: FlushSize and FlushInterval grow to at
+// most this multiple of their configured values.
+const maxTuneFactor = 16
+
+// WriteBufferConfig configures a WriteBuffer. Zero values select the
+// defaults.
+type WriteBufferConfig struct {
+	Size          int           // Records the queue holds before Enqueue refuses more
+	FlushSize     int           // Records written per transaction; a full group is written at once
+	FlushInterval time.Duration // Longest a queued record waits before its group is written
+
+	// AutoTune lets the writer adjust FlushSize and FlushInterval after each
+	// group, from the queue depth and how long the write took. The
+	// configured values are the lower bounds; see WriteBuffer.tune
+	AutoTune bool
+
+	// OnFlush, if set, is called from the writer goroutine after each group
+	// is written, with its records and how long the write took. A group
+	// that fails is retried one record at a time, so err reports a single
+	// record that could not be stored.
+	OnFlush func(entries []LogSize, elapsed time.Duration, err error)
+}
+
+// WriteBuffer queues log records and inserts them in groups from a
+// background goroutine, so a request can be answered as soon as its record
+// is queued instead of waiting on a SQLite commit. Queued records are lost
+// if the process dies before they are flushed; Close writes them out on a
+// clean shutdown.
+type WriteBuffer struct {
+	db  *SQLiteController
+	cfg WriteBufferConfig
+
+	mu     sync.RWMutex // Held for writing by Close, so no Enqueue sends on a closed queue
+	closed bool
+	queue  chan LogSize
+	done   chan struct{} // Closed once the writer has flushed everything and exited
+
+	flushSize     atomic.Int64 // Current group size; differs from cfg.FlushSize once tuned
+	flushInterval atomic.Int64 // Current flush interval in nanoseconds
+}
+
+// NewWriteBuffer starts a WriteBuffer writing through c. Records are stored
+// under c's tenant scope, like InsertLog.
+//
+// Parameters:
+//   - cfg: Queue size, grouping and flush hook
+//
+// Returns:
+//   - *WriteBuffer: Running buffer; call Close to flush and stop it
+func (c *SQLiteController) NewWriteBuffer(cfg WriteBufferConfig) *WriteBuffer {
+	if cfg.Size <= 0 {
+		cfg.Size = DefaultWriteBufferSize
+	}
+	if cfg.FlushSize <= 0 {
+		cfg.FlushSize = DefaultWriteFlushSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultWriteFlushInterval
+	}
+	b := &WriteBuffer{db: c, cfg: cfg, queue: make(chan LogSize, cfg.Size), done: make(chan struct{})}
+	b.flushSize.Store(int64(cfg.FlushSize))
+	b.flushInterval.Store(int64(cfg.FlushInterval))
+	go b.run()
+	return b
+}
+
+// Enqueue queues entry to be inserted. A zero Timestamp is replaced with the
+// current time, so the record is dated when it was received rather than
+// when it is written.
+//
+// Parameters:
+//   - entry: Log record to store
+//
+// Returns:
+//   - bool: False if the record was not queued because the queue is full
+//     or the buffer is closed; the caller should insert it directly
+func (b *WriteBuffer) Enqueue(entry LogSize) bool {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = b.db.now()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return false
+	}
+	select {
+	case b.queue <- entry:
+		return true
+	default:
+		return false
+	}
+}
+
+// Len returns the number of records queued and not yet being written.
+func (b *WriteBuffer) Len() int {
+	return len(b.queue)
+}
+
+// Config returns the buffer's configuration, with defaults applied and, with
+// AutoTune, the current FlushSize and FlushInterval.
+func (b *WriteBuffer) Config() WriteBufferConfig {
+	cfg := b.cfg
+	cfg.FlushSize = int(b.flushSize.Load())
+	cfg.FlushInterval = time.Duration(b.flushInterval.Load())
+	return cfg
+}
+
+// Close stops accepting records and waits until every queued record has
+// been written. It is safe to call more than once.
+func (b *WriteBuffer) Close() {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	b.mu.Unlock()
+	<-b.done
+}
+
+// run collects queued records into groups of up to FlushSize and writes a
+// group when it is full or has waited FlushInterval, until the queue is
+// closed and drained.
+func (b *WriteBuffer) run() {
+	defer close(b.done)
+	size, interval := b.cfg.FlushSize, b.cfg.FlushInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	group := make([]LogSize, 0, size)
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+		started := time.Now()
+		b.write(group)
+		if b.cfg.AutoTune {
+			b.tune(len(group), len(b.queue), time.Since(started))
+			size = int(b.flushSize.Load())
+			if d := time.Duration(b.flushInterval.Load()); d != interval {
+				interval = d
+				ticker.Reset(interval)
+			}
+		}
+		group = make([]LogSize, 0, size)
+	}
+	for {
+		select {
+		case entry, ok := <-b.queue:
+			if !ok {
+				flush()
+				return
+			}
+			group = append(group, entry)
+			if len(group) >= size {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// tune adjusts the flush size and interval after a group of written records
+// took elapsed to store, with depth records still queued. A backlog of at
+// least a group doubles the size, so commits keep up with bursts; an idle
+// queue and a group under a quarter full halve it again. A write taking more
+// than half the interval doubles the interval, so slow storage commits
+// fewer, larger groups; one under an eighth of it halves the interval again.
+// Neither drops below its configured value or grows beyond maxTuneFactor
+// times it, and the size never exceeds the queue.
+func (b *WriteBuffer) tune(written, depth int, elapsed time.Duration) {
+	size, minSize := int(b.flushSize.Load()), b.cfg.FlushSize
+	maxSize := min(minSize*maxTuneFactor, b.cfg.Size)
+	switch {
+	case depth >= size:
+		size = min(size*2, maxSize)
+	case depth == 0 && written < size/4:
+		size = max(size/2, minSize)
+	}
+	b.flushSize.Store(int64(max(size, minSize)))
+
+	interval, minInterval := time.Duration(b.flushInterval.Load()), b.cfg.FlushInterval
+	switch {
+	case elapsed > interval/2:
+		interval = min(interval*2, minInterval*maxTuneFactor)
+	case elapsed < interval/8:
+		interval = max(interval/2, minInterval)
+	}
+	b.flushInterval.Store(int64(interval))
+}
+
+// write inserts a group in one transaction. If that fails, each record is
+// inserted on its own, so one bad record does not cost the whole group.
+func (b *WriteBuffer) write(group []LogSize) {
+	started := time.Now()
+	err := b.db.InsertLogs(group)
+	if err != nil && len(group) > 1 {
+		b.db.logger.Warn("Failed to write buffered log sizes; retrying one at a time", "error", err, "records", len(group))
+		for _, entry := range group {
+			b.write([]LogSize{entry})
+		}
+		return
+	}
+	if err != nil {
+		b.db.logger.Error("Dropped buffered log size", "error", err, "filesize", group[0].Filesize)
+	}
+	if b.cfg.OnFlush != nil {
+		b.cfg.OnFlush(group, time.Since(started), err)
+	}
+}
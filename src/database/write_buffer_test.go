@@ -0,0 +1,142 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteBufferFlushesGroups(t *testing.T) {
+	tempFile := "test_write_buffer.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	var mu sync.Mutex
+	var groups []int
+	flushed := make(chan struct{}, 10)
+	buffer := controller.NewWriteBuffer(WriteBufferConfig{
+		FlushSize:     3,
+		FlushInterval: time.Hour,
+		OnFlush: func(entries []LogSize, elapsed time.Duration, err error) {
+			if err != nil {
+				t.Errorf("Unexpected flush error: %v", err)
+			}
+			mu.Lock()
+			groups = append(groups, len(entries))
+			mu.Unlock()
+			flushed <- struct{}{}
+		},
+	})
+
+	at := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		if !buffer.Enqueue(LogSize{Timestamp: at.Add(time.Duration(i) * time.Second), Filesize: 100, RecordCount: 1, Tenant: "acme"}) {
+			t.Fatalf("Expected record %d to be queued", i)
+		}
+	}
+
+	// A full group is written without waiting for the interval
+	select {
+	case <-flushed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a full group to be flushed")
+	}
+	if logs, _ := controller.QueryByTimeRange(at, at.Add(time.Minute)); len(logs) != 3 {
+		t.Errorf("Expected the first 3 records written, got %d", len(logs))
+	}
+
+	// Close writes the rest and stops accepting records
+	buffer.Close()
+	buffer.Close()
+	logs, err := controller.ForTenant("acme").QueryByTimeRange(at, at.Add(time.Minute))
+	if err != nil || len(logs) != 4 {
+		t.Errorf("Expected all 4 records written by Close, got %d (%v)", len(logs), err)
+	}
+	if buffer.Enqueue(LogSize{Filesize: 1}) {
+		t.Error("Expected a closed buffer to refuse records")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(groups) != 2 || groups[0] != 3 || groups[1] != 1 {
+		t.Errorf("Expected groups of 3 and 1, got %v", groups)
+	}
+	if aggregates, _ := controller.QueryMinuteAggregates(at, at.Add(time.Minute)); len(aggregates) != 1 || aggregates[0].TotalSize != 400 {
+		t.Errorf("Expected the minute aggregate to include every record, got %+v", aggregates)
+	}
+}
+
+func TestWriteBufferFull(t *testing.T) {
+	tempFile := "test_write_buffer_full.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	// The writer is held in OnFlush, so the queue fills up
+	release := make(chan struct{})
+	buffer := controller.NewWriteBuffer(WriteBufferConfig{
+		Size:      1,
+		FlushSize: 1,
+		OnFlush:   func([]LogSize, time.Duration, error) { <-release },
+	})
+	queued := 0
+	for i := 0; i < 5; i++ {
+		if buffer.Enqueue(LogSize{Filesize: 10}) {
+			queued++
+		}
+	}
+	if queued < 1 || queued > 2 {
+		t.Errorf("Expected the queue to refuse records once full, queued %d", queued)
+	}
+	close(release)
+	buffer.Close()
+	if count, _ := controller.CountByTimeRange(time.Time{}, time.Time{}); count.Batches != int64(queued) {
+		t.Errorf("Expected the %d queued records written, got %+v", queued, count)
+	}
+}
+
+func TestWriteBufferTune(t *testing.T) {
+	b := &WriteBuffer{cfg: WriteBufferConfig{Size: 1000, FlushSize: 10, FlushInterval: 100 * time.Millisecond, AutoTune: true}}
+	b.flushSize.Store(10)
+	b.flushInterval.Store(int64(100 * time.Millisecond))
+
+	steps := []struct {
+		written, depth int
+		elapsed        time.Duration
+		size           int
+		interval       time.Duration
+	}{
+		// A backlog grows the groups, up to 16 times the configured size
+		{10, 50, 20 * time.Millisecond, 20, 100 * time.Millisecond},
+		{20, 50, 20 * time.Millisecond, 40, 100 * time.Millisecond},
+		{40, 500, 20 * time.Millisecond, 80, 100 * time.Millisecond},
+		{80, 500, 20 * time.Millisecond, 160, 100 * time.Millisecond},
+		{160, 500, 20 * time.Millisecond, 160, 100 * time.Millisecond},
+		// Slow writes lengthen the interval
+		{160, 10, 80 * time.Millisecond, 160, 200 * time.Millisecond},
+		// An idle queue shrinks both back, never below the configured values
+		{5, 0, time.Millisecond, 80, 100 * time.Millisecond},
+		{5, 0, time.Millisecond, 40, 100 * time.Millisecond},
+		{1, 0, time.Millisecond, 20, 100 * time.Millisecond},
+		{1, 0, time.Millisecond, 10, 100 * time.Millisecond},
+		{1, 0, time.Millisecond, 10, 100 * time.Millisecond},
+	}
+	for i, step := range steps {
+		b.tune(step.written, step.depth, step.elapsed)
+		if cfg := b.Config(); cfg.FlushSize != step.size || cfg.FlushInterval != step.interval {
+			t.Errorf("Step %d: expected size %d every %s, got %d every %s", i, step.size, step.interval, cfg.FlushSize, cfg.FlushInterval)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package database
+
+import "time"
+
+// createZoneTrafficTable holds the DDL for hourly request counts reported by
+// Cloudflare's analytics for each zone. They are the expected volume that
+// coverage reports compare ingested records against.
+const createZoneTrafficTable = `CREATE TABLE IF NOT EXISTS zone_traffic (
+	zone TEXT NOT NULL,
+	hour DATETIME NOT NULL,
+	requests INTEGER NOT NULL,
+	fetched_at DATETIME NOT NULL,
+	PRIMARY KEY (zone, hour)
+);
+CREATE INDEX IF NOT EXISTS idx_zone_traffic_hour ON zone_traffic(hour);`
+
+// ZoneTrafficHour is the request count for one zone and hour.
+type ZoneTrafficHour struct {
+	Zone     string    // Zone identifier
+	Hour     time.Time // Start of the hour
+	Requests int64     // Requests served by the zone during the hour
+}
+
+// UpsertZoneTraffic stores hourly request counts, replacing any previously
+// stored count for the same zone and hour.
+//
+// Parameters:
+//   - hours: Counts to store
+//
+// Returns:
+//   - error: Any error encountered; on error nothing is stored
+func (c *SQLiteController) UpsertZoneTraffic(hours []ZoneTrafficHour) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		c.logger.Error("Failed to begin zone traffic transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	now := c.now().UTC()
+	for _, h := range hours {
+		_, err := tx.Exec(`INSERT INTO zone_traffic (zone, hour, requests, fetched_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(zone, hour) DO UPDATE SET requests = excluded.requests, fetched_at = excluded.fetched_at`,
+			h.Zone, h.Hour.UTC().Truncate(time.Hour), h.Requests, now)
+		if err != nil {
+			c.logger.Error("Failed to store zone traffic", "error", err, "zone", h.Zone)
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.logger.Error("Failed to commit zone traffic", "error", err)
+		return err
+	}
+	c.logger.Info("Zone traffic stored", "hours", len(hours))
+	return nil
+}
+
+// QueryZoneTraffic returns the stored counts for hours in [start, end),
+// ordered by hour and zone.
+//
+// Parameters:
+//   - start: Start time (inclusive)
+//   - end: End time (exclusive)
+//
+// Returns:
+//   - []ZoneTrafficHour: Stored counts
+//   - error: Any error encountered during the query
+func (c *SQLiteController) QueryZoneTraffic(start, end time.Time) ([]ZoneTrafficHour, error) {
+	rows, err := c.db.Query(`SELECT zone, hour, requests FROM zone_traffic WHERE hour >= ? AND hour < ? ORDER BY hour, zone`,
+		start.UTC().Truncate(time.Hour), end.UTC())
+	if err != nil {
+		c.logger.Error("Failed to query zone traffic", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ZoneTrafficHour
+	for rows.Next() {
+		var h ZoneTrafficHour
+		if err := rows.Scan(&h.Zone, &h.Hour, &h.Requests); err != nil {
+			c.logger.Error("Failed to scan zone traffic row", "error", err)
+			return nil, err
+		}
+		h.Hour = h.Hour.UTC()
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
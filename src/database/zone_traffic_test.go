@@ -0,0 +1,42 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestUpsertZoneTraffic(t *testing.T) {
+	tempFile := "test_zone_traffic.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	hour := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	err = controller.UpsertZoneTraffic([]ZoneTrafficHour{
+		{Zone: "zone-a", Hour: hour, Requests: 100},
+		{Zone: "zone-a", Hour: hour.Add(time.Hour), Requests: 200},
+	})
+	if err != nil {
+		t.Fatalf("Failed to store zone traffic: %v", err)
+	}
+
+	// A later sync replaces the count for the same hour
+	if err := controller.UpsertZoneTraffic([]ZoneTrafficHour{{Zone: "zone-a", Hour: hour, Requests: 150}}); err != nil {
+		t.Fatalf("Failed to update zone traffic: %v", err)
+	}
+
+	traffic, err := controller.QueryZoneTraffic(hour, hour.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query zone traffic: %v", err)
+	}
+	if len(traffic) != 1 || traffic[0].Requests != 150 || !traffic[0].Hour.Equal(hour) {
+		t.Errorf("Expected updated count for the first hour only, got %+v", traffic)
+	}
+}
@@ -0,0 +1,56 @@
+package database
+
+import "time"
+
+// ZoneUsage summarizes the log records stored for one Cloudflare zone.
+type ZoneUsage struct {
+	Zone        string `json:"zone"`         // Zone name or ID; empty for batches ingested without one
+	Records     int64  `json:"records"`      // Number of stored log batches
+	TotalSize   int64  `json:"total_size"`   // Sum of batch sizes in bytes
+	RecordCount int64  `json:"record_count"` // Sum of log lines across the batches
+}
+
+// QueryZoneUsage returns the usage of every zone with records in
+// [start, end), largest first. A zero start or end leaves that side of the
+// range open. On a scoped controller only the records in scope are counted,
+// so a dataset-scoped controller reports each zone's share of the dataset.
+//
+// Parameters:
+//   - start: Start time (inclusive), or zero for no lower bound
+//   - end: End time (exclusive), or zero for no upper bound
+//
+// Returns:
+//   - []ZoneUsage: Usage per zone within the range
+//   - error: Any error encountered during the query
+func (c *SQLiteController) QueryZoneUsage(start, end time.Time) ([]ZoneUsage, error) {
+	if err := c.checkAttached(start); err != nil {
+		return nil, err
+	}
+	query := `SELECT zone, COUNT(*), COALESCE(SUM(filesize), 0), COALESCE(SUM(record_count), 0) FROM log_sizes WHERE 1 = 1`
+	var args []any
+	if !start.IsZero() {
+		query += ` AND timestamp >= ?`
+		args = append(args, start.UTC())
+	}
+	if !end.IsZero() {
+		query += ` AND timestamp < ?`
+		args = append(args, end.UTC())
+	}
+	filter, filterArgs := c.tenantFilter()
+	rows, err := c.db.Query(query+filter+` GROUP BY zone ORDER BY SUM(filesize) DESC, zone`, append(args, filterArgs...)...)
+	if err != nil {
+		c.logger.Error("Failed to query zone usage", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ZoneUsage{}
+	for rows.Next() {
+		var u ZoneUsage
+		if err := rows.Scan(&u.Zone, &u.Records, &u.TotalSize, &u.RecordCount); err != nil {
+			c.logger.Error("Failed to scan zone usage row", "error", err)
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestForZoneScopesLogRecords(t *testing.T) {
+	tempFile := "test_zones.db"
+	defer os.Remove(tempFile)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	controller, err := NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer controller.Close()
+
+	at := time.Date(2025, 9, 15, 10, 0, 30, 0, time.UTC)
+	for _, l := range []LogSize{
+		{Timestamp: at, Filesize: 100, RecordCount: 2, Dataset: "http_requests", Zone: "example.com"},
+		{Timestamp: at.Add(10 * time.Second), Filesize: 50, RecordCount: 1, Dataset: "firewall_events", Zone: "example.com"},
+		{Timestamp: at, Filesize: 300, RecordCount: 3, Dataset: "http_requests", Zone: "example.org"},
+		{Timestamp: at, Filesize: 7},
+	} {
+		if err := controller.InsertLog(l); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+
+	check := func(name string, c *SQLiteController, batches int, bytes int64) {
+		t.Helper()
+		logs, err := c.QueryByTimeRange(at.Add(-time.Minute), at.Add(time.Minute))
+		if err != nil {
+			t.Fatalf("%s: failed to query range: %v", name, err)
+		}
+		var sum int64
+		for _, l := range logs {
+			sum += l.Filesize
+		}
+		if len(logs) != batches || sum != bytes {
+			t.Errorf("%s: expected %d records of %d bytes, got %+v", name, batches, bytes, logs)
+		}
+		count, err := c.CountByTimeRange(time.Time{}, time.Time{})
+		if err != nil || count.Batches != int64(batches) || count.Bytes != bytes {
+			t.Errorf("%s: expected the count to match, got %+v, %v", name, count, err)
+		}
+	}
+	check("example.com", controller.ForZone("example.com"), 2, 150)
+	check("example.com http_requests", controller.ForZone("example.com").ForDataset("http_requests"), 1, 100)
+
+	// The SQL path filters like the recent buffer
+	controller.SetRecentBufferSize(0)
+	check("example.com from SQL", controller.ForZone("example.com"), 2, 150)
+	since, err := controller.ForZone("example.org").QuerySince(0, 10)
+	if err != nil || len(since) != 1 || since[0].Zone != "example.org" {
+		t.Errorf("Expected the example.org record only, got %+v, %v", since, err)
+	}
+
+	usage, err := controller.QueryZoneUsage(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to query zone usage: %v", err)
+	}
+	want := []ZoneUsage{
+		{Zone: "example.org", Records: 1, TotalSize: 300, RecordCount: 3},
+		{Zone: "example.com", Records: 2, TotalSize: 150, RecordCount: 3},
+		{Zone: "", Records: 1, TotalSize: 7},
+	}
+	if len(usage) != len(want) {
+		t.Fatalf("Expected %d zones, got %+v", len(want), usage)
+	}
+	for i := range want {
+		if usage[i] != want[i] {
+			t.Errorf("Zone %d: expected %+v, got %+v", i, want[i], usage[i])
+		}
+	}
+	firewall, err := controller.ForDataset("firewall_events").QueryZoneUsage(time.Time{}, time.Time{})
+	if err != nil || len(firewall) != 1 || firewall[0].Zone != "example.com" || firewall[0].TotalSize != 50 {
+		t.Errorf("Expected example.com's firewall_events usage only, got %+v, %v", firewall, err)
+	}
+}
@@ -0,0 +1,165 @@
+// Package encryption seals files that leave the database, such as exports
+// and backups, so log-volume metadata and zone names are not readable at
+// rest by anyone without the key.
+//
+// Files are encrypted with AES-256-GCM, which also authenticates them: a
+// sealed file that has been modified or truncated fails to open rather than
+// yielding altered data.
+//
+// # Configuration
+//
+// The 32-byte key is supplied base64-encoded, either directly or as a file.
+// The file form suits keys delivered by a KMS or secret manager agent that
+// writes the decrypted key to a mounted path:
+//
+//	LPE_ENCRYPTION_KEY=<base64>          Key material
+//	LPE_ENCRYPTION_KEY_FILE=/run/keys/k  File containing the base64 key
+//
+// A new key can be generated with `openssl rand -base64 32`.
+//
+// # Usage
+//
+//	key, err := encryption.KeyFromEnv(os.Getenv)
+//	if err != nil {
+//		return err
+//	}
+//	if key != nil {
+//		sealed, err := key.Seal(data)
+//		// ...
+//	}
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize is the length of an encryption key in bytes.
+const KeySize = 32
+
+// magic prefixes every sealed file so it can be recognised, and is
+// authenticated together with the ciphertext.
+var magic = []byte("LPEENC1\n")
+
+// ErrNotSealed is returned by Open for data that was not produced by Seal.
+var ErrNotSealed = errors.New("encryption: data is not sealed")
+
+// ErrDecrypt is returned by Open when data was sealed with a different key
+// or has been modified.
+var ErrDecrypt = errors.New("encryption: wrong key or corrupted data")
+
+// Key is an AES-256 key.
+type Key struct {
+	aead cipher.AEAD
+}
+
+// NewKey creates a key from raw key material.
+//
+// Parameters:
+//   - raw: KeySize bytes of key material
+//
+// Returns:
+//   - *Key: The key
+//   - error: If raw has the wrong length
+func NewKey(raw []byte) (*Key, error) {
+	if len(raw) != KeySize {
+		return nil, fmt.Errorf("encryption: key must be %d bytes, got %d", KeySize, len(raw))
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{aead: aead}, nil
+}
+
+// ParseKey decodes a base64-encoded key. Surrounding whitespace is ignored.
+func ParseKey(encoded string) (*Key, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("encryption: key is not valid base64: %w", err)
+	}
+	return NewKey(raw)
+}
+
+// KeyFromEnv reads the key from LPE_ENCRYPTION_KEY or, if that is unset,
+// from the file named by LPE_ENCRYPTION_KEY_FILE.
+//
+// Parameters:
+//   - getenv: Environment lookup, normally os.Getenv
+//
+// Returns:
+//   - *Key: The configured key, or nil when encryption is not configured
+//   - error: If a key is configured but cannot be read or decoded
+func KeyFromEnv(getenv func(string) string) (*Key, error) {
+	if encoded := getenv("LPE_ENCRYPTION_KEY"); encoded != "" {
+		return ParseKey(encoded)
+	}
+	path := strings.TrimSpace(getenv("LPE_ENCRYPTION_KEY_FILE"))
+	if path == "" {
+		return nil, nil
+	}
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: read key file: %w", err)
+	}
+	return ParseKey(string(encoded))
+}
+
+// Seal encrypts and authenticates plaintext with a fresh random nonce.
+//
+// Parameters:
+//   - plaintext: Data to encrypt
+//
+// Returns:
+//   - []byte: Magic header, nonce and ciphertext
+//   - error: If no random nonce could be generated
+func (k *Key) Seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(magic)+len(nonce)+len(plaintext)+k.aead.Overhead())
+	out = append(out, magic...)
+	out = append(out, nonce...)
+	return k.aead.Seal(out, nonce, plaintext, magic), nil
+}
+
+// Open decrypts data produced by Seal.
+//
+// Parameters:
+//   - sealed: Output of Seal
+//
+// Returns:
+//   - []byte: The plaintext
+//   - error: ErrNotSealed or ErrDecrypt
+func (k *Key) Open(sealed []byte) ([]byte, error) {
+	if !IsSealed(sealed) {
+		return nil, ErrNotSealed
+	}
+	rest := sealed[len(magic):]
+	if len(rest) < k.aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := rest[:k.aead.NonceSize()], rest[k.aead.NonceSize():]
+	plaintext, err := k.aead.Open(nil, nonce, ciphertext, magic)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// IsSealed reports whether data starts with the sealed-file header.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
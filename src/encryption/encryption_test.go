@@ -0,0 +1,62 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"testing"
+)
+
+var testKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, KeySize))
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	key, err := ParseKey(testKey)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	plaintext := []byte("id,timestamp,filesize\n1,2025-01-01T00:00:00Z,100\n")
+	sealed, err := key.Seal(plaintext)
+	if err != nil {
+		t.Fatalf("Failed to seal: %v", err)
+	}
+	if !IsSealed(sealed) || bytes.Contains(sealed, []byte("filesize")) {
+		t.Fatal("Expected sealed output to be marked and unreadable")
+	}
+	opened, err := key.Open(sealed)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("Open() = %q, %v", opened, err)
+	}
+
+	sealed[len(sealed)-1] ^= 1
+	if _, err := key.Open(sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt for modified data, got %v", err)
+	}
+	if _, err := key.Open(plaintext); !errors.Is(err, ErrNotSealed) {
+		t.Errorf("Expected ErrNotSealed for plaintext, got %v", err)
+	}
+}
+
+func TestKeyFromEnv(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(k string) string { return env[k] }
+
+	if key, err := KeyFromEnv(getenv); key != nil || err != nil {
+		t.Errorf("Expected no key when unconfigured, got %v, %v", key, err)
+	}
+
+	keyFile := "test_encryption.key"
+	defer os.Remove(keyFile)
+	if err := os.WriteFile(keyFile, []byte(testKey+"\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	env["LPE_ENCRYPTION_KEY_FILE"] = keyFile
+	if key, err := KeyFromEnv(getenv); key == nil || err != nil {
+		t.Errorf("Expected a key from the key file, got %v", err)
+	}
+
+	env["LPE_ENCRYPTION_KEY"] = base64.StdEncoding.EncodeToString([]byte("too short"))
+	if _, err := KeyFromEnv(getenv); err == nil {
+		t.Error("Expected an error for a short key")
+	}
+}
@@ -0,0 +1,201 @@
+// Package estimator projects tracked log volume into the monthly cost of
+// keeping it at a destination: ingestion and requests as priced by the
+// reports package, plus the storage held once retention is reached and the
+// data read back out, both priced in tiers.
+//
+// Pricing comes from a configured pricing model or one of the built-in
+// presets for Cloudflare R2, Amazon S3 and Google Cloud Storage. A
+// configured model with a preset's name takes precedence over the preset.
+//
+// FitTrend fits a linear trend or moving average to recent daily totals,
+// and Trend.Project extends it over the rest of a month with confidence
+// bounds.
+//
+// # Usage
+//
+//	model, ok := estimator.Preset("r2")
+//	usage, err := db.QueryTenantUsage(start, end)
+//	if err != nil {
+//		return err
+//	}
+//	estimate := estimator.Project(model, estimator.Sum(usage), 7, estimator.Options{RetentionDays: 30})
+package estimator
+
+import (
+	"math"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/reports"
+)
+
+// MonthDays is the length of the month estimates are projected to.
+const MonthDays = 30
+
+// Options describe how the projected data is kept and used at the
+// destination.
+type Options struct {
+	RetentionDays int     // Days data is kept at the destination
+	DownloadRatio float64 // Fraction of each month's volume read back out
+}
+
+// Estimate is the projected monthly cost of the observed volume.
+type Estimate struct {
+	PricingModel  string             `json:"pricing_model"`  // Name of the model the usage was priced with
+	Currency      string             `json:"currency"`       // Currency of every cost in the estimate
+	Days          float64            `json:"days"`           // Length of the observed window in days
+	Bytes         int64              `json:"bytes"`          // Bytes per month
+	Records       int64              `json:"records"`        // Log lines per month
+	Requests      int64              `json:"requests"`       // Destination write requests per month
+	RetentionDays int                `json:"retention_days"` // Days data is kept at the destination
+	StoredBytes   int64              `json:"stored_bytes"`   // Bytes held once retention is reached
+	DownloadBytes int64              `json:"download_bytes"` // Bytes read back out per month
+	MonthlyCost   float64            `json:"monthly_cost"`   // Sum of the breakdown
+	Breakdown     []reports.CostItem `json:"breakdown"`      // MonthlyCost by component
+}
+
+// Presets returns the built-in pricing models, named "r2", "s3" and "gcs".
+// Rates are the providers' public list prices in USD for standard storage
+// (S3 in us-east-1, GCS in the US multi-region) at the time of writing;
+// free allowances are modelled as free tiers where they are per month of
+// usage. Every ingested batch is priced as one write request, as Logpush
+// writes one object per batch.
+func Presets() []config.PricingModel {
+	return []config.PricingModel{
+		{
+			Name:               "r2",
+			Currency:           "USD",
+			PerMillionRequests: 4.50,
+			Storage:            []config.PriceTier{{UpToGB: 10, PerGB: 0}, {PerGB: 0.015}},
+			// Egress from R2 is free
+		},
+		{
+			Name:               "s3",
+			Currency:           "USD",
+			PerMillionRequests: 5,
+			Storage:            []config.PriceTier{{UpToGB: 50000, PerGB: 0.023}, {UpToGB: 500000, PerGB: 0.022}, {PerGB: 0.021}},
+			Download: []config.PriceTier{
+				{UpToGB: 100, PerGB: 0},
+				{UpToGB: 10000, PerGB: 0.09},
+				{UpToGB: 50000, PerGB: 0.085},
+				{UpToGB: 150000, PerGB: 0.07},
+				{PerGB: 0.05},
+			},
+		},
+		{
+			Name:               "gcs",
+			Currency:           "USD",
+			PerMillionRequests: 5,
+			Storage:            []config.PriceTier{{PerGB: 0.026}},
+			Download:           []config.PriceTier{{UpToGB: 1000, PerGB: 0.12}, {UpToGB: 10000, PerGB: 0.11}, {PerGB: 0.08}},
+		},
+	}
+}
+
+// Preset returns the built-in pricing model with the given name.
+//
+// Parameters:
+//   - name: "r2", "s3" or "gcs"
+//
+// Returns:
+//   - config.PricingModel: The preset
+//   - bool: Whether a preset has that name
+func Preset(name string) (config.PricingModel, bool) {
+	for _, m := range Presets() {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return config.PricingModel{}, false
+}
+
+// Sum adds up usage across tenants.
+func Sum(usage []database.TenantUsage) database.TenantUsage {
+	var total database.TenantUsage
+	for _, u := range usage {
+		total.Records += u.Records
+		total.TotalSize += u.TotalSize
+		total.RecordCount += u.RecordCount
+	}
+	return total
+}
+
+// Tiered prices quantity GB across tiers. Anything beyond a bounded last
+// tier is priced at the last tier's rate, and no tiers price nothing.
+//
+// Parameters:
+//   - tiers: Tiers in increasing order, as validated by config
+//   - quantity: Amount to price in GB (or GB-months)
+//
+// Returns:
+//   - float64: Unrounded cost
+func Tiered(tiers []config.PriceTier, quantity float64) float64 {
+	var total, prev float64
+	for i, t := range tiers {
+		if quantity <= prev {
+			break
+		}
+		upper := t.UpToGB
+		if upper == 0 || i == len(tiers)-1 {
+			upper = math.Inf(1)
+		}
+		total += (min(quantity, upper) - prev) * t.PerGB
+		prev = upper
+	}
+	return total
+}
+
+// Project scales the usage observed over days to a 30-day month and prices
+// it with model. Ingestion, records, requests and egress are itemized as in
+// a chargeback report; the bytes held once RetentionDays of data have
+// accumulated are added as "stored" GB-months and the share read back out
+// as "download" GB, each at the effective rate of its tiers.
+//
+// Parameters:
+//   - model: Pricing model to apply
+//   - usage: Usage observed over the window, summed across tenants
+//   - days: Length of the observed window in days
+//   - opts: Retention and download assumptions
+//
+// Returns:
+//   - Estimate: The projected month
+func Project(model config.PricingModel, usage database.TenantUsage, days float64, opts Options) Estimate {
+	estimate := Estimate{
+		PricingModel:  model.Name,
+		Currency:      model.Currency,
+		Days:          days,
+		RetentionDays: opts.RetentionDays,
+	}
+	if days > 0 {
+		scale := MonthDays / days
+		estimate.Bytes = int64(math.Round(float64(usage.TotalSize) * scale))
+		estimate.Records = int64(math.Round(float64(usage.RecordCount) * scale))
+		estimate.Requests = int64(math.Round(float64(usage.Records) * scale))
+		estimate.StoredBytes = int64(math.Round(float64(usage.TotalSize) / days * float64(opts.RetentionDays)))
+		estimate.DownloadBytes = int64(math.Round(float64(estimate.Bytes) * opts.DownloadRatio))
+	}
+
+	estimate.Breakdown = reports.Breakdown(model, estimate.Bytes, estimate.Records, estimate.Requests)
+	gb := model.Units.BytesPerGB()
+	estimate.Breakdown = append(estimate.Breakdown,
+		tieredItem("stored", model.Units.GB()+"-month", model.Storage, float64(estimate.StoredBytes)/gb),
+		tieredItem("download", model.Units.GB(), model.Download, float64(estimate.DownloadBytes)/gb),
+	)
+	var cents float64
+	for _, item := range estimate.Breakdown {
+		cents += math.Round(item.Cost * 100)
+	}
+	estimate.MonthlyCost = cents / 100
+	return estimate
+}
+
+// tieredItem prices quantity across tiers as one cost item whose rate is
+// the effective rate over all tiers.
+func tieredItem(name, unit string, tiers []config.PriceTier, quantity float64) reports.CostItem {
+	cost := Tiered(tiers, quantity)
+	item := reports.CostItem{Item: name, Quantity: quantity, Unit: unit, Cost: math.Round(cost*100) / 100}
+	if quantity > 0 {
+		item.Rate = cost / quantity
+	}
+	return item
+}
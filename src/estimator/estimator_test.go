@@ -0,0 +1,99 @@
+package estimator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestTiered(t *testing.T) {
+	tiers := []config.PriceTier{{UpToGB: 10, PerGB: 0}, {UpToGB: 110, PerGB: 1}, {PerGB: 0.5}}
+	for _, tc := range []struct {
+		quantity, want float64
+	}{
+		{0, 0},
+		{5, 0},
+		{60, 50},
+		{110, 100},
+		{210, 150},
+	} {
+		if got := Tiered(tiers, tc.quantity); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("Tiered(%v) = %v, want %v", tc.quantity, got, tc.want)
+		}
+	}
+	// A bounded last tier extends to everything beyond it
+	if got := Tiered([]config.PriceTier{{UpToGB: 10, PerGB: 2}}, 15); got != 30 {
+		t.Errorf("Expected the last tier to price the overflow, got %v", got)
+	}
+	if got := Tiered(nil, 100); got != 0 {
+		t.Errorf("Expected no tiers to price nothing, got %v", got)
+	}
+}
+
+func TestProject(t *testing.T) {
+	// A week of 7 GB in 7000 batches of 70 million lines
+	usage := Sum([]database.TenantUsage{
+		{Tenant: "", Records: 3000, RecordCount: 30e6, TotalSize: 3e9},
+		{Tenant: "acme", Records: 4000, RecordCount: 40e6, TotalSize: 4e9},
+	})
+	model := config.PricingModel{
+		Name:               "custom",
+		Currency:           "EUR",
+		PerGB:              0.1,
+		PerMillionRequests: 100,
+		Storage:            []config.PriceTier{{UpToGB: 10, PerGB: 0}, {PerGB: 1}},
+		Download:           []config.PriceTier{{PerGB: 2}},
+	}
+	estimate := Project(model, usage, 7, Options{RetentionDays: 60, DownloadRatio: 0.5})
+	if estimate.Bytes != 30e9 || estimate.Records != 300e6 || estimate.Requests != 30000 {
+		t.Fatalf("Expected a week scaled to a 30-day month, got %+v", estimate)
+	}
+	if estimate.StoredBytes != 60e9 || estimate.DownloadBytes != 15e9 {
+		t.Fatalf("Expected 60 days held and half downloaded, got %+v", estimate)
+	}
+
+	costs := map[string]float64{}
+	for _, item := range estimate.Breakdown {
+		costs[item.Item] = item.Cost
+	}
+	// 30 GB at 0.1, 0.03 million requests at 100, 50 of 60 GB-months at 1
+	// and 15 GB at 2
+	want := map[string]float64{"storage": 3, "records": 0, "requests": 3, "stored": 50, "download": 30}
+	for item, cost := range want {
+		if costs[item] != cost {
+			t.Errorf("Expected %s to cost %v, got %v", item, cost, costs[item])
+		}
+	}
+	if estimate.MonthlyCost != 86 || estimate.Currency != "EUR" || estimate.PricingModel != "custom" {
+		t.Errorf("Unexpected estimate %+v", estimate)
+	}
+
+	if empty := Project(model, database.TenantUsage{}, 0, Options{RetentionDays: 30}); empty.MonthlyCost != 0 {
+		t.Errorf("Expected nothing observed to cost nothing, got %+v", empty)
+	}
+}
+
+func TestPresetsAreValid(t *testing.T) {
+	doc := config.NewDocument()
+	doc.PricingModels = Presets()
+	if err := doc.Validate(); err != nil {
+		t.Fatalf("Expected the presets to be valid pricing models: %v", err)
+	}
+	for _, name := range []string{"r2", "s3", "gcs"} {
+		if _, ok := Preset(name); !ok {
+			t.Errorf("Expected a %s preset", name)
+		}
+	}
+	if _, ok := Preset("azure"); ok {
+		t.Error("Expected no preset for an unknown name")
+	}
+
+	// R2 keeps the first 10 GB free and never charges for egress
+	r2, _ := Preset("r2")
+	estimate := Project(r2, database.TenantUsage{TotalSize: 1e9}, 1, Options{RetentionDays: 30, DownloadRatio: 1})
+	if estimate.MonthlyCost != 0.3 {
+		t.Errorf("Expected 20 GB-months over the free tier at 0.015, got %+v", estimate)
+	}
+}
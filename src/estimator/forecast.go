@@ -0,0 +1,120 @@
+package estimator
+
+import "math"
+
+// Forecast models accepted by FitTrend.
+const (
+	ForecastLinear        = "linear"         // Least squares line through the daily totals
+	ForecastMovingAverage = "moving_average" // Mean of the daily totals
+)
+
+// ForecastConfidence is the coverage of the bounds returned by
+// Trend.Project, which assume normally distributed daily deviations.
+const ForecastConfidence = 0.95
+
+// forecastZ is the normal quantile of two-sided ForecastConfidence bounds.
+const forecastZ = 1.959964
+
+// Trend is a model of daily totals fitted by FitTrend.
+type Trend struct {
+	Model     string  // ForecastLinear or ForecastMovingAverage
+	Days      int     // Number of daily totals fitted
+	Intercept float64 // Expected total on day 0
+	Slope     float64 // Change of the expected total per day; 0 for a moving average
+	Sigma     float64 // Standard deviation of a day's total around the model; 0 with too few days to tell
+
+	meanDay float64 // Mean day index of the fitted totals
+	sxx     float64 // Sum of squared deviations of the day indexes
+}
+
+// FitTrend fits model to daily totals. A linear fit needs three days to
+// estimate its spread; with fewer, the mean is used instead.
+//
+// Parameters:
+//   - model: ForecastLinear or ForecastMovingAverage
+//   - daily: Totals of consecutive days, oldest first; day i has index i
+//
+// Returns:
+//   - Trend: The fitted model; Model names the one actually used
+func FitTrend(model string, daily []float64) Trend {
+	n := len(daily)
+	t := Trend{Model: ForecastMovingAverage, Days: n}
+	if n == 0 {
+		return t
+	}
+	var sum float64
+	for _, y := range daily {
+		sum += y
+	}
+	mean := sum / float64(n)
+
+	if model == ForecastLinear && n >= 3 {
+		t.Model = ForecastLinear
+		t.meanDay = float64(n-1) / 2
+		var sxy float64
+		for i, y := range daily {
+			dx := float64(i) - t.meanDay
+			t.sxx += dx * dx
+			sxy += dx * (y - mean)
+		}
+		t.Slope = sxy / t.sxx
+		t.Intercept = mean - t.Slope*t.meanDay
+		var sse float64
+		for i, y := range daily {
+			r := y - t.At(float64(i))
+			sse += r * r
+		}
+		t.Sigma = math.Sqrt(sse / float64(n-2))
+		return t
+	}
+
+	t.Intercept = mean
+	if n >= 2 {
+		var ss float64
+		for _, y := range daily {
+			ss += (y - mean) * (y - mean)
+		}
+		t.Sigma = math.Sqrt(ss / float64(n-1))
+	}
+	return t
+}
+
+// At returns the expected total of the day with the given index.
+func (t Trend) At(day float64) float64 {
+	return t.Intercept + t.Slope*day
+}
+
+// Project sums the expected totals of future days, each counted by the
+// fraction of it that is still to come, with ForecastConfidence bounds
+// covering both the uncertainty of the fit and the variation of single
+// days. Negative expectations of a falling trend are cut off at 0.
+//
+// Parameters:
+//   - days: Indexes of the future days, on the scale of the fitted totals
+//   - weights: Fraction of each day to count, from 0 to 1
+//
+// Returns:
+//   - float64: Projected sum
+//   - float64: Lower bound, at least 0
+//   - float64: Upper bound
+func (t Trend) Project(days, weights []float64) (float64, float64, float64) {
+	if t.Days == 0 {
+		return 0, 0, 0
+	}
+	var value, sumW, sumW2, sumWDx float64
+	for i, day := range days {
+		w := weights[i]
+		value += w * t.At(day)
+		sumW += w
+		sumW2 += w * w
+		sumWDx += w * (day - t.meanDay)
+	}
+	// Variance of the fitted line's sum plus the days' own noise
+	variance := sumW*sumW/float64(t.Days) + sumW2
+	if t.Model == ForecastLinear {
+		variance += sumWDx * sumWDx / t.sxx
+	}
+	margin := forecastZ * t.Sigma * math.Sqrt(variance)
+	value = max(value, 0)
+	return value, max(value-margin, 0), value + margin
+}
@@ -0,0 +1,47 @@
+package estimator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFitTrend(t *testing.T) {
+	// An exact line has no spread, so the bounds collapse onto it
+	line := FitTrend(ForecastLinear, []float64{10, 12, 14, 16})
+	if line.Model != ForecastLinear || line.Slope != 2 || line.Intercept != 10 || line.Sigma > 1e-9 {
+		t.Fatalf("Unexpected linear fit %+v", line)
+	}
+	value, lower, upper := line.Project([]float64{4, 5}, []float64{0.5, 1})
+	if value != 9+20 || math.Abs(upper-lower) > 1e-6 {
+		t.Errorf("Expected half of day 4 and all of day 5, got %v [%v, %v]", value, lower, upper)
+	}
+
+	// Noisy days widen the bounds, more so further from the fitted days
+	noisy := FitTrend(ForecastLinear, []float64{10, 14, 12, 18, 14, 20})
+	_, nearLow, nearHigh := noisy.Project([]float64{6}, []float64{1})
+	_, farLow, farHigh := noisy.Project([]float64{30}, []float64{1})
+	if nearHigh-nearLow <= 0 || farHigh-farLow <= nearHigh-nearLow {
+		t.Errorf("Expected wider bounds further out, got [%v, %v] and [%v, %v]", nearLow, nearHigh, farLow, farHigh)
+	}
+
+	avg := FitTrend(ForecastMovingAverage, []float64{8, 12})
+	if avg.Model != ForecastMovingAverage || avg.Intercept != 10 || avg.Slope != 0 || avg.Sigma == 0 {
+		t.Errorf("Unexpected moving average %+v", avg)
+	}
+	if value, _, _ := avg.Project([]float64{2, 3, 4}, []float64{1, 1, 1}); value != 30 {
+		t.Errorf("Expected three days at the mean, got %v", value)
+	}
+
+	// Too few days for a line fall back to the mean
+	if short := FitTrend(ForecastLinear, []float64{5, 7}); short.Model != ForecastMovingAverage || short.Intercept != 6 {
+		t.Errorf("Expected a moving average from two days, got %+v", short)
+	}
+	// A falling trend never projects below zero
+	falling := FitTrend(ForecastLinear, []float64{30, 20, 10})
+	if value, lower, _ := falling.Project([]float64{5, 6}, []float64{1, 1}); value != 0 || lower != 0 {
+		t.Errorf("Expected the projection cut off at zero, got %v, %v", value, lower)
+	}
+	if value, lower, upper := FitTrend(ForecastLinear, nil).Project([]float64{0}, []float64{1}); value != 0 || lower != 0 || upper != 0 {
+		t.Errorf("Expected nothing projected without history, got %v [%v, %v]", value, lower, upper)
+	}
+}
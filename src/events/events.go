@@ -0,0 +1,188 @@
+// Package events is an in-process publish/subscribe bus for LogpushEstimator's
+// domain events.
+//
+// Subsystems publish what happened (a batch was ingested, expired data was
+// pruned, a Logpush job was synced, an alert fired) without knowing who is
+// listening, and integrations such as metrics, the admin event log,
+// webhooks or live dashboards subscribe to the topics they need. Adding an
+// integration is then a new subscriber rather than another call in every
+// publisher.
+//
+// Delivery is synchronous: Publish calls each matching subscriber in turn
+// on the publisher's goroutine, so subscribers must return quickly and
+// hand slow work such as network calls to their own goroutines or, like
+// the webhook outbox, queue it for a background worker. A panicking
+// subscriber is logged and does not affect the publisher or the other
+// subscribers.
+//
+// # Usage
+//
+//	bus := events.NewBus(nil, logger)
+//	unsubscribe := bus.Subscribe(func(e events.Event) {
+//		received := e.Data.(events.IngestReceived)
+//		logger.Info("Batch ingested", "bytes", received.Bytes)
+//	}, events.TopicIngestReceived)
+//	defer unsubscribe()
+//
+//	bus.Publish(events.TopicIngestReceived, events.IngestReceived{Bytes: 1024, Records: 3})
+package events
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/clock"
+)
+
+// Topics published by the estimator. The payload type of each is named
+// after it.
+const (
+	TopicIngestReceived  = "ingest.received"  // A batch was stored by the ingestion endpoint
+	TopicRetentionPruned = "retention.pruned" // Expired data was removed by a background job
+	TopicAlertFired      = "alert.fired"      // A monitored condition started failing
+	TopicJobSynced       = "job.synced"       // A tracked Logpush job's status was refreshed
+)
+
+// Topics lists every topic published by the estimator.
+var Topics = []string{TopicIngestReceived, TopicRetentionPruned, TopicAlertFired, TopicJobSynced}
+
+// Event is one published occurrence.
+type Event struct {
+	Topic string    `json:"topic"` // One of the Topic constants
+	Time  time.Time `json:"time"`  // When the event was published
+	Data  any       `json:"data"`  // Topic-specific payload, e.g. IngestReceived
+}
+
+// IngestReceived is the payload of TopicIngestReceived.
+type IngestReceived struct {
+	Tenant  string `json:"tenant,omitempty"`  // Tenant the batch was stored under; empty for the default
+	Dataset string `json:"dataset,omitempty"` // Dataset named by the ?dataset= parameter, if any
+	Zone    string `json:"zone,omitempty"`    // Zone named by the path or the ?zone= parameter, if any
+	Bytes   int64  `json:"bytes"`             // Batch size in bytes
+	Records int64  `json:"records"`           // Records in the batch
+}
+
+// RetentionPruned is the payload of TopicRetentionPruned.
+type RetentionPruned struct {
+	Kind    string    `json:"kind"`    // What was pruned: log_sizes, minute_aggregates or trash
+	Removed int64     `json:"removed"` // Rows or batches removed
+	Cutoff  time.Time `json:"cutoff"`  // Data older than this was removed
+}
+
+// AlertFired is the payload of TopicAlertFired.
+type AlertFired struct {
+	Alert   string `json:"alert"`          // Kind of alert, e.g. logpush_job_failing
+	Subject string `json:"subject"`        // What the alert is about, e.g. a job ID
+	Message string `json:"message"`        // Human-readable description
+	Path    string `json:"path,omitempty"` // Dashboard or API path showing the data behind the alert
+}
+
+// JobSynced is the payload of TopicJobSynced.
+type JobSynced struct {
+	JobID  int64  `json:"job_id"` // Cloudflare Logpush job ID
+	Name   string `json:"name"`   // Job name
+	Health string `json:"health"` // unknown, disabled, pending, healthy or failing
+}
+
+// Handler receives published events.
+type Handler func(Event)
+
+// subscription is one subscriber's handler and topic filter.
+type subscription struct {
+	handler Handler
+	topics  map[string]bool // nil receives every topic
+}
+
+// Bus delivers published events to subscribers. It is safe for concurrent
+// use, and a nil *Bus discards everything published to it so optional
+// publishers need no checks.
+type Bus struct {
+	clock  clock.Clock
+	logger *slog.Logger
+
+	mu     sync.RWMutex
+	subs   map[int]*subscription
+	nextID int
+}
+
+// NewBus creates a bus without subscribers.
+//
+// Parameters:
+//   - clk: Source of event times; nil uses the system clock
+//   - logger: Logger for subscriber panics; nil uses slog.Default()
+//
+// Returns:
+//   - *Bus: Empty bus
+func NewBus(clk clock.Clock, logger *slog.Logger) *Bus {
+	if clk == nil {
+		clk = clock.System
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Bus{clock: clk, logger: logger, subs: make(map[int]*subscription)}
+}
+
+// Subscribe registers handler for events on the given topics, or on every
+// topic when none are given.
+//
+// Parameters:
+//   - handler: Called on the publisher's goroutine for each matching event
+//   - topics: Topics to receive; empty receives all
+//
+// Returns:
+//   - func(): Removes the subscription; safe to call more than once
+func (b *Bus) Subscribe(handler Handler, topics ...string) func() {
+	sub := &subscription{handler: handler}
+	if len(topics) > 0 {
+		sub.topics = make(map[string]bool, len(topics))
+		for _, t := range topics {
+			sub.topics[t] = true
+		}
+	}
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = sub
+	b.mu.Unlock()
+	return func() {
+		b.mu.Lock()
+		delete(b.subs, id)
+		b.mu.Unlock()
+	}
+}
+
+// Publish stamps data with the current time and delivers it to every
+// subscriber of topic before returning.
+//
+// Parameters:
+//   - topic: Event topic, normally one of the Topic constants
+//   - data: Payload; the type documented for the topic
+func (b *Bus) Publish(topic string, data any) {
+	if b == nil {
+		return
+	}
+	e := Event{Topic: topic, Time: b.clock.Now().UTC(), Data: data}
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.subs))
+	for _, sub := range b.subs {
+		if sub.topics == nil || sub.topics[topic] {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	b.mu.RUnlock()
+	for _, h := range handlers {
+		b.deliver(h, e)
+	}
+}
+
+// deliver calls one handler, logging rather than propagating a panic.
+func (b *Bus) deliver(h Handler, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.logger.Error("Event subscriber panicked", "topic", e.Topic, "panic", r)
+		}
+	}()
+	h(e)
+}
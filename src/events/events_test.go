@@ -0,0 +1,53 @@
+package events
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/clock"
+)
+
+func TestBusDeliversByTopic(t *testing.T) {
+	at := time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC)
+	bus := NewBus(clock.Func(func() time.Time { return at }), nil)
+
+	var ingested, all []Event
+	bus.Subscribe(func(e Event) { ingested = append(ingested, e) }, TopicIngestReceived)
+	unsubscribe := bus.Subscribe(func(e Event) { all = append(all, e) })
+
+	bus.Publish(TopicIngestReceived, IngestReceived{Bytes: 10, Records: 2})
+	bus.Publish(TopicRetentionPruned, RetentionPruned{Kind: "trash", Removed: 1})
+
+	if len(ingested) != 1 || ingested[0].Data.(IngestReceived).Bytes != 10 {
+		t.Errorf("Expected one ingest event, got %+v", ingested)
+	}
+	if len(all) != 2 || all[1].Topic != TopicRetentionPruned || !all[1].Time.Equal(at) {
+		t.Errorf("Expected both events stamped with the clock, got %+v", all)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	bus.Publish(TopicIngestReceived, IngestReceived{})
+	if len(all) != 2 || len(ingested) != 2 {
+		t.Errorf("Expected only the remaining subscriber to receive events, got %d and %d", len(all), len(ingested))
+	}
+}
+
+func TestBusRecoversSubscriberPanics(t *testing.T) {
+	bus := NewBus(nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	bus.Subscribe(func(Event) { panic("boom") })
+	delivered := false
+	bus.Subscribe(func(Event) { delivered = true })
+
+	bus.Publish(TopicAlertFired, AlertFired{Alert: "test"})
+	if !delivered {
+		t.Error("Expected a panicking subscriber not to block the others")
+	}
+}
+
+func TestNilBusDiscards(t *testing.T) {
+	var bus *Bus
+	bus.Publish(TopicJobSynced, JobSynced{JobID: 1}) // must not panic
+}
@@ -0,0 +1,239 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+)
+
+// archiveTimeLayout formats the times in archive file names.
+const archiveTimeLayout = "20060102T150405Z"
+
+// encryptedSuffix is appended to the name of a sealed archive.
+const encryptedSuffix = ".enc"
+
+// Archived describes an archive written by Archive.
+type Archived struct {
+	File     string   `json:"file"`     // Path of the gzip-compressed CSV, sealed when Encrypted
+	Manifest Manifest `json:"manifest"` // Manifest of the uncompressed CSV, also written next to File
+}
+
+// Archive writes the records in [start, end) to dir as a gzip-compressed
+// CSV, in the same format as Build, with its manifest beside it, so
+// records can be deleted from the database without losing them. The files
+// are named after end and the time of archiving:
+//
+//	logpush-archive-20250601T000000Z-20250915T103000Z.csv.gz
+//	logpush-archive-20250601T000000Z-20250915T103000Z.manifest.json
+//
+// With a key the archive is sealed and gets a .enc suffix; the manifest
+// describes the CSV before compression and encryption either way. Each file
+// is renamed into place once complete, so a partly written archive is never
+// left under the final name. Nothing is written for an empty range.
+//
+// Parameters:
+//   - db: Database controller to read from
+//   - dir: Directory to write to; created if missing
+//   - start: Range start (inclusive)
+//   - end: Range end (exclusive)
+//   - key: Encryption key for the archive, or nil to leave it unsealed
+//
+// Returns:
+//   - Archived: The archive written, with File empty when the range was empty
+//   - error: Any error encountered; the records must not be deleted then
+func Archive(db *database.SQLiteController, dir string, start, end time.Time, key *encryption.Key) (Archived, error) {
+	data, m, err := Build(db, start, end)
+	if err != nil || m.Rows == 0 {
+		return Archived{Manifest: m}, err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return Archived{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return Archived{}, err
+	}
+	archive := buf.Bytes()
+	base := filepath.Join(dir, "logpush-archive-"+m.End.Format(archiveTimeLayout)+"-"+m.GeneratedAt.Format(archiveTimeLayout))
+	file := base + ".csv.gz"
+	if key != nil {
+		if archive, err = key.Seal(archive); err != nil {
+			return Archived{}, err
+		}
+		file += encryptedSuffix
+	}
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return Archived{}, err
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return Archived{}, err
+	}
+	if err := writeFileAtomic(file, archive); err != nil {
+		return Archived{}, err
+	}
+	if err := writeFileAtomic(base+".manifest.json", append(manifest, '\n')); err != nil {
+		os.Remove(file)
+		return Archived{}, err
+	}
+	return Archived{File: file, Manifest: m}, nil
+}
+
+// ErrSealedArchive is returned by ReadArchives for a sealed archive when no
+// key was given.
+var ErrSealedArchive = errors.New("archive is sealed and no key is configured")
+
+// ArchiveFile is an archive found by FindArchives.
+type ArchiveFile struct {
+	Path     string   // Path of the archive, sealed when it ends in .enc
+	Manifest Manifest // Manifest written beside the archive
+}
+
+// FindArchives lists the archives Archive wrote to dir whose manifest range
+// overlaps [start, end), oldest first. A missing dir holds no archives.
+//
+// Parameters:
+//   - dir: Directory the archives were written to
+//   - start: Range start (inclusive)
+//   - end: Range end (exclusive)
+//
+// Returns:
+//   - []ArchiveFile: Overlapping archives
+//   - error: Any error reading a manifest, or a manifest without its archive
+func FindArchives(dir string, start, end time.Time) ([]ArchiveFile, error) {
+	manifests, err := filepath.Glob(filepath.Join(dir, "logpush-archive-*.manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+	var files []ArchiveFile
+	for _, path := range manifests {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var m Manifest
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		if !m.Start.Before(end) || !start.Before(m.End) {
+			continue
+		}
+		file := strings.TrimSuffix(path, ".manifest.json") + ".csv.gz"
+		if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+			file += encryptedSuffix
+			_, err = os.Stat(file)
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, ArchiveFile{Path: file, Manifest: m})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Manifest.End.Before(files[j].Manifest.End) })
+	return files, nil
+}
+
+// ReadArchives returns the records in [start, end) held by files. Each
+// archive is checked against its manifest before its records are used.
+//
+// Parameters:
+//   - files: Archives from FindArchives
+//   - start: Range start (inclusive)
+//   - end: Range end (exclusive)
+//   - key: Encryption key for sealed archives, or nil
+//
+// Returns:
+//   - []database.LogSize: Archived records in the range, oldest first
+//   - error: ErrSealedArchive, a manifest mismatch, or any error reading the files
+func ReadArchives(files []ArchiveFile, start, end time.Time, key *encryption.Key) ([]database.LogSize, error) {
+	var out []database.LogSize
+	for _, f := range files {
+		logs, err := readArchive(f, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range logs {
+			if !l.Timestamp.Before(start) && l.Timestamp.Before(end) {
+				out = append(out, l)
+			}
+		}
+	}
+	sortLogs(out)
+	return out, nil
+}
+
+// readArchive decodes the records of f, failing if its CSV does not match
+// the manifest.
+func readArchive(f ArchiveFile, key *encryption.Key) ([]database.LogSize, error) {
+	name := filepath.Base(f.Path)
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(f.Path, encryptedSuffix) {
+		if key == nil {
+			return nil, fmt.Errorf("%s: %w", name, ErrSealedArchive)
+		}
+		if data, err = key.Open(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	csvData, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	sum := sha256.Sum256(csvData)
+	if int64(len(csvData)) != f.Manifest.Bytes || hex.EncodeToString(sum[:]) != f.Manifest.SHA256 {
+		return nil, fmt.Errorf("%s: does not match its manifest", name)
+	}
+	return ReadCSV(bytes.NewReader(csvData))
+}
+
+// writeFileAtomic writes data to a temporary file beside path, syncs it
+// and renames it to path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+// Package export writes raw log size records out of the database for
+// hand-off to other systems, together with a manifest that lets the
+// recipient (or an auditor) check the file is complete and unmodified.
+//
+// A Manifest records the time range, row count, byte counts and SHA-256 of
+// an export. Encoding is deterministic: the same records always produce the
+// same bytes, so Verify can re-export the manifest's range and report
+// whether the database still matches what was handed over.
+//
+// Stream writes a range as CSV or NDJSON while reading it, without a
+// manifest, for ranges too large to build in memory.
+//
+// # Usage
+//
+//	data, manifest, err := export.Build(db, start, end)
+//	if err != nil {
+//		return err
+//	}
+//	// ... deliver data and manifest ...
+//	result, err := export.Verify(db, manifest)
+package export
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// FormatCSV is the only export format currently produced.
+const FormatCSV = "csv"
+
+// ErrUnsupportedFormat is returned by Verify for manifests of a format this
+// version cannot reproduce.
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// csvHeader is the first line of every CSV export.
+var csvHeader = []string{"id", "timestamp", "filesize", "record_count", "min_record_size", "max_record_size", "avg_record_size"}
+
+// Manifest describes a single export.
+type Manifest struct {
+	Format      string    `json:"format"`       // Export format, e.g. "csv"
+	Start       time.Time `json:"start"`        // Range start (inclusive), UTC
+	End         time.Time `json:"end"`          // Range end (exclusive), UTC
+	Rows        int64     `json:"rows"`         // Data rows, excluding the header
+	Bytes       int64     `json:"bytes"`        // Length of the export file in bytes
+	LogBytes    int64     `json:"log_bytes"`    // Sum of the filesize column
+	SHA256      string    `json:"sha256"`       // Hex SHA-256 of the export file
+	GeneratedAt time.Time `json:"generated_at"` // When the export was produced, UTC
+}
+
+// Verification is the result of checking a manifest against the database.
+type Verification struct {
+	Valid      bool     `json:"valid"`      // True when the current data matches the manifest
+	Mismatches []string `json:"mismatches"` // Manifest fields that differ from the current data
+	Current    Manifest `json:"current"`    // Manifest of the range as it is now
+}
+
+// WriteCSV encodes logs as CSV with a header row. Timestamps are written in
+// UTC with nanosecond precision.
+//
+// Parameters:
+//   - w: Destination for the encoded records
+//   - logs: Records to write, in the order given
+//
+// Returns:
+//   - error: Any error encountered while writing
+func WriteCSV(w io.Writer, logs []database.LogSize) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, l := range logs {
+		err := cw.Write([]string{
+			strconv.FormatInt(l.ID, 10),
+			l.Timestamp.UTC().Format(time.RFC3339Nano),
+			strconv.FormatInt(l.Filesize, 10),
+			strconv.FormatInt(l.RecordCount, 10),
+			strconv.FormatInt(l.MinRecordSize, 10),
+			strconv.FormatInt(l.MaxRecordSize, 10),
+			strconv.FormatFloat(l.AvgRecordSize, 'f', -1, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV decodes records written by WriteCSV.
+//
+// Parameters:
+//   - r: Source of the encoded records, starting with the header row
+//
+// Returns:
+//   - []database.LogSize: Decoded records, in file order
+//   - error: Any error encountered while reading, or a malformed row
+func ReadCSV(r io.Reader) ([]database.LogSize, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader)
+	if _, err := cr.Read(); err != nil {
+		return nil, err
+	}
+	var logs []database.LogSize
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return logs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var l database.LogSize
+		ints := []*int64{&l.ID, &l.Filesize, &l.RecordCount, &l.MinRecordSize, &l.MaxRecordSize}
+		for i, field := range []string{row[0], row[2], row[3], row[4], row[5]} {
+			if *ints[i], err = strconv.ParseInt(field, 10, 64); err != nil {
+				return nil, err
+			}
+		}
+		if l.Timestamp, err = time.Parse(time.RFC3339Nano, row[1]); err != nil {
+			return nil, err
+		}
+		if l.AvgRecordSize, err = strconv.ParseFloat(row[6], 64); err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+}
+
+// sortLogs orders logs by timestamp, and records sharing a timestamp by ID,
+// so output is reproducible.
+func sortLogs(logs []database.LogSize) {
+	sort.SliceStable(logs, func(i, j int) bool {
+		if !logs[i].Timestamp.Equal(logs[j].Timestamp) {
+			return logs[i].Timestamp.Before(logs[j].Timestamp)
+		}
+		return logs[i].ID < logs[j].ID
+	})
+}
+
+// Build exports the records in [start, end) as CSV and returns the file
+// contents with its manifest.
+//
+// Parameters:
+//   - db: Database controller to read from
+//   - start: Range start (inclusive)
+//   - end: Range end (exclusive)
+//
+// Returns:
+//   - []byte: The CSV file
+//   - Manifest: Description of the file
+//   - error: Any error encountered while querying or encoding
+func Build(db *database.SQLiteController, start, end time.Time) ([]byte, Manifest, error) {
+	logs, err := db.QueryByTimeRange(start, end)
+	if err != nil {
+		return nil, Manifest{}, err
+	}
+	sortLogs(logs)
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, logs); err != nil {
+		return nil, Manifest{}, err
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	m := Manifest{
+		Format:      FormatCSV,
+		Start:       start.UTC(),
+		End:         end.UTC(),
+		Rows:        int64(len(logs)),
+		Bytes:       int64(buf.Len()),
+		SHA256:      hex.EncodeToString(sum[:]),
+		GeneratedAt: time.Now().UTC(),
+	}
+	for _, l := range logs {
+		m.LogBytes += l.Filesize
+	}
+	return buf.Bytes(), m, nil
+}
+
+// Verify re-exports the manifest's time range and compares the result with
+// the manifest, reporting which fields no longer match (for example because
+// records in the range were deleted or added after the export).
+//
+// Parameters:
+//   - db: Database controller to read from
+//   - m: Manifest from a previous export
+//
+// Returns:
+//   - Verification: Comparison result including the current manifest
+//   - error: ErrUnsupportedFormat, or any error encountered while exporting
+func Verify(db *database.SQLiteController, m Manifest) (Verification, error) {
+	if m.Format != FormatCSV {
+		return Verification{}, fmt.Errorf("%w: %q", ErrUnsupportedFormat, m.Format)
+	}
+	_, current, err := Build(db, m.Start, m.End)
+	if err != nil {
+		return Verification{}, err
+	}
+
+	v := Verification{Mismatches: []string{}, Current: current}
+	if current.Rows != m.Rows {
+		v.Mismatches = append(v.Mismatches, "rows")
+	}
+	if current.Bytes != m.Bytes {
+		v.Mismatches = append(v.Mismatches, "bytes")
+	}
+	if current.LogBytes != m.LogBytes {
+		v.Mismatches = append(v.Mismatches, "log_bytes")
+	}
+	if current.SHA256 != m.SHA256 {
+		v.Mismatches = append(v.Mismatches, "sha256")
+	}
+	v.Valid = len(v.Mismatches) == 0
+	return v, nil
+}
@@ -0,0 +1,241 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+)
+
+func newTestDB(t *testing.T, path string) *database.SQLiteController {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(path, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(path)
+	})
+	return db
+}
+
+func TestWriteCSV(t *testing.T) {
+	ts := time.Date(2025, 9, 15, 14, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, []database.LogSize{{ID: 7, Timestamp: ts, Filesize: 2048, RecordCount: 4, MinRecordSize: 100, MaxRecordSize: 900, AvgRecordSize: 512}})
+	if err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	want := "id,timestamp,filesize,record_count,min_record_size,max_record_size,avg_record_size\n" +
+		"7,2025-09-15T12:30:00Z,2048,4,100,900,512\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected CSV:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestBuildManifest(t *testing.T) {
+	db := newTestDB(t, "test_export_build.db")
+
+	ts := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	for _, size := range []int64{100, 200, 300} {
+		if err := db.InsertLog(database.LogSize{Timestamp: ts, Filesize: size}); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+
+	data, m, err := Build(db, ts.Add(-time.Minute), ts.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to build export: %v", err)
+	}
+
+	sum := sha256.Sum256(data)
+	if m.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Manifest checksum does not match data")
+	}
+	if m.Rows != 3 || m.LogBytes != 600 || m.Bytes != int64(len(data)) {
+		t.Errorf("Unexpected manifest: %+v", m)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 4 {
+		t.Errorf("Expected header and 3 rows, got %d lines", lines)
+	}
+
+	again, _, err := Build(db, ts.Add(-time.Minute), ts.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to rebuild export: %v", err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Errorf("Expected identical output for the same range")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	db := newTestDB(t, "test_export_verify.db")
+
+	now := time.Now().UTC()
+	if err := db.InsertLog(database.LogSize{Timestamp: now.Add(-time.Hour), Filesize: 1024}); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+	_, m, err := Build(db, now.Add(-2*time.Hour), now)
+	if err != nil {
+		t.Fatalf("Failed to build export: %v", err)
+	}
+
+	v, err := Verify(db, m)
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if !v.Valid {
+		t.Fatalf("Expected unchanged range to verify, mismatches: %v", v.Mismatches)
+	}
+
+	// Records added inside the range invalidate the manifest
+	if err := db.InsertLog(database.LogSize{Timestamp: now.Add(-30 * time.Minute), Filesize: 10}); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+	v, err = Verify(db, m)
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if v.Valid || v.Current.Rows != 2 {
+		t.Errorf("Expected a mismatch after inserting into the range, got %+v", v)
+	}
+
+	m.Format = "parquet"
+	if _, err := Verify(db, m); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	db := newTestDB(t, "test_export_stream.db")
+
+	ts := time.Date(2025, 9, 15, 12, 0, 0, 0, time.UTC)
+	entries := []database.LogSize{
+		{Timestamp: ts.Add(time.Minute), Filesize: 200, RecordCount: 2, Zone: "example.com", Labels: map[string]string{"team": "edge", "env": "prod"}},
+		{Timestamp: ts, Filesize: 100, UncompressedSize: 300, Encoding: "gzip", RecordCount: 1, Dataset: "http_requests"},
+	}
+	if err := db.InsertLogs(entries); err != nil {
+		t.Fatalf("Failed to insert logs: %v", err)
+	}
+
+	var buf bytes.Buffer
+	rows, err := Stream(&buf, db, FormatCSV, ts, ts.Add(time.Hour))
+	if err != nil || rows != 2 {
+		t.Fatalf("Expected 2 CSV rows, got %d (%v)", rows, err)
+	}
+	want := "id,timestamp,tenant,dataset,zone,filesize,uncompressed_size,content_encoding,record_count,min_record_size,max_record_size,avg_record_size,labels\n" +
+		"2,2025-09-15T12:00:00Z,,http_requests,,100,300,gzip,1,0,0,0,\n" +
+		"1,2025-09-15T12:01:00Z,,,example.com,200,200,,2,0,0,0,\"env=prod,team=edge\"\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected CSV:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	rows, err = Stream(&buf, db, FormatNDJSON, ts, ts.Add(time.Minute))
+	if err != nil || rows != 1 {
+		t.Fatalf("Expected 1 NDJSON row, got %d (%v)", rows, err)
+	}
+	want = `{"id":2,"timestamp":"2025-09-15T12:00:00Z","tenant":"","dataset":"http_requests","zone":"","filesize":100,"uncompressed_size":300,` +
+		`"content_encoding":"gzip","record_count":1,"min_record_size":0,"max_record_size":0,"avg_record_size":0,"labels":{}}` + "\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected NDJSON:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	if _, err := Stream(io.Discard, db, "parquet", ts, ts.Add(time.Hour)); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestArchiveSealed(t *testing.T) {
+	db := newTestDB(t, "test_export_archive.db")
+	key, err := encryption.NewKey(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("NewKey failed: %v", err)
+	}
+	dir := filepath.Join(t.TempDir(), "archive")
+
+	ts := time.Now().UTC().Add(-time.Hour)
+	if archived, err := Archive(db, dir, ts.Add(-time.Minute), ts.Add(time.Minute), key); err != nil || archived.File != "" {
+		t.Fatalf("Expected nothing archived for an empty range, got %+v, %v", archived, err)
+	}
+	if err := db.InsertLog(database.LogSize{Timestamp: ts, Filesize: 100}); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+
+	archived, err := Archive(db, dir, ts.Add(-time.Minute), ts.Add(time.Minute), key)
+	if err != nil || !strings.HasSuffix(archived.File, ".csv.gz.enc") || archived.Manifest.Rows != 1 {
+		t.Fatalf("Expected a sealed archive of one row, got %+v, %v", archived, err)
+	}
+	sealed, err := os.ReadFile(archived.File)
+	if err != nil {
+		t.Fatalf("Archive not written: %v", err)
+	}
+	compressed, err := key.Open(sealed)
+	if err != nil {
+		t.Fatalf("Archive does not decrypt: %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("Archive is not gzip: %v", err)
+	}
+	data, _ := io.ReadAll(zr)
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != archived.Manifest.SHA256 {
+		t.Errorf("Manifest checksum does not match the archived CSV")
+	}
+}
+
+func TestReadArchives(t *testing.T) {
+	db := newTestDB(t, "test_export_read_archives.db")
+	key, err := encryption.NewKey(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("NewKey failed: %v", err)
+	}
+	dir := t.TempDir()
+
+	ts := time.Now().UTC().Add(-48 * time.Hour).Truncate(time.Second)
+	for i, size := range []int64{100, 200} {
+		if err := db.InsertLog(database.LogSize{Timestamp: ts.Add(time.Duration(i) * time.Minute), Filesize: size, RecordCount: 2, AvgRecordSize: 12.5}); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+	if _, err := Archive(db, dir, ts, ts.Add(time.Hour), key); err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+
+	if files, err := FindArchives(dir, ts.Add(time.Hour), ts.Add(2*time.Hour)); err != nil || len(files) != 0 {
+		t.Fatalf("Expected no archives after the archived range, got %+v, %v", files, err)
+	}
+	files, err := FindArchives(dir, ts.Add(-time.Hour), ts.Add(30*time.Second))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected one overlapping archive, got %+v, %v", files, err)
+	}
+	if _, err := ReadArchives(files, ts, ts.Add(time.Hour), nil); !errors.Is(err, ErrSealedArchive) {
+		t.Errorf("Expected ErrSealedArchive without a key, got %v", err)
+	}
+
+	logs, err := ReadArchives(files, ts.Add(-time.Hour), ts.Add(30*time.Second), key)
+	if err != nil {
+		t.Fatalf("ReadArchives failed: %v", err)
+	}
+	if len(logs) != 1 || logs[0].Filesize != 100 || logs[0].RecordCount != 2 || logs[0].AvgRecordSize != 12.5 || !logs[0].Timestamp.Equal(ts) {
+		t.Errorf("Expected only the first record, got %+v", logs)
+	}
+
+	if files, err := FindArchives(filepath.Join(dir, "missing"), ts, ts.Add(time.Hour)); err != nil || len(files) != 0 {
+		t.Errorf("Expected no archives in a missing directory, got %+v, %v", files, err)
+	}
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// FormatNDJSON streams one JSON object per line, the format BigQuery and
+// most log pipelines load directly.
+const FormatNDJSON = "ndjson"
+
+// streamColumns is the header of streamed CSV exports. Unlike csvHeader,
+// which is fixed so manifests stay verifiable, it carries every stored
+// column, in the order of the Record fields.
+var streamColumns = []string{"id", "timestamp", "tenant", "dataset", "zone", "filesize", "uncompressed_size", "content_encoding",
+	"record_count", "min_record_size", "max_record_size", "avg_record_size", "labels"}
+
+// Record is one log size record as streamed by Stream. In CSV, labels are
+// written as key=value pairs ordered by key and joined by commas.
+type Record struct {
+	ID               int64             `json:"id"`                // Record ID
+	Timestamp        time.Time         `json:"timestamp"`         // When the batch was received, UTC
+	Tenant           string            `json:"tenant"`            // Tenant the batch was ingested for; empty for the default tenant
+	Dataset          string            `json:"dataset"`           // Logpush dataset; empty if not named at ingestion
+	Zone             string            `json:"zone"`              // Cloudflare zone; empty if not named at ingestion
+	Filesize         int64             `json:"filesize"`          // Bytes as received
+	UncompressedSize int64             `json:"uncompressed_size"` // Bytes after decompression
+	Encoding         string            `json:"content_encoding"`  // Content-Encoding received with, e.g. "gzip"
+	RecordCount      int64             `json:"record_count"`      // Log lines in the batch
+	MinRecordSize    int64             `json:"min_record_size"`   // Smallest line in bytes
+	MaxRecordSize    int64             `json:"max_record_size"`   // Largest line in bytes
+	AvgRecordSize    float64           `json:"avg_record_size"`   // Average line in bytes
+	Labels           map[string]string `json:"labels"`            // Labels sent with the batch; empty without labels
+}
+
+// newRecord converts a stored record for streaming.
+func newRecord(l database.LogSize) Record {
+	labels := l.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	return Record{
+		ID: l.ID, Timestamp: l.Timestamp.UTC(), Tenant: l.Tenant, Dataset: l.Dataset, Zone: l.Zone,
+		Filesize: l.Filesize, UncompressedSize: l.UncompressedSize, Encoding: l.Encoding,
+		RecordCount: l.RecordCount, MinRecordSize: l.MinRecordSize, MaxRecordSize: l.MaxRecordSize, AvgRecordSize: l.AvgRecordSize,
+		Labels: labels,
+	}
+}
+
+// csvRow formats r in the order of streamColumns.
+func (r Record) csvRow() []string {
+	labels := make([]string, 0, len(r.Labels))
+	for k, v := range r.Labels {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+	return []string{
+		strconv.FormatInt(r.ID, 10),
+		r.Timestamp.Format(time.RFC3339Nano),
+		r.Tenant,
+		r.Dataset,
+		r.Zone,
+		strconv.FormatInt(r.Filesize, 10),
+		strconv.FormatInt(r.UncompressedSize, 10),
+		r.Encoding,
+		strconv.FormatInt(r.RecordCount, 10),
+		strconv.FormatInt(r.MinRecordSize, 10),
+		strconv.FormatInt(r.MaxRecordSize, 10),
+		strconv.FormatFloat(r.AvgRecordSize, 'f', -1, 64),
+		strings.Join(labels, ","),
+	}
+}
+
+// ValidStreamFormat reports whether format can be passed to Stream.
+func ValidStreamFormat(format string) bool {
+	return format == FormatCSV || format == FormatNDJSON
+}
+
+// Stream writes the records in [start, end) to w as they are read, ordered
+// by timestamp, then ID, so exports of any size use constant memory. CSV
+// starts with a header row; NDJSON has no header. Streams carry every
+// stored column and are not checksummed: use Build when the recipient needs
+// a manifest.
+//
+// Parameters:
+//   - w: Destination for the encoded records
+//   - db: Database controller to read from
+//   - format: FormatCSV or FormatNDJSON
+//   - start: Range start (inclusive)
+//   - end: Range end (exclusive)
+//
+// Returns:
+//   - int64: Records written
+//   - error: ErrUnsupportedFormat, or any error encountered while reading
+//     or writing; records already written stay written
+func Stream(w io.Writer, db *database.SQLiteController, format string, start, end time.Time) (int64, error) {
+	var rows int64
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(streamColumns); err != nil {
+			return 0, err
+		}
+		err := db.EachByTimeRange(start, end, func(l database.LogSize) error {
+			rows++
+			return cw.Write(newRecord(l).csvRow())
+		})
+		cw.Flush()
+		if err == nil {
+			err = cw.Error()
+		}
+		return rows, err
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		err := db.EachByTimeRange(start, end, func(l database.LogSize) error {
+			rows++
+			return enc.Encode(newRecord(l))
+		})
+		return rows, err
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
@@ -0,0 +1,154 @@
+// Package features provides a small feature-flag registry for LogpushEstimator.
+//
+// Experimental endpoints are registered behind a named flag together with the
+// API routes they serve. Operators enable flags through the environment, and
+// the GUI server consults the registry when mounting routes so disabled
+// features answer with a 404 instead of running.
+//
+// # Configuration
+//
+// Flags are enabled with a comma-separated list, and individual flags can be
+// forced on or off with a per-flag variable:
+//
+//	LPE_FEATURES=burst-detection
+//	LPE_FEATURE_BURST_DETECTION=false
+//
+// Per-flag variables take precedence over the list.
+//
+// # Usage
+//
+//	flags := features.NewRegistry(features.Defaults()...)
+//	unknown := flags.ApplyEnv(os.Getenv)
+//	if flags.Enabled("burst-detection") {
+//		// ...
+//	}
+package features
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Flag describes a single feature flag.
+type Flag struct {
+	Name        string   `json:"name"`        // Unique flag name (kebab-case)
+	Description string   `json:"description"` // Human-readable summary of the gated capability
+	Enabled     bool     `json:"enabled"`     // Current state of the flag
+	Paths       []string `json:"paths"`       // Route paths served only while the flag is enabled
+}
+
+// Defaults returns the built-in experimental flags with their default state.
+func Defaults() []Flag {
+	return []Flag{
+		{
+			Name:        "burst-detection",
+			Description: "Burst detection over per-minute aggregates",
+			Paths:       []string{"/api/stats/bursts"},
+		},
+		{
+			Name:        "dataset-parsers",
+			Description: "Parse HTTP request, firewall and Workers trace records into dimensions at ingest (costs CPU)",
+			Paths:       []string{"/api/stats/dimensions", "/api/dimensions/query"},
+		},
+	}
+}
+
+// Registry holds the known feature flags. It is safe for concurrent use so
+// flags can be toggled at runtime while requests are being served.
+type Registry struct {
+	mu    sync.RWMutex
+	flags map[string]*Flag
+}
+
+// NewRegistry creates a registry containing the given flags.
+func NewRegistry(flags ...Flag) *Registry {
+	r := &Registry{flags: make(map[string]*Flag, len(flags))}
+	for _, f := range flags {
+		f := f
+		f.Paths = append([]string(nil), f.Paths...)
+		r.flags[f.Name] = &f
+	}
+	return r
+}
+
+// Enabled reports whether the named flag exists and is enabled.
+func (r *Registry) Enabled(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.flags[name]
+	return ok && f.Enabled
+}
+
+// Set changes the state of a flag. It returns false if the flag is unknown.
+func (r *Registry) Set(name string, enabled bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.flags[name]
+	if ok {
+		f.Enabled = enabled
+	}
+	return ok
+}
+
+// List returns a snapshot of all flags ordered by name.
+func (r *Registry) List() []Flag {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Flag, 0, len(r.flags))
+	for _, f := range r.flags {
+		cp := *f
+		cp.Paths = append([]string(nil), f.Paths...)
+		out = append(out, cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// FlagForPath returns the name of the flag gating path, if any.
+func (r *Registry) FlagForPath(path string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for name, f := range r.flags {
+		for _, p := range f.Paths {
+			if p == path {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ApplyEnv enables flags listed in LPE_FEATURES and then applies per-flag
+// LPE_FEATURE_<NAME> overrides, where <NAME> is the flag name upper-cased with
+// dashes replaced by underscores. It returns names from LPE_FEATURES that do
+// not match any registered flag so the caller can warn about typos.
+func (r *Registry) ApplyEnv(getenv func(string) string) []string {
+	var unknown []string
+	for _, name := range strings.Split(getenv("LPE_FEATURES"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !r.Set(name, true) {
+			unknown = append(unknown, name)
+		}
+	}
+
+	for _, f := range r.List() {
+		value := getenv(EnvName(f.Name))
+		if value == "" {
+			continue
+		}
+		if enabled, err := strconv.ParseBool(value); err == nil {
+			r.Set(f.Name, enabled)
+		}
+	}
+	return unknown
+}
+
+// EnvName returns the per-flag environment variable for a flag name.
+func EnvName(flag string) string {
+	return "LPE_FEATURE_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
@@ -0,0 +1,60 @@
+package features
+
+import "testing"
+
+func TestRegistryApplyEnv(t *testing.T) {
+	r := NewRegistry(
+		Flag{Name: "alpha", Paths: []string{"/api/alpha"}},
+		Flag{Name: "beta-test", Enabled: true},
+		Flag{Name: "gamma"},
+	)
+
+	env := map[string]string{
+		"LPE_FEATURES":          "alpha, gamma, typo",
+		"LPE_FEATURE_BETA_TEST": "false",
+		"LPE_FEATURE_GAMMA":     "0",
+		"LPE_FEATURE_UNRELATED": "true",
+	}
+	unknown := r.ApplyEnv(func(key string) string { return env[key] })
+
+	if len(unknown) != 1 || unknown[0] != "typo" {
+		t.Errorf("Expected unknown flags [typo], got %v", unknown)
+	}
+	if !r.Enabled("alpha") {
+		t.Error("Expected alpha to be enabled from LPE_FEATURES")
+	}
+	if r.Enabled("beta-test") {
+		t.Error("Expected beta-test to be disabled by its override")
+	}
+	if r.Enabled("gamma") {
+		t.Error("Expected per-flag override to win over LPE_FEATURES")
+	}
+	if r.Enabled("missing") {
+		t.Error("Unknown flags must report disabled")
+	}
+}
+
+func TestRegistryFlagForPath(t *testing.T) {
+	r := NewRegistry(Defaults()...)
+
+	name, ok := r.FlagForPath("/api/stats/bursts")
+	if !ok || name != "burst-detection" {
+		t.Errorf("Expected /api/stats/bursts to be gated by burst-detection, got %q (%v)", name, ok)
+	}
+	if _, ok := r.FlagForPath("/api/stats/summary"); ok {
+		t.Error("Expected /api/stats/summary to be ungated")
+	}
+}
+
+func TestRegistryListIsSnapshot(t *testing.T) {
+	r := NewRegistry(Flag{Name: "b"}, Flag{Name: "a"})
+
+	list := r.List()
+	if len(list) != 2 || list[0].Name != "a" {
+		t.Fatalf("Expected flags sorted by name, got %+v", list)
+	}
+	list[0].Enabled = true
+	if r.Enabled("a") {
+		t.Error("Mutating the List result must not change the registry")
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/yamldoc"
+)
+
+// configExportFilename is the attachment name suggested for exported
+// configuration documents.
+const configExportFilename = "logpush-estimator.yaml"
+
+// decodeConfigDocument reads a YAML configuration document from the
+// request body. JSON documents are YAML too, so they are accepted as well.
+func decodeConfigDocument(r *http.Request) (config.Document, error) {
+	var doc config.Document
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return doc, err
+	}
+	err = yamldoc.Unmarshal(body, &doc)
+	return doc, err
+}
+
+// makeConfigExportHandler serves GET /api/admin/config/export. The response
+// is the bare configuration document as YAML (not wrapped in the API
+// envelope) so it can be committed to version control as-is.
+func makeConfigExportHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: config export", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		doc, err := config.Export(db)
+		if err != nil {
+			logger.Error("Failed to export configuration", "error", err)
+			sendErrorResponse(w, "Failed to export configuration")
+			return
+		}
+
+		out, err := yamldoc.Marshal(doc)
+		if err != nil {
+			logger.Error("Failed to encode configuration", "error", err)
+			sendErrorResponse(w, "Failed to export configuration")
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+configExportFilename+`"`)
+		w.Write(out)
+	}
+}
+
+// makeConfigImportHandler serves POST /api/admin/config/import, replacing the
+// stored configuration with the document in the request body. With
+// dry_run=true it returns the change set the import would make instead.
+func makeConfigImportHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: config import", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		doc, err := decodeConfigDocument(r)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid configuration document: "+err.Error())
+			return
+		}
+		if isDryRun(r) {
+			sendConfigChangeSet(w, logger, db, doc, true)
+			return
+		}
+		if err := config.Import(db, doc); err != nil {
+			var invalid *config.ValidationError
+			if errors.As(err, &invalid) {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			logger.Error("Failed to import configuration", "error", err)
+			sendErrorResponse(w, "Failed to import configuration")
+			return
+		}
+
+		exported, err := config.Export(db)
+		if err != nil {
+			sendErrorResponse(w, "Failed to read imported configuration")
+			return
+		}
+		logger.Info("Configuration imported")
+		sendSuccessResponse(w, exported)
+	}
+}
+
+// makeConfigHandler serves /api/admin/config for declarative management. GET
+// returns the current document (secrets redacted) and PUT applies a complete
+// document idempotently, returning the change set. With dry_run=true the
+// change set is computed but not applied.
+func makeConfigHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: config", "method", r.Method, "remote_addr", r.RemoteAddr)
+
+		switch r.Method {
+		case http.MethodGet:
+			doc, err := config.Export(db)
+			if err != nil {
+				logger.Error("Failed to read configuration", "error", err)
+				sendErrorResponse(w, "Failed to read configuration")
+				return
+			}
+			sendSuccessResponse(w, doc)
+
+		case http.MethodPut:
+			doc, err := decodeConfigDocument(r)
+			if err != nil {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid configuration document: "+err.Error())
+				return
+			}
+			sendConfigChangeSet(w, logger, db, doc, isDryRun(r))
+
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// sendConfigChangeSet applies doc (or only previews it when dryRun is set)
+// and responds with the resulting change set.
+func sendConfigChangeSet(w http.ResponseWriter, logger *slog.Logger, db *database.SQLiteController, doc config.Document, dryRun bool) {
+	apply := config.Apply
+	if dryRun {
+		apply = config.Preview
+	}
+	changes, err := apply(db, doc)
+	if err != nil {
+		var invalid *config.ValidationError
+		if errors.As(err, &invalid) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.Error("Failed to apply configuration", "error", err)
+		sendErrorResponse(w, "Failed to apply configuration")
+		return
+	}
+	logger.Info("Configuration change set", "dry_run", dryRun, "created", changes.Created, "updated", changes.Updated, "deleted", changes.Deleted)
+	sendSuccessResponse(w, changes)
+}
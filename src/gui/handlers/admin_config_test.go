@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/yamldoc"
+)
+
+func TestAPIConfigExportImport(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	apiHandlers := MakeAPIHandlers(db, logger)
+	export, imp := apiHandlers["/api/admin/config/export"], apiHandlers["/api/admin/config/import"]
+
+	body := `{"apiVersion":"logpush-estimator/v1","kind":"EstimatorConfig",
+		"budgets":[{"name":"monthly","period":"monthly","limit_bytes":1000000000}],
+		"tokens":[{"name":"ci","scopes":["admin"],"secret":"abc"}],
+		"retention":{"raw_days":30,"minute_aggregate_hours":48}}`
+	rr := httptest.NewRecorder()
+	imp.ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/config/import", strings.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from import, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	export.ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/config/export", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from export, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/yaml" {
+		t.Errorf("Expected application/yaml, got %q", ct)
+	}
+	if !strings.HasPrefix(rr.Body.String(), "apiVersion: logpush-estimator/v1\nkind: EstimatorConfig\n") {
+		t.Errorf("Expected a block-style YAML document, got %s", rr.Body.String())
+	}
+
+	// The export is the bare document, ready to import again
+	var doc config.Document
+	if err := yamldoc.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Could not parse exported document: %v", err)
+	}
+	if len(doc.Budgets) != 1 || doc.Tokens[0].Secret != config.Redacted || doc.Retention.RawDays != 30 {
+		t.Errorf("Unexpected exported document: %+v", doc)
+	}
+
+	rr2 := httptest.NewRecorder()
+	imp.ServeHTTP(rr2, httptest.NewRequest("POST", "/api/admin/config/import", strings.NewReader(rr.Body.String())))
+	if rr2.Code != http.StatusOK {
+		t.Errorf("Expected exported document to re-import, got %d: %s", rr2.Code, rr2.Body.String())
+	}
+
+	// A hand-written YAML document imports like the JSON one
+	yamlBody := `# Managed in git
+apiVersion: logpush-estimator/v1
+kind: EstimatorConfig
+budgets:
+  - name: daily
+    period: daily
+    limit_bytes: 5000
+tokens:
+  - {name: ci, scopes: [admin], secret: REDACTED}
+retention:
+  raw_days: 30
+  minute_aggregate_hours: 48
+`
+	rr = httptest.NewRecorder()
+	imp.ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/config/import", strings.NewReader(yamlBody)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from a YAML import, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if doc, err := config.Export(db); err != nil || len(doc.Budgets) != 1 || doc.Budgets[0].Name != "daily" || len(doc.Tokens) != 1 {
+		t.Errorf("Expected the YAML document stored, got %+v (%v)", doc, err)
+	}
+}
+
+func TestAPIConfigImportValidation(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/admin/config/import"]
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{"Invalid JSON", "POST", "{", http.StatusBadRequest},
+		{"Invalid YAML", "POST", "apiVersion: logpush-estimator/v1\n\tkind: EstimatorConfig\n", http.StatusBadRequest},
+		{"Unknown YAML field", "POST", "apiVersion: logpush-estimator/v1\nkind: EstimatorConfig\npricing: []\n", http.StatusBadRequest},
+		{"Unknown field", "POST", `{"apiVersion":"logpush-estimator/v1","kind":"EstimatorConfig","pricing":[]}`, http.StatusBadRequest},
+		{"Wrong kind", "POST", `{"apiVersion":"logpush-estimator/v1","kind":"Other"}`, http.StatusBadRequest},
+		{"Unsupported method", "GET", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tt.method, "/api/admin/config/import", strings.NewReader(tt.body)))
+			if rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPIConfigApply(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/admin/config"]
+
+	body := `{"apiVersion":"logpush-estimator/v1","kind":"EstimatorConfig",
+		"alert_rules":[{"name":"spike","metric":"bytes_per_hour","comparison":">","threshold":1e9,"window_minutes":60,"enabled":true}],
+		"retention":{"raw_days":0,"minute_aggregate_hours":48}}`
+
+	apply := func() config.ChangeSet {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("PUT", "/api/admin/config", strings.NewReader(body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200 from PUT, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var response struct {
+			Success bool             `json:"success"`
+			Data    config.ChangeSet `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Could not parse JSON response: %v", err)
+		}
+		return response.Data
+	}
+
+	// The alert rule plus the retention and sampling settings
+	if cs := apply(); cs.Created != 3 {
+		t.Errorf("Expected 3 objects created, got %+v", cs)
+	}
+	if cs := apply(); len(cs.Changes) != 0 {
+		t.Errorf("Expected repeated PUT to be a no-op, got %+v", cs)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/admin/config", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for DELETE, got %d", rr.Code)
+	}
+}
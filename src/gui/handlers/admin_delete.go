@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+	"github.com/melatonein5/LogpushEstimator/src/export"
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+)
+
+// largeDeleteRows is the row count above which a delete must be confirmed
+// with the token returned by a dry run.
+var largeDeleteRows int64 = 100000
+
+// DeletionReport is the response body for destructive admin operations.
+type DeletionReport struct {
+	Operation            string `json:"operation"`               // "delete-range", "prune" or "purge-dataset"
+	Dataset              string `json:"dataset,omitempty"`       // Dataset the delete is limited to; empty for every dataset
+	DryRun               bool   `json:"dry_run"`                 // True when nothing was deleted
+	Start                string `json:"start"`                   // Range start (inclusive), RFC3339
+	End                  string `json:"end"`                     // Range end (exclusive), RFC3339
+	Rows                 int64  `json:"rows"`                    // Rows affected (or that would be)
+	Bytes                int64  `json:"bytes"`                   // Bytes affected (or that would be)
+	ConfirmationRequired bool   `json:"confirmation_required"`   // Whether a confirm token is needed to delete
+	ConfirmToken         string `json:"confirm_token,omitempty"` // Token to pass as confirm=, returned on dry runs
+	TrashID              int64  `json:"trash_id,omitempty"`      // Trash batch to restore the deleted rows from
+	Archive              string `json:"archive,omitempty"`       // Archive file the rows were written to before deletion
+}
+
+// isDryRun reports whether the request asked for dry_run=true.
+func isDryRun(r *http.Request) bool {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	return dryRun
+}
+
+// deletionConfirmToken derives the confirmation token for deleting a range.
+// It changes whenever the range, dataset or number of affected rows changes,
+// so a token from a stale dry run is rejected.
+func deletionConfirmToken(operation, dataset string, start, end time.Time, rows int64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%d|%d", operation, dataset, start.UnixNano(), end.UnixNano(), rows)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// runDeletion summarizes or deletes the log records in [start, end), only
+// those of dataset unless it is empty, and writes the DeletionReport. Deletes
+// above largeDeleteRows are refused with 409 Conflict unless the request
+// carries the matching confirm token. When archive is not nil it is called
+// right before the delete, which only happens if it succeeds; it returns the
+// archive file written, if any.
+func runDeletion(w http.ResponseWriter, r *http.Request, db *database.SQLiteController, logger *slog.Logger, operation, dataset string, start, end time.Time, archive func() (string, error)) {
+	if dataset != "" {
+		db = db.ForDataset(dataset)
+	}
+	summary, err := db.SummarizeTimeRange(start, end)
+	if err != nil {
+		sendErrorResponse(w, "Failed to summarize affected records")
+		return
+	}
+
+	report := DeletionReport{
+		Operation:            operation,
+		Dataset:              dataset,
+		DryRun:               isDryRun(r),
+		Start:                start.UTC().Format(time.RFC3339),
+		End:                  end.UTC().Format(time.RFC3339),
+		Rows:                 summary.Rows,
+		Bytes:                summary.Bytes,
+		ConfirmationRequired: summary.Rows > largeDeleteRows,
+	}
+	token := deletionConfirmToken(operation, dataset, start, end, summary.Rows)
+
+	if report.DryRun {
+		if report.ConfirmationRequired {
+			report.ConfirmToken = token
+		}
+		logger.Info("Dry run", "operation", operation, "dataset", dataset, "rows", summary.Rows, "bytes", summary.Bytes)
+		sendSuccessResponse(w, report)
+		return
+	}
+
+	if report.ConfirmationRequired && r.URL.Query().Get("confirm") != token {
+		sendErrorResponseWithStatus(w, http.StatusConflict,
+			fmt.Sprintf("Deleting %d rows requires confirmation: repeat with dry_run=true and pass the returned confirm_token as confirm", summary.Rows))
+		return
+	}
+
+	if archive != nil {
+		file, err := archive()
+		if err != nil {
+			logger.Error("Failed to archive records; nothing was deleted", "operation", operation, "error", err)
+			sendErrorResponse(w, "Failed to archive records; nothing was deleted")
+			return
+		}
+		report.Archive = file
+	}
+
+	deleted, err := db.DeleteByTimeRange(start, end)
+	if err != nil {
+		sendErrorResponse(w, "Failed to delete records")
+		return
+	}
+	report.Rows, report.Bytes, report.TrashID = deleted.Rows, deleted.Bytes, deleted.TrashID
+	logger.Info("Records deleted", "operation", operation, "dataset", dataset, "rows", deleted.Rows, "bytes", deleted.Bytes, "trash_id", deleted.TrashID)
+	sendSuccessResponse(w, report)
+}
+
+// makeDeleteRangeHandler serves POST /api/admin/delete-range, deleting the
+// log records between the required start and end parameters (RFC3339), only
+// those of one dataset when dataset= is given.
+func makeDeleteRangeHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: delete range", "remote_addr", r.RemoteAddr, "dry_run", isDryRun(r))
+
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			w.Header().Set("Allow", "POST, DELETE")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		start, err := parseTimestamp(r.URL.Query().Get("start"))
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, invalidTimeMessage("start"))
+			return
+		}
+		end, err := parseTimestamp(r.URL.Query().Get("end"))
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, invalidTimeMessage("end"))
+			return
+		}
+		if !start.Before(end) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "start must be before end")
+			return
+		}
+		dataset := r.URL.Query().Get("dataset")
+		if dataset != "" && !ingest.ValidDataset(dataset) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid dataset name")
+			return
+		}
+
+		runDeletion(w, r, db, logger, "delete-range", dataset, start, end, nil)
+	}
+}
+
+// MakePruneHandler creates the POST /api/admin/prune handler, deleting log
+// records older than `days` days, or than the configured retention.raw_days
+// when the parameter is omitted. The cutoff is truncated to the hour so a
+// dry run and the following delete agree on the range. Under the archive
+// raw_policy the records are first written to retention.archive_dir with
+// export.Archive, sealed when a key is configured.
+//
+// Parameters:
+//   - key: Encryption key for archives, or nil to write them unsealed
+//   - db: Database controller to prune
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function
+func MakePruneHandler(key *encryption.Key, db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: prune", "remote_addr", r.RemoteAddr, "dry_run", isDryRun(r))
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		doc, err := config.Export(db)
+		if err != nil {
+			sendErrorResponse(w, "Failed to read retention settings")
+			return
+		}
+		days := doc.Retention.RawDays
+		if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+			d, err := strconv.Atoi(daysStr)
+			if err != nil || d <= 0 {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "days must be a positive integer")
+				return
+			}
+			days = d
+		}
+		if days == 0 {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "No retention configured; pass days")
+			return
+		}
+
+		start := time.Unix(0, 0).UTC()
+		cutoff := now().UTC().Add(-time.Duration(days) * 24 * time.Hour).Truncate(time.Hour)
+		var archive func() (string, error)
+		if doc.Retention.Archives() {
+			archive = func() (string, error) {
+				archived, err := export.Archive(db, doc.Retention.ArchiveDir, start, cutoff, key)
+				if err == nil && archived.File != "" {
+					logger.Info("Records archived", "file", archived.File, "rows", archived.Manifest.Rows)
+				}
+				return archived.File, err
+			}
+		}
+		runDeletion(w, r, db, logger, "prune", "", start, cutoff, archive)
+	}
+}
+
+// makePurgeDatasetHandler serves POST /api/admin/purge-dataset, deleting
+// every log record of the required dataset. The range ends at the next full
+// hour so a dry run and the following delete agree on it.
+func makePurgeDatasetHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: purge dataset", "remote_addr", r.RemoteAddr, "dry_run", isDryRun(r))
+
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			w.Header().Set("Allow", "POST, DELETE")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		dataset := r.URL.Query().Get("dataset")
+		if dataset == "" {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "dataset is required")
+			return
+		}
+		if !ingest.ValidDataset(dataset) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid dataset name")
+			return
+		}
+
+		end := now().UTC().Truncate(time.Hour).Add(time.Hour)
+		runDeletion(w, r, db, logger, "purge-dataset", dataset, time.Unix(0, 0).UTC(), end, nil)
+	}
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func decodeDeletionReport(t *testing.T, rr *httptest.ResponseRecorder) DeletionReport {
+	t.Helper()
+	var response struct {
+		Success bool           `json:"success"`
+		Data    DeletionReport `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	return response.Data
+}
+
+func TestAPIDeleteRangeDryRun(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/admin/delete-range"]
+
+	now := time.Now().UTC()
+	query := "start=" + now.Add(-time.Hour).Format(time.RFC3339) + "&end=" + now.Add(time.Hour).Format(time.RFC3339)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/delete-range?dry_run=true&"+query, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from dry run, got %d: %s", rr.Code, rr.Body.String())
+	}
+	report := decodeDeletionReport(t, rr)
+	if !report.DryRun || report.Rows != 5 || report.Bytes != 31744 || report.ConfirmationRequired {
+		t.Errorf("Unexpected dry run report: %+v", report)
+	}
+	if logs, _ := db.GetAll(); len(logs) != 5 {
+		t.Fatalf("Dry run must not delete, %d rows remain", len(logs))
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/delete-range?"+query, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from delete, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if report := decodeDeletionReport(t, rr); report.DryRun || report.Rows != 5 {
+		t.Errorf("Unexpected delete report: %+v", report)
+	}
+	if logs, _ := db.GetAll(); len(logs) != 0 {
+		t.Errorf("Expected all rows deleted, %d remain", len(logs))
+	}
+}
+
+func TestAPIDeleteRangeRequiresConfirmation(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	defer func(limit int64) { largeDeleteRows = limit }(largeDeleteRows)
+	largeDeleteRows = 3
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/admin/delete-range"]
+
+	now := time.Now().UTC()
+	query := "start=" + now.Add(-time.Hour).Format(time.RFC3339) + "&end=" + now.Add(time.Hour).Format(time.RFC3339)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/delete-range?"+query, nil))
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 without confirmation, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/delete-range?dry_run=true&"+query, nil))
+	report := decodeDeletionReport(t, rr)
+	if !report.ConfirmationRequired || report.ConfirmToken == "" {
+		t.Fatalf("Expected dry run to return a confirm token, got %+v", report)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/delete-range?confirm=wrong&"+query, nil))
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 with a wrong token, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/delete-range?confirm="+report.ConfirmToken+"&"+query, nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 with the confirm token, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestAPIPurgeDataset(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	defer func(limit int64) { largeDeleteRows = limit }(largeDeleteRows)
+	largeDeleteRows = 1
+
+	for _, size := range []int64{100, 200} {
+		if err := db.InsertLog(database.LogSize{Filesize: size, RecordCount: 1, Dataset: "http_requests"}); err != nil {
+			t.Fatalf("InsertLog failed: %v", err)
+		}
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	apiHandlers := MakeAPIHandlers(db, logger)
+	post := func(target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		path, _, _ := strings.Cut(target, "?")
+		apiHandlers[path].ServeHTTP(rr, httptest.NewRequest("POST", target, nil))
+		return rr
+	}
+
+	for _, target := range []string{"/api/admin/purge-dataset", "/api/admin/purge-dataset?dataset=Bad"} {
+		if rr := post(target); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", target, rr.Code)
+		}
+	}
+
+	rr := post("/api/admin/purge-dataset?dataset=http_requests&dry_run=true")
+	report := decodeDeletionReport(t, rr)
+	if rr.Code != http.StatusOK || !report.DryRun || report.Dataset != "http_requests" || report.Rows != 2 || report.Bytes != 300 || report.ConfirmToken == "" {
+		t.Fatalf("Unexpected dry run report: %d %+v", rr.Code, report)
+	}
+	if logs, _ := db.GetAll(); len(logs) != 7 {
+		t.Fatalf("Dry run must not delete, %d rows remain", len(logs))
+	}
+
+	// The token of a dry run of another operation does not confirm the purge
+	start := time.Unix(0, 0).UTC().Format(time.RFC3339)
+	end := now().UTC().Truncate(time.Hour).Add(time.Hour).Format(time.RFC3339)
+	other := decodeDeletionReport(t, post("/api/admin/delete-range?dataset=http_requests&dry_run=true&start="+start+"&end="+end))
+	if other.Rows != 2 || other.ConfirmToken == "" || other.ConfirmToken == report.ConfirmToken {
+		t.Errorf("Expected a dataset-scoped delete-range with its own token, got %+v", other)
+	}
+	if rr := post("/api/admin/purge-dataset?dataset=http_requests&confirm=" + other.ConfirmToken); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 without the purge's own token, got %d", rr.Code)
+	}
+
+	rr = post("/api/admin/purge-dataset?dataset=http_requests&confirm=" + report.ConfirmToken)
+	if report := decodeDeletionReport(t, rr); rr.Code != http.StatusOK || report.DryRun || report.Rows != 2 || report.TrashID == 0 {
+		t.Errorf("Unexpected purge report: %d %+v", rr.Code, report)
+	}
+	logs, _ := db.GetAll()
+	if len(logs) != 5 {
+		t.Errorf("Expected the other datasets' 5 rows to remain, got %d", len(logs))
+	}
+	for _, l := range logs {
+		if l.Dataset == "http_requests" {
+			t.Errorf("Expected every http_requests record purged, found %+v", l)
+		}
+	}
+}
+
+func TestAPIPrune(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/admin/prune"]
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		status int
+	}{
+		{"No retention configured", "POST", "/api/admin/prune?dry_run=true", http.StatusBadRequest},
+		{"Invalid days", "POST", "/api/admin/prune?days=-1", http.StatusBadRequest},
+		{"Explicit days", "POST", "/api/admin/prune?days=30&dry_run=true", http.StatusOK},
+		{"Unsupported method", "GET", "/api/admin/prune", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.url, nil))
+			if rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	// Fresh records are newer than the cutoff and must survive a real prune
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/prune?days=1", nil))
+	if report := decodeDeletionReport(t, rr); report.Rows != 0 {
+		t.Errorf("Expected nothing pruned, got %+v", report)
+	}
+	if logs, _ := db.GetAll(); len(logs) != 5 {
+		t.Errorf("Expected 5 rows after prune, got %d", len(logs))
+	}
+}
+
+func TestAPIPruneArchivesFirst(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	old := time.Now().UTC().AddDate(0, 0, -60)
+	for _, size := range []int64{100, 200} {
+		if err := db.InsertLog(database.LogSize{Timestamp: old, Filesize: size, RecordCount: 1}); err != nil {
+			t.Fatalf("InsertLog failed: %v", err)
+		}
+	}
+	// The archive directory cannot be created below a regular file
+	blocked := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocked, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	doc := config.NewDocument()
+	doc.Retention.RawDays = 30
+	doc.Retention.RawPolicy = config.RawPolicyArchive
+	doc.Retention.ArchiveDir = filepath.Join(blocked, "archive")
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/admin/prune"]
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/prune", nil))
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500 when archiving fails, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if logs, _ := db.GetAll(); len(logs) != 7 {
+		t.Fatalf("Expected nothing deleted without an archive, got %d rows", len(logs))
+	}
+
+	doc.Retention.ArchiveDir = t.TempDir()
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/prune", nil))
+	report := decodeDeletionReport(t, rr)
+	if report.Rows != 2 || filepath.Dir(report.Archive) != doc.Retention.ArchiveDir {
+		t.Fatalf("Expected two rows archived and pruned, got %+v", report)
+	}
+
+	f, err := os.Open(report.Archive)
+	if err != nil {
+		t.Fatalf("Archive not written: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Archive is not gzip: %v", err)
+	}
+	data, _ := io.ReadAll(zr)
+	if lines := strings.Count(string(data), "\n"); lines != 3 {
+		t.Errorf("Expected a header and two rows, got %q", data)
+	}
+	if _, err := os.Stat(strings.TrimSuffix(report.Archive, ".csv.gz") + ".manifest.json"); err != nil {
+		t.Errorf("Expected a manifest beside the archive: %v", err)
+	}
+	if logs, _ := db.GetAll(); len(logs) != 5 {
+		t.Errorf("Expected the 5 fresh rows to be kept, got %d", len(logs))
+	}
+}
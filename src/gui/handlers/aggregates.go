@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+)
+
+// The summary, time series and size breakdown are aggregated in SQL, so
+// computing them does not load the records. Ranges reaching into archives
+// are the exception: archived records are only in files, so those ranges
+// are loaded, merged and aggregated in Go.
+
+// sqlRange returns the bounds of q for the database's range aggregates,
+// with both sides open when every record is wanted.
+func (q logsQuery) sqlRange() (time.Time, time.Time) {
+	if q.all {
+		return time.Time{}, time.Time{}
+	}
+	return q.start, q.end
+}
+
+// summary computes the summary statistics of the records selected by q.
+func (a archiveReader) summary(q logsQuery) (LogSizeStats, error) {
+	if len(q.archives) > 0 {
+		logs, err := a.load(q)
+		if err != nil {
+			return LogSizeStats{}, err
+		}
+		return calculateStats(logs), nil
+	}
+	s, err := a.db.StatsByTimeRange(q.sqlRange())
+	if err != nil || s.Batches == 0 {
+		return LogSizeStats{}, err
+	}
+	return LogSizeStats{
+		TotalRecords: s.Batches,
+		TotalSize:    s.Bytes,
+		AverageSize:  s.AvgSize,
+		MinSize:      s.MinSize,
+		MaxSize:      s.MaxSize,
+		LastUpdated:  s.Last.Format(time.RFC3339),
+
+		TotalUncompressedSize: s.UncompressedBytes,
+		CompressionRatio:      compressionRatio(s.UncompressedBytes, s.Bytes),
+
+		RecordCount:        s.Records,
+		AvgRecordsPerBatch: float64(s.Records) / float64(s.Batches),
+		AvgRecordSize:      s.AvgRecordSize,
+	}, nil
+}
+
+// timeSeries sums the records selected by q into buckets of the given
+// length.
+func (a archiveReader) timeSeries(q logsQuery, bucket time.Duration) ([]TimeSeriesPoint, error) {
+	if len(q.archives) > 0 {
+		logs, err := a.load(q)
+		if err != nil {
+			return nil, err
+		}
+		return aggregateByInterval(logs, bucket), nil
+	}
+	start, end := q.sqlRange()
+	buckets, err := a.db.BucketsByTimeRange(start, end, bucket)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]TimeSeriesPoint, 0, len(buckets))
+	for _, b := range buckets {
+		points = append(points, TimeSeriesPoint{
+			Timestamp: b.Start.Format(time.RFC3339),
+			Count:     int(b.Batches),
+			Records:   b.Records,
+			TotalSize: b.Bytes,
+		})
+	}
+	return points, nil
+}
+
+// sizeBreakdown distributes the records selected by q into the file size
+// ranges of calculateSizeBreakdown.
+func (a archiveReader) sizeBreakdown(q logsQuery, units config.Units) ([]SizeBreakdown, error) {
+	if len(q.archives) > 0 {
+		logs, err := a.load(q)
+		if err != nil {
+			return nil, err
+		}
+		return calculateSizeBreakdown(logs, units), nil
+	}
+	ranges := breakdownRanges(units)
+	bounds := make([]float64, 0, len(ranges)-1)
+	for _, r := range ranges[:len(ranges)-1] {
+		bounds = append(bounds, r.Max)
+	}
+	start, end := q.sqlRange()
+	counts, err := a.db.CountBySize(start, end, bounds)
+	if err != nil {
+		return nil, err
+	}
+	rangeCounts := make([]int, len(counts))
+	for i, n := range counts {
+		rangeCounts[i] = int(n)
+	}
+	return sizeBreakdown(ranges, rangeCounts), nil
+}
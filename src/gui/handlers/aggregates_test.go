@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// TestSQLAggregatesMatchGo checks that the summary, time series and size
+// breakdown computed in SQL equal those computed from the loaded records.
+func TestSQLAggregatesMatchGo(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	base := time.Now().UTC().Add(-3 * time.Hour)
+	for i, size := range []int64{1, 999, 1000, 1024, 99_999, 10_000_000_000} {
+		l := database.LogSize{Timestamp: base.Add(time.Duration(i) * 17 * time.Minute), Filesize: size}
+		if i%2 == 0 {
+			l.UncompressedSize = size * 7
+		}
+		if err := db.InsertLog(l); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+
+	a := archiveReader{db: db}
+	for _, q := range []logsQuery{
+		{all: true},
+		{start: base.Add(time.Hour), end: time.Now().UTC().Add(time.Minute)},
+		{start: base.Add(-48 * time.Hour), end: base.Add(-24 * time.Hour)},
+	} {
+		logs, err := a.load(q)
+		if err != nil {
+			t.Fatalf("Failed to load records: %v", err)
+		}
+
+		stats, err := a.summary(q)
+		if err != nil {
+			t.Fatalf("summary failed: %v", err)
+		}
+		if want := calculateStats(logs); stats != want {
+			t.Errorf("%+v: expected summary %+v, got %+v", q, want, stats)
+		}
+
+		for _, bucket := range []time.Duration{time.Minute, time.Hour, 24 * time.Hour} {
+			series, err := a.timeSeries(q, bucket)
+			if err != nil {
+				t.Fatalf("timeSeries failed: %v", err)
+			}
+			if want := aggregateByInterval(logs, bucket); !reflect.DeepEqual(series, want) {
+				t.Errorf("%+v: expected %s buckets %+v, got %+v", q, bucket, want, series)
+			}
+		}
+
+		for _, units := range []config.Units{config.UnitsDecimal, config.UnitsBinary} {
+			breakdown, err := a.sizeBreakdown(q, units)
+			if err != nil {
+				t.Fatalf("sizeBreakdown failed: %v", err)
+			}
+			if want := calculateSizeBreakdown(logs, units); !reflect.DeepEqual(breakdown, want) {
+				t.Errorf("%+v: expected %s breakdown %+v, got %+v", q, units, want, breakdown)
+			}
+		}
+	}
+}
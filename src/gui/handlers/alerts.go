@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/events"
+)
+
+// AlertTest is the response body for POST /api/alerts/{name}/test.
+type AlertTest struct {
+	Rule       string            `json:"rule"`        // Rule name
+	Metric     string            `json:"metric"`      // Metric evaluated
+	Value      float64           `json:"value"`       // Metric value over the rule's window ending now
+	Comparison string            `json:"comparison"`  // ">" or "<"
+	Threshold  float64           `json:"threshold"`   // Value the metric is compared against
+	WindowFrom time.Time         `json:"window_from"` // Start of the evaluation window, UTC
+	WouldFire  bool              `json:"would_fire"`  // Whether the rule's condition holds now
+	Alert      events.AlertFired `json:"alert"`       // Test alert published to webhooks
+}
+
+// EvaluateAlertRule computes a rule's metric over the window ending at now.
+// Rates are averaged over the window; ingest_failures counts the failed
+// requests in it and budget_percent is the highest percentage any budget
+// has reached in its current period.
+//
+// Parameters:
+//   - db: Database controller for usage queries
+//   - doc: Configuration holding the budgets
+//   - a: Rule to evaluate
+//   - now: End of the evaluation window
+//
+// Returns:
+//   - float64: Metric value
+//   - bool: Whether the rule's condition holds
+//   - error: Any error encountered while querying
+func EvaluateAlertRule(db *database.SQLiteController, doc config.Document, a config.AlertRule, now time.Time) (float64, bool, error) {
+	window := time.Duration(a.WindowMinutes) * time.Minute
+	from := now.Add(-window)
+
+	var value float64
+	switch a.Metric {
+	case "bytes_per_hour", "records_per_hour":
+		usage, err := db.QueryTenantUsage(from, now)
+		if err != nil {
+			return 0, false, err
+		}
+		for _, u := range usage {
+			if a.Metric == "bytes_per_hour" {
+				value += float64(u.TotalSize)
+			} else {
+				value += float64(u.RecordCount)
+			}
+		}
+		value /= window.Hours()
+	case "ingest_failures":
+		outcomes, err := db.IngestOutcomesSince(from)
+		if err != nil {
+			return 0, false, err
+		}
+		value = float64(outcomes.Failures)
+	case "budget_percent":
+		for _, b := range doc.Budgets {
+			status, err := EvaluateBudgetNow(db, doc, b, now)
+			if err != nil {
+				return 0, false, err
+			}
+			value = max(value, status.Percent)
+		}
+	default:
+		return 0, false, fmt.Errorf("unsupported metric %q", a.Metric)
+	}
+	value = math.Round(value*100) / 100
+
+	if a.Comparison == "<" {
+		return value, value < a.Threshold, nil
+	}
+	return value, value > a.Threshold, nil
+}
+
+// makeAlertsHandler serves /api/alerts. GET lists alert rules with their
+// updated_at and POST creates one from the JSON body; with dry_run=true the
+// rule is only validated.
+func makeAlertsHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: alerts", "method", r.Method, "remote_addr", r.RemoteAddr)
+
+		switch r.Method {
+		case http.MethodGet:
+			rules, err := config.ListAlertRules(db)
+			if err != nil {
+				sendErrorResponse(w, "Failed to fetch alert rules")
+				return
+			}
+			sendSuccessResponse(w, rules)
+
+		case http.MethodPost:
+			var a config.StoredAlertRule
+			if err := decodeStoredObject(r, &a); err != nil {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid alert rule: "+err.Error())
+				return
+			}
+			rules, err := config.ListAlertRules(db)
+			if err != nil {
+				sendErrorResponse(w, "Failed to fetch alert rules")
+				return
+			}
+			for _, existing := range rules {
+				if existing.Name == a.Name {
+					sendErrorResponseWithStatus(w, http.StatusConflict, "Alert rule already exists; use PUT /api/alerts/"+a.Name)
+					return
+				}
+			}
+			saveAlertRule(w, r, db, logger, a.AlertRule, time.Time{})
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// saveAlertRule validates and, unless dry_run is set, stores an alert rule.
+func saveAlertRule(w http.ResponseWriter, r *http.Request, db *database.SQLiteController, logger *slog.Logger, a config.AlertRule, since time.Time) {
+	if isDryRun(r) {
+		if err := config.CheckAlertRule(db, a); err != nil {
+			sendConfigEditError(w, logger, err, "Alert rule")
+			return
+		}
+		sendSuccessResponse(w, map[string]any{"dry_run": true, "valid": true, "alert_rule": a})
+		return
+	}
+	saved, err := config.SaveAlertRule(db, a, since)
+	if err != nil {
+		sendConfigEditError(w, logger, err, "Alert rule")
+		return
+	}
+	sendSuccessResponse(w, saved)
+}
+
+// MakeAlertHandler creates the /api/alerts/{name} handler: GET returns the
+// rule, PUT replaces it with the JSON body and DELETE removes it, with the
+// same updated_at preconditions as budgets. POST /api/alerts/{name}/test
+// evaluates the rule now, whether or not it is enabled, and publishes a
+// test alert.fired event on bus.
+//
+// Parameters:
+//   - bus: Bus test alerts are published on; nil discards them
+//   - db: Database controller for the configuration and metrics
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Handler for /api/alerts/
+func MakeAlertHandler(bus *events.Bus, db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/api/alerts/")
+		name, test := strings.CutSuffix(name, "/test")
+		logger.Info("API request: alert rule", "method", r.Method, "name", name, "test", test, "remote_addr", r.RemoteAddr)
+
+		rules, err := config.ListAlertRules(db)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch alert rules")
+			return
+		}
+		var current *config.StoredAlertRule
+		for i := range rules {
+			if rules[i].Name == name {
+				current = &rules[i]
+			}
+		}
+
+		if test {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", "POST")
+				sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			if current == nil {
+				sendErrorResponseWithStatus(w, http.StatusNotFound, "Alert rule not found")
+				return
+			}
+			doc, err := config.Export(db)
+			if err != nil {
+				sendErrorResponse(w, "Failed to load budgets")
+				return
+			}
+			a, at := current.AlertRule, now()
+			value, fires, err := EvaluateAlertRule(db, doc, a, at)
+			if err != nil {
+				sendErrorResponse(w, "Failed to evaluate alert rule")
+				return
+			}
+			alert := events.AlertFired{
+				Alert:   "alert_rule_test",
+				Subject: a.Name,
+				Message: fmt.Sprintf("Test: alert rule %q: %s is %g over the last %d minutes (fires when %s %g)", a.Name, a.Metric, value, a.WindowMinutes, a.Comparison, a.Threshold),
+				Path:    "/api/alerts/" + a.Name,
+			}
+			bus.Publish(events.TopicAlertFired, alert)
+			sendSuccessResponse(w, AlertTest{
+				Rule:       a.Name,
+				Metric:     a.Metric,
+				Value:      value,
+				Comparison: a.Comparison,
+				Threshold:  a.Threshold,
+				WindowFrom: at.Add(-time.Duration(a.WindowMinutes) * time.Minute).UTC(),
+				WouldFire:  fires,
+				Alert:      alert,
+			})
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			if current == nil {
+				sendErrorResponseWithStatus(w, http.StatusNotFound, "Alert rule not found")
+				return
+			}
+			sendSuccessResponse(w, current)
+
+		case http.MethodPut:
+			var a config.StoredAlertRule
+			if err := decodeStoredObject(r, &a); err != nil {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid alert rule: "+err.Error())
+				return
+			}
+			a.Name = name
+			saveAlertRule(w, r, db, logger, a.AlertRule, a.UpdatedAt)
+
+		case http.MethodDelete:
+			since, err := unmodifiedSince(r)
+			if err != nil {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			deleted, err := config.DeleteAlertRule(db, name, since)
+			if err != nil {
+				sendConfigEditError(w, logger, err, "Alert rule")
+				return
+			}
+			if !deleted {
+				sendErrorResponseWithStatus(w, http.StatusNotFound, "Alert rule not found")
+				return
+			}
+			sendSuccessResponse(w, map[string]string{"deleted": name})
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/events"
+	"github.com/melatonein5/LogpushEstimator/src/testsupport"
+)
+
+func TestAPIAlertRules(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	apiHandlers := MakeAPIHandlers(db, logger)
+	list, single := apiHandlers["/api/alerts"], apiHandlers["/api/alerts/"]
+
+	serve := func(h http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rr
+	}
+	rule := `{"name":"spike","metric":"bytes_per_hour","comparison":">","threshold":1000,"window_minutes":60,"enabled":true}`
+
+	if rr := serve(list, "POST", "/api/alerts?dry_run=true", rule); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from dry run, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rules, _ := config.ListAlertRules(db); len(rules) != 0 {
+		t.Fatalf("Expected dry run not to store the rule, got %+v", rules)
+	}
+
+	rr := serve(list, "POST", "/api/alerts", rule)
+	var created struct {
+		Data config.StoredAlertRule `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from POST, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(list, "POST", "/api/alerts", rule); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for an existing rule, got %d", rr.Code)
+	}
+	invalid := map[string]string{
+		"unknown metric": `{"name":"x","metric":"latency","comparison":">","window_minutes":5}`,
+		"unknown field":  `{"name":"x","metric":"bytes_per_hour","comparison":">","window_minutes":5,"severity":"high"}`,
+	}
+	for name, body := range invalid {
+		if rr := serve(list, "POST", "/api/alerts", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rr.Code)
+		}
+	}
+
+	// The first PUT with the read updated_at wins; a second one is refused
+	stamp, _ := json.Marshal(created.Data.UpdatedAt)
+	update := `{"metric":"bytes_per_hour","comparison":">","threshold":2000,"window_minutes":60,"enabled":true,"updated_at":` + string(stamp) + `}`
+	time.Sleep(10 * time.Millisecond)
+	if rr := serve(single, "PUT", "/api/alerts/spike", update); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from PUT, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(single, "PUT", "/api/alerts/spike", update); rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale updated_at, got %d", rr.Code)
+	}
+	stale := url.QueryEscape(created.Data.UpdatedAt.Format(time.RFC3339Nano))
+	if rr := serve(single, "DELETE", "/api/alerts/spike?updated_at="+stale, ""); rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale delete, got %d", rr.Code)
+	}
+
+	rr = serve(single, "GET", "/api/alerts/spike", "")
+	var fetched struct {
+		Data config.StoredAlertRule `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &fetched); err != nil || fetched.Data.Threshold != 2000 {
+		t.Fatalf("Expected the updated rule, got %s", rr.Body.String())
+	}
+
+	current := url.QueryEscape(fetched.Data.UpdatedAt.Format(time.RFC3339Nano))
+	if rr := serve(single, "DELETE", "/api/alerts/spike?updated_at="+current, ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 from DELETE, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(single, "GET", "/api/alerts/spike", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", rr.Code)
+	}
+}
+
+func TestAlertRuleTestFire(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	at := time.Date(2025, 9, 20, 12, 0, 0, 0, time.UTC)
+	SetClock(testsupport.NewClock(at))
+	defer SetClock(nil)
+	for _, size := range []int64{3000, 5000} {
+		if err := db.InsertLog(database.LogSize{Timestamp: at.Add(-30 * time.Minute), Filesize: size, RecordCount: 10}); err != nil {
+			t.Fatalf("InsertLog failed: %v", err)
+		}
+	}
+	doc := config.NewDocument()
+	doc.PricingModels = []config.PricingModel{{Name: "r2", Currency: "USD", PerGB: 1e6, Default: true}}
+	doc.Budgets = []config.Budget{{Name: "monthly", Period: "monthly", LimitCost: 10}}
+	doc.AlertRules = []config.AlertRule{{Name: "spike", Metric: "bytes_per_hour", Comparison: ">", Threshold: 2000, WindowMinutes: 120}}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	bus := events.NewBus(nil, logger)
+	var fired []events.AlertFired
+	bus.Subscribe(func(e events.Event) { fired = append(fired, e.Data.(events.AlertFired)) }, events.TopicAlertFired)
+
+	rr := httptest.NewRecorder()
+	MakeAlertHandler(bus, db, logger).ServeHTTP(rr, httptest.NewRequest("POST", "/api/alerts/spike/test", nil))
+	var alertResp struct {
+		Data AlertTest `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &alertResp); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from test-fire, got %d: %s", rr.Code, rr.Body.String())
+	}
+	// 8000 bytes over a two-hour window
+	if got := alertResp.Data; got.Value != 4000 || !got.WouldFire || got.Alert.Alert != "alert_rule_test" {
+		t.Errorf("Unexpected test result %+v", got)
+	}
+
+	rr = httptest.NewRecorder()
+	MakeBudgetHandler(bus, db, logger).ServeHTTP(rr, httptest.NewRequest("POST", "/api/budgets/monthly/test", nil))
+	var budgetResp struct {
+		Data BudgetTest `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &budgetResp); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from budget test-fire, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := budgetResp.Data.Status; got.Bytes != 8000 || got.Cost != 8 || got.Exceeded {
+		t.Errorf("Unexpected budget status %+v", got)
+	}
+
+	if len(fired) != 2 || fired[0].Subject != "spike" || fired[1].Alert != "budget_test" || fired[1].Path != "/api/reports/chargeback?month=2025-09" {
+		t.Errorf("Expected a test alert for each call, got %+v", fired)
+	}
+
+	rr = httptest.NewRecorder()
+	MakeAlertHandler(bus, db, logger).ServeHTTP(rr, httptest.NewRequest("POST", "/api/alerts/missing/test", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown rule, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// makeAnnotationsHandler serves /api/annotations: the periods marked on the
+// time series charts, such as Cloudflare status page incidents affecting
+// Logpush, that overlap the range selected by last, start/end or hours.
+// Without a range every annotation is returned.
+func makeAnnotationsHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: annotations", "remote_addr", r.RemoteAddr)
+
+		start, end, all, err := requestRange(r)
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+			return
+		}
+		if all {
+			start, end = time.Unix(0, 0).UTC(), now().UTC().Add(time.Hour)
+		}
+
+		annotations, err := db.QueryAnnotations(start, end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch annotations")
+			return
+		}
+		sendSuccessResponse(w, annotations)
+	}
+}
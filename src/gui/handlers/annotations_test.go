@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestAnnotationsHandler(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	started := time.Now().UTC().Add(-3 * time.Hour).Truncate(time.Second)
+	err := db.UpsertAnnotations([]database.Annotation{
+		{Source: database.AnnotationCloudflareStatus, ExternalID: "recent", Title: "Logpush delays", StartedAt: started},
+		{Source: database.AnnotationCloudflareStatus, ExternalID: "old", Title: "Logpush errors", StartedAt: started.AddDate(0, 0, -10)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to store annotations: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/annotations"]
+
+	get := func(query string) (int, []database.Annotation) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/annotations"+query, nil))
+		var annotations []database.Annotation
+		json.NewDecoder(rr.Body).Decode(&APIResponse{Data: &annotations})
+		return rr.Code, annotations
+	}
+
+	// The old incident is still ongoing, so it overlaps the last day too
+	if code, got := get("?last=24h"); code != http.StatusOK || len(got) != 2 || got[1].ExternalID != "recent" {
+		t.Errorf("Expected both ongoing incidents in the last day, got %d %+v", code, got)
+	}
+	if code, got := get("?start=" + started.AddDate(0, 0, -20).Format(time.RFC3339) + "&end=" + started.AddDate(0, 0, -5).Format(time.RFC3339)); code != http.StatusOK || len(got) != 1 || got[0].ExternalID != "old" {
+		t.Errorf("Expected only the old incident, got %d %+v", code, got)
+	}
+	if code, _ := get("?last=soon"); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a malformed range, got %d", code)
+	}
+}
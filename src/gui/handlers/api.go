@@ -9,10 +9,62 @@
 // The package provides the following API endpoints:
 //
 //   - /api/stats/summary: Summary statistics (total records, sizes, averages)
-//   - /api/logs/recent: Recent log entries (configurable limit)
-//   - /api/logs/time-range: Time-filtered log data with query parameters
-//   - /api/charts/time-series: Hourly aggregated data for time-series charts
+//   - /api/logs/recent: Recent log entries, paged with limit and offset
+//   - /api/logs/time-range: Time-filtered log data, paged with limit and offset
+//   - /api/logs/since: Records after an ID cursor, for incremental consumers
+//   - /api/logs/count: Counts and byte sums in a range, computed in SQL
+//   - /api/charts/time-series: Aggregated data for time-series charts, with
+//     the bucket size adapted to the range
 //   - /api/charts/size-breakdown: Size distribution data for charts
+//   - /api/annotations: Periods marked on the charts, such as Cloudflare incidents
+//   - /api/stats/records: Per-record size statistics across batches
+//   - /api/charts/record-sizes: Hourly record counts and average record size
+//   - /api/charts/record-size-breakdown: Batch distribution by average record size
+//   - /api/charts/minutes: Per-minute series over the rolling 48h window
+//   - /api/stats/bursts: Minutes whose volume exceeds a multiple of the baseline
+//   - /api/slo/ingest: Ingest availability and error budget over 7 and 30 days
+//   - /api/stats/forwarding: Relay latency and status codes per upstream destination
+//   - /api/stats/diff: Totals, dataset and size distribution changes between two windows
+//   - /api/stats/change-points: Days on which the daily volume shifted to a new level
+//   - /api/stats/dimensions: Volume per dimension value from the dataset parsers
+//   - /api/charts/by-dimension: Hourly or daily volume per value of one dimension
+//   - /api/dimensions: Dimensions registered with the dataset parsers
+//   - /api/dimensions/query: Dimension totals filtered and grouped by dataset, value and time
+//   - /api/samples: Redacted samples of ingested payloads
+//   - /api/samples/redactions: Audit of what redaction rules removed
+//   - /api/preferences: Per-browser dashboard preferences (GET, PUT)
+//   - /api/views: Saved query definitions (GET, POST, and GET/PUT/DELETE by name)
+//   - /api/scenarios: Saved estimation scenarios (GET, POST, and GET/PUT/DELETE by name)
+//   - /api/scenarios/compare: Monthly volume and cost of scenarios side by side
+//   - /api/recommendations: Ranked cost reduction suggestions with estimated savings
+//   - /api/estimate/cost: Projected monthly storage and egress cost of the tracked volume
+//   - /api/estimate/forecast: Bytes and log lines projected to the end of the month
+//   - /api/dashboard/layout: Widgets the dashboard renders and their data endpoints
+//   - /api/admin/config: Declarative configuration (GET, PUT with change set)
+//   - /api/admin/config/export, /api/admin/config/import: Configuration as code
+//   - /api/alerts, /api/budgets: Alert rules and budgets (GET, POST, GET/PUT/DELETE
+//     by name, and POST {name}/test to fire a test alert)
+//   - /api/admin/tenants/bulk: Tenants, ingest tokens and budgets from a manifest
+//   - /api/admin/tokens/{name}/rotate: Token rotation with overlapping validity
+//   - /api/estimates/coverage: Ingested records versus Cloudflare zone analytics
+//   - /api/estimates/bandwidth: Destination throughput needed for observed bursts
+//   - /api/cloudflare/jobs, /api/cloudflare/jobs/{id}/health: Logpush job health
+//   - /api/export: Raw records streamed as CSV or NDJSON
+//   - /api/export/csv, /api/export/manifest, /api/export/verify: Audited exports
+//   - /api/exports, /api/exports/{id}: Exports built in the background (POST, then
+//     GET status, GET {id}/download, DELETE)
+//   - /api/admin/delete-range, /api/admin/prune, /api/admin/purge-dataset: Deletes with dry_run support
+//   - /api/admin/retention: Retention policy applied by the pruning janitor
+//   - /api/admin/trash, /api/admin/trash/restore: Undo deletes within trash_days
+//   - /api/admin/integrity: Rollup consistency issues and on-demand checks
+//   - /api/admin/shards: Monthly shard files and whether their records are read
+//   - /api/admin/recovery: What the startup scan found after an unclean shutdown
+//   - /api/admin/outbox, /api/admin/outbox/retry: Webhook deliveries and dead letters
+//   - /api/tenants: Tenants with stored records (dashboards at /t/{tenant}/)
+//   - /api/datasets: Logpush datasets with stored records (filter with ?dataset=)
+//   - /api/zones: Cloudflare zones with stored records (filter with ?zone=)
+//   - /api/labels: Labels sent with stored records (filter with ?label=key:value)
+//   - /api/reports/chargeback: Monthly cost allocated across tenants (JSON or CSV)
 //
 // # Response Format
 //
@@ -41,31 +93,46 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/melatonein5/LogpushEstimator/src/config"
 	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
 )
 
 // APIResponse wraps all API responses in a consistent format.
 // This structure ensures uniform response handling across all API endpoints.
 type APIResponse struct {
-	Success bool        `json:"success"`         // Indicates if the request was successful
-	Data    interface{} `json:"data,omitempty"`  // Response data (present on success)
-	Error   string      `json:"error,omitempty"` // Error message (present on failure)
+	Success        bool          `json:"success"`                   // Indicates if the request was successful
+	Data           interface{}   `json:"data,omitempty"`            // Response data (present on success)
+	Error          string        `json:"error,omitempty"`           // Error message (present on failure)
+	AllowedMethods []string      `json:"allowed_methods,omitempty"` // Methods the route accepts (present on 405)
+	Meta           *ResponseMeta `json:"meta,omitempty"`            // Where the data came from, when not only the database
 }
 
 // LogSizeStats represents summary statistics for log size data.
 // This structure provides comprehensive metrics about stored log records.
 type LogSizeStats struct {
 	TotalRecords int64   `json:"total_records"` // Total number of log records
-	TotalSize    int64   `json:"total_size"`    // Sum of all log sizes in bytes
+	TotalSize    int64   `json:"total_size"`    // Sum of all log sizes in bytes, as received
 	AverageSize  float64 `json:"average_size"`  // Average log size in bytes
 	MinSize      int64   `json:"min_size"`      // Smallest log size in bytes
 	MaxSize      int64   `json:"max_size"`      // Largest log size in bytes
 	LastUpdated  string  `json:"last_updated"`  // ISO timestamp of most recent record
+
+	TotalUncompressedSize int64   `json:"total_uncompressed_size"` // Sum of log sizes after decompression in bytes
+	CompressionRatio      float64 `json:"compression_ratio"`       // TotalUncompressedSize / TotalSize; 1 when nothing was compressed
+
+	// Logpush bills and throttles on records (log lines) as well as bytes.
+	// TotalRecords above counts batches.
+	RecordCount        int64   `json:"record_count"`          // Sum of records across the batches
+	AvgRecordsPerBatch float64 `json:"avg_records_per_batch"` // RecordCount / TotalRecords
+	AvgRecordSize      float64 `json:"avg_record_size"`       // Mean record size in bytes, weighted by record count
 }
 
 // TimeSeriesPoint represents a single data point for time-series charts.
@@ -73,6 +140,7 @@ type LogSizeStats struct {
 type TimeSeriesPoint struct {
 	Timestamp string `json:"timestamp"`  // ISO timestamp for the data point
 	Count     int    `json:"count"`      // Number of log records in this time period
+	Records   int64  `json:"records"`    // Sum of records (log lines) in this time period
 	TotalSize int64  `json:"total_size"` // Sum of log sizes in this time period
 }
 
@@ -94,16 +162,132 @@ type SizeBreakdown struct {
 //   - logger: Structured logger for request logging
 //
 // Returns:
-//   - map[string]http.HandlerFunc: Map of API paths to handler functions
+//   - APIHandlers: Map of API paths to handler functions; register them
+//     with RegisterRoutes
 //
 // The returned map contains handlers for:
 //   - /api/stats/summary: Statistical summary of all log data
-//   - /api/logs/recent: Recent log entries (with optional limit parameter)
-//   - /api/logs/time-range: Time-filtered log data (requires start/end parameters)
-//   - /api/charts/time-series: Hourly aggregated data for charts
+//   - /api/logs/recent: Recent log entries (optional limit and offset paging)
+//   - /api/logs/time-range: Time-filtered log data (requires start/end; optional limit and offset)
+//   - /api/logs/since: Records with an ID greater than the id cursor
+//   - /api/logs/count: Batch, record and byte totals in a range, without rows
+//   - /api/charts/time-series: Minute, hour or day buckets sized to the range
 //   - /api/charts/size-breakdown: Size distribution analysis
-func MakeAPIHandlers(db *database.SQLiteController, logger *slog.Logger) map[string]http.HandlerFunc {
-	handlers := make(map[string]http.HandlerFunc)
+//   - /api/annotations: Chart annotations overlapping a range
+//   - /api/stats/records: Per-record size statistics
+//   - /api/charts/record-sizes: Hourly record count and record size series
+//   - /api/charts/record-size-breakdown: Average record size distribution
+//   - /api/charts/minutes: Per-minute series for high-resolution charts
+//   - /api/stats/bursts: Burst detection over per-minute aggregates
+//   - /api/stats/diff: Before/after comparison of two time windows
+//   - /api/stats/change-points: Level shifts in daily volume (PELT)
+//   - /api/slo/ingest: Ingest availability SLO report
+//   - /api/stats/dimensions: Records and bytes per parsed dimension value
+//   - /api/charts/by-dimension: Series of records and bytes per dimension value
+//   - /api/dimensions: Registered dimensions and the fields they read
+//   - /api/dimensions/query: Grouped and filtered dimension totals
+//   - /api/samples: Most recent redacted payload samples
+//   - /api/samples/redactions: Per-rule redaction totals
+//   - /api/preferences: Dashboard preferences for the caller's browser token
+//   - /api/views, /api/views/{name}: Saved views rendered at /views/{name}
+//   - /api/scenarios, /api/scenarios/{name}: Saved estimation scenarios
+//   - /api/scenarios/compare: Cost and volume matrix of saved scenarios
+//   - /api/recommendations: Fields to drop, datasets to sample and values to filter
+//   - /api/estimate/cost: Monthly cost under a pricing model or the r2, s3 and gcs presets
+//   - /api/estimate/forecast: Month-end volume from a trend fitted to recent days
+//   - /api/dashboard/layout: Server-driven widget layout for the dashboard
+//   - /api/admin/config: Read or idempotently apply the configuration document
+//   - /api/admin/config/export: Download the configuration document
+//   - /api/admin/config/import: Replace the configuration from a document
+//   - /api/alerts, /api/alerts/{name}: Alert rules with updated_at preconditions
+//   - /api/alerts/{name}/test: Evaluate a rule now and publish a test alert
+//   - /api/budgets, /api/budgets/{name}: Budgets with updated_at preconditions
+//   - /api/budgets/{name}/test: A budget's current spend and a test alert
+//   - /api/admin/tenants/bulk: Provision tenants from a JSON or CSV manifest
+//   - /api/admin/tokens/{name}/rotate: New token secret, keeping the old one for a grace period
+//   - /api/estimates/coverage: Hourly observed records versus zone requests
+//   - /api/estimates/bandwidth: Bytes, events and requests per second a destination must accept
+//   - /api/cloudflare/jobs: Tracked Logpush jobs with their last synced status
+//   - /api/cloudflare/jobs/{id}/health: Status of a single tracked job
+//   - /api/export: Every column of the raw records in a time range, streamed as CSV or NDJSON
+//   - /api/export/csv: Raw records in a time range as CSV
+//   - /api/export/manifest: Row count, byte counts and SHA-256 of an export
+//   - /api/export/verify: Check a previous export's manifest against the data
+//   - /api/exports: Start building an export in the background
+//   - /api/exports/{id}, /api/exports/{id}/download: Export job status and finished file
+//   - /api/admin/delete-range: Delete records in a time range (dry_run, confirm)
+//   - /api/admin/prune: Delete records older than the retention (dry_run, confirm)
+//   - /api/admin/purge-dataset: Delete every record of one dataset (dry_run, confirm)
+//   - /api/admin/retention: View (GET) or change (PUT) the retention policy
+//   - /api/admin/trash: Deleted record batches that can still be restored
+//   - /api/admin/trash/restore: Restore a deleted batch by id
+//   - /api/admin/integrity: List recorded integrity issues (GET) or run checks (POST)
+//   - /api/admin/outbox: Queued, delivered and dead-lettered webhook notifications
+//   - /api/admin/outbox/retry: Resend an undelivered notification by id
+//   - /api/tenants: Record counts and bytes per tenant
+//   - /api/datasets: Record counts and bytes per Logpush dataset
+//   - /api/zones: Record counts and bytes per Cloudflare zone
+//   - /api/labels: Record counts and bytes per ingest label
+//   - /api/reports/chargeback: A month's priced usage split across tenants
+//   - /api/: JSON 404 for unknown API routes
+//
+// Endpoints that can scan the full history (see expensiveEndpoints) share a
+// ConcurrencyLimiter and answer 429 Too Many Requests once its queue is full.
+func MakeAPIHandlers(db *database.SQLiteController, logger *slog.Logger) APIHandlers {
+	return MakeAPIHandlersWithCache(db, logger, nil)
+}
+
+// MakeAPIHandlersWithCache creates the handlers returned by MakeAPIHandlers,
+// serving the dashboard's summary, time series and breakdown requests from
+// cache. Deleting or restoring records invalidates the cache.
+//
+// Parameters:
+//   - db: Database controller for data access
+//   - logger: Structured logger for request logging
+//   - cache: Statistics cache shared with StatsCache.Warm, or nil to cache nothing
+//
+// Returns:
+//   - APIHandlers: Map of API paths to handler functions
+func MakeAPIHandlersWithCache(db *database.SQLiteController, logger *slog.Logger, cache *StatsCache) APIHandlers {
+	return MakeAPIHandlersWithArchives(db, logger, cache, nil)
+}
+
+// MakeAPIHandlersWithArchives creates the handlers returned by
+// MakeAPIHandlersWithCache. Under the archive raw_policy, the range,
+// summary, time series and breakdown endpoints also read the archives of
+// pruned periods, so old months stay visible on the dashboard; responses
+// that did so carry meta.archived. Sealed archives need key.
+//
+// Parameters:
+//   - db: Database controller for data access
+//   - logger: Structured logger for request logging
+//   - cache: Statistics cache shared with StatsCache.Warm, or nil to cache nothing
+//   - key: Encryption key for sealed archives, or nil
+//
+// Returns:
+//   - APIHandlers: Map of API paths to handler functions
+func MakeAPIHandlersWithArchives(db *database.SQLiteController, logger *slog.Logger, cache *StatsCache, key *encryption.Key) APIHandlers {
+	return MakeAPIHandlersWithLimiter(db, logger, cache, key, NewExpensiveLimiter())
+}
+
+// MakeAPIHandlersWithLimiter creates the handlers returned by
+// MakeAPIHandlersWithArchives, running the expensive endpoints under
+// limiter. The handler sets built for ?dataset=, ?zone= and ?label= share
+// it, so a process passing one limiter to every set it builds caps its
+// full-history requests as a whole.
+//
+// Parameters:
+//   - db: Database controller for data access
+//   - logger: Structured logger for request logging
+//   - cache: Statistics cache shared with StatsCache.Warm, or nil to cache nothing
+//   - key: Encryption key for sealed archives, or nil
+//   - limiter: Limiter shared by the expensive endpoints (see NewExpensiveLimiter)
+//
+// Returns:
+//   - APIHandlers: Map of API paths to handler functions
+func MakeAPIHandlersWithLimiter(db *database.SQLiteController, logger *slog.Logger, cache *StatsCache, key *encryption.Key, limiter *ConcurrencyLimiter) APIHandlers {
+	handlers := make(APIHandlers)
+	archives := archiveReader{db: db, key: key}
 
 	// Recent logs endpoint with optional time range filtering
 	handlers["/api/logs/recent"] = func(w http.ResponseWriter, r *http.Request) {
@@ -116,17 +300,26 @@ func MakeAPIHandlers(db *database.SQLiteController, logger *slog.Logger) map[str
 
 		var start, end time.Time
 
-		if startStr != "" && endStr != "" {
+		last, err := lastParam(r.URL.Query().Get)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if last > 0 {
+			// Use relative range
+			end = now().UTC()
+			start = end.Add(-last)
+		} else if startStr != "" && endStr != "" {
 			// Use custom time range
-			var err error
-			start, err = time.Parse(time.RFC3339, startStr)
+			start, err = parseTimestamp(startStr)
 			if err != nil {
-				sendErrorResponse(w, "Invalid start time format (use RFC3339)")
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, invalidTimeMessage("start"))
 				return
 			}
-			end, err = time.Parse(time.RFC3339, endStr)
+			end, err = parseTimestamp(endStr)
 			if err != nil {
-				sendErrorResponse(w, "Invalid end time format (use RFC3339)")
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, invalidTimeMessage("end"))
 				return
 			}
 		} else if hoursStr != "" {
@@ -135,14 +328,36 @@ func MakeAPIHandlers(db *database.SQLiteController, logger *slog.Logger) map[str
 			if h, err := strconv.Atoi(hoursStr); err == nil && h > 0 {
 				hours = h
 			}
-			end = time.Now()
+			end = now().UTC()
 			start = end.Add(-time.Duration(hours) * time.Hour)
 		} else {
 			// Default to last 24 hours
-			end = time.Now()
+			end = now().UTC()
 			start = end.Add(-24 * time.Hour)
 		}
 
+		maxPoints, err := parseMaxPoints(r)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		page, err := parsePage(r)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if page != nil {
+			logs, meta, err := page.query(db, logsQuery{start: start, end: end})
+			if err != nil {
+				logger.Error("Failed to query page of recent logs", "error", err)
+				sendErrorResponse(w, "Failed to fetch recent logs")
+				return
+			}
+			sendSuccessResponseWithMeta(w, decimateLogs(logs, maxPoints), withPage(nil, meta))
+			return
+		}
+
 		logs, err := db.QueryByTimeRange(start, end)
 		if err != nil {
 			logger.Error("Failed to query recent logs", "error", err)
@@ -150,178 +365,427 @@ func MakeAPIHandlers(db *database.SQLiteController, logger *slog.Logger) map[str
 			return
 		}
 
-		sendSuccessResponse(w, logs)
+		sendSuccessResponse(w, decimateLogs(logs, maxPoints))
 	}
 
 	// Time range query endpoint
 	handlers["/api/logs/range"] = func(w http.ResponseWriter, r *http.Request) {
 		logger.Info("API request: time range query", "remote_addr", r.RemoteAddr)
 
-		startStr := r.URL.Query().Get("start")
-		endStr := r.URL.Query().Get("end")
+		start, end, err := parseRangeParams(r)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
-		if startStr == "" || endStr == "" {
-			sendErrorResponse(w, "start and end parameters required")
+		maxPoints, err := parseMaxPoints(r)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		start, err := time.Parse(time.RFC3339, startStr)
+		page, err := parsePage(r)
 		if err != nil {
-			sendErrorResponse(w, "Invalid start time format (use RFC3339)")
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		end, err := time.Parse(time.RFC3339, endStr)
+		q, err := archives.queryRange(start, end, false)
 		if err != nil {
-			sendErrorResponse(w, "Invalid end time format (use RFC3339)")
+			logger.Error("Failed to find archives for range", "error", err, "start", start, "end", end)
+			sendErrorResponse(w, "Failed to read archived logs")
 			return
 		}
 
-		logs, err := db.QueryByTimeRange(start, end)
+		// Without archives a page is read by itself; archived records are
+		// merged in memory, so those ranges are loaded in full and sliced
+		if page != nil && len(q.archives) == 0 {
+			logs, meta, err := page.query(db, q)
+			if err != nil {
+				logger.Error("Failed to query page of logs by range", "error", err, "start", start, "end", end)
+				sendErrorResponse(w, "Failed to fetch logs")
+				return
+			}
+			sendSuccessResponseWithMeta(w, decimateLogs(logs, maxPoints), withPage(nil, meta))
+			return
+		}
+		logs, err := archives.load(q)
 		if err != nil {
 			logger.Error("Failed to query logs by range", "error", err, "start", start, "end", end)
 			sendErrorResponse(w, "Failed to fetch logs")
 			return
 		}
 
-		sendSuccessResponse(w, logs)
+		meta := archiveMeta(q.archives)
+		if page != nil {
+			var pageMeta *PageMeta
+			logs, pageMeta = page.slice(logs)
+			meta = withPage(meta, pageMeta)
+		}
+		sendSuccessResponseWithMeta(w, decimateLogs(logs, maxPoints), meta)
 	}
 
 	// Summary statistics endpoint with optional time range filtering
 	handlers["/api/stats/summary"] = func(w http.ResponseWriter, r *http.Request) {
 		logger.Info("API request: summary stats", "remote_addr", r.RemoteAddr)
 
-		// Check for optional time range parameters
-		startStr := r.URL.Query().Get("start")
-		endStr := r.URL.Query().Get("end")
-		hoursStr := r.URL.Query().Get("hours")
-
-		var logs []database.LogSize
-		var err error
-
-		if startStr != "" && endStr != "" {
-			// Use custom time range
-			start, err := time.Parse(time.RFC3339, startStr)
-			if err != nil {
-				sendErrorResponse(w, "Invalid start time format (use RFC3339)")
-				return
-			}
-			end, err := time.Parse(time.RFC3339, endStr)
+		q, err := archives.queryForRequest(r)
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+			return
+		}
+		stats, err := cache.get(r, func() (any, error) {
 			if err != nil {
-				sendErrorResponse(w, "Invalid end time format (use RFC3339)")
-				return
-			}
-			logs, err = db.QueryByTimeRange(start, end)
-		} else if hoursStr != "" {
-			// Use hours parameter
-			hours := 0 // 0 means all data
-			if h, err := strconv.Atoi(hoursStr); err == nil && h > 0 {
-				hours = h
-			}
-			if hours > 0 {
-				end := time.Now()
-				start := end.Add(-time.Duration(hours) * time.Hour)
-				logs, err = db.QueryByTimeRange(start, end)
-			} else {
-				logs, err = db.GetAll()
+				return nil, err
 			}
-		} else {
-			// Default to all data
-			logs, err = db.GetAll()
-		}
-
+			return archives.summary(q)
+		})
 		if err != nil {
 			logger.Error("Failed to get logs for stats", "error", err)
 			sendErrorResponse(w, "Failed to fetch statistics")
 			return
 		}
 
-		stats := calculateStats(logs)
-		sendSuccessResponse(w, stats)
+		sendSuccessResponseWithMeta(w, stats, archiveMeta(q.archives))
 	}
 
-	// Time series data for charts (hourly aggregation)
+	// Time series data for charts, bucketed by minute, hour or day to suit
+	// the requested range
 	handlers["/api/charts/timeseries"] = func(w http.ResponseWriter, r *http.Request) {
 		logger.Info("API request: time series data", "remote_addr", r.RemoteAddr)
 
-		hoursStr := r.URL.Query().Get("hours")
-		hours := 24 // default to 24 hours
-		if hoursStr != "" {
-			if h, err := strconv.Atoi(hoursStr); err == nil && h > 0 {
-				hours = h
-			}
+		format, err := parseTableFormat(r, instanceUnits(db))
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		series, err := parseSeriesRequest(r.URL.Query().Get, now())
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+			return
 		}
 
-		end := time.Now()
-		start := end.Add(-time.Duration(hours) * time.Hour)
-
-		logs, err := db.QueryByTimeRange(start, end)
+		q, err := archives.queryRange(series.start, series.end, false)
+		timeSeries, err := cache.get(r, func() (any, error) {
+			if err != nil {
+				return nil, err
+			}
+			return archives.timeSeries(q, series.bucket)
+		})
 		if err != nil {
 			logger.Error("Failed to query logs for time series", "error", err)
 			sendErrorResponse(w, "Failed to fetch time series data")
 			return
 		}
 
-		timeSeries := aggregateByHour(logs)
-		sendSuccessResponse(w, timeSeries)
+		w.Header().Set("X-Series-Interval", series.interval)
+		if format.table {
+			format.send(w, r, format.timeSeriesTable(timeSeries.([]TimeSeriesPoint)), archiveMeta(q.archives))
+			return
+		}
+		sendSuccessResponseWithMeta(w, timeSeries, archiveMeta(q.archives))
 	}
 
 	// Size breakdown for distribution charts with optional time range filtering
 	handlers["/api/charts/breakdown"] = func(w http.ResponseWriter, r *http.Request) {
 		logger.Info("API request: size breakdown", "remote_addr", r.RemoteAddr)
 
-		// Check for optional time range parameters
-		startStr := r.URL.Query().Get("start")
-		endStr := r.URL.Query().Get("end")
-		hoursStr := r.URL.Query().Get("hours")
-
-		var logs []database.LogSize
-		var err error
-
-		if startStr != "" && endStr != "" {
-			// Use custom time range
-			start, err := time.Parse(time.RFC3339, startStr)
-			if err != nil {
-				sendErrorResponse(w, "Invalid start time format (use RFC3339)")
-				return
-			}
-			end, err := time.Parse(time.RFC3339, endStr)
+		format, err := parseTableFormat(r, instanceUnits(db))
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		q, err := archives.queryForRequest(r)
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+			return
+		}
+		breakdown, err := cache.get(r, func() (any, error) {
 			if err != nil {
-				sendErrorResponse(w, "Invalid end time format (use RFC3339)")
-				return
+				return nil, err
 			}
-			logs, err = db.QueryByTimeRange(start, end)
-		} else if hoursStr != "" {
-			// Use hours parameter
-			hours := 0 // 0 means all data
-			if h, err := strconv.Atoi(hoursStr); err == nil && h > 0 {
-				hours = h
-			}
-			if hours > 0 {
-				end := time.Now()
-				start := end.Add(-time.Duration(hours) * time.Hour)
-				logs, err = db.QueryByTimeRange(start, end)
-			} else {
-				logs, err = db.GetAll()
-			}
-		} else {
-			// Default to all data
-			logs, err = db.GetAll()
-		}
-
+			return archives.sizeBreakdown(q, format.units)
+		})
 		if err != nil {
 			logger.Error("Failed to get logs for breakdown", "error", err)
 			sendErrorResponse(w, "Failed to fetch breakdown data")
 			return
 		}
 
-		breakdown := calculateSizeBreakdown(logs)
-		sendSuccessResponse(w, breakdown)
+		if format.table {
+			format.send(w, r, format.breakdownTable("File size distribution", "File size", breakdown.([]SizeBreakdown)), archiveMeta(q.archives))
+			return
+		}
+		sendSuccessResponseWithMeta(w, breakdown, archiveMeta(q.archives))
+	}
+
+	// Periods drawn over the time series, such as Cloudflare incidents
+	handlers["/api/annotations"] = makeAnnotationsHandler(db, logger)
+
+	// Cursor-based tailing by ID for incremental consumers
+	handlers["/api/logs/since"] = makeLogsSinceHandler(db, logger)
+
+	// Counts and byte sums without the records
+	handlers["/api/logs/count"] = makeLogsCountHandler(db, logger)
+
+	// Per-record size statistics and distributions
+	handlers["/api/stats/records"] = makeRecordStatsHandler(db, logger)
+	handlers["/api/charts/record-sizes"] = makeRecordSizeSeriesHandler(db, logger)
+	handlers["/api/charts/record-size-breakdown"] = makeRecordSizeBreakdownHandler(db, logger)
+
+	// High-resolution per-minute data and burst detection
+	handlers["/api/charts/minutes"] = makeMinuteSeriesHandler(db, logger)
+	handlers["/api/stats/bursts"] = makeBurstHandler(db, logger)
+
+	// Service level reporting for the ingest endpoint
+	handlers["/api/slo/ingest"] = makeIngestSLOHandler(db, logger)
+
+	// Latency and status codes of the destinations batches are relayed to
+	handlers["/api/stats/forwarding"] = makeForwardingHandler(db, logger)
+
+	// Before/after comparison of two windows, e.g. around a sampling change
+	handlers["/api/stats/diff"] = makeDiffHandler(db, logger)
+
+	// When a configuration or traffic change shifted the daily volume
+	handlers["/api/stats/change-points"] = makeChangePointHandler(db, logger)
+
+	// Dimensions extracted by the optional dataset parsers
+	handlers["/api/stats/dimensions"] = makeDimensionsHandler(db, logger)
+	handlers["/api/charts/by-dimension"] = makeDimensionSeriesHandler(db, logger)
+	handlers["/api/dimensions"] = makeDimensionRegistryHandler(logger)
+	handlers["/api/dimensions/query"] = makeDimensionQueryHandler(db, logger)
+
+	// Redacted samples of ingested payloads
+	handlers["/api/samples"] = makeSamplesHandler(db, logger)
+	handlers["/api/samples/redactions"] = makeRedactionAuditHandler(db, logger)
+
+	// Per-browser dashboard preferences
+	handlers["/api/preferences"] = makePreferencesHandler(db, logger)
+
+	// Saved views (named query definitions)
+	handlers["/api/views"] = makeViewsHandler(db, logger)
+	handlers["/api/views/"] = makeViewHandler(db, logger)
+
+	// Estimation scenarios and their side-by-side comparison
+	handlers["/api/scenarios"] = makeScenariosHandler(db, logger)
+	handlers["/api/scenarios/"] = makeScenarioHandler(db, logger)
+	handlers["/api/scenarios/compare"] = makeScenarioCompareHandler(db, logger)
+
+	// Cost reduction suggestions from samples, dimensions and volume
+	handlers["/api/recommendations"] = makeRecommendationsHandler(db, logger)
+
+	// Projected cost of the tracked volume under tiered pricing
+	handlers["/api/estimate/cost"] = makeCostEstimateHandler(db, logger)
+
+	// Bytes and log lines projected to the end of the month
+	handlers["/api/estimate/forecast"] = makeForecastHandler(db, logger)
+
+	// Widgets rendered by the dashboard from their data endpoints
+	handlers["/api/dashboard/layout"] = makeDashboardLayoutHandler(db, logger)
+
+	// Configuration as code
+	handlers["/api/admin/config"] = makeConfigHandler(db, logger)
+	handlers["/api/admin/config/export"] = makeConfigExportHandler(db, logger)
+	handlers["/api/admin/config/import"] = makeConfigImportHandler(db, logger)
+
+	// Single-object management of alert rules and budgets
+	handlers["/api/alerts"] = makeAlertsHandler(db, logger)
+	handlers["/api/alerts/"] = MakeAlertHandler(nil, db, logger)
+	handlers["/api/budgets"] = makeBudgetsHandler(db, logger)
+	handlers["/api/budgets/"] = MakeBudgetHandler(nil, db, logger)
+
+	// Tenants, ingest tokens and budgets from a manifest, in one transaction
+	handlers["/api/admin/tenants/bulk"] = makeTenantBulkHandler(db, logger)
+
+	// Token rotation with a grace period for the replaced secret
+	handlers["/api/admin/tokens/"] = makeTokenRotateHandler(db, logger)
+
+	// Ingested volume compared with Cloudflare zone analytics
+	handlers["/api/estimates/coverage"] = makeCoverageHandler(db, logger)
+
+	// Destination throughput needed for the observed sustained and burst rates
+	handlers["/api/estimates/bandwidth"] = makeBandwidthHandler(db, logger)
+
+	// Tracked Logpush jobs and their synced health
+	handlers["/api/cloudflare/jobs"] = makeLogpushJobsHandler(db, logger)
+	handlers["/api/cloudflare/jobs/"] = makeLogpushJobHealthHandler(db, logger)
+
+	// Raw data export with checksummed manifests, or streamed in full
+	handlers["/api/export"] = makeStreamExportHandler(key, db, logger)
+	handlers["/api/export/csv"] = MakeExportCSVHandler(nil, db, logger)
+	handlers["/api/export/manifest"] = makeExportManifestHandler(db, logger)
+	handlers["/api/export/verify"] = makeExportVerifyHandler(db, logger)
+
+	// Exports built in the background and downloaded when complete; the
+	// estimator supplies the export directory
+	handlers["/api/exports"] = MakeExportJobsHandler(nil, logger)
+	handlers["/api/exports/"] = MakeExportJobsHandler(nil, logger)
+
+	// Destructive maintenance, all supporting dry_run=true
+	handlers["/api/admin/delete-range"] = makeDeleteRangeHandler(db, logger)
+	handlers["/api/admin/prune"] = MakePruneHandler(nil, db, logger)
+	handlers["/api/admin/purge-dataset"] = makePurgeDatasetHandler(db, logger)
+	handlers["/api/admin/trash"] = makeTrashHandler(db, logger)
+	handlers["/api/admin/trash/restore"] = makeTrashRestoreHandler(db, logger)
+
+	// Retention policy the background janitor prunes raw records by
+	handlers["/api/admin/retention"] = makeRetentionHandler(db, logger)
+
+	// Cross-checks of derived data against raw records
+	handlers["/api/admin/integrity"] = makeIntegrityHandler(db, logger)
+
+	// Monthly shard files, when LPE_DB_SHARDING=monthly
+	handlers["/api/admin/shards"] = makeShardsHandler(db, logger)
+
+	// What was checked and repaired at startup after an unclean shutdown
+	handlers["/api/admin/recovery"] = makeRecoveryHandler(db, logger)
+
+	// Webhook notifications waiting in, or dead-lettered by, the outbox
+	handlers["/api/admin/outbox"] = makeOutboxHandler(db, logger)
+	handlers["/api/admin/outbox/retry"] = makeOutboxRetryHandler(db, logger)
+
+	// Tenants whose scoped dashboards are served by TenantRouter
+	handlers["/api/tenants"] = makeTenantsHandler(db, logger)
+
+	// Volume per Logpush dataset, which the record endpoints filter by
+	handlers["/api/datasets"] = makeDatasetsHandler(db, logger)
+
+	// Volume per Cloudflare zone, which the record endpoints filter by
+	handlers["/api/zones"] = makeZonesHandler(db, logger)
+
+	// Volume per label sent in X-LPE-Label-* headers, which the record
+	// endpoints filter by
+	handlers["/api/labels"] = makeLabelsHandler(db, logger)
+	handlers["/api/reports/chargeback"] = MakeChargebackHandler(nil, db, logger)
+
+	// Cached statistics no longer reflect the records once any are removed
+	// or restored
+	if cache != nil {
+		for _, path := range cacheInvalidatingEndpoints {
+			handlers[path] = InvalidatesCache(cache, handlers[path])
+		}
+	}
+
+	// JSON 404 for every other path under /api/
+	handlers["/api/"] = makeAPINotFoundHandler(logger)
+
+	// Full-history endpoints share a concurrency limit with queueing
+	for _, path := range expensiveEndpoints {
+		handlers[path] = limiter.Wrap(handlers[path])
+	}
+
+	// ?dataset=, ?zone= and ?label= are served by handlers over a scoped
+	// controller
+	if db.Dataset() == "" && db.Zone() == "" && len(db.Labels()) == 0 {
+		datasets := &datasetRouter{db: db, logger: logger, cache: cache, key: key, limiter: limiter}
+		for _, path := range datasetScopedPaths {
+			handlers[path] = datasets.wrap(path, handlers[path])
+		}
 	}
 
 	return handlers
 }
 
+// requestError reports a client-supplied parameter that could not be parsed.
+// Its message is safe to return to the caller verbatim.
+type requestError struct{ message string }
+
+func (e *requestError) Error() string { return e.message }
+
+// queryLogsForRequest loads the log records selected by the optional last,
+// start/end or hours query parameters shared by the statistics endpoints. When
+// none is supplied (or hours is not a positive integer) every record is
+// returned.
+//
+// Parameters:
+//   - db: Database controller for data access
+//   - r: Incoming request carrying the query parameters
+//
+// Returns:
+//   - []database.LogSize: Matching log records
+//   - error: *requestError for bad parameters, or a database error
+func queryLogsForRequest(db *database.SQLiteController, r *http.Request) ([]database.LogSize, error) {
+	start, end, all, err := requestRange(r)
+	if err == nil {
+		err = checkReadable(db, start, all)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if all {
+		return db.GetAll()
+	}
+	return db.QueryByTimeRange(start, end)
+}
+
+// checkReadable refuses a range starting at start, or every record when
+// all is set, that reaches back into months whose shard files are not
+// attached (see database.ErrShardDetached).
+//
+// Parameters:
+//   - db: Database controller the range is read from
+//   - start: Start of the range
+//   - all: Whether every record is wanted
+//
+// Returns:
+//   - error: *requestError naming the earliest readable time, or nil
+func checkReadable(db *database.SQLiteController, start time.Time, all bool) error {
+	since := db.ReadableSince()
+	if since.IsZero() || (!all && !start.Before(since)) {
+		return nil
+	}
+	return &requestError{fmt.Sprintf("Records before %s are in shard files that are not attached; select a range starting at %s or later",
+		since.Format("2006-01"), since.Format(time.RFC3339))}
+}
+
+// requestRange returns the range selected by the optional last, start/end or
+// hours query parameters, as used by queryLogsForRequest.
+//
+// Parameters:
+//   - r: Incoming request carrying the query parameters
+//
+// Returns:
+//   - time.Time: Range start (inclusive)
+//   - time.Time: Range end (exclusive)
+//   - bool: True when no range was selected and every record is wanted
+//   - error: *requestError for bad parameters
+func requestRange(r *http.Request) (time.Time, time.Time, bool, error) {
+	startStr := r.URL.Query().Get("start")
+	endStr := r.URL.Query().Get("end")
+	hoursStr := r.URL.Query().Get("hours")
+
+	last, err := lastParam(r.URL.Query().Get)
+	if err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+	if last > 0 {
+		end := now().UTC()
+		return end.Add(-last), end, false, nil
+	}
+
+	if startStr != "" && endStr != "" {
+		start, err := parseTimestamp(startStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, false, &requestError{invalidTimeMessage("start")}
+		}
+		end, err := parseTimestamp(endStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, false, &requestError{invalidTimeMessage("end")}
+		}
+		return start, end, false, nil
+	}
+
+	if h, err := strconv.Atoi(hoursStr); err == nil && h > 0 {
+		end := now().UTC()
+		return end.Add(-time.Duration(h) * time.Hour), end, false, nil
+	}
+
+	return time.Time{}, time.Time{}, true, nil
+}
+
 // sendSuccessResponse sends a successful API response with the provided data.
 // It sets appropriate headers including CORS headers for development and
 // formats the response using the standard APIResponse structure.
@@ -330,9 +794,20 @@ func MakeAPIHandlers(db *database.SQLiteController, logger *slog.Logger) map[str
 //   - w: HTTP response writer
 //   - data: Data to include in the response
 func sendSuccessResponse(w http.ResponseWriter, data interface{}) {
+	sendSuccessResponseWithMeta(w, data, nil)
+}
+
+// sendSuccessResponseWithMeta sends a successful API response like
+// sendSuccessResponse, with meta describing where the data came from.
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - data: Data to include in the response
+//   - meta: Source of the data, or nil to omit it
+func sendSuccessResponseWithMeta(w http.ResponseWriter, data interface{}, meta *ResponseMeta) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*") // Enable CORS for local development
-	response := APIResponse{Success: true, Data: data}
+	response := APIResponse{Success: true, Data: data, Meta: meta}
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -343,10 +818,25 @@ func sendSuccessResponse(w http.ResponseWriter, data interface{}) {
 //   - w: HTTP response writer
 //   - message: Error message to include in the response
 func sendErrorResponse(w http.ResponseWriter, message string) {
+	sendErrorResponseWithStatus(w, http.StatusInternalServerError, message)
+}
+
+// sendErrorResponseWithStatus sends an error API response using an explicit
+// HTTP status code, for errors that are not server-side failures. A 405
+// response lists the methods from the Allow header in allowed_methods.
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - status: HTTP status code to send
+//   - message: Error message to include in the response
+func sendErrorResponseWithStatus(w http.ResponseWriter, status int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.WriteHeader(http.StatusInternalServerError)
 	response := APIResponse{Success: false, Error: message}
+	if status == http.StatusMethodNotAllowed {
+		response.AllowedMethods = allowedMethods(w.Header())
+	}
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -367,25 +857,38 @@ func calculateStats(logs []database.LogSize) LogSizeStats {
 		return LogSizeStats{}
 	}
 
-	var total int64
+	var total, uncompressed, records int64
+	var recordBytes float64
 	min := logs[0].Filesize
 	max := logs[0].Filesize
 	var lastUpdated time.Time
 
 	for _, log := range logs {
 		total += log.Filesize
+		if log.UncompressedSize > 0 {
+			uncompressed += log.UncompressedSize
+		} else {
+			// Archived records do not carry their uncompressed size
+			uncompressed += log.Filesize
+		}
 		if log.Filesize < min {
 			min = log.Filesize
 		}
 		if log.Filesize > max {
 			max = log.Filesize
 		}
+		records += log.RecordCount
+		recordBytes += log.AvgRecordSize * float64(log.RecordCount)
 		if log.Timestamp.After(lastUpdated) {
 			lastUpdated = log.Timestamp
 		}
 	}
 
 	avg := float64(total) / float64(len(logs))
+	avgRecordSize := 0.0
+	if records > 0 {
+		avgRecordSize = recordBytes / float64(records)
+	}
 
 	return LogSizeStats{
 		TotalRecords: int64(len(logs)),
@@ -394,60 +897,59 @@ func calculateStats(logs []database.LogSize) LogSizeStats {
 		MinSize:      min,
 		MaxSize:      max,
 		LastUpdated:  lastUpdated.Format(time.RFC3339),
-	}
-}
 
-func aggregateByHour(logs []database.LogSize) []TimeSeriesPoint {
-	hourMap := make(map[string]struct {
-		Count     int
-		TotalSize int64
-	})
+		TotalUncompressedSize: uncompressed,
+		CompressionRatio:      compressionRatio(uncompressed, total),
 
-	for _, log := range logs {
-		hourKey := log.Timestamp.Truncate(time.Hour).Format("2006-01-02T15:04:05Z07:00")
-		data := hourMap[hourKey]
-		data.Count++
-		data.TotalSize += log.Filesize
-		hourMap[hourKey] = data
+		RecordCount:        records,
+		AvgRecordsPerBatch: float64(records) / float64(len(logs)),
+		AvgRecordSize:      avgRecordSize,
 	}
+}
 
-	var result []TimeSeriesPoint
-	for timestamp, data := range hourMap {
-		result = append(result, TimeSeriesPoint{
-			Timestamp: timestamp,
-			Count:     data.Count,
-			TotalSize: data.TotalSize,
-		})
+// compressionRatio returns how many bytes each byte received expanded to,
+// or 1 when nothing was received.
+func compressionRatio(uncompressed, received int64) float64 {
+	if received == 0 {
+		return 1
 	}
+	return float64(uncompressed) / float64(received)
+}
 
-	return result
+// aggregateByHour sums log records into hourly buckets.
+func aggregateByHour(logs []database.LogSize) []TimeSeriesPoint {
+	return aggregateByInterval(logs, time.Hour)
 }
 
-func calculateSizeBreakdown(logs []database.LogSize) []SizeBreakdown {
-	ranges := []struct {
-		Name string
-		Min  int64
-		Max  int64
-	}{
-		{"< 1KB", 0, 1024},
-		{"1KB - 10KB", 1024, 10 * 1024},
-		{"10KB - 100KB", 10 * 1024, 100 * 1024},
-		{"100KB - 1MB", 100 * 1024, 1024 * 1024},
-		{"1MB - 10MB", 1024 * 1024, 10 * 1024 * 1024},
-		{"> 10MB", 10 * 1024 * 1024, int64(^uint64(0) >> 1)}, // max int64
-	}
+// breakdownRanges returns the file size ranges of the size breakdown, in
+// decimal or binary units.
+func breakdownRanges(units config.Units) []sizeRange {
+	return sizeRanges(units, 1, 10, 100, 1000, 10000)
+}
 
+// calculateSizeBreakdown distributes batches into file size ranges, in
+// decimal or binary units.
+func calculateSizeBreakdown(logs []database.LogSize, units config.Units) []SizeBreakdown {
+	ranges := breakdownRanges(units)
 	rangeCounts := make([]int, len(ranges))
-	total := len(logs)
-
 	for _, log := range logs {
 		for i, r := range ranges {
-			if log.Filesize >= r.Min && log.Filesize < r.Max {
+			if size := float64(log.Filesize); size >= r.Min && (r.Max == 0 || size < r.Max) {
 				rangeCounts[i]++
 				break
 			}
 		}
 	}
+	return sizeBreakdown(ranges, rangeCounts)
+}
+
+// sizeBreakdown labels the number of batches in each size range with the
+// range and its share of all batches.
+func sizeBreakdown(ranges []sizeRange, rangeCounts []int) []SizeBreakdown {
+	total := 0
+	for _, n := range rangeCounts {
+		total += n
+	}
 
 	var result []SizeBreakdown
 	for i, r := range ranges {
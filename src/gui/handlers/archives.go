@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+	"github.com/melatonein5/LogpushEstimator/src/export"
+)
+
+// ResponseMeta describes where a response's data came from, when some of it
+// was not read from the database, and which page of a listing it is.
+type ResponseMeta struct {
+	Archived bool      `json:"archived,omitempty"` // Records of pruned periods were read from archives, a slower path
+	Archives []string  `json:"archives,omitempty"` // Names of the archive files read
+	Page     *PageMeta `json:"page,omitempty"`     // Page of the records returned, when limit or offset was given
+}
+
+// archiveReader extends range queries into periods pruned under the
+// archive raw_policy by reading the archives written before the records
+// were deleted.
+type archiveReader struct {
+	db  *database.SQLiteController
+	key *encryption.Key // Key for sealed archives, or nil
+}
+
+// find returns the archives holding records in [start, end). It returns
+// none when the archive raw_policy is off, and for tenant-, dataset-, zone-
+// or label-scoped queries: archives hold every record without its tenant,
+// dataset, zone or labels.
+func (a archiveReader) find(start, end time.Time) ([]export.ArchiveFile, error) {
+	if a.db.Tenant() != "" || a.db.Dataset() != "" || a.db.Zone() != "" || len(a.db.Labels()) > 0 {
+		return nil, nil
+	}
+	doc, err := config.Export(a.db)
+	if err != nil || !doc.Retention.Archives() {
+		return nil, err
+	}
+	return export.FindArchives(doc.Retention.ArchiveDir, start, end)
+}
+
+// merge adds the records in [start, end) held by files to logs, skipping
+// records still in the database (restored from the trash after archiving).
+// Archived records are interleaved by timestamp, so records ordered by
+// timestamp, as from QueryByTimeRange, stay ordered.
+func (a archiveReader) merge(logs []database.LogSize, files []export.ArchiveFile, start, end time.Time) ([]database.LogSize, error) {
+	if len(files) == 0 {
+		return logs, nil
+	}
+	archived, err := export.ReadArchives(files, start, end, a.key)
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[int64]bool, len(logs))
+	for _, l := range logs {
+		stored[l.ID] = true
+	}
+	merged := make([]database.LogSize, 0, len(logs)+len(archived))
+	i := 0
+	for _, l := range archived {
+		if stored[l.ID] {
+			continue
+		}
+		for i < len(logs) && logs[i].Timestamp.Before(l.Timestamp) {
+			merged = append(merged, logs[i])
+			i++
+		}
+		merged = append(merged, l)
+	}
+	return append(merged, logs[i:]...), nil
+}
+
+// logsQuery is the range selected by a request's last, start/end or hours
+// parameters, with the archives overlapping it.
+type logsQuery struct {
+	start, end time.Time
+	all        bool                 // No range was selected; every record is wanted
+	archives   []export.ArchiveFile // Archives holding records in the range
+}
+
+// queryForRequest parses the range parameters of r, as queryLogsForRequest
+// does, and finds the archives overlapping the range.
+//
+// Returns:
+//   - logsQuery: The range and its archives
+//   - error: *requestError for bad parameters, or an error reading the archives
+func (a archiveReader) queryForRequest(r *http.Request) (logsQuery, error) {
+	start, end, all, err := requestRange(r)
+	if err == nil {
+		err = checkReadable(a.db, start, all)
+	}
+	if err != nil {
+		return logsQuery{}, err
+	}
+	return a.queryRange(start, end, all)
+}
+
+// queryRange finds the archives overlapping [start, end), or every archive
+// when all is set.
+func (a archiveReader) queryRange(start, end time.Time, all bool) (logsQuery, error) {
+	q := logsQuery{start: start, end: end, all: all}
+	if all {
+		q.start, q.end = time.Unix(0, 0).UTC(), now().UTC()
+	}
+	var err error
+	q.archives, err = a.find(q.start, q.end)
+	return q, err
+}
+
+// load returns the records selected by q from the database, with those of
+// its archives merged in.
+func (a archiveReader) load(q logsQuery) ([]database.LogSize, error) {
+	var (
+		logs []database.LogSize
+		err  error
+	)
+	if q.all {
+		logs, err = a.db.GetAll()
+	} else {
+		logs, err = a.db.QueryByTimeRange(q.start, q.end)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a.merge(logs, q.archives, q.start, q.end)
+}
+
+// archiveMeta returns the metadata flagging a response that read files, or
+// nil when none were read.
+func archiveMeta(files []export.ArchiveFile) *ResponseMeta {
+	if len(files) == 0 {
+		return nil
+	}
+	meta := &ResponseMeta{Archived: true}
+	for _, f := range files {
+		meta.Archives = append(meta.Archives, filepath.Base(f.Path))
+	}
+	return meta
+}
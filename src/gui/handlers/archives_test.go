@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestAPIQueriesReadArchives(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	old := time.Now().UTC().AddDate(0, 0, -60).Truncate(time.Second)
+	for _, size := range []int64{100, 200} {
+		if err := db.InsertLog(database.LogSize{Timestamp: old, Filesize: size, RecordCount: 1}); err != nil {
+			t.Fatalf("InsertLog failed: %v", err)
+		}
+	}
+	doc := config.NewDocument()
+	doc.Retention.RawDays = 30
+	doc.Retention.RawPolicy = config.RawPolicyArchive
+	doc.Retention.ArchiveDir = t.TempDir()
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+	rr := httptest.NewRecorder()
+	handlers["/api/admin/prune"].ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/prune", nil))
+	if report := decodeDeletionReport(t, rr); report.Rows != 2 || report.Archive == "" {
+		t.Fatalf("Expected two rows archived and pruned, got %+v", report)
+	}
+
+	get := func(path string, data any) *ResponseMeta {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handlers[path].ServeHTTP(rr, httptest.NewRequest("GET", path+"?"+url.Values{
+			"start": {old.Add(-time.Hour).Format(time.RFC3339)},
+			"end":   {old.Add(time.Hour).Format(time.RFC3339)},
+		}.Encode(), nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", path, rr.Code, rr.Body.String())
+		}
+		resp := APIResponse{Data: data}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", path, err)
+		}
+		return resp.Meta
+	}
+
+	var stats LogSizeStats
+	if meta := get("/api/stats/summary", &stats); meta == nil || !meta.Archived || len(meta.Archives) != 1 {
+		t.Errorf("Expected the summary flagged as read from one archive, got %+v", meta)
+	}
+	if stats.TotalRecords != 2 || stats.TotalSize != 300 {
+		t.Errorf("Expected the two archived records in the summary, got %+v", stats)
+	}
+	var logs []map[string]any
+	if meta := get("/api/logs/range", &logs); meta == nil || len(logs) != 2 {
+		t.Errorf("Expected two archived records flagged in the range, got %d with %+v", len(logs), meta)
+	}
+
+	// Recent ranges do not touch the archives
+	rr = httptest.NewRecorder()
+	handlers["/api/stats/summary"].ServeHTTP(rr, httptest.NewRequest("GET", "/api/stats/summary?hours=24", nil))
+	var resp APIResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil || resp.Meta != nil {
+		t.Errorf("Expected no archive metadata for the last day, got %+v, %v", resp.Meta, err)
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+)
+
+// MakeBackupHandler creates the GET /api/admin/backup handler, which
+// downloads a consistent copy of the whole database. When a key is
+// configured the copy is sealed and served as logpush-backup.db.enc, so the
+// backup is never at rest in plaintext once it leaves the server.
+//
+// Parameters:
+//   - key: Encryption key for the backup, or nil to serve the plain database
+//   - db: Database controller to back up
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function
+func MakeBackupHandler(key *encryption.Key, db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: backup", "remote_addr", r.RemoteAddr, "encrypted", key != nil)
+
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		data, err := db.Snapshot()
+		if err != nil {
+			sendErrorResponse(w, "Failed to back up database")
+			return
+		}
+		filename := backupFilename
+		if key != nil {
+			if data, err = key.Seal(data); err != nil {
+				logger.Error("Failed to encrypt backup", "error", err)
+				sendErrorResponse(w, "Failed to encrypt backup")
+				return
+			}
+			filename += encryptedSuffix
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.Header().Set("X-Backup-Encrypted", strconv.FormatBool(key != nil))
+		if _, err := w.Write(data); err != nil {
+			logger.Error("Failed to write backup", "error", err)
+		}
+	}
+}
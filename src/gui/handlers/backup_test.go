@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+)
+
+func TestBackupHandler(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	rr := httptest.NewRecorder()
+	MakeBackupHandler(nil, db, logger).ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/backup", nil))
+	if rr.Code != http.StatusOK || !bytes.HasPrefix(rr.Body.Bytes(), []byte("SQLite format 3")) {
+		t.Fatalf("Expected a plain SQLite file, got %d", rr.Code)
+	}
+
+	key, err := encryption.NewKey(bytes.Repeat([]byte{1}, encryption.KeySize))
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	rr = httptest.NewRecorder()
+	MakeBackupHandler(key, db, logger).ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/backup", nil))
+	if rr.Header().Get("X-Backup-Encrypted") != "true" || bytes.Contains(rr.Body.Bytes(), []byte("SQLite format 3")) {
+		t.Fatal("Expected the backup to be encrypted")
+	}
+	plain, err := key.Open(rr.Body.Bytes())
+	if err != nil || !bytes.HasPrefix(plain, []byte("SQLite format 3")) {
+		t.Errorf("Expected the backup to decrypt to a SQLite file, got %v", err)
+	}
+
+	rr = httptest.NewRecorder()
+	MakeBackupHandler(nil, db, logger).ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/backup", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rr.Code)
+	}
+}
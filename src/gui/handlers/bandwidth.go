@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// Bandwidth estimate defaults: the last 24 hours of minute aggregates, and
+// 50% headroom above the busiest minute.
+const (
+	defaultBandwidthMinutes  = 24 * 60
+	defaultBandwidthHeadroom = 1.5
+)
+
+// Throughput is a rate of delivery to a Logpush destination.
+type Throughput struct {
+	BytesPerSecond    float64 `json:"bytes_per_second"`    // Payload bytes per second
+	MegabitsPerSecond float64 `json:"megabits_per_second"` // Payload bandwidth in Mbit/s
+	EventsPerSecond   float64 `json:"events_per_second"`   // Records per second, e.g. Splunk HEC events
+	RequestsPerSecond float64 `json:"requests_per_second"` // Batches per second, e.g. S3 PUTs or HEC POSTs
+}
+
+// BandwidthReport is the response body for /api/estimates/bandwidth.
+type BandwidthReport struct {
+	Minutes       int        `json:"minutes"`        // Size of the analyzed window in minutes
+	ActiveMinutes int        `json:"active_minutes"` // Minutes in which anything was received
+	BatchBytes    float64    `json:"batch_bytes"`    // Bytes per destination request assumed
+	BatchRecords  float64    `json:"batch_records"`  // Records per destination request assumed
+	Headroom      float64    `json:"headroom"`       // Multiplier applied to the peak for Required
+	Sustained     Throughput `json:"sustained"`      // Average over the whole window
+	P95           Throughput `json:"p95"`            // 95th percentile minute
+	Peak          Throughput `json:"peak"`           // Busiest minute
+	PeakMinute    string     `json:"peak_minute"`    // Start of the busiest minute; empty without data
+	Required      Throughput `json:"required"`       // Peak times headroom: what the destination must accept
+}
+
+// makeBandwidthHandler serves /api/estimates/bandwidth, converting the
+// sustained and burst byte rates seen in the per-minute aggregates into
+// the throughput a destination must accept: bandwidth, events per second
+// (what Splunk HEC throttles on) and requests per second (S3 PUTs, HTTP
+// POSTs). Destinations throttle on rates rather than totals, so a pipeline
+// whose storage is sized correctly can still back up during bursts.
+//
+// Query parameters:
+//   - minutes or last: Window size (default 24h, at most the retained 48h)
+//   - headroom: Multiplier applied to the peak (default 1.5, at least 1)
+//   - batch_bytes, batch_records: Size of each request or object the
+//     destination receives, such as Logpush's max_upload_bytes and
+//     max_upload_records; default the observed average batch. A request
+//     ends at whichever limit is reached first.
+func makeBandwidthHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: bandwidth estimate", "remote_addr", r.RemoteAddr)
+
+		minutes, err := windowParam(r, "minutes", time.Minute, defaultBandwidthMinutes)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		minutes = min(minutes, int(database.MinuteAggregateWindow/time.Minute))
+		headroom, err := positiveFloatParam(r, "headroom", defaultBandwidthHeadroom)
+		if err != nil || headroom < 1 {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "headroom must be a number of at least 1")
+			return
+		}
+		batchBytes, err := positiveFloatParam(r, "batch_bytes", 0)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "batch_bytes must be a positive number")
+			return
+		}
+		batchRecords, err := positiveFloatParam(r, "batch_records", 0)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "batch_records must be a positive number")
+			return
+		}
+
+		end := now().UTC()
+		start := end.Add(-time.Duration(minutes) * time.Minute)
+		aggregates, err := db.QueryMinuteAggregates(start, end)
+		if err != nil {
+			logger.Error("Failed to query minute aggregates for bandwidth", "error", err)
+			sendErrorResponse(w, "Failed to fetch bandwidth data")
+			return
+		}
+
+		sendSuccessResponse(w, estimateBandwidth(aggregates, minutes, headroom, batchBytes, batchRecords))
+	}
+}
+
+// positiveFloatParam reads a positive number from the named query
+// parameter, returning def when it is absent.
+func positiveFloatParam(r *http.Request, name string, def float64) (float64, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 || math.IsInf(v, 0) {
+		return 0, errors.New(name + " must be a positive number")
+	}
+	return v, nil
+}
+
+// estimateBandwidth computes a BandwidthReport from the minute aggregates
+// of a window. Zero batchBytes or batchRecords select the observed average
+// batch.
+func estimateBandwidth(aggregates []database.MinuteAggregate, minutes int, headroom, batchBytes, batchRecords float64) BandwidthReport {
+	report := BandwidthReport{Minutes: minutes, ActiveMinutes: len(aggregates), Headroom: headroom}
+
+	var totalBytes, totalRecords, totalBatches int64
+	for _, a := range aggregates {
+		totalBytes += a.TotalSize
+		totalRecords += a.Records
+		totalBatches += a.Batches
+	}
+	if batchBytes == 0 && totalBatches > 0 {
+		batchBytes = float64(totalBytes) / float64(totalBatches)
+	}
+	if batchRecords == 0 && totalBatches > 0 {
+		batchRecords = float64(totalRecords) / float64(totalBatches)
+	}
+	report.BatchBytes, report.BatchRecords = batchBytes, batchRecords
+	if len(aggregates) == 0 {
+		return report
+	}
+
+	rate := func(bytes, records int64, seconds float64) Throughput {
+		t := Throughput{
+			BytesPerSecond:  float64(bytes) / seconds,
+			EventsPerSecond: float64(records) / seconds,
+		}
+		t.MegabitsPerSecond = t.BytesPerSecond * 8 / 1e6
+		if batchBytes > 0 {
+			t.RequestsPerSecond = t.BytesPerSecond / batchBytes
+		}
+		if batchRecords > 0 {
+			t.RequestsPerSecond = max(t.RequestsPerSecond, t.EventsPerSecond/batchRecords)
+		}
+		return t
+	}
+	report.Sustained = rate(totalBytes, totalRecords, float64(minutes)*60)
+
+	// Idle minutes are not stored but count towards the percentile
+	sorted := append([]database.MinuteAggregate(nil), aggregates...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].TotalSize < sorted[j].TotalSize })
+	if idx := int(math.Ceil(0.95*float64(minutes))) - 1 - (minutes - len(sorted)); idx >= 0 {
+		p95 := sorted[min(idx, len(sorted)-1)]
+		report.P95 = rate(p95.TotalSize, p95.Records, 60)
+	}
+	peak := sorted[len(sorted)-1]
+	report.Peak = rate(peak.TotalSize, peak.Records, 60)
+	report.PeakMinute = peak.Minute.UTC().Format(time.RFC3339)
+	report.Required = Throughput{
+		BytesPerSecond:    report.Peak.BytesPerSecond * headroom,
+		MegabitsPerSecond: report.Peak.MegabitsPerSecond * headroom,
+		EventsPerSecond:   report.Peak.EventsPerSecond * headroom,
+		RequestsPerSecond: report.Peak.RequestsPerSecond * headroom,
+	}
+	return report
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestEstimateBandwidth(t *testing.T) {
+	start := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	// 20 minutes, 10 of them idle: nine steady minutes and one burst
+	var aggregates []database.MinuteAggregate
+	for i := 0; i < 9; i++ {
+		aggregates = append(aggregates, database.MinuteAggregate{Minute: start.Add(time.Duration(i) * time.Minute), Batches: 1, Records: 600, TotalSize: 600_000})
+	}
+	burst := start.Add(15 * time.Minute)
+	aggregates = append(aggregates, database.MinuteAggregate{Minute: burst, Batches: 4, Records: 6000, TotalSize: 6_000_000})
+
+	report := estimateBandwidth(aggregates, 20, 2, 0, 0)
+	if report.ActiveMinutes != 10 || report.BatchBytes != 11_400_000.0/13 || report.BatchRecords != 11_400.0/13 {
+		t.Errorf("Unexpected observed batch size: %+v", report)
+	}
+	if report.Sustained.BytesPerSecond != 11_400_000.0/1200 || report.Sustained.EventsPerSecond != 11_400.0/1200 {
+		t.Errorf("Unexpected sustained rate: %+v", report.Sustained)
+	}
+	if report.P95.BytesPerSecond != 10_000 {
+		t.Errorf("Expected the 19th of 20 minutes as p95, got %+v", report.P95)
+	}
+	if report.Peak.BytesPerSecond != 100_000 || report.Peak.MegabitsPerSecond != 0.8 || report.PeakMinute != burst.Format(time.RFC3339) {
+		t.Errorf("Unexpected peak: %+v at %s", report.Peak, report.PeakMinute)
+	}
+	if report.Required.EventsPerSecond != 200 {
+		t.Errorf("Expected the peak's 100 events/s doubled, got %+v", report.Required)
+	}
+
+	// Requests end at whichever batch limit is reached first
+	report = estimateBandwidth(aggregates, 20, 1, 1_000_000, 10_000)
+	if report.Peak.RequestsPerSecond != 0.1 {
+		t.Errorf("Expected 100 KB/s in 1 MB objects to need 0.1 PUTs/s, got %+v", report.Peak)
+	}
+	report = estimateBandwidth(aggregates, 20, 1, 1_000_000, 10)
+	if report.Peak.RequestsPerSecond != 10 {
+		t.Errorf("Expected 100 events/s in 10-event requests to need 10 requests/s, got %+v", report.Peak)
+	}
+
+	if empty := estimateBandwidth(nil, 60, 1.5, 0, 0); empty.Peak != (Throughput{}) || empty.PeakMinute != "" {
+		t.Errorf("Expected zero rates without data, got %+v", empty)
+	}
+}
+
+func TestBandwidthHandler(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/estimates/bandwidth"]
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/api/estimates/bandwidth?last=1h&batch_bytes=5000000", nil))
+	var response struct {
+		Data BandwidthReport `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d: %s", rr.Code, rr.Body.String())
+	}
+	if response.Data.Minutes != 60 || response.Data.Headroom != 1.5 || response.Data.BatchBytes != 5_000_000 {
+		t.Errorf("Unexpected parameters in report: %+v", response.Data)
+	}
+
+	for _, query := range []string{"headroom=0.5", "batch_bytes=-1", "batch_records=x"} {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest("GET", "/api/estimates/bandwidth?"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxSafeInteger is the largest integer a JavaScript number holds exactly
+// (Number.MAX_SAFE_INTEGER, 2^53 - 1). Cumulative byte totals pass it at
+// about 8 PiB.
+const maxSafeInteger = 1<<53 - 1
+
+// WithInt64Strings lets API clients ask for integers JavaScript cannot
+// represent exactly as strings: with int64=string, any integer in a JSON
+// response beyond ±(2^53 - 1) is emitted as a decimal string, so JSON.parse
+// does not round it. Smaller numbers, and responses that are not JSON, are
+// unchanged. int64=number, the default, leaves every number as it is; any
+// other value is rejected with 400.
+//
+// Parameters:
+//   - next: Handler to wrap
+//
+// Returns:
+//   - http.HandlerFunc: Handler honoring the int64 parameter
+func WithInt64Strings(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("int64") {
+		case "", "number":
+			next(w, r)
+		case "string":
+			sw := &int64StringWriter{ResponseWriter: w}
+			next(sw, r)
+			sw.finish()
+		default:
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid int64 format (use number or string)")
+		}
+	}
+}
+
+// int64StringWriter buffers a JSON response so its unsafe integers can be
+// quoted before it is sent. Other responses are passed straight through.
+type int64StringWriter struct {
+	http.ResponseWriter
+	status      int          // Status held back until the body is rewritten
+	wroteHeader bool         // Whether the status has been decided
+	passThrough bool         // Whether the response is not JSON
+	buf         bytes.Buffer // Buffered JSON body
+}
+
+func (sw *int64StringWriter) WriteHeader(code int) {
+	if sw.wroteHeader {
+		return
+	}
+	sw.wroteHeader = true
+	sw.status = code
+	sw.passThrough = !strings.HasPrefix(sw.Header().Get("Content-Type"), "application/json")
+	if sw.passThrough {
+		sw.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (sw *int64StringWriter) Write(p []byte) (int, error) {
+	sw.WriteHeader(http.StatusOK)
+	if sw.passThrough {
+		return sw.ResponseWriter.Write(p)
+	}
+	return sw.buf.Write(p)
+}
+
+// Flush forwards flushes of responses that are passed through, so streamed
+// downloads are not held back.
+func (sw *int64StringWriter) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok && sw.passThrough {
+		f.Flush()
+	}
+}
+
+// finish sends the buffered JSON response with its unsafe integers quoted.
+func (sw *int64StringWriter) finish() {
+	if !sw.wroteHeader || sw.passThrough {
+		return
+	}
+	body := quoteUnsafeIntegers(sw.buf.Bytes())
+	sw.Header().Del("Content-Length")
+	sw.ResponseWriter.WriteHeader(sw.status)
+	sw.ResponseWriter.Write(body)
+}
+
+// quoteUnsafeIntegers rewrites a JSON document so integers beyond
+// ±maxSafeInteger become strings, keeping everything else, including key
+// order and whitespace, as it was.
+//
+// Parameters:
+//   - doc: JSON document to rewrite
+//
+// Returns:
+//   - []byte: The rewritten document
+func quoteUnsafeIntegers(doc []byte) []byte {
+	out := make([]byte, 0, len(doc))
+	for i := 0; i < len(doc); {
+		c := doc[i]
+		switch {
+		case c == '"':
+			// Copy the string, skipping escaped characters
+			j := i + 1
+			for j < len(doc) && doc[j] != '"' {
+				if doc[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, len(doc))
+			out = append(out, doc[i:j]...)
+			i = j
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(doc) && strings.IndexByte("0123456789.eE+-", doc[j]) >= 0 {
+				j++
+			}
+			number := doc[i:j]
+			if unsafeInteger(string(number)) {
+				out = append(append(append(out, '"'), number...), '"')
+			} else {
+				out = append(out, number...)
+			}
+			i = j
+		default:
+			out = append(out, c)
+			i++
+		}
+	}
+	return out
+}
+
+// unsafeInteger reports whether number is an integer literal JavaScript
+// would round.
+func unsafeInteger(number string) bool {
+	if strings.ContainsAny(number, ".eE") {
+		return false
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		// Beyond the int64 range, as uint64 totals can be
+		return true
+	}
+	return n > maxSafeInteger || n < -maxSafeInteger
+}
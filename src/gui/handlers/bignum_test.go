@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestQuoteUnsafeIntegers(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"safe numbers", `{"a":9007199254740991,"b":-3,"c":1.5}`, `{"a":9007199254740991,"b":-3,"c":1.5}`},
+		{"beyond 2^53", `{"total":9007199254740993,"min":-9007199254740993}`, `{"total":"9007199254740993","min":"-9007199254740993"}`},
+		{"beyond int64", `[18446744073709551615]`, `["18446744073709551615"]`},
+		{"floats", `{"x":1e+21,"y":12345678901234567.5}`, `{"x":1e+21,"y":12345678901234567.5}`},
+		{"strings untouched", `{"s":"12345678901234567890","e":"a\"99999999999999999"}`, `{"s":"12345678901234567890","e":"a\"99999999999999999"}`},
+		{"whitespace kept", "{\n  \"n\": 10000000000000000\n}\n", "{\n  \"n\": \"10000000000000000\"\n}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(quoteUnsafeIntegers([]byte(tt.doc))); got != tt.want {
+				t.Errorf("quoteUnsafeIntegers(%s) = %s, want %s", tt.doc, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithInt64Strings(t *testing.T) {
+	handler := WithInt64Strings(func(w http.ResponseWriter, r *http.Request) {
+		sendSuccessResponse(w, map[string]int64{"total_size": 1 << 60, "count": 5})
+	})
+	get := func(query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest("GET", "/api/stats/summary?"+query, nil))
+		return rr
+	}
+
+	if body := get("").Body.String(); body != `{"success":true,"data":{"count":5,"total_size":1152921504606846976}}`+"\n" {
+		t.Errorf("Expected numbers by default, got %s", body)
+	}
+	if body := get("int64=string").Body.String(); body != `{"success":true,"data":{"count":5,"total_size":"1152921504606846976"}}`+"\n" {
+		t.Errorf("Expected the large total as a string, got %s", body)
+	}
+	if rr := get("int64=bigint"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown int64 format, got %d", rr.Code)
+	}
+
+	// Non-JSON responses are passed through as written
+	csv := WithInt64Strings(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("size\n10000000000000000\n"))
+	})
+	rr := httptest.NewRecorder()
+	csv(rr, httptest.NewRequest("GET", "/api/export/csv?int64=string", nil))
+	if rr.Code != http.StatusAccepted || rr.Body.String() != "size\n10000000000000000\n" {
+		t.Errorf("Expected the CSV unchanged, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/events"
+	"github.com/melatonein5/LogpushEstimator/src/reports"
+)
+
+// BudgetTest is the response body for POST /api/budgets/{name}/test.
+type BudgetTest struct {
+	Status reports.BudgetStatus `json:"status"` // Spend in the current period
+	Alert  events.AlertFired    `json:"alert"`  // Test alert published to webhooks
+}
+
+// EvaluateBudgetNow reports a budget's spend in the period containing now,
+// pricing it with the budget's model like a chargeback.
+//
+// Parameters:
+//   - db: Database controller for usage queries
+//   - doc: Configuration holding the budget's pricing model
+//   - b: Budget to evaluate
+//   - now: Time within the period to evaluate
+//
+// Returns:
+//   - reports.BudgetStatus: Spend against the limits
+//   - error: Any error encountered while querying usage
+func EvaluateBudgetNow(db *database.SQLiteController, doc config.Document, b config.Budget, now time.Time) (reports.BudgetStatus, error) {
+	start, end := reports.BudgetPeriod(b.Period, now)
+	usage, err := db.QueryTenantUsage(start, end)
+	if err != nil {
+		return reports.BudgetStatus{}, err
+	}
+	model, _ := doc.PricingModel(b.PricingModel)
+	var requests map[string]int64
+	if b.LimitCost > 0 && model.Batching != nil {
+		hourly, err := db.QueryTenantHourlyUsage(start, end)
+		if err != nil {
+			return reports.BudgetStatus{}, err
+		}
+		requests = reports.EstimateRequests(*model.Batching, hourly)
+	}
+	return reports.EvaluateBudget(b, model, start, usage, requests), nil
+}
+
+// BudgetAlert describes a budget's status as the alert.fired payload sent
+// when it is exceeded.
+//
+// Parameters:
+//   - b: Budget the status is for
+//   - status: Spend in the current period
+//
+// Returns:
+//   - events.AlertFired: Alert naming the budget, its spend and the chargeback report
+func BudgetAlert(b config.Budget, status reports.BudgetStatus) events.AlertFired {
+	spent := fmt.Sprintf("%d bytes", status.Bytes)
+	if b.LimitCost > 0 {
+		spent = fmt.Sprintf("%.2f %s", status.Cost, status.Currency)
+	}
+	return events.AlertFired{
+		Alert:   "budget_exceeded",
+		Subject: b.Name,
+		Message: fmt.Sprintf("Budget %q is at %.1f%% of its %s limit (%s since %s)", b.Name, status.Percent, b.Period, spent, status.Start.Format(time.DateOnly)),
+		Path:    "/api/reports/chargeback?month=" + status.Start.Format(reports.MonthLayout),
+	}
+}
+
+// decodeStoredObject reads a budget or alert rule from the request body,
+// rejecting unknown fields.
+func decodeStoredObject(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// unmodifiedSince reads the updated_at precondition of a DELETE from the
+// query string; absent, the delete is unconditional.
+func unmodifiedSince(r *http.Request) (time.Time, error) {
+	v := r.URL.Query().Get("updated_at")
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}, &requestError{"Invalid updated_at (use the RFC 3339 timestamp returned with the object)"}
+	}
+	return t, nil
+}
+
+// sendConfigEditError answers a failed single-object edit: 412 when the
+// object changed since the caller read it, 400 for invalid objects and 500
+// otherwise.
+func sendConfigEditError(w http.ResponseWriter, logger *slog.Logger, err error, what string) {
+	var invalid *config.ValidationError
+	switch {
+	case errors.Is(err, config.ErrModified):
+		sendErrorResponseWithStatus(w, http.StatusPreconditionFailed, what+" was modified since updated_at; fetch it again and retry")
+	case errors.As(err, &invalid):
+		sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+	default:
+		logger.Error("Failed to save configuration", "error", err)
+		sendErrorResponse(w, "Failed to save "+strings.ToLower(what))
+	}
+}
+
+// makeBudgetsHandler serves /api/budgets. GET lists budgets with their
+// updated_at and POST creates one from the JSON body; with dry_run=true the
+// budget is only validated.
+func makeBudgetsHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: budgets", "method", r.Method, "remote_addr", r.RemoteAddr)
+
+		switch r.Method {
+		case http.MethodGet:
+			budgets, err := config.ListBudgets(db)
+			if err != nil {
+				sendErrorResponse(w, "Failed to fetch budgets")
+				return
+			}
+			sendSuccessResponse(w, budgets)
+
+		case http.MethodPost:
+			var b config.StoredBudget
+			if err := decodeStoredObject(r, &b); err != nil {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid budget: "+err.Error())
+				return
+			}
+			budgets, err := config.ListBudgets(db)
+			if err != nil {
+				sendErrorResponse(w, "Failed to fetch budgets")
+				return
+			}
+			for _, existing := range budgets {
+				if existing.Name == b.Name {
+					sendErrorResponseWithStatus(w, http.StatusConflict, "Budget already exists; use PUT /api/budgets/"+b.Name)
+					return
+				}
+			}
+			saveBudget(w, r, db, logger, b.Budget, time.Time{})
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// saveBudget validates and, unless dry_run is set, stores a budget.
+func saveBudget(w http.ResponseWriter, r *http.Request, db *database.SQLiteController, logger *slog.Logger, b config.Budget, since time.Time) {
+	if isDryRun(r) {
+		if err := config.CheckBudget(db, b); err != nil {
+			sendConfigEditError(w, logger, err, "Budget")
+			return
+		}
+		sendSuccessResponse(w, map[string]any{"dry_run": true, "valid": true, "budget": b})
+		return
+	}
+	saved, err := config.SaveBudget(db, b, since)
+	if err != nil {
+		sendConfigEditError(w, logger, err, "Budget")
+		return
+	}
+	sendSuccessResponse(w, saved)
+}
+
+// MakeBudgetHandler creates the /api/budgets/{name} handler: GET returns the
+// budget, PUT replaces it with the JSON body and DELETE removes it. PUT
+// bodies and DELETE requests (?updated_at=) may carry the updated_at last
+// read, and are refused with 412 if the budget changed since. POST
+// /api/budgets/{name}/test evaluates the budget now and publishes a test
+// alert.fired event on bus, so webhooks and ticket integrations can be
+// checked end to end.
+//
+// Parameters:
+//   - bus: Bus test alerts are published on; nil discards them
+//   - db: Database controller for the configuration and usage
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Handler for /api/budgets/
+func MakeBudgetHandler(bus *events.Bus, db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/api/budgets/")
+		name, test := strings.CutSuffix(name, "/test")
+		logger.Info("API request: budget", "method", r.Method, "name", name, "test", test, "remote_addr", r.RemoteAddr)
+
+		budgets, err := config.ListBudgets(db)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch budgets")
+			return
+		}
+		var current *config.StoredBudget
+		for i := range budgets {
+			if budgets[i].Name == name {
+				current = &budgets[i]
+			}
+		}
+
+		if test {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", "POST")
+				sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			if current == nil {
+				sendErrorResponseWithStatus(w, http.StatusNotFound, "Budget not found")
+				return
+			}
+			doc, err := config.Export(db)
+			if err != nil {
+				sendErrorResponse(w, "Failed to load pricing models")
+				return
+			}
+			status, err := EvaluateBudgetNow(db, doc, current.Budget, now())
+			if err != nil {
+				sendErrorResponse(w, "Failed to query budget usage")
+				return
+			}
+			alert := BudgetAlert(current.Budget, status)
+			alert.Alert = "budget_test"
+			alert.Message = "Test: " + alert.Message
+			bus.Publish(events.TopicAlertFired, alert)
+			sendSuccessResponse(w, BudgetTest{Status: status, Alert: alert})
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			if current == nil {
+				sendErrorResponseWithStatus(w, http.StatusNotFound, "Budget not found")
+				return
+			}
+			sendSuccessResponse(w, current)
+
+		case http.MethodPut:
+			var b config.StoredBudget
+			if err := decodeStoredObject(r, &b); err != nil {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid budget: "+err.Error())
+				return
+			}
+			b.Name = name
+			saveBudget(w, r, db, logger, b.Budget, b.UpdatedAt)
+
+		case http.MethodDelete:
+			since, err := unmodifiedSince(r)
+			if err != nil {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			deleted, err := config.DeleteBudget(db, name, since)
+			if err != nil {
+				sendConfigEditError(w, logger, err, "Budget")
+				return
+			}
+			if !deleted {
+				sendErrorResponseWithStatus(w, http.StatusNotFound, "Budget not found")
+				return
+			}
+			sendSuccessResponse(w, map[string]string{"deleted": name})
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// defaultBurstFactor is how many times the baseline a minute must exceed to be
+// reported as a burst when no factor parameter is supplied.
+const defaultBurstFactor = 3.0
+
+// MinutePoint is a single per-minute data point for high-resolution charts.
+type MinutePoint struct {
+	Timestamp string `json:"timestamp"`  // ISO timestamp for the start of the minute
+	Batches   int64  `json:"batches"`    // Number of batches received in the minute
+	Records   int64  `json:"records"`    // Number of records received in the minute
+	TotalSize int64  `json:"total_size"` // Sum of batch sizes in bytes
+}
+
+// Burst describes a minute whose volume exceeded the burst threshold.
+type Burst struct {
+	Timestamp string  `json:"timestamp"`  // ISO timestamp for the start of the minute
+	TotalSize int64   `json:"total_size"` // Bytes received in the minute
+	Ratio     float64 `json:"ratio"`      // TotalSize divided by the baseline
+}
+
+// BurstReport is the response body for /api/stats/bursts.
+type BurstReport struct {
+	Minutes       int     `json:"minutes"`        // Size of the analyzed window in minutes
+	Factor        float64 `json:"factor"`         // Threshold multiplier applied to the baseline
+	BaselineBytes float64 `json:"baseline_bytes"` // Median bytes per active minute
+	Bursts        []Burst `json:"bursts"`         // Minutes above Factor * BaselineBytes
+}
+
+// parseMinutesParam reads the "minutes" (or "last") query parameter,
+// defaulting to 60 and capping the value at the retained minute aggregate
+// window.
+func parseMinutesParam(r *http.Request) (int, error) {
+	minutes, err := windowParam(r, "minutes", time.Minute, 60)
+	if err != nil {
+		return 0, err
+	}
+	if max := int(database.MinuteAggregateWindow / time.Minute); minutes > max {
+		minutes = max
+	}
+	return minutes, nil
+}
+
+// makeMinuteSeriesHandler serves /api/charts/minutes, a zero-filled
+// per-minute series for the last `minutes` minutes (default 60, max 48h).
+func makeMinuteSeriesHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: minute series", "remote_addr", r.RemoteAddr)
+
+		format, err := parseTableFormat(r, instanceUnits(db))
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		minutes, err := parseMinutesParam(r)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		end := now().UTC()
+		start := end.Add(-time.Duration(minutes) * time.Minute)
+
+		maxPoints, err := parseMaxPoints(r)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		aggregates, err := db.QueryMinuteAggregates(start, end)
+		if err != nil {
+			logger.Error("Failed to query minute aggregates", "error", err)
+			sendErrorResponse(w, "Failed to fetch minute data")
+			return
+		}
+
+		points := decimateMinutes(fillMinuteSeries(aggregates, start, end), maxPoints)
+		if format.table {
+			format.send(w, r, format.minuteTable(points), nil)
+			return
+		}
+		sendSuccessResponse(w, points)
+	}
+}
+
+// makeBurstHandler serves /api/stats/bursts, reporting minutes in the window
+// whose byte volume exceeds `factor` times the median active minute.
+func makeBurstHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: bursts", "remote_addr", r.RemoteAddr)
+
+		minutes, err := parseMinutesParam(r)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		factor := defaultBurstFactor
+		if f, err := strconv.ParseFloat(r.URL.Query().Get("factor"), 64); err == nil && f > 1 {
+			factor = f
+		}
+
+		end := now().UTC()
+		start := end.Add(-time.Duration(minutes) * time.Minute)
+
+		aggregates, err := db.QueryMinuteAggregates(start, end)
+		if err != nil {
+			logger.Error("Failed to query minute aggregates for bursts", "error", err)
+			sendErrorResponse(w, "Failed to fetch burst data")
+			return
+		}
+
+		report := detectBursts(aggregates, factor)
+		report.Minutes = minutes
+		sendSuccessResponse(w, report)
+	}
+}
+
+// fillMinuteSeries expands sparse minute aggregates into one point per minute
+// between start and end so charts render gaps as zero rather than
+// interpolating across them.
+func fillMinuteSeries(aggregates []database.MinuteAggregate, start, end time.Time) []MinutePoint {
+	byMinute := make(map[int64]database.MinuteAggregate, len(aggregates))
+	for _, a := range aggregates {
+		byMinute[a.Minute.Unix()] = a
+	}
+
+	var points []MinutePoint
+	for m := start.Truncate(time.Minute); m.Before(end); m = m.Add(time.Minute) {
+		a := byMinute[m.Unix()]
+		points = append(points, MinutePoint{
+			Timestamp: m.Format(time.RFC3339),
+			Batches:   a.Batches,
+			Records:   a.Records,
+			TotalSize: a.TotalSize,
+		})
+	}
+	return points
+}
+
+// detectBursts flags minutes whose volume is more than factor times the
+// median of active (non-empty) minutes. Using the median keeps a single large
+// spike from raising its own threshold.
+func detectBursts(aggregates []database.MinuteAggregate, factor float64) BurstReport {
+	report := BurstReport{Factor: factor, Bursts: []Burst{}}
+	if len(aggregates) == 0 {
+		return report
+	}
+
+	sizes := make([]int64, len(aggregates))
+	for i, a := range aggregates {
+		sizes[i] = a.TotalSize
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+	mid := len(sizes) / 2
+	if len(sizes)%2 == 0 {
+		report.BaselineBytes = float64(sizes[mid-1]+sizes[mid]) / 2
+	} else {
+		report.BaselineBytes = float64(sizes[mid])
+	}
+	if report.BaselineBytes <= 0 {
+		return report
+	}
+
+	for _, a := range aggregates {
+		ratio := float64(a.TotalSize) / report.BaselineBytes
+		if ratio > factor {
+			report.Bursts = append(report.Bursts, Burst{
+				Timestamp: a.Minute.Format(time.RFC3339),
+				TotalSize: a.TotalSize,
+				Ratio:     ratio,
+			})
+		}
+	}
+	return report
+}
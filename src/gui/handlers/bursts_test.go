@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestDetectBursts(t *testing.T) {
+	minute := time.Now().Truncate(time.Minute)
+	aggregates := []database.MinuteAggregate{
+		{Minute: minute, TotalSize: 100},
+		{Minute: minute.Add(time.Minute), TotalSize: 120},
+		{Minute: minute.Add(2 * time.Minute), TotalSize: 80},
+		{Minute: minute.Add(3 * time.Minute), TotalSize: 1000},
+	}
+
+	report := detectBursts(aggregates, 3)
+
+	if report.BaselineBytes != 110 {
+		t.Errorf("Expected median baseline 110, got %f", report.BaselineBytes)
+	}
+	if len(report.Bursts) != 1 {
+		t.Fatalf("Expected 1 burst, got %d", len(report.Bursts))
+	}
+	if report.Bursts[0].TotalSize != 1000 {
+		t.Errorf("Expected burst of 1000 bytes, got %d", report.Bursts[0].TotalSize)
+	}
+
+	if empty := detectBursts(nil, 3); len(empty.Bursts) != 0 {
+		t.Errorf("Expected no bursts for empty input, got %d", len(empty.Bursts))
+	}
+}
+
+func TestFillMinuteSeries(t *testing.T) {
+	start := time.Now().Truncate(time.Minute).Add(-5 * time.Minute)
+	end := start.Add(5 * time.Minute)
+	aggregates := []database.MinuteAggregate{
+		{Minute: start.Add(2 * time.Minute), Batches: 1, TotalSize: 42},
+	}
+
+	points := fillMinuteSeries(aggregates, start, end)
+
+	if len(points) != 5 {
+		t.Fatalf("Expected 5 points, got %d", len(points))
+	}
+	if points[2].TotalSize != 42 || points[1].TotalSize != 0 {
+		t.Errorf("Unexpected zero-filled series: %+v", points)
+	}
+}
+
+func TestAPIMinuteEndpoints(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+
+	req, err := http.NewRequest("GET", "/api/charts/minutes?minutes=10", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handlers["/api/charts/minutes"].ServeHTTP(rr, req)
+
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	points, ok := response.Data.([]interface{})
+	if !ok || len(points) < 10 {
+		t.Errorf("Expected at least 10 minute points, got %v", response.Data)
+	}
+
+	req, err = http.NewRequest("GET", "/api/stats/bursts", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	handlers["/api/stats/bursts"].ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+}
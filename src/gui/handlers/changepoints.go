@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// Change-point defaults: 90 complete days analyzed, levels lasting at least
+// a week, so weekday and weekend traffic are not reported as shifts.
+const (
+	defaultChangePointDays    = 90
+	defaultChangePointMinDays = 7
+	maxChangePointMinDays     = 30
+)
+
+// ChangePoint is a day on which the daily volume shifted to a new level.
+type ChangePoint struct {
+	Date      string   `json:"date"`       // First day at the new level, YYYY-MM-DD
+	Before    float64  `json:"before"`     // Mean daily value of the preceding level
+	After     float64  `json:"after"`      // Mean daily value of the new level
+	Delta     float64  `json:"delta"`      // After minus Before
+	ChangePct *float64 `json:"change_pct"` // Delta as a percentage of Before; null when Before is 0
+	Score     float64  `json:"score"`      // Delta in standard errors of the difference of the two means
+}
+
+// VolumeLevel is a run of days between change points.
+type VolumeLevel struct {
+	Start string  `json:"start"` // First day, YYYY-MM-DD
+	End   string  `json:"end"`   // Last day, YYYY-MM-DD
+	Days  int     `json:"days"`  // Length in days
+	Mean  float64 `json:"mean"`  // Mean daily value
+}
+
+// ChangePointReport is the response body for /api/stats/change-points.
+type ChangePointReport struct {
+	Metric       string        `json:"metric"`        // "bytes", "records" or "batches"
+	Start        string        `json:"start"`         // First day analyzed; empty without records
+	End          string        `json:"end"`           // Last day analyzed; empty without records
+	Days         int           `json:"days"`          // Days analyzed
+	MinDays      int           `json:"min_days"`      // Shortest level reported
+	Noise        float64       `json:"noise"`         // Estimated standard deviation of a day around its level
+	Penalty      float64       `json:"penalty"`       // Cost of each change point, in units of Noise squared
+	Levels       []VolumeLevel `json:"levels"`        // Levels, oldest first
+	ChangePoints []ChangePoint `json:"change_points"` // Shifts between consecutive levels, oldest first
+}
+
+// makeChangePointHandler serves /api/stats/change-points, detecting the
+// days on which the daily volume shifted to a new level in the last `days`
+// complete days (default 90, or last=), counted from the first day with
+// records. metric= selects bytes (the default), records or batches,
+// min_days= the shortest level reported (default 7), and sensitivity=
+// divides the penalty, so 2 reports smaller shifts.
+func makeChangePointHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: change points", "remote_addr", r.RemoteAddr)
+
+		days, err := windowParam(r, "days", 24*time.Hour, defaultChangePointDays)
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+			return
+		}
+		days = min(days, maxQueryWindowHours/24)
+
+		q := r.URL.Query()
+		metric := q.Get("metric")
+		switch metric {
+		case "":
+			metric = "bytes"
+		case "bytes", "records", "batches":
+		default:
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "metric must be bytes, records or batches")
+			return
+		}
+		minDays := defaultChangePointMinDays
+		if v := q.Get("min_days"); v != "" {
+			if minDays, err = strconv.Atoi(v); err != nil || minDays < 2 || minDays > maxChangePointMinDays {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "min_days must be a whole number from 2 to 30")
+				return
+			}
+		}
+		sensitivity := 1.0
+		if v := q.Get("sensitivity"); v != "" {
+			if sensitivity, err = strconv.ParseFloat(v, 64); err != nil || sensitivity <= 0 || math.IsInf(sensitivity, 0) {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "sensitivity must be a positive number")
+				return
+			}
+		}
+
+		today := now().UTC().Truncate(24 * time.Hour)
+		buckets, err := db.BucketsByTimeRange(today.Add(-time.Duration(days)*24*time.Hour), today, 24*time.Hour)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch daily volume")
+			return
+		}
+		sendSuccessResponse(w, detectChangePoints(buckets, today, metric, minDays, sensitivity))
+	}
+}
+
+// detectChangePoints fills the daily buckets from the first one to the day
+// before end and segments them into levels.
+func detectChangePoints(buckets []database.TimeBucket, end time.Time, metric string, minDays int, sensitivity float64) ChangePointReport {
+	report := ChangePointReport{Metric: metric, MinDays: minDays, Levels: []VolumeLevel{}, ChangePoints: []ChangePoint{}}
+	if len(buckets) == 0 {
+		return report
+	}
+	const day = 24 * time.Hour
+	first := buckets[0].Start
+	series := make([]float64, int(end.Sub(first)/day))
+	for _, b := range buckets {
+		v := b.Bytes
+		switch metric {
+		case "records":
+			v = b.Records
+		case "batches":
+			v = b.Batches
+		}
+		series[int(b.Start.Sub(first)/day)] = float64(v)
+	}
+	report.Start, report.End, report.Days = first.Format(time.DateOnly), end.Add(-day).Format(time.DateOnly), len(series)
+
+	report.Noise = dailyNoise(series)
+	report.Penalty = 2 * math.Log(float64(len(series))) / sensitivity
+	bounds := []int{0, len(series)}
+	if report.Noise > 0 {
+		bounds = segmentLevels(series, report.Noise, report.Penalty, minDays)
+	}
+
+	mean := func(from, to int) float64 {
+		var sum float64
+		for _, v := range series[from:to] {
+			sum += v
+		}
+		return sum / float64(to-from)
+	}
+	for i := 0; i+1 < len(bounds); i++ {
+		report.Levels = append(report.Levels, VolumeLevel{
+			Start: first.Add(time.Duration(bounds[i]) * day).Format(time.DateOnly),
+			End:   first.Add(time.Duration(bounds[i+1]-1) * day).Format(time.DateOnly),
+			Days:  bounds[i+1] - bounds[i],
+			Mean:  mean(bounds[i], bounds[i+1]),
+		})
+	}
+	for i := 1; i < len(report.Levels); i++ {
+		before, after := report.Levels[i-1], report.Levels[i]
+		cp := ChangePoint{Date: after.Start, Before: before.Mean, After: after.Mean, Delta: after.Mean - before.Mean}
+		if before.Mean != 0 {
+			pct := cp.Delta / before.Mean * 100
+			cp.ChangePct = &pct
+		}
+		cp.Score = cp.Delta / (report.Noise * math.Sqrt(1/float64(before.Days)+1/float64(after.Days)))
+		report.ChangePoints = append(report.ChangePoints, cp)
+	}
+	return report
+}
+
+// dailyNoise estimates the standard deviation of days around their level
+// from the median absolute difference between consecutive days, which a
+// few level shifts barely move. Series too flat for the median fall back
+// to the standard deviation of the differences.
+func dailyNoise(series []float64) float64 {
+	if len(series) < 2 {
+		return 0
+	}
+	diffs := make([]float64, len(series)-1)
+	var sumSq float64
+	for i := range diffs {
+		diffs[i] = math.Abs(series[i+1] - series[i])
+		sumSq += diffs[i] * diffs[i]
+	}
+	sort.Float64s(diffs)
+	median := diffs[len(diffs)/2]
+	if len(diffs)%2 == 0 {
+		median = (diffs[len(diffs)/2-1] + diffs[len(diffs)/2]) / 2
+	}
+	// For normal noise the difference of two days has standard deviation
+	// sigma*sqrt(2) and median absolute value 0.6745 times that
+	if sigma := median / (0.6745 * math.Sqrt2); sigma > 0 {
+		return sigma
+	}
+	return math.Sqrt(sumSq/float64(len(diffs))) / math.Sqrt2
+}
+
+// segmentLevels splits series into levels of at least minDays days by
+// minimizing the squared deviations from each level's mean, in units of
+// sigma squared, plus penalty per change point. It uses PELT (Killick et
+// al., 2012), which prunes split points that can no longer be optimal and
+// finds the exact optimum in close to linear time.
+//
+// Returns:
+//   - []int: Level boundaries: 0, the index of each change point's first
+//     day, and len(series)
+func segmentLevels(series []float64, sigma, penalty float64, minDays int) []int {
+	n := len(series)
+	if n < 2*minDays {
+		return []int{0, n}
+	}
+	sum, sumSq := make([]float64, n+1), make([]float64, n+1)
+	for i, v := range series {
+		v /= sigma
+		sum[i+1], sumSq[i+1] = sum[i]+v, sumSq[i]+v*v
+	}
+	cost := func(s, t int) float64 {
+		d := sum[t] - sum[s]
+		return sumSq[t] - sumSq[s] - d*d/float64(t-s)
+	}
+
+	best := make([]float64, n+1)
+	last := make([]int, n+1)
+	best[0] = -penalty
+	candidates := []int{0}
+	for t := minDays; t <= n; t++ {
+		// A split becomes possible once the level after it can be minDays
+		// long; before that it cannot end a level
+		if s := t - minDays; s >= minDays {
+			candidates = append(candidates, s)
+		}
+		best[t] = math.Inf(1)
+		for _, s := range candidates {
+			if v := best[s] + cost(s, t) + penalty; v < best[t] {
+				best[t], last[t] = v, s
+			}
+		}
+		kept := candidates[:0]
+		for _, s := range candidates {
+			if best[s]+cost(s, t) <= best[t] {
+				kept = append(kept, s)
+			}
+		}
+		candidates = kept
+	}
+
+	bounds := []int{n}
+	for t := n; t > 0; t = last[t] {
+		bounds = append(bounds, last[t])
+	}
+	for i, j := 0, len(bounds)-1; i < j; i, j = i+1, j-1 {
+		bounds[i], bounds[j] = bounds[j], bounds[i]
+	}
+	return bounds
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/testsupport"
+)
+
+func TestAPIChangePoints(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	first := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	SetClock(testsupport.NewClock(first.Add(60*24*time.Hour + 3*time.Hour)))
+	defer SetClock(nil)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/stats/change-points"]
+	get := func(query string) (int, ChangePointReport) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/stats/change-points"+query, nil))
+		var resp struct {
+			Data ChangePointReport `json:"data"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return rr.Code, resp.Data
+	}
+
+	if code, report := get(""); code != 200 || report.Days != 0 || len(report.ChangePoints) != 0 {
+		t.Errorf("Expected no analysis without records, got %d %+v", code, report)
+	}
+
+	// 60 noisy days whose volume halves on August 10th, when sampling was
+	// enabled
+	noise := []int64{0, 40, -30, 20, -10, 50, -40}
+	for d := 0; d < 60; d++ {
+		size := 2000 + noise[d%len(noise)]
+		if d >= 40 {
+			size = 1000 + noise[d%len(noise)]
+		}
+		if err := db.InsertLog(database.LogSize{Timestamp: first.Add(time.Duration(d)*24*time.Hour + time.Hour), Filesize: size, RecordCount: 10}); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+
+	code, report := get("")
+	if code != 200 || report.Days != 60 || report.Start != "2025-07-01" || report.End != "2025-08-29" {
+		t.Fatalf("Unexpected analysis %d %+v", code, report)
+	}
+	if len(report.ChangePoints) != 1 || len(report.Levels) != 2 {
+		t.Fatalf("Expected one change point, got %+v", report.ChangePoints)
+	}
+	cp := report.ChangePoints[0]
+	if cp.Date != "2025-08-10" || cp.Delta > -900 || cp.Delta < -1100 || cp.ChangePct == nil || cp.Score > -10 {
+		t.Errorf("Unexpected change point %+v", cp)
+	}
+
+	// Batches did not change
+	if _, report := get("?metric=batches"); len(report.ChangePoints) != 0 || len(report.Levels) != 1 {
+		t.Errorf("Expected a single level of batches, got %+v", report)
+	}
+	// The last ten days are all at the new level
+	if _, report := get("?days=10"); report.Days != 10 || len(report.ChangePoints) != 0 {
+		t.Errorf("Expected no change within the last ten days, got %+v", report)
+	}
+
+	for _, query := range []string{"?metric=cost", "?min_days=1", "?min_days=31", "?sensitivity=0", "?sensitivity=x"} {
+		if code, _ := get(query); code != 400 {
+			t.Errorf("Expected 400 for %s, got %d", query, code)
+		}
+	}
+}
+
+func TestSegmentLevels(t *testing.T) {
+	series := []float64{10, 11, 9, 10, 30, 31, 29, 30, 10, 9, 11, 10}
+	bounds := segmentLevels(series, 1, 2*2.48, 3)
+	if len(bounds) != 4 || bounds[1] != 4 || bounds[2] != 8 {
+		t.Errorf("Expected levels split at 4 and 8, got %v", bounds)
+	}
+	// Levels shorter than minDays are not reported
+	bounds = segmentLevels(series, 1, 5, 5)
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i]-bounds[i-1] < 5 {
+			t.Errorf("Expected levels of at least 5 days, got %v", bounds)
+		}
+	}
+	if bounds := segmentLevels(series, 1, 5, 7); len(bounds) != 2 {
+		t.Errorf("Expected no split of 12 days into levels of 7, got %v", bounds)
+	}
+}
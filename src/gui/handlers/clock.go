@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/clock"
+)
+
+// handlerClock is the clock set by SetClock.
+var handlerClock clock.Source
+
+// SetClock replaces the source of the current time that handlers use for
+// windows ending now, such as the default last 24 hours, so tests can
+// produce reproducible responses from fixed data.
+//
+// Parameters:
+//   - c: Clock to read, or nil for the system time
+func SetClock(c clock.Clock) {
+	handlerClock.Set(c)
+}
+
+// now returns the current time from the clock set by SetClock.
+func now() time.Time {
+	return handlerClock.Now()
+}
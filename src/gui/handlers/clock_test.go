@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/testsupport"
+)
+
+// TestClockSimulatesWeeksAcrossDST ingests one record an hour for three
+// weeks spanning the US spring DST change on a simulated clock shared by the
+// database and handlers, then checks that windows ending now cover elapsed
+// time rather than wall-clock hours.
+func TestClockSimulatesWeeksAcrossDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone data unavailable: %v", err)
+	}
+	// Noon EDT on the day clocks went forward; local midnight was 11 hours ago.
+	end := time.Date(2024, time.March, 10, 12, 0, 0, 0, newYork)
+	clock := testsupport.NewClock(end.Add(-21*24*time.Hour + 30*time.Minute))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	db := testsupport.NewDatabase(t)
+	db.SetClock(clock)
+	db.SetRecentBufferSize(0)
+	for i := 0; i < 21*24; i++ {
+		if err := db.InsertLogSize(1024); err != nil {
+			t.Fatalf("Failed to insert log size: %v", err)
+		}
+		clock.Advance(time.Hour)
+	}
+	clock.Set(end)
+	last := end.Add(-30 * time.Minute)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+
+	tests := []struct {
+		target string
+		want   int64
+	}{
+		{"/api/stats/summary?hours=24", 24},
+		{"/api/stats/summary?hours=168", 168},
+		{"/api/stats/summary?last=12h", 12},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handlers["/api/stats/summary"](rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		var resp struct {
+			Data LogSizeStats `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: invalid response %s", tt.target, rec.Body.String())
+		}
+		if resp.Data.TotalRecords != tt.want {
+			t.Errorf("%s: expected %d records, got %d", tt.target, tt.want, resp.Data.TotalRecords)
+		}
+		if resp.Data.LastUpdated != last.UTC().Format(time.RFC3339) {
+			t.Errorf("%s: expected last update %s, got %s", tt.target, last.UTC().Format(time.RFC3339), resp.Data.LastUpdated)
+		}
+	}
+}
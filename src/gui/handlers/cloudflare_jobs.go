@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/melatonein5/LogpushEstimator/src/cloudflare"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// LogpushJobRequest is the request body for /api/cloudflare/jobs/create.
+type LogpushJobRequest struct {
+	Dataset     string `json:"dataset"`           // Logpush dataset, e.g. "http_requests"
+	ZoneID      string `json:"zone_id,omitempty"` // Zone for zone-scoped datasets; defaults to the only configured zone
+	PublicURL   string `json:"public_url"`        // HTTPS base URL of this instance's ingestion server
+	IngestToken string `json:"ingest_token"`      // Token Cloudflare sends as an Authorization bearer header
+	Name        string `json:"name,omitempty"`    // Job name; defaults to logpush-estimator-<dataset>
+	Enabled     *bool  `json:"enabled,omitempty"` // Whether the job starts pushing immediately (default true)
+}
+
+// LogpushJobResult is the response body for /api/cloudflare/jobs/create.
+// Destinations are always shown with their credentials redacted.
+type LogpushJobResult struct {
+	DryRun bool                  `json:"dry_run"` // True when the job was only previewed
+	Scope  cloudflare.Scope      `json:"scope"`   // Zone or account the job belongs to
+	Job    cloudflare.LogpushJob `json:"job"`     // Job as sent (dry run) or as created
+}
+
+// MakeLogpushJobCreateHandler creates the POST /api/cloudflare/jobs/create
+// handler, which builds a Logpush job pushing dataset to this instance's
+// /ingest endpoint and creates it through the Cloudflare API. With
+// dry_run=true the job is only previewed, which works without an API token.
+// Created jobs are recorded so their health can be tracked.
+//
+// Parameters:
+//   - client: Cloudflare API client, or nil when the integration is not configured
+//   - settings: Cloudflare settings supplying the account and default zone
+//   - db: Database controller jobs are recorded in
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function
+func MakeLogpushJobCreateHandler(client *cloudflare.Client, settings cloudflare.Settings, db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: create logpush job", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		var req LogpushJobRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid JSON body")
+			return
+		}
+		scope, job, err := buildLogpushJob(req, settings)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if isDryRun(r) {
+			job.DestinationConf = cloudflare.RedactDestination(job.DestinationConf)
+			sendSuccessResponse(w, LogpushJobResult{DryRun: true, Scope: scope, Job: job})
+			return
+		}
+		if client == nil {
+			sendErrorResponseWithStatus(w, http.StatusServiceUnavailable, "Cloudflare integration is not configured")
+			return
+		}
+
+		created, err := client.CreateLogpushJob(r.Context(), scope, job)
+		if err != nil {
+			logger.Error("Failed to create logpush job", "error", err, "dataset", job.Dataset)
+			var apiErr *cloudflare.APIError
+			if errors.As(err, &apiErr) {
+				sendErrorResponseWithStatus(w, http.StatusBadGateway, apiErr.Error())
+				return
+			}
+			sendErrorResponseWithStatus(w, http.StatusBadGateway, "Failed to reach the Cloudflare API")
+			return
+		}
+		created.DestinationConf = cloudflare.RedactDestination(created.DestinationConf)
+
+		if err := db.SaveLogpushJob(database.LogpushJob{
+			ID:          created.ID,
+			Scope:       scope.Kind,
+			ScopeID:     scope.ID,
+			Name:        created.Name,
+			Dataset:     created.Dataset,
+			Destination: created.DestinationConf,
+			Enabled:     created.Enabled,
+		}); err != nil {
+			// The job exists at Cloudflare regardless, so report it rather
+			// than an error the caller might retry into a duplicate job
+			logger.Error("Failed to record created logpush job", "error", err, "id", created.ID)
+		}
+		sendSuccessResponse(w, LogpushJobResult{Scope: scope, Job: created})
+	}
+}
+
+// buildLogpushJob validates req and resolves the scope and job definition.
+func buildLogpushJob(req LogpushJobRequest, settings cloudflare.Settings) (cloudflare.Scope, cloudflare.LogpushJob, error) {
+	kind, ok := cloudflare.DatasetScope(req.Dataset)
+	if !ok {
+		return cloudflare.Scope{}, cloudflare.LogpushJob{}, errors.New("unknown dataset: " + req.Dataset)
+	}
+
+	scope := cloudflare.Scope{Kind: kind}
+	switch kind {
+	case cloudflare.ScopeZone:
+		scope.ID = req.ZoneID
+		if scope.ID == "" && len(settings.Zones) == 1 {
+			scope.ID = settings.Zones[0]
+		}
+		if scope.ID == "" {
+			return scope, cloudflare.LogpushJob{}, errors.New("zone_id is required for zone-scoped datasets")
+		}
+	case cloudflare.ScopeAccount:
+		scope.ID = settings.AccountID
+		if scope.ID == "" {
+			return scope, cloudflare.LogpushJob{}, errors.New("LPE_CLOUDFLARE_ACCOUNT_ID is required for account-scoped datasets")
+		}
+	}
+
+	destination, err := cloudflare.HTTPDestination(req.PublicURL, req.Dataset, req.IngestToken)
+	if err != nil {
+		return scope, cloudflare.LogpushJob{}, err
+	}
+	job := cloudflare.LogpushJob{
+		Name:            req.Name,
+		Dataset:         req.Dataset,
+		DestinationConf: destination,
+		Enabled:         req.Enabled == nil || *req.Enabled,
+	}
+	if job.Name == "" {
+		job.Name = "logpush-estimator-" + strings.ReplaceAll(req.Dataset, "_", "-")
+	}
+	return scope, job, nil
+}
+
+// LogpushJobStatus is a tracked job together with its health classification.
+type LogpushJobStatus struct {
+	database.LogpushJob
+	Health string `json:"health"` // unknown, disabled, pending, healthy or failing
+}
+
+// makeLogpushJobsHandler serves GET /api/cloudflare/jobs, listing every
+// tracked Logpush job with the status last synced from Cloudflare.
+func makeLogpushJobsHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: logpush jobs", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		jobs, err := db.ListLogpushJobs()
+		if err != nil {
+			sendErrorResponse(w, "Failed to list logpush jobs")
+			return
+		}
+		statuses := make([]LogpushJobStatus, 0, len(jobs))
+		for _, j := range jobs {
+			statuses = append(statuses, LogpushJobStatus{LogpushJob: j, Health: j.Health()})
+		}
+		sendSuccessResponse(w, statuses)
+	}
+}
+
+// makeLogpushJobHealthHandler serves GET /api/cloudflare/jobs/{id}/health,
+// returning one tracked job's last synced status.
+func makeLogpushJobHealthHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: logpush job health", "remote_addr", r.RemoteAddr, "path", r.URL.Path)
+
+		idStr, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/cloudflare/jobs/"), "/health")
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if !ok || err != nil {
+			sendErrorResponseWithStatus(w, http.StatusNotFound, "Not found")
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		job, found, err := db.GetLogpushJob(id)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch logpush job")
+			return
+		}
+		if !found {
+			sendErrorResponseWithStatus(w, http.StatusNotFound, "Logpush job not found")
+			return
+		}
+		sendSuccessResponse(w, LogpushJobStatus{LogpushJob: job, Health: job.Health()})
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/cloudflare"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestLogpushJobCreateDryRun(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// A dry run needs no API client
+	handler := MakeLogpushJobCreateHandler(nil, cloudflare.Settings{Zones: []string{"zone-a"}}, db, logger)
+	body := `{"dataset":"http_requests","public_url":"https://estimator.example.com","ingest_token":"secret"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/cloudflare/jobs/create?dry_run=true", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "secret") {
+		t.Error("Expected the ingest token to be redacted from the preview")
+	}
+	var resp struct {
+		Data LogpushJobResult `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if !resp.Data.DryRun || resp.Data.Scope.ID != "zone-a" || resp.Data.Job.Name != "logpush-estimator-http-requests" {
+		t.Errorf("Unexpected preview %+v", resp.Data)
+	}
+
+	jobs, _ := db.ListLogpushJobs()
+	if len(jobs) != 0 {
+		t.Errorf("Expected a dry run to record nothing, got %+v", jobs)
+	}
+}
+
+func TestLogpushJobCreate(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	var sent cloudflare.LogpushJob
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/acct/logpush/jobs" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&sent)
+		created := sent
+		created.ID = 99
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "result": created})
+	}))
+	defer server.Close()
+	client := cloudflare.NewClient("token")
+	client.BaseURL = server.URL
+
+	handler := MakeLogpushJobCreateHandler(client, cloudflare.Settings{APIToken: "token", AccountID: "acct"}, db, logger)
+	body := `{"dataset":"audit_logs","public_url":"https://estimator.example.com","ingest_token":"secret","enabled":false}`
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodPost, "/api/cloudflare/jobs/create", strings.NewReader(body)))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(sent.DestinationConf, "Bearer+secret") || sent.Enabled {
+		t.Errorf("Unexpected job sent to Cloudflare %+v", sent)
+	}
+	jobs, err := db.ListLogpushJobs()
+	if err != nil {
+		t.Fatalf("Failed to list jobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != 99 || jobs[0].Scope != "accounts" || strings.Contains(jobs[0].Destination, "secret") {
+		t.Errorf("Expected the created job to be recorded redacted, got %+v", jobs)
+	}
+}
+
+func TestLogpushJobCreateValidation(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeLogpushJobCreateHandler(nil, cloudflare.Settings{}, db, logger)
+
+	tests := []struct {
+		name   string
+		query  string
+		body   string
+		status int
+	}{
+		{"unknown dataset", "?dry_run=true", `{"dataset":"bogus","public_url":"https://e.example.com"}`, http.StatusBadRequest},
+		{"missing zone", "?dry_run=true", `{"dataset":"http_requests","public_url":"https://e.example.com"}`, http.StatusBadRequest},
+		{"missing account", "?dry_run=true", `{"dataset":"audit_logs","public_url":"https://e.example.com"}`, http.StatusBadRequest},
+		{"plain http", "?dry_run=true", `{"dataset":"http_requests","zone_id":"z","public_url":"http://e.example.com"}`, http.StatusBadRequest},
+		{"not configured", "", `{"dataset":"http_requests","zone_id":"z","public_url":"https://e.example.com"}`, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest(http.MethodPost, "/api/cloudflare/jobs/create"+tt.query, strings.NewReader(tt.body)))
+			if rr.Code != tt.status {
+				t.Errorf("Expected %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestLogpushJobHealthHandler(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	db.SaveLogpushJob(database.LogpushJob{ID: 5, Scope: "zones", ScopeID: "zone-a", Name: "job", Dataset: "http_requests", Destination: "https://example.com/ingest", Enabled: true})
+	failed := time.Now().UTC().Add(-time.Minute)
+	db.UpdateLogpushJobHealth(database.LogpushJob{ID: 5, Enabled: true, LastError: &failed, ErrorMessage: "destination returned 401"})
+
+	handlers := MakeAPIHandlers(db, logger)
+	rr := httptest.NewRecorder()
+	handlers["/api/cloudflare/jobs/"].ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/cloudflare/jobs/5/health", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Data LogpushJobStatus `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if resp.Data.Health != database.JobHealthFailing || resp.Data.ErrorMessage != "destination returned 401" {
+		t.Errorf("Expected a failing job, got %+v", resp.Data)
+	}
+
+	rr = httptest.NewRecorder()
+	handlers["/api/cloudflare/jobs"].ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/cloudflare/jobs", nil))
+	var list struct {
+		Data []LogpushJobStatus `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Data) != 1 || list.Data[0].Health != database.JobHealthFailing {
+		t.Errorf("Expected the job in the list, got %+v", list.Data)
+	}
+
+	for path, status := range map[string]int{
+		"/api/cloudflare/jobs/6/health":   http.StatusNotFound,
+		"/api/cloudflare/jobs/abc/health": http.StatusNotFound,
+		"/api/cloudflare/jobs/5":          http.StatusNotFound,
+	} {
+		rr := httptest.NewRecorder()
+		handlers["/api/cloudflare/jobs/"].ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != status {
+			t.Errorf("%s: expected %d, got %d", path, status, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// LogCountResponse is the response body for /api/logs/count.
+type LogCountResponse struct {
+	database.LogCount
+	Start string `json:"start,omitempty"` // Start of the counted range (RFC3339); omitted when open
+	End   string `json:"end,omitempty"`   // End of the counted range (RFC3339); omitted when open
+}
+
+// makeLogsCountHandler serves /api/logs/count, returning the number of
+// batches, records and bytes in a range (last=, start=/end= or hours=, or
+// the whole history) without the records themselves. The totals are also
+// sent as X-Batch-Count, X-Record-Count and X-Total-Bytes headers, so HEAD
+// requests get them without a body.
+func makeLogsCountHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: logs count", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		start, end, err := parseOptionalRange(r)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		count, err := db.CountByTimeRange(start, end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to count logs")
+			return
+		}
+
+		response := LogCountResponse{LogCount: count}
+		if !start.IsZero() {
+			response.Start = start.UTC().Format(time.RFC3339)
+			response.End = end.UTC().Format(time.RFC3339)
+		}
+		w.Header().Set("X-Batch-Count", strconv.FormatInt(count.Batches, 10))
+		w.Header().Set("X-Record-Count", strconv.FormatInt(count.Records, 10))
+		w.Header().Set("X-Total-Bytes", strconv.FormatInt(count.Bytes, 10))
+		sendSuccessResponse(w, response)
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestAPILogsCount(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/logs/count"]
+
+	for _, target := range []string{"/api/logs/count", "/api/logs/count?last=1h"} {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", target, rr.Code, rr.Body.String())
+		}
+		var response struct {
+			Data LogCountResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if response.Data.Batches != 5 || response.Data.Bytes != 1024+2048+4096+8192+16384 {
+			t.Errorf("%s: unexpected totals %+v", target, response.Data)
+		}
+		if rr.Header().Get("X-Batch-Count") != "5" {
+			t.Errorf("%s: expected X-Batch-Count 5, got %q", target, rr.Header().Get("X-Batch-Count"))
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("HEAD", "/api/logs/count", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("X-Total-Bytes") != "31744" {
+		t.Errorf("Expected totals in HEAD headers, got %d %v", rr.Code, rr.Header())
+	}
+
+	for _, target := range []string{"/api/logs/count?start=soon&end=later", "/api/logs/count?start=2025-09-15T00:00:00Z"} {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rr.Code)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("POST", "/api/logs/count", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// defaultCoverageThreshold is the observed/expected ratio below which an
+// hour is reported as a gap when no threshold parameter is supplied.
+const defaultCoverageThreshold = 0.9
+
+// maxCoverageHours bounds the coverage window.
+const maxCoverageHours = 24 * 31
+
+// CoveragePoint compares one hour of ingested records with the requests
+// Cloudflare's analytics reported for the configured zones.
+type CoveragePoint struct {
+	Timestamp        string   `json:"timestamp"`         // ISO timestamp for the start of the hour
+	ObservedRecords  int64    `json:"observed_records"`  // Records received through Logpush
+	ExpectedRequests int64    `json:"expected_requests"` // Requests reported by zone analytics
+	Ratio            *float64 `json:"ratio"`             // Observed / expected; null without analytics
+	Gap              bool     `json:"gap"`               // Ratio below the threshold
+}
+
+// CoverageReport is the response body for /api/estimates/coverage.
+type CoverageReport struct {
+	Hours            int             `json:"hours"`             // Size of the window in complete hours
+	Threshold        float64         `json:"threshold"`         // Ratio below which an hour is a gap
+	ObservedRecords  int64           `json:"observed_records"`  // Records received in hours with analytics
+	ExpectedRequests int64           `json:"expected_requests"` // Requests reported in the window
+	Ratio            *float64        `json:"ratio"`             // Overall observed / expected
+	Gaps             int             `json:"gaps"`              // Number of hours flagged as gaps
+	Points           []CoveragePoint `json:"points"`            // One entry per hour, oldest first
+}
+
+// makeCoverageHandler serves /api/estimates/coverage, comparing the records
+// received per hour with the zone request counts synced from Cloudflare's
+// GraphQL Analytics API. Hours whose ratio falls below `threshold` (default
+// 0.9) point at sampling, filters or a broken Logpush job. The window covers
+// the last `hours` complete hours (default 24).
+func makeCoverageHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: coverage", "remote_addr", r.RemoteAddr)
+
+		hours := 24
+		if h, err := strconv.Atoi(r.URL.Query().Get("hours")); err == nil && h > 0 {
+			hours = min(h, maxCoverageHours)
+		}
+		threshold := defaultCoverageThreshold
+		if thresholdStr := r.URL.Query().Get("threshold"); thresholdStr != "" {
+			t, err := strconv.ParseFloat(thresholdStr, 64)
+			if err != nil || t <= 0 {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "threshold must be a positive number")
+				return
+			}
+			threshold = t
+		}
+
+		// The current hour is still filling on both sides, so only complete
+		// hours are compared
+		end := now().UTC().Truncate(time.Hour)
+		start := end.Add(-time.Duration(hours) * time.Hour)
+
+		logs, err := db.QueryByTimeRange(start, end)
+		if err != nil {
+			logger.Error("Failed to query logs for coverage", "error", err)
+			sendErrorResponse(w, "Failed to fetch coverage data")
+			return
+		}
+		traffic, err := db.QueryZoneTraffic(start, end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch zone traffic")
+			return
+		}
+
+		sendSuccessResponse(w, buildCoverageReport(logs, traffic, start, hours, threshold))
+	}
+}
+
+// buildCoverageReport buckets observed records and expected requests by hour.
+// Hours without analytics data have no ratio and are never gaps, so the
+// report stays meaningful before the first sync or for unsynced periods.
+func buildCoverageReport(logs []database.LogSize, traffic []database.ZoneTrafficHour, start time.Time, hours int, threshold float64) CoverageReport {
+	observed := make(map[int64]int64)
+	for _, l := range logs {
+		observed[l.Timestamp.UTC().Truncate(time.Hour).Unix()] += l.RecordCount
+	}
+	expected := make(map[int64]int64)
+	for _, t := range traffic {
+		expected[t.Hour.UTC().Truncate(time.Hour).Unix()] += t.Requests
+	}
+
+	report := CoverageReport{Hours: hours, Threshold: threshold, Points: make([]CoveragePoint, 0, hours)}
+	for i := 0; i < hours; i++ {
+		hour := start.Add(time.Duration(i) * time.Hour)
+		key := hour.Unix()
+		point := CoveragePoint{
+			Timestamp:        hour.Format(time.RFC3339),
+			ObservedRecords:  observed[key],
+			ExpectedRequests: expected[key],
+		}
+		if point.ExpectedRequests > 0 {
+			ratio := float64(point.ObservedRecords) / float64(point.ExpectedRequests)
+			point.Ratio = &ratio
+			point.Gap = ratio < threshold
+			report.ObservedRecords += point.ObservedRecords
+			report.ExpectedRequests += point.ExpectedRequests
+			if point.Gap {
+				report.Gaps++
+			}
+		}
+		report.Points = append(report.Points, point)
+	}
+	if report.ExpectedRequests > 0 {
+		ratio := float64(report.ObservedRecords) / float64(report.ExpectedRequests)
+		report.Ratio = &ratio
+	}
+	return report
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestBuildCoverageReport(t *testing.T) {
+	start := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	logs := []database.LogSize{
+		{Timestamp: start.Add(5 * time.Minute), RecordCount: 60},
+		{Timestamp: start.Add(40 * time.Minute), RecordCount: 40},
+		{Timestamp: start.Add(70 * time.Minute), RecordCount: 50},
+	}
+	traffic := []database.ZoneTrafficHour{
+		{Zone: "a", Hour: start, Requests: 60},
+		{Zone: "b", Hour: start, Requests: 40},
+		{Zone: "a", Hour: start.Add(time.Hour), Requests: 100},
+	}
+
+	report := buildCoverageReport(logs, traffic, start, 3, 0.9)
+
+	if len(report.Points) != 3 {
+		t.Fatalf("Expected 3 hourly points, got %d", len(report.Points))
+	}
+	if p := report.Points[0]; p.Ratio == nil || *p.Ratio != 1 || p.Gap {
+		t.Errorf("Expected full coverage in the first hour, got %+v", p)
+	}
+	if p := report.Points[1]; p.Ratio == nil || *p.Ratio != 0.5 || !p.Gap {
+		t.Errorf("Expected a gap in the second hour, got %+v", p)
+	}
+	if p := report.Points[2]; p.Ratio != nil || p.Gap {
+		t.Errorf("Expected no ratio without analytics, got %+v", p)
+	}
+	if report.Gaps != 1 || report.ExpectedRequests != 200 || report.ObservedRecords != 150 {
+		t.Errorf("Unexpected totals %+v", report)
+	}
+}
+
+func TestAPICoverageWithoutAnalytics(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/estimates/coverage"]
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/estimates/coverage?hours=6", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	var resp struct {
+		Data CoverageReport `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Data.Points) != 6 || resp.Data.Ratio != nil || resp.Data.Gaps != 0 {
+		t.Errorf("Expected 6 points without ratios, got %+v", resp.Data)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/estimates/coverage?threshold=-1", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative threshold, got %d", rr.Code)
+	}
+}
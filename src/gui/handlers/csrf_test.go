@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestWithCSRFProtection(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := WithCSRFProtection(logger, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	session := &http.Cookie{Name: preferencesCookie, Value: "browser"}
+	csrf := &http.Cookie{Name: csrfCookie, Value: "token"}
+
+	tests := []struct {
+		name    string
+		method  string
+		cookies []*http.Cookie
+		header  string
+		want    int
+	}{
+		{"Safe method with session", "GET", []*http.Cookie{session}, "", http.StatusNoContent},
+		{"API client without session", "POST", nil, "", http.StatusNoContent},
+		{"Session without token", "PUT", []*http.Cookie{session, csrf}, "", http.StatusForbidden},
+		{"Session with wrong token", "DELETE", []*http.Cookie{session, csrf}, "other", http.StatusForbidden},
+		{"Session without CSRF cookie", "POST", []*http.Cookie{session}, "token", http.StatusForbidden},
+		{"Session with matching token", "POST", []*http.Cookie{session, csrf}, "token", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/views", nil)
+			for _, c := range tt.cookies {
+				req.AddCookie(c)
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeader, tt.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, rr.Code)
+			}
+		})
+	}
+}
+
+func TestDashboardIssuesCSRFToken(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	rr := httptest.NewRecorder()
+	MakeDashboardHandlerWithPreferences(db, logger).ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	var issued *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == csrfCookie {
+			issued = c
+		}
+	}
+	if issued == nil || len(issued.Value) != 64 || issued.SameSite != http.SameSiteStrictMode {
+		t.Fatalf("Expected a strict CSRF cookie to be issued, got %+v", issued)
+	}
+
+	// An existing token is reused rather than rotated under an open page
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(issued)
+	rr = httptest.NewRecorder()
+	MakeDashboardHandlerWithPreferences(db, logger).ServeHTTP(rr, req)
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == csrfCookie {
+			t.Errorf("Expected the existing CSRF token to be kept, got a new one")
+		}
+	}
+}
@@ -27,8 +27,9 @@
 // # Template Requirements
 //
 // The dashboard handler expects to find HTML templates in the
-// 'src/gui/templates/' directory relative to the application root.
-// Static files should be organized under 'src/gui/static/'.
+// 'src/gui/templates/' directory relative to the application root, unless
+// SetTemplateOverrideDir names a directory holding a replacement
+// dashboard.html. Static files should be organized under 'src/gui/static/'.
 package handlers
 
 import (
@@ -40,6 +41,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/i18n"
 )
 
 // MakeDashboardHandler creates an HTTP handler for serving the main dashboard interface.
@@ -61,22 +67,146 @@ import (
 func MakeDashboardHandler(logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger.Info("Dashboard request", "remote_addr", r.RemoteAddr, "path", r.URL.Path)
+		renderDashboard(w, r, logger, newDashboardData(database.DefaultPreferences()))
+	}
+}
 
-		// Parse the dashboard template
-		tmpl, err := template.ParseFiles("src/gui/templates/dashboard.html")
-		if err != nil {
-			logger.Error("Failed to parse dashboard template", "error", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return
+// MakeDashboardHandlerWithPreferences creates a dashboard handler that renders
+// the page using the caller's saved preferences (default time range, units,
+// timezone). Callers without a browser token are issued one so preferences
+// saved from the page are tied to the same browser, together with the CSRF
+// token the page's scripts send on state-changing requests.
+//
+// Parameters:
+//   - db: Database controller used to load preferences
+//   - logger: Structured logger for request logging and error reporting
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function for dashboard requests
+func MakeDashboardHandlerWithPreferences(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("Dashboard request", "remote_addr", r.RemoteAddr, "path", r.URL.Path)
+
+		prefs := database.DefaultPreferences()
+		if token, err := preferencesToken(w, r); err != nil {
+			logger.Error("Failed to issue preferences token", "error", err)
+		} else if saved, _, err := db.GetPreferences(token); err != nil {
+			logger.Error("Failed to load preferences for dashboard", "error", err)
+		} else {
+			prefs = saved
 		}
 
-		w.Header().Set("Content-Type", "text/html")
-		err = tmpl.Execute(w, nil)
-		if err != nil {
-			logger.Error("Failed to execute dashboard template", "error", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return
+		data := newDashboardData(prefs)
+		data.Units = displayUnits(db, prefs.Units)
+		var err error
+		if data.CSRFToken, err = csrfToken(w, r); err != nil {
+			logger.Error("Failed to issue CSRF token", "error", err)
 		}
+		renderDashboard(w, r, logger, data)
+	}
+}
+
+// RangeOption is a selectable entry in the dashboard time range menu.
+type RangeOption struct {
+	Hours int    // Length of the range in hours
+	Label string // Text shown in the menu
+}
+
+// DashboardData is the data passed to the dashboard template.
+type DashboardData struct {
+	Preferences  database.Preferences // Preferences applied to the initial view
+	RangeOptions []RangeOption        // Time range menu entries
+	View         *database.SavedView  // Saved view being rendered, nil for the plain dashboard
+	CSRFToken    string               // Token scripts send in the X-CSRF-Token header
+	Tenant       string               // Tenant the page is scoped to, empty for the whole instance
+	Locale       string               // Language the page is rendered in, set by renderDashboard
+	Units        config.Units         // Units sizes are shown in: the preference, or the instance's
+}
+
+// newDashboardData builds template data for prefs, adding the preferred time
+// range to the menu when it is not one of the standard options.
+func newDashboardData(prefs database.Preferences) DashboardData {
+	var options []RangeOption
+	for _, hours := range []int{1, 6, 24, 168, 720} {
+		options = append(options, RangeOption{hours, rangeLabel(i18n.For(i18n.Default), hours)})
+	}
+	found := false
+	for _, o := range options {
+		if o.Hours == prefs.DefaultRangeHours {
+			found = true
+			break
+		}
+	}
+	if !found && prefs.DefaultRangeHours > 0 {
+		options = append(options, RangeOption{prefs.DefaultRangeHours, rangeLabel(i18n.For(i18n.Default), prefs.DefaultRangeHours)})
+	}
+	units := config.Units(prefs.Units)
+	if units == "" {
+		units = config.UnitsDecimal
+	}
+	return DashboardData{Preferences: prefs, RangeOptions: options, Units: units}
+}
+
+// dashboardTemplate is the built-in dashboard template.
+const dashboardTemplate = "src/gui/templates/dashboard.html"
+
+// templateOverrideDir holds the directory set by SetTemplateOverrideDir.
+var templateOverrideDir atomic.Value
+
+// SetTemplateOverrideDir sets a directory whose dashboard.html, when
+// present, is rendered instead of the built-in dashboard template. The file
+// is looked up on every request, so it can be added or edited without a
+// restart.
+//
+// Parameters:
+//   - dir: Override directory, or empty to always use the built-in template
+func SetTemplateOverrideDir(dir string) {
+	templateOverrideDir.Store(dir)
+}
+
+// dashboardTemplatePath returns the dashboard template to render.
+func dashboardTemplatePath() string {
+	if dir, _ := templateOverrideDir.Load().(string); dir != "" {
+		override := filepath.Join(dir, "dashboard.html")
+		if _, err := os.Stat(override); err == nil {
+			return override
+		}
+	}
+	return dashboardTemplate
+}
+
+// renderDashboard parses and executes the dashboard template with data, in
+// the locale from the caller's preferences or Accept-Language header. The
+// template translates its text with the "t" function (see the i18n package).
+func renderDashboard(w http.ResponseWriter, r *http.Request, logger *slog.Logger, data DashboardData) {
+	locale, err := requestLocale(r, data.Preferences.Locale)
+	if err != nil {
+		// An unsupported ?lang= renders in English rather than failing the page
+		locale = i18n.Default
+	}
+	p := i18n.For(locale)
+	data.Locale = p.Locale()
+	for i := range data.RangeOptions {
+		data.RangeOptions[i].Label = rangeLabel(p, data.RangeOptions[i].Hours)
+	}
+
+	// Parse the dashboard template
+	path := dashboardTemplatePath()
+	tmpl, err := template.New(filepath.Base(path)).Funcs(template.FuncMap{"t": p.T}).ParseFiles(path)
+	if err != nil {
+		logger.Error("Failed to parse dashboard template", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Content-Language", data.Locale)
+	w.Header().Add("Vary", "Accept-Language")
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		logger.Error("Failed to execute dashboard template", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 }
 
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+)
+
+// datasetScopedPaths lists the API routes that accept ?dataset=, ?zone= and
+// ?label= to only read the records of one Logpush dataset, Cloudflare zone
+// or set of labels.
+var datasetScopedPaths = []string{
+	"/api/stats/summary",
+	"/api/logs/recent",
+	"/api/logs/range",
+	"/api/logs/since",
+	"/api/logs/count",
+	"/api/charts/timeseries",
+	"/api/charts/breakdown",
+	"/api/stats/records",
+	"/api/charts/record-sizes",
+	"/api/charts/record-size-breakdown",
+	"/api/charts/minutes",
+	"/api/stats/dimensions",
+	"/api/charts/by-dimension",
+	"/api/stats/bursts",
+	"/api/estimates/bandwidth",
+	"/api/estimate/forecast",
+	"/api/stats/change-points",
+	"/api/datasets",
+	"/api/zones",
+	"/api/labels",
+	"/api/export",
+}
+
+// maxDatasetScopes bounds how many dataset, zone and label combinations keep
+// their scoped handlers; beyond it the handlers are rebuilt, since the names
+// come from requests.
+const maxDatasetScopes = 64
+
+// recordScope is a dataset, zone and label filter; each is empty when
+// unused. Labels are key=value pairs ordered by key and joined by commas.
+type recordScope struct {
+	dataset string
+	zone    string
+	labels  string
+}
+
+// datasetRouter serves ?dataset=, ?zone= and ?label= requests from API
+// handlers built over a controller scoped with ForDataset, ForZone and
+// ForLabels, the way TenantRouter serves tenants. They share the unscoped
+// handlers' cache, encryption key and limiter.
+type datasetRouter struct {
+	db      *database.SQLiteController
+	logger  *slog.Logger
+	cache   *StatsCache         // Shared with the unscoped handlers, or nil
+	key     *encryption.Key     // Key for sealed archives and exports, or nil
+	limiter *ConcurrencyLimiter // Shared with the unscoped handlers
+
+	mu       sync.Mutex
+	datasets map[recordScope]map[string]http.HandlerFunc // Dataset, zone and labels to scoped API handlers
+}
+
+// wrap returns next for requests without ?dataset=, ?zone= or ?label=, and
+// otherwise the handler for path scoped to the named dataset, zone and
+// labels.
+func (d *datasetRouter) wrap(path string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		labels, err := labelFilters(r)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		scope := recordScope{dataset: r.URL.Query().Get("dataset"), zone: r.URL.Query().Get("zone"), labels: labels}
+		if scope == (recordScope{}) {
+			next(w, r)
+			return
+		}
+		if scope.dataset != "" && !ingest.ValidDataset(scope.dataset) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid dataset name")
+			return
+		}
+		if scope.zone != "" && !ingest.ValidZone(scope.zone) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid zone name")
+			return
+		}
+		d.handlers(scope)[path](w, r)
+	}
+}
+
+// handlers returns scope's API handlers, building them on first use.
+func (d *datasetRouter) handlers(scope recordScope) map[string]http.HandlerFunc {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if scoped, ok := d.datasets[scope]; ok {
+		return scoped
+	}
+	if d.datasets == nil || len(d.datasets) >= maxDatasetScopes {
+		d.datasets = make(map[recordScope]map[string]http.HandlerFunc)
+	}
+	logger := d.logger
+	if scope.dataset != "" {
+		logger = logger.With("dataset", scope.dataset)
+	}
+	if scope.zone != "" {
+		logger = logger.With("zone", scope.zone)
+	}
+	labels := make(map[string]string)
+	if scope.labels != "" {
+		logger = logger.With("labels", scope.labels)
+		for _, pair := range strings.Split(scope.labels, ",") {
+			key, value, _ := strings.Cut(pair, "=")
+			labels[key] = value
+		}
+	}
+	scoped := MakeAPIHandlersWithLimiter(d.db.ForDataset(scope.dataset).ForZone(scope.zone).ForLabels(labels), logger, d.cache, d.key, d.limiter)
+	d.datasets[scope] = scoped
+	return scoped
+}
+
+// labelFilters reads the ?label=key:value filters of r, which may be
+// repeated to require several labels, as key=value pairs ordered by key and
+// joined by commas; empty without filters.
+//
+// Parameters:
+//   - r: Incoming request
+//
+// Returns:
+//   - string: Encoded label filter
+//   - error: *requestError if a filter is malformed, names a key twice or
+//     there are more than ingest.MaxLabels
+func labelFilters(r *http.Request) (string, error) {
+	filters := r.URL.Query()["label"]
+	if len(filters) > ingest.MaxLabels {
+		return "", &requestError{fmt.Sprintf("At most %d label filters are allowed", ingest.MaxLabels)}
+	}
+	seen := make(map[string]bool, len(filters))
+	pairs := make([]string, 0, len(filters))
+	for _, f := range filters {
+		key, value, ok := strings.Cut(f, ":")
+		if !ok || !ingest.ValidLabel(key, value) {
+			return "", &requestError{"Invalid label filter; use label=key:value"}
+		}
+		if seen[key] {
+			return "", &requestError{"Label " + key + " is filtered twice"}
+		}
+		seen[key] = true
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ","), nil
+}
+
+// makeDatasetsHandler serves /api/datasets: the batches, records and bytes
+// stored per Logpush dataset in the range selected by last, start/end or
+// hours (the whole history without one), largest first. Batches ingested
+// without a dataset are listed under the empty name. With ?zone= only that
+// zone's batches are counted.
+func makeDatasetsHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: datasets", "remote_addr", r.RemoteAddr)
+
+		start, end, all, err := requestRange(r)
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+			return
+		}
+		if all {
+			start, end = time.Time{}, time.Time{}
+		}
+
+		usage, err := db.QueryDatasetUsage(start, end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to query dataset usage")
+			return
+		}
+		sendSuccessResponse(w, usage)
+	}
+}
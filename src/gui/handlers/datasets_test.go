@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestAPIDatasetFiltering(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	for _, l := range []database.LogSize{
+		{Filesize: 100, RecordCount: 1, Dataset: "http_requests"},
+		{Filesize: 200, RecordCount: 2, Dataset: "http_requests"},
+		{Filesize: 50, RecordCount: 1, Dataset: "firewall_events"},
+	} {
+		if err := db.InsertLog(l); err != nil {
+			t.Fatalf("InsertLog failed: %v", err)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+	get := func(path, target string, data any) int {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handlers[path].ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &APIResponse{Data: data}); err != nil {
+				t.Fatalf("%s: failed to decode response: %v", target, err)
+			}
+		}
+		return rr.Code
+	}
+
+	var stats LogSizeStats
+	if code := get("/api/stats/summary", "/api/stats/summary?dataset=http_requests", &stats); code != http.StatusOK || stats.TotalRecords != 2 || stats.TotalSize != 300 {
+		t.Errorf("Expected the two http_requests batches, got %d %+v", code, stats)
+	}
+	var count LogCountResponse
+	if get("/api/logs/count", "/api/logs/count?dataset=firewall_events&last=1h", &count); count.Batches != 1 || count.Bytes != 50 {
+		t.Errorf("Expected the firewall_events batch, got %+v", count)
+	}
+	if get("/api/stats/summary", "/api/stats/summary", &stats); stats.TotalRecords != 8 {
+		t.Errorf("Expected every batch without a dataset filter, got %+v", stats)
+	}
+	if code := get("/api/stats/summary", "/api/stats/summary?dataset=HTTP%20requests", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid dataset name, got %d", code)
+	}
+
+	var usage []database.DatasetUsage
+	if code := get("/api/datasets", "/api/datasets", &usage); code != http.StatusOK || len(usage) != 3 {
+		t.Fatalf("Expected three datasets including the unnamed one, got %d %+v", code, usage)
+	}
+	if usage[0].Dataset != "" || usage[1].Dataset != "http_requests" || usage[1].TotalSize != 300 {
+		t.Errorf("Expected datasets largest first, got %+v", usage)
+	}
+}
+
+func TestAPIDatasetScopesShareLimiter(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	limiter := NewConcurrencyLimiter(1, 0)
+	handlers := MakeAPIHandlersWithLimiter(db, logger, nil, nil, limiter)
+	tenants := NewTenantRouter(db, logger, nil, nil, limiter, nil)
+	if err := db.ForTenant("acme").InsertLog(database.LogSize{Filesize: 10, RecordCount: 1}); err != nil {
+		t.Fatalf("InsertLog failed: %v", err)
+	}
+
+	// Hold the only slot, as a running request would
+	limiter.admitted <- struct{}{}
+	for _, target := range []string{
+		"/api/stats/summary",
+		"/api/stats/summary?dataset=http_requests",
+		"/api/stats/summary?zone=example.com",
+		"/api/charts/timeseries?label=team:edge",
+		"/api/export?dataset=http_requests",
+	} {
+		path, _, _ := strings.Cut(target, "?")
+		rr := httptest.NewRecorder()
+		handlers[path].ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusTooManyRequests {
+			t.Errorf("%s: expected 429 while the shared limiter is full, got %d", target, rr.Code)
+		}
+	}
+	rr := httptest.NewRecorder()
+	tenants.ServeHTTP(rr, httptest.NewRequest("GET", "/t/acme/api/stats/summary", nil))
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Tenant route: expected 429 while the shared limiter is full, got %d", rr.Code)
+	}
+
+	<-limiter.admitted
+	rr = httptest.NewRecorder()
+	handlers["/api/stats/summary"].ServeHTTP(rr, httptest.NewRequest("GET", "/api/stats/summary?zone=example.com", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 once the limiter is free, got %d", rr.Code)
+	}
+}
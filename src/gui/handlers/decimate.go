@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// minMaxPoints is the smallest max_points accepted: LTTB always keeps the
+// first and last point and needs at least one bucket between them.
+const minMaxPoints = 3
+
+// parseMaxPoints reads the optional max_points parameter of endpoints whose
+// series can be decimated.
+//
+// Parameters:
+//   - r: Incoming request
+//
+// Returns:
+//   - int: Maximum number of points to return, or 0 for no limit
+//   - error: *requestError if max_points is malformed
+func parseMaxPoints(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("max_points")
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < minMaxPoints {
+		return 0, &requestError{"max_points must be an integer of at least " + strconv.Itoa(minMaxPoints)}
+	}
+	return n, nil
+}
+
+// decimate downsamples points, ordered by x, to at most maxPoints using
+// Largest-Triangle-Three-Buckets, which keeps the points that contribute
+// most to the shape of the plotted line, such as peaks and troughs. Series
+// that already fit, and a maxPoints of 0, are returned unchanged.
+//
+// Parameters:
+//   - points: Series ordered by x
+//   - maxPoints: Maximum number of points to keep, or 0 for no limit
+//   - x: Horizontal coordinate of a point, e.g. its Unix time
+//   - y: Vertical coordinate of a point, e.g. its size
+//
+// Returns:
+//   - []T: The kept points, in their original order
+func decimate[T any](points []T, maxPoints int, x, y func(T) float64) []T {
+	return pick(points, lttb(len(points), maxPoints,
+		func(i int) float64 { return x(points[i]) },
+		func(i int) float64 { return y(points[i]) }))
+}
+
+// pick returns the points at the given indices, or all points when keep is
+// nil.
+func pick[T any](points []T, keep []int) []T {
+	if keep == nil {
+		return points
+	}
+	out := make([]T, len(keep))
+	for i, idx := range keep {
+		out[i] = points[idx]
+	}
+	return out
+}
+
+// lttb returns the indices of the n-point series to keep so that at most
+// threshold remain, or nil when the series needs no downsampling.
+//
+// The first and last points are always kept. The points in between are split
+// into threshold-2 buckets, and from each bucket the point forming the
+// largest triangle with the previously kept point and the average of the
+// next bucket is kept.
+func lttb(n, threshold int, x, y func(int) float64) []int {
+	if threshold <= 0 || n <= threshold || threshold < minMaxPoints {
+		return nil
+	}
+
+	keep := make([]int, 0, threshold)
+	keep = append(keep, 0)
+	every := float64(n-2) / float64(threshold-2)
+	a := 0
+	for i := 0; i < threshold-2; i++ {
+		// Average of the next bucket (the last point after the final bucket)
+		nextStart := int(math.Floor(float64(i+1)*every)) + 1
+		nextEnd := min(int(math.Floor(float64(i+2)*every))+1, n)
+		if nextStart >= n-1 {
+			nextStart, nextEnd = n-1, n
+		}
+		var avgX, avgY float64
+		for j := nextStart; j < nextEnd; j++ {
+			avgX += x(j)
+			avgY += y(j)
+		}
+		count := float64(nextEnd - nextStart)
+		avgX /= count
+		avgY /= count
+
+		// Point of the current bucket with the largest triangle
+		start := int(math.Floor(float64(i)*every)) + 1
+		end := int(math.Floor(float64(i+1)*every)) + 1
+		ax, ay := x(a), y(a)
+		best, bestArea := start, -1.0
+		for j := start; j < end; j++ {
+			area := math.Abs((ax-avgX)*(y(j)-ay) - (ax-x(j))*(avgY-ay))
+			if area > bestArea {
+				best, bestArea = j, area
+			}
+		}
+		keep = append(keep, best)
+		a = best
+	}
+	return append(keep, n-1)
+}
+
+// decimateLogs downsamples raw log records, ordered by timestamp, by their
+// size over time.
+func decimateLogs(logs []database.LogSize, maxPoints int) []database.LogSize {
+	return decimate(logs, maxPoints,
+		func(l database.LogSize) float64 { return float64(l.Timestamp.UnixNano()) },
+		func(l database.LogSize) float64 { return float64(l.Filesize) })
+}
+
+// decimateMinutes downsamples a filled per-minute series by bytes per
+// minute. Its points are one minute apart, so their position is the x
+// coordinate.
+func decimateMinutes(points []MinutePoint, maxPoints int) []MinutePoint {
+	return pick(points, lttb(len(points), maxPoints,
+		func(i int) float64 { return float64(i) },
+		func(i int) float64 { return float64(points[i].TotalSize) }))
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestLTTB(t *testing.T) {
+	// A flat series with a single spike keeps the spike
+	ys := make([]float64, 1000)
+	ys[437] = 100
+	keep := lttb(len(ys), 20, func(i int) float64 { return float64(i) }, func(i int) float64 { return ys[i] })
+	if len(keep) != 20 {
+		t.Fatalf("Expected 20 points, got %d", len(keep))
+	}
+	if keep[0] != 0 || keep[len(keep)-1] != len(ys)-1 {
+		t.Errorf("Expected the first and last points to be kept, got %v", keep)
+	}
+	spike := false
+	for i, idx := range keep {
+		if i > 0 && idx <= keep[i-1] {
+			t.Errorf("Expected increasing indices, got %v", keep)
+		}
+		if idx == 437 {
+			spike = true
+		}
+	}
+	if !spike {
+		t.Errorf("Expected the spike to be kept, got %v", keep)
+	}
+
+	// Series that already fit are not decimated
+	if keep := lttb(10, 20, func(i int) float64 { return 0 }, func(i int) float64 { return 0 }); keep != nil {
+		t.Errorf("Expected no decimation, got %v", keep)
+	}
+	if keep := lttb(1000, 0, func(i int) float64 { return 0 }, func(i int) float64 { return 0 }); keep != nil {
+		t.Errorf("Expected no decimation without a limit, got %v", keep)
+	}
+}
+
+func TestDecimateKeepsShape(t *testing.T) {
+	points := make([]float64, 500)
+	for i := range points {
+		points[i] = math.Sin(float64(i) / 20)
+	}
+	out := decimate(points, 50, func(p float64) float64 { return p }, func(p float64) float64 { return p })
+	if len(out) != 50 {
+		t.Fatalf("Expected 50 points, got %d", len(out))
+	}
+	lo, hi := 0.0, 0.0
+	for _, p := range out {
+		lo, hi = min(lo, p), max(hi, p)
+	}
+	if lo > -0.99 || hi < 0.99 {
+		t.Errorf("Expected the extremes to survive decimation, got [%f, %f]", lo, hi)
+	}
+}
+
+func TestAPIMaxPoints(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+
+	rr := httptest.NewRecorder()
+	handlers["/api/logs/recent"](rr, httptest.NewRequest("GET", "/api/logs/recent?max_points=3", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var response struct {
+		Data []json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Data) != 3 {
+		t.Errorf("Expected 3 records, got %d", len(response.Data))
+	}
+
+	rr = httptest.NewRecorder()
+	handlers["/api/charts/minutes"](rr, httptest.NewRequest("GET", "/api/charts/minutes?minutes=120&max_points=10", nil))
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Data) != 10 {
+		t.Errorf("Expected 10 minute points, got %d", len(response.Data))
+	}
+
+	for _, path := range []string{"/api/logs/recent", "/api/logs/range", "/api/charts/minutes"} {
+		rr = httptest.NewRecorder()
+		handlers[path](rr, httptest.NewRequest("GET", path+"?start=2025-01-01T00:00:00Z&end=2025-01-02T00:00:00Z&max_points=2", nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400 for max_points=2, got %d", path, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// DiffMetric compares one value between window A and window B.
+type DiffMetric struct {
+	A         float64  `json:"a"`          // Value in window A
+	B         float64  `json:"b"`          // Value in window B
+	Delta     float64  `json:"delta"`      // B minus A
+	ChangePct *float64 `json:"change_pct"` // Delta as a percentage of A; null when A is 0
+}
+
+// DiffWindow is one of the two compared time ranges.
+type DiffWindow struct {
+	Start string  `json:"start"` // Start of the window (inclusive)
+	End   string  `json:"end"`   // End of the window (exclusive)
+	Hours float64 `json:"hours"` // Length of the window
+}
+
+// DiffTotals compares the totals of the two windows. The hourly rates make
+// windows of different lengths comparable.
+type DiffTotals struct {
+	Batches           DiffMetric `json:"batches"`            // Stored log batches
+	Records           DiffMetric `json:"records"`            // Log lines across the batches
+	Bytes             DiffMetric `json:"bytes"`              // Batch sizes as received
+	UncompressedBytes DiffMetric `json:"uncompressed_bytes"` // Batch sizes after decompression
+	AvgBatchSize      DiffMetric `json:"avg_batch_size"`     // Mean batch size as received
+	AvgRecordSize     DiffMetric `json:"avg_record_size"`    // Mean log line size
+	BatchesPerHour    DiffMetric `json:"batches_per_hour"`   // Batches divided by the window's hours
+	RecordsPerHour    DiffMetric `json:"records_per_hour"`   // Log lines divided by the window's hours
+	BytesPerHour      DiffMetric `json:"bytes_per_hour"`     // Bytes divided by the window's hours
+}
+
+// DatasetDiff compares the usage of one Logpush dataset.
+type DatasetDiff struct {
+	Dataset string     `json:"dataset"` // Dataset name; empty for batches ingested without one
+	Batches DiffMetric `json:"batches"` // Stored log batches
+	Records DiffMetric `json:"records"` // Log lines across the batches
+	Bytes   DiffMetric `json:"bytes"`   // Batch sizes as received
+	ShareA  float64    `json:"share_a"` // Percentage of window A's bytes
+	ShareB  float64    `json:"share_b"` // Percentage of window B's bytes
+}
+
+// SizeRangeDiff compares the share of batches in one size range.
+type SizeRangeDiff struct {
+	Range  string  `json:"range"`   // Size range, as in /api/charts/breakdown
+	CountA int64   `json:"count_a"` // Batches in the range in window A
+	CountB int64   `json:"count_b"` // Batches in the range in window B
+	ShareA float64 `json:"share_a"` // Percentage of window A's batches
+	ShareB float64 `json:"share_b"` // Percentage of window B's batches
+	Shift  float64 `json:"shift"`   // ShareB minus ShareA, in percentage points
+}
+
+// DistributionDiff compares how batch sizes are distributed in the two
+// windows.
+type DistributionDiff struct {
+	Ranges         []SizeRangeDiff `json:"ranges"`          // Size ranges, smallest first
+	TotalVariation float64         `json:"total_variation"` // Half the sum of the absolute shifts, from 0 (same distribution) to 100 (no overlap)
+}
+
+// StatsDiff is the response body for /api/stats/diff.
+type StatsDiff struct {
+	A                DiffWindow       `json:"a"`                 // First window, usually before a change
+	B                DiffWindow       `json:"b"`                 // Second window, usually after it
+	Totals           DiffTotals       `json:"totals"`            // Totals and hourly rates
+	Datasets         []DatasetDiff    `json:"datasets"`          // Datasets with records in either window, largest byte change first
+	SizeDistribution DistributionDiff `json:"size_distribution"` // Shift in the batch size distribution
+}
+
+// makeDiffHandler serves /api/stats/diff, comparing two arbitrary windows
+// given as a_start, a_end, b_start and b_end: totals and hourly rates, each
+// dataset's usage, and the batch size distribution. It is meant for
+// before/after analysis of a change such as enabling sampling on a job.
+func makeDiffHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: stats diff", "remote_addr", r.RemoteAddr)
+
+		aStart, aEnd, err := parseDiffWindow(r, "a")
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		bStart, bEnd, err := parseDiffWindow(r, "b")
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		diff, err := buildStatsDiff(db, aStart, aEnd, bStart, bEnd)
+		if err != nil {
+			sendErrorResponse(w, "Failed to compare time ranges")
+			return
+		}
+		sendSuccessResponse(w, diff)
+	}
+}
+
+// parseDiffWindow reads the window named by prefix from <prefix>_start and
+// <prefix>_end, both required, with the end after the start.
+func parseDiffWindow(r *http.Request, prefix string) (time.Time, time.Time, error) {
+	q := r.URL.Query()
+	startParam, endParam := prefix+"_start", prefix+"_end"
+	if q.Get(startParam) == "" || q.Get(endParam) == "" {
+		return time.Time{}, time.Time{}, &requestError{"a_start, a_end, b_start and b_end parameters required"}
+	}
+	start, err := parseTimestamp(q.Get(startParam))
+	if err != nil {
+		return time.Time{}, time.Time{}, &requestError{invalidTimeMessage(startParam)}
+	}
+	end, err := parseTimestamp(q.Get(endParam))
+	if err != nil {
+		return time.Time{}, time.Time{}, &requestError{invalidTimeMessage(endParam)}
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, &requestError{endParam + " must be after " + startParam}
+	}
+	return start, end, nil
+}
+
+// diffWindowData is what is compared of one window.
+type diffWindowData struct {
+	window   DiffWindow
+	stats    database.LogStats
+	datasets []database.DatasetUsage
+	sizes    []int64
+}
+
+// buildStatsDiff queries both windows and compares them.
+func buildStatsDiff(db *database.SQLiteController, aStart, aEnd, bStart, bEnd time.Time) (StatsDiff, error) {
+	ranges := breakdownRanges(instanceUnits(db))
+	bounds := make([]float64, 0, len(ranges)-1)
+	for _, r := range ranges[:len(ranges)-1] {
+		bounds = append(bounds, r.Max)
+	}
+	load := func(start, end time.Time) (diffWindowData, error) {
+		d := diffWindowData{window: DiffWindow{
+			Start: start.UTC().Format(time.RFC3339),
+			End:   end.UTC().Format(time.RFC3339),
+			Hours: end.Sub(start).Hours(),
+		}}
+		var err error
+		if d.stats, err = db.StatsByTimeRange(start, end); err != nil {
+			return d, err
+		}
+		if d.datasets, err = db.QueryDatasetUsage(start, end); err != nil {
+			return d, err
+		}
+		d.sizes, err = db.CountBySize(start, end, bounds)
+		return d, err
+	}
+	a, err := load(aStart, aEnd)
+	if err != nil {
+		return StatsDiff{}, err
+	}
+	b, err := load(bStart, bEnd)
+	if err != nil {
+		return StatsDiff{}, err
+	}
+
+	perHour := func(n int64, d diffWindowData) float64 { return float64(n) / d.window.Hours }
+	diff := StatsDiff{
+		A: a.window,
+		B: b.window,
+		Totals: DiffTotals{
+			Batches:           diffMetric(float64(a.stats.Batches), float64(b.stats.Batches)),
+			Records:           diffMetric(float64(a.stats.Records), float64(b.stats.Records)),
+			Bytes:             diffMetric(float64(a.stats.Bytes), float64(b.stats.Bytes)),
+			UncompressedBytes: diffMetric(float64(a.stats.UncompressedBytes), float64(b.stats.UncompressedBytes)),
+			AvgBatchSize:      diffMetric(a.stats.AvgSize, b.stats.AvgSize),
+			AvgRecordSize:     diffMetric(a.stats.AvgRecordSize, b.stats.AvgRecordSize),
+			BatchesPerHour:    diffMetric(perHour(a.stats.Batches, a), perHour(b.stats.Batches, b)),
+			RecordsPerHour:    diffMetric(perHour(a.stats.Records, a), perHour(b.stats.Records, b)),
+			BytesPerHour:      diffMetric(perHour(a.stats.Bytes, a), perHour(b.stats.Bytes, b)),
+		},
+		Datasets:         diffDatasets(a, b),
+		SizeDistribution: diffDistribution(ranges, a.sizes, b.sizes),
+	}
+	return diff, nil
+}
+
+// diffMetric compares a value in window A with the value in window B.
+func diffMetric(a, b float64) DiffMetric {
+	m := DiffMetric{A: a, B: b, Delta: b - a}
+	if a != 0 {
+		pct := m.Delta / a * 100
+		m.ChangePct = &pct
+	}
+	return m
+}
+
+// share returns n as a percentage of total, or 0 when total is 0.
+func share(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
+
+// diffDatasets compares every dataset with records in either window,
+// largest absolute byte change first.
+func diffDatasets(a, b diffWindowData) []DatasetDiff {
+	usage := make(map[string][2]database.DatasetUsage)
+	for i, datasets := range [][]database.DatasetUsage{a.datasets, b.datasets} {
+		for _, u := range datasets {
+			pair := usage[u.Dataset]
+			pair[i] = u
+			usage[u.Dataset] = pair
+		}
+	}
+	out := make([]DatasetDiff, 0, len(usage))
+	for name, pair := range usage {
+		out = append(out, DatasetDiff{
+			Dataset: name,
+			Batches: diffMetric(float64(pair[0].Records), float64(pair[1].Records)),
+			Records: diffMetric(float64(pair[0].RecordCount), float64(pair[1].RecordCount)),
+			Bytes:   diffMetric(float64(pair[0].TotalSize), float64(pair[1].TotalSize)),
+			ShareA:  share(pair[0].TotalSize, a.stats.Bytes),
+			ShareB:  share(pair[1].TotalSize, b.stats.Bytes),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		di, dj := math.Abs(out[i].Bytes.Delta), math.Abs(out[j].Bytes.Delta)
+		if di != dj {
+			return di > dj
+		}
+		return out[i].Dataset < out[j].Dataset
+	})
+	return out
+}
+
+// diffDistribution compares the share of batches in each size range.
+func diffDistribution(ranges []sizeRange, a, b []int64) DistributionDiff {
+	var totalA, totalB int64
+	for i := range ranges {
+		totalA += a[i]
+		totalB += b[i]
+	}
+	d := DistributionDiff{Ranges: make([]SizeRangeDiff, 0, len(ranges))}
+	for i, r := range ranges {
+		rd := SizeRangeDiff{Range: r.Name, CountA: a[i], CountB: b[i], ShareA: share(a[i], totalA), ShareB: share(b[i], totalB)}
+		rd.Shift = rd.ShareB - rd.ShareA
+		d.TotalVariation += math.Abs(rd.Shift) / 2
+		d.Ranges = append(d.Ranges, rd)
+	}
+	return d
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestAPIStatsDiff(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/stats/diff"]
+	get := func(query string) (int, string) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/stats/diff"+query, nil))
+		return rr.Code, rr.Body.String()
+	}
+
+	// Two hours before sampling was enabled on http_requests, one after
+	base := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	for _, l := range []database.LogSize{
+		{Timestamp: base, Filesize: 5000, RecordCount: 100, Dataset: "http_requests"},
+		{Timestamp: base.Add(90 * time.Minute), Filesize: 5000, RecordCount: 100, Dataset: "http_requests"},
+		{Timestamp: base.Add(time.Hour), Filesize: 500, RecordCount: 5, Dataset: "firewall_events"},
+		{Timestamp: base.Add(2 * time.Hour), Filesize: 500, RecordCount: 10, Dataset: "http_requests"},
+		{Timestamp: base.Add(150 * time.Minute), Filesize: 500, RecordCount: 5, Dataset: "firewall_events"},
+	} {
+		if err := db.InsertLog(l); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+
+	code, body := get("?a_start=2025-09-01T00:00:00Z&a_end=2025-09-01T02:00:00Z&b_start=2025-09-01T02:00:00Z&b_end=2025-09-01T03:00:00Z")
+	if code != 200 {
+		t.Fatalf("Expected 200, got %d %s", code, body)
+	}
+	var resp struct {
+		Data StatsDiff `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	d := resp.Data
+	if d.A.Hours != 2 || d.B.Hours != 1 {
+		t.Errorf("Unexpected windows %+v %+v", d.A, d.B)
+	}
+	if m := d.Totals.Bytes; m.A != 10500 || m.B != 1000 || m.Delta != -9500 || m.ChangePct == nil {
+		t.Errorf("Unexpected bytes comparison %+v", m)
+	}
+	if m := d.Totals.BatchesPerHour; m.A != 1.5 || m.B != 2 {
+		t.Errorf("Expected hourly rates to account for the window lengths, got %+v", m)
+	}
+	if len(d.Datasets) != 2 || d.Datasets[0].Dataset != "http_requests" || d.Datasets[0].Records.B != 10 ||
+		d.Datasets[1].Bytes.Delta != 0 || d.Datasets[1].ShareB != 50 {
+		t.Errorf("Unexpected dataset comparison %+v", d.Datasets)
+	}
+	var countA, countB int64
+	for _, r := range d.SizeDistribution.Ranges {
+		countA += r.CountA
+		countB += r.CountB
+	}
+	if countA != 3 || countB != 2 || d.SizeDistribution.TotalVariation < 66 || d.SizeDistribution.TotalVariation > 67 {
+		t.Errorf("Unexpected size distribution %+v", d.SizeDistribution)
+	}
+
+	// A dataset missing from window A has no percentage change
+	code, body = get("?a_start=2025-09-01T01:00:00Z&a_end=2025-09-01T01:30:00Z&b_start=2025-09-01T00:00:00Z&b_end=2025-09-01T03:00:00Z")
+	if code != 200 {
+		t.Fatalf("Expected 200, got %d %s", code, body)
+	}
+	json.Unmarshal([]byte(body), &resp)
+	for _, ds := range resp.Data.Datasets {
+		if ds.Dataset == "http_requests" && (ds.Bytes.A != 0 || ds.Bytes.ChangePct != nil) {
+			t.Errorf("Expected no percentage change from zero, got %+v", ds.Bytes)
+		}
+	}
+
+	for _, query := range []string{
+		"",
+		"?a_start=2025-09-01T00:00:00Z&a_end=2025-09-01T02:00:00Z",
+		"?a_start=yesterday&a_end=2025-09-01T02:00:00Z&b_start=2025-09-01T02:00:00Z&b_end=2025-09-01T03:00:00Z",
+		"?a_start=2025-09-01T02:00:00Z&a_end=2025-09-01T00:00:00Z&b_start=2025-09-01T02:00:00Z&b_end=2025-09-01T03:00:00Z",
+	} {
+		if code, _ := get(query); code != 400 {
+			t.Errorf("Expected 400 for %q, got %d", query, code)
+		}
+	}
+}
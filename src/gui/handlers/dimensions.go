@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+)
+
+// DimensionBreakdown is the response body for /api/stats/dimensions.
+type DimensionBreakdown struct {
+	Dimension string                    `json:"dimension"` // Dimension that was queried
+	Hours     int                       `json:"hours"`     // Size of the window in hours
+	Values    []database.DimensionCount `json:"values"`    // Totals per dataset and value, largest first
+}
+
+// makeDimensionsHandler serves /api/stats/dimensions, totalling one
+// dimension extracted by the dataset parsers (e.g. status_class, action,
+// script) over the last `hours` hours (default 24), optionally scoped by
+// dataset=, zone= and label=. Values are only collected while the
+// dataset-parsers feature flag is enabled.
+func makeDimensionsHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: dimensions", "remote_addr", r.RemoteAddr)
+
+		dimension := r.URL.Query().Get("dimension")
+		if dimension == "" {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "dimension is required")
+			return
+		}
+		hours, err := windowParam(r, "hours", time.Hour, 24)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		end := now().UTC()
+		values, err := db.QueryDimension(dimension, end.Add(-time.Duration(hours)*time.Hour), end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch dimension data")
+			return
+		}
+		sendSuccessResponse(w, DimensionBreakdown{Dimension: dimension, Hours: hours, Values: values})
+	}
+}
+
+// DimensionValueTotal is one value's total over a /api/charts/by-dimension
+// window.
+type DimensionValueTotal struct {
+	Value   string  `json:"value"`   // Dimension value, e.g. "5xx"
+	Records int64   `json:"records"` // Number of records
+	Bytes   int64   `json:"bytes"`   // Total size of the records in bytes
+	Share   float64 `json:"share"`   // Percentage of the window's bytes
+}
+
+// DimensionSeriesPoint is one bucket of /api/charts/by-dimension. Every
+// value of the window has an entry, 0 when it had no records.
+type DimensionSeriesPoint struct {
+	Timestamp string           `json:"timestamp"` // Start of the bucket, RFC 3339 UTC
+	Records   map[string]int64 `json:"records"`   // Records per value
+	Bytes     map[string]int64 `json:"bytes"`     // Bytes per value
+}
+
+// DimensionSeries is the response body for /api/charts/by-dimension.
+type DimensionSeries struct {
+	Dimension string                 `json:"dimension"` // Dimension that was queried
+	Dataset   string                 `json:"dataset"`   // Dataset read; empty for all
+	Interval  string                 `json:"interval"`  // Bucket size: "hour" or "day"
+	Hours     int                    `json:"hours"`     // Size of the window in hours
+	Values    []DimensionValueTotal  `json:"values"`    // Totals per value, largest first
+	Points    []DimensionSeriesPoint `json:"points"`    // Zero-filled buckets, oldest first
+}
+
+// makeDimensionSeriesHandler serves /api/charts/by-dimension, the volume of
+// each value of one dimension (dim=, e.g. status_class) over the last
+// `hours` hours (default 24, or last=) in hourly or daily buckets, so a
+// stacked chart shows whether, say, a storm of 5xx responses is inflating
+// log volume. interval= picks the bucket size; by default hours are used
+// up to defaultSeriesPoints buckets and days beyond. dataset=, zone= and
+// label= read the values of one dataset, zone or set of labels (see
+// datasetScopedPaths). Values are only collected while the dataset-parsers
+// feature flag is enabled.
+func makeDimensionSeriesHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: dimension series", "remote_addr", r.RemoteAddr)
+
+		format, err := parseTableFormat(r, instanceUnits(db))
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		q := r.URL.Query()
+		dimension := q.Get("dim")
+		if dimension == "" {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "dim is required")
+			return
+		}
+		dataset := q.Get("dataset")
+		if dataset != "" && !ingest.ValidDataset(dataset) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid dataset name")
+			return
+		}
+		hours, err := windowParam(r, "hours", time.Hour, 24)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		hours = min(hours, maxQueryWindowHours)
+		interval := q.Get("interval")
+		switch interval {
+		case "":
+			interval = "hour"
+			if hours > defaultSeriesPoints {
+				interval = "day"
+			}
+		case "hour", "day":
+		default:
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "interval must be hour or day")
+			return
+		}
+		bucket, _ := parseSeriesInterval(interval)
+
+		end := now().UTC()
+		start := end.Add(-time.Duration(hours) * time.Hour)
+		rows, err := db.QueryDimensionHours(dimension, dataset, start, end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch dimension data")
+			return
+		}
+		series := buildDimensionSeries(rows, start, end, bucket)
+		series.Dimension, series.Dataset, series.Interval, series.Hours = dimension, dataset, interval, hours
+		if format.table {
+			format.send(w, r, format.dimensionTable(series), nil)
+			return
+		}
+		sendSuccessResponse(w, series)
+	}
+}
+
+// buildDimensionSeries sums hourly rows into zero-filled buckets covering
+// [start, end) and totals each value.
+func buildDimensionSeries(rows []database.DimensionHour, start, end time.Time, bucket time.Duration) DimensionSeries {
+	totals := make(map[string]*DimensionValueTotal)
+	var all int64
+	for _, row := range rows {
+		t, ok := totals[row.Value]
+		if !ok {
+			t = &DimensionValueTotal{Value: row.Value}
+			totals[row.Value] = t
+		}
+		t.Records += row.Records
+		t.Bytes += row.Bytes
+		all += row.Bytes
+	}
+	series := DimensionSeries{Values: make([]DimensionValueTotal, 0, len(totals)), Points: []DimensionSeriesPoint{}}
+	for _, t := range totals {
+		t.Share = share(t.Bytes, all)
+		series.Values = append(series.Values, *t)
+	}
+	sort.Slice(series.Values, func(i, j int) bool {
+		if series.Values[i].Bytes != series.Values[j].Bytes {
+			return series.Values[i].Bytes > series.Values[j].Bytes
+		}
+		return series.Values[i].Value < series.Values[j].Value
+	})
+
+	index := make(map[time.Time]int)
+	for t := start.Truncate(bucket); t.Before(end); t = t.Add(bucket) {
+		p := DimensionSeriesPoint{Timestamp: t.Format(time.RFC3339), Records: map[string]int64{}, Bytes: map[string]int64{}}
+		for value := range totals {
+			p.Records[value], p.Bytes[value] = 0, 0
+		}
+		index[t] = len(series.Points)
+		series.Points = append(series.Points, p)
+	}
+	for _, row := range rows {
+		if i, ok := index[row.Hour.Truncate(bucket)]; ok {
+			series.Points[i].Records[row.Value] += row.Records
+			series.Points[i].Bytes[row.Value] += row.Bytes
+		}
+	}
+	return series
+}
+
+// makeDimensionRegistryHandler serves /api/dimensions, the dimensions the
+// dataset parsers extract, so clients know which names /api/stats/dimensions
+// and /api/dimensions/query accept.
+func makeDimensionRegistryHandler(logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: dimension registry", "remote_addr", r.RemoteAddr)
+		sendSuccessResponse(w, ingest.Dimensions())
+	}
+}
+
+// Limits on the groups returned by /api/dimensions/query.
+const (
+	defaultDimensionGroupLimit = 1000
+	maxDimensionGroupLimit     = 10000
+)
+
+// DimensionQueryResult is the response body for /api/dimensions/query.
+type DimensionQueryResult struct {
+	Dimension string                    `json:"dimension"` // Dimension that was queried
+	Datasets  []string                  `json:"datasets"`  // Dataset filter; empty for all
+	Values    []string                  `json:"values"`    // Value filter; empty for all
+	GroupBy   []string                  `json:"group_by"`  // Group keys, in the order given
+	Hours     int                       `json:"hours"`     // Size of the window in hours
+	Groups    []database.DimensionGroup `json:"groups"`    // Totals per group
+}
+
+// makeDimensionQueryHandler serves /api/dimensions/query, the generic query
+// over the dimension rollups: one dimension (dimension=) over the last
+// `hours` hours (default 24, or last=), filtered by comma-separated dataset=
+// and value= lists and grouped by a comma-separated group_by= of dataset,
+// value and hour or day (default value). limit= caps the number of groups.
+func makeDimensionQueryHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: dimension query", "remote_addr", r.RemoteAddr)
+
+		q := r.URL.Query()
+		query := database.DimensionQuery{
+			Dimension: q.Get("dimension"),
+			Datasets:  commaList(q.Get("dataset")),
+			Values:    commaList(q.Get("value")),
+			GroupBy:   commaList(q.Get("group_by")),
+			Limit:     defaultDimensionGroupLimit,
+		}
+		if query.Dimension == "" {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "dimension is required")
+			return
+		}
+		for _, dataset := range query.Datasets {
+			if !ingest.ValidDataset(dataset) {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid dataset name: "+dataset)
+				return
+			}
+		}
+		if query.GroupBy == nil {
+			query.GroupBy = []string{database.DimensionGroupValue}
+		}
+		if !database.ValidDimensionGroupBy(query.GroupBy) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "group_by must list distinct keys of dataset, value and hour or day")
+			return
+		}
+		if s := q.Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 || n > maxDimensionGroupLimit {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "limit must be between 1 and 10000")
+				return
+			}
+			query.Limit = n
+		}
+		hours, err := windowParam(r, "hours", time.Hour, 24)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		hours = min(hours, maxQueryWindowHours)
+
+		query.End = now().UTC()
+		query.Start = query.End.Add(-time.Duration(hours) * time.Hour)
+		groups, err := db.QueryDimensionGroups(query)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch dimension data")
+			return
+		}
+		sendSuccessResponse(w, DimensionQueryResult{
+			Dimension: query.Dimension,
+			Datasets:  nonNil(query.Datasets),
+			Values:    nonNil(query.Values),
+			GroupBy:   query.GroupBy,
+			Hours:     hours,
+			Groups:    groups,
+		})
+	}
+}
+
+// commaList splits a comma-separated parameter into its trimmed, non-empty
+// items, or nil if there are none.
+func commaList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// nonNil returns s, or an empty slice if s is nil, so it encodes as [].
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+	"github.com/melatonein5/LogpushEstimator/src/testsupport"
+)
+
+func TestDimensionsHandler(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	db.AddDimensionCounts(time.Now(), []database.DimensionCount{
+		{Dataset: "workers_trace_events", Dimension: "script", Value: "api", Records: 3, Bytes: 300},
+		{Dataset: "workers_trace_events", Dimension: "script", Value: "auth", Records: 1, Bytes: 900},
+	})
+
+	handler := MakeAPIHandlers(db, logger)["/api/stats/dimensions"]
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/stats/dimensions?dimension=script", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Data DimensionBreakdown `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if resp.Data.Hours != 24 || len(resp.Data.Values) != 2 || resp.Data.Values[0].Value != "auth" {
+		t.Errorf("Expected auth first by bytes, got %+v", resp.Data)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/stats/dimensions", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a dimension, got %d", rr.Code)
+	}
+}
+
+func TestDimensionSeriesHandler(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	SetClock(testsupport.NewClock(time.Date(2025, 9, 15, 12, 30, 0, 0, time.UTC)))
+	defer SetClock(nil)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// A 5xx storm at 11:00 on top of steady 2xx traffic
+	for _, c := range []struct {
+		at      time.Time
+		dataset string
+		value   string
+		bytes   int64
+	}{
+		{time.Date(2025, 9, 15, 10, 5, 0, 0, time.UTC), "http_requests", "2xx", 1000},
+		{time.Date(2025, 9, 15, 11, 5, 0, 0, time.UTC), "http_requests", "2xx", 1000},
+		{time.Date(2025, 9, 15, 11, 40, 0, 0, time.UTC), "http_requests", "5xx", 6000},
+		{time.Date(2025, 9, 15, 11, 50, 0, 0, time.UTC), "other", "5xx", 500},
+		{time.Date(2025, 9, 15, 8, 0, 0, 0, time.UTC), "http_requests", "2xx", 99},
+	} {
+		db.AddDimensionCounts(c.at, []database.DimensionCount{{Dataset: c.dataset, Dimension: "status_class", Value: c.value, Records: c.bytes / 100, Bytes: c.bytes}})
+	}
+
+	handler := MakeAPIHandlers(db, logger)["/api/charts/by-dimension"]
+	get := func(query string) (int, DimensionSeries) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/charts/by-dimension"+query, nil))
+		var resp struct {
+			Data DimensionSeries `json:"data"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return rr.Code, resp.Data
+	}
+
+	code, series := get("?dim=status_class&hours=3&dataset=http_requests")
+	if code != http.StatusOK || series.Interval != "hour" || len(series.Points) != 4 || series.Points[0].Timestamp != "2025-09-15T09:00:00Z" {
+		t.Fatalf("Expected four zero-filled hours from 09:00, got %d %+v", code, series)
+	}
+	if len(series.Values) != 2 || series.Values[0].Value != "5xx" || series.Values[0].Bytes != 6000 || series.Values[0].Share != 75 {
+		t.Errorf("Expected 5xx first with 75%% of the bytes, got %+v", series.Values)
+	}
+	if p := series.Points[0]; p.Bytes["2xx"] != 0 || p.Bytes["5xx"] != 0 {
+		t.Errorf("Expected an empty first hour with both values, got %+v", p)
+	}
+	if p := series.Points[2]; p.Bytes["2xx"] != 1000 || p.Bytes["5xx"] != 6000 || p.Records["5xx"] != 60 {
+		t.Errorf("Expected the storm in the 11:00 bucket, got %+v", p)
+	}
+
+	// Every dataset's values, in daily buckets
+	if _, series := get("?dim=status_class&hours=3&interval=day"); len(series.Points) != 1 || series.Points[0].Bytes["5xx"] != 6500 {
+		t.Errorf("Expected one day with both datasets' 5xx bytes, got %+v", series)
+	}
+	if _, series := get("?dim=status_class&last=30d"); series.Interval != "day" || series.Hours != 720 {
+		t.Errorf("Expected daily buckets for 30 days, got %+v", series)
+	}
+
+	for _, query := range []string{"", "?dim=status_class&interval=minute", "?dim=status_class&dataset=Bad", "?dim=status_class&format=csv"} {
+		if code, _ := get(query); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %q, got %d", query, code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/charts/by-dimension?dim=status_class&hours=2&format=table&units=decimal", nil))
+	var table struct {
+		Data DataTable `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &table)
+	if len(table.Data.Columns) != 3 || table.Data.Columns[1] != "5xx" || len(table.Data.Rows) != 3 || table.Data.Rows[1].Cells[0] != "6.5 KB" {
+		t.Errorf("Expected a column per value, largest first, got %+v", table.Data)
+	}
+
+	// zone= and label= read only the counts recorded under them
+	db.ForZone("example.com").ForLabels(map[string]string{"env": "prod"}).AddDimensionCounts(time.Date(2025, 9, 15, 12, 10, 0, 0, time.UTC),
+		[]database.DimensionCount{{Dataset: "http_requests", Dimension: "status_class", Value: "4xx", Records: 2, Bytes: 200}})
+	for _, query := range []string{"?dim=status_class&hours=3&zone=example.com", "?dim=status_class&hours=3&label=env:prod"} {
+		if code, series := get(query); code != http.StatusOK || len(series.Values) != 1 || series.Values[0].Value != "4xx" || series.Values[0].Bytes != 200 {
+			t.Errorf("Expected only the scoped 4xx counts for %q, got %d %+v", query, code, series.Values)
+		}
+	}
+	if _, series := get("?dim=status_class&hours=3&zone=other.com"); len(series.Values) != 0 {
+		t.Errorf("Expected no values for an unrecorded zone, got %+v", series.Values)
+	}
+}
+
+func TestDimensionRegistryHandler(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	rr := httptest.NewRecorder()
+	MakeAPIHandlers(db, logger)["/api/dimensions"].ServeHTTP(rr, httptest.NewRequest("GET", "/api/dimensions", nil))
+	var resp struct {
+		Data []ingest.Dimension `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	found := false
+	for _, d := range resp.Data {
+		if d.Name == "colo" && d.Dataset == "http_requests" && d.Field == "EdgeColoCode" {
+			found = true
+		}
+	}
+	if rr.Code != http.StatusOK || !found {
+		t.Errorf("Expected the colo dimension to be listed, got %d: %+v", rr.Code, resp.Data)
+	}
+}
+
+func TestDimensionQueryHandler(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	SetClock(testsupport.NewClock(time.Date(2025, 9, 15, 12, 30, 0, 0, time.UTC)))
+	defer SetClock(nil)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	db.AddDimensionCounts(time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC), []database.DimensionCount{
+		{Dataset: "http_requests", Dimension: "colo", Value: "FRA", Records: 10, Bytes: 1000},
+		{Dataset: "http_requests", Dimension: "colo", Value: "AMS", Records: 2, Bytes: 400},
+	})
+	db.AddDimensionCounts(time.Date(2025, 9, 15, 11, 0, 0, 0, time.UTC), []database.DimensionCount{
+		{Dataset: "http_requests", Dimension: "colo", Value: "FRA", Records: 1, Bytes: 100},
+		{Dataset: "firewall_events", Dimension: "colo", Value: "FRA", Records: 5, Bytes: 500},
+	})
+	// Outside the default 24-hour window
+	db.AddDimensionCounts(time.Date(2025, 9, 13, 11, 0, 0, 0, time.UTC), []database.DimensionCount{
+		{Dataset: "http_requests", Dimension: "colo", Value: "AMS", Records: 9, Bytes: 9000},
+	})
+	handler := MakeAPIHandlers(db, logger)["/api/dimensions/query"]
+
+	query := func(params string) (int, DimensionQueryResult) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/dimensions/query?"+params, nil))
+		var resp struct {
+			Data DimensionQueryResult `json:"data"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return rr.Code, resp.Data
+	}
+
+	code, result := query("dimension=colo")
+	if code != http.StatusOK || result.Hours != 24 || len(result.GroupBy) != 1 || result.GroupBy[0] != "value" {
+		t.Fatalf("Expected a 24-hour query grouped by value, got %d: %+v", code, result)
+	}
+	if len(result.Groups) != 2 || result.Groups[0].Value != "FRA" || result.Groups[0].Bytes != 1600 || result.Groups[1].Bytes != 400 {
+		t.Errorf("Expected FRA then AMS summed across datasets, got %+v", result.Groups)
+	}
+
+	_, result = query("dimension=colo&group_by=hour,dataset&dataset=http_requests&value=FRA,%20AMS")
+	if len(result.Groups) != 2 || result.Groups[0].Bucket != "2025-09-15T10:00:00Z" || result.Groups[0].Bytes != 1400 ||
+		result.Groups[1].Dataset != "http_requests" || result.Groups[1].Bytes != 100 || len(result.Values) != 2 || result.Values[1] != "AMS" {
+		t.Errorf("Expected http_requests totals per hour, got %+v", result)
+	}
+
+	_, result = query("dimension=colo&group_by=value&limit=1&hours=72")
+	if len(result.Groups) != 1 || result.Groups[0].Value != "AMS" || result.Groups[0].Bytes != 9400 {
+		t.Errorf("Expected only the largest value over 72 hours, got %+v", result.Groups)
+	}
+
+	for _, params := range []string{
+		"",
+		"dimension=colo&group_by=zone",
+		"dimension=colo&group_by=hour,day",
+		"dimension=colo&dataset=Bad-Name",
+		"dimension=colo&limit=0",
+		"dimension=colo&last=soon",
+	} {
+		if code, _ := query(params); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %q, got %d", params, code)
+		}
+	}
+}
@@ -143,7 +143,9 @@
 // # CORS Support
 //
 // API handlers include CORS headers for development environments,
-// allowing cross-origin requests from frontend applications.
+// allowing cross-origin requests from frontend applications. WithMethods
+// answers OPTIONS preflights for every API route and rejects methods a
+// route does not accept with 405 and an Allow header.
 //
 // # Error Handling
 //
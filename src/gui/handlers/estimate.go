@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/estimator"
+)
+
+// Cost estimate defaults: a week of observed usage kept for 30 days and
+// never read back, priced as R2 when no pricing model is configured.
+const (
+	defaultEstimateDays      = 7
+	defaultEstimateRetention = 30
+	defaultEstimateModel     = "r2"
+)
+
+// CostEstimate is the response body for /api/estimate/cost.
+type CostEstimate struct {
+	Start time.Time `json:"start"` // Start of the observed window
+	End   time.Time `json:"end"`   // End of the observed window
+	estimator.Estimate
+}
+
+// selectEstimateModel returns the configured pricing model named name, or
+// else the preset of that name. Without a name it returns the default
+// configured model, or the R2 preset when none is configured.
+func selectEstimateModel(models []config.PricingModel, name string) (config.PricingModel, error) {
+	if name == "" && len(models) == 0 {
+		name = defaultEstimateModel
+	}
+	for _, m := range models {
+		if name != "" && m.Name == name {
+			return m, nil
+		}
+	}
+	if preset, ok := estimator.Preset(name); ok {
+		return preset, nil
+	}
+	return selectPricingModel(models, name)
+}
+
+// makeCostEstimateHandler serves /api/estimate/cost, projecting the usage
+// of the last `days` days (default 7, or last=) to a 30-day month and
+// pricing ingestion, requests, the storage held for retention_days
+// (default 30) and the download_ratio share of each month read back out
+// (default 0). model names a configured pricing model or one of the r2, s3
+// and gcs presets.
+func makeCostEstimateHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: cost estimate", "remote_addr", r.RemoteAddr)
+
+		days, err := windowParam(r, "days", 24*time.Hour, defaultEstimateDays)
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+			return
+		}
+		days = min(days, maxQueryWindowHours/24)
+
+		q := r.URL.Query()
+		opts := estimator.Options{RetentionDays: defaultEstimateRetention}
+		if v := q.Get("retention_days"); v != "" {
+			if opts.RetentionDays, err = strconv.Atoi(v); err != nil || opts.RetentionDays < 0 {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "retention_days must be a non-negative integer")
+				return
+			}
+		}
+		if v := q.Get("download_ratio"); v != "" {
+			if opts.DownloadRatio, err = strconv.ParseFloat(v, 64); err != nil || opts.DownloadRatio < 0 {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "download_ratio must be a non-negative number")
+				return
+			}
+		}
+
+		doc, err := config.Export(db)
+		if err != nil {
+			sendErrorResponse(w, "Failed to load pricing models")
+			return
+		}
+		model, err := selectEstimateModel(doc.PricingModels, q.Get("model"))
+		if errors.As(err, &reqErr) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+			return
+		}
+		// Presets are priced in the configured units like stored models
+		model.Units = doc.Units
+
+		end := now().UTC()
+		start := end.Add(-time.Duration(days) * 24 * time.Hour)
+		usage, err := db.QueryTenantUsage(start, end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to query usage")
+			return
+		}
+		sendSuccessResponse(w, CostEstimate{
+			Start:    start,
+			End:      end,
+			Estimate: estimator.Project(model, estimator.Sum(usage), float64(days), opts),
+		})
+	}
+}
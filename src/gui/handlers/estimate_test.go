@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+)
+
+func TestAPICostEstimate(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/estimate/cost"]
+	get := func(query string) (*httptest.ResponseRecorder, CostEstimate) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/estimate/cost"+query, nil))
+		var resp struct {
+			Data CostEstimate `json:"data"`
+		}
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Could not parse JSON response: %v", err)
+			}
+		}
+		return rr, resp.Data
+	}
+
+	// Without a configured model the R2 preset prices a week of usage
+	rr, estimate := get("")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if estimate.PricingModel != "r2" || estimate.Days != 7 || estimate.RetentionDays != 30 {
+		t.Errorf("Expected the r2 preset over 7 days with 30 days retained, got %+v", estimate)
+	}
+	// 5 batches of 31744 bytes in a week, scaled to 30 days
+	if estimate.Bytes != 136046 || estimate.Requests != 21 || estimate.StoredBytes != 136046 {
+		t.Errorf("Expected the week scaled to a month, got %+v", estimate)
+	}
+
+	// A configured model takes precedence over the preset of the same name
+	doc := config.NewDocument()
+	doc.PricingModels = []config.PricingModel{{Name: "s3", Currency: "EUR", Download: []config.PriceTier{{PerGB: 1e6}}}}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Failed to add pricing model: %v", err)
+	}
+	if _, estimate = get("?model=s3&download_ratio=1"); estimate.Currency != "EUR" || estimate.MonthlyCost <= 0 {
+		t.Errorf("Expected the configured s3 model to price downloads, got %+v", estimate)
+	}
+	if _, estimate = get("?model=gcs&days=1&retention_days=0"); estimate.PricingModel != "gcs" || estimate.StoredBytes != 0 {
+		t.Errorf("Expected the gcs preset with nothing retained, got %+v", estimate)
+	}
+
+	for _, query := range []string{"?model=azure", "?download_ratio=-1", "?retention_days=x", "?last=bogus"} {
+		if rr, _ := get(query); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
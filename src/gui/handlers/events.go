@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"log/slog"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/events"
+)
+
+// eventLogSize is how many recent events /api/admin/events keeps.
+const eventLogSize = 100
+
+// EventLogReport is the response body for /api/admin/events.
+type EventLogReport struct {
+	Since  string           `json:"since"`  // When collection started (RFC3339)
+	Counts map[string]int64 `json:"counts"` // Events published per topic since startup
+	Recent []events.Event   `json:"recent"` // Most recent events, newest first
+}
+
+// EventLog counts the domain events published on a bus and keeps the most
+// recent ones in memory, so maintainers can see what the estimator has
+// been doing. It is safe for concurrent use; it resets on restart unless
+// carried over with State and Restore.
+type EventLog struct {
+	mu     sync.Mutex
+	since  time.Time
+	counts map[string]int64
+	recent []events.Event // Ring buffer of recent events
+	next   int            // Next ring buffer slot to overwrite
+	size   int
+}
+
+// NewEventLog creates an event log subscribed to every topic on bus.
+//
+// Parameters:
+//   - bus: Bus to record events from
+//
+// Returns:
+//   - *EventLog: Empty event log
+func NewEventLog(bus *events.Bus) *EventLog {
+	l := &EventLog{since: now().UTC(), counts: make(map[string]int64), size: eventLogSize}
+	bus.Subscribe(l.Record)
+	return l
+}
+
+// Record adds one event to the log.
+func (l *EventLog) Record(e events.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.counts[e.Topic]++
+	if len(l.recent) < l.size {
+		l.recent = append(l.recent, e)
+		return
+	}
+	l.recent[l.next] = e
+	l.next = (l.next + 1) % l.size
+}
+
+// Snapshot returns the counts and recent events, newest first.
+func (l *EventLog) Snapshot() EventLogReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	report := EventLogReport{
+		Since:  l.since.Format(time.RFC3339),
+		Counts: make(map[string]int64, len(l.counts)),
+		Recent: make([]events.Event, 0, len(l.recent)),
+	}
+	for topic, n := range l.counts {
+		report.Counts[topic] = n
+	}
+	// The oldest event sits at l.next once the ring buffer is full
+	for i := len(l.recent) - 1; i >= 0; i-- {
+		report.Recent = append(report.Recent, l.recent[(l.next+i)%len(l.recent)])
+	}
+	return report
+}
+
+// EventLogState is the content of an EventLog, saved so counts and recent
+// events survive a restart.
+type EventLogState struct {
+	Since  time.Time        `json:"since"`  // When collection started
+	Counts map[string]int64 `json:"counts"` // Events published per topic
+	Recent []events.Event   `json:"recent"` // Most recent events, oldest first
+}
+
+// State returns the counts and recent events for saving.
+func (l *EventLog) State() EventLogState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	state := EventLogState{Since: l.since, Counts: maps.Clone(l.counts), Recent: make([]events.Event, 0, len(l.recent))}
+	state.Recent = append(state.Recent, l.recent[l.next:]...)
+	state.Recent = append(state.Recent, l.recent[:l.next]...)
+	return state
+}
+
+// Restore adds the counts in a saved state to those recorded so far, puts
+// the saved events before the ones recorded since startup, and moves the
+// start of collection back to the saved one. Restored event payloads are
+// decoded JSON rather than the original types.
+//
+// Parameters:
+//   - state: State from State, typically saved by a previous process
+func (l *EventLog) Restore(state EventLogState) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !state.Since.IsZero() && state.Since.Before(l.since) {
+		l.since = state.Since.UTC()
+	}
+	for topic, n := range state.Counts {
+		l.counts[topic] += n
+	}
+	recent := append(append([]events.Event(nil), state.Recent...), l.recent[l.next:]...)
+	recent = append(recent, l.recent[:l.next]...)
+	if len(recent) > l.size {
+		recent = recent[len(recent)-l.size:]
+	}
+	l.recent, l.next = recent, 0
+}
+
+// MakeEventLogHandler creates the /api/admin/events handler reporting the
+// domain events published since startup.
+//
+// Parameters:
+//   - log: Event log subscribed to the estimator's bus
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function
+func MakeEventLogHandler(log *EventLog, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: events", "remote_addr", r.RemoteAddr)
+		sendSuccessResponse(w, log.Snapshot())
+	}
+}
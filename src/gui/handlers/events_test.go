@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/events"
+)
+
+func TestEventLogKeepsRecentEvents(t *testing.T) {
+	bus := events.NewBus(nil, nil)
+	log := NewEventLog(bus)
+	log.size = 3
+
+	for i := int64(1); i <= 5; i++ {
+		bus.Publish(events.TopicIngestReceived, events.IngestReceived{Bytes: i})
+	}
+	bus.Publish(events.TopicRetentionPruned, events.RetentionPruned{Kind: "trash"})
+
+	report := log.Snapshot()
+	if report.Counts[events.TopicIngestReceived] != 5 || report.Counts[events.TopicRetentionPruned] != 1 {
+		t.Errorf("Unexpected counts: %v", report.Counts)
+	}
+	if len(report.Recent) != 3 || report.Recent[0].Topic != events.TopicRetentionPruned {
+		t.Fatalf("Expected the 3 newest events, newest first, got %+v", report.Recent)
+	}
+	if got := report.Recent[2].Data.(events.IngestReceived).Bytes; got != 4 {
+		t.Errorf("Expected the oldest kept event to be the 4th batch, got %d bytes", got)
+	}
+}
+
+func TestEventLogStateRoundTrip(t *testing.T) {
+	bus := events.NewBus(nil, nil)
+	previous := NewEventLog(bus)
+	previous.size = 3
+	for i := int64(1); i <= 4; i++ {
+		bus.Publish(events.TopicIngestReceived, events.IngestReceived{Bytes: i})
+	}
+	data, err := json.Marshal(previous.State())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus = events.NewBus(nil, nil)
+	log := NewEventLog(bus)
+	log.size = 3
+	bus.Publish(events.TopicJobSynced, events.JobSynced{JobID: 1})
+	var state EventLogState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	log.Restore(state)
+
+	report := log.Snapshot()
+	if report.Counts[events.TopicIngestReceived] != 4 || report.Counts[events.TopicJobSynced] != 1 {
+		t.Errorf("Unexpected restored counts: %v", report.Counts)
+	}
+	if len(report.Recent) != 3 || report.Recent[0].Topic != events.TopicJobSynced || report.Recent[2].Topic != events.TopicIngestReceived {
+		t.Fatalf("Expected the event since startup before the saved ones, got %+v", report.Recent)
+	}
+	// Restored payloads are decoded JSON and encode the same as the originals
+	got, _ := json.Marshal(report.Recent[1].Data)
+	if want, _ := json.Marshal(events.IngestReceived{Bytes: 4}); string(got) != string(want) {
+		t.Errorf("Expected restored payload %s, got %s", want, got)
+	}
+}
+
+func TestMakeEventLogHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	bus := events.NewBus(nil, logger)
+	log := NewEventLog(bus)
+	bus.Publish(events.TopicJobSynced, events.JobSynced{JobID: 7, Health: "healthy"})
+
+	rr := httptest.NewRecorder()
+	MakeEventLogHandler(log, logger)(rr, httptest.NewRequest("GET", "/api/admin/events", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	var response struct {
+		Data struct {
+			Counts map[string]int64 `json:"counts"`
+			Recent []struct {
+				Topic string         `json:"topic"`
+				Data  map[string]any `json:"data"`
+			} `json:"recent"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Data.Counts[events.TopicJobSynced] != 1 || len(response.Data.Recent) != 1 ||
+		response.Data.Recent[0].Data["job_id"] != float64(7) {
+		t.Errorf("Unexpected response: %s", rr.Body.String())
+	}
+}
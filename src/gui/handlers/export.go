@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+	"github.com/melatonein5/LogpushEstimator/src/export"
+)
+
+// Attachment names suggested for exported data, its manifest and backups.
+const (
+	exportFilename         = "logpush-export.csv"
+	streamFilename         = "logpush-export"
+	exportManifestFilename = "logpush-export.manifest.json"
+	backupFilename         = "logpush-backup.db"
+
+	// encryptedSuffix is appended to the names of sealed attachments
+	encryptedSuffix = ".enc"
+)
+
+// parseExportRange reads the required start and end parameters.
+// Exports always cover an explicit range so that the data and manifest
+// requests, and any later verification, describe exactly the same records.
+func parseExportRange(r *http.Request) (time.Time, time.Time, error) {
+	start, err := parseTimestamp(r.URL.Query().Get("start"))
+	if err != nil {
+		return time.Time{}, time.Time{}, &requestError{"start is required (" + timeFormats + ")"}
+	}
+	end, err := parseTimestamp(r.URL.Query().Get("end"))
+	if err != nil {
+		return time.Time{}, time.Time{}, &requestError{"end is required (" + timeFormats + ")"}
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, &requestError{"start must be before end"}
+	}
+	return start, end, nil
+}
+
+// buildExportForRequest validates the request and builds the export. It
+// writes the error response itself and reports whether the caller should
+// continue.
+func buildExportForRequest(w http.ResponseWriter, r *http.Request, db *database.SQLiteController, logger *slog.Logger) ([]byte, export.Manifest, bool) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return nil, export.Manifest{}, false
+	}
+	start, end, err := parseExportRange(r)
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+		return nil, export.Manifest{}, false
+	}
+	data, manifest, err := export.Build(db, start, end)
+	if err != nil {
+		logger.Error("Failed to build export", "error", err, "start", start, "end", end)
+		sendErrorResponse(w, "Failed to build export")
+		return nil, export.Manifest{}, false
+	}
+	return data, manifest, true
+}
+
+// MakeExportCSVHandler creates the GET /api/export/csv?start=&end= handler,
+// returning the raw records in the range as a CSV attachment. The row count
+// and SHA-256 are repeated in X-Export-Rows and X-Export-SHA256 headers; the
+// full manifest is available from /api/export/manifest with the same
+// parameters. When a key is configured the file is sealed and served as
+// logpush-export.csv.enc; the manifest still describes the decrypted CSV.
+//
+// Parameters:
+//   - key: Encryption key for the file, or nil to serve plain CSV
+//   - db: Database controller to export from
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function
+func MakeExportCSVHandler(key *encryption.Key, db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: export csv", "remote_addr", r.RemoteAddr)
+
+		data, manifest, ok := buildExportForRequest(w, r, db, logger)
+		if !ok {
+			return
+		}
+
+		contentType, filename := "text/csv", exportFilename
+		if key != nil {
+			sealed, err := key.Seal(data)
+			if err != nil {
+				logger.Error("Failed to encrypt export", "error", err)
+				sendErrorResponse(w, "Failed to encrypt export")
+				return
+			}
+			data, contentType, filename = sealed, "application/octet-stream", exportFilename+encryptedSuffix
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.Header().Set("X-Export-Rows", strconv.FormatInt(manifest.Rows, 10))
+		w.Header().Set("X-Export-SHA256", manifest.SHA256)
+		w.Header().Set("X-Export-Encrypted", strconv.FormatBool(key != nil))
+		if _, err := w.Write(data); err != nil {
+			logger.Error("Failed to write export", "error", err)
+		}
+	}
+}
+
+// makeStreamExportHandler serves GET /api/export?format=&start=&end=:
+// every column of the raw records in the range, as CSV (the default) or
+// NDJSON, written as they are read so ranges of any size can be pulled into
+// spreadsheets or loaded into BigQuery. The stream has no manifest and
+// cannot be sealed, so it is refused with 403 when a key is configured;
+// /api/export/csv and /api/exports serve audited or sealed files. Once the
+// first record is sent a failure can only end the stream early, so it is
+// logged and the response is cut short.
+func makeStreamExportHandler(key *encryption.Key, db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: export stream", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		if key != nil {
+			sendErrorResponseWithStatus(w, http.StatusForbidden,
+				"Streamed exports are not encrypted; use /api/export/csv or /api/exports while an encryption key is configured")
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = export.FormatCSV
+		}
+		if !export.ValidStreamFormat(format) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "format must be csv or ndjson")
+			return
+		}
+		start, end, err := parseExportRange(r)
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+			return
+		}
+
+		contentType := "text/csv"
+		if format == export.FormatNDJSON {
+			contentType = "application/x-ndjson"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+streamFilename+"."+format+`"`)
+		rows, err := export.Stream(w, db, format, start, end)
+		if err != nil {
+			logger.Error("Failed to stream export", "error", err, "format", format, "rows", rows, "start", start, "end", end)
+			return
+		}
+		logger.Info("Export streamed", "format", format, "rows", rows, "start", start, "end", end)
+	}
+}
+
+// makeExportManifestHandler serves GET /api/export/manifest?start=&end=. The
+// response is the bare manifest (not wrapped in the API envelope) so it can
+// be delivered next to the CSV and later posted to /api/export/verify.
+func makeExportManifestHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: export manifest", "remote_addr", r.RemoteAddr)
+
+		_, manifest, ok := buildExportForRequest(w, r, db, logger)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+exportManifestFilename+`"`)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(manifest); err != nil {
+			logger.Error("Failed to encode manifest", "error", err)
+		}
+	}
+}
+
+// makeExportVerifyHandler serves POST /api/export/verify. The body is a
+// manifest from a previous export; the response reports whether the
+// database still holds exactly the records that were exported.
+func makeExportVerifyHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: export verify", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		var manifest export.Manifest
+		if err := json.NewDecoder(r.Body).Decode(&manifest); err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid manifest: "+err.Error())
+			return
+		}
+		if !manifest.Start.Before(manifest.End) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid manifest: start must be before end")
+			return
+		}
+
+		result, err := export.Verify(db, manifest)
+		if errors.Is(err, export.ErrUnsupportedFormat) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to verify export", "error", err)
+			sendErrorResponse(w, "Failed to verify export")
+			return
+		}
+		logger.Info("Export verified", "valid", result.Valid, "mismatches", result.Mismatches)
+		sendSuccessResponse(w, result)
+	}
+}
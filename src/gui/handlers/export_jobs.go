@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+	"github.com/melatonein5/LogpushEstimator/src/export"
+)
+
+// ExportJobStatus is the response body of POST /api/exports and
+// GET /api/exports/{id}.
+type ExportJobStatus struct {
+	database.ExportJob
+	StatusURL   string `json:"status_url"`             // Where the job's status is polled
+	DownloadURL string `json:"download_url,omitempty"` // Where the file is downloaded, once complete
+}
+
+// ExportJobs builds CSV exports in the background and keeps the files in a
+// directory until they are deleted, so an export of months of records does
+// not hold an HTTP connection open while it is built. Jobs are recorded in
+// the database and built one at a time, in the order requested. It is safe
+// for concurrent use.
+type ExportJobs struct {
+	db     *database.SQLiteController
+	dir    string
+	key    *encryption.Key
+	logger *slog.Logger
+	slot   chan struct{} // Held by the job being built
+}
+
+// NewExportJobs creates an export job runner writing files to dir.
+//
+// Parameters:
+//   - dir: Directory finished exports are written to; created if missing
+//   - key: Encryption key for the files, or nil to write plain CSV
+//   - db: Database controller to export from and record jobs in
+//   - logger: Structured logger for job progress
+//
+// Returns:
+//   - *ExportJobs: Runner with no jobs in progress
+func NewExportJobs(dir string, key *encryption.Key, db *database.SQLiteController, logger *slog.Logger) *ExportJobs {
+	return &ExportJobs{db: db, dir: dir, key: key, logger: logger, slot: make(chan struct{}, 1)}
+}
+
+// Start records a pending export of [start, end) and builds it in the
+// background.
+//
+// Parameters:
+//   - start: Range start (inclusive)
+//   - end: Range end (exclusive)
+//
+// Returns:
+//   - database.ExportJob: The pending job
+//   - error: Any error encountered recording the job
+func (e *ExportJobs) Start(start, end time.Time) (database.ExportJob, error) {
+	job, err := e.db.CreateExportJob(start, end)
+	if err != nil {
+		return job, err
+	}
+	go e.build(job)
+	return job, nil
+}
+
+// build waits for its turn, then writes the job's export to disk and
+// records the outcome.
+func (e *ExportJobs) build(job database.ExportJob) {
+	e.slot <- struct{}{}
+	defer func() { <-e.slot }()
+
+	e.logger.Info("Building export", "id", job.ID, "start", job.Start, "end", job.End)
+	if err := e.db.StartExportJob(job.ID); err != nil {
+		return
+	}
+	if err := e.write(&job); err != nil {
+		e.logger.Error("Failed to build export", "id", job.ID, "error", err)
+		e.db.FailExportJob(job.ID, "Failed to build export")
+		return
+	}
+	if err := e.db.CompleteExportJob(job); err != nil {
+		os.Remove(job.File)
+		return
+	}
+	e.logger.Info("Export complete", "id", job.ID, "rows", job.Rows, "bytes", job.Bytes)
+}
+
+// write builds the export and writes it to a file named after the job,
+// setting the job's file and manifest figures. The file is renamed into
+// place so a download never sees it partly written.
+func (e *ExportJobs) write(job *database.ExportJob) error {
+	data, manifest, err := export.Build(e.db, job.Start, job.End)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("export-%d.csv", job.ID)
+	if e.key != nil {
+		if data, err = e.key.Seal(data); err != nil {
+			return err
+		}
+		name += encryptedSuffix
+	}
+	if err := os.MkdirAll(e.dir, 0o750); err != nil {
+		return err
+	}
+	path := filepath.Join(e.dir, name)
+	if err := os.WriteFile(path+".tmp", data, 0o640); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+	job.File, job.Encrypted = path, e.key != nil
+	job.Rows, job.Bytes, job.SHA256 = manifest.Rows, manifest.Bytes, manifest.SHA256
+	return nil
+}
+
+// exportJobStatus adds the status and download URLs to job.
+func exportJobStatus(job database.ExportJob) ExportJobStatus {
+	status := ExportJobStatus{ExportJob: job, StatusURL: fmt.Sprintf("/api/exports/%d", job.ID)}
+	if job.Status == database.ExportComplete {
+		status.DownloadURL = status.StatusURL + "/download"
+	}
+	return status
+}
+
+// MakeExportJobsHandler creates the handler for asynchronous exports,
+// mounted at both /api/exports and /api/exports/:
+//
+//   - POST /api/exports?start=&end=: Start building a CSV export of the range
+//   - GET /api/exports/{id}: Status of the job, with download_url once complete
+//   - GET /api/exports/{id}/download: The finished file, as from /api/export/csv
+//   - DELETE /api/exports/{id}: Remove a finished job and its file
+//
+// Parameters:
+//   - jobs: Runner building the exports, or nil when no export directory is
+//     configured, in which case every request gets 503
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function
+func MakeExportJobsHandler(jobs *ExportJobs, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: export jobs", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+
+		if jobs == nil {
+			sendErrorResponseWithStatus(w, http.StatusServiceUnavailable, "Export jobs need an export directory")
+			return
+		}
+		if r.URL.Path == "/api/exports" {
+			startExportJob(w, r, jobs, logger)
+			return
+		}
+
+		rest := strings.TrimPrefix(r.URL.Path, "/api/exports/")
+		idText, download := strings.CutSuffix(rest, "/download")
+		id, err := strconv.ParseInt(idText, 10, 64)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusNotFound, "Export not found")
+			return
+		}
+		if download && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		job, found, err := jobs.db.GetExportJob(id)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch export")
+			return
+		}
+		if !found {
+			sendErrorResponseWithStatus(w, http.StatusNotFound, "Export not found")
+			return
+		}
+
+		switch {
+		case download:
+			serveExportFile(w, r, job, logger)
+		case r.Method == http.MethodGet:
+			sendSuccessResponse(w, exportJobStatus(job))
+		case r.Method == http.MethodDelete:
+			if job.Status == database.ExportPending || job.Status == database.ExportRunning {
+				sendErrorResponseWithStatus(w, http.StatusConflict, "Export is still being built")
+				return
+			}
+			if job.File != "" {
+				if err := os.Remove(job.File); err != nil && !errors.Is(err, os.ErrNotExist) {
+					logger.Error("Failed to remove export file", "id", id, "error", err)
+					sendErrorResponse(w, "Failed to delete export")
+					return
+				}
+			}
+			if _, err := jobs.db.DeleteExportJob(id); err != nil {
+				sendErrorResponse(w, "Failed to delete export")
+				return
+			}
+			sendSuccessResponse(w, map[string]int64{"deleted": id})
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// startExportJob serves POST /api/exports.
+func startExportJob(w http.ResponseWriter, r *http.Request, jobs *ExportJobs, logger *slog.Logger) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	start, end, err := parseExportRange(r)
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+		return
+	}
+	job, err := jobs.Start(start, end)
+	if err != nil {
+		sendErrorResponse(w, "Failed to start export")
+		return
+	}
+	logger.Info("Export job started", "id", job.ID, "start", start, "end", end)
+	sendSuccessResponse(w, exportJobStatus(job))
+}
+
+// serveExportFile serves the file of a finished export with the same
+// headers as /api/export/csv. Range requests are supported, so an
+// interrupted download of a large export can be resumed.
+func serveExportFile(w http.ResponseWriter, r *http.Request, job database.ExportJob, logger *slog.Logger) {
+	if job.Status != database.ExportComplete {
+		sendErrorResponseWithStatus(w, http.StatusConflict, "Export is "+job.Status)
+		return
+	}
+	f, err := os.Open(job.File)
+	if err != nil {
+		logger.Error("Failed to open export file", "id", job.ID, "error", err)
+		sendErrorResponseWithStatus(w, http.StatusGone, "Export file is no longer available")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		sendErrorResponse(w, "Failed to read export")
+		return
+	}
+
+	contentType, filename := "text/csv", exportFilename
+	if job.Encrypted {
+		contentType, filename = "application/octet-stream", exportFilename+encryptedSuffix
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("X-Export-Rows", strconv.FormatInt(job.Rows, 10))
+	w.Header().Set("X-Export-SHA256", job.SHA256)
+	w.Header().Set("X-Export-Encrypted", strconv.FormatBool(job.Encrypted))
+	http.ServeContent(w, r, filename, info.ModTime(), f)
+}
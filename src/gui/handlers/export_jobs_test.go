@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestAPIExportJobs(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeExportJobsHandler(NewExportJobs(t.TempDir(), nil, db, logger), logger)
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+		return rr
+	}
+	var resp struct {
+		Data ExportJobStatus `json:"data"`
+	}
+
+	now := time.Now().UTC()
+	query := "?start=" + now.Add(-time.Hour).Format(time.RFC3339) + "&end=" + now.Add(time.Hour).Format(time.RFC3339)
+	rr := serve("POST", "/api/exports"+query)
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	statusURL := resp.Data.StatusURL
+
+	deadline := time.Now().Add(5 * time.Second)
+	for resp.Data.Status != database.ExportComplete {
+		if time.Now().After(deadline) {
+			t.Fatalf("Export did not complete: %+v", resp.Data)
+		}
+		time.Sleep(10 * time.Millisecond)
+		rr = serve("GET", statusURL)
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Invalid status response: %s", rr.Body.String())
+		}
+	}
+	if resp.Data.Rows != 5 || resp.Data.DownloadURL != statusURL+"/download" {
+		t.Errorf("Unexpected completed job %+v", resp.Data)
+	}
+
+	rr = serve("GET", resp.Data.DownloadURL)
+	if rr.Code != http.StatusOK || rr.Header().Get("X-Export-SHA256") != resp.Data.SHA256 || strings.Count(rr.Body.String(), "\n") != 6 {
+		t.Errorf("Expected the CSV with header and 5 rows, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := serve("DELETE", statusURL); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 from DELETE, got %d", rr.Code)
+	}
+	if rr := serve("GET", statusURL); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", rr.Code)
+	}
+
+	if rr := serve("POST", "/api/exports?start=2025-01-02"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without an end, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	MakeAPIHandlers(db, logger)["/api/exports"].ServeHTTP(rr, httptest.NewRequest("POST", "/api/exports"+query, nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without an export directory, got %d", rr.Code)
+	}
+}
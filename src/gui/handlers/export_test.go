@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+	"github.com/melatonein5/LogpushEstimator/src/export"
+)
+
+func TestAPIExportCSVAndManifest(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+
+	now := time.Now().UTC()
+	query := "?start=" + now.Add(-time.Hour).Format(time.RFC3339) + "&end=" + now.Add(time.Hour).Format(time.RFC3339)
+
+	rr := httptest.NewRecorder()
+	handlers["/api/export/csv"].ServeHTTP(rr, httptest.NewRequest("GET", "/api/export/csv"+query, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("Expected text/csv, got %q", ct)
+	}
+	if lines := strings.Count(rr.Body.String(), "\n"); lines != 6 {
+		t.Errorf("Expected header and 5 rows, got %d lines", lines)
+	}
+	csvSum := rr.Header().Get("X-Export-SHA256")
+
+	rr = httptest.NewRecorder()
+	handlers["/api/export/manifest"].ServeHTTP(rr, httptest.NewRequest("GET", "/api/export/manifest"+query, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var manifest export.Manifest
+	if err := json.Unmarshal(rr.Body.Bytes(), &manifest); err != nil {
+		t.Fatalf("Failed to decode manifest: %v", err)
+	}
+	if manifest.Rows != 5 || manifest.LogBytes != 31744 {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+	if manifest.SHA256 != csvSum {
+		t.Errorf("Expected manifest checksum %s to match CSV header %s", manifest.SHA256, csvSum)
+	}
+}
+
+func TestExportCSVEncrypted(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	key, err := encryption.NewKey(bytes.Repeat([]byte{2}, encryption.KeySize))
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	now := time.Now().UTC()
+	query := "?start=" + now.Add(-time.Hour).Format(time.RFC3339) + "&end=" + now.Add(time.Hour).Format(time.RFC3339)
+	rr := httptest.NewRecorder()
+	MakeExportCSVHandler(key, db, logger).ServeHTTP(rr, httptest.NewRequest("GET", "/api/export/csv"+query, nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("X-Export-Encrypted") != "true" {
+		t.Fatalf("Expected an encrypted export, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Header().Get("Content-Disposition"), "logpush-export.csv.enc") {
+		t.Errorf("Unexpected attachment name %q", rr.Header().Get("Content-Disposition"))
+	}
+
+	plain, err := key.Open(rr.Body.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to decrypt export: %v", err)
+	}
+	sum := sha256.Sum256(plain)
+	if hex.EncodeToString(sum[:]) != rr.Header().Get("X-Export-SHA256") {
+		t.Error("Expected the checksum to describe the decrypted CSV")
+	}
+}
+
+func TestAPIExportRequiresRange(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/export/csv"]
+
+	for _, query := range []string{"", "?start=2025-01-01T00:00:00Z", "?start=2025-01-02T00:00:00Z&end=2025-01-01T00:00:00Z"} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/export/csv"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %q, got %d", query, rr.Code)
+		}
+	}
+}
+
+func TestAPIExportStream(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/export"]
+
+	now := time.Now().UTC()
+	query := "start=" + now.Add(-time.Hour).Format(time.RFC3339) + "&end=" + now.Add(time.Hour).Format(time.RFC3339)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/export?"+query, nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("Expected a CSV export by default, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rr.Header().Get("Content-Disposition"), `filename="logpush-export.csv"`) {
+		t.Errorf("Unexpected attachment name %q", rr.Header().Get("Content-Disposition"))
+	}
+	if lines := strings.Count(rr.Body.String(), "\n"); lines != 6 {
+		t.Errorf("Expected header and 5 rows, got %d lines", lines)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/export?format=ndjson&"+query, nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("Expected an NDJSON export, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	var sizes int64
+	for _, line := range strings.Split(strings.TrimSpace(rr.Body.String()), "\n") {
+		var record export.Record
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Failed to decode %q: %v", line, err)
+		}
+		sizes += record.Filesize
+	}
+	if sizes != 31744 {
+		t.Errorf("Expected 31744 bytes across the records, got %d", sizes)
+	}
+
+	for _, bad := range []string{"format=xlsx&" + query, "format=ndjson", "format=csv&start=2025-01-02T00:00:00Z&end=2025-01-01T00:00:00Z"} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/export?"+bad, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %q, got %d", bad, rr.Code)
+		}
+	}
+
+	// A stream cannot be sealed, so it is refused while a key is configured
+	key, err := encryption.NewKey(bytes.Repeat([]byte{2}, encryption.KeySize))
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	sealed := MakeAPIHandlersWithLimiter(db, logger, nil, key, NewExpensiveLimiter())
+	for _, target := range []string{"/api/export?" + query, "/api/export?dataset=http_requests&" + query} {
+		rr := httptest.NewRecorder()
+		sealed["/api/export"].ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusForbidden || strings.Contains(rr.Body.String(), "filesize") {
+			t.Errorf("%s: expected 403 without records while a key is configured, got %d", target, rr.Code)
+		}
+	}
+}
+
+func TestAPIExportVerify(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/export/verify"]
+
+	now := time.Now().UTC()
+	_, manifest, err := export.Build(db, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to build export: %v", err)
+	}
+
+	verify := func(m export.Manifest) export.Verification {
+		t.Helper()
+		body, _ := json.Marshal(m)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/export/verify", strings.NewReader(string(body))))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data export.Verification `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp.Data
+	}
+
+	if v := verify(manifest); !v.Valid {
+		t.Errorf("Expected manifest to verify, mismatches: %v", v.Mismatches)
+	}
+
+	tampered := manifest
+	tampered.SHA256 = strings.Repeat("0", 64)
+	if v := verify(tampered); v.Valid || len(v.Mismatches) != 1 || v.Mismatches[0] != "sha256" {
+		t.Errorf("Expected a sha256 mismatch, got %+v", v)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/export/verify", strings.NewReader(`{"format":"parquet","start":"2025-01-01T00:00:00Z","end":"2025-01-02T00:00:00Z"}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unsupported format, got %d", rr.Code)
+	}
+}
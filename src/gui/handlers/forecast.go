@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/estimator"
+	"github.com/melatonein5/LogpushEstimator/src/reports"
+)
+
+// defaultForecastDays is how many complete days /api/estimate/forecast
+// fits its model to unless days= or last= is given.
+const defaultForecastDays = 14
+
+// ForecastSeries is the projection of one quantity for the month.
+type ForecastSeries struct {
+	MonthToDate int64   `json:"month_to_date"` // Ingested since the start of the month
+	Remaining   float64 `json:"remaining"`     // Projected for the rest of the month
+	Projected   float64 `json:"projected"`     // MonthToDate plus Remaining
+	Lower       float64 `json:"lower"`         // Lower confidence bound of Projected
+	Upper       float64 `json:"upper"`         // Upper confidence bound of Projected
+	DailyTrend  float64 `json:"daily_trend"`   // Change of the expected daily total per day; 0 for a moving average
+}
+
+// Forecast is the response body for /api/estimate/forecast.
+type Forecast struct {
+	Month      string         `json:"month"`       // Month projected, e.g. "2025-09"
+	MonthStart time.Time      `json:"month_start"` // First instant of the month, UTC
+	MonthEnd   time.Time      `json:"month_end"`   // First instant of the next month, UTC
+	AsOf       time.Time      `json:"as_of"`       // When the projection was made
+	Model      string         `json:"model"`       // Model used: "linear" or "moving_average"
+	FitStart   time.Time      `json:"fit_start"`   // First day the model was fitted to; zero when none was
+	Days       int            `json:"days"`        // Complete days the model was fitted to
+	Confidence float64        `json:"confidence"`  // Coverage of the bounds, e.g. 0.95
+	Bytes      ForecastSeries `json:"bytes"`       // Bytes as received
+	Records    ForecastSeries `json:"records"`     // Log lines
+}
+
+// makeForecastHandler serves /api/estimate/forecast, projecting the bytes
+// and log lines of the current UTC month. model= (linear, the default, or
+// moving_average) is fitted to the daily totals of the last `days`
+// complete days (default 14, or last=), counted from the first day with
+// records, and extended from now to the end of the month.
+func makeForecastHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: forecast", "remote_addr", r.RemoteAddr)
+
+		days, err := windowParam(r, "days", 24*time.Hour, defaultForecastDays)
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+			return
+		}
+		days = min(days, maxQueryWindowHours/24)
+		model := r.URL.Query().Get("model")
+		switch model {
+		case "":
+			model = estimator.ForecastLinear
+		case estimator.ForecastLinear, estimator.ForecastMovingAverage:
+		default:
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "model must be linear or moving_average")
+			return
+		}
+
+		asOf := now().UTC()
+		forecast, err := buildForecast(db, model, days, asOf)
+		if err != nil {
+			sendErrorResponse(w, "Failed to forecast volume")
+			return
+		}
+		sendSuccessResponse(w, forecast)
+	}
+}
+
+// buildForecast fits model to the daily totals of the days complete days
+// before asOf and projects the rest of asOf's month.
+func buildForecast(db *database.SQLiteController, model string, days int, asOf time.Time) (Forecast, error) {
+	const day = 24 * time.Hour
+	today := asOf.Truncate(day)
+	monthStart, monthEnd := reports.MonthBounds(asOf)
+	forecast := Forecast{
+		Month:      monthStart.Format(reports.MonthLayout),
+		AsOf:       asOf,
+		Confidence: estimator.ForecastConfidence,
+		MonthStart: monthStart,
+		MonthEnd:   monthEnd,
+	}
+
+	// Days before the first record predate ingestion rather than having
+	// no traffic, so the fit starts at the first day with records
+	buckets, err := db.BucketsByTimeRange(today.Add(-time.Duration(days)*day), today, day)
+	if err != nil {
+		return Forecast{}, err
+	}
+	var dailyBytes, dailyRecords []float64
+	if len(buckets) > 0 {
+		forecast.FitStart = buckets[0].Start
+		n := int(today.Sub(forecast.FitStart) / day)
+		dailyBytes, dailyRecords = make([]float64, n), make([]float64, n)
+		for _, b := range buckets {
+			i := int(b.Start.Sub(forecast.FitStart) / day)
+			dailyBytes[i], dailyRecords[i] = float64(b.Bytes), float64(b.Records)
+		}
+	}
+
+	// The rest of today counts by the fraction still to come
+	var future, weights []float64
+	for d := today; d.Before(monthEnd); d = d.Add(day) {
+		future = append(future, float64(d.Sub(forecast.FitStart)/day))
+		weights = append(weights, min(d.Add(day).Sub(asOf), day).Hours()/24)
+	}
+
+	sofar, err := db.StatsByTimeRange(monthStart, asOf)
+	if err != nil {
+		return Forecast{}, err
+	}
+	project := func(daily []float64, monthToDate int64) (ForecastSeries, estimator.Trend) {
+		trend := estimator.FitTrend(model, daily)
+		remaining, lower, upper := trend.Project(future, weights)
+		mtd := float64(monthToDate)
+		return ForecastSeries{
+			MonthToDate: monthToDate,
+			Remaining:   remaining,
+			Projected:   mtd + remaining,
+			Lower:       mtd + lower,
+			Upper:       mtd + upper,
+			DailyTrend:  trend.Slope,
+		}, trend
+	}
+	var trend estimator.Trend
+	forecast.Bytes, trend = project(dailyBytes, sofar.Bytes)
+	forecast.Records, _ = project(dailyRecords, sofar.Records)
+	forecast.Model, forecast.Days = model, trend.Days
+	if trend.Days > 0 {
+		forecast.Model = trend.Model
+	}
+	return forecast, nil
+}
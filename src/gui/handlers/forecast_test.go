@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/testsupport"
+)
+
+func TestAPIForecast(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	asOf := time.Date(2025, 9, 28, 12, 0, 0, 0, time.UTC)
+	SetClock(testsupport.NewClock(asOf))
+	defer SetClock(nil)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/estimate/forecast"]
+	get := func(query string) (int, Forecast) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/estimate/forecast"+query, nil))
+		var resp struct {
+			Data Forecast `json:"data"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return rr.Code, resp.Data
+	}
+
+	if code, f := get(""); code != 200 || f.Days != 0 || f.Bytes.Projected != 0 || f.Month != "2025-09" {
+		t.Errorf("Expected an empty forecast without records, got %d %+v", code, f)
+	}
+
+	// Ingestion started on the 21st and grows by 100 bytes a day
+	for d := 0; d < 7; d++ {
+		day := time.Date(2025, 9, 21+d, 6, 0, 0, 0, time.UTC)
+		if err := db.InsertLog(database.LogSize{Timestamp: day, Filesize: int64(1000 + 100*d), RecordCount: 10}); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+	if err := db.InsertLog(database.LogSize{Timestamp: asOf.Add(-time.Hour), Filesize: 800, RecordCount: 10}); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+
+	code, f := get("")
+	if code != 200 || f.Model != "linear" || f.Days != 7 || !f.FitStart.Equal(time.Date(2025, 9, 21, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Unexpected forecast %d %+v", code, f)
+	}
+	// Half of the 28th (1700) and the 29th and 30th (1800 and 1900)
+	if f.Bytes.MonthToDate != 7*1000+2100+800 || f.Bytes.Remaining != 850+1800+1900 || f.Bytes.DailyTrend != 100 {
+		t.Errorf("Unexpected bytes forecast %+v", f.Bytes)
+	}
+	if f.Records.Remaining != 25 || f.Records.Lower > f.Records.Projected || f.Records.Upper < f.Records.Projected {
+		t.Errorf("Unexpected records forecast %+v", f.Records)
+	}
+
+	if code, f := get("?model=moving_average&days=2"); code != 200 || f.Model != "moving_average" || f.Days != 2 || f.Bytes.Remaining != 1550*2.5 {
+		t.Errorf("Unexpected moving average forecast %d %+v", code, f)
+	}
+	if code, _ := get("?model=arima"); code != 400 {
+		t.Errorf("Expected 400 for an unknown model, got %d", code)
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// defaultForwardingWindow is the range /api/stats/forwarding covers when
+// none is given.
+const defaultForwardingWindow = 24 * time.Hour
+
+// ForwardingHour is one hour of forwarding requests to a destination.
+type ForwardingHour struct {
+	Timestamp    string  `json:"timestamp"`      // ISO timestamp for the start of the hour
+	Requests     int64   `json:"requests"`       // Forwarding requests sent
+	Errors       int64   `json:"errors"`         // Requests that got no response or a non-2xx status
+	AvgLatencyMs float64 `json:"avg_latency_ms"` // Mean latency
+	MaxLatencyMs float64 `json:"max_latency_ms"` // Slowest request
+}
+
+// ForwardingDestination summarizes the requests relay mode sent to one
+// destination.
+type ForwardingDestination struct {
+	Destination  string           `json:"destination"`    // Upstream host
+	Requests     int64            `json:"requests"`       // Forwarding requests sent
+	Errors       int64            `json:"errors"`         // Requests that got no response or a non-2xx status
+	ErrorRate    float64          `json:"error_rate"`     // Errors as a percentage of requests
+	AvgLatencyMs float64          `json:"avg_latency_ms"` // Mean latency
+	MaxLatencyMs float64          `json:"max_latency_ms"` // Slowest request
+	StatusCodes  map[string]int64 `json:"status_codes"`   // Requests per status code, "none" when no response was received
+	Hours        []ForwardingHour `json:"hours"`          // Hours with requests, oldest first
+}
+
+// ForwardingReport is the response body for /api/stats/forwarding.
+type ForwardingReport struct {
+	Start        string                  `json:"start"`                  // Start of the range
+	End          string                  `json:"end"`                    // End of the range
+	Destinations []ForwardingDestination `json:"destinations,omitempty"` // Destinations with requests in the range, by name; absent when there are none
+}
+
+// makeForwardingHandler serves /api/stats/forwarding, reporting the latency
+// and status codes of the requests relay mode sent to each upstream
+// destination, overall and per hour. It covers the range given by hours=,
+// or start= and end=, and the last 24 hours by default. Statistics are kept
+// per hour, so the range is widened to whole hours.
+func makeForwardingHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: forwarding statistics", "remote_addr", r.RemoteAddr)
+
+		start, end, err := parseOptionalRange(r)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if end.IsZero() {
+			end = now().UTC()
+		}
+		if start.IsZero() {
+			start = end.Add(-defaultForwardingWindow)
+		}
+
+		rows, err := db.RelayForwardsByTimeRange(start, end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch forwarding statistics")
+			return
+		}
+		sendSuccessResponse(w, buildForwardingReport(rows, start, end))
+	}
+}
+
+// forwardingTotals accumulates forwarding counters.
+type forwardingTotals struct {
+	requests, errors int64
+	latencySum       float64
+	latencyMax       float64
+}
+
+// add counts the requests of one hourly counter row.
+func (t *forwardingTotals) add(row database.RelayForwardHour) {
+	t.requests += row.Requests
+	if row.Status/100 != 2 {
+		t.errors += row.Requests
+	}
+	t.latencySum += row.LatencyMsSum
+	t.latencyMax = max(t.latencyMax, row.LatencyMsMax)
+}
+
+// avgLatency returns the mean latency, or 0 without requests.
+func (t forwardingTotals) avgLatency() float64 {
+	if t.requests == 0 {
+		return 0
+	}
+	return t.latencySum / float64(t.requests)
+}
+
+// buildForwardingReport sums hourly counters, ordered by destination and
+// hour, into one entry per destination.
+func buildForwardingReport(rows []database.RelayForwardHour, start, end time.Time) ForwardingReport {
+	report := ForwardingReport{Start: start.UTC().Format(time.RFC3339), End: end.UTC().Format(time.RFC3339)}
+	for i := 0; i < len(rows); {
+		dest := ForwardingDestination{Destination: rows[i].Destination, StatusCodes: make(map[string]int64), Hours: []ForwardingHour{}}
+		var total forwardingTotals
+		for i < len(rows) && rows[i].Destination == dest.Destination {
+			hour := rows[i].Hour
+			var hourTotal forwardingTotals
+			for ; i < len(rows) && rows[i].Destination == dest.Destination && rows[i].Hour.Equal(hour); i++ {
+				code := "none"
+				if rows[i].Status != 0 {
+					code = strconv.Itoa(rows[i].Status)
+				}
+				dest.StatusCodes[code] += rows[i].Requests
+				total.add(rows[i])
+				hourTotal.add(rows[i])
+			}
+			dest.Hours = append(dest.Hours, ForwardingHour{
+				Timestamp:    hour.Format(time.RFC3339),
+				Requests:     hourTotal.requests,
+				Errors:       hourTotal.errors,
+				AvgLatencyMs: hourTotal.avgLatency(),
+				MaxLatencyMs: hourTotal.latencyMax,
+			})
+		}
+		dest.Requests, dest.Errors = total.requests, total.errors
+		dest.AvgLatencyMs, dest.MaxLatencyMs = total.avgLatency(), total.latencyMax
+		if total.requests > 0 {
+			dest.ErrorRate = float64(total.errors) / float64(total.requests) * 100
+		}
+		report.Destinations = append(report.Destinations, dest)
+	}
+	return report
+}
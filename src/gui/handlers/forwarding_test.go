@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIForwarding(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/stats/forwarding"]
+	get := func(query string) (int, string) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/stats/forwarding"+query, nil))
+		return rr.Code, rr.Body.String()
+	}
+
+	if code, body := get(""); code != 200 || !json.Valid([]byte(body)) || strings.Contains(body, "destinations") {
+		t.Errorf("Expected no destinations before any relay traffic, got %d %s", code, body)
+	}
+	if code, _ := get("?start=yesterday&end=today"); code != 400 {
+		t.Errorf("Expected 400 for a malformed range, got %d", code)
+	}
+
+	db.RecordRelayForward("hec.example.com", 200, 20*time.Millisecond)
+	db.RecordRelayForward("hec.example.com", 200, 40*time.Millisecond)
+	db.RecordRelayForward("hec.example.com", 503, 90*time.Millisecond)
+	db.RecordRelayForward("hec.example.com", 0, 30*time.Millisecond)
+	db.RecordRelayForward("bucket.example.com", 200, 10*time.Millisecond)
+
+	code, body := get("?hours=2")
+	var resp struct {
+		Data ForwardingReport `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); code != 200 || err != nil {
+		t.Fatalf("Unexpected response %d %s", code, body)
+	}
+	if len(resp.Data.Destinations) != 2 || resp.Data.Destinations[0].Destination != "bucket.example.com" {
+		t.Fatalf("Expected two destinations by name, got %+v", resp.Data.Destinations)
+	}
+	hec := resp.Data.Destinations[1]
+	if hec.Requests != 4 || hec.Errors != 2 || hec.ErrorRate != 50 || hec.AvgLatencyMs != 45 || hec.MaxLatencyMs != 90 {
+		t.Errorf("Unexpected totals %+v", hec)
+	}
+	if hec.StatusCodes["200"] != 2 || hec.StatusCodes["503"] != 1 || hec.StatusCodes["none"] != 1 {
+		t.Errorf("Unexpected status codes %v", hec.StatusCodes)
+	}
+	if len(hec.Hours) != 1 || hec.Hours[0].Requests != 4 || hec.Hours[0].Errors != 2 || hec.Hours[0].AvgLatencyMs != 45 {
+		t.Errorf("Unexpected hours %+v", hec.Hours)
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/testsupport"
+)
+
+// TestGoldenResponses checks the dashboard's main API responses for a fixed
+// set of records against testdata/golden. Run with -update after an
+// intended change to a response.
+func TestGoldenResponses(t *testing.T) {
+	clock := testsupport.NewClock(testsupport.Epoch)
+	SetClock(clock)
+	defer SetClock(nil)
+
+	db := testsupport.NewDatabase(t)
+	testsupport.SeedLogs(t, db, testsupport.LoadLogs(t, "logs.json", clock.Now()))
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+
+	tests := []struct {
+		golden string
+		target string
+	}{
+		{"summary", "/api/stats/summary"},
+		{"summary_24h", "/api/stats/summary?hours=24"},
+		{"timeseries_24h", "/api/charts/timeseries?hours=24"},
+		{"breakdown", "/api/charts/breakdown"},
+		{"recent_6h", "/api/logs/recent?hours=6"},
+		{"tenants", "/api/tenants"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			handler, ok := handlers[req.URL.Path]
+			if !ok {
+				t.Fatalf("No handler for %s", req.URL.Path)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			testsupport.AssertGoldenJSON(t, tt.golden, rec.Body.Bytes())
+		})
+	}
+}
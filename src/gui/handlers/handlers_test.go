@@ -14,7 +14,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/melatonein5/LogpushEstimator/src/config"
 	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/i18n"
 )
 
 func TestMakeDashboardHandler(t *testing.T) {
@@ -44,6 +46,66 @@ func TestMakeDashboardHandler(t *testing.T) {
 	}
 }
 
+func TestDashboardTemplateOverride(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	dir := t.TempDir()
+	SetTemplateOverrideDir(dir)
+	defer SetTemplateOverrideDir("")
+
+	if got := dashboardTemplatePath(); got != dashboardTemplate {
+		t.Errorf("Expected the built-in template without an override file, got %s", got)
+	}
+
+	override := filepath.Join(dir, "dashboard.html")
+	if err := os.WriteFile(override, []byte("custom {{.Tenant}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	MakeDashboardHandler(logger)(rr, httptest.NewRequest("GET", "/", nil))
+	if rr.Code != http.StatusOK || rr.Body.String() != "custom " {
+		t.Errorf("Expected the override template, got %d: %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDashboardLocale(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	dir := t.TempDir()
+	SetTemplateOverrideDir(dir)
+	defer SetTemplateOverrideDir("")
+	if err := os.WriteFile(filepath.Join(dir, "dashboard.html"), []byte(`{{.Locale}}|{{t "Total Records"}}|{{range .RangeOptions}}{{.Label}};{{end}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	render := func(target, acceptLanguage string) string {
+		t.Helper()
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		rr := httptest.NewRecorder()
+		MakeDashboardHandler(logger)(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", target, rr.Code)
+		}
+		return rr.Body.String()
+	}
+	if got := render("/", "de-AT,de;q=0.9"); !strings.HasPrefix(got, "de|Datensätze gesamt|Letzte Stunde;") {
+		t.Errorf("Expected a German dashboard, got %q", got)
+	}
+	if got := render("/?lang=fr", "de"); !strings.HasPrefix(got, "fr|Enregistrements|") {
+		t.Errorf("Expected lang to override Accept-Language, got %q", got)
+	}
+	if got := render("/?lang=xx", "pt-BR"); !strings.HasPrefix(got, "en|Total Records|Last Hour;") {
+		t.Errorf("Expected English for unsupported languages, got %q", got)
+	}
+
+	// The built-in template renders in every supported locale
+	SetTemplateOverrideDir(filepath.Join("..", "templates"))
+	for _, locale := range i18n.Supported() {
+		if got := render("/?lang="+locale, ""); !strings.Contains(got, `<html lang="`+locale+`">`) {
+			t.Errorf("Expected the dashboard in %s, got %.200q", locale, got)
+		}
+	}
+}
+
 func TestMakeStaticFileHandler(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
@@ -268,8 +330,8 @@ func TestAPITimeRangeQueryMissingParams(t *testing.T) {
 	rr := httptest.NewRecorder()
 	handlers["/api/logs/range"].ServeHTTP(rr, req)
 
-	if status := rr.Code; status != http.StatusInternalServerError {
-		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
 	}
 
 	var response APIResponse
@@ -303,8 +365,8 @@ func TestAPITimeRangeQueryInvalidFormat(t *testing.T) {
 	rr := httptest.NewRecorder()
 	handlers["/api/logs/range"].ServeHTTP(rr, req)
 
-	if status := rr.Code; status != http.StatusInternalServerError {
-		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
 	}
 
 	var response APIResponse
@@ -518,11 +580,14 @@ func TestCalculateSizeBreakdown(t *testing.T) {
 		{ID: 6, Filesize: 50 * 1024 * 1024}, // > 10MB
 	}
 
-	breakdown := calculateSizeBreakdown(logs)
+	breakdown := calculateSizeBreakdown(logs, config.UnitsBinary)
 
 	if len(breakdown) != 6 {
 		t.Errorf("Expected 6 size ranges, got %d", len(breakdown))
 	}
+	if breakdown[1].Range != "1KiB - 10KiB" || breakdown[5].Range != "> 10MiB" {
+		t.Errorf("Expected binary range labels, got %q and %q", breakdown[1].Range, breakdown[5].Range)
+	}
 
 	// Each range should have exactly 1 entry (16.67% each)
 	for i, item := range breakdown {
@@ -537,6 +602,21 @@ func TestCalculateSizeBreakdown(t *testing.T) {
 	}
 }
 
+func TestCalculateSizeBreakdownDecimal(t *testing.T) {
+	// 1000 bytes is 1KB in decimal units but under 1KiB
+	breakdown := calculateSizeBreakdown([]database.LogSize{{Filesize: 1000}, {Filesize: 1_000_000}}, config.UnitsDecimal)
+
+	want := []string{"< 1KB", "1KB - 10KB", "10KB - 100KB", "100KB - 1MB", "1MB - 10MB", "> 10MB"}
+	for i, item := range breakdown {
+		if item.Range != want[i] {
+			t.Errorf("Expected range %d to be %q, got %q", i, want[i], item.Range)
+		}
+	}
+	if breakdown[1].Count != 1 || breakdown[4].Count != 1 {
+		t.Errorf("Expected 1000 bytes in 1KB - 10KB and 10^6 in 1MB - 10MB, got %+v", breakdown)
+	}
+}
+
 func TestSendSuccessResponse(t *testing.T) {
 	rr := httptest.NewRecorder()
 	testData := map[string]string{"test": "data"}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// Number of recorded integrity issues returned by default, and at most.
+const (
+	defaultIntegrityLimit = 100
+	maxIntegrityLimit     = 1000
+)
+
+// makeIntegrityHandler serves /api/admin/integrity. GET lists the most
+// recently recorded discrepancies (limit, default 100); POST runs the
+// integrity checks immediately, repairing derived data unless repair=false.
+func makeIntegrityHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: integrity", "remote_addr", r.RemoteAddr, "method", r.Method)
+
+		switch r.Method {
+		case http.MethodGet:
+			limit := defaultIntegrityLimit
+			if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+				l, err := strconv.Atoi(limitStr)
+				if err != nil || l <= 0 {
+					sendErrorResponseWithStatus(w, http.StatusBadRequest, "limit must be a positive integer")
+					return
+				}
+				limit = min(l, maxIntegrityLimit)
+			}
+			issues, err := db.ListIntegrityIssues(limit)
+			if err != nil {
+				sendErrorResponse(w, "Failed to load integrity issues")
+				return
+			}
+			sendSuccessResponse(w, map[string]interface{}{"issues": issues})
+
+		case http.MethodPost:
+			repair := true
+			if repairStr := r.URL.Query().Get("repair"); repairStr != "" {
+				parsed, err := strconv.ParseBool(repairStr)
+				if err != nil {
+					sendErrorResponseWithStatus(w, http.StatusBadRequest, "repair must be true or false")
+					return
+				}
+				repair = parsed
+			}
+			run, err := db.CheckIntegrity(repair)
+			if err != nil {
+				sendErrorResponse(w, "Failed to run integrity checks")
+				return
+			}
+			sendSuccessResponse(w, run)
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestAPIIntegrityRunAndList(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/admin/integrity"]
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/integrity?repair=false", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var runResp struct {
+		Data database.IntegrityRun `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &runResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if runResp.Data.Repair || len(runResp.Data.Issues) != 0 || len(runResp.Data.Checks) == 0 {
+		t.Errorf("Expected a clean check-only run, got %+v", runResp.Data)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/integrity", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	var listResp struct {
+		Data struct {
+			Issues []database.IntegrityIssue `json:"issues"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &listResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if listResp.Data.Issues == nil || len(listResp.Data.Issues) != 0 {
+		t.Errorf("Expected an empty issue list, got %+v", listResp.Data.Issues)
+	}
+}
+
+func TestAPIIntegrityRejectsBadInput(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/admin/integrity"]
+
+	cases := []struct {
+		method, query string
+		want          int
+	}{
+		{"POST", "?repair=maybe", http.StatusBadRequest},
+		{"GET", "?limit=0", http.StatusBadRequest},
+		{"DELETE", "", http.StatusMethodNotAllowed},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(tc.method, "/api/admin/integrity"+tc.query, nil))
+		if rr.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.query, tc.want, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+)
+
+// makeLabelsHandler serves /api/labels: the batches, records and bytes
+// stored per label key and value in the range selected by last, start/end
+// or hours (the whole history without one). Labels are grouped by key, then
+// listed largest first; a batch with several labels counts towards each.
+// With ?dataset=, ?zone= or ?label= only the matching batches are counted.
+func makeLabelsHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: labels", "remote_addr", r.RemoteAddr)
+
+		start, end, all, err := requestRange(r)
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+			return
+		}
+		if all {
+			start, end = time.Time{}, time.Time{}
+		}
+
+		usage, err := db.QueryLabelUsage(start, end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to query label usage")
+			return
+		}
+		sendSuccessResponse(w, usage)
+	}
+}
+
+// LabelKeyReport describes the stored values of one label key against the
+// configured limits.
+type LabelKeyReport struct {
+	ingest.LabelCardinality
+	Records   int64 `json:"records"`    // Stored batches carrying the key
+	TotalSize int64 `json:"total_size"` // Sum of those batches' sizes in bytes
+	AtLimit   bool  `json:"at_limit"`   // Whether new values are merged or rejected
+}
+
+// LabelAdminReport is the GET /api/admin/labels response.
+type LabelAdminReport struct {
+	Limits ingest.LabelLimits `json:"limits"` // Limits in force, with defaults applied
+	Keys   []LabelKeyReport   `json:"keys"`   // Every known key, ordered by key
+}
+
+// LabelRemovalReport is the DELETE /api/admin/labels response.
+type LabelRemovalReport struct {
+	Key     string `json:"key"`             // Label key removed
+	Value   string `json:"value,omitempty"` // Value removed; empty for every value
+	DryRun  bool   `json:"dry_run"`         // True when nothing was changed
+	Records int64  `json:"records"`         // Records changed (or that would be)
+}
+
+// MakeLabelAdminHandler creates the /api/admin/labels handler. GET reports
+// the label_limits in force and, per label key, its distinct values, the
+// batches stored with it and how often its limit merged or rejected a
+// batch since startup. DELETE ?key=[&value=] removes the label from every
+// stored record (with dry_run=true, only counts them) and frees its values
+// in guard, for cleaning up a key that carried unbounded values.
+//
+// Parameters:
+//   - guard: Label guard the ingestion handler enforces the limits with
+//   - db: Database controller holding the records and configuration
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function
+func MakeLabelAdminHandler(guard *ingest.LabelGuard, db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: admin labels", "remote_addr", r.RemoteAddr, "method", r.Method)
+
+		switch r.Method {
+		case http.MethodGet:
+			doc, err := config.Export(db)
+			if err != nil {
+				sendErrorResponse(w, "Failed to load label limits")
+				return
+			}
+			usage, err := db.QueryLabelUsage(time.Time{}, time.Time{})
+			if err != nil {
+				sendErrorResponse(w, "Failed to query label usage")
+				return
+			}
+			sendSuccessResponse(w, labelAdminReport(doc.LabelLimits.Limits(), guard.Cardinality(), usage))
+		case http.MethodDelete:
+			q := r.URL.Query()
+			key, value := q.Get("key"), q.Get("value")
+			if !ingest.ValidLabelKey(key) || (value != "" && !ingest.ValidLabel(key, value)) {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "key (and value, if given) must be a valid label")
+				return
+			}
+			report := LabelRemovalReport{Key: key, Value: value, DryRun: isDryRun(r)}
+			var err error
+			if report.DryRun {
+				report.Records, err = db.CountLabel(key, value)
+			} else {
+				report.Records, err = db.RemoveLabel(key, value)
+			}
+			if err != nil {
+				sendErrorResponse(w, "Failed to remove label")
+				return
+			}
+			if !report.DryRun {
+				guard.Forget(key, value)
+				logger.Info("Label removed", "key", key, "value", value, "records", report.Records)
+			}
+			sendSuccessResponse(w, report)
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// labelAdminReport merges the guard's cardinality with the stored usage.
+func labelAdminReport(limits ingest.LabelLimits, cardinality []ingest.LabelCardinality, usage []database.LabelUsage) LabelAdminReport {
+	report := LabelAdminReport{Limits: limits, Keys: make([]LabelKeyReport, 0, len(cardinality))}
+	byKey := make(map[string]int, len(cardinality))
+	for _, c := range cardinality {
+		byKey[c.Key] = len(report.Keys)
+		report.Keys = append(report.Keys, LabelKeyReport{LabelCardinality: c, AtLimit: c.Values >= limits.MaxValues})
+	}
+	for _, u := range usage {
+		// Usage is listed per value; a batch carries each key at most once
+		i, ok := byKey[u.Key]
+		if !ok {
+			continue
+		}
+		report.Keys[i].Records += u.Records
+		report.Keys[i].TotalSize += u.TotalSize
+	}
+	return report
+}
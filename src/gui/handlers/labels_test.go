@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+)
+
+func TestAPILabelFiltering(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	for _, l := range []database.LogSize{
+		{Filesize: 100, RecordCount: 1, Zone: "example.com", Labels: map[string]string{"env": "prod", "team": "edge"}},
+		{Filesize: 200, RecordCount: 2, Zone: "example.org", Labels: map[string]string{"env": "prod"}},
+		{Filesize: 50, RecordCount: 1, Labels: map[string]string{"env": "staging"}},
+		{Filesize: 7, RecordCount: 1},
+	} {
+		if err := db.InsertLog(l); err != nil {
+			t.Fatalf("InsertLog failed: %v", err)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+	get := func(path, target string, data any) int {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handlers[path].ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &APIResponse{Data: data}); err != nil {
+				t.Fatalf("%s: failed to decode response: %v", target, err)
+			}
+		}
+		return rr.Code
+	}
+
+	var stats LogSizeStats
+	if code := get("/api/stats/summary", "/api/stats/summary?label=env:prod", &stats); code != http.StatusOK || stats.TotalRecords != 2 || stats.TotalSize != 300 {
+		t.Errorf("Expected the two env=prod batches, got %d %+v", code, stats)
+	}
+	if get("/api/stats/summary", "/api/stats/summary?label=env:prod&label=team:edge", &stats); stats.TotalRecords != 1 || stats.TotalSize != 100 {
+		t.Errorf("Expected the batch with both labels, got %+v", stats)
+	}
+	if get("/api/stats/summary", "/api/stats/summary?label=env:prod&zone=example.org", &stats); stats.TotalRecords != 1 || stats.TotalSize != 200 {
+		t.Errorf("Expected example.org's env=prod batch, got %+v", stats)
+	}
+	var logs []database.LogSize
+	if get("/api/logs/recent", "/api/logs/recent?label=env:staging", &logs); len(logs) != 1 || logs[0].Labels["env"] != "staging" {
+		t.Errorf("Expected the staging batch with its labels, got %+v", logs)
+	}
+	for _, target := range []string{"/api/stats/summary?label=env", "/api/stats/summary?label=Env:prod", "/api/stats/summary?label=env:prod&label=env:staging"} {
+		if code := get("/api/stats/summary", target, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, code)
+		}
+	}
+
+	var usage []database.LabelUsage
+	if code := get("/api/labels", "/api/labels", &usage); code != http.StatusOK || len(usage) != 3 {
+		t.Fatalf("Expected three labels, got %d %+v", code, usage)
+	}
+	if usage[0].Key != "env" || usage[0].Value != "prod" || usage[0].TotalSize != 300 || usage[2].Key != "team" {
+		t.Errorf("Expected labels by key, largest first, got %+v", usage)
+	}
+	if get("/api/labels", "/api/labels?zone=example.com", &usage); len(usage) != 2 || usage[0].TotalSize != 100 {
+		t.Errorf("Expected example.com's labels only, got %+v", usage)
+	}
+}
+
+func TestLabelAdminHandler(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	for _, l := range []database.LogSize{
+		{Filesize: 100, Labels: map[string]string{"env": "prod", "request": "a1"}},
+		{Filesize: 200, Labels: map[string]string{"env": "prod", "request": "b2"}},
+	} {
+		if err := db.InsertLog(l); err != nil {
+			t.Fatalf("InsertLog failed: %v", err)
+		}
+	}
+	guard := ingest.NewLabelGuard()
+	for _, v := range []string{"a1", "b2"} {
+		guard.Observe("request", v)
+	}
+	guard.Observe("env", "prod")
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeLabelAdminHandler(guard, db, logger)
+	serve := func(method, target string, data any) int {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &APIResponse{Data: data}); err != nil {
+				t.Fatalf("%s: failed to decode response: %v", target, err)
+			}
+		}
+		return rr.Code
+	}
+
+	var report LabelAdminReport
+	if code := serve("GET", "/api/admin/labels", &report); code != http.StatusOK || report.Limits.MaxValues != ingest.DefaultMaxLabelValues || len(report.Keys) != 2 {
+		t.Fatalf("Expected the default limits and two keys, got %d %+v", code, report)
+	}
+	if k := report.Keys[1]; k.Key != "request" || k.Values != 2 || k.Records != 2 || k.TotalSize != 300 || k.AtLimit {
+		t.Errorf("Unexpected request key report %+v", k)
+	}
+
+	var removal LabelRemovalReport
+	if code := serve("DELETE", "/api/admin/labels?key=request&dry_run=true", &removal); code != http.StatusOK || !removal.DryRun || removal.Records != 2 {
+		t.Errorf("Expected a dry run counting 2 records, got %d %+v", code, removal)
+	}
+	if serve("GET", "/api/admin/labels", &report); len(report.Keys) != 2 {
+		t.Errorf("Expected a dry run to change nothing, got %+v", report.Keys)
+	}
+	if code := serve("DELETE", "/api/admin/labels?key=request", &removal); code != http.StatusOK || removal.Records != 2 {
+		t.Errorf("Expected 2 records changed, got %d %+v", code, removal)
+	}
+	if serve("GET", "/api/admin/labels", &report); len(report.Keys) != 1 || report.Keys[0].Key != "env" || report.Keys[0].Records != 2 {
+		t.Errorf("Expected only env left, got %+v", report.Keys)
+	}
+
+	for _, target := range []string{"/api/admin/labels", "/api/admin/labels?key=Bad%20Key", "/api/admin/labels?key=env&value=a,b"} {
+		if code := serve("DELETE", target, nil); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", target, code)
+		}
+	}
+	if code := serve("POST", "/api/admin/labels", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", code)
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// Widget types the dashboard knows how to render.
+const (
+	WidgetStat  = "stat"  // A single value picked from the response
+	WidgetChart = "chart" // A line chart over an array of rows
+	WidgetTable = "table" // A table over an array of rows
+)
+
+// Widget describes one dashboard widget and where its data comes from.
+// Field paths are dotted, with numeric segments indexing arrays, e.g.
+// "windows.0.availability"; they are resolved against the data of the
+// standard response envelope.
+type Widget struct {
+	ID       string            `json:"id"`                // Stable identifier, unique within the layout
+	Type     string            `json:"type"`              // WidgetStat, WidgetChart or WidgetTable
+	Title    string            `json:"title"`             // Heading shown on the widget
+	Endpoint string            `json:"endpoint"`          // API path the data is read from
+	Params   map[string]string `json:"params,omitempty"`  // Query parameters sent with every request
+	Range    bool              `json:"range"`             // Whether the dashboard's selected time range is added to Params
+	Field    string            `json:"field,omitempty"`   // Stat: path of the value; chart and table: path of the rows, empty for the data itself
+	Format   string            `json:"format,omitempty"`  // Stat: how the value is formatted, see WidgetColumn
+	X        string            `json:"x,omitempty"`       // Chart: row field labelling the x axis
+	Columns  []WidgetColumn    `json:"columns,omitempty"` // Chart: plotted series; table: columns in order
+}
+
+// WidgetColumn is a table column or chart series.
+type WidgetColumn struct {
+	Field  string `json:"field"`            // Row field holding the value
+	Label  string `json:"label"`            // Column heading or series name
+	Format string `json:"format,omitempty"` // "number" (default), "bytes", "percent", "currency" (with the response's currency) or "datetime"
+}
+
+// DashboardLayout is the response body for /api/dashboard/layout.
+type DashboardLayout struct {
+	Widgets []Widget `json:"widgets"` // Widgets in display order
+}
+
+// defaultLayout is the widget layout served to the dashboard. The built-in
+// cards and charts are rendered by the page itself; these widgets follow
+// them, so a new server-side metric only needs an entry here to appear.
+var defaultLayout = []Widget{
+	{
+		ID: "avg-record-size", Type: WidgetStat, Title: "Average Record Size",
+		Endpoint: "/api/stats/records", Range: true, Field: "avg_record_size", Format: "bytes",
+	},
+	{
+		ID: "monthly-cost", Type: WidgetStat, Title: "Projected Monthly Cost",
+		Endpoint: "/api/estimate/cost", Field: "monthly_cost", Format: "currency",
+	},
+	{
+		ID: "ingest-availability", Type: WidgetStat, Title: "Ingest Availability (7d)",
+		Endpoint: "/api/slo/ingest", Field: "windows.0.availability", Format: "percent",
+	},
+	{
+		ID: "records-per-hour", Type: WidgetChart, Title: "📝 Records per Hour",
+		Endpoint: "/api/charts/record-sizes", Range: true, X: "timestamp",
+		Columns: []WidgetColumn{{Field: "records", Label: "Records"}},
+	},
+	{
+		ID: "ingest-slo", Type: WidgetTable, Title: "🎯 Ingest SLO",
+		Endpoint: "/api/slo/ingest", Field: "windows",
+		Columns: []WidgetColumn{
+			{Field: "window", Label: "Window"},
+			{Field: "successes", Label: "Successes"},
+			{Field: "failures", Label: "Failures"},
+			{Field: "availability", Label: "Availability", Format: "percent"},
+			{Field: "error_budget_remaining", Label: "Error Budget Left", Format: "percent"},
+		},
+	},
+	{
+		ID: "relay-destinations", Type: WidgetTable, Title: "📡 Relay Destinations",
+		Endpoint: "/api/stats/forwarding", Range: true, Field: "destinations",
+		Columns: []WidgetColumn{
+			{Field: "destination", Label: "Destination"},
+			{Field: "requests", Label: "Requests"},
+			{Field: "error_rate", Label: "Errors", Format: "percent"},
+			{Field: "avg_latency_ms", Label: "Avg Latency (ms)"},
+			{Field: "max_latency_ms", Label: "Max Latency (ms)"},
+		},
+	},
+}
+
+// makeDashboardLayoutHandler serves /api/dashboard/layout: the widgets the
+// dashboard renders after its built-in cards and charts. Under /t/{tenant}/
+// only widgets whose endpoints are tenant-scoped are listed.
+func makeDashboardLayoutHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: dashboard layout", "remote_addr", r.RemoteAddr)
+
+		layout := DashboardLayout{Widgets: make([]Widget, 0, len(defaultLayout))}
+		for _, widget := range defaultLayout {
+			if db.Tenant() == "" || slices.Contains(tenantScopedPaths, widget.Endpoint) {
+				layout.Widgets = append(layout.Widgets, widget)
+			}
+		}
+		sendSuccessResponse(w, layout)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestAPIDashboardLayout(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	apiHandlers := MakeAPIHandlers(db, logger)
+	get := func(handler http.HandlerFunc) DashboardLayout {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/dashboard/layout", nil))
+		var resp struct {
+			Data DashboardLayout `json:"data"`
+		}
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Could not parse JSON response: %v", err)
+		}
+		return resp.Data
+	}
+
+	layout := get(apiHandlers["/api/dashboard/layout"])
+	if len(layout.Widgets) != len(defaultLayout) {
+		t.Fatalf("Expected every widget on the instance dashboard, got %d", len(layout.Widgets))
+	}
+	seen := map[string]bool{}
+	for _, w := range layout.Widgets {
+		if seen[w.ID] {
+			t.Errorf("Duplicate widget ID %q", w.ID)
+		}
+		seen[w.ID] = true
+		if _, ok := apiHandlers[w.Endpoint]; !ok {
+			t.Errorf("Widget %q reads from unregistered endpoint %s", w.ID, w.Endpoint)
+		}
+		if !slices.Contains([]string{WidgetStat, WidgetChart, WidgetTable}, w.Type) {
+			t.Errorf("Widget %q has unknown type %q", w.ID, w.Type)
+		}
+		if w.Type != WidgetStat && len(w.Columns) == 0 {
+			t.Errorf("Widget %q has nothing to plot or tabulate", w.ID)
+		}
+	}
+
+	// Tenant dashboards only get widgets they can fetch under /t/{tenant}/
+	tenant := get(MakeAPIHandlers(db.ForTenant("acme"), logger)["/api/dashboard/layout"])
+	if len(tenant.Widgets) == 0 || len(tenant.Widgets) == len(defaultLayout) {
+		t.Fatalf("Expected a subset of the widgets for a tenant, got %d", len(tenant.Widgets))
+	}
+	for _, w := range tenant.Widgets {
+		if !slices.Contains(tenantScopedPaths, w.Endpoint) {
+			t.Errorf("Widget %q reads from %s, which tenants cannot reach", w.ID, w.Endpoint)
+		}
+	}
+}
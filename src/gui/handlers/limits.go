@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Defaults for the limiter shared by expensive endpoints: at most two run at
+// once and up to eight more wait for a slot before callers receive 429.
+const (
+	expensiveConcurrency = 2
+	expensiveQueueDepth  = 8
+)
+
+// retryAfterSeconds is advertised to callers rejected by a limiter.
+const retryAfterSeconds = 2
+
+// expensiveEndpoints lists routes that can scan the full history. They share
+// one limiter so a burst of heavy requests cannot starve ingestion of
+// database access.
+var expensiveEndpoints = []string{
+	"/api/stats/summary",
+	"/api/charts/breakdown",
+	"/api/stats/records",
+	"/api/stats/diff",
+	"/api/charts/timeseries",
+	"/api/dimensions/query",
+	"/api/charts/record-sizes",
+	"/api/charts/record-size-breakdown",
+	"/api/admin/config/export",
+	"/api/export",
+	"/api/export/csv",
+	"/api/export/manifest",
+	"/api/export/verify",
+	"/api/exports",
+}
+
+// NewExpensiveLimiter creates the limiter for expensiveEndpoints. A process
+// creates one and passes it to every handler set it builds, so the limit
+// holds across the unscoped, dataset-scoped and tenant-scoped routes.
+//
+// Returns:
+//   - *ConcurrencyLimiter: Limiter with the default concurrency and queue depth
+func NewExpensiveLimiter() *ConcurrencyLimiter {
+	return NewConcurrencyLimiter(expensiveConcurrency, expensiveQueueDepth)
+}
+
+// ConcurrencyLimiter bounds how many requests run a handler at once. Requests
+// beyond the limit wait in a bounded queue; once the queue is full further
+// requests are rejected with 429 Too Many Requests.
+type ConcurrencyLimiter struct {
+	slots    chan struct{} // Held while a request is running
+	admitted chan struct{} // Held while a request is running or queued
+}
+
+// NewConcurrencyLimiter creates a limiter allowing concurrency requests to run
+// at once with up to queueDepth more waiting.
+//
+// Parameters:
+//   - concurrency: Maximum number of requests running at once (at least 1)
+//   - queueDepth: Maximum number of requests waiting for a slot
+//
+// Returns:
+//   - *ConcurrencyLimiter: Limiter ready to wrap handlers
+func NewConcurrencyLimiter(concurrency, queueDepth int) *ConcurrencyLimiter {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if queueDepth < 0 {
+		queueDepth = 0
+	}
+	return &ConcurrencyLimiter{
+		slots:    make(chan struct{}, concurrency),
+		admitted: make(chan struct{}, concurrency+queueDepth),
+	}
+}
+
+// Wrap returns a handler that runs h under the limiter. Queued requests stop
+// waiting when the client goes away.
+func (l *ConcurrencyLimiter) Wrap(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case l.admitted <- struct{}{}:
+		default:
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+			sendErrorResponseWithStatus(w, http.StatusTooManyRequests, "Too many concurrent expensive requests, retry later")
+			return
+		}
+		defer func() { <-l.admitted }()
+
+		select {
+		case l.slots <- struct{}{}:
+		case <-r.Context().Done():
+			return
+		}
+		defer func() { <-l.slots }()
+
+		h(w, r)
+	}
+}
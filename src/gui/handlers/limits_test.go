@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1, 1)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	handler := limiter.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// The first request runs and the second waits in the queue
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+			codes[i] = rr.Code
+		}(i)
+	}
+	<-started
+
+	// Wait until the second request has been admitted to the queue
+	for len(limiter.admitted) < 2 {
+	}
+
+	// A third request finds the queue full
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 when the queue is full, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header on 429")
+	}
+
+	close(release)
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Request %d: expected 200 after queueing, got %d", i, code)
+		}
+	}
+}
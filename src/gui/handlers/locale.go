@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/melatonein5/LogpushEstimator/src/i18n"
+)
+
+// requestLocale picks the locale to render a response to r in: the lang
+// query parameter, then preferred (a locale saved in the caller's
+// preferences, or empty), then the Accept-Language header.
+func requestLocale(r *http.Request, preferred string) (string, error) {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		if !i18n.Valid(lang) {
+			return "", &requestError{"lang must be one of " + strings.Join(i18n.Supported(), ", ")}
+		}
+		return lang, nil
+	}
+	if i18n.Valid(preferred) {
+		return preferred, nil
+	}
+	return i18n.Negotiate(r.Header.Get("Accept-Language")), nil
+}
+
+// rangeLabel names the time range menu entry covering the last hours hours.
+func rangeLabel(p *i18n.Printer, hours int) string {
+	switch hours {
+	case 1:
+		return p.T("Last Hour")
+	case 6:
+		return p.T("Last 6 Hours")
+	case 24:
+		return p.T("Last 24 Hours")
+	case 168:
+		return p.T("Last 7 Days")
+	case 720:
+		return p.T("Last 30 Days")
+	}
+	return p.T("Last %d Hours", hours)
+}
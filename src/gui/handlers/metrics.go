@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"log/slog"
+	"maps"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencySamples is how many recent latencies are kept per route for
+// percentile calculation.
+const latencySamples = 1024
+
+// RouteStats is the per-route entry reported by /api/admin/api-stats.
+// Latency percentiles cover the most recent requests to the route.
+type RouteStats struct {
+	Route    string           `json:"route"`    // Route path the handler is mounted at
+	Requests int64            `json:"requests"` // Requests served since startup
+	Errors   int64            `json:"errors"`   // Requests answered with a 5xx status
+	Statuses map[string]int64 `json:"statuses"` // Request count per HTTP status code
+	MeanMs   float64          `json:"mean_ms"`  // Mean latency over all requests
+	P50Ms    float64          `json:"p50_ms"`   // Median latency
+	P90Ms    float64          `json:"p90_ms"`   // 90th percentile latency
+	P99Ms    float64          `json:"p99_ms"`   // 99th percentile latency
+	MaxMs    float64          `json:"max_ms"`   // Slowest request since startup
+}
+
+// APIStatsReport is the response body for /api/admin/api-stats.
+type APIStatsReport struct {
+	Since  string       `json:"since"`  // When collection started (RFC3339)
+	Routes []RouteStats `json:"routes"` // Routes ordered by request count, busiest first
+}
+
+// routeMetrics accumulates observations for a single route.
+type routeMetrics struct {
+	requests int64
+	errors   int64
+	statuses map[int]int64
+	total    time.Duration
+	max      time.Duration
+	samples  []time.Duration // Ring buffer of recent latencies
+	next     int             // Next ring buffer slot to overwrite
+}
+
+// APIMetrics records request counts, statuses and latencies per route in
+// memory. It is safe for concurrent use; counters reset on restart unless
+// carried over with State and Restore.
+type APIMetrics struct {
+	mu      sync.Mutex
+	since   time.Time
+	routes  map[string]*routeMetrics
+	samples int
+}
+
+// NewAPIMetrics creates an empty metrics collector.
+func NewAPIMetrics() *APIMetrics {
+	return &APIMetrics{since: time.Now().UTC(), routes: make(map[string]*routeMetrics), samples: latencySamples}
+}
+
+// Observe records a single request to route.
+func (m *APIMetrics) Observe(route string, status int, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rm, ok := m.routes[route]
+	if !ok {
+		rm = &routeMetrics{statuses: make(map[int]int64)}
+		m.routes[route] = rm
+	}
+	rm.requests++
+	rm.statuses[status]++
+	if status >= 500 {
+		rm.errors++
+	}
+	rm.total += latency
+	if latency > rm.max {
+		rm.max = latency
+	}
+	if len(rm.samples) < m.samples {
+		rm.samples = append(rm.samples, latency)
+	} else {
+		rm.samples[rm.next] = latency
+		rm.next = (rm.next + 1) % m.samples
+	}
+}
+
+// Snapshot returns the current statistics for every observed route, busiest
+// first.
+func (m *APIMetrics) Snapshot() APIStatsReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := APIStatsReport{Since: m.since.Format(time.RFC3339), Routes: []RouteStats{}}
+	for route, rm := range m.routes {
+		sorted := append([]time.Duration(nil), rm.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		statuses := make(map[string]int64, len(rm.statuses))
+		for code, n := range rm.statuses {
+			statuses[strconv.Itoa(code)] = n
+		}
+		report.Routes = append(report.Routes, RouteStats{
+			Route:    route,
+			Requests: rm.requests,
+			Errors:   rm.errors,
+			Statuses: statuses,
+			MeanMs:   milliseconds(rm.total / time.Duration(rm.requests)),
+			P50Ms:    milliseconds(percentile(sorted, 0.50)),
+			P90Ms:    milliseconds(percentile(sorted, 0.90)),
+			P99Ms:    milliseconds(percentile(sorted, 0.99)),
+			MaxMs:    milliseconds(rm.max),
+		})
+	}
+	sort.Slice(report.Routes, func(i, j int) bool {
+		if report.Routes[i].Requests != report.Routes[j].Requests {
+			return report.Routes[i].Requests > report.Routes[j].Requests
+		}
+		return report.Routes[i].Route < report.Routes[j].Route
+	})
+	return report
+}
+
+// RouteState is one route's counters in a saved APIMetricsState.
+type RouteState struct {
+	Route    string          `json:"route"`      // Route path the handler is mounted at
+	Requests int64           `json:"requests"`   // Requests served
+	Errors   int64           `json:"errors"`     // Requests answered with a 5xx status
+	Statuses map[int]int64   `json:"statuses"`   // Request count per HTTP status code
+	Total    time.Duration   `json:"total_ns"`   // Sum of all latencies
+	Max      time.Duration   `json:"max_ns"`     // Slowest request
+	Samples  []time.Duration `json:"samples_ns"` // Recent latencies, oldest first
+}
+
+// APIMetricsState is the content of an APIMetrics, saved so counters and
+// latency percentiles survive a restart.
+type APIMetricsState struct {
+	Since  time.Time    `json:"since"`  // When collection started
+	Routes []RouteState `json:"routes"` // Counters per route
+}
+
+// State returns the counters of every observed route for saving.
+func (m *APIMetrics) State() APIMetricsState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := APIMetricsState{Since: m.since, Routes: make([]RouteState, 0, len(m.routes))}
+	for route, rm := range m.routes {
+		samples := make([]time.Duration, 0, len(rm.samples))
+		samples = append(samples, rm.samples[rm.next:]...)
+		samples = append(samples, rm.samples[:rm.next]...)
+		state.Routes = append(state.Routes, RouteState{
+			Route:    route,
+			Requests: rm.requests,
+			Errors:   rm.errors,
+			Statuses: maps.Clone(rm.statuses),
+			Total:    rm.total,
+			Max:      rm.max,
+			Samples:  samples,
+		})
+	}
+	return state
+}
+
+// Restore adds the counters in a saved state to those observed so far and
+// moves the start of collection back to the saved one.
+//
+// Parameters:
+//   - state: State from State, typically saved by a previous process
+func (m *APIMetrics) Restore(state APIMetricsState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !state.Since.IsZero() && state.Since.Before(m.since) {
+		m.since = state.Since.UTC()
+	}
+	for _, saved := range state.Routes {
+		if saved.Requests <= 0 {
+			continue
+		}
+		rm, ok := m.routes[saved.Route]
+		if !ok {
+			rm = &routeMetrics{statuses: make(map[int]int64)}
+			m.routes[saved.Route] = rm
+		}
+		rm.requests += saved.Requests
+		rm.errors += saved.Errors
+		for code, n := range saved.Statuses {
+			rm.statuses[code] += n
+		}
+		rm.total += saved.Total
+		rm.max = max(rm.max, saved.Max)
+
+		// Saved latencies are older than any observed since startup
+		samples := append(append([]time.Duration(nil), saved.Samples...), rm.samples[rm.next:]...)
+		samples = append(samples, rm.samples[:rm.next]...)
+		if len(samples) > m.samples {
+			samples = samples[len(samples)-m.samples:]
+		}
+		rm.samples, rm.next = samples, 0
+	}
+}
+
+// Wrap returns a handler that records every request to next under route.
+func (m *APIMetrics) Wrap(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		m.Observe(route, rec.status, time.Since(start))
+	}
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// percentile returns the nearest-rank percentile p (0-1) of sorted samples.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p*float64(len(sorted))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// milliseconds converts d to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// MakeAPIStatsHandler creates the /api/admin/api-stats handler reporting
+// per-route request counts, status codes and latency percentiles, so
+// maintainers can see which endpoints dominate load.
+//
+// Parameters:
+//   - metrics: Collector the API routes are wrapped with
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function
+func MakeAPIStatsHandler(metrics *APIMetrics, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: api stats", "remote_addr", r.RemoteAddr)
+		sendSuccessResponse(w, metrics.Snapshot())
+	}
+}
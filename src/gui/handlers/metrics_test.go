@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAPIMetricsSnapshot(t *testing.T) {
+	metrics := NewAPIMetrics()
+	for i := 1; i <= 100; i++ {
+		metrics.Observe("/api/stats/summary", http.StatusOK, time.Duration(i)*time.Millisecond)
+	}
+	metrics.Observe("/api/logs/recent", http.StatusInternalServerError, 5*time.Millisecond)
+
+	report := metrics.Snapshot()
+	if len(report.Routes) != 2 || report.Routes[0].Route != "/api/stats/summary" {
+		t.Fatalf("Expected busiest route first, got %+v", report.Routes)
+	}
+
+	summary := report.Routes[0]
+	if summary.Requests != 100 || summary.Statuses["200"] != 100 || summary.Errors != 0 {
+		t.Errorf("Unexpected counts: %+v", summary)
+	}
+	if summary.P50Ms != 50 || summary.P90Ms != 90 || summary.P99Ms != 99 || summary.MaxMs != 100 {
+		t.Errorf("Unexpected percentiles: %+v", summary)
+	}
+	if summary.MeanMs != 50.5 {
+		t.Errorf("Expected mean 50.5ms, got %v", summary.MeanMs)
+	}
+	if report.Routes[1].Errors != 1 {
+		t.Errorf("Expected 5xx to count as an error, got %+v", report.Routes[1])
+	}
+}
+
+func TestAPIMetricsSampleWindow(t *testing.T) {
+	metrics := NewAPIMetrics()
+	metrics.samples = 10
+
+	// Old slow requests fall out of the percentile window but not the max
+	for i := 0; i < 10; i++ {
+		metrics.Observe("/r", http.StatusOK, time.Second)
+	}
+	for i := 0; i < 10; i++ {
+		metrics.Observe("/r", http.StatusOK, time.Millisecond)
+	}
+
+	stats := metrics.Snapshot().Routes[0]
+	if stats.P99Ms != 1 || stats.MaxMs != 1000 || stats.Requests != 20 {
+		t.Errorf("Unexpected stats after window rollover: %+v", stats)
+	}
+}
+
+func TestAPIMetricsStateRoundTrip(t *testing.T) {
+	previous := NewAPIMetrics()
+	previous.samples = 10
+	for i := 1; i <= 15; i++ {
+		previous.Observe("/r", http.StatusOK, time.Duration(i)*time.Millisecond)
+	}
+	previous.Observe("/r", http.StatusBadGateway, 100*time.Millisecond)
+	data, err := json.Marshal(previous.State())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests observed before the restore are kept alongside the saved ones
+	metrics := NewAPIMetrics()
+	metrics.samples = 10
+	metrics.Observe("/r", http.StatusOK, time.Millisecond)
+	var state APIMetricsState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	metrics.Restore(state)
+
+	report := metrics.Snapshot()
+	if report.Since != previous.Snapshot().Since {
+		t.Errorf("Expected collection to start at the saved time, got %s", report.Since)
+	}
+	stats := report.Routes[0]
+	if stats.Requests != 17 || stats.Errors != 1 || stats.Statuses["502"] != 1 || stats.MaxMs != 100 {
+		t.Errorf("Unexpected restored counts: %+v", stats)
+	}
+	// The window holds the 9 newest saved latencies (7-15ms and 100ms less
+	// the oldest) and the one observed since startup
+	if stats.P50Ms != 11 || stats.P99Ms != 100 {
+		t.Errorf("Unexpected restored percentiles: %+v", stats)
+	}
+}
+
+func TestMakeAPIStatsHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	metrics := NewAPIMetrics()
+
+	notFound := metrics.Wrap("/api/missing", func(w http.ResponseWriter, r *http.Request) {
+		sendErrorResponseWithStatus(w, http.StatusNotFound, "nope")
+	})
+	notFound.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/missing", nil))
+
+	rr := httptest.NewRecorder()
+	MakeAPIStatsHandler(metrics, logger).ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/api-stats", nil))
+
+	var response struct {
+		Success bool           `json:"success"`
+		Data    APIStatsReport `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if len(response.Data.Routes) != 1 || response.Data.Routes[0].Statuses["404"] != 1 {
+		t.Errorf("Expected the wrapped 404 to be recorded, got %+v", response.Data.Routes)
+	}
+}
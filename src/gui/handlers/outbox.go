@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// Outbox listing limits.
+const (
+	defaultOutboxLimit = 100
+	maxOutboxLimit     = 1000
+)
+
+// makeOutboxHandler serves GET /api/admin/outbox, reporting how many
+// webhook notifications are pending, delivered and dead-lettered, and
+// listing the most recent ones, newest first. ?status= restricts the list
+// to one state and ?limit= sets its length.
+func makeOutboxHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: outbox", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		status := r.URL.Query().Get("status")
+		switch status {
+		case "", database.OutboxPending, database.OutboxDelivered, database.OutboxDead:
+		default:
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "status must be pending, delivered or dead")
+			return
+		}
+		limit := defaultOutboxLimit
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 || n > maxOutboxLimit {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+				return
+			}
+			limit = n
+		}
+
+		counts, err := db.CountOutbox()
+		if err != nil {
+			sendErrorResponse(w, "Failed to count outbox messages")
+			return
+		}
+		messages, err := db.ListOutbox(status, limit)
+		if err != nil {
+			sendErrorResponse(w, "Failed to list outbox messages")
+			return
+		}
+		sendSuccessResponse(w, map[string]interface{}{"counts": counts, "messages": messages})
+	}
+}
+
+// makeOutboxRetryHandler serves POST /api/admin/outbox/retry?id=N, making
+// undelivered message N due again with its attempts reset, typically to
+// resend a dead letter once the receiving end is fixed.
+func makeOutboxRetryHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: outbox retry", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		if err != nil || id <= 0 {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "id must be a positive integer")
+			return
+		}
+		found, err := db.RetryOutbox(id)
+		if err != nil {
+			sendErrorResponse(w, "Failed to retry outbox message")
+			return
+		}
+		if !found {
+			sendErrorResponseWithStatus(w, http.StatusNotFound, "Undelivered outbox message not found")
+			return
+		}
+		logger.Info("Outbox message requeued", "id", id)
+		sendSuccessResponse(w, map[string]interface{}{"id": id, "status": database.OutboxPending})
+	}
+}
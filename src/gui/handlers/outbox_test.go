@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestAPIOutboxListAndRetry(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+
+	err := db.EnqueueOutbox([]database.OutboxMessage{
+		{Destination: "ops", URL: "https://example.com/hook", Topic: "alert.fired", Payload: json.RawMessage(`{"topic":"alert.fired"}`)},
+		{Destination: "ops", URL: "https://example.com/hook", Topic: "job.synced", Payload: json.RawMessage(`{"topic":"job.synced"}`)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	due, _ := db.DueOutbox(time.Now(), 10)
+	db.MarkOutboxFailed(due[0].ID, "webhook answered 410 Gone", time.Time{})
+
+	rr := httptest.NewRecorder()
+	handlers["/api/admin/outbox"].ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/outbox?status=dead", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	var listResp struct {
+		Data struct {
+			Counts   map[string]int64         `json:"counts"`
+			Messages []database.OutboxMessage `json:"messages"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &listResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if listResp.Data.Counts["dead"] != 1 || listResp.Data.Counts["pending"] != 1 {
+		t.Errorf("Unexpected counts: %v", listResp.Data.Counts)
+	}
+	if msgs := listResp.Data.Messages; len(msgs) != 1 || msgs[0].ID != due[0].ID || string(msgs[0].Payload) != `{"topic":"alert.fired"}` {
+		t.Fatalf("Expected the dead letter, got %+v", msgs)
+	}
+
+	retryURL := "/api/admin/outbox/retry?id=" + strconv.FormatInt(due[0].ID, 10)
+	rr = httptest.NewRecorder()
+	handlers["/api/admin/outbox/retry"].ServeHTTP(rr, httptest.NewRequest("POST", retryURL, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if counts, _ := db.CountOutbox(); counts[database.OutboxPending] != 2 {
+		t.Errorf("Expected the dead letter to be pending again, got %v", counts)
+	}
+
+	for _, tc := range []struct {
+		method, url string
+		want        int
+	}{
+		{"GET", "/api/admin/outbox?status=lost", http.StatusBadRequest},
+		{"GET", "/api/admin/outbox?limit=0", http.StatusBadRequest},
+		{"POST", "/api/admin/outbox/retry?id=999", http.StatusNotFound},
+		{"POST", "/api/admin/outbox/retry?id=x", http.StatusBadRequest},
+	} {
+		rr := httptest.NewRecorder()
+		path := tc.url[:len("/api/admin/outbox")]
+		if tc.method == "POST" {
+			path = "/api/admin/outbox/retry"
+		}
+		handlers[path].ServeHTTP(rr, httptest.NewRequest(tc.method, tc.url, nil))
+		if rr.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.url, tc.want, rr.Code)
+		}
+	}
+}
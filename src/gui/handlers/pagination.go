@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// Page sizes for the log listing endpoints when only offset is given, and
+// the most a single page may hold.
+const (
+	defaultPageLimit = 1000
+	maxPageLimit     = 10000
+)
+
+// PageMeta describes the page of records a listing returned, so clients can
+// walk a large range without loading it in one response.
+type PageMeta struct {
+	Total      int64 `json:"total"`       // Records in the whole range
+	Limit      int   `json:"limit"`       // Most records the page may hold
+	Offset     int   `json:"offset"`      // Records of the range skipped before the page
+	NextOffset int   `json:"next_offset"` // Offset of the following page, or total when this is the last
+	HasMore    bool  `json:"has_more"`    // Whether records follow this page
+}
+
+// pageRequest is the page selected by a request's limit and offset.
+type pageRequest struct {
+	limit, offset int
+}
+
+// parsePage reads the optional limit and offset parameters of the log
+// listing endpoints. A request with neither is not paged and gets every
+// record, as before pagination existed.
+//
+// Parameters:
+//   - r: Incoming request
+//
+// Returns:
+//   - *pageRequest: The requested page, or nil when the request is not paged
+//   - error: *requestError if limit or offset is malformed
+func parsePage(r *http.Request) (*pageRequest, error) {
+	q := r.URL.Query()
+	limitStr, offsetStr := q.Get("limit"), q.Get("offset")
+	if limitStr == "" && offsetStr == "" {
+		return nil, nil
+	}
+	page := &pageRequest{limit: defaultPageLimit}
+	if limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			return nil, &requestError{"limit must be a positive integer"}
+		}
+		page.limit = min(l, maxPageLimit)
+	}
+	if offsetStr != "" {
+		o, err := strconv.Atoi(offsetStr)
+		if err != nil || o < 0 {
+			return nil, &requestError{"offset must be a non-negative integer"}
+		}
+		page.offset = o
+	}
+	return page, nil
+}
+
+// meta returns the metadata of the page p of a range holding total records.
+func (p pageRequest) meta(total int64) *PageMeta {
+	next := min(int64(p.offset+p.limit), max(total, int64(p.offset)))
+	return &PageMeta{
+		Total:      total,
+		Limit:      p.limit,
+		Offset:     p.offset,
+		NextOffset: int(next),
+		HasMore:    next < total,
+	}
+}
+
+// slice returns the page p of logs already loaded in full, as when archives
+// were merged in.
+func (p pageRequest) slice(logs []database.LogSize) ([]database.LogSize, *PageMeta) {
+	start := min(p.offset, len(logs))
+	end := min(start+p.limit, len(logs))
+	return logs[start:end], p.meta(int64(len(logs)))
+}
+
+// query returns the page p of the records selected by q from the database,
+// with the size of the whole range.
+func (p pageRequest) query(db *database.SQLiteController, q logsQuery) ([]database.LogSize, *PageMeta, error) {
+	count, err := db.CountByTimeRange(q.start, q.end)
+	if err != nil {
+		return nil, nil, err
+	}
+	logs, err := db.QueryByTimeRangePage(q.start, q.end, p.limit, p.offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	return logs, p.meta(count.Batches), nil
+}
+
+// withPage adds page to meta, allocating it when nil.
+func withPage(meta *ResponseMeta, page *PageMeta) *ResponseMeta {
+	if page == nil {
+		return meta
+	}
+	if meta == nil {
+		meta = &ResponseMeta{}
+	}
+	meta.Page = page
+	return meta
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// getLogsPage requests a page of a log listing and decodes its records and
+// page metadata.
+func getLogsPage(t *testing.T, handler http.HandlerFunc, target string) ([]database.LogSize, *PageMeta) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("%s: expected 200, got %d: %s", target, rr.Code, rr.Body.String())
+	}
+	var logs []database.LogSize
+	resp := APIResponse{Data: &logs}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("%s: failed to decode response: %v", target, err)
+	}
+	if resp.Meta == nil {
+		return logs, nil
+	}
+	return logs, resp.Meta.Page
+}
+
+func TestAPILogsPaginate(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+
+	for _, path := range []string{"/api/logs/recent", "/api/logs/range"} {
+		handler, target := handlers[path], path+"?last=1h"
+		var sizes []int64
+		next := 0
+		for range 3 {
+			logs, page := getLogsPage(t, handler, target+"&limit=2&offset="+strconv.Itoa(next))
+			if page == nil || page.Total != 5 || page.Limit != 2 || page.Offset != next {
+				t.Fatalf("%s: unexpected page metadata %+v", target, page)
+			}
+			for _, l := range logs {
+				sizes = append(sizes, l.Filesize)
+			}
+			next = page.NextOffset
+			if !page.HasMore {
+				break
+			}
+		}
+		if len(sizes) != 5 || sizes[0] != 1024 || sizes[4] != 16384 || next != 5 {
+			t.Errorf("%s: expected every record once over three pages, got %v ending at %d", target, sizes, next)
+		}
+
+		// Without limit or offset every record is returned, with no metadata
+		if logs, page := getLogsPage(t, handler, target); len(logs) != 5 || page != nil {
+			t.Errorf("%s: expected all 5 records unpaged, got %d with %+v", target, len(logs), page)
+		}
+		if logs, page := getLogsPage(t, handler, target+"&offset=10"); len(logs) != 0 || page.HasMore || page.Limit != defaultPageLimit {
+			t.Errorf("%s: expected an empty last page past the end, got %d with %+v", target, len(logs), page)
+		}
+	}
+}
+
+func TestAPILogsRejectBadPages(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+
+	for _, query := range []string{"limit=0", "limit=x", "offset=-1", "offset=y"} {
+		for _, path := range []string{"/api/logs/recent", "/api/logs/range"} {
+			rr := httptest.NewRecorder()
+			handlers[path].ServeHTTP(rr, httptest.NewRequest("GET", path+"?last=1h&"+query, nil))
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s?%s: expected 400, got %d", path, query, rr.Code)
+			}
+		}
+	}
+}
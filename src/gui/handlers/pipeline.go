@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// IngestPipelineStatus is the response body for /api/admin/ingest-pipeline.
+type IngestPipelineStatus struct {
+	Mode            string  `json:"mode"`              // How batches reach the database: "synchronous" or "buffered"
+	BatchSize       int     `json:"batch_size"`        // Most ingested batches written per database transaction
+	FlushIntervalMs float64 `json:"flush_interval_ms"` // Longest a batch waits before it is written
+	QueueDepth      int     `json:"queue_depth"`       // Batches acknowledged but not yet written
+	AutoTuned       bool    `json:"auto_tuned"`        // Whether batch size and flush interval follow load
+	Inserts         int64   `json:"inserts"`           // Batches written since startup
+	Failures        int64   `json:"failures"`          // Batches whose insert failed
+	LastInsertMs    float64 `json:"last_insert_ms"`    // Latency of the latest insert
+	MeanInsertMs    float64 `json:"mean_insert_ms"`    // Mean insert latency since startup
+	MaxInsertMs     float64 `json:"max_insert_ms"`     // Slowest insert since startup
+}
+
+// IngestPipeline observes how long ingested batches take to be written to
+// the database. By default the ingestion handler inserts each batch before
+// acknowledging it, one batch per transaction; with a write buffer, batches
+// are queued and written in groups whose size and interval the buffer may
+// tune to the load. The latencies show whether the database keeps up. It is
+// safe for concurrent use; counters reset on restart.
+type IngestPipeline struct {
+	mu       sync.Mutex
+	buffer   *database.WriteBuffer // Set when ingestion is buffered
+	inserts  int64
+	failures int64
+	total    time.Duration
+	last     time.Duration
+	max      time.Duration
+}
+
+// NewIngestPipeline creates a pipeline monitor with no inserts observed.
+func NewIngestPipeline() *IngestPipeline {
+	return &IngestPipeline{}
+}
+
+// UseBuffer reports buffer's current grouping, whether it is auto-tuned, and
+// its queue depth in Status, for when ingested batches are written through
+// it.
+func (p *IngestPipeline) UseBuffer(buffer *database.WriteBuffer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buffer = buffer
+}
+
+// Observe records one batch insert that took latency; ok is false when
+// the insert failed.
+func (p *IngestPipeline) Observe(latency time.Duration, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !ok {
+		p.failures++
+	}
+	p.inserts++
+	p.total += latency
+	p.last = latency
+	if latency > p.max {
+		p.max = latency
+	}
+}
+
+// Status reports the pipeline settings and the insert latencies observed
+// so far.
+func (p *IngestPipeline) Status() IngestPipelineStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := IngestPipelineStatus{
+		Mode:         "synchronous",
+		BatchSize:    1,
+		Inserts:      p.inserts,
+		Failures:     p.failures,
+		LastInsertMs: milliseconds(p.last),
+		MaxInsertMs:  milliseconds(p.max),
+	}
+	if p.buffer != nil {
+		cfg := p.buffer.Config()
+		status.Mode = "buffered"
+		status.BatchSize = cfg.FlushSize
+		status.FlushIntervalMs = milliseconds(cfg.FlushInterval)
+		status.QueueDepth = p.buffer.Len()
+		status.AutoTuned = cfg.AutoTune
+	}
+	if p.inserts > 0 {
+		status.MeanInsertMs = milliseconds(p.total / time.Duration(p.inserts))
+	}
+	return status
+}
+
+// MakeIngestPipelineHandler creates the GET /api/admin/ingest-pipeline
+// handler reporting how ingested batches are written and how long the
+// writes take.
+//
+// Parameters:
+//   - pipeline: Monitor the ingestion handler reports its inserts to
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function
+func MakeIngestPipelineHandler(pipeline *IngestPipeline, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: ingest pipeline", "remote_addr", r.RemoteAddr)
+		sendSuccessResponse(w, pipeline.Status())
+	}
+}
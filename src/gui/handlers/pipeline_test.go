@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestIngestPipelineStatus(t *testing.T) {
+	pipeline := NewIngestPipeline()
+	if status := pipeline.Status(); status.Inserts != 0 || status.MeanInsertMs != 0 || status.BatchSize != 1 {
+		t.Fatalf("Expected an empty synchronous pipeline, got %+v", status)
+	}
+
+	pipeline.Observe(2*time.Millisecond, true)
+	pipeline.Observe(6*time.Millisecond, true)
+	pipeline.Observe(4*time.Millisecond, false)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	rr := httptest.NewRecorder()
+	MakeIngestPipelineHandler(pipeline, logger).ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/ingest-pipeline", nil))
+	var resp struct {
+		Data IngestPipelineStatus `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid response %s: %v", rr.Body.String(), err)
+	}
+	want := IngestPipelineStatus{Mode: "synchronous", BatchSize: 1, Inserts: 3, Failures: 1, LastInsertMs: 4, MeanInsertMs: 4, MaxInsertMs: 6}
+	if resp.Data != want {
+		t.Errorf("Expected %+v, got %+v", want, resp.Data)
+	}
+}
+
+func TestIngestPipelineBuffered(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	buffer := db.NewWriteBuffer(database.WriteBufferConfig{FlushSize: 50, FlushInterval: time.Second, AutoTune: true})
+	defer buffer.Close()
+
+	pipeline := NewIngestPipeline()
+	pipeline.UseBuffer(buffer)
+	status := pipeline.Status()
+	if status.Mode != "buffered" || status.BatchSize != 50 || status.FlushIntervalMs != 1000 || status.QueueDepth != 0 || !status.AutoTuned {
+		t.Errorf("Expected an auto-tuned pipeline writing groups of 50 every second, got %+v", status)
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/i18n"
+)
+
+// preferencesCookie names the cookie carrying the browser token that
+// preferences are stored under.
+const preferencesCookie = "lpe_token"
+
+// preferencesCookieMaxAge keeps the browser token for roughly a year.
+const preferencesCookieMaxAge = 365 * 24 * 60 * 60
+
+// maxPreferenceRangeHours bounds default_range_hours to one year.
+const maxPreferenceRangeHours = 24 * 365
+
+// preferencesToken returns the caller's browser token, issuing a new random
+// token cookie when the request does not carry one.
+func preferencesToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if cookie, err := r.Cookie(preferencesCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	http.SetCookie(w, &http.Cookie{
+		Name:     preferencesCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   preferencesCookieMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token, nil
+}
+
+// validatePreferences checks user-supplied preferences, returning a
+// client-facing message for the first invalid field.
+func validatePreferences(p database.Preferences) string {
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return "Invalid timezone (use an IANA name such as Europe/Berlin)"
+		}
+	}
+	if p.DefaultRangeHours <= 0 || p.DefaultRangeHours > maxPreferenceRangeHours {
+		return "default_range_hours must be between 1 and 8760"
+	}
+	switch config.Units(p.Units) {
+	case "", config.UnitsBinary, config.UnitsDecimal:
+	default:
+		return "units must be empty, \"binary\" or \"decimal\""
+	}
+	if p.Locale != "" && !i18n.Valid(p.Locale) {
+		return "locale must be empty or one of " + strings.Join(i18n.Supported(), ", ")
+	}
+	return ""
+}
+
+// makePreferencesHandler serves /api/preferences. GET returns the caller's
+// preferences (or the defaults), while PUT and POST replace them with the JSON
+// body. Omitted fields keep their current values.
+func makePreferencesHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: preferences", "method", r.Method, "remote_addr", r.RemoteAddr)
+
+		token, err := preferencesToken(w, r)
+		if err != nil {
+			logger.Error("Failed to issue preferences token", "error", err)
+			sendErrorResponse(w, "Failed to identify user")
+			return
+		}
+
+		prefs, _, err := db.GetPreferences(token)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch preferences")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			sendSuccessResponse(w, prefs)
+
+		case http.MethodPut, http.MethodPost:
+			if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid JSON body")
+				return
+			}
+			prefs.Token = token
+			if msg := validatePreferences(prefs); msg != "" {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, msg)
+				return
+			}
+			saved, err := db.SavePreferences(prefs)
+			if err != nil {
+				sendErrorResponse(w, "Failed to save preferences")
+				return
+			}
+			sendSuccessResponse(w, saved)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
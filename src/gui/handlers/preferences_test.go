@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestAPIPreferences(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/preferences"]
+
+	// First request without a cookie issues a token and returns defaults
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/preferences", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != preferencesCookie {
+		t.Fatalf("Expected a %s cookie to be issued, got %v", preferencesCookie, cookies)
+	}
+
+	// Save preferences with the issued token
+	body := `{"timezone":"America/New_York","default_range_hours":168,"favorite_datasets":["http_requests"],"units":"decimal","locale":"de"}`
+	req := httptest.NewRequest("PUT", "/api/preferences", strings.NewReader(body))
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from PUT, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Read them back
+	req = httptest.NewRequest("GET", "/api/preferences", nil)
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var response struct {
+		Success bool                 `json:"success"`
+		Data    database.Preferences `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if response.Data.DefaultRangeHours != 168 || response.Data.Units != "decimal" || response.Data.Locale != "de" || response.Data.Timezone != "America/New_York" {
+		t.Errorf("Unexpected saved preferences: %+v", response.Data)
+	}
+}
+
+func TestAPIPreferencesValidation(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/preferences"]
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{"Invalid JSON", "PUT", "{", http.StatusBadRequest},
+		{"Unknown timezone", "PUT", `{"timezone":"Mars/Olympus"}`, http.StatusBadRequest},
+		{"Zero range", "PUT", `{"default_range_hours":0}`, http.StatusBadRequest},
+		{"Bad units", "PUT", `{"units":"furlongs"}`, http.StatusBadRequest},
+		{"Unsupported locale", "PUT", `{"locale":"klingon"}`, http.StatusBadRequest},
+		{"Unsupported method", "DELETE", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tt.method, "/api/preferences", strings.NewReader(tt.body)))
+			if rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rr.Code)
+			}
+		})
+	}
+}
+
+func TestNewDashboardData(t *testing.T) {
+	prefs := database.DefaultPreferences()
+	if data := newDashboardData(prefs); len(data.RangeOptions) != 5 {
+		t.Errorf("Expected 5 standard range options, got %d", len(data.RangeOptions))
+	}
+
+	prefs.DefaultRangeHours = 48
+	data := newDashboardData(prefs)
+	last := data.RangeOptions[len(data.RangeOptions)-1]
+	if last.Hours != 48 {
+		t.Errorf("Expected non-standard preferred range to be appended, got %+v", data.RangeOptions)
+	}
+}
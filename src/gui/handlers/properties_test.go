@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// propertyConfig returns a testing/quick configuration with a logged seed,
+// so a failing run can be reproduced by hard-coding the seed.
+func propertyConfig(t *testing.T) *quick.Config {
+	seed := time.Now().UnixNano()
+	t.Logf("property seed %d", seed)
+	return &quick.Config{MaxCount: 200, Rand: rand.New(rand.NewSource(seed))}
+}
+
+// logSample is a random set of log records within a week of a fixed time,
+// with sizes spread across every breakdown range.
+type logSample []database.LogSize
+
+// Generate implements quick.Generator.
+func (logSample) Generate(r *rand.Rand, size int) reflect.Value {
+	base := time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC)
+	logs := make(logSample, r.Intn(size+1))
+	for i := range logs {
+		logs[i] = database.LogSize{
+			ID:        int64(i + 1),
+			Timestamp: base.Add(-time.Duration(r.Int63n(int64(7 * 24 * time.Hour)))),
+			// Up to 1TB, with a uniformly random magnitude so small sizes are as
+			// likely as large ones.
+			Filesize: r.Int63n(int64(1) << uint(r.Intn(40)+1)),
+		}
+	}
+	return reflect.ValueOf(logs)
+}
+
+// largeSizes is a list of non-negative sizes that may reach math.MaxInt64.
+type largeSizes []int64
+
+// Generate implements quick.Generator.
+func (largeSizes) Generate(r *rand.Rand, size int) reflect.Value {
+	sizes := make(largeSizes, r.Intn(size+1))
+	for i := range sizes {
+		switch r.Intn(10) {
+		case 0:
+			sizes[i] = math.MaxInt64
+		case 1:
+			sizes[i] = 0
+		default:
+			sizes[i] = r.Int63n(int64(1) << uint(r.Intn(62)+1))
+		}
+	}
+	return reflect.ValueOf(sizes)
+}
+
+func TestPropertyBucketTotalsMatchRawTotals(t *testing.T) {
+	property := func(logs logSample) bool {
+		var wantSize int64
+		for _, l := range logs {
+			wantSize += l.Filesize
+		}
+		for _, iv := range seriesIntervals {
+			points := aggregateByInterval(logs, iv.duration)
+			var count int
+			var size int64
+			var prev time.Time
+			for i, p := range points {
+				ts, err := time.Parse(time.RFC3339, p.Timestamp)
+				if err != nil || !ts.Equal(ts.Truncate(iv.duration)) || p.Count == 0 {
+					return false
+				}
+				if i > 0 && !ts.After(prev) {
+					return false
+				}
+				prev = ts
+				count += p.Count
+				size += p.TotalSize
+			}
+			if count != len(logs) || size != wantSize {
+				t.Logf("%s buckets: %d records / %d bytes, raw: %d / %d", iv.name, count, size, len(logs), wantSize)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, propertyConfig(t)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyBreakdownPercentagesSumTo100(t *testing.T) {
+	property := func(sizes largeSizes) bool {
+		logs := make([]database.LogSize, len(sizes))
+		for i, s := range sizes {
+			logs[i] = database.LogSize{Filesize: s}
+		}
+		breakdown := calculateSizeBreakdown(logs, config.UnitsDecimal)
+		var count int
+		var percentage float64
+		for _, b := range breakdown {
+			count += b.Count
+			percentage += b.Percentage
+		}
+		if count != len(logs) {
+			t.Logf("breakdown counts %d of %d records", count, len(logs))
+			return false
+		}
+		if len(logs) == 0 {
+			return percentage == 0
+		}
+		return math.Abs(percentage-100) < 1e-9
+	}
+	if err := quick.Check(property, propertyConfig(t)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertySummaryMatchesRecords(t *testing.T) {
+	property := func(logs logSample) bool {
+		stats := calculateStats(logs)
+		if stats.TotalRecords != int64(len(logs)) {
+			return false
+		}
+		if len(logs) == 0 {
+			return stats.TotalSize == 0
+		}
+		var size int64
+		for _, l := range logs {
+			size += l.Filesize
+			if l.Filesize < stats.MinSize || l.Filesize > stats.MaxSize {
+				return false
+			}
+		}
+		return size == stats.TotalSize &&
+			stats.AverageSize >= float64(stats.MinSize) && stats.AverageSize <= float64(stats.MaxSize)
+	}
+	if err := quick.Check(property, propertyConfig(t)); err != nil {
+		t.Error(err)
+	}
+}
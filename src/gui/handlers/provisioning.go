@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// maxManifestTenants bounds the tenants provisioned by one request.
+const maxManifestTenants = 1000
+
+// TenantManifestEntry is one tenant of a bulk provisioning manifest. Limits
+// left at zero fall back to the default budget given in the query string.
+type TenantManifestEntry struct {
+	Name        string  `json:"name"`        // Tenant name, as in /t/{tenant}/
+	Description string  `json:"description"` // Free-form description
+	LimitBytes  int64   `json:"limit_bytes"` // Volume limit of the tenant's budget
+	LimitCost   float64 `json:"limit_cost"`  // Cost limit of the tenant's budget
+}
+
+// ProvisionedTenant reports what was created for one tenant.
+type ProvisionedTenant struct {
+	Tenant string `json:"tenant"`           // Tenant name
+	Token  string `json:"token"`            // Name of the tenant's ingest token
+	Secret string `json:"secret,omitempty"` // Token value; only returned here, and not for dry runs
+	Budget string `json:"budget,omitempty"` // Name of the tenant's budget, if it has one
+}
+
+// TenantProvisioning is the response body of POST /api/admin/tenants/bulk.
+type TenantProvisioning struct {
+	DryRun  bool                `json:"dry_run"` // Whether nothing was stored
+	Tenants []ProvisionedTenant `json:"tenants"` // Tenants in manifest order
+	Changes config.ChangeSet    `json:"changes"` // Configuration objects created
+}
+
+// parseTenantManifest reads a manifest as JSON ({"tenants": [...]}) or,
+// with a text/csv content type, as CSV with a header row naming the columns
+// name, description, limit_bytes and limit_cost. Only name is required.
+func parseTenantManifest(r *http.Request) ([]TenantManifestEntry, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "text/csv" {
+		var manifest struct {
+			Tenants []TenantManifestEntry `json:"tenants"`
+		}
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&manifest); err != nil {
+			return nil, &requestError{"Invalid manifest: " + err.Error()}
+		}
+		return manifest.Tenants, nil
+	}
+
+	reader := csv.NewReader(r.Body)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, &requestError{"Invalid manifest: missing CSV header"}
+	}
+	columns := map[string]int{}
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if !slices.Contains([]string{"name", "description", "limit_bytes", "limit_cost"}, h) {
+			return nil, &requestError{fmt.Sprintf("Invalid manifest: unknown column %q", h)}
+		}
+		columns[h] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, &requestError{"Invalid manifest: the CSV header needs a name column"}
+	}
+
+	var entries []TenantManifestEntry
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, &requestError{"Invalid manifest: " + err.Error()}
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		entry := TenantManifestEntry{Name: field("name"), Description: field("description")}
+		if v := field("limit_bytes"); v != "" {
+			if entry.LimitBytes, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return nil, &requestError{fmt.Sprintf("Invalid manifest: line %d: invalid limit_bytes", line)}
+			}
+		}
+		if v := field("limit_cost"); v != "" {
+			if entry.LimitCost, err = strconv.ParseFloat(v, 64); err != nil {
+				return nil, &requestError{fmt.Sprintf("Invalid manifest: line %d: invalid limit_cost", line)}
+			}
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// defaultTenantBudget reads the budget given to tenants without limits of
+// their own from the period, limit_bytes, limit_cost and pricing_model query
+// parameters. Without limits, such tenants get no budget.
+func defaultTenantBudget(r *http.Request) (config.Budget, error) {
+	q := r.URL.Query()
+	b := config.Budget{Period: q.Get("period"), PricingModel: q.Get("pricing_model")}
+	if b.Period == "" {
+		b.Period = "monthly"
+	}
+	var err error
+	if v := q.Get("limit_bytes"); v != "" {
+		if b.LimitBytes, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return b, &requestError{"Invalid limit_bytes"}
+		}
+	}
+	if v := q.Get("limit_cost"); v != "" {
+		if b.LimitCost, err = strconv.ParseFloat(v, 64); err != nil {
+			return b, &requestError{"Invalid limit_cost"}
+		}
+	}
+	return b, nil
+}
+
+// newTokenSecret returns a random ingest token value.
+func newTokenSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// makeTenantBulkHandler serves POST /api/admin/tenants/bulk. For each
+// tenant in the manifest it provisions the tenant, an ingest token and,
+// when limits are given, a budget, all named after the tenant. The whole
+// manifest is applied in one configuration transaction: if any tenant,
+// token or budget already exists (409) or is invalid (400), nothing is
+// stored. With dry_run=true the change set is returned without storing it.
+func makeTenantBulkHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: bulk tenant provisioning", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		entries, err := parseTenantManifest(r)
+		if err == nil {
+			switch {
+			case len(entries) == 0:
+				err = &requestError{"Invalid manifest: no tenants"}
+			case len(entries) > maxManifestTenants:
+				err = &requestError{fmt.Sprintf("Invalid manifest: at most %d tenants per request", maxManifestTenants)}
+			}
+		}
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		defaults, err := defaultTenantBudget(r)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		dryRun := isDryRun(r)
+		result := TenantProvisioning{DryRun: dryRun, Tenants: make([]ProvisionedTenant, 0, len(entries))}
+		var tokens []config.Token
+		for _, e := range entries {
+			provisioned := ProvisionedTenant{Tenant: e.Name, Token: e.Name}
+			token := config.Token{Name: e.Name, Scopes: []string{"ingest"}, Tenant: e.Name}
+			if token.Secret, err = newTokenSecret(); err != nil {
+				sendErrorResponse(w, "Failed to generate token")
+				return
+			}
+			if !dryRun {
+				provisioned.Secret = token.Secret
+			}
+			if e.LimitBytes != 0 || e.LimitCost != 0 || defaults.LimitBytes != 0 || defaults.LimitCost != 0 {
+				provisioned.Budget = e.Name
+			}
+			result.Tenants = append(result.Tenants, provisioned)
+			tokens = append(tokens, token)
+		}
+
+		result.Changes, err = config.Update(db, func(doc *config.Document) error {
+			for i, e := range entries {
+				if slices.ContainsFunc(doc.Tenants, func(t config.Tenant) bool { return t.Name == e.Name }) {
+					return fmt.Errorf("%w: tenant %q", config.ErrExists, e.Name)
+				}
+				if slices.ContainsFunc(doc.Tokens, func(t config.Token) bool { return t.Name == e.Name }) {
+					return fmt.Errorf("%w: token %q", config.ErrExists, e.Name)
+				}
+				doc.Tenants = append(doc.Tenants, config.Tenant{Name: e.Name, Description: e.Description})
+				doc.Tokens = append(doc.Tokens, tokens[i])
+				if result.Tenants[i].Budget == "" {
+					continue
+				}
+				if slices.ContainsFunc(doc.Budgets, func(b config.Budget) bool { return b.Name == e.Name }) {
+					return fmt.Errorf("%w: budget %q", config.ErrExists, e.Name)
+				}
+				budget := defaults
+				budget.Name, budget.Tenant = e.Name, e.Name
+				if e.LimitBytes != 0 || e.LimitCost != 0 {
+					budget.LimitBytes, budget.LimitCost = e.LimitBytes, e.LimitCost
+				}
+				doc.Budgets = append(doc.Budgets, budget)
+			}
+			return nil
+		}, dryRun)
+		if err != nil {
+			var invalid *config.ValidationError
+			switch {
+			case errors.Is(err, config.ErrExists):
+				sendErrorResponseWithStatus(w, http.StatusConflict, strings.TrimPrefix(err.Error(), "config: ")+"; nothing was provisioned")
+			case errors.As(err, &invalid):
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error()+"; nothing was provisioned")
+			default:
+				logger.Error("Failed to provision tenants", "error", err)
+				sendErrorResponse(w, "Failed to provision tenants")
+			}
+			return
+		}
+
+		logger.Info("Tenants provisioned", "dry_run", dryRun, "tenants", len(entries), "created", result.Changes.Created)
+		sendSuccessResponse(w, result)
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+)
+
+func TestAPITenantBulkProvisioning(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/admin/tenants/bulk"]
+	provision := func(target, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", target, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	manifest := `{"tenants":[{"name":"acme","description":"Acme Corp"},{"name":"globex","limit_bytes":5000}]}`
+	rr := provision("/api/admin/tenants/bulk?dry_run=true&limit_cost=100", "application/json", manifest)
+	var resp struct {
+		Data TenantProvisioning `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from dry run, got %d: %s", rr.Code, rr.Body.String())
+	}
+	// Two tenants, tokens and budgets, plus the retention and sampling
+	// settings of a fresh database
+	if !resp.Data.DryRun || resp.Data.Changes.Created != 8 || resp.Data.Tenants[0].Secret != "" {
+		t.Errorf("Unexpected dry run result %+v", resp.Data)
+	}
+	if doc, _ := config.Export(db); len(doc.Tenants) != 0 {
+		t.Fatalf("Expected the dry run to store nothing, got %+v", doc.Tenants)
+	}
+
+	rr = provision("/api/admin/tenants/bulk?limit_cost=100", "application/json", manifest)
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(resp.Data.Tenants) != 2 || len(resp.Data.Tenants[0].Secret) != 64 || resp.Data.Tenants[1].Budget != "globex" {
+		t.Errorf("Unexpected provisioning result %+v", resp.Data)
+	}
+	doc, err := config.Export(db)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(doc.Tenants) != 2 || doc.Tenants[0].Description != "Acme Corp" || len(doc.Tokens) != 2 || doc.Tokens[0].Tenant != "acme" {
+		t.Errorf("Unexpected stored tenants and tokens %+v %+v", doc.Tenants, doc.Tokens)
+	}
+	budgets := map[string]config.Budget{}
+	for _, b := range doc.Budgets {
+		budgets[b.Name] = b
+	}
+	if budgets["acme"].LimitCost != 100 || budgets["globex"].LimitBytes != 5000 || budgets["globex"].LimitCost != 0 || budgets["globex"].Tenant != "globex" {
+		t.Errorf("Expected the default budget for acme and globex's own limit, got %+v", doc.Budgets)
+	}
+
+	// One existing tenant rejects the whole manifest
+	csv := "name,description\ninitech,Initech\nacme,Acme again\n"
+	if rr := provision("/api/admin/tenants/bulk", "text/csv", csv); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for an existing tenant, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if doc, _ := config.Export(db); len(doc.Tenants) != 2 {
+		t.Errorf("Expected nothing provisioned from a conflicting manifest, got %+v", doc.Tenants)
+	}
+
+	rr = provision("/api/admin/tenants/bulk", "text/csv; charset=utf-8", "name,limit_bytes\ninitech,1000\nhooli,\n")
+	resp.Data = TenantProvisioning{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from a CSV manifest, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if resp.Data.Tenants[0].Budget != "initech" || resp.Data.Tenants[1].Budget != "" {
+		t.Errorf("Expected a budget only for the tenant with limits, got %+v", resp.Data.Tenants)
+	}
+
+	invalid := map[string][2]string{
+		"invalid tenant name": {"application/json", `{"tenants":[{"name":"Bad_Name"}]}`},
+		"empty manifest":      {"application/json", `{"tenants":[]}`},
+		"unknown column":      {"text/csv", "name,plan\nx,gold\n"},
+		"invalid limit":       {"text/csv", "name,limit_bytes\nx,lots\n"},
+	}
+	for name, tc := range invalid {
+		if rr := provision("/api/admin/tenants/bulk", tc[0], tc[1]); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxLast bounds relative ranges to ten years.
+const maxLast = 10 * 365 * 24 * time.Hour
+
+// maxQueryWindowHours bounds the look-back windows read by windowParam to a
+// year, so a large hours= or days= cannot make an endpoint scan the whole
+// history. Endpoints counting in days allow maxQueryWindowHours/24.
+const maxQueryWindowHours = 366 * 24
+
+// lastUnits are the units accepted by last=, by suffix.
+var lastUnits = map[byte]time.Duration{
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+// errLastFormat describes the accepted last= values.
+const errLastFormat = "last must be a positive whole number followed by m, h, d or w, e.g. 90m, 24h or 7d"
+
+// parseLast parses a relative range such as 90m, 24h, 7d or 2w.
+//
+// Parameters:
+//   - value: Number of minutes (m), hours (h), days (d) or weeks (w)
+//
+// Returns:
+//   - time.Duration: Length of the range
+//   - error: *requestError describing the accepted format
+func parseLast(value string) (time.Duration, error) {
+	if len(value) < 2 {
+		return 0, &requestError{errLastFormat}
+	}
+	unit, ok := lastUnits[value[len(value)-1]]
+	if !ok {
+		return 0, &requestError{errLastFormat}
+	}
+	n, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if err != nil || n <= 0 {
+		return 0, &requestError{errLastFormat}
+	}
+	if n > int64(maxLast/unit) {
+		return 0, &requestError{"last must not exceed 10 years"}
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// lastParam reads the optional last= parameter, an alternative to explicit
+// start and end times that selects the range ending now.
+//
+// Parameters:
+//   - get: Query parameter lookup
+//
+// Returns:
+//   - time.Duration: Length of the range, or 0 when last is absent
+//   - error: *requestError if last is malformed or combined with start/end
+func lastParam(get func(string) string) (time.Duration, error) {
+	value := get("last")
+	if value == "" {
+		return 0, nil
+	}
+	if get("start") != "" || get("end") != "" {
+		return 0, &requestError{"last cannot be combined with start or end"}
+	}
+	return parseLast(value)
+}
+
+// windowParam returns the window of endpoints that look back a whole number
+// of units (hours or minutes): last= rounded up to whole units, otherwise a
+// positive unit count in param, otherwise def.
+//
+// Parameters:
+//   - r: Incoming request
+//   - param: Name of the unit count parameter, e.g. "hours"
+//   - unit: Length of one unit
+//   - def: Window used when neither parameter is given
+//
+// Returns:
+//   - int: Window in units
+//   - error: *requestError if last is malformed
+func windowParam(r *http.Request, param string, unit time.Duration, def int) (int, error) {
+	q := r.URL.Query()
+	last, err := lastParam(q.Get)
+	if err != nil {
+		return 0, err
+	}
+	if last > 0 {
+		return int((last + unit - 1) / unit), nil
+	}
+	if n, err := strconv.Atoi(q.Get(param)); err == nil && n > 0 {
+		return n, nil
+	}
+	return def, nil
+}
+
+// parseRangeParams reads the range of endpoints that require one: either
+// last= or both start= and end=.
+//
+// Parameters:
+//   - r: Incoming request
+//
+// Returns:
+//   - time.Time: Start of the range
+//   - time.Time: End of the range
+//   - error: *requestError if the range is missing or malformed
+func parseRangeParams(r *http.Request) (time.Time, time.Time, error) {
+	q := r.URL.Query()
+	last, err := lastParam(q.Get)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if last > 0 {
+		end := now().UTC()
+		return end.Add(-last), end, nil
+	}
+
+	startStr, endStr := q.Get("start"), q.Get("end")
+	if startStr == "" || endStr == "" {
+		return time.Time{}, time.Time{}, &requestError{"start and end parameters required"}
+	}
+	start, err := parseTimestamp(startStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, &requestError{invalidTimeMessage("start")}
+	}
+	end, err := parseTimestamp(endStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, &requestError{invalidTimeMessage("end")}
+	}
+	return start, end, nil
+}
+
+// parseOptionalRange reads the range of endpoints that default to the whole
+// history: last=, start= and end= together, or hours=. A zero start and end
+// select every record.
+//
+// Parameters:
+//   - r: Incoming request
+//
+// Returns:
+//   - time.Time: Start of the range, or zero
+//   - time.Time: End of the range, or zero
+//   - error: *requestError if a parameter is malformed
+func parseOptionalRange(r *http.Request) (time.Time, time.Time, error) {
+	q := r.URL.Query()
+	if q.Get("last") == "" && q.Get("start") == "" && q.Get("end") == "" {
+		if h, err := strconv.Atoi(q.Get("hours")); err == nil && h > 0 {
+			end := now().UTC()
+			return end.Add(-time.Duration(h) * time.Hour), end, nil
+		}
+		return time.Time{}, time.Time{}, nil
+	}
+	return parseRangeParams(r)
+}
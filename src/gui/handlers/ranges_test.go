@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseLast(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"90m", 90 * time.Minute},
+		{"24h", 24 * time.Hour},
+		{"7d", 7 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := parseLast(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseLast(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "7", "d", "0d", "-1h", "1.5h", "7y", "1h30m", "99999w"} {
+		if _, err := parseLast(bad); err == nil {
+			t.Errorf("Expected an error for last=%q", bad)
+		}
+	}
+}
+
+func TestLastParam(t *testing.T) {
+	get := func(query string) func(string) string {
+		values, _ := url.ParseQuery(query)
+		return values.Get
+	}
+
+	if last, err := lastParam(get("hours=6")); last != 0 || err != nil {
+		t.Errorf("Expected no range without last, got %v, %v", last, err)
+	}
+	if _, err := lastParam(get("last=7d&start=2025-09-01T00:00:00Z")); err == nil {
+		t.Error("Expected an error when last is combined with start")
+	}
+
+	r := httptest.NewRequest("GET", "/?last=90m", nil)
+	if minutes, err := windowParam(r, "minutes", time.Minute, 60); err != nil || minutes != 90 {
+		t.Errorf("Expected 90 minutes, got %d, %v", minutes, err)
+	}
+	r = httptest.NewRequest("GET", "/?last=90m", nil)
+	if hours, err := windowParam(r, "hours", time.Hour, 24); err != nil || hours != 2 {
+		t.Errorf("Expected 90m rounded up to 2 hours, got %d, %v", hours, err)
+	}
+	r = httptest.NewRequest("GET", "/?hours=6", nil)
+	if hours, err := windowParam(r, "hours", time.Hour, 24); err != nil || hours != 6 {
+		t.Errorf("Expected hours=6 to be used, got %d, %v", hours, err)
+	}
+}
+
+func TestAPILastParameter(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+
+	for _, path := range []string{
+		"/api/logs/recent",
+		"/api/logs/range",
+		"/api/stats/summary",
+		"/api/charts/timeseries",
+		"/api/charts/breakdown",
+		"/api/stats/records",
+		"/api/charts/record-sizes",
+		"/api/charts/minutes",
+	} {
+		rr := httptest.NewRecorder()
+		handlers[path](rr, httptest.NewRequest("GET", path+"?last=90m", nil))
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s?last=90m: expected 200, got %d: %s", path, rr.Code, rr.Body.String())
+		}
+
+		rr = httptest.NewRecorder()
+		handlers[path](rr, httptest.NewRequest("GET", path+"?last=soon", nil))
+		if rr.Code == http.StatusOK {
+			t.Errorf("%s?last=soon: expected an error", path)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/events"
+)
+
+// rateWindows are the sliding windows ByteRates reports, shortest first.
+var rateWindows = []struct {
+	name   string
+	length time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+}
+
+// rateSeconds is how many one-second buckets ByteRates keeps: enough for
+// the longest window.
+const rateSeconds = 3600
+
+// WindowRate is the ingest rate over one sliding window.
+type WindowRate struct {
+	Window           string  `json:"window"`             // Window name: 1m, 5m or 1h
+	Seconds          int     `json:"seconds"`            // Window length in seconds
+	Bytes            int64   `json:"bytes"`              // Bytes ingested within the window
+	Batches          int64   `json:"batches"`            // Batches ingested within the window
+	BytesPerSecond   float64 `json:"bytes_per_second"`   // Bytes divided by the window length
+	BatchesPerSecond float64 `json:"batches_per_second"` // Batches divided by the window length
+}
+
+// RatesReport is the response body for /api/stats/rates.
+type RatesReport struct {
+	Time    time.Time    `json:"time"`    // When the rates were computed
+	Windows []WindowRate `json:"windows"` // Rates over each window, shortest first
+}
+
+// rateBucket holds what was ingested during one second.
+type rateBucket struct {
+	second  int64 // Unix second the bucket holds; older contents are stale
+	bytes   int64
+	batches int64
+}
+
+// ByteRates tracks ingest throughput over sliding windows of the last
+// minute, five minutes and hour, from the ingest.received events published
+// for every stored batch. Ingestion only adds to a one-second bucket, and
+// reading the rates sums at most an hour of buckets, so the dashboard's
+// throughput widget and metric scrapes never query the database. It is
+// safe for concurrent use; it starts empty on restart, so rates read
+// during the first window after startup cover less than the full window.
+type ByteRates struct {
+	mu      sync.Mutex
+	buckets [rateSeconds]rateBucket
+}
+
+// NewByteRates creates a rate tracker fed by the ingest.received events
+// published on bus.
+//
+// Parameters:
+//   - bus: Bus ingestion publishes on
+//
+// Returns:
+//   - *ByteRates: Tracker with every rate at zero
+func NewByteRates(bus *events.Bus) *ByteRates {
+	r := &ByteRates{}
+	bus.Subscribe(func(e events.Event) {
+		if received, ok := e.Data.(events.IngestReceived); ok {
+			r.Add(e.Time, received.Bytes)
+		}
+	}, events.TopicIngestReceived)
+	return r
+}
+
+// Add records a batch of size bytes ingested at t.
+func (r *ByteRates) Add(t time.Time, bytes int64) {
+	second := t.Unix()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := &r.buckets[second%rateSeconds]
+	if b.second != second {
+		*b = rateBucket{second: second}
+	}
+	b.bytes += bytes
+	b.batches++
+}
+
+// Rates returns the rate over each window ending at now. The current,
+// partly elapsed second counts towards every window.
+func (r *ByteRates) Rates() RatesReport {
+	current := now()
+	last := current.Unix()
+	report := RatesReport{Time: current.UTC(), Windows: make([]WindowRate, len(rateWindows))}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, w := range rateWindows {
+		seconds := int64(w.length / time.Second)
+		rate := WindowRate{Window: w.name, Seconds: int(seconds)}
+		for s := last - seconds + 1; s <= last; s++ {
+			if b := r.buckets[s%rateSeconds]; b.second == s {
+				rate.Bytes += b.bytes
+				rate.Batches += b.batches
+			}
+		}
+		rate.BytesPerSecond = float64(rate.Bytes) / float64(seconds)
+		rate.BatchesPerSecond = float64(rate.Batches) / float64(seconds)
+		report.Windows[i] = rate
+	}
+	return report
+}
+
+// MakeRatesHandler creates the /api/stats/rates handler reporting bytes
+// and batches ingested per second over the last minute, five minutes and
+// hour, for the dashboard's current throughput widget.
+//
+// Parameters:
+//   - rates: Tracker subscribed to the estimator's bus
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function
+func MakeRatesHandler(rates *ByteRates, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: ingest rates", "remote_addr", r.RemoteAddr)
+		sendSuccessResponse(w, rates.Rates())
+	}
+}
+
+// MakeRatesMetricsHandler creates the GET /metrics handler exposing the
+// ingest rates as Prometheus gauges in the text exposition format:
+//
+//	lpe_ingest_bytes_per_second{window="1m"} 5120
+//	lpe_ingest_batches_per_second{window="1m"} 0.5
+//
+// Parameters:
+//   - rates: Tracker subscribed to the estimator's bus
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function
+func MakeRatesMetricsHandler(rates *ByteRates, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("Metrics request", "remote_addr", r.RemoteAddr)
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		report := rates.Rates()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		fmt.Fprintln(w, "# HELP lpe_ingest_bytes_per_second Bytes ingested per second over a sliding window.")
+		fmt.Fprintln(w, "# TYPE lpe_ingest_bytes_per_second gauge")
+		for _, rate := range report.Windows {
+			fmt.Fprintf(w, "lpe_ingest_bytes_per_second{window=%q} %g\n", rate.Window, rate.BytesPerSecond)
+		}
+		fmt.Fprintln(w, "# HELP lpe_ingest_batches_per_second Batches ingested per second over a sliding window.")
+		fmt.Fprintln(w, "# TYPE lpe_ingest_batches_per_second gauge")
+		for _, rate := range report.Windows {
+			fmt.Fprintf(w, "lpe_ingest_batches_per_second{window=%q} %g\n", rate.Window, rate.BatchesPerSecond)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/events"
+	"github.com/melatonein5/LogpushEstimator/src/testsupport"
+)
+
+func TestByteRatesSlidingWindows(t *testing.T) {
+	clock := testsupport.NewClock(testsupport.Epoch)
+	SetClock(clock)
+	defer SetClock(nil)
+	bus := events.NewBus(clock, nil)
+	rates := NewByteRates(bus)
+
+	// 600 bytes 30 minutes ago, then 60 bytes a second for the last two minutes
+	bus.Publish(events.TopicIngestReceived, events.IngestReceived{Bytes: 600})
+	clock.Advance(28 * time.Minute)
+	for i := 0; i < 120; i++ {
+		clock.Advance(time.Second)
+		bus.Publish(events.TopicIngestReceived, events.IngestReceived{Bytes: 60})
+	}
+	// Other topics are ignored
+	bus.Publish(events.TopicJobSynced, events.JobSynced{})
+
+	want := map[string]struct {
+		bytes, batches int64
+		perSecond      float64
+	}{
+		"1m": {3600, 60, 60},
+		"5m": {7200, 120, 24},
+		"1h": {7800, 121, 7800.0 / 3600},
+	}
+	for _, w := range rates.Rates().Windows {
+		if exp := want[w.Window]; w.Bytes != exp.bytes || w.Batches != exp.batches || w.BytesPerSecond != exp.perSecond {
+			t.Errorf("Window %s: expected %+v, got %+v", w.Window, exp, w)
+		}
+	}
+
+	// An hour later the old buckets no longer count, even where reused
+	clock.Advance(time.Hour)
+	rates.Add(clock.Now(), 10)
+	for _, w := range rates.Rates().Windows {
+		if w.Bytes != 10 || w.Batches != 1 {
+			t.Errorf("Window %s: expected only the new batch, got %+v", w.Window, w)
+		}
+	}
+}
+
+func TestMakeRatesMetricsHandler(t *testing.T) {
+	clock := testsupport.NewClock(testsupport.Epoch)
+	SetClock(clock)
+	defer SetClock(nil)
+	rates := NewByteRates(events.NewBus(clock, nil))
+	rates.Add(clock.Now(), 6000)
+
+	rec := httptest.NewRecorder()
+	MakeRatesMetricsHandler(rates, slog.New(slog.NewTextHandler(io.Discard, nil)))(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE lpe_ingest_bytes_per_second gauge",
+		`lpe_ingest_bytes_per_second{window="1m"} 100`,
+		`lpe_ingest_bytes_per_second{window="1h"} 1.6666666666666667`,
+		`lpe_ingest_batches_per_second{window="5m"} 0.0033333333333333335`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected line %q in:\n%s", line, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+
+	rec = httptest.NewRecorder()
+	MakeRatesMetricsHandler(rates, slog.New(slog.NewTextHandler(io.Discard, nil)))(rec, httptest.NewRequest("POST", "/metrics", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/reports"
+)
+
+// Recommendation defaults: a week of observed data and the ten largest
+// savings.
+const (
+	defaultRecommendationDays  = 7
+	defaultRecommendationLimit = 10
+	maxRecommendationLimit     = 100
+)
+
+// RecommendationsReport is the response body for /api/recommendations.
+type RecommendationsReport struct {
+	Days            int                      `json:"days"`            // Days of observed data analyzed
+	Start           time.Time                `json:"start"`           // Start of the observed window
+	End             time.Time                `json:"end"`             // End of the observed window
+	MonthlyBytes    int64                    `json:"monthly_bytes"`   // Observed volume projected to a 30-day month
+	Samples         int                      `json:"samples"`         // Payload samples field sizes were measured on
+	PricingModel    string                   `json:"pricing_model"`   // Model savings were priced with; empty when none is configured
+	Recommendations []reports.Recommendation `json:"recommendations"` // Suggestions, largest savings first
+}
+
+// makeRecommendationsHandler serves /api/recommendations, ranking cost
+// reduction suggestions derived from the last `days` days (default 7, or
+// last=): fields worth dropping and compressibility from the payload
+// samples, datasets worth sampling, and dimension values worth filtering.
+// Savings are priced with the default pricing model, or the one named by
+// `model`; without any model only byte savings are reported. `limit` caps
+// the suggestions returned (default 10).
+func makeRecommendationsHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: recommendations", "remote_addr", r.RemoteAddr)
+
+		days, err := windowParam(r, "days", 24*time.Hour, defaultRecommendationDays)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		days = min(days, maxQueryWindowHours/24)
+		limit := defaultRecommendationLimit
+		if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+			limit = min(l, maxRecommendationLimit)
+		}
+
+		doc, err := config.Export(db)
+		if err != nil {
+			sendErrorResponse(w, "Failed to load pricing models")
+			return
+		}
+		var model *config.PricingModel
+		if name := r.URL.Query().Get("model"); name != "" || len(doc.PricingModels) > 0 {
+			m, err := selectPricingModel(doc.PricingModels, name)
+			if err != nil {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			model = &m
+		}
+
+		end := now().UTC()
+		start := end.Add(-time.Duration(days) * 24 * time.Hour)
+		usage, err := db.QueryTenantUsage(start, end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to query usage")
+			return
+		}
+		samples, err := db.ListSamples(scenarioSampleLimit)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch payload samples")
+			return
+		}
+		dimensions, err := db.QueryDimensionTotals(start, end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch dimension data")
+			return
+		}
+
+		in := reports.RecommendationInput{Days: float64(days), Samples: samples, Dimensions: dimensions, Model: model}
+		for _, u := range usage {
+			in.Bytes += u.TotalSize
+		}
+		report := RecommendationsReport{
+			Days:            days,
+			Start:           start,
+			End:             end,
+			MonthlyBytes:    int64(math.Round(float64(in.Bytes) * 30 / float64(days))),
+			Samples:         len(samples),
+			Recommendations: reports.Recommend(in),
+		}
+		if model != nil {
+			report.PricingModel = model.Name
+		}
+		if len(report.Recommendations) > limit {
+			report.Recommendations = report.Recommendations[:limit]
+		}
+		sendSuccessResponse(w, report)
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestRecommendationsHandler(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/recommendations"]
+
+	content := `{"RequestHeaders":"` + strings.Repeat("x", 400) + `","EdgeResponseStatus":200}`
+	if err := db.InsertSample(database.PayloadSample{Dataset: "http_requests", BatchBytes: 4096, RecordCount: 8, Content: content}, 10); err != nil {
+		t.Fatalf("Failed to insert sample: %v", err)
+	}
+
+	// Without a pricing model only byte savings are reported
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/recommendations?limit=1", nil))
+	var resp struct {
+		Data RecommendationsReport `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if resp.Data.Days != 7 || resp.Data.Samples != 1 || resp.Data.MonthlyBytes == 0 || len(resp.Data.Recommendations) != 1 {
+		t.Fatalf("Expected one recommendation from a week of data, got %+v", resp.Data)
+	}
+	if top := resp.Data.Recommendations[0]; top.SavedBytes == 0 || top.SavedCost != 0 || resp.Data.PricingModel != "" {
+		t.Errorf("Expected unpriced savings, got %+v", top)
+	}
+
+	doc := config.NewDocument()
+	doc.PricingModels = []config.PricingModel{{Name: "r2", Currency: "USD", PerGB: 1e6, Default: true}}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Failed to add pricing model: %v", err)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/recommendations", nil))
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if resp.Data.PricingModel != "r2" || len(resp.Data.Recommendations) < 2 || resp.Data.Recommendations[0].SavedCost == 0 {
+		t.Errorf("Expected savings priced with r2, got %+v", resp.Data)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/recommendations?model=s3", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown model, got %d", rr.Code)
+	}
+}
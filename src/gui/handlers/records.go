@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// RecordStats summarizes per-record sizes across a set of batches.
+// Comparing it with LogSizeStats shows whether volume growth is driven by
+// more events or by bigger events.
+type RecordStats struct {
+	TotalBatches       int64   `json:"total_batches"`         // Number of batches analyzed
+	TotalRecords       int64   `json:"total_records"`         // Sum of records across all batches
+	TotalSize          int64   `json:"total_size"`            // Sum of batch sizes in bytes
+	AvgRecordsPerBatch float64 `json:"avg_records_per_batch"` // Mean number of records per batch
+	AvgRecordSize      float64 `json:"avg_record_size"`       // Mean record size weighted by record count
+	MinRecordSize      int64   `json:"min_record_size"`       // Smallest record seen in any batch
+	MaxRecordSize      int64   `json:"max_record_size"`       // Largest record seen in any batch
+}
+
+// RecordSizePoint is an hourly data point for the record size chart.
+type RecordSizePoint struct {
+	Timestamp     string  `json:"timestamp"`       // ISO timestamp for the start of the hour
+	Batches       int     `json:"batches"`         // Number of batches received in the hour
+	Records       int64   `json:"records"`         // Number of records received in the hour
+	TotalSize     int64   `json:"total_size"`      // Sum of batch sizes in bytes
+	AvgRecordSize float64 `json:"avg_record_size"` // Mean record size weighted by record count
+}
+
+// makeRecordStatsHandler serves /api/stats/records, accepting the same
+// start/end and hours parameters as /api/stats/summary.
+func makeRecordStatsHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: record stats", "remote_addr", r.RemoteAddr)
+
+		logs, err := queryLogsForRequest(db, r)
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to get logs for record stats", "error", err)
+			sendErrorResponse(w, "Failed to fetch record statistics")
+			return
+		}
+
+		sendSuccessResponse(w, calculateRecordStats(logs))
+	}
+}
+
+// makeRecordSizeSeriesHandler serves /api/charts/record-sizes with hourly
+// record counts and average record size over the last `hours` hours.
+func makeRecordSizeSeriesHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: record size series", "remote_addr", r.RemoteAddr)
+
+		format, err := parseTableFormat(r, instanceUnits(db))
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		hours, err := windowParam(r, "hours", time.Hour, 24)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		end := now().UTC()
+		start := end.Add(-time.Duration(hours) * time.Hour)
+
+		logs, err := db.QueryByTimeRange(start, end)
+		if err != nil {
+			logger.Error("Failed to query logs for record size series", "error", err)
+			sendErrorResponse(w, "Failed to fetch record size data")
+			return
+		}
+
+		points := aggregateRecordSizesByHour(logs)
+		if format.table {
+			format.send(w, r, format.recordSizeTable(points), nil)
+			return
+		}
+		sendSuccessResponse(w, points)
+	}
+}
+
+// makeRecordSizeBreakdownHandler serves /api/charts/record-size-breakdown,
+// grouping batches by their average record size.
+func makeRecordSizeBreakdownHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: record size breakdown", "remote_addr", r.RemoteAddr)
+
+		format, err := parseTableFormat(r, instanceUnits(db))
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logs, err := queryLogsForRequest(db, r)
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to get logs for record size breakdown", "error", err)
+			sendErrorResponse(w, "Failed to fetch record size breakdown")
+			return
+		}
+
+		breakdown := calculateRecordSizeBreakdown(logs, format.units)
+		if format.table {
+			format.send(w, r, format.breakdownTable("Average record size distribution", "Average record size", breakdown), nil)
+			return
+		}
+		sendSuccessResponse(w, breakdown)
+	}
+}
+
+// calculateRecordStats aggregates per-batch record statistics. Batches stored
+// before record parsing was introduced (RecordCount == 0) only contribute to
+// TotalBatches and TotalSize.
+func calculateRecordStats(logs []database.LogSize) RecordStats {
+	var stats RecordStats
+	var recordBytes float64
+
+	for _, log := range logs {
+		stats.TotalBatches++
+		stats.TotalSize += log.Filesize
+		if log.RecordCount == 0 {
+			continue
+		}
+		if stats.TotalRecords == 0 || log.MinRecordSize < stats.MinRecordSize {
+			stats.MinRecordSize = log.MinRecordSize
+		}
+		if log.MaxRecordSize > stats.MaxRecordSize {
+			stats.MaxRecordSize = log.MaxRecordSize
+		}
+		stats.TotalRecords += log.RecordCount
+		recordBytes += log.AvgRecordSize * float64(log.RecordCount)
+	}
+
+	if stats.TotalBatches > 0 {
+		stats.AvgRecordsPerBatch = float64(stats.TotalRecords) / float64(stats.TotalBatches)
+	}
+	if stats.TotalRecords > 0 {
+		stats.AvgRecordSize = recordBytes / float64(stats.TotalRecords)
+	}
+	return stats
+}
+
+// aggregateRecordSizesByHour buckets batches by hour, returning points ordered
+// by timestamp.
+func aggregateRecordSizesByHour(logs []database.LogSize) []RecordSizePoint {
+	type bucket struct {
+		point       RecordSizePoint
+		recordBytes float64
+	}
+	buckets := make(map[string]*bucket)
+
+	for _, log := range logs {
+		hourKey := log.Timestamp.Truncate(time.Hour).Format(time.RFC3339)
+		b, ok := buckets[hourKey]
+		if !ok {
+			b = &bucket{point: RecordSizePoint{Timestamp: hourKey}}
+			buckets[hourKey] = b
+		}
+		b.point.Batches++
+		b.point.Records += log.RecordCount
+		b.point.TotalSize += log.Filesize
+		b.recordBytes += log.AvgRecordSize * float64(log.RecordCount)
+	}
+
+	result := make([]RecordSizePoint, 0, len(buckets))
+	for _, b := range buckets {
+		if b.point.Records > 0 {
+			b.point.AvgRecordSize = b.recordBytes / float64(b.point.Records)
+		}
+		result = append(result, b.point)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Timestamp < result[j].Timestamp })
+	return result
+}
+
+// calculateRecordSizeBreakdown distributes batches with parsed records into
+// average record size ranges.
+func calculateRecordSizeBreakdown(logs []database.LogSize, units config.Units) []SizeBreakdown {
+	ranges := sizeRanges(units, 0.25, 0.5, 1, 2, 4)
+
+	rangeCounts := make([]int, len(ranges))
+	total := 0
+
+	for _, log := range logs {
+		if log.RecordCount == 0 {
+			continue
+		}
+		total++
+		for i, r := range ranges {
+			if log.AvgRecordSize >= r.Min && (r.Max == 0 || log.AvgRecordSize < r.Max) {
+				rangeCounts[i]++
+				break
+			}
+		}
+	}
+
+	result := make([]SizeBreakdown, 0, len(ranges))
+	for i, r := range ranges {
+		percentage := 0.0
+		if total > 0 {
+			percentage = float64(rangeCounts[i]) / float64(total) * 100
+		}
+		result = append(result, SizeBreakdown{
+			Range:      r.Name,
+			Count:      rangeCounts[i],
+			Percentage: percentage,
+		})
+	}
+	return result
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestCalculateRecordStats(t *testing.T) {
+	logs := []database.LogSize{
+		{ID: 1, Filesize: 1000, RecordCount: 10, MinRecordSize: 50, MaxRecordSize: 150, AvgRecordSize: 100},
+		{ID: 2, Filesize: 3000, RecordCount: 10, MinRecordSize: 200, MaxRecordSize: 400, AvgRecordSize: 300},
+		{ID: 3, Filesize: 500}, // stored before record parsing existed
+	}
+
+	stats := calculateRecordStats(logs)
+
+	if stats.TotalBatches != 3 {
+		t.Errorf("Expected 3 batches, got %d", stats.TotalBatches)
+	}
+	if stats.TotalRecords != 20 {
+		t.Errorf("Expected 20 records, got %d", stats.TotalRecords)
+	}
+	if stats.AvgRecordSize != 200 {
+		t.Errorf("Expected weighted average record size 200, got %f", stats.AvgRecordSize)
+	}
+	if stats.MinRecordSize != 50 || stats.MaxRecordSize != 400 {
+		t.Errorf("Expected min/max 50/400, got %d/%d", stats.MinRecordSize, stats.MaxRecordSize)
+	}
+}
+
+func TestAggregateRecordSizesByHour(t *testing.T) {
+	hour := time.Now().Truncate(time.Hour)
+	logs := []database.LogSize{
+		{Timestamp: hour.Add(time.Hour), Filesize: 400, RecordCount: 2, AvgRecordSize: 200},
+		{Timestamp: hour, Filesize: 100, RecordCount: 1, AvgRecordSize: 100},
+		{Timestamp: hour.Add(10 * time.Minute), Filesize: 300, RecordCount: 1, AvgRecordSize: 300},
+	}
+
+	points := aggregateRecordSizesByHour(logs)
+
+	if len(points) != 2 {
+		t.Fatalf("Expected 2 hourly points, got %d", len(points))
+	}
+	if points[0].Records != 2 || points[0].AvgRecordSize != 200 {
+		t.Errorf("Unexpected first bucket: %+v", points[0])
+	}
+	if points[1].Batches != 1 || points[1].Records != 2 {
+		t.Errorf("Unexpected second bucket: %+v", points[1])
+	}
+}
+
+func TestCalculateRecordSizeBreakdown(t *testing.T) {
+	logs := []database.LogSize{
+		{RecordCount: 1, AvgRecordSize: 100},
+		{RecordCount: 1, AvgRecordSize: 700},
+		{RecordCount: 0, AvgRecordSize: 0}, // ignored
+	}
+
+	breakdown := calculateRecordSizeBreakdown(logs, config.UnitsBinary)
+
+	total := 0.0
+	for _, item := range breakdown {
+		total += item.Percentage
+	}
+	if total < 99.9 || total > 100.1 {
+		t.Errorf("Expected percentages to sum to 100, got %f", total)
+	}
+	if breakdown[0].Count != 1 || breakdown[2].Count != 1 {
+		t.Errorf("Unexpected breakdown: %+v", breakdown)
+	}
+}
+
+func TestAPIRecordEndpoints(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+
+	for _, path := range []string{"/api/stats/records", "/api/charts/record-sizes", "/api/charts/record-size-breakdown"} {
+		t.Run(path, func(t *testing.T) {
+			req, err := http.NewRequest("GET", path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handlers[path].ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusOK {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+			}
+
+			var response APIResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Errorf("Could not parse JSON response: %v", err)
+			}
+			if !response.Success {
+				t.Errorf("Expected success=true, got error=%v", response.Error)
+			}
+		})
+	}
+}
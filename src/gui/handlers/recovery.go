@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// makeRecoveryHandler serves GET /api/admin/recovery, reporting whether the
+// previous process shut down cleanly and, if not, what the startup recovery
+// scan checked and repaired. It answers 404 when no scan ran, e.g. when the
+// handlers are embedded without the server's startup.
+func makeRecoveryHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: recovery", "remote_addr", r.RemoteAddr)
+
+		rec, ok := db.LastRecovery()
+		if !ok {
+			sendErrorResponseWithStatus(w, http.StatusNotFound, "No recovery scan has run")
+			return
+		}
+		sendSuccessResponse(w, rec)
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestAPIRecovery(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/admin/recovery"]
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/recovery", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 before any scan, got %d", rr.Code)
+	}
+
+	if _, err := db.Recover([]string{"process 42 did not release logpush.db.lock"}, true); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/recovery", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Data database.Recovery `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !resp.Data.Unclean || len(resp.Data.Signs) != 1 || resp.Data.Integrity == nil {
+		t.Errorf("Unexpected recovery report %+v", resp.Data)
+	}
+}
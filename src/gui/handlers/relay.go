@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/melatonein5/LogpushEstimator/src/relay"
+)
+
+// RelayStatus is the response body for /api/admin/relay.
+type RelayStatus struct {
+	Enabled bool `json:"enabled"` // Whether ingested batches are relayed upstream
+
+	*relay.QueueStatus // Breaker, queue and outcomes; absent when relaying is disabled
+}
+
+// MakeRelayStatusHandler creates the GET /api/admin/relay handler
+// reporting the upstream's circuit breaker, the batches queued for retry
+// and the last day's relay outcomes.
+//
+// Parameters:
+//   - queue: Relay queue of the ingestion handler; nil when relaying is disabled
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function
+func MakeRelayStatusHandler(queue *relay.Queue, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: relay status", "remote_addr", r.RemoteAddr)
+		if queue == nil {
+			sendSuccessResponse(w, RelayStatus{})
+			return
+		}
+		status, err := queue.Status()
+		if err != nil {
+			logger.Error("Failed to read relay status", "error", err)
+			sendErrorResponse(w, "Failed to read relay status")
+			return
+		}
+		sendSuccessResponse(w, RelayStatus{Enabled: true, QueueStatus: &status})
+	}
+}
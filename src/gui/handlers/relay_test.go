@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestRelayStatusDisabled(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	rr := httptest.NewRecorder()
+	MakeRelayStatusHandler(nil, logger).ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/relay", nil))
+	if rr.Code != 200 || rr.Body.String() != `{"success":true,"data":{"enabled":false}}`+"\n" {
+		t.Errorf("Expected relaying reported disabled, got %d %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+	"github.com/melatonein5/LogpushEstimator/src/i18n"
+	"github.com/melatonein5/LogpushEstimator/src/reports"
+)
+
+// selectPricingModel returns the model named name, or the default model
+// (falling back to the only model) when name is empty.
+func selectPricingModel(models []config.PricingModel, name string) (config.PricingModel, error) {
+	if len(models) == 0 {
+		return config.PricingModel{}, &requestError{"No pricing model is configured; add one to pricing_models in /api/admin/config"}
+	}
+	for _, m := range models {
+		if (name == "" && m.Default) || (name != "" && m.Name == name) {
+			return m, nil
+		}
+	}
+	if name == "" && len(models) == 1 {
+		return models[0], nil
+	}
+	if name == "" {
+		return config.PricingModel{}, &requestError{"No default pricing model; pass model="}
+	}
+	return config.PricingModel{}, &requestError{"Unknown pricing model: " + name}
+}
+
+// MakeChargebackHandler creates the GET /api/reports/chargeback handler. It
+// prices a month of observed usage (?month=YYYY-MM, default the current UTC
+// month) with the default pricing model, or the one named by ?model=, and
+// allocates the cost across tenants in proportion to their usage. With
+// ?format=csv the report is served as a CSV attachment, sealed as
+// chargeback-YYYY-MM.csv.enc when a key is configured. ?lang= translates
+// the CSV's column titles for finance teams reading it as a spreadsheet.
+//
+// Parameters:
+//   - key: Encryption key for CSV reports, or nil to serve plain CSV
+//   - db: Database controller to read usage and pricing models from
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function
+func MakeChargebackHandler(key *encryption.Key, db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: chargeback report", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		q := r.URL.Query()
+		month := now().UTC()
+		if v := q.Get("month"); v != "" {
+			parsed, err := reports.ParseMonth(v)
+			if err != nil {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid month (use YYYY-MM)")
+				return
+			}
+			month = parsed
+		}
+		format := q.Get("format")
+		if format != "" && format != "json" && format != "csv" {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "format must be json or csv")
+			return
+		}
+		// Billing systems import the CSV by its column names, so only an
+		// explicit lang translates them, not the Accept-Language header
+		var printer *i18n.Printer
+		if q.Get("lang") != "" {
+			locale, err := requestLocale(r, "")
+			if err != nil {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			printer = i18n.For(locale)
+		}
+
+		doc, err := config.Export(db)
+		if err != nil {
+			sendErrorResponse(w, "Failed to load pricing models")
+			return
+		}
+		model, err := selectPricingModel(doc.PricingModels, q.Get("model"))
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		start, end := reports.MonthBounds(month)
+		usage, err := db.QueryTenantUsage(start, end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to query tenant usage")
+			return
+		}
+		var requests map[string]int64
+		if model.Batching != nil {
+			hourly, err := db.QueryTenantHourlyUsage(start, end)
+			if err != nil {
+				sendErrorResponse(w, "Failed to query tenant usage")
+				return
+			}
+			requests = reports.EstimateRequests(*model.Batching, hourly)
+		}
+		report := reports.Allocate(month, model, usage, requests)
+
+		if format != "csv" {
+			sendSuccessResponse(w, report)
+			return
+		}
+
+		var buf bytes.Buffer
+		if printer != nil {
+			err = report.WriteLocalizedCSV(&buf, printer)
+		} else {
+			err = report.WriteCSV(&buf)
+		}
+		if err != nil {
+			logger.Error("Failed to write chargeback CSV", "error", err)
+			sendErrorResponse(w, "Failed to write chargeback report")
+			return
+		}
+		data, contentType, filename := buf.Bytes(), "text/csv", "chargeback-"+report.Month+".csv"
+		if key != nil {
+			sealed, err := key.Seal(data)
+			if err != nil {
+				logger.Error("Failed to encrypt chargeback report", "error", err)
+				sendErrorResponse(w, "Failed to encrypt chargeback report")
+				return
+			}
+			data, contentType, filename = sealed, "application/octet-stream", filename+encryptedSuffix
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.Header().Set("X-Export-Encrypted", strconv.FormatBool(key != nil))
+		if _, err := w.Write(data); err != nil {
+			logger.Error("Failed to write chargeback report", "error", err)
+		}
+	}
+}
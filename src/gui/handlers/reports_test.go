@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+	"github.com/melatonein5/LogpushEstimator/src/reports"
+)
+
+func TestChargebackHandler(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/reports/chargeback"]
+
+	// Without a pricing model there is nothing to price usage with
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/reports/chargeback", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 without pricing models, got %d", rr.Code)
+	}
+
+	doc := config.NewDocument()
+	doc.PricingModels = []config.PricingModel{{Name: "r2", Currency: "USD", PerGB: 1000, Default: true}}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Failed to add pricing model: %v", err)
+	}
+	at := time.Date(2025, 9, 10, 0, 0, 0, 0, time.UTC)
+	db.ForTenant("acme").InsertLog(database.LogSize{Timestamp: at, Filesize: 3_000_000})
+	db.ForTenant("globex").InsertLog(database.LogSize{Timestamp: at, Filesize: 1_000_000})
+	db.ForTenant("globex").InsertLog(database.LogSize{Timestamp: at.AddDate(0, 1, 0), Filesize: 1_000_000})
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/reports/chargeback?month=2025-09", nil))
+	var resp struct {
+		Success bool               `json:"success"`
+		Data    reports.Chargeback `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if resp.Data.TotalCost != 4 || len(resp.Data.Lines) != 2 || resp.Data.Lines[0].Tenant != "acme" || resp.Data.Lines[0].Cost != 3 {
+		t.Errorf("Expected $3 for acme and $1 for globex in September, got %+v", resp.Data)
+	}
+
+	for query, want := range map[string]int{
+		"?month=2025-9":  http.StatusBadRequest,
+		"?model=s3":      http.StatusBadRequest,
+		"?format=xml":    http.StatusBadRequest,
+		"?model=r2":      http.StatusOK,
+		"?month=2025-10": http.StatusOK,
+	} {
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/reports/chargeback"+query, nil))
+		if rr.Code != want {
+			t.Errorf("%s: expected %d, got %d", query, want, rr.Code)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/reports/chargeback?month=2025-09&format=csv", nil))
+	if !strings.Contains(rr.Header().Get("Content-Disposition"), "chargeback-2025-09.csv") {
+		t.Errorf("Unexpected attachment name %q", rr.Header().Get("Content-Disposition"))
+	}
+	if !strings.Contains(rr.Body.String(), "2025-09,acme,1,0,3000000,1,0.750000,3.00,USD\n") {
+		t.Errorf("Unexpected CSV:\n%s", rr.Body.String())
+	}
+
+	// Only an explicit lang translates the column titles
+	req := httptest.NewRequest("GET", "/api/reports/chargeback?month=2025-09&format=csv", nil)
+	req.Header.Set("Accept-Language", "fr")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if !strings.HasPrefix(rr.Body.String(), "month,tenant,") {
+		t.Errorf("Expected machine-readable titles without lang, got:\n%s", rr.Body.String())
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/reports/chargeback?month=2025-09&format=csv&lang=fr", nil))
+	if !strings.HasPrefix(rr.Body.String(), "Mois,Client,") {
+		t.Errorf("Expected French titles, got:\n%s", rr.Body.String())
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/reports/chargeback?format=csv&lang=tlh", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unsupported lang, got %d", rr.Code)
+	}
+}
+
+func TestChargebackHandlerBatching(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	doc := config.NewDocument()
+	doc.PricingModels = []config.PricingModel{{
+		Name: "s3", Currency: "USD", PerMillionRequests: 5,
+		Batching: &config.BatchPolicy{MaxBytes: 1_000_000},
+	}}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Failed to add pricing model: %v", err)
+	}
+	// One batch of 2.5 MB becomes three objects of at most 1 MB
+	at := time.Date(2025, 9, 10, 0, 0, 0, 0, time.UTC)
+	db.ForTenant("acme").InsertLog(database.LogSize{Timestamp: at, Filesize: 2_500_000})
+
+	rr := httptest.NewRecorder()
+	MakeChargebackHandler(nil, db, logger).ServeHTTP(rr, httptest.NewRequest("GET", "/api/reports/chargeback?month=2025-09", nil))
+	var resp struct {
+		Data reports.Chargeback `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if !resp.Data.Estimated || resp.Data.Requests != 3 || len(resp.Data.Lines) != 1 || resp.Data.Lines[0].Requests != 3 {
+		t.Errorf("Expected 3 estimated requests, got %+v", resp.Data)
+	}
+}
+
+func TestChargebackHandlerEncrypted(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	key, err := encryption.NewKey(bytes.Repeat([]byte{3}, encryption.KeySize))
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	doc := config.NewDocument()
+	doc.PricingModels = []config.PricingModel{{Name: "r2", Currency: "USD", PerGB: 1}}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Failed to add pricing model: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	MakeChargebackHandler(key, db, logger).ServeHTTP(rr, httptest.NewRequest("GET", "/api/reports/chargeback?format=csv", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("X-Export-Encrypted") != "true" {
+		t.Fatalf("Expected an encrypted report, got %d", rr.Code)
+	}
+	plain, err := key.Open(rr.Body.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to decrypt report: %v", err)
+	}
+	if !strings.HasPrefix(string(plain), "month,tenant,") {
+		t.Errorf("Unexpected decrypted report: %s", plain)
+	}
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// defaultSeriesPoints is the number of points the time series endpoint aims
+// for when it picks a bucket size.
+const defaultSeriesPoints = 300
+
+// maxSeriesPoints bounds the points= override.
+const maxSeriesPoints = 5000
+
+// seriesIntervals are the bucket sizes the time series endpoint can use,
+// finest first, by the name accepted in interval=.
+var seriesIntervals = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"minute", time.Minute},
+	{"hour", time.Hour},
+	{"day", 24 * time.Hour},
+}
+
+// pickSeriesInterval returns the finest bucket size that divides window into
+// at most points buckets, or the coarsest when none does.
+//
+// Parameters:
+//   - window: Length of the requested range
+//   - points: Maximum number of buckets wanted
+//
+// Returns:
+//   - string: Interval name
+//   - time.Duration: Bucket size
+func pickSeriesInterval(window time.Duration, points int) (string, time.Duration) {
+	for _, iv := range seriesIntervals {
+		if int64(window/iv.duration) <= int64(points) {
+			return iv.name, iv.duration
+		}
+	}
+	last := seriesIntervals[len(seriesIntervals)-1]
+	return last.name, last.duration
+}
+
+// parseSeriesInterval returns the bucket size named by interval=.
+func parseSeriesInterval(name string) (time.Duration, bool) {
+	for _, iv := range seriesIntervals {
+		if iv.name == name {
+			return iv.duration, true
+		}
+	}
+	return 0, false
+}
+
+// seriesRequest is a parsed time series request.
+type seriesRequest struct {
+	start, end time.Time
+	interval   string
+	bucket     time.Duration
+}
+
+// parseSeriesRequest reads the window (last, start/end, or hours back from
+// now, default 24) and bucket size (interval=, or picked to give about points=
+// buckets) of a time series request.
+//
+// Parameters:
+//   - get: Query parameter lookup
+//   - now: Current time
+//
+// Returns:
+//   - seriesRequest: Window and bucket size
+//   - error: *requestError for bad parameters
+func parseSeriesRequest(get func(string) string, now time.Time) (seriesRequest, error) {
+	var req seriesRequest
+	last, err := lastParam(get)
+	if err != nil {
+		return req, err
+	}
+	if last > 0 {
+		req.end = now.UTC()
+		req.start = req.end.Add(-last)
+	} else if startStr, endStr := get("start"), get("end"); startStr != "" || endStr != "" {
+		if startStr == "" || endStr == "" {
+			return req, &requestError{"start and end must be given together"}
+		}
+		if req.start, err = parseTimestamp(startStr); err != nil {
+			return req, &requestError{invalidTimeMessage("start")}
+		}
+		if req.end, err = parseTimestamp(endStr); err != nil {
+			return req, &requestError{invalidTimeMessage("end")}
+		}
+		if !req.end.After(req.start) {
+			return req, &requestError{"end must be after start"}
+		}
+	} else {
+		hours := 24 // default to 24 hours
+		if h, err := strconv.Atoi(get("hours")); err == nil && h > 0 {
+			hours = h
+		}
+		req.end = now.UTC()
+		req.start = req.end.Add(-time.Duration(hours) * time.Hour)
+	}
+
+	if name := get("interval"); name != "" {
+		bucket, ok := parseSeriesInterval(name)
+		if !ok {
+			return req, &requestError{"interval must be minute, hour or day"}
+		}
+		req.interval, req.bucket = name, bucket
+		return req, nil
+	}
+
+	points := defaultSeriesPoints
+	if v := get("points"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 1 || p > maxSeriesPoints {
+			return req, &requestError{"points must be between 1 and " + strconv.Itoa(maxSeriesPoints)}
+		}
+		points = p
+	}
+	req.interval, req.bucket = pickSeriesInterval(req.end.Sub(req.start), points)
+	return req, nil
+}
+
+// aggregateByInterval sums log records into buckets of the given size,
+// aligned to UTC, ordered by time. Empty buckets are omitted.
+//
+// Parameters:
+//   - logs: Log records to aggregate
+//   - bucket: Bucket size
+//
+// Returns:
+//   - []TimeSeriesPoint: One point per non-empty bucket
+func aggregateByInterval(logs []database.LogSize, bucket time.Duration) []TimeSeriesPoint {
+	type totals struct {
+		count     int
+		records   int64
+		totalSize int64
+	}
+	buckets := make(map[time.Time]*totals)
+	for _, log := range logs {
+		key := log.Timestamp.UTC().Truncate(bucket)
+		t := buckets[key]
+		if t == nil {
+			t = &totals{}
+			buckets[key] = t
+		}
+		t.count++
+		t.records += log.RecordCount
+		t.totalSize += log.Filesize
+	}
+
+	keys := make([]time.Time, 0, len(buckets))
+	for k := range buckets {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Before(keys[j]) })
+
+	result := make([]TimeSeriesPoint, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, TimeSeriesPoint{
+			Timestamp: k.Format(time.RFC3339),
+			Count:     buckets[k].count,
+			Records:   buckets[k].records,
+			TotalSize: buckets[k].totalSize,
+		})
+	}
+	return result
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestPickSeriesInterval(t *testing.T) {
+	tests := []struct {
+		window time.Duration
+		points int
+		want   string
+	}{
+		{time.Hour, 300, "minute"},
+		{5 * time.Hour, 300, "minute"},
+		{6 * time.Hour, 300, "hour"},
+		{24 * time.Hour, 300, "hour"},
+		{7 * 24 * time.Hour, 300, "hour"},
+		{30 * 24 * time.Hour, 300, "day"},
+		{10 * 365 * 24 * time.Hour, 300, "day"},
+		{24 * time.Hour, 2000, "minute"},
+	}
+	for _, tt := range tests {
+		if got, _ := pickSeriesInterval(tt.window, tt.points); got != tt.want {
+			t.Errorf("pickSeriesInterval(%v, %d) = %s, want %s", tt.window, tt.points, got, tt.want)
+		}
+	}
+}
+
+func TestParseSeriesRequest(t *testing.T) {
+	now := time.Date(2025, 9, 15, 12, 0, 0, 0, time.UTC)
+	parse := func(query string) (seriesRequest, error) {
+		values, _ := url.ParseQuery(query)
+		return parseSeriesRequest(values.Get, now)
+	}
+
+	req, err := parse("")
+	if err != nil || req.interval != "hour" || !req.end.Equal(now) || !req.start.Equal(now.Add(-24*time.Hour)) {
+		t.Errorf("Expected the last 24 hours by the hour, got %+v (%v)", req, err)
+	}
+
+	req, err = parse("start=2025-08-01T00:00:00Z&end=2025-09-01T00:00:00Z")
+	if err != nil || req.interval != "day" {
+		t.Errorf("Expected a month by the day, got %+v (%v)", req, err)
+	}
+
+	req, err = parse("hours=168&interval=minute")
+	if err != nil || req.interval != "minute" || req.bucket != time.Minute {
+		t.Errorf("Expected the interval override, got %+v (%v)", req, err)
+	}
+
+	req, err = parse("hours=24&points=2000")
+	if err != nil || req.interval != "minute" {
+		t.Errorf("Expected minutes with points=2000, got %+v (%v)", req, err)
+	}
+
+	for _, bad := range []string{
+		"start=2025-09-01T00:00:00Z",
+		"start=yesterday&end=2025-09-01T00:00:00Z",
+		"start=2025-09-02T00:00:00Z&end=2025-09-01T00:00:00Z",
+		"interval=week",
+		"points=0",
+		"points=many",
+	} {
+		if _, err := parse(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestAggregateByInterval(t *testing.T) {
+	base := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	logs := []database.LogSize{
+		{ID: 1, Timestamp: base.Add(25 * time.Hour), Filesize: 300},
+		{ID: 2, Timestamp: base, Filesize: 100},
+		{ID: 3, Timestamp: base.Add(time.Hour), Filesize: 200},
+	}
+
+	days := aggregateByInterval(logs, 24*time.Hour)
+	if len(days) != 2 || days[0].Timestamp != "2025-09-15T00:00:00Z" || days[0].Count != 2 || days[0].TotalSize != 300 {
+		t.Errorf("Unexpected day buckets: %+v", days)
+	}
+
+	hours := aggregateByInterval(logs, time.Hour)
+	if len(hours) != 3 || hours[0].TotalSize != 100 || hours[2].TotalSize != 300 {
+		t.Errorf("Expected 3 hour buckets oldest first, got %+v", hours)
+	}
+
+	if empty := aggregateByInterval(nil, time.Hour); empty == nil || len(empty) != 0 {
+		t.Errorf("Expected an empty series, got %#v", empty)
+	}
+}
+
+func TestAPITimeSeriesResolution(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/charts/timeseries"]
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/api/charts/timeseries?hours=2", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("X-Series-Interval"); got != "minute" {
+		t.Errorf("Expected minute buckets for 2 hours, got %q", got)
+	}
+	var response struct {
+		Data []TimeSeriesPoint `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	total := 0
+	for _, p := range response.Data {
+		total += p.Count
+	}
+	if total != 5 {
+		t.Errorf("Expected 5 records across the series, got %d", total)
+	}
+
+	rr = httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/api/charts/timeseries?interval=week", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown interval, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// RetentionStatus is the response body for GET /api/admin/retention: the
+// policy and what the retention janitor would prune on its next run.
+type RetentionStatus struct {
+	config.StoredRetention
+	Cutoff  *time.Time               `json:"cutoff,omitempty"` // Raw records before this have expired; omitted when raw_days is 0
+	Expired database.DeletionSummary `json:"expired"`          // Expired records still stored, awaiting the janitor
+}
+
+// makeRetentionHandler serves /api/admin/retention. GET returns the
+// retention policy with the cutoff it implies now and the expired records
+// awaiting the background janitor; PUT replaces the policy with the JSON
+// body, refused with 412 when the body's updated_at is set and the policy
+// changed since. With dry_run=true a PUT only validates the policy.
+func makeRetentionHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: retention", "method", r.Method, "remote_addr", r.RemoteAddr)
+
+		switch r.Method {
+		case http.MethodGet:
+			stored, err := config.GetRetention(db)
+			if err != nil {
+				sendErrorResponse(w, "Failed to read retention settings")
+				return
+			}
+			status := RetentionStatus{StoredRetention: stored}
+			if cutoff, ok := stored.RawCutoff(now()); ok {
+				status.Cutoff = &cutoff
+				if status.Expired, err = db.SummarizeTimeRange(time.Unix(0, 0), cutoff); err != nil {
+					sendErrorResponse(w, "Failed to summarize expired records")
+					return
+				}
+			}
+			sendSuccessResponse(w, status)
+
+		case http.MethodPut:
+			var body config.StoredRetention
+			if err := decodeStoredObject(r, &body); err != nil {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid retention: "+err.Error())
+				return
+			}
+			if isDryRun(r) {
+				if err := config.CheckRetention(db, body.Retention); err != nil {
+					sendConfigEditError(w, logger, err, "Retention")
+					return
+				}
+				sendSuccessResponse(w, map[string]any{"dry_run": true, "valid": true, "retention": body.Retention})
+				return
+			}
+			saved, err := config.SaveRetention(db, body.Retention, body.UpdatedAt)
+			if err != nil {
+				sendConfigEditError(w, logger, err, "Retention")
+				return
+			}
+			logger.Info("Retention updated", "raw_days", saved.RawDays, "raw_policy", saved.RawPolicy)
+			sendSuccessResponse(w, saved)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestAPIRetention(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	if err := db.InsertLog(database.LogSize{Timestamp: now().AddDate(0, 0, -100), Filesize: 500}); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/admin/retention"]
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rr
+	}
+	get := func() RetentionStatus {
+		t.Helper()
+		var response struct {
+			Data RetentionStatus `json:"data"`
+		}
+		rr := serve("GET", "/api/admin/retention", "")
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200 from GET, got %d: %s", rr.Code, rr.Body.String())
+		}
+		return response.Data
+	}
+
+	// Records are kept forever by default
+	if status := get(); status.RawDays != 0 || status.Cutoff != nil || status.Expired.Rows != 0 {
+		t.Errorf("Expected the default policy with nothing expired, got %+v", status)
+	}
+
+	policy := `{"raw_days": 90, "minute_aggregate_hours": 48, "trash_days": 7}`
+	if rr := serve("PUT", "/api/admin/retention?dry_run=true", policy); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from dry run, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if stored, _ := config.GetRetention(db); stored.RawDays != 0 {
+		t.Fatalf("Expected dry run not to store the policy, got %+v", stored)
+	}
+	if rr := serve("PUT", "/api/admin/retention", policy); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from PUT, got %d: %s", rr.Code, rr.Body.String())
+	}
+	status := get()
+	if status.RawDays != 90 || status.UpdatedAt.IsZero() || status.Cutoff == nil || status.Expired.Rows != 1 || status.Expired.Bytes != 500 {
+		t.Errorf("Expected the 100 day old record to have expired, got %+v", status)
+	}
+
+	// A PUT based on a stale read is refused
+	stale := `{"raw_days": 30, "minute_aggregate_hours": 48, "updated_at": "2020-01-01T00:00:00Z"}`
+	if rr := serve("PUT", "/api/admin/retention", stale); rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale updated_at, got %d", rr.Code)
+	}
+	for name, body := range map[string]string{
+		"negative days": `{"raw_days": -1, "minute_aggregate_hours": 48}`,
+		"unknown field": `{"raw_days": 30, "minute_aggregate_hours": 48, "keep": "forever"}`,
+		"bad policy":    `{"raw_days": 30, "minute_aggregate_hours": 48, "raw_policy": "shred"}`,
+	} {
+		if rr := serve("PUT", "/api/admin/retention", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rr.Code)
+		}
+	}
+	if rr := serve("DELETE", "/api/admin/retention", ""); rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for DELETE, got %d", rr.Code)
+	}
+	if status := get(); status.RawDays != 90 || !status.UpdatedAt.Before(time.Now()) {
+		t.Errorf("Expected the stored policy unchanged, got %+v", status)
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Router is the part of a router RegisterRoutes needs. *http.ServeMux and
+// chi's router satisfy it directly; RouterFunc adapts routers whose
+// registration methods have other signatures, such as gorilla/mux and echo.
+//
+// Patterns ending in a slash (e.g. /api/views/) cover every path below
+// them, as with http.ServeMux; routers with their own wildcard syntax
+// should translate them, e.g. to /api/views/* for chi.
+type Router interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// RouterFunc adapts a registration function to Router, for example
+//
+//	handlers.RouterFunc(func(pattern string, h http.Handler) { r.Handle(pattern, h) })
+//
+// for a gorilla/mux router, whose Handle returns the route it creates.
+type RouterFunc func(pattern string, handler http.Handler)
+
+// Handle calls f(pattern, handler).
+func (f RouterFunc) Handle(pattern string, handler http.Handler) {
+	f(pattern, handler)
+}
+
+// APIHandlers maps API route paths to their handlers, as returned by
+// MakeAPIHandlers.
+type APIHandlers map[string]http.HandlerFunc
+
+// RegisterRoutes registers every handler with r under its path, wrapped
+// with WithMethods so wrong methods get 405 and OPTIONS preflights are
+// answered whatever the router, and with WithInt64Strings so int64=string
+// works on every route. Routes are registered in path order so
+// routers that care about registration order behave the same on every run.
+//
+// Parameters:
+//   - r: Router to register the routes with
+func (h APIHandlers) RegisterRoutes(r Router) {
+	paths := make([]string, 0, len(h))
+	for path := range h {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		r.Handle(path, WithMethods(path, WithInt64Strings(h[path])))
+	}
+}
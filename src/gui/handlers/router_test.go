@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestRegisterRoutesServeMux(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	mux := http.NewServeMux()
+	MakeAPIHandlers(db, logger).RegisterRoutes(mux)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/api/stats/summary", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("OPTIONS", "/api/views/daily", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 for a preflight below a subtree route, got %d", rr.Code)
+	}
+}
+
+func TestRegisterRoutesRouterFunc(t *testing.T) {
+	routes := APIHandlers{
+		"/api/b": func(w http.ResponseWriter, r *http.Request) {},
+		"/api/a": func(w http.ResponseWriter, r *http.Request) {},
+	}
+	var patterns []string
+	var handler http.Handler
+	routes.RegisterRoutes(RouterFunc(func(pattern string, h http.Handler) {
+		patterns = append(patterns, pattern)
+		handler = h
+	}))
+
+	if !slices.Equal(patterns, []string{"/api/a", "/api/b"}) {
+		t.Errorf("Expected routes in path order, got %v", patterns)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/b", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected registered handlers to check methods, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// makeAPINotFoundHandler serves the /api/ catch-all, answering requests for
+// unknown API routes with the JSON error envelope rather than Go's plain
+// text 404 page, so API clients can always parse the response.
+func makeAPINotFoundHandler(logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: unknown route", "path", r.URL.Path, "method", r.Method, "remote_addr", r.RemoteAddr)
+		sendErrorResponseWithStatus(w, http.StatusNotFound, "Not found: "+r.URL.Path)
+	}
+}
+
+// allowedMethods returns the methods listed in a response's Allow header.
+func allowedMethods(h http.Header) []string {
+	var methods []string
+	for _, value := range h.Values("Allow") {
+		for _, m := range strings.Split(value, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				methods = append(methods, m)
+			}
+		}
+	}
+	return methods
+}
+
+// routeMethods lists the methods each API route accepts besides OPTIONS,
+// keyed by the path it is registered at. Routes not listed are read-only
+// and accept GET. HEAD is accepted wherever GET is; routes listing it
+// explicitly serve it themselves.
+var routeMethods = map[string][]string{
+	"/api/admin/config":           {http.MethodGet, http.MethodPut},
+	"/api/admin/config/import":    {http.MethodPost},
+	"/api/admin/delete-range":     {http.MethodPost, http.MethodDelete},
+	"/api/admin/prune":            {http.MethodPost},
+	"/api/admin/purge-dataset":    {http.MethodPost, http.MethodDelete},
+	"/api/admin/retention":        {http.MethodGet, http.MethodPut},
+	"/api/admin/integrity":        {http.MethodGet, http.MethodPost},
+	"/api/admin/outbox/retry":     {http.MethodPost},
+	"/api/admin/trash/restore":    {http.MethodPost},
+	"/api/admin/tenants/bulk":     {http.MethodPost},
+	"/api/admin/tokens/":          {http.MethodPost},
+	"/api/cloudflare/jobs/create": {http.MethodPost},
+	"/api/export/verify":          {http.MethodPost},
+	"/api/exports":                {http.MethodPost},
+	"/api/exports/":               {http.MethodGet, http.MethodDelete},
+	"/api/logs/count":             {http.MethodGet, http.MethodHead},
+	"/api/preferences":            {http.MethodGet, http.MethodPut, http.MethodPost},
+	"/api/views":                  {http.MethodGet, http.MethodPost},
+	"/api/views/":                 {http.MethodGet, http.MethodPut, http.MethodDelete},
+	"/api/scenarios":              {http.MethodGet, http.MethodPost},
+	"/api/scenarios/":             {http.MethodGet, http.MethodPut, http.MethodDelete},
+	"/api/alerts":                 {http.MethodGet, http.MethodPost},
+	"/api/alerts/":                {http.MethodGet, http.MethodPut, http.MethodDelete, http.MethodPost},
+	"/api/budgets":                {http.MethodGet, http.MethodPost},
+	"/api/budgets/":               {http.MethodGet, http.MethodPut, http.MethodDelete, http.MethodPost},
+}
+
+// corsAllowHeaders are the request headers a cross-origin caller may send.
+const corsAllowHeaders = "Content-Type, " + CSRFHeader
+
+// corsMaxAge is how long, in seconds, browsers may cache a preflight result.
+const corsMaxAge = "600"
+
+// routeAllow returns the methods accepted at path, in the order they are
+// listed in Allow headers.
+func routeAllow(path string) []string {
+	methods, ok := routeMethods[path]
+	if !ok {
+		methods = []string{http.MethodGet}
+	}
+	allow := make([]string, 0, len(methods)+2)
+	for _, m := range methods {
+		allow = append(allow, m)
+		if m == http.MethodGet && !slices.Contains(methods, http.MethodHead) {
+			allow = append(allow, http.MethodHead)
+		}
+	}
+	return append(allow, http.MethodOptions)
+}
+
+// WithMethods wraps the API handler registered at path so the route's
+// accepted methods are enforced in one place: OPTIONS requests, including
+// CORS preflights, are answered with 204 and the Allow and
+// Access-Control-Allow-* headers; methods the route does not accept get a
+// JSON 405 listing the ones it does; and HEAD is served by the GET handler
+// with the body discarded. The /api/ catch-all is returned unchanged so
+// unknown routes stay 404 whatever the method.
+//
+// Parameters:
+//   - path: Route path the handler is registered at
+//   - next: Handler to wrap
+//
+// Returns:
+//   - http.HandlerFunc: Method-checked handler
+func WithMethods(path string, next http.HandlerFunc) http.HandlerFunc {
+	if path == "/api/" {
+		return next
+	}
+	allow := routeAllow(path)
+	allowHeader := strings.Join(allow, ", ")
+	accepted := make(map[string]bool, len(allow))
+	for _, m := range allow {
+		accepted[m] = true
+	}
+	headHandled := slices.Contains(routeMethods[path], http.MethodHead)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodOptions:
+			w.Header().Set("Allow", allowHeader)
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Allow-Methods", allowHeader)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+		case !accepted[r.Method]:
+			w.Header().Set("Allow", allowHeader)
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		case r.Method == http.MethodHead && !headHandled:
+			// net/http drops the body of a response to HEAD, so the GET
+			// handler can serve it unchanged
+			get := r.Clone(r.Context())
+			get.Method = http.MethodGet
+			next(w, get)
+		default:
+			next(w, r)
+		}
+	}
+}
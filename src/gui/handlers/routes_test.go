@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestAPINotFound(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := makeAPINotFoundHandler(logger)
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/api/nope", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON, got %s", ct)
+	}
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || response.Success || response.Error == "" {
+		t.Errorf("Expected the error envelope, got %s", rr.Body.String())
+	}
+}
+
+func TestMethodNotAllowedListsAllowedMethods(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	rr := httptest.NewRecorder()
+	MakeAPIHandlers(db, logger)["/api/logs/count"](rr, httptest.NewRequest("DELETE", "/api/logs/count", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected 405, got %d", rr.Code)
+	}
+	var response APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if !reflect.DeepEqual(response.AllowedMethods, []string{"GET", "HEAD"}) {
+		t.Errorf("Expected allowed_methods [GET HEAD], got %v", response.AllowedMethods)
+	}
+}
+
+func TestWithMethods(t *testing.T) {
+	var gotMethod string
+	next := func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		sendSuccessResponse(w, "ok")
+	}
+
+	t.Run("OPTIONS preflight", func(t *testing.T) {
+		gotMethod = ""
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("OPTIONS", "/api/views", nil)
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		WithMethods("/api/views", next)(rr, req)
+		if rr.Code != http.StatusNoContent {
+			t.Errorf("Expected 204, got %d", rr.Code)
+		}
+		if allow := rr.Header().Get("Allow"); allow != "GET, HEAD, POST, OPTIONS" {
+			t.Errorf("Unexpected Allow header %q", allow)
+		}
+		if got := rr.Header().Get("Access-Control-Allow-Methods"); got != "GET, HEAD, POST, OPTIONS" {
+			t.Errorf("Unexpected Access-Control-Allow-Methods %q", got)
+		}
+		if rr.Header().Get("Access-Control-Allow-Origin") != "*" || rr.Header().Get("Access-Control-Allow-Headers") == "" {
+			t.Errorf("Expected CORS preflight headers, got %v", rr.Header())
+		}
+		if gotMethod != "" {
+			t.Error("Expected OPTIONS not to reach the handler")
+		}
+	})
+
+	t.Run("wrong method", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		WithMethods("/api/stats/summary", next)(rr, httptest.NewRequest("POST", "/api/stats/summary", nil))
+		if rr.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("Expected 405, got %d", rr.Code)
+		}
+		if allow := rr.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS" {
+			t.Errorf("Unexpected Allow header %q", allow)
+		}
+		var response APIResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if !reflect.DeepEqual(response.AllowedMethods, []string{"GET", "HEAD", "OPTIONS"}) {
+			t.Errorf("Unexpected allowed_methods %v", response.AllowedMethods)
+		}
+	})
+
+	t.Run("HEAD served by GET", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		WithMethods("/api/stats/summary", next)(rr, httptest.NewRequest("HEAD", "/api/stats/summary", nil))
+		if rr.Code != http.StatusOK || gotMethod != "GET" {
+			t.Errorf("Expected HEAD to reach the handler as GET, got %d %s", rr.Code, gotMethod)
+		}
+	})
+
+	t.Run("HEAD served by route", func(t *testing.T) {
+		WithMethods("/api/logs/count", next)(httptest.NewRecorder(), httptest.NewRequest("HEAD", "/api/logs/count", nil))
+		if gotMethod != "HEAD" {
+			t.Errorf("Expected HEAD to reach the handler unchanged, got %s", gotMethod)
+		}
+	})
+
+	t.Run("catch-all", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+		WithMethods("/api/", makeAPINotFoundHandler(logger))(rr, httptest.NewRequest("DELETE", "/api/nope", nil))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404, got %d", rr.Code)
+		}
+	})
+}
+
+func TestRouteMethodsMatchRegisteredRoutes(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	registered := MakeAPIHandlers(db, logger)
+	for path := range routeMethods {
+		if _, ok := registered[path]; !ok && path != "/api/cloudflare/jobs/create" {
+			t.Errorf("routeMethods lists %s, which is not registered", path)
+		}
+	}
+}
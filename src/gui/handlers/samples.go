@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// Limits for the samples listing.
+const (
+	defaultSamplesLimit = 20
+	maxSamplesLimit     = 1000
+)
+
+// maxRedactionAuditHours bounds the redaction audit window.
+const maxRedactionAuditHours = 24 * 365
+
+// makeSamplesHandler serves GET /api/samples, listing the most recent payload
+// samples (newest first, up to `limit`, default 20). Samples are only taken
+// when sampling.every_n is set in the configuration, and sensitive fields are
+// redacted before they are stored. The current redaction rules are applied
+// again before samples are returned, so rules added later also cover
+// samples stored before them.
+func makeSamplesHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: samples", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		limit := defaultSamplesLimit
+		if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+			limit = min(l, maxSamplesLimit)
+		}
+		doc, err := config.Export(db)
+		if err != nil {
+			sendErrorResponse(w, "Failed to load redaction rules")
+			return
+		}
+		policy, err := doc.Sampling.Policy()
+		if err != nil {
+			logger.Error("Failed to compile redaction rules", "error", err)
+			sendErrorResponse(w, "Failed to load redaction rules")
+			return
+		}
+		samples, err := db.ListSamples(limit)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch samples")
+			return
+		}
+
+		for i, s := range samples {
+			// Truncated samples are no longer valid JSON, so only the
+			// pattern rules can be applied to them
+			if redacted, audit, err := policy.Redact([]byte(s.Content)); err == nil {
+				samples[i].Content = string(redacted)
+				samples[i].RedactedFields += audit.Total()
+			} else {
+				text, audit := policy.RedactText(s.Content)
+				samples[i].Content = text
+				samples[i].RedactedFields += audit.Total()
+			}
+		}
+		sendSuccessResponse(w, samples)
+	}
+}
+
+// makeRedactionAuditHandler serves GET /api/samples/redactions, totalling
+// what each redaction rule removed from samples stored in the last `hours`
+// hours (default 24). The audit is kept after the samples it describes are
+// trimmed.
+func makeRedactionAuditHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: redaction audit", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		hours := 24
+		if h, err := strconv.Atoi(r.URL.Query().Get("hours")); err == nil && h > 0 {
+			hours = min(h, maxRedactionAuditHours)
+		}
+		end := now().UTC()
+		totals, err := db.QueryRedactionAudit(end.Add(-time.Duration(hours)*time.Hour), end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch redaction audit")
+			return
+		}
+		sendSuccessResponse(w, totals)
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestSamplesHandler(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	for _, content := range []string{`{"n":1}`, `{"n":2}`} {
+		db.InsertSample(database.PayloadSample{Content: content, RecordCount: 1}, 10)
+	}
+
+	handler := MakeAPIHandlers(db, logger)["/api/samples"]
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/samples?limit=1", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Data []database.PayloadSample `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Data) != 1 || resp.Data[0].Content != `{"n":2}` {
+		t.Errorf("Expected only the newest sample, got %+v", resp.Data)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/samples", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rr.Code)
+	}
+}
+
+func TestSamplesHandlerAppliesCurrentRules(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	// Stored before the email rule existed, one of them truncated
+	db.InsertSample(database.PayloadSample{Content: `{"to":"a@example.com"}`}, 10)
+	db.InsertSample(database.PayloadSample{Content: `{"to":"b@example.com","pa`, Truncated: true}, 10)
+
+	doc := config.NewDocument()
+	doc.Sampling.RedactPatterns = []config.RedactPattern{{Name: "email", Regex: `[\w.]+@[\w.]+`}}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Failed to add redaction rule: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	MakeAPIHandlers(db, logger)["/api/samples"].ServeHTTP(rr, httptest.NewRequest("GET", "/api/samples", nil))
+	var resp struct {
+		Data []database.PayloadSample `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Data) != 2 {
+		t.Fatalf("Expected 2 samples, got %+v", resp.Data)
+	}
+	for _, s := range resp.Data {
+		if strings.Contains(s.Content, "@example.com") || s.RedactedFields != 1 {
+			t.Errorf("Expected the email to be redacted on return, got %+v", s)
+		}
+	}
+}
+
+func TestRedactionAuditHandler(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	db.InsertSample(database.PayloadSample{Content: `{}`, Redactions: map[string]int{"field:ClientIP": 2}}, 10)
+
+	rr := httptest.NewRecorder()
+	MakeAPIHandlers(db, logger)["/api/samples/redactions"].ServeHTTP(rr, httptest.NewRequest("GET", "/api/samples/redactions?hours=1", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Data []database.RedactionTotal `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Data) != 1 || resp.Data[0].Rule != "field:ClientIP" || resp.Data[0].Redactions != 2 {
+		t.Errorf("Unexpected audit %+v", resp.Data)
+	}
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/reports"
+)
+
+// Scenario comparison defaults: the last 30 days of usage, looked up to a
+// year back.
+const (
+	defaultScenarioDays = 30
+	maxScenarioDays     = 366
+)
+
+// scenarioSampleLimit is how many recent payload samples size the fields of
+// a scenario.
+const scenarioSampleLimit = 500
+
+// ScenarioComparison is the response body for /api/scenarios/compare.
+type ScenarioComparison struct {
+	Days      int                      `json:"days"`      // Days of observed usage projected
+	Start     time.Time                `json:"start"`     // Start of the observed window
+	End       time.Time                `json:"end"`       // End of the observed window
+	Scenarios []reports.ScenarioResult `json:"scenarios"` // One row per scenario; deltas are against the first
+}
+
+// validateScenario checks a scenario definition, defaulting the sample rate
+// to 1. It returns a client-facing message for the first invalid field.
+func validateScenario(s *database.Scenario) string {
+	if !viewNamePattern.MatchString(s.Name) || s.Name == "compare" {
+		return "name must be 1-64 letters, digits, '-' or '_', other than \"compare\""
+	}
+	if s.SampleRate == 0 {
+		s.SampleRate = 1
+	}
+	if s.SampleRate < 0 || s.SampleRate > 1 {
+		return "sample_rate must be greater than 0 and at most 1"
+	}
+	if s.RetentionDays < 0 {
+		return "retention_days cannot be negative"
+	}
+	for _, f := range s.Fields {
+		if f == "" {
+			return "fields cannot contain empty names"
+		}
+	}
+	return ""
+}
+
+// decodeScenario reads a scenario from the request body and validates it.
+// A non-empty name overrides any name in the body.
+func decodeScenario(r *http.Request, name string) (database.Scenario, string) {
+	var s database.Scenario
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		return s, "Invalid JSON body"
+	}
+	if name != "" {
+		s.Name = name
+	}
+	return s, validateScenario(&s)
+}
+
+// makeScenariosHandler serves /api/scenarios. GET lists saved scenarios and
+// POST creates or replaces the scenario named in the JSON body.
+func makeScenariosHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: scenarios", "method", r.Method, "remote_addr", r.RemoteAddr)
+
+		switch r.Method {
+		case http.MethodGet:
+			scenarios, err := db.ListScenarios()
+			if err != nil {
+				sendErrorResponse(w, "Failed to fetch scenarios")
+				return
+			}
+			sendSuccessResponse(w, scenarios)
+
+		case http.MethodPost:
+			scenario, msg := decodeScenario(r, "")
+			if msg != "" {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, msg)
+				return
+			}
+			saved, err := db.SaveScenario(scenario)
+			if err != nil {
+				sendErrorResponse(w, "Failed to save scenario")
+				return
+			}
+			sendSuccessResponse(w, saved)
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// makeScenarioHandler serves /api/scenarios/{name}: GET returns the
+// scenario, PUT replaces it with the JSON body and DELETE removes it.
+func makeScenarioHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/api/scenarios/")
+		logger.Info("API request: scenario", "method", r.Method, "name", name, "remote_addr", r.RemoteAddr)
+
+		if !viewNamePattern.MatchString(name) {
+			sendErrorResponseWithStatus(w, http.StatusNotFound, "Scenario not found")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			scenario, found, err := db.GetScenario(name)
+			if err != nil {
+				sendErrorResponse(w, "Failed to fetch scenario")
+				return
+			}
+			if !found {
+				sendErrorResponseWithStatus(w, http.StatusNotFound, "Scenario not found")
+				return
+			}
+			sendSuccessResponse(w, scenario)
+
+		case http.MethodPut:
+			scenario, msg := decodeScenario(r, name)
+			if msg != "" {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, msg)
+				return
+			}
+			saved, err := db.SaveScenario(scenario)
+			if err != nil {
+				sendErrorResponse(w, "Failed to save scenario")
+				return
+			}
+			sendSuccessResponse(w, saved)
+
+		case http.MethodDelete:
+			deleted, err := db.DeleteScenario(name)
+			if err != nil {
+				sendErrorResponse(w, "Failed to delete scenario")
+				return
+			}
+			if !deleted {
+				sendErrorResponseWithStatus(w, http.StatusNotFound, "Scenario not found")
+				return
+			}
+			sendSuccessResponse(w, map[string]string{"deleted": name})
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// makeScenarioCompareHandler serves /api/scenarios/compare, projecting the
+// usage of the last `days` days (default 30, or last=) to a month under
+// each scenario and pricing it with the scenario's destination. `names`
+// selects and orders scenarios as a comma-separated list; by default every
+// scenario is compared in name order. Deltas are relative to the first.
+func makeScenarioCompareHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: scenario comparison", "remote_addr", r.RemoteAddr)
+
+		days, err := windowParam(r, "days", 24*time.Hour, defaultScenarioDays)
+		if err != nil {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		days = min(days, maxScenarioDays)
+
+		var scenarios []database.Scenario
+		if names := r.URL.Query().Get("names"); names != "" {
+			for _, name := range strings.Split(names, ",") {
+				s, found, err := db.GetScenario(strings.TrimSpace(name))
+				if err != nil {
+					sendErrorResponse(w, "Failed to fetch scenarios")
+					return
+				}
+				if !found {
+					sendErrorResponseWithStatus(w, http.StatusBadRequest, "Unknown scenario: "+name)
+					return
+				}
+				scenarios = append(scenarios, s)
+			}
+		} else if scenarios, err = db.ListScenarios(); err != nil {
+			sendErrorResponse(w, "Failed to fetch scenarios")
+			return
+		}
+
+		doc, err := config.Export(db)
+		if err != nil {
+			sendErrorResponse(w, "Failed to load pricing models")
+			return
+		}
+		models := make([]config.PricingModel, len(scenarios))
+		for i, s := range scenarios {
+			if models[i], err = selectPricingModel(doc.PricingModels, s.Destination); err != nil {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "scenario "+s.Name+": "+err.Error())
+				return
+			}
+		}
+
+		end := now().UTC()
+		start := end.Add(-time.Duration(days) * 24 * time.Hour)
+		hourly, err := db.QueryTenantHourlyUsage(start, end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to query usage")
+			return
+		}
+		samples, err := db.ListSamples(scenarioSampleLimit)
+		if err != nil {
+			sendErrorResponse(w, "Failed to fetch payload samples")
+			return
+		}
+
+		comparison := ScenarioComparison{Days: days, Start: start, End: end, Scenarios: make([]reports.ScenarioResult, len(scenarios))}
+		for i, s := range scenarios {
+			result := reports.ProjectScenario(s, models[i], hourly, float64(days), samples)
+			if i > 0 {
+				result.Delta = math.Round((result.MonthlyCost-comparison.Scenarios[0].MonthlyCost)*100) / 100
+			}
+			comparison.Scenarios[i] = result
+		}
+		sendSuccessResponse(w, comparison)
+	}
+}
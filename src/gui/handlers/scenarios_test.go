@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestAPIScenarios(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	apiHandlers := MakeAPIHandlers(db, logger)
+	list, single, compare := apiHandlers["/api/scenarios"], apiHandlers["/api/scenarios/"], apiHandlers["/api/scenarios/compare"]
+
+	for _, body := range []string{`{"name":"current"}`, `{"name":"sampled","sample_rate":0.1,"retention_days":30}`} {
+		rr := httptest.NewRecorder()
+		list.ServeHTTP(rr, httptest.NewRequest("POST", "/api/scenarios", strings.NewReader(body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200 from POST, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	// Without a pricing model there is nothing to price scenarios with
+	rr := httptest.NewRecorder()
+	compare.ServeHTTP(rr, httptest.NewRequest("GET", "/api/scenarios/compare", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 without pricing models, got %d", rr.Code)
+	}
+	doc := config.NewDocument()
+	doc.PricingModels = []config.PricingModel{{Name: "r2", Currency: "USD", PerGB: 1e6}}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Failed to add pricing model: %v", err)
+	}
+
+	rr = httptest.NewRecorder()
+	compare.ServeHTTP(rr, httptest.NewRequest("GET", "/api/scenarios/compare?names=sampled,current", nil))
+	var resp struct {
+		Data ScenarioComparison `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	rows := resp.Data.Scenarios
+	if resp.Data.Days != 30 || len(rows) != 2 || rows[0].Scenario != "sampled" || rows[1].Scenario != "current" {
+		t.Fatalf("Expected sampled and current in the requested order, got %+v", resp.Data)
+	}
+	if rows[0].MonthlyCost <= 0 || rows[1].Delta <= 0 || rows[0].RetainedBytes == 0 {
+		t.Errorf("Expected current to cost more than sampled, got %+v", rows)
+	}
+
+	rr = httptest.NewRecorder()
+	compare.ServeHTTP(rr, httptest.NewRequest("GET", "/api/scenarios/compare?names=missing", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown scenario, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	single.ServeHTTP(rr, httptest.NewRequest("PUT", "/api/scenarios/current", strings.NewReader(`{"fields":["ClientIP"]}`)))
+	var scenarioResponse struct {
+		Data database.Scenario `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &scenarioResponse); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if scenarioResponse.Data.Name != "current" || scenarioResponse.Data.SampleRate != 1 || len(scenarioResponse.Data.Fields) != 1 {
+		t.Errorf("Expected replaced scenario with default sample rate, got %+v", scenarioResponse.Data)
+	}
+
+	rr = httptest.NewRecorder()
+	single.ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/scenarios/current", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from DELETE, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	single.ServeHTTP(rr, httptest.NewRequest("GET", "/api/scenarios/current", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", rr.Code)
+	}
+}
+
+func TestAPIScenariosValidation(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/scenarios"]
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Invalid JSON", "{"},
+		{"Missing name", `{"sample_rate":0.5}`},
+		{"Reserved name", `{"name":"compare"}`},
+		{"Sample rate above 1", `{"name":"a","sample_rate":1.5}`},
+		{"Negative retention", `{"name":"a","retention_days":-1}`},
+		{"Empty field", `{"name":"a","fields":[""]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/scenarios", strings.NewReader(tt.body)))
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d: %s", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// ShardReport is the response body for /api/admin/shards.
+type ShardReport struct {
+	Enabled     bool             `json:"enabled"`      // Whether log records are sharded by month (LPE_DB_SHARDING=monthly)
+	MaxAttached int              `json:"max_attached"` // Most shards whose records are read
+	Shards      []database.Shard `json:"shards"`       // Shard files, oldest first
+}
+
+// makeShardsHandler serves GET /api/admin/shards, listing the monthly shard
+// files next to the database, so operators can see which closed months can
+// be backed up once or moved to cold storage, and which are read.
+func makeShardsHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: shards", "remote_addr", r.RemoteAddr)
+
+		shards, err := db.Shards()
+		if err != nil {
+			sendErrorResponse(w, "Failed to list shards")
+			return
+		}
+		sendSuccessResponse(w, ShardReport{Enabled: db.ShardedByMonth(), MaxAttached: database.MaxAttachedShards, Shards: shards})
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/testsupport"
+)
+
+func TestAPIShards(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	rr := httptest.NewRecorder()
+	MakeAPIHandlers(db, logger)["/api/admin/shards"].ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/shards", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Data ShardReport `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Data.Enabled || resp.Data.Shards == nil || len(resp.Data.Shards) != 0 || resp.Data.MaxAttached != 10 {
+		t.Errorf("Expected sharding reported as disabled, got %+v", resp.Data)
+	}
+}
+
+func TestAPIDetachedShardsRefused(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(filepath.Join(t.TempDir(), "logpush.db"), logger)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	at := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	SetClock(testsupport.NewClock(at))
+	defer SetClock(nil)
+	db.SetClock(testsupport.NewClock(at))
+	for month := 1; month <= 12; month++ {
+		if err := db.InsertLog(database.LogSize{Timestamp: time.Date(2024, time.Month(month), 15, 0, 0, 0, 0, time.UTC), Filesize: 100}); err != nil {
+			t.Fatalf("Failed to insert log: %v", err)
+		}
+	}
+	if err := db.ShardByMonth(); err != nil {
+		t.Fatalf("ShardByMonth failed: %v", err)
+	}
+
+	handler := MakeAPIHandlers(db, logger)["/api/stats/summary"]
+	for target, want := range map[string]int{
+		"/api/stats/summary": http.StatusBadRequest,
+		"/api/stats/summary?start=2024-02-01T00:00:00Z&end=2025-01-01T00:00:00Z": http.StatusBadRequest,
+		"/api/stats/summary?start=2024-03-01T00:00:00Z&end=2025-01-01T00:00:00Z": http.StatusOK,
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != want {
+			t.Errorf("%s: expected %d, got %d: %s", target, want, rr.Code, rr.Body.String())
+		}
+	}
+}
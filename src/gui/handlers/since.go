@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// Page sizes for /api/logs/since when no limit is given, and the most a
+// single call may return.
+const (
+	defaultSinceLimit = 1000
+	maxSinceLimit     = 10000
+)
+
+// LogsSincePage is the response body for /api/logs/since.
+type LogsSincePage struct {
+	Records []database.LogSize `json:"records"`  // Records with an ID greater than the cursor, oldest first
+	NextID  int64              `json:"next_id"`  // Cursor for the next call: the last returned ID, or the request cursor if none
+	HasMore bool               `json:"has_more"` // Whether more records are already available past NextID
+}
+
+// makeLogsSinceHandler serves /api/logs/since?id=N&limit=M, returning records
+// with an ID greater than N in ID order. Consumers tail new records by
+// passing back next_id; unlike timestamp cursors this never skips or repeats
+// records that share a timestamp.
+func makeLogsSinceHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: logs since", "remote_addr", r.RemoteAddr)
+
+		var id int64
+		if idStr := r.URL.Query().Get("id"); idStr != "" {
+			parsed, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil || parsed < 0 {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "id must be a non-negative integer")
+				return
+			}
+			id = parsed
+		}
+
+		limit := defaultSinceLimit
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			l, err := strconv.Atoi(limitStr)
+			if err != nil || l <= 0 {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "limit must be a positive integer")
+				return
+			}
+			limit = min(l, maxSinceLimit)
+		}
+
+		// Fetch one extra record to learn whether another page is waiting
+		logs, err := db.QuerySince(id, limit+1)
+		if err != nil {
+			logger.Error("Failed to query logs since cursor", "error", err, "id", id)
+			sendErrorResponse(w, "Failed to fetch logs")
+			return
+		}
+
+		page := LogsSincePage{Records: []database.LogSize{}, NextID: id}
+		if len(logs) > limit {
+			logs, page.HasMore = logs[:limit], true
+		}
+		if len(logs) > 0 {
+			page.Records = logs
+			page.NextID = logs[len(logs)-1].ID
+		}
+		sendSuccessResponse(w, page)
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// decodeSincePage unwraps a LogsSincePage from an API response.
+func decodeSincePage(t *testing.T, rr *httptest.ResponseRecorder) LogsSincePage {
+	t.Helper()
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Success bool          `json:"success"`
+		Data    LogsSincePage `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.Data
+}
+
+func TestAPILogsSincePaginates(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/logs/since"]
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/logs/since?limit=3", nil))
+	page := decodeSincePage(t, rr)
+	if len(page.Records) != 3 || !page.HasMore {
+		t.Fatalf("Expected 3 records with more available, got %d (has_more=%v)", len(page.Records), page.HasMore)
+	}
+	if page.NextID != page.Records[2].ID {
+		t.Errorf("Expected next_id %d, got %d", page.Records[2].ID, page.NextID)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/logs/since?limit=3&id="+strconv.FormatInt(page.NextID, 10), nil))
+	rest := decodeSincePage(t, rr)
+	if len(rest.Records) != 2 || rest.HasMore {
+		t.Fatalf("Expected final 2 records, got %d (has_more=%v)", len(rest.Records), rest.HasMore)
+	}
+	if rest.Records[1].Filesize != 16384 {
+		t.Errorf("Expected last record of 16384 bytes, got %d", rest.Records[1].Filesize)
+	}
+
+	// Caught up: the cursor is echoed back with no records
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/logs/since?id="+strconv.FormatInt(rest.NextID, 10), nil))
+	done := decodeSincePage(t, rr)
+	if len(done.Records) != 0 || done.NextID != rest.NextID || done.HasMore {
+		t.Errorf("Expected empty page at cursor %d, got %+v", rest.NextID, done)
+	}
+}
+
+func TestAPILogsSinceRejectsBadParameters(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/logs/since"]
+
+	for _, query := range []string{"id=abc", "id=-1", "limit=0", "limit=x"} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/logs/since?"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %q, got %d", query, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// defaultIngestSLOTarget is the availability objective (in percent) used when
+// no target parameter is supplied.
+const defaultIngestSLOTarget = 99.9
+
+// sloWindows are the rolling windows reported by /api/slo/ingest.
+var sloWindows = []struct {
+	Name string
+	Days int
+}{
+	{"7d", 7},
+	{"30d", 30},
+}
+
+// SLOWindow reports ingest availability over a single rolling window.
+type SLOWindow struct {
+	Window               string  `json:"window"`                 // Window label (e.g. "7d")
+	Successes            int64   `json:"successes"`              // Successfully handled ingest requests
+	Failures             int64   `json:"failures"`               // Ingest requests that failed server-side
+	Availability         float64 `json:"availability"`           // Percentage of successful requests
+	ErrorBudgetRemaining float64 `json:"error_budget_remaining"` // Percentage of the error budget left (may be negative)
+}
+
+// SLOReport is the response body for /api/slo/ingest.
+type SLOReport struct {
+	Target  float64     `json:"target"`  // Availability objective in percent
+	Windows []SLOWindow `json:"windows"` // One entry per rolling window
+}
+
+// makeIngestSLOHandler serves /api/slo/ingest, reporting availability and the
+// remaining error budget for the ingest endpoint over 7 and 30 days. Gaps in
+// ingest availability become gaps in the collected data, so this is a direct
+// measure of how trustworthy the estimates are.
+func makeIngestSLOHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: ingest SLO", "remote_addr", r.RemoteAddr)
+
+		target := defaultIngestSLOTarget
+		if t, err := strconv.ParseFloat(r.URL.Query().Get("target"), 64); err == nil && t > 0 && t < 100 {
+			target = t
+		}
+
+		report := SLOReport{Target: target}
+		current := now().UTC()
+		for _, window := range sloWindows {
+			outcomes, err := db.IngestOutcomesSince(current.AddDate(0, 0, -window.Days))
+			if err != nil {
+				logger.Error("Failed to query ingest outcomes", "error", err, "window", window.Name)
+				sendErrorResponse(w, "Failed to fetch SLO data")
+				return
+			}
+			report.Windows = append(report.Windows, calculateSLOWindow(window.Name, outcomes, target))
+		}
+
+		sendSuccessResponse(w, report)
+	}
+}
+
+// calculateSLOWindow derives availability and error budget figures from raw
+// outcome counts. A window with no requests is reported as fully available
+// with its entire budget remaining.
+func calculateSLOWindow(name string, outcomes database.IngestOutcomes, target float64) SLOWindow {
+	window := SLOWindow{
+		Window:               name,
+		Successes:            outcomes.Successes,
+		Failures:             outcomes.Failures,
+		Availability:         100,
+		ErrorBudgetRemaining: 100,
+	}
+
+	total := outcomes.Successes + outcomes.Failures
+	if total == 0 {
+		return window
+	}
+
+	window.Availability = float64(outcomes.Successes) / float64(total) * 100
+	allowedFailures := (100 - target) / 100 * float64(total)
+	window.ErrorBudgetRemaining = (1 - float64(outcomes.Failures)/allowedFailures) * 100
+	return window
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestCalculateSLOWindow(t *testing.T) {
+	tests := []struct {
+		name              string
+		outcomes          database.IngestOutcomes
+		expectedAvail     float64
+		expectedRemaining float64
+	}{
+		{"No traffic", database.IngestOutcomes{}, 100, 100},
+		{"No failures", database.IngestOutcomes{Successes: 1000}, 100, 100},
+		{"Half budget used", database.IngestOutcomes{Successes: 1999, Failures: 1}, 99.95, 50},
+		{"Budget exhausted", database.IngestOutcomes{Successes: 990, Failures: 10}, 99, -900},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window := calculateSLOWindow("7d", tt.outcomes, 99.9)
+			if diff := window.Availability - tt.expectedAvail; diff > 0.001 || diff < -0.001 {
+				t.Errorf("Expected availability %f, got %f", tt.expectedAvail, window.Availability)
+			}
+			if diff := window.ErrorBudgetRemaining - tt.expectedRemaining; diff > 0.001 || diff < -0.001 {
+				t.Errorf("Expected error budget remaining %f, got %f", tt.expectedRemaining, window.ErrorBudgetRemaining)
+			}
+		})
+	}
+}
+
+func TestAPIIngestSLO(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	db.RecordIngestOutcome(true)
+	db.RecordIngestOutcome(false)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+
+	req, err := http.NewRequest("GET", "/api/slo/ingest?target=99", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handlers["/api/slo/ingest"].ServeHTTP(rr, req)
+
+	var response struct {
+		Success bool      `json:"success"`
+		Data    SLOReport `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if !response.Success {
+		t.Fatalf("Expected success=true")
+	}
+	if response.Data.Target != 99 {
+		t.Errorf("Expected target 99, got %f", response.Data.Target)
+	}
+	if len(response.Data.Windows) != 2 {
+		t.Fatalf("Expected 2 windows, got %d", len(response.Data.Windows))
+	}
+	if response.Data.Windows[0].Availability != 50 {
+		t.Errorf("Expected 50%% availability, got %f", response.Data.Windows[0].Availability)
+	}
+}
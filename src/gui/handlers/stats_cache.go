@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults for the dashboard statistics cache: responses are recomputed after
+// 15 seconds, and a response up to 10 minutes old is served while a fresh
+// one is computed in the background.
+const (
+	DefaultStatsCacheTTL      = 15 * time.Second
+	DefaultStatsCacheMaxStale = 10 * time.Minute
+)
+
+// statsCacheMaxEntries bounds how many distinct responses are kept.
+const statsCacheMaxEntries = 64
+
+// statsCachePaths lists the endpoints whose responses StatsCache holds. Only
+// requests without parameters or with just hours= or last= are cached, which covers
+// what the dashboard requests on load.
+var statsCachePaths = map[string]bool{
+	"/api/stats/summary":     true,
+	"/api/charts/timeseries": true,
+	"/api/charts/breakdown":  true,
+}
+
+// warmupTargets are the requests the dashboard makes on its first load with
+// the default 24 hour range. Warm computes them in this order.
+var warmupTargets = []string{
+	"/api/stats/summary",
+	"/api/charts/timeseries?hours=24",
+	"/api/charts/breakdown",
+}
+
+// cacheInvalidatingEndpoints lists routes that delete or restore records.
+var cacheInvalidatingEndpoints = []string{
+	"/api/admin/delete-range",
+	"/api/admin/prune",
+	"/api/admin/purge-dataset",
+	"/api/admin/trash/restore",
+}
+
+// InvalidatesCache returns a handler that runs h and then discards the
+// cache if h may have changed stored records. MakeAPIHandlersWithCache
+// wraps its routes that delete or restore records; handlers replacing one
+// of them need wrapping again.
+//
+// Parameters:
+//   - cache: Cache to discard
+//   - h: Handler that may change stored records
+//
+// Returns:
+//   - http.HandlerFunc: Wrapped handler
+func InvalidatesCache(cache *StatsCache, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h(w, r)
+		if r.Method != http.MethodGet {
+			cache.Invalidate()
+		}
+	}
+}
+
+// Warm-up states reported by WarmupProgress.
+const (
+	WarmupPending = "pending"
+	WarmupRunning = "running"
+	WarmupDone    = "done"
+)
+
+// WarmupProgress reports how far cache warming has got.
+type WarmupProgress struct {
+	State      string     `json:"state"`                 // pending, running or done
+	Completed  int        `json:"completed"`             // Responses computed so far
+	Failed     int        `json:"failed"`                // Responses that could not be computed
+	Total      int        `json:"total"`                 // Responses to compute
+	StartedAt  *time.Time `json:"started_at,omitempty"`  // When warming started
+	FinishedAt *time.Time `json:"finished_at,omitempty"` // When warming finished
+}
+
+// statsCacheEntry is a computed response and when it was computed.
+type statsCacheEntry struct {
+	value      any
+	computedAt time.Time
+	refreshing bool // Whether a background recomputation is running
+}
+
+// StatsCache holds the summary, time series and breakdown responses the
+// dashboard loads first, so they need not be recomputed from a full scan of
+// the history on every page load. Responses younger than the TTL are served
+// as is; older ones, up to the maximum staleness, are served while a fresh
+// copy is computed in the background, so callers only wait on the database
+// when nothing usable is cached. Warm fills the cache at startup.
+//
+// A nil *StatsCache caches nothing.
+type StatsCache struct {
+	ttl      time.Duration
+	maxStale time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]*statsCacheEntry
+	progress WarmupProgress
+}
+
+// NewStatsCache creates an empty statistics cache.
+//
+// Parameters:
+//   - ttl: Age after which a response is recomputed
+//   - maxStale: Age up to which a response is still served while it is
+//     recomputed; values below ttl disable serving stale responses
+//
+// Returns:
+//   - *StatsCache: Cache to pass to MakeAPIHandlersWithCache
+func NewStatsCache(ttl, maxStale time.Duration) *StatsCache {
+	return &StatsCache{
+		ttl:      ttl,
+		maxStale: max(ttl, maxStale),
+		now:      time.Now,
+		entries:  make(map[string]*statsCacheEntry),
+		progress: WarmupProgress{State: WarmupPending, Total: len(warmupTargets)},
+	}
+}
+
+// cacheKey returns the key r is cached under, or false if r's response is
+// not cached.
+func cacheKey(r *http.Request) (string, bool) {
+	if !statsCachePaths[r.URL.Path] {
+		return "", false
+	}
+	q := r.URL.Query()
+	for name := range q {
+		if name != "hours" && name != "last" {
+			return "", false
+		}
+	}
+	return r.URL.Path + "?" + q.Encode(), true
+}
+
+// get returns the response for r, calling compute when nothing usable is
+// cached. Errors are returned to the caller and not cached.
+func (c *StatsCache) get(r *http.Request, compute func() (any, error)) (any, error) {
+	key, ok := cacheKey(r)
+	if c == nil || !ok {
+		return compute()
+	}
+
+	c.mu.Lock()
+	entry, found := c.entries[key]
+	if found {
+		age := c.now().Sub(entry.computedAt)
+		if age < c.ttl {
+			c.mu.Unlock()
+			return entry.value, nil
+		}
+		if age < c.maxStale {
+			if !entry.refreshing {
+				entry.refreshing = true
+				go c.refresh(key, compute)
+			}
+			value := entry.value
+			c.mu.Unlock()
+			return value, nil
+		}
+	}
+	c.mu.Unlock()
+
+	value, err := compute()
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, value)
+	return value, nil
+}
+
+// refresh recomputes a stale entry in the background.
+func (c *StatsCache) refresh(key string, compute func() (any, error)) {
+	value, err := compute()
+	if err != nil {
+		c.mu.Lock()
+		if entry, ok := c.entries[key]; ok {
+			entry.refreshing = false
+		}
+		c.mu.Unlock()
+		return
+	}
+	c.store(key, value)
+}
+
+// store records a computed response, dropping expired entries to make room
+// when the cache is full.
+func (c *StatsCache) store(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= statsCacheMaxEntries {
+		for k, e := range c.entries {
+			if now.Sub(e.computedAt) >= c.maxStale {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= statsCacheMaxEntries {
+			return
+		}
+	}
+	c.entries[key] = &statsCacheEntry{value: value, computedAt: now}
+}
+
+// Invalidate discards every cached response, e.g. after records are deleted.
+func (c *StatsCache) Invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// Progress returns the state of cache warming for health reporting.
+func (c *StatsCache) Progress() WarmupProgress {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.progress
+}
+
+// discardResponseWriter accepts and drops a response produced while warming.
+type discardResponseWriter struct{ header http.Header }
+
+func (d *discardResponseWriter) Header() http.Header         { return d.header }
+func (d *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (d *discardResponseWriter) WriteHeader(int)             {}
+
+// Warm computes the responses the dashboard requests on its first load by
+// calling their handlers, which fills the cache (and primes the database's
+// recent record buffer along the way). It blocks until done; callers
+// usually run it in its own goroutine and report Progress meanwhile.
+//
+// Parameters:
+//   - handlers: API handlers from MakeAPIHandlersWithCache built with c
+//   - logger: Structured logger for progress logging
+func (c *StatsCache) Warm(handlers map[string]http.HandlerFunc, logger *slog.Logger) {
+	started := c.now()
+	c.mu.Lock()
+	c.progress.State = WarmupRunning
+	c.progress.StartedAt = &started
+	c.mu.Unlock()
+	logger.Info("Warming dashboard statistics cache", "requests", len(warmupTargets))
+
+	for _, target := range warmupTargets {
+		req, err := http.NewRequest(http.MethodGet, target, nil)
+		if err != nil {
+			panic(err) // warmupTargets are constant
+		}
+		handler, ok := handlers[req.URL.Path]
+		if ok {
+			req.RemoteAddr = "cache-warmup"
+			handler(&discardResponseWriter{header: make(http.Header)}, req)
+			_, ok = c.lookup(req)
+		}
+
+		c.mu.Lock()
+		if ok {
+			c.progress.Completed++
+		} else {
+			c.progress.Failed++
+		}
+		c.mu.Unlock()
+		if !ok {
+			logger.Warn("Failed to warm cached response", "request", target)
+		}
+	}
+
+	finished := c.now()
+	c.mu.Lock()
+	c.progress.State = WarmupDone
+	c.progress.FinishedAt = &finished
+	progress := c.progress
+	c.mu.Unlock()
+	logger.Info("Dashboard statistics cache warmed",
+		"completed", progress.Completed, "failed", progress.Failed, "duration", finished.Sub(started))
+}
+
+// lookup reports whether a response for r is cached, regardless of its age.
+func (c *StatsCache) lookup(r *http.Request) (any, bool) {
+	key, ok := cacheKey(r)
+	if !ok {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, found := c.entries[key]
+	if !found {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// CachedResponse is one StatsCache entry in a saved StatsCacheState.
+type CachedResponse struct {
+	Key        string          `json:"key"`         // Request path and query the response is cached under
+	Value      json.RawMessage `json:"value"`       // Response data as JSON
+	ComputedAt time.Time       `json:"computed_at"` // When the response was computed
+}
+
+// StatsCacheState is the content of a StatsCache, saved so a restarted
+// process can serve the dashboard's first load from the previous
+// process's responses while it recomputes them.
+type StatsCacheState struct {
+	Entries []CachedResponse `json:"entries"`
+}
+
+// State returns the cached responses for saving.
+//
+// Returns:
+//   - StatsCacheState: Every cached response, encoded as JSON
+//   - error: If a response cannot be encoded
+func (c *StatsCache) State() (StatsCacheState, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := StatsCacheState{Entries: make([]CachedResponse, 0, len(c.entries))}
+	for key, entry := range c.entries {
+		value, err := json.Marshal(entry.value)
+		if err != nil {
+			return StatsCacheState{}, err
+		}
+		state.Entries = append(state.Entries, CachedResponse{Key: key, Value: value, ComputedAt: entry.computedAt})
+	}
+	return state, nil
+}
+
+// Restore adds the responses in a saved state to the cache, keeping their
+// original computation time, so they are refreshed in the background on
+// first use like any other stale response. Responses older than the
+// maximum staleness would never be served and are skipped.
+//
+// Parameters:
+//   - state: State from State, typically saved by a previous process
+//
+// Returns:
+//   - int: Responses restored
+func (c *StatsCache) Restore(state StatsCacheState) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now, restored := c.now(), 0
+	for _, e := range state.Entries {
+		if now.Sub(e.ComputedAt) >= c.maxStale || len(c.entries) >= statsCacheMaxEntries {
+			continue
+		}
+		// Cached values are only ever encoded into responses, so the
+		// raw JSON serves the same bytes as the original value
+		c.entries[e.Key] = &statsCacheEntry{value: e.Value, computedAt: e.ComputedAt}
+		restored++
+	}
+	return restored
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStatsCacheServesFreshAndStale(t *testing.T) {
+	cache := NewStatsCache(time.Minute, time.Hour)
+	now := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	cache.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+
+	calls := make(chan struct{}, 10)
+	value := 1
+	compute := func() (any, error) {
+		calls <- struct{}{}
+		return value, nil
+	}
+	req := httptest.NewRequest("GET", "/api/stats/summary", nil)
+
+	if v, _ := cache.get(req, compute); v != 1 {
+		t.Fatalf("Expected computed value 1, got %v", v)
+	}
+	<-calls
+
+	// Fresh: served without recomputing
+	value = 2
+	if v, _ := cache.get(req, compute); v != 1 {
+		t.Errorf("Expected cached value 1, got %v", v)
+	}
+	if len(calls) != 0 {
+		t.Error("Expected no recomputation while fresh")
+	}
+
+	// Stale: served while refreshed in the background
+	advance(2 * time.Minute)
+	if v, _ := cache.get(req, compute); v != 1 {
+		t.Errorf("Expected stale value 1, got %v", v)
+	}
+	<-calls
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if v, _ := cache.lookup(req); v == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the background refresh to store 2")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Too old: recomputed synchronously
+	advance(2 * time.Hour)
+	value = 3
+	if v, _ := cache.get(req, compute); v != 3 {
+		t.Errorf("Expected recomputed value 3, got %v", v)
+	}
+
+	// Invalidated: recomputed
+	cache.Invalidate()
+	value = 4
+	if v, _ := cache.get(req, compute); v != 4 {
+		t.Errorf("Expected value 4 after invalidation, got %v", v)
+	}
+}
+
+func TestStatsCacheKeys(t *testing.T) {
+	tests := []struct {
+		target    string
+		cacheable bool
+	}{
+		{"/api/stats/summary", true},
+		{"/api/stats/summary?hours=6", true},
+		{"/api/charts/timeseries?hours=24", true},
+		{"/api/charts/breakdown?start=2025-09-15T00:00:00Z&end=2025-09-16T00:00:00Z", false},
+		{"/api/logs/recent", false},
+	}
+	for _, tt := range tests {
+		_, ok := cacheKey(httptest.NewRequest("GET", tt.target, nil))
+		if ok != tt.cacheable {
+			t.Errorf("cacheKey(%s) cacheable = %v, want %v", tt.target, ok, tt.cacheable)
+		}
+	}
+
+	// A nil cache computes every time
+	var cache *StatsCache
+	calls := 0
+	for range 2 {
+		cache.get(httptest.NewRequest("GET", "/api/stats/summary", nil), func() (any, error) {
+			calls++
+			return nil, nil
+		})
+	}
+	if calls != 2 {
+		t.Errorf("Expected a nil cache to compute every time, computed %d times", calls)
+	}
+}
+
+func TestStatsCacheStateRoundTrip(t *testing.T) {
+	start := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	previous := NewStatsCache(time.Minute, time.Hour)
+	previous.now = func() time.Time { return start }
+	summary := httptest.NewRequest("GET", "/api/stats/summary", nil)
+	breakdown := httptest.NewRequest("GET", "/api/charts/breakdown", nil)
+	previous.get(summary, func() (any, error) { return map[string]int{"total": 3}, nil })
+	previous.now = func() time.Time { return start.Add(2 * time.Hour) }
+	previous.get(breakdown, func() (any, error) { return []int{1, 2}, nil })
+
+	state, err := previous.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restored 30 minutes later: the breakdown is stale but usable, the
+	// summary past the maximum staleness is dropped
+	cache := NewStatsCache(time.Minute, time.Hour)
+	cache.now = func() time.Time { return start.Add(150 * time.Minute) }
+	if n := cache.Restore(state); n != 1 {
+		t.Fatalf("Expected 1 response restored, got %d", n)
+	}
+	if _, ok := cache.lookup(summary); ok {
+		t.Error("Expected the expired summary not to be restored")
+	}
+	refreshed := make(chan struct{})
+	value, err := cache.get(breakdown, func() (any, error) {
+		close(refreshed)
+		return []int{3}, nil
+	})
+	if got, _ := json.Marshal(value); err != nil || string(got) != "[1,2]" {
+		t.Errorf("Expected the restored breakdown to be served, got %s, %v", got, err)
+	}
+	<-refreshed
+}
+
+func TestStatsCacheWarm(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	cache := NewStatsCache(time.Hour, time.Hour)
+	handlers := MakeAPIHandlersWithCache(db, logger, cache)
+
+	if p := cache.Progress(); p.State != WarmupPending || p.Total != len(warmupTargets) {
+		t.Errorf("Expected pending progress before warming, got %+v", p)
+	}
+	cache.Warm(handlers, logger)
+	p := cache.Progress()
+	if p.State != WarmupDone || p.Completed != len(warmupTargets) || p.Failed != 0 || p.FinishedAt == nil {
+		t.Errorf("Expected completed warm-up, got %+v", p)
+	}
+
+	// Records inserted after warming are not seen until the cache expires
+	if err := db.InsertLogSize(100); err != nil {
+		t.Fatalf("Failed to insert log: %v", err)
+	}
+	summary := func() LogSizeStats {
+		rr := httptest.NewRecorder()
+		handlers["/api/stats/summary"](rr, httptest.NewRequest("GET", "/api/stats/summary", nil))
+		var response struct {
+			Data LogSizeStats `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return response.Data
+	}
+	if stats := summary(); stats.TotalRecords != 5 {
+		t.Errorf("Expected the warmed summary of 5 records, got %d", stats.TotalRecords)
+	}
+
+	// Deleting records clears the cache
+	rr := httptest.NewRecorder()
+	handlers["/api/admin/delete-range"](rr, httptest.NewRequest("POST", "/api/admin/delete-range?start=2000-01-01T00:00:00Z&end=2000-01-02T00:00:00Z&dry_run=true", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from dry run, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if stats := summary(); stats.TotalRecords != 6 {
+		t.Errorf("Expected a recomputed summary of 6 records, got %d", stats.TotalRecords)
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"log/slog"
+	"maps"
+	"net/http"
+	"runtime"
+	"sync"
+
+	"github.com/melatonein5/LogpushEstimator/src/features"
+)
+
+// VersionInfo is the response body for /api/version.
+type VersionInfo struct {
+	Version   string            `json:"version"`             // Application version (set at build time)
+	GoVersion string            `json:"go_version"`          // Go runtime version
+	Features  []features.Flag   `json:"features"`            // Feature flags and their current state
+	Listeners map[string]string `json:"listeners,omitempty"` // Addresses the servers listen on, by server name
+}
+
+// Listeners records the addresses the servers actually listen on, which
+// differ from the configured ports after falling back to ephemeral ones. It
+// is safe for concurrent use.
+type Listeners struct {
+	mu    sync.RWMutex
+	addrs map[string]string
+}
+
+// NewListeners creates an empty address record.
+func NewListeners() *Listeners {
+	return &Listeners{addrs: make(map[string]string)}
+}
+
+// Set records the address the named server listens on.
+func (l *Listeners) Set(name, addr string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.addrs[name] = addr
+}
+
+// All returns a copy of the recorded addresses, or nil when none are.
+func (l *Listeners) All() map[string]string {
+	if l == nil {
+		return nil
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if len(l.addrs) == 0 {
+		return nil
+	}
+	return maps.Clone(l.addrs)
+}
+
+// MakeVersionHandler creates the /api/version handler reporting the build
+// version, the state of every feature flag and the addresses the servers
+// listen on, so operators can confirm which experimental capabilities a
+// running instance has enabled and test harnesses can find its ports.
+//
+// Parameters:
+//   - version: Application version string
+//   - flags: Feature flag registry to report
+//   - listeners: Listening addresses to report, or nil
+//   - logger: Structured logger for request logging
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function
+func MakeVersionHandler(version string, flags *features.Registry, listeners *Listeners, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: version", "remote_addr", r.RemoteAddr)
+		sendSuccessResponse(w, VersionInfo{
+			Version:   version,
+			GoVersion: runtime.Version(),
+			Features:  flags.List(),
+			Listeners: listeners.All(),
+		})
+	}
+}
+
+// WithFeatureGate wraps next so that it only runs while the feature flag gating
+// path is enabled. Paths without a flag are returned unwrapped. Disabled
+// features respond with 404 and the standard error envelope.
+//
+// Parameters:
+//   - flags: Feature flag registry to consult on every request
+//   - path: Route path the handler is mounted at
+//   - next: Handler to protect
+//
+// Returns:
+//   - http.HandlerFunc: Gated handler
+func WithFeatureGate(flags *features.Registry, path string, next http.HandlerFunc) http.HandlerFunc {
+	name, gated := flags.FlagForPath(path)
+	if !gated {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !flags.Enabled(name) {
+			sendErrorResponseWithStatus(w, http.StatusNotFound, "Feature '"+name+"' is not enabled")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// readOnlySafeRoutes accept a state-changing method on a read-only
+// instance because they only read: verifying an export compares the
+// uploaded file with the stored records.
+var readOnlySafeRoutes = map[string]bool{
+	"/api/export/verify": true,
+}
+
+// WithReadOnly wraps the API handler registered at path for a read-only
+// instance, which serves dashboards from a copy of another instance's
+// database: requests that would change state (POST, PUT, PATCH, DELETE)
+// are rejected with 403 and the standard error envelope, while GET, HEAD
+// and OPTIONS reach next unchanged.
+//
+// Parameters:
+//   - path: Route path the handler is mounted at
+//   - next: Handler to protect
+//
+// Returns:
+//   - http.HandlerFunc: Read-only handler
+func WithReadOnly(path string, next http.HandlerFunc) http.HandlerFunc {
+	if readOnlySafeRoutes[path] {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !csrfSafeMethods[r.Method] {
+			sendErrorResponseWithStatus(w, http.StatusForbidden, "This instance is read-only")
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/features"
+)
+
+func TestMakeVersionHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	flags := features.NewRegistry(features.Flag{Name: "alpha", Enabled: true})
+
+	req, err := http.NewRequest("GET", "/api/version", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	listeners := NewListeners()
+	listeners.Set("gui", "127.0.0.1:53124")
+	MakeVersionHandler("1.2.3", flags, listeners, logger).ServeHTTP(rr, req)
+
+	var response struct {
+		Success bool        `json:"success"`
+		Data    VersionInfo `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if response.Data.Version != "1.2.3" {
+		t.Errorf("Expected version 1.2.3, got %s", response.Data.Version)
+	}
+	if len(response.Data.Features) != 1 || !response.Data.Features[0].Enabled {
+		t.Errorf("Expected enabled alpha flag, got %+v", response.Data.Features)
+	}
+	if response.Data.Listeners["gui"] != "127.0.0.1:53124" {
+		t.Errorf("Expected the GUI listener address, got %v", response.Data.Listeners)
+	}
+
+	rr = httptest.NewRecorder()
+	MakeVersionHandler("1.2.3", flags, nil, logger).ServeHTTP(rr, req)
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+}
+
+func TestWithFeatureGate(t *testing.T) {
+	flags := features.NewRegistry(features.Flag{Name: "alpha", Paths: []string{"/api/alpha"}})
+	next := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) }
+
+	gated := WithFeatureGate(flags, "/api/alpha", next)
+
+	rr := httptest.NewRecorder()
+	gated(rr, httptest.NewRequest("GET", "/api/alpha", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 while flag is disabled, got %d", rr.Code)
+	}
+
+	flags.Set("alpha", true)
+	rr = httptest.NewRecorder()
+	gated(rr, httptest.NewRequest("GET", "/api/alpha", nil))
+	if rr.Code != http.StatusTeapot {
+		t.Errorf("Expected handler to run once flag is enabled, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	WithFeatureGate(flags, "/api/other", next)(rr, httptest.NewRequest("GET", "/api/other", nil))
+	if rr.Code != http.StatusTeapot {
+		t.Errorf("Expected ungated path to pass through, got %d", rr.Code)
+	}
+}
+
+func TestWithReadOnly(t *testing.T) {
+	next := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) }
+
+	cases := []struct {
+		path, method string
+		want         int
+	}{
+		{"/api/views", "GET", http.StatusTeapot},
+		{"/api/views", "HEAD", http.StatusTeapot},
+		{"/api/views", "OPTIONS", http.StatusTeapot},
+		{"/api/views", "POST", http.StatusForbidden},
+		{"/api/views/", "DELETE", http.StatusForbidden},
+		{"/api/preferences", "PUT", http.StatusForbidden},
+		{"/api/export/verify", "POST", http.StatusTeapot},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		WithReadOnly(tc.path, next)(rr, httptest.NewRequest(tc.method, tc.path, nil))
+		if rr.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.path, tc.want, rr.Code)
+		}
+	}
+}
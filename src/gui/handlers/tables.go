@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"html/template"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/i18n"
+)
+
+// DataTable is the format=table form of a chart endpoint's data: the same
+// points as labeled rows of formatted values, ready to render as an HTML
+// table for screen readers and browsers without JavaScript.
+type DataTable struct {
+	Caption string     `json:"caption"` // What the table shows
+	Columns []string   `json:"columns"` // Column headings; the first heads the row labels
+	Rows    []TableRow `json:"rows"`    // One row per point, in chart order
+	Locale  string     `json:"locale"`  // Language of the headings and number formats
+}
+
+// TableRow is one labeled row of a DataTable.
+type TableRow struct {
+	Label string   `json:"label"` // Row heading, such as a bucket's time or size range
+	Cells []string `json:"cells"` // Formatted values for the columns after the first
+}
+
+// tableFormat holds the format, units and lang query parameters of the
+// chart endpoints.
+type tableFormat struct {
+	table bool          // format=table was requested
+	units config.Units  // Sizes in KB and MB (decimal) or KiB and MiB (binary)
+	p     *i18n.Printer // Locale the table is written in
+}
+
+// parseTableFormat reads format (json or table, default json), units
+// (binary or decimal, default the instance's units) and the table's locale
+// from r.
+func parseTableFormat(r *http.Request, instance config.Units) (tableFormat, error) {
+	q := r.URL.Query()
+	f := tableFormat{units: instance}
+	switch q.Get("format") {
+	case "", "json":
+	case "table":
+		f.table = true
+	default:
+		return f, &requestError{"format must be json or table"}
+	}
+	switch units := config.Units(q.Get("units")); units {
+	case "":
+	case config.UnitsBinary, config.UnitsDecimal:
+		f.units = units
+	default:
+		return f, &requestError{"units must be binary or decimal"}
+	}
+	locale, err := requestLocale(r, "")
+	if err != nil {
+		return f, err
+	}
+	f.p = i18n.For(locale)
+	return f, nil
+}
+
+// newTable starts a table with translated caption and column headings.
+func (f tableFormat) newTable(caption string, columns ...string) DataTable {
+	t := DataTable{Caption: f.p.T(caption), Rows: []TableRow{}, Locale: f.p.Locale()}
+	for _, c := range columns {
+		t.Columns = append(t.Columns, f.p.T(c))
+	}
+	return t
+}
+
+// bytes formats n the way the dashboard does, e.g. "1.5 KiB" or "1.5 KB".
+func (f tableFormat) bytes(n int64) string {
+	return f.p.Bytes(n, !f.units.Binary())
+}
+
+// timeSeriesTable tabulates /api/charts/timeseries.
+func (f tableFormat) timeSeriesTable(points []TimeSeriesPoint) DataTable {
+	t := f.newTable("Ingestion over time", "Time (UTC)", "Batches", "Records", "Total size")
+	for _, p := range points {
+		t.Rows = append(t.Rows, TableRow{Label: p.Timestamp, Cells: []string{f.p.Number(int64(p.Count)), f.p.Number(p.Records), f.bytes(p.TotalSize)}})
+	}
+	return t
+}
+
+// breakdownTable tabulates /api/charts/breakdown and
+// /api/charts/record-size-breakdown, whose buckets count batches.
+func (f tableFormat) breakdownTable(caption, rangeColumn string, buckets []SizeBreakdown) DataTable {
+	t := f.newTable(caption, rangeColumn, "Batches", "Share")
+	for _, b := range buckets {
+		t.Rows = append(t.Rows, TableRow{Label: b.Range, Cells: []string{f.p.Number(int64(b.Count)), f.p.Percent(b.Percentage)}})
+	}
+	return t
+}
+
+// recordSizeTable tabulates /api/charts/record-sizes.
+func (f tableFormat) recordSizeTable(points []RecordSizePoint) DataTable {
+	t := f.newTable("Average record size by hour", "Hour (UTC)", "Batches", "Records", "Total size", "Average record size")
+	for _, p := range points {
+		t.Rows = append(t.Rows, TableRow{Label: p.Timestamp, Cells: []string{
+			f.p.Number(int64(p.Batches)), f.p.Number(p.Records), f.bytes(p.TotalSize), f.bytes(int64(math.Round(p.AvgRecordSize))),
+		}})
+	}
+	return t
+}
+
+// minuteTable tabulates /api/charts/minutes.
+func (f tableFormat) minuteTable(points []MinutePoint) DataTable {
+	t := f.newTable("Ingestion per minute", "Minute (UTC)", "Batches", "Records", "Total size")
+	for _, p := range points {
+		t.Rows = append(t.Rows, TableRow{Label: p.Timestamp, Cells: []string{f.p.Number(p.Batches), f.p.Number(p.Records), f.bytes(p.TotalSize)}})
+	}
+	return t
+}
+
+// dimensionTable tabulates /api/charts/by-dimension, with the bytes of
+// each value, largest first, in its own column.
+func (f tableFormat) dimensionTable(series DimensionSeries) DataTable {
+	t := f.newTable("Volume by dimension", "Time (UTC)")
+	t.Caption += ": " + series.Dimension
+	for _, v := range series.Values {
+		t.Columns = append(t.Columns, v.Value)
+	}
+	for _, p := range series.Points {
+		row := TableRow{Label: p.Timestamp, Cells: make([]string, 0, len(series.Values))}
+		for _, v := range series.Values {
+			row.Cells = append(row.Cells, f.bytes(p.Bytes[v.Value]))
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+// tablePage renders a DataTable as a standalone page for browsers that
+// follow a chart's table link without JavaScript.
+var tablePage = template.Must(template.New("table").Parse(`<!DOCTYPE html>
+<html lang="{{.Locale}}">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Caption}} - LogpushEstimator</title>
+    <link rel="stylesheet" href="/static/css/style.css">
+</head>
+<body>
+    <div class="container">
+        <div class="table-section">
+            <div class="table-container">
+                <table>
+                    <caption>{{.Caption}}</caption>
+                    <thead>
+                        <tr>{{range .Columns}}<th scope="col">{{.}}</th>{{end}}</tr>
+                    </thead>
+                    <tbody>
+                        {{range .Rows}}<tr><th scope="row">{{.Label}}</th>{{range .Cells}}<td>{{.}}</td>{{end}}</tr>
+                        {{else}}<tr><td colspan="{{len .Columns}}">{{$.Empty}}</td></tr>
+                        {{end}}
+                    </tbody>
+                </table>
+            </div>
+        </div>
+    </div>
+</body>
+</html>
+`))
+
+// send sends t as an HTML page to clients that accept HTML, such as a
+// browser following a link, and otherwise as a JSON API response.
+func (f tableFormat) send(w http.ResponseWriter, r *http.Request, t DataTable, meta *ResponseMeta) {
+	w.Header().Set("Content-Language", t.Locale)
+	w.Header().Add("Vary", "Accept-Language")
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		sendSuccessResponseWithMeta(w, t, meta)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tablePage.Execute(w, struct {
+		DataTable
+		Empty string // Shown in place of rows when there are none
+	}{t, f.p.T("No data for this range")})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+)
+
+func TestChartTables(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+	get := func(path, query, accept string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("GET", path+"?"+query, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rr := httptest.NewRecorder()
+		handlers[path].ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Every chart endpoint tabulates its data with one cell per column
+	for _, path := range []string{
+		"/api/charts/timeseries",
+		"/api/charts/breakdown",
+		"/api/charts/record-sizes",
+		"/api/charts/record-size-breakdown",
+		"/api/charts/minutes",
+	} {
+		rr := get(path, "format=table&hours=24", "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", path, rr.Code, rr.Body.String())
+		}
+		var table DataTable
+		if err := json.Unmarshal(rr.Body.Bytes(), &APIResponse{Data: &table}); err != nil {
+			t.Fatalf("%s: failed to decode table: %v", path, err)
+		}
+		if table.Caption == "" || len(table.Columns) < 2 || len(table.Rows) == 0 {
+			t.Errorf("%s: expected a captioned table with rows, got %+v", path, table)
+		}
+		for _, row := range table.Rows {
+			if row.Label == "" || len(row.Cells) != len(table.Columns)-1 {
+				t.Errorf("%s: row %+v does not match columns %v", path, row, table.Columns)
+			}
+		}
+	}
+
+	var table DataTable
+	json.Unmarshal(get("/api/charts/breakdown", "format=table&units=decimal", "").Body.Bytes(), &APIResponse{Data: &table})
+	if len(table.Rows) != 6 || table.Rows[1].Label != "1KB - 10KB" || table.Rows[1].Cells[0] != "4" || table.Rows[1].Cells[1] != "80.0%" {
+		t.Errorf("Expected the size ranges with counts and shares, got %+v", table.Rows)
+	}
+
+	// Browsers following a link get the table as a page
+	rr := get("/api/charts/breakdown", "format=table", "text/html,application/xhtml+xml")
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html") ||
+		!strings.Contains(rr.Body.String(), `<caption>File size distribution</caption>`) ||
+		!strings.Contains(rr.Body.String(), `<th scope="row">1KB - 10KB</th>`) {
+		t.Errorf("Expected an HTML table, got %s: %s", rr.Header().Get("Content-Type"), rr.Body.String())
+	}
+
+	// Headings and numbers follow the caller's language
+	req := httptest.NewRequest("GET", "/api/charts/breakdown?format=table", nil)
+	req.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+	rr = httptest.NewRecorder()
+	handlers["/api/charts/breakdown"].ServeHTTP(rr, req)
+	json.Unmarshal(rr.Body.Bytes(), &APIResponse{Data: &table})
+	if table.Locale != "de" || table.Caption != "Verteilung der Dateigrößen" || table.Rows[1].Cells[1] != "80,0 %" || rr.Header().Get("Content-Language") != "de" {
+		t.Errorf("Expected a German table, got %+v", table)
+	}
+
+	// Sizes and ranges default to the instance's units
+	doc := config.NewDocument()
+	doc.Units = config.UnitsBinary
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Failed to import config: %v", err)
+	}
+	json.Unmarshal(get("/api/charts/breakdown", "format=table", "").Body.Bytes(), &APIResponse{Data: &table})
+	if table.Rows[1].Label != "1KiB - 10KiB" {
+		t.Errorf("Expected binary ranges, got %+v", table.Rows)
+	}
+	json.Unmarshal(get("/api/charts/timeseries", "format=table", "").Body.Bytes(), &APIResponse{Data: &table})
+	if cells := table.Rows[0].Cells; !strings.HasSuffix(cells[len(cells)-1], "KiB") {
+		t.Errorf("Expected sizes in KiB, got %+v", table.Rows)
+	}
+
+	for _, query := range []string{"format=xml", "format=table&units=metric", "format=table&lang=xx"} {
+		if rr := get("/api/charts/timeseries", query, ""); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
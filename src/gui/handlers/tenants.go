@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+)
+
+// tenantScopedPaths lists the API routes served under /t/{tenant}/. They
+// only read log records, which are scoped to the tenant; instance-wide
+// routes such as admin, config, views and Cloudflare jobs are not exposed.
+// Preferences are per browser and are included so the tenant dashboard can
+// save its default range, and the dashboard layout so it can render its
+// widgets.
+var tenantScopedPaths = []string{
+	"/api/stats/summary",
+	"/api/logs/recent",
+	"/api/logs/range",
+	"/api/logs/since",
+	"/api/logs/count",
+	"/api/charts/timeseries",
+	"/api/charts/breakdown",
+	"/api/stats/records",
+	"/api/charts/record-sizes",
+	"/api/charts/record-size-breakdown",
+	"/api/charts/minutes",
+	"/api/stats/bursts",
+	"/api/estimates/bandwidth",
+	"/api/estimate/forecast",
+	"/api/stats/change-points",
+	"/api/datasets",
+	"/api/zones",
+	"/api/labels",
+	"/api/export",
+	"/api/preferences",
+	"/api/dashboard/layout",
+}
+
+// ValidTenantName reports whether name can be used as a tenant: 1 to 63
+// lowercase letters, digits or hyphens, not starting or ending with a hyphen.
+func ValidTenantName(name string) bool {
+	return config.ValidTenantName(name)
+}
+
+// ParseTenantPath splits a /t/{tenant}/... path into the tenant and the path
+// that follows it. /t/{tenant} without a trailing slash yields "/".
+//
+// Parameters:
+//   - path: Request path
+//
+// Returns:
+//   - string: Tenant name
+//   - string: Remaining path, starting with "/"
+//   - bool: Whether path is a tenant path with a valid tenant name
+func ParseTenantPath(path string) (string, string, bool) {
+	after, found := strings.CutPrefix(path, "/t/")
+	if !found {
+		return "", "", false
+	}
+	tenant, rest, _ := strings.Cut(after, "/")
+	return tenant, "/" + rest, ValidTenantName(tenant)
+}
+
+// TenantRouter serves per-tenant dashboards at /t/{tenant}/ and the
+// tenant-scoped API at /t/{tenant}/api/... Each tenant's handlers are built
+// by MakeAPIHandlersWithLimiter over a database controller scoped with
+// ForTenant, so every log record query they run is filtered to the tenant,
+// and share the unscoped API's cache, encryption key and limiter. Tenants
+// exist once records have been ingested for them through
+// /t/{tenant}/ingest; unknown tenants are 404.
+type TenantRouter struct {
+	db      *database.SQLiteController
+	logger  *slog.Logger
+	cache   *StatsCache
+	key     *encryption.Key
+	limiter *ConcurrencyLimiter
+	wrap    func(path string, handler http.HandlerFunc) http.HandlerFunc
+
+	mu      sync.Mutex
+	tenants map[string]map[string]http.HandlerFunc // Tenant to scoped API handlers
+}
+
+// NewTenantRouter creates a tenant router.
+//
+// Parameters:
+//   - db: Unscoped database controller
+//   - logger: Structured logger for request logging
+//   - cache: Statistics cache shared with the unscoped API, or nil
+//   - key: Encryption key for sealed archives and exports, or nil
+//   - limiter: Limiter the tenants' expensive endpoints share with the
+//     unscoped API (see NewExpensiveLimiter)
+//   - wrap: Middleware applied to each scoped API handler, given its path
+//     without the /t/{tenant} prefix; nil applies none
+//
+// Returns:
+//   - *TenantRouter: Router to mount at /t/
+func NewTenantRouter(db *database.SQLiteController, logger *slog.Logger, cache *StatsCache, key *encryption.Key, limiter *ConcurrencyLimiter, wrap func(path string, handler http.HandlerFunc) http.HandlerFunc) *TenantRouter {
+	if wrap == nil {
+		wrap = func(_ string, handler http.HandlerFunc) http.HandlerFunc { return handler }
+	}
+	return &TenantRouter{db: db, logger: logger, cache: cache, key: key, limiter: limiter, wrap: wrap, tenants: make(map[string]map[string]http.HandlerFunc)}
+}
+
+// ServeHTTP routes a /t/{tenant}/... request.
+func (t *TenantRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tenant, rest, ok := ParseTenantPath(r.URL.Path)
+	isAPI := strings.HasPrefix(rest, "/api/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	exists, err := t.db.TenantExists(tenant)
+	if err != nil {
+		if isAPI {
+			sendErrorResponse(w, "Failed to look up tenant")
+		} else {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+	if !exists {
+		if isAPI {
+			sendErrorResponseWithStatus(w, http.StatusNotFound, "Unknown tenant")
+		} else {
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	switch {
+	case rest == "/":
+		t.serveDashboard(w, r, tenant)
+	case isAPI:
+		handler, found := t.handlers(tenant)[rest]
+		if !found {
+			sendErrorResponseWithStatus(w, http.StatusNotFound, "Not found")
+			return
+		}
+		handler(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveDashboard renders the dashboard for tenant. Its scripts fetch data
+// from the tenant's API.
+func (t *TenantRouter) serveDashboard(w http.ResponseWriter, r *http.Request, tenant string) {
+	t.logger.Info("Tenant dashboard request", "remote_addr", r.RemoteAddr, "tenant", tenant)
+
+	prefs := database.DefaultPreferences()
+	if token, err := preferencesToken(w, r); err != nil {
+		t.logger.Error("Failed to issue preferences token", "error", err)
+	} else if saved, _, err := t.db.GetPreferences(token); err != nil {
+		t.logger.Error("Failed to load preferences for tenant dashboard", "error", err)
+	} else {
+		prefs = saved
+	}
+
+	data := newDashboardData(prefs)
+	data.Tenant = tenant
+	data.Units = displayUnits(t.db, prefs.Units)
+	var err error
+	if data.CSRFToken, err = csrfToken(w, r); err != nil {
+		t.logger.Error("Failed to issue CSRF token", "error", err)
+	}
+	renderDashboard(w, r, t.logger, data)
+}
+
+// handlers returns tenant's scoped API handlers, building them on first use.
+// They are kept so per-route limits are shared across a tenant's requests.
+func (t *TenantRouter) handlers(tenant string) map[string]http.HandlerFunc {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if scoped, ok := t.tenants[tenant]; ok {
+		return scoped
+	}
+
+	all := MakeAPIHandlersWithLimiter(t.db.ForTenant(tenant), t.logger.With("tenant", tenant), t.cache, t.key, t.limiter)
+	scoped := make(map[string]http.HandlerFunc, len(tenantScopedPaths))
+	for _, path := range tenantScopedPaths {
+		scoped[path] = t.wrap(path, all[path])
+	}
+	t.tenants[tenant] = scoped
+	return scoped
+}
+
+// WithTenantHost adds host-based tenant scoping: a request for
+// {tenant}.{domain} is served as if its path were prefixed with /t/{tenant},
+// so the subdomain only sees that tenant's dashboard and API. Static assets
+// are served unchanged. An empty domain disables host-based scoping.
+//
+// Parameters:
+//   - domain: Parent domain of the tenant subdomains, e.g. "lpe.example.com"
+//   - next: Handler serving both tenant and non-tenant paths
+//
+// Returns:
+//   - http.Handler: Handler rewriting tenant subdomain requests
+func WithTenantHost(domain string, next http.Handler) http.Handler {
+	if domain == "" {
+		return next
+	}
+	suffix := "." + strings.ToLower(strings.TrimSuffix(domain, "."))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		tenant, found := strings.CutSuffix(strings.ToLower(host), suffix)
+		if found && ValidTenantName(tenant) && !strings.HasPrefix(r.URL.Path, "/static/") && !strings.HasPrefix(r.URL.Path, "/t/") {
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = "/t/" + tenant + r.URL.Path
+			r2.URL.RawPath = ""
+			next.ServeHTTP(w, r2)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// makeTenantsHandler serves GET /api/tenants, listing every tenant with
+// stored records and its totals.
+func makeTenantsHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: tenants", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		tenants, err := db.ListTenants()
+		if err != nil {
+			sendErrorResponse(w, "Failed to list tenants")
+			return
+		}
+		sendSuccessResponse(w, tenants)
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestParseTenantPath(t *testing.T) {
+	tests := []struct {
+		path, tenant, rest string
+		ok                 bool
+	}{
+		{"/t/acme/api/stats/summary", "acme", "/api/stats/summary", true},
+		{"/t/acme/", "acme", "/", true},
+		{"/t/acme", "acme", "/", true},
+		{"/t/Acme/", "Acme", "/", false},
+		{"/t/-acme/", "-acme", "/", false},
+		{"/t//api/stats/summary", "", "/api/stats/summary", false},
+		{"/api/stats/summary", "", "", false},
+	}
+	for _, tt := range tests {
+		tenant, rest, ok := ParseTenantPath(tt.path)
+		if tenant != tt.tenant || rest != tt.rest || ok != tt.ok {
+			t.Errorf("ParseTenantPath(%q) = %q, %q, %v; want %q, %q, %v", tt.path, tenant, rest, ok, tt.tenant, tt.rest, tt.ok)
+		}
+	}
+}
+
+func TestTenantRouter(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	if err := db.ForTenant("acme").InsertLog(database.LogSize{Filesize: 100, RecordCount: 1}); err != nil {
+		t.Fatalf("Failed to insert tenant log: %v", err)
+	}
+
+	var wrapped []string
+	router := NewTenantRouter(db, logger, nil, nil, NewExpensiveLimiter(), func(path string, handler http.HandlerFunc) http.HandlerFunc {
+		wrapped = append(wrapped, path)
+		return handler
+	})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/t/acme/api/stats/summary", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var response struct {
+		Success bool         `json:"success"`
+		Data    LogSizeStats `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	// The test database holds five untenanted batches; only acme's is counted
+	if response.Data.TotalRecords != 1 || response.Data.TotalSize != 100 {
+		t.Errorf("Expected acme's single batch, got %+v", response.Data)
+	}
+	if len(wrapped) != len(tenantScopedPaths) {
+		t.Errorf("Expected every scoped route to be wrapped once, got %v", wrapped)
+	}
+
+	// Instance-wide routes are not served under a tenant
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/t/acme/api/admin/config", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for admin route, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/t/globex/api/stats/summary", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown tenant, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/t/acme/", nil))
+	// The template is resolved relative to the application root, so the
+	// handler either renders (200) or reports the missing template (500)
+	if rr.Code != http.StatusOK && rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 200 or 500, got %v", rr.Code)
+	}
+
+	// Handlers are built once per tenant
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/t/acme/api/logs/recent", nil))
+	if len(wrapped) != len(tenantScopedPaths) {
+		t.Errorf("Expected cached tenant handlers, got %d wraps", len(wrapped))
+	}
+}
+
+func TestWithTenantHost(t *testing.T) {
+	var seen string
+	handler := WithTenantHost("lpe.example.com", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.URL.Path
+	}))
+
+	tests := []struct {
+		host, path, want string
+	}{
+		{"acme.lpe.example.com", "/", "/t/acme/"},
+		{"acme.lpe.example.com:8081", "/api/stats/summary", "/t/acme/api/stats/summary"},
+		{"acme.lpe.example.com", "/static/js/dashboard.js", "/static/js/dashboard.js"},
+		{"lpe.example.com", "/", "/"},
+		{"a.b.lpe.example.com", "/", "/"},
+		{"acme.other.com", "/", "/"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = tt.host
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if seen != tt.want {
+			t.Errorf("%s%s served as %q, want %q", tt.host, tt.path, seen, tt.want)
+		}
+	}
+}
+
+func TestAPITenants(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	if err := db.ForTenant("acme").InsertLog(database.LogSize{Filesize: 100}); err != nil {
+		t.Fatalf("Failed to insert tenant log: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	MakeAPIHandlers(db, logger)["/api/tenants"].ServeHTTP(rr, httptest.NewRequest("GET", "/api/tenants", nil))
+	var response struct {
+		Success bool                   `json:"success"`
+		Data    []database.TenantUsage `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if len(response.Data) != 2 || response.Data[1].Tenant != "acme" || response.Data[1].TotalSize != 100 {
+		t.Errorf("Unexpected tenants: %+v", response.Data)
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeFormats names the accepted time formats in validation errors.
+const timeFormats = "RFC3339, Unix seconds or Unix milliseconds"
+
+// epochMillisThreshold separates Unix seconds from Unix milliseconds: 1e11
+// seconds is in the year 5138, while 1e11 milliseconds is in 1973, so any
+// integer at least this large is read as milliseconds.
+const epochMillisThreshold = 1e11
+
+// errTimestampFormat is returned by parseTimestamp for unrecognised values.
+var errTimestampFormat = errors.New("time must be " + timeFormats)
+
+// parseTimestamp parses a time parameter given as RFC3339 (with optional
+// fractional seconds, as in RFC3339Nano), Unix seconds (optionally with a
+// fractional part) or Unix milliseconds, the formats scripting clients
+// commonly emit.
+//
+// Parameters:
+//   - value: Time to parse, e.g. "2025-09-15T14:30:00Z", "1757946600" or
+//     "1757946600000"
+//
+// Returns:
+//   - time.Time: The time in UTC
+//   - error: errTimestampFormat for unrecognised values
+func parseTimestamp(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t.UTC(), nil
+	}
+
+	whole, frac, hasFrac := strings.Cut(value, ".")
+	n, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || whole == "" || whole[0] == '+' {
+		return time.Time{}, errTimestampFormat
+	}
+	if hasFrac {
+		// Fractional Unix seconds, to nanosecond precision
+		if frac == "" || len(frac) > 9 || strings.TrimLeft(frac, "0123456789") != "" || n >= epochMillisThreshold || n <= -epochMillisThreshold {
+			return time.Time{}, errTimestampFormat
+		}
+		nanos, _ := strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		if strings.HasPrefix(whole, "-") {
+			nanos = -nanos
+		}
+		return time.Unix(n, nanos).UTC(), nil
+	}
+	if n >= epochMillisThreshold || n <= -epochMillisThreshold {
+		return time.UnixMilli(n).UTC(), nil
+	}
+	return time.Unix(n, 0).UTC(), nil
+}
+
+// invalidTimeMessage is the client-facing error for a malformed time
+// parameter.
+func invalidTimeMessage(param string) string {
+	return "Invalid " + param + " time format (use " + timeFormats + ")"
+}
+
+// decodeTimestamp reads an optional time from a JSON body field, given as a
+// string in any format parseTimestamp accepts or as a number of Unix
+// seconds or milliseconds.
+//
+// Parameters:
+//   - raw: Field value; empty or null when the field is absent
+//
+// Returns:
+//   - *time.Time: The time in UTC, or nil when absent
+//   - error: errTimestampFormat for unrecognised values
+func decodeTimestamp(raw json.RawMessage) (*time.Time, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	value := string(raw)
+	if raw[0] == '"' {
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, errTimestampFormat
+		}
+	}
+	t, err := parseTimestamp(value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
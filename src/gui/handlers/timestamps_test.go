@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2025, 9, 15, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2025-09-15T14:30:00Z", want},
+		{"2025-09-15T16:30:00+02:00", want},
+		{"2025-09-15T14:30:00.123456789Z", want.Add(123456789)},
+		{"1757946600", want},
+		{"1757946600000", want},
+		{"1757946600123", want.Add(123 * time.Millisecond)},
+		{"1757946600.5", want.Add(500 * time.Millisecond)},
+		{"0", time.Unix(0, 0).UTC()},
+		{"-1.5", time.Unix(-2, 500000000).UTC()},
+	}
+	for _, tt := range tests {
+		got, err := parseTimestamp(tt.value)
+		if err != nil || !got.Equal(tt.want) || got.Location() != time.UTC {
+			t.Errorf("parseTimestamp(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "yesterday", "2025-09-15", "1757946600.", "1757946600.5e3", "+1757946600", "1.2.3", "1757946600000.5"} {
+		if _, err := parseTimestamp(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestAPIEpochTimeParameters(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+
+	end := time.Now().Add(time.Hour)
+	start := end.Add(-2 * time.Hour)
+	target := "/api/logs/range?start=" + strconv.FormatInt(start.Unix(), 10) + "&end=" + strconv.FormatInt(end.UnixMilli(), 10)
+	rr := httptest.NewRecorder()
+	handlers["/api/logs/range"](rr, httptest.NewRequest("GET", target, nil))
+	var response struct {
+		Data []json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(response.Data) != 5 {
+		t.Errorf("Expected 5 records, got %d", len(response.Data))
+	}
+
+	rr = httptest.NewRecorder()
+	handlers["/api/stats/summary"](rr, httptest.NewRequest("GET", "/api/stats/summary?start=soon&end=later", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a malformed time, got %d", rr.Code)
+	}
+
+	// Saved views accept epoch timestamps too
+	body := []byte(`{"name":"epoch","start":1757946600,"end":"1757950200000"}`)
+	rr = httptest.NewRecorder()
+	handlers["/api/views"](rr, httptest.NewRequest("POST", "/api/views", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 saving a view with epoch times, got %d: %s", rr.Code, rr.Body.String())
+	}
+	view, _, err := db.GetView("epoch")
+	if err != nil {
+		t.Fatalf("Failed to load view: %v", err)
+	}
+	if view.Start == nil || view.Start.Unix() != 1757946600 || view.End == nil || view.End.Unix() != 1757950200 {
+		t.Errorf("Expected the epoch window to be stored, got %v - %v", view.Start, view.End)
+	}
+}
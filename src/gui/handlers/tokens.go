@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+)
+
+// Grace periods of token rotations.
+const (
+	defaultRotationGrace = 24 * time.Hour
+	maxRotationGrace     = 30 * 24 * time.Hour
+)
+
+// TokenRotation is the response body of POST /api/admin/tokens/{name}/rotate.
+type TokenRotation struct {
+	Token              string    `json:"token"`                // Token name
+	Secret             string    `json:"secret"`               // New secret; only returned here
+	PreviousValidUntil time.Time `json:"previous_valid_until"` // When the replaced secret stops being accepted
+}
+
+// WithAdminToken answers requests that do not present the secret of an
+// instance-wide admin token with 401. It guards the endpoints whose
+// responses carry token secrets, which stay closed until such a token is
+// configured. The token is accepted in the same forms as an ingest token
+// (see ingest.Credentials).
+//
+// Parameters:
+//   - settings: Configuration holding the tokens
+//   - logger: Structured logger for rejected requests
+//   - next: Handler to protect
+//
+// Returns:
+//   - http.HandlerFunc: Handler requiring an admin token
+func WithAdminToken(settings *config.Watcher, logger *slog.Logger, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		doc := settings.Current()
+		if !doc.AuthenticateAdmin(ingest.Credentials(r), now()) {
+			logger.Warn("Rejected admin request without a valid token", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			sendErrorResponseWithStatus(w, http.StatusUnauthorized, "Missing or invalid admin token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// adminBootstrapRoutes accept writes without a token until an admin token
+// is configured, so the first one can be added through them.
+var adminBootstrapRoutes = map[string]bool{
+	"/api/admin/config":        true,
+	"/api/admin/config/import": true,
+}
+
+// WithAdminWrites requires an admin token, as WithAdminToken does, for the
+// state-changing requests (POST, PUT, PATCH, DELETE) to the admin route at
+// path; GET, HEAD and OPTIONS reach next unchanged. The configuration
+// routes stay writable without a token until an admin token exists.
+//
+// Parameters:
+//   - settings: Configuration holding the tokens
+//   - logger: Structured logger for rejected requests
+//   - path: Route path the handler is mounted at
+//   - next: Handler to protect
+//
+// Returns:
+//   - http.HandlerFunc: Handler requiring an admin token for writes
+func WithAdminWrites(settings *config.Watcher, logger *slog.Logger, path string, next http.HandlerFunc) http.HandlerFunc {
+	guarded := WithAdminToken(settings, logger, next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if csrfSafeMethods[r.Method] {
+			next(w, r)
+			return
+		}
+		if adminBootstrapRoutes[path] {
+			if doc := settings.Current(); !doc.HasAdminToken() {
+				next(w, r)
+				return
+			}
+		}
+		guarded(w, r)
+	}
+}
+
+// makeTokenRotateHandler serves POST /api/admin/tokens/{name}/rotate. It
+// gives the token a new random secret and keeps the replaced one valid for
+// the grace period (grace, a duration such as "48h"; default 24h, at most
+// 30 days, 0 revokes it at once), so clients such as Logpush jobs can be
+// switched to the new secret without a gap.
+func makeTokenRotateHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/tokens/"), "/rotate")
+		logger.Info("API request: token rotation", "token", name, "remote_addr", r.RemoteAddr)
+
+		if !ok || name == "" || strings.Contains(name, "/") {
+			sendErrorResponseWithStatus(w, http.StatusNotFound, "Not found")
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		grace := defaultRotationGrace
+		if v := r.URL.Query().Get("grace"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 || d > maxRotationGrace {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid grace (use a duration between 0 and 720h)")
+				return
+			}
+			grace = d
+		}
+
+		secret, err := newTokenSecret()
+		if err != nil {
+			sendErrorResponse(w, "Failed to generate token")
+			return
+		}
+		validUntil := now().UTC().Add(grace)
+		found, err := config.RotateToken(db, name, secret, validUntil)
+		switch {
+		case errors.Is(err, config.ErrNotRotatable):
+			sendErrorResponseWithStatus(w, http.StatusConflict, "Token secret is a secret reference; rotate it in its secret store")
+			return
+		case err != nil:
+			logger.Error("Failed to rotate token", "token", name, "error", err)
+			sendErrorResponse(w, "Failed to rotate token")
+			return
+		case !found:
+			sendErrorResponseWithStatus(w, http.StatusNotFound, "Token not found")
+			return
+		}
+
+		logger.Info("Token rotated", "token", name, "previous_valid_until", validUntil)
+		sendSuccessResponse(w, TokenRotation{Token: name, Secret: secret, PreviousValidUntil: validUntil})
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/testsupport"
+)
+
+func TestAPITokenRotation(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	at := time.Date(2025, 9, 20, 12, 0, 0, 0, time.UTC)
+	SetClock(testsupport.NewClock(at))
+	defer SetClock(nil)
+	doc := config.NewDocument()
+	doc.Tokens = []config.Token{{Name: "logpush", Scopes: []string{"ingest"}, Secret: "old"}}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/admin/tokens/"]
+	rotate := func(target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("POST", target, nil))
+		return rr
+	}
+
+	rr := rotate("/api/admin/tokens/logpush/rotate?grace=48h")
+	var resp struct {
+		Data TokenRotation `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(resp.Data.Secret) != 64 || !resp.Data.PreviousValidUntil.Equal(at.Add(48*time.Hour)) {
+		t.Errorf("Unexpected rotation %+v", resp.Data)
+	}
+
+	exported, _ := config.Export(db)
+	token := exported.Tokens[0]
+	if token.PreviousSecret != config.Redacted || token.PreviousValidUntil == nil || !token.PreviousValidUntil.Equal(at.Add(48*time.Hour)) {
+		t.Errorf("Expected the old secret to be kept for the grace period, got %+v", token)
+	}
+
+	tests := map[string]int{
+		"/api/admin/tokens/missing/rotate":            http.StatusNotFound,
+		"/api/admin/tokens/logpush":                   http.StatusNotFound,
+		"/api/admin/tokens/logpush/rotate?grace=900h": http.StatusBadRequest,
+		"/api/admin/tokens/logpush/rotate?grace=-1h":  http.StatusBadRequest,
+	}
+	for target, want := range tests {
+		if rr := rotate(target); rr.Code != want {
+			t.Errorf("%s: expected %d, got %d", target, want, rr.Code)
+		}
+	}
+}
+
+func TestWithAdminToken(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := WithAdminToken(config.NewWatcher(db, 0, nil), logger, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	call := func(token string) int {
+		req := httptest.NewRequest("POST", "/api/admin/tokens/logpush/rotate", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Closed until an admin token exists
+	if code := call(""); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without admin tokens, got %d", code)
+	}
+
+	doc := config.NewDocument()
+	doc.Tokens = []config.Token{
+		{Name: "logpush", Scopes: []string{"ingest"}, Secret: "ingest-token"},
+		{Name: "ops", Scopes: []string{"admin"}, Secret: "admin-token"},
+	}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "ingest-token": http.StatusUnauthorized, "admin-token": http.StatusNoContent} {
+		if code := call(token); code != want {
+			t.Errorf("Token %q: expected %d, got %d", token, want, code)
+		}
+	}
+}
+
+func TestWithAdminWrites(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	settings := config.NewWatcher(db, 0, nil)
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	handlers := map[string]http.HandlerFunc{
+		"/api/admin/config":       WithAdminWrites(settings, logger, "/api/admin/config", ok),
+		"/api/admin/delete-range": WithAdminWrites(settings, logger, "/api/admin/delete-range", ok),
+	}
+	call := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		handlers[path].ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Before an admin token exists only the configuration can be written
+	for _, c := range []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/api/admin/delete-range", http.StatusNoContent},
+		{"POST", "/api/admin/delete-range", http.StatusUnauthorized},
+		{"PUT", "/api/admin/config", http.StatusNoContent},
+	} {
+		if code := call(c.method, c.path, ""); code != c.want {
+			t.Errorf("%s %s without admin tokens: expected %d, got %d", c.method, c.path, c.want, code)
+		}
+	}
+
+	doc := config.NewDocument()
+	doc.Tokens = []config.Token{{Name: "ops", Scopes: []string{"admin"}, Secret: "admin-token"}}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	for _, c := range []struct {
+		method, path, token string
+		want                int
+	}{
+		{"GET", "/api/admin/config", "", http.StatusNoContent},
+		{"PUT", "/api/admin/config", "", http.StatusUnauthorized},
+		{"PUT", "/api/admin/config", "admin-token", http.StatusNoContent},
+		{"DELETE", "/api/admin/delete-range", "", http.StatusUnauthorized},
+		{"DELETE", "/api/admin/delete-range", "admin-token", http.StatusNoContent},
+	} {
+		if code := call(c.method, c.path, c.token); code != c.want {
+			t.Errorf("%s %s with token %q: expected %d, got %d", c.method, c.path, c.token, c.want, code)
+		}
+	}
+}
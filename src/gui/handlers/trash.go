@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// TrashEntry is a restorable delete batch as listed by /api/admin/trash.
+type TrashEntry struct {
+	database.TrashBatch
+	ExpiresAt time.Time `json:"expires_at"` // When the batch will be purged permanently
+}
+
+// makeTrashHandler serves GET /api/admin/trash, listing the delete batches
+// that can still be restored, newest first, with the time each one expires
+// under the configured retention.trash_days.
+func makeTrashHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: trash", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		doc, err := config.Export(db)
+		if err != nil {
+			sendErrorResponse(w, "Failed to read retention settings")
+			return
+		}
+		batches, err := db.ListTrash()
+		if err != nil {
+			sendErrorResponse(w, "Failed to list trash")
+			return
+		}
+
+		retention := doc.Retention.TrashRetention()
+		entries := make([]TrashEntry, 0, len(batches))
+		for _, b := range batches {
+			entries = append(entries, TrashEntry{TrashBatch: b, ExpiresAt: b.DeletedAt.Add(retention)})
+		}
+		sendSuccessResponse(w, map[string]interface{}{"batches": entries})
+	}
+}
+
+// makeTrashRestoreHandler serves POST /api/admin/trash/restore?id=N, moving
+// the records of trash batch N back into the live tables.
+func makeTrashRestoreHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: trash restore", "remote_addr", r.RemoteAddr)
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		if err != nil || id <= 0 {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, "id must be a positive integer")
+			return
+		}
+
+		restored, found, err := db.RestoreTrash(id)
+		if err != nil {
+			sendErrorResponse(w, "Failed to restore trash batch")
+			return
+		}
+		if !found {
+			sendErrorResponseWithStatus(w, http.StatusNotFound, "Trash batch not found")
+			return
+		}
+		logger.Info("Trash batch restored", "id", id, "rows", restored.Rows, "bytes", restored.Bytes)
+		sendSuccessResponse(w, map[string]interface{}{"id": id, "rows": restored.Rows, "bytes": restored.Bytes})
+	}
+}
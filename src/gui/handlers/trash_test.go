@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAPITrashListAndRestore(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+
+	now := time.Now().UTC()
+	query := "start=" + now.Add(-time.Hour).Format(time.RFC3339) + "&end=" + now.Add(time.Hour).Format(time.RFC3339)
+	rr := httptest.NewRecorder()
+	handlers["/api/admin/delete-range"].ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/delete-range?"+query, nil))
+	report := decodeDeletionReport(t, rr)
+	if report.TrashID == 0 {
+		t.Fatalf("Expected delete report to name a trash batch, got %+v", report)
+	}
+
+	rr = httptest.NewRecorder()
+	handlers["/api/admin/trash"].ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/trash", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	var listResp struct {
+		Data struct {
+			Batches []TrashEntry `json:"batches"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &listResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	batches := listResp.Data.Batches
+	if len(batches) != 1 || batches[0].ID != report.TrashID || batches[0].Rows != 5 {
+		t.Fatalf("Expected the deleted batch in the trash, got %+v", batches)
+	}
+	if got := batches[0].ExpiresAt.Sub(batches[0].DeletedAt); got != 7*24*time.Hour {
+		t.Errorf("Expected default 7 day expiry, got %v", got)
+	}
+
+	restoreURL := "/api/admin/trash/restore?id=" + strconv.FormatInt(report.TrashID, 10)
+	rr = httptest.NewRecorder()
+	handlers["/api/admin/trash/restore"].ServeHTTP(rr, httptest.NewRequest("POST", restoreURL, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if logs, _ := db.GetAll(); len(logs) != 5 {
+		t.Errorf("Expected 5 rows after restore, got %d", len(logs))
+	}
+
+	rr = httptest.NewRecorder()
+	handlers["/api/admin/trash/restore"].ServeHTTP(rr, httptest.NewRequest("POST", restoreURL, nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 restoring a batch twice, got %d", rr.Code)
+	}
+}
+
+func TestAPITrashRestoreRejectsBadInput(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/admin/trash/restore"]
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/admin/trash/restore?id=abc", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad id, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/trash/restore?id=1", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// instanceUnits returns the unit system configured for the instance, or
+// decimal units when the configuration cannot be read.
+func instanceUnits(db *database.SQLiteController) config.Units {
+	doc, err := config.Export(db)
+	if err != nil || doc.Units == "" {
+		return config.UnitsDecimal
+	}
+	return doc.Units
+}
+
+// displayUnits returns the unit system sizes are shown to a user in: the
+// units saved in their preferences, or the instance's when they chose none.
+func displayUnits(db *database.SQLiteController, preferred string) config.Units {
+	if preferred != "" {
+		return config.Units(preferred)
+	}
+	return instanceUnits(db)
+}
+
+// sizeRange is one bucket of a size distribution.
+type sizeRange struct {
+	Name string  // Bucket label, e.g. "1KB - 10KB"
+	Min  float64 // Inclusive lower bound in bytes
+	Max  float64 // Exclusive upper bound in bytes; 0 for no bound
+}
+
+// sizeRanges builds distribution buckets from bounds given in kilobytes,
+// counting in KB and MB (powers of 1000) or, with binary units, in KiB and
+// MiB (powers of 1024), so a bound of 1000 is 1MB or 1MiB. The first bucket
+// is below bounds[0] and the last above the final bound.
+func sizeRanges(units config.Units, bounds ...float64) []sizeRange {
+	k, names := 1000.0, []string{"B", "KB", "MB", "GB"}
+	if units.Binary() {
+		k, names = 1024, []string{"B", "KiB", "MiB", "GiB"}
+	}
+	type bound struct {
+		bytes float64
+		label string
+	}
+	scaled := make([]bound, len(bounds))
+	for i, b := range bounds {
+		power := 1
+		for b >= 1000 && power < len(names)-1 {
+			b /= 1000
+			power++
+		}
+		if b < 1 {
+			b *= k
+			power--
+		}
+		scaled[i] = bound{b * math.Pow(k, float64(power)), strconv.FormatFloat(b, 'f', -1, 64) + names[power]}
+	}
+
+	ranges := []sizeRange{{"< " + scaled[0].label, 0, scaled[0].bytes}}
+	for i := 1; i < len(scaled); i++ {
+		ranges = append(ranges, sizeRange{scaled[i-1].label + " - " + scaled[i].label, scaled[i-1].bytes, scaled[i].bytes})
+	}
+	last := scaled[len(scaled)-1]
+	return append(ranges, sizeRange{"> " + last.label, last.bytes, 0})
+}
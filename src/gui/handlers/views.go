@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// viewNamePattern restricts view names to values that are safe to use as a
+// single URL path segment.
+var viewNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// validateView checks a view definition, filling in the default 24 hour
+// range when no range is given. It returns a client-facing message for the
+// first invalid field.
+func validateView(v *database.SavedView) string {
+	if !viewNamePattern.MatchString(v.Name) {
+		return "name must be 1-64 letters, digits, '-' or '_'"
+	}
+	switch {
+	case v.Start != nil || v.End != nil:
+		if v.Start == nil || v.End == nil {
+			return "start and end must be given together"
+		}
+		if !v.Start.Before(*v.End) {
+			return "start must be before end"
+		}
+		if v.RangeHours != 0 {
+			return "range_hours cannot be combined with start and end"
+		}
+	case v.RangeHours == 0:
+		v.RangeHours = 24
+	case v.RangeHours < 0 || v.RangeHours > maxPreferenceRangeHours:
+		return "range_hours must be between 1 and 8760"
+	}
+	if v.Interval != "" && v.Interval != "minute" && v.Interval != "hour" {
+		return "interval must be \"minute\" or \"hour\""
+	}
+	return ""
+}
+
+// decodeView reads a view definition from the request body and validates it.
+// start and end accept the formats of time query parameters, including Unix
+// timestamps. A non-empty name overrides any name in the body.
+func decodeView(r *http.Request, name string) (database.SavedView, string) {
+	var body struct {
+		database.SavedView
+		Start json.RawMessage `json:"start"`
+		End   json.RawMessage `json:"end"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return body.SavedView, "Invalid JSON body"
+	}
+	v := body.SavedView
+	var err error
+	if v.Start, err = decodeTimestamp(body.Start); err != nil {
+		return v, invalidTimeMessage("start")
+	}
+	if v.End, err = decodeTimestamp(body.End); err != nil {
+		return v, invalidTimeMessage("end")
+	}
+	if name != "" {
+		v.Name = name
+	}
+	return v, validateView(&v)
+}
+
+// makeViewsHandler serves /api/views. GET lists saved views and POST creates
+// or replaces the view named in the JSON body.
+func makeViewsHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: views", "method", r.Method, "remote_addr", r.RemoteAddr)
+
+		switch r.Method {
+		case http.MethodGet:
+			views, err := db.ListViews()
+			if err != nil {
+				sendErrorResponse(w, "Failed to fetch views")
+				return
+			}
+			sendSuccessResponse(w, views)
+
+		case http.MethodPost:
+			view, msg := decodeView(r, "")
+			if msg != "" {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, msg)
+				return
+			}
+			saved, err := db.SaveView(view)
+			if err != nil {
+				sendErrorResponse(w, "Failed to save view")
+				return
+			}
+			sendSuccessResponse(w, saved)
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// makeViewHandler serves /api/views/{name}: GET returns the view, PUT replaces
+// it with the JSON body and DELETE removes it.
+func makeViewHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/api/views/")
+		logger.Info("API request: view", "method", r.Method, "name", name, "remote_addr", r.RemoteAddr)
+
+		if !viewNamePattern.MatchString(name) {
+			sendErrorResponseWithStatus(w, http.StatusNotFound, "View not found")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			view, found, err := db.GetView(name)
+			if err != nil {
+				sendErrorResponse(w, "Failed to fetch view")
+				return
+			}
+			if !found {
+				sendErrorResponseWithStatus(w, http.StatusNotFound, "View not found")
+				return
+			}
+			sendSuccessResponse(w, view)
+
+		case http.MethodPut:
+			view, msg := decodeView(r, name)
+			if msg != "" {
+				sendErrorResponseWithStatus(w, http.StatusBadRequest, msg)
+				return
+			}
+			saved, err := db.SaveView(view)
+			if err != nil {
+				sendErrorResponse(w, "Failed to save view")
+				return
+			}
+			sendSuccessResponse(w, saved)
+
+		case http.MethodDelete:
+			deleted, err := db.DeleteView(name)
+			if err != nil {
+				sendErrorResponse(w, "Failed to delete view")
+				return
+			}
+			if !deleted {
+				sendErrorResponseWithStatus(w, http.StatusNotFound, "View not found")
+				return
+			}
+			sendSuccessResponse(w, map[string]string{"deleted": name})
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			sendErrorResponseWithStatus(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// MakeViewPageHandler creates an HTTP handler that renders a saved view at
+// /views/{name}. The page is the regular dashboard opened with the view's
+// range and interval in place of the caller's default range.
+//
+// Parameters:
+//   - db: Database controller used to load views and preferences
+//   - logger: Structured logger for request logging and error reporting
+//
+// Returns:
+//   - http.HandlerFunc: Configured handler function for view pages
+func MakeViewPageHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/views/")
+		logger.Info("View page request", "remote_addr", r.RemoteAddr, "name", name)
+
+		if !viewNamePattern.MatchString(name) {
+			http.NotFound(w, r)
+			return
+		}
+		view, found, err := db.GetView(name)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+
+		prefs := database.DefaultPreferences()
+		if token, err := preferencesToken(w, r); err != nil {
+			logger.Error("Failed to issue preferences token", "error", err)
+		} else if saved, _, err := db.GetPreferences(token); err != nil {
+			logger.Error("Failed to load preferences for view", "error", err)
+		} else {
+			prefs = saved
+		}
+		if view.RangeHours > 0 {
+			prefs.DefaultRangeHours = view.RangeHours
+		}
+
+		data := newDashboardData(prefs)
+		data.View = &view
+		data.Units = displayUnits(db, prefs.Units)
+		if data.CSRFToken, err = csrfToken(w, r); err != nil {
+			logger.Error("Failed to issue CSRF token", "error", err)
+		}
+		renderDashboard(w, r, logger, data)
+	}
+}
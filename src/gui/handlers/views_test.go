@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestAPIViews(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	apiHandlers := MakeAPIHandlers(db, logger)
+	list, single := apiHandlers["/api/views"], apiHandlers["/api/views/"]
+
+	body := `{"name":"incident","description":"5xx spike","start":"2024-03-01T00:00:00Z","end":"2024-03-01T06:00:00Z","interval":"minute","datasets":["http_requests"],"filters":{"status":"5xx"}}`
+	rr := httptest.NewRecorder()
+	list.ServeHTTP(rr, httptest.NewRequest("POST", "/api/views", strings.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from POST, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	list.ServeHTTP(rr, httptest.NewRequest("GET", "/api/views", nil))
+	var listResponse struct {
+		Success bool                 `json:"success"`
+		Data    []database.SavedView `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &listResponse); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if len(listResponse.Data) != 1 || listResponse.Data[0].Filters["status"] != "5xx" {
+		t.Fatalf("Unexpected view list: %+v", listResponse.Data)
+	}
+
+	// PUT by name replaces the definition; the path name wins over the body
+	rr = httptest.NewRecorder()
+	single.ServeHTTP(rr, httptest.NewRequest("PUT", "/api/views/incident", strings.NewReader(`{"name":"other"}`)))
+	var viewResponse struct {
+		Success bool               `json:"success"`
+		Data    database.SavedView `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &viewResponse); err != nil {
+		t.Fatalf("Could not parse JSON response: %v", err)
+	}
+	if viewResponse.Data.Name != "incident" || viewResponse.Data.RangeHours != 24 || viewResponse.Data.Start != nil {
+		t.Errorf("Expected replaced view with default range, got %+v", viewResponse.Data)
+	}
+
+	rr = httptest.NewRecorder()
+	single.ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/views/incident", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from DELETE, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	single.ServeHTTP(rr, httptest.NewRequest("GET", "/api/views/incident", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", rr.Code)
+	}
+}
+
+func TestAPIViewsValidation(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeAPIHandlers(db, logger)["/api/views"]
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{"Invalid JSON", "POST", "{", http.StatusBadRequest},
+		{"Missing name", "POST", `{"range_hours":6}`, http.StatusBadRequest},
+		{"Unsafe name", "POST", `{"name":"../etc"}`, http.StatusBadRequest},
+		{"Start without end", "POST", `{"name":"a","start":"2024-03-01T00:00:00Z"}`, http.StatusBadRequest},
+		{"Reversed window", "POST", `{"name":"a","start":"2024-03-02T00:00:00Z","end":"2024-03-01T00:00:00Z"}`, http.StatusBadRequest},
+		{"Range and window", "POST", `{"name":"a","range_hours":6,"start":"2024-03-01T00:00:00Z","end":"2024-03-02T00:00:00Z"}`, http.StatusBadRequest},
+		{"Bad interval", "POST", `{"name":"a","interval":"fortnight"}`, http.StatusBadRequest},
+		{"Unsupported method", "DELETE", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tt.method, "/api/views", strings.NewReader(tt.body)))
+			if rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestMakeViewPageHandler(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handler := MakeViewPageHandler(db, logger)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/views/missing", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown view, got %d", rr.Code)
+	}
+
+	if _, err := db.SaveView(database.SavedView{Name: "weekly", RangeHours: 168}); err != nil {
+		t.Fatalf("Failed to save view: %v", err)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/views/weekly", nil))
+
+	// The template is resolved relative to the application root, so the
+	// handler either renders (200) or reports the missing template (500)
+	if rr.Code != http.StatusOK && rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 200 or 500, got %v", rr.Code)
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// makeZonesHandler serves /api/zones: the batches, records and bytes stored
+// per Cloudflare zone in the range selected by last, start/end or hours
+// (the whole history without one), largest first. Batches ingested without
+// a zone are listed under the empty name. With ?dataset= only that
+// dataset's batches are counted.
+func makeZonesHandler(db *database.SQLiteController, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("API request: zones", "remote_addr", r.RemoteAddr)
+
+		start, end, all, err := requestRange(r)
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendErrorResponseWithStatus(w, http.StatusBadRequest, reqErr.message)
+			return
+		}
+		if all {
+			start, end = time.Time{}, time.Time{}
+		}
+
+		usage, err := db.QueryZoneUsage(start, end)
+		if err != nil {
+			sendErrorResponse(w, "Failed to query zone usage")
+			return
+		}
+		sendSuccessResponse(w, usage)
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestAPIZoneFiltering(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	for _, l := range []database.LogSize{
+		{Filesize: 100, RecordCount: 1, Dataset: "http_requests", Zone: "example.com"},
+		{Filesize: 200, RecordCount: 2, Dataset: "firewall_events", Zone: "example.com"},
+		{Filesize: 50, RecordCount: 1, Dataset: "http_requests", Zone: "example.org"},
+	} {
+		if err := db.InsertLog(l); err != nil {
+			t.Fatalf("InsertLog failed: %v", err)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	handlers := MakeAPIHandlers(db, logger)
+	get := func(path, target string, data any) int {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handlers[path].ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &APIResponse{Data: data}); err != nil {
+				t.Fatalf("%s: failed to decode response: %v", target, err)
+			}
+		}
+		return rr.Code
+	}
+
+	var stats LogSizeStats
+	if code := get("/api/stats/summary", "/api/stats/summary?zone=example.com", &stats); code != http.StatusOK || stats.TotalRecords != 2 || stats.TotalSize != 300 {
+		t.Errorf("Expected the two example.com batches, got %d %+v", code, stats)
+	}
+	if get("/api/stats/summary", "/api/stats/summary?zone=example.com&dataset=http_requests", &stats); stats.TotalRecords != 1 || stats.TotalSize != 100 {
+		t.Errorf("Expected example.com's http_requests batch, got %+v", stats)
+	}
+	if code := get("/api/stats/summary", "/api/stats/summary?zone=Example.com", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid zone name, got %d", code)
+	}
+
+	var usage []database.ZoneUsage
+	if code := get("/api/zones", "/api/zones", &usage); code != http.StatusOK || len(usage) != 3 {
+		t.Fatalf("Expected three zones including the unnamed one, got %d %+v", code, usage)
+	}
+	if usage[0].Zone != "" || usage[1].Zone != "example.com" || usage[1].TotalSize != 300 {
+		t.Errorf("Expected zones largest first, got %+v", usage)
+	}
+	if get("/api/zones", "/api/zones?dataset=http_requests", &usage); len(usage) != 2 || usage[0].Zone != "example.com" || usage[0].TotalSize != 100 {
+		t.Errorf("Expected the http_requests batches per zone, got %+v", usage)
+	}
+	var datasets []database.DatasetUsage
+	if get("/api/datasets", "/api/datasets?zone=example.org", &datasets); len(datasets) != 1 || datasets[0].Dataset != "http_requests" {
+		t.Errorf("Expected example.org's datasets only, got %+v", datasets)
+	}
+}
@@ -2,8 +2,30 @@
 class LogPushDashboard {
     constructor() {
         this.charts = {};
-        this.currentTimeRange = 24; // Default to 24 hours
+        // Server-rendered preferences for this browser (see /api/preferences)
+        this.preferences = window.lpePreferences || { default_range_hours: 24, units: '', timezone: '' };
+        // Units sizes are shown in: the saved preference, or the instance's
+        this.units = window.lpeUnits || this.preferences.units || 'decimal';
+        this.currentTimeRange = this.preferences.default_range_hours || 24;
         this.customDateRange = null;
+        // Saved view rendered at /views/{name}, if any (see /api/views)
+        this.view = window.lpeView || null;
+        // Sent with state-changing requests; the API rejects them without it
+        const csrfMeta = document.querySelector('meta[name="csrf-token"]');
+        this.csrfToken = csrfMeta ? csrfMeta.content : '';
+        // Tenant dashboards at /t/{tenant}/ read from the tenant's scoped API
+        this.tenant = window.lpeTenant || '';
+        this.apiBase = this.tenant ? `/t/${encodeURIComponent(this.tenant)}` : '';
+        // Dataset the record charts are filtered to; empty for all
+        this.dataset = '';
+        // Last URL each main chart was drawn from, for its format=table view
+        this.tableUrls = {};
+        // Language the server rendered the page in; tables are fetched in it
+        this.locale = window.lpeLocale || 'en';
+        if (this.view && this.view.start && this.view.end) {
+            this.customDateRange = { start: new Date(this.view.start), end: new Date(this.view.end) };
+            this.currentTimeRange = null;
+        }
         this.init();
     }
 
@@ -11,8 +33,12 @@ class LogPushDashboard {
         console.log('🚀 Initializing LogPush Dashboard');
         this.setupEventListeners();
         this.initializeDatePickers();
+        this.showViewDateRange();
+        await Promise.all([this.loadLayout(), this.loadDatasets()]);
         await this.loadDashboardData();
         this.startAutoRefresh();
+        this.startThroughputRefresh();
+        this.loadRecommendations();
     }
 
     initializeDatePickers() {
@@ -34,6 +60,17 @@ class LogPushDashboard {
         document.getElementById('end-date').value = formatForDatetimeLocal(now);
     }
 
+    showViewDateRange() {
+        // Reflect a saved view's fixed window in the custom range controls
+        if (!this.view || !this.customDateRange) {
+            return;
+        }
+        document.getElementById('nav-time-range').value = 'custom';
+        document.getElementById('custom-range-controls').style.display = 'flex';
+        document.getElementById('start-date').value = this.formatDateForInput(this.customDateRange.start);
+        document.getElementById('end-date').value = this.formatDateForInput(this.customDateRange.end);
+    }
+
     setupEventListeners() {
         // Keep the old refresh button working for compatibility
         const oldRefreshBtn = document.getElementById('refresh-btn');
@@ -48,10 +85,32 @@ class LogPushDashboard {
             this.handleTimeRangeChange(e.target.value);
         });
 
+        document.getElementById('nav-dataset').addEventListener('change', (e) => {
+            this.dataset = e.target.value;
+            this.loadDashboardData();
+        });
+
         document.getElementById('apply-custom').addEventListener('click', () => {
             this.applyCustomDateRange();
         });
 
+        const savePrefsBtn = document.getElementById('save-prefs-btn');
+        if (savePrefsBtn) {
+            savePrefsBtn.addEventListener('click', () => {
+                this.savePreferences();
+            });
+        }
+
+        const saveViewBtn = document.getElementById('save-view-btn');
+        if (saveViewBtn && this.tenant) {
+            // Saved views belong to the whole instance, not a tenant
+            saveViewBtn.style.display = 'none';
+        } else if (saveViewBtn) {
+            saveViewBtn.addEventListener('click', () => {
+                this.saveView();
+            });
+        }
+
         // Handle Enter key in date inputs
         document.getElementById('start-date').addEventListener('keypress', (e) => {
             if (e.key === 'Enter') this.applyCustomDateRange();
@@ -61,6 +120,11 @@ class LogPushDashboard {
             if (e.key === 'Enter') this.applyCustomDateRange();
         });
 
+        // Chart tables are fetched when opened, and refreshed with their chart
+        document.querySelectorAll('details.chart-table').forEach(details => {
+            details.addEventListener('toggle', () => this.loadChartTable(details.dataset.tableFor));
+        });
+
         // Keep old time-range selector working if it exists
         const oldTimeRange = document.getElementById('time-range');
         if (oldTimeRange) {
@@ -89,6 +153,9 @@ class LogPushDashboard {
     }
 
     getTimeRangeLabel(hours) {
+        // The server renders the menu in the page's language
+        const option = document.querySelector(`#nav-time-range option[value="${hours}"]`);
+        if (option) return option.textContent;
         if (hours === 1) return 'Last Hour';
         if (hours === 6) return 'Last 6 Hours';
         if (hours === 24) return 'Last 24 Hours';
@@ -100,11 +167,12 @@ class LogPushDashboard {
     updateChartTitle(timeRangeLabel) {
         const chartTitle = document.getElementById('chart-title');
         if (chartTitle) {
+            const title = chartTitle.dataset.title || 'Ingestion Over Time';
             // For custom ranges that already include formatting, don't add extra parenthesis
             if (timeRangeLabel.includes(' - ')) {
-                chartTitle.textContent = `📈 Ingestion Over Time [${timeRangeLabel}]`;
+                chartTitle.textContent = `📈 ${title} [${timeRangeLabel}]`;
             } else {
-                chartTitle.textContent = `📈 Ingestion Over Time (${timeRangeLabel})`;
+                chartTitle.textContent = `📈 ${title} (${timeRangeLabel})`;
             }
         }
     }
@@ -148,6 +216,70 @@ class LogPushDashboard {
         this.showMessage('Custom date range applied', 'success');
     }
 
+    async savePreferences() {
+        if (!this.currentTimeRange) {
+            this.showMessage('Custom ranges cannot be saved as the default view', 'error');
+            return;
+        }
+        const prefs = { ...this.preferences, default_range_hours: this.currentTimeRange };
+        try {
+            const response = await fetch(`${this.apiBase}/api/preferences`, {
+                method: 'PUT',
+                headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': this.csrfToken },
+                body: JSON.stringify(prefs)
+            });
+            const result = await response.json();
+            if (!result.success) {
+                throw new Error(result.error);
+            }
+            this.preferences = result.data;
+            this.showMessage('Default view saved', 'success');
+        } catch (error) {
+            console.error('Error saving preferences:', error);
+            this.showMessage('Failed to save preferences', 'error');
+        }
+    }
+
+    async saveView() {
+        const name = prompt('Name for this view (letters, digits, - or _):', this.view ? this.view.name : '');
+        if (!name) {
+            return;
+        }
+        const view = {
+            name: name,
+            description: this.view ? this.view.description : '',
+            interval: this.view ? this.view.interval : '',
+            datasets: this.view ? this.view.datasets : [],
+            filters: this.view ? this.view.filters : {}
+        };
+        if (this.customDateRange) {
+            view.start = this.customDateRange.start.toISOString();
+            view.end = this.customDateRange.end.toISOString();
+        } else {
+            view.range_hours = this.currentTimeRange;
+        }
+        try {
+            const response = await fetch('/api/views', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': this.csrfToken },
+                body: JSON.stringify(view)
+            });
+            const result = await response.json();
+            if (!result.success) {
+                throw new Error(result.error);
+            }
+            this.showMessage(`View saved: ${window.location.origin}/views/${result.data.name}`, 'success');
+        } catch (error) {
+            console.error('Error saving view:', error);
+            this.showMessage(`Failed to save view: ${error.message}`, 'error');
+        }
+    }
+
+    formatDateTime(value) {
+        const options = this.preferences.timezone ? { timeZone: this.preferences.timezone } : {};
+        return new Date(value).toLocaleString([], options);
+    }
+
     async loadDashboardData() {
         console.log('📊 Loading dashboard data...');
         this.setLoadingState(true);
@@ -168,7 +300,9 @@ class LogPushDashboard {
                 this.loadStats(),
                 this.loadTimeSeriesData(),
                 this.loadRecentLogs(),
-                this.loadSizeBreakdown()
+                this.loadSizeBreakdown(),
+                this.loadJobHealth(),
+                this.loadWidgets()
             ]);
             
             this.updateLastRefresh();
@@ -197,7 +331,7 @@ class LogPushDashboard {
             url += `?hours=${this.currentTimeRange}`;
         }
         
-        const response = await fetch(url);
+        const response = await fetch(this.datasetUrl(url));
         const result = await response.json();
         
         if (result.success) {
@@ -208,39 +342,107 @@ class LogPushDashboard {
     }
 
     async loadTimeSeriesData(hours = null) {
-        let url = '/api/charts/timeseries';
+        const url = '/api/charts/timeseries';
         
         // Use current state to determine parameters
+        const rangeHours = hours || this.currentTimeRange;
+        const minuteView = this.view && this.view.interval === 'minute' && rangeHours <= 48;
+        if (!this.customDateRange && (rangeHours === 1 || minuteView)) {
+            // The last hour (or a minute-resolution view) is served from per-minute aggregates
+            return this.loadMinuteSeriesData(rangeHours * 60);
+        }
+        let query;
         if (this.customDateRange) {
-            // For custom date ranges, calculate hours and let the API filter
-            const diffHours = Math.ceil((this.customDateRange.end - this.customDateRange.start) / (1000 * 60 * 60));
-            const maxHours = Math.min(diffHours * 2, 8760); // Get a bit more data to ensure coverage
-            url += `?hours=${maxHours}`;
+            // The API picks a bucket size suited to the custom range
+            query = new URLSearchParams({
+                start: this.customDateRange.start.toISOString(),
+                end: this.customDateRange.end.toISOString()
+            }).toString();
         } else {
             const timeRange = hours || this.currentTimeRange || 24;
-            url += `?hours=${timeRange}`;
+            query = `hours=${timeRange}`;
         }
         
-        const response = await fetch(url);
+        this.tableUrls.timeseries = this.datasetUrl(`${url}?${query}`);
+        const [response, annotations] = await Promise.all([
+            fetch(this.tableUrls.timeseries),
+            this.loadAnnotations(query)
+        ]);
         const result = await response.json();
         
         if (result.success) {
-            let data = result.data;
-            
-            // If using custom date range, filter the data client-side
-            if (this.customDateRange) {
-                data = result.data.filter(point => {
-                    const pointDate = new Date(point.timestamp);
-                    return pointDate >= this.customDateRange.start && pointDate <= this.customDateRange.end;
-                });
-            }
-            
-            this.updateTimeSeriesChart(data);
+            this.updateTimeSeriesChart(result.data, response.headers.get('X-Series-Interval'), annotations);
+            this.loadChartTable('timeseries');
         } else {
             throw new Error(result.error);
         }
     }
 
+    async loadMinuteSeriesData(minutes = 60) {
+        this.tableUrls.timeseries = this.datasetUrl(`/api/charts/minutes?minutes=${minutes}`);
+        const [response, annotations] = await Promise.all([
+            fetch(this.tableUrls.timeseries),
+            this.loadAnnotations(`last=${minutes}m`)
+        ]);
+        const result = await response.json();
+        
+        if (result.success) {
+            this.updateTimeSeriesChart(result.data.map(point => ({
+                timestamp: point.timestamp,
+                count: point.batches,
+                records: point.records,
+                total_size: point.total_size
+            })), null, annotations);
+            this.loadChartTable('timeseries');
+        } else {
+            throw new Error(result.error);
+        }
+    }
+
+    async loadDatasets() {
+        // Batches are tagged with the dataset named at ingestion; the
+        // selector only appears once more than one has been seen
+        try {
+            const response = await fetch(`${this.apiBase}/api/datasets`);
+            const result = await response.json();
+            const named = result.success ? result.data.filter(d => d.dataset) : [];
+            const select = document.getElementById('nav-dataset');
+            named.forEach(d => {
+                select.appendChild(new Option(d.dataset, d.dataset));
+            });
+            select.parentElement.style.display = named.length > 1 ? '' : 'none';
+        } catch (error) {
+            console.error('Error loading datasets:', error);
+        }
+    }
+
+    datasetUrl(url) {
+        // Record endpoints read only the selected dataset's batches; others
+        // ignore the parameter
+        if (!this.dataset) {
+            return this.apiBase + url;
+        }
+        const separator = url.includes('?') ? '&' : '?';
+        return `${this.apiBase}${url}${separator}dataset=${encodeURIComponent(this.dataset)}`;
+    }
+
+    async loadAnnotations(query) {
+        // Annotations such as Cloudflare incidents are optional; an error
+        // here must not fail the chart. They belong to the whole instance,
+        // so tenant dashboards skip them.
+        if (this.tenant) {
+            return [];
+        }
+        try {
+            const response = await fetch(`/api/annotations?${query}`);
+            const result = await response.json();
+            return result.success ? result.data : [];
+        } catch (error) {
+            console.error('Error loading annotations:', error);
+            return [];
+        }
+    }
+
     async loadRecentLogs() {
         let url = '/api/logs/recent';
         
@@ -255,7 +457,7 @@ class LogPushDashboard {
             url += `?hours=${this.currentTimeRange}`;
         }
         
-        const response = await fetch(url);
+        const response = await fetch(this.datasetUrl(url));
         const result = await response.json();
         
         if (result.success) {
@@ -281,26 +483,281 @@ class LogPushDashboard {
             url += `?hours=${this.currentTimeRange}`;
         }
         
-        const response = await fetch(url);
+        // Range labels follow the instance's units unless the user chose
+        // their own
+        if (this.preferences.units) {
+            url += `${url.includes('?') ? '&' : '?'}units=${this.preferences.units}`;
+        }
+        this.tableUrls.breakdown = this.datasetUrl(url);
+        const response = await fetch(this.tableUrls.breakdown);
         const result = await response.json();
         
         if (result.success) {
             this.updateSizeDistributionChart(result.data);
             this.updateBreakdownTable(result.data);
+            this.loadChartTable('breakdown');
         } else {
             throw new Error(result.error);
         }
     }
 
+    async loadJobHealth() {
+        // Logpush job health is optional; an error here must not fail the dashboard.
+        // Jobs belong to the whole instance, so tenant dashboards skip it.
+        if (this.tenant) {
+            return;
+        }
+        try {
+            const response = await fetch('/api/cloudflare/jobs');
+            const result = await response.json();
+            if (result.success) {
+                this.updateJobHealth(result.data);
+            }
+        } catch (error) {
+            console.error('Error loading Logpush job health:', error);
+        }
+    }
+
+    updateJobHealth(jobs) {
+        const container = document.getElementById('job-health');
+        const failing = jobs.filter(job => job.health === 'failing');
+        container.innerHTML = '';
+        container.style.display = failing.length ? 'block' : 'none';
+
+        failing.forEach(job => {
+            const item = document.createElement('p');
+            const since = job.last_error ? ` since ${this.formatDateTime(job.last_error)}` : '';
+            item.textContent = `⚠️ Logpush job ${job.name} (${job.dataset}) is failing${since}: ${job.error_message || 'no error message'}`;
+            container.appendChild(item);
+        });
+    }
+
+    async loadThroughput() {
+        // Rates are instance-wide and served from memory, so they are polled
+        // more often than the rest of the dashboard; tenant dashboards skip them
+        try {
+            const response = await fetch('/api/stats/rates');
+            const result = await response.json();
+            if (result.success) {
+                this.updateThroughput(result.data.windows);
+            }
+        } catch (error) {
+            console.error('Error loading throughput:', error);
+        }
+    }
+
+    updateThroughput(windows) {
+        const rate = (name) => windows.find(w => w.window === name) || { bytes_per_second: 0 };
+        const perSecond = (w) => this.formatBytes(Math.round(w.bytes_per_second)) + '/s';
+        document.getElementById('current-throughput').textContent = perSecond(rate('1m'));
+        document.getElementById('throughput-detail').textContent =
+            `5m avg ${perSecond(rate('5m'))} · 1h avg ${perSecond(rate('1h'))}`;
+    }
+
+    async loadRecommendations() {
+        // Recommendations cover a week of instance-wide data and change slowly,
+        // so they are loaded once per page; tenant dashboards skip them
+        if (this.tenant) {
+            return;
+        }
+        try {
+            const response = await fetch('/api/recommendations?limit=5');
+            const result = await response.json();
+            if (result.success) {
+                this.updateRecommendations(result.data.recommendations);
+            }
+        } catch (error) {
+            console.error('Error loading recommendations:', error);
+        }
+    }
+
+    async loadLayout() {
+        // Widgets beyond the built-in cards and charts are described by the
+        // server, so new metrics show up without changes here
+        this.widgets = [];
+        this.widgetCharts = {};
+        try {
+            const response = await fetch(`${this.apiBase}/api/dashboard/layout`);
+            const result = await response.json();
+            if (result.success) {
+                this.widgets = result.data.widgets;
+                this.widgets.forEach(widget => this.renderWidget(widget));
+            }
+        } catch (error) {
+            console.error('Error loading dashboard layout:', error);
+        }
+    }
+
+    renderWidget(widget) {
+        let element;
+        if (widget.type === 'stat') {
+            element = document.createElement('div');
+            element.className = 'stat-card';
+            element.innerHTML = '<h3></h3><span></span>';
+            document.querySelector('.stats-grid').appendChild(element);
+        } else if (widget.type === 'chart' || widget.type === 'table') {
+            element = document.createElement('div');
+            element.className = 'chart-container';
+            element.innerHTML = widget.type === 'chart'
+                ? '<h2></h2><canvas></canvas>'
+                : '<h2></h2><div class="table-container"><table><thead><tr></tr></thead><tbody></tbody></table></div>';
+            document.getElementById('layout-widgets').appendChild(element);
+            if (widget.type === 'table') {
+                const header = element.querySelector('thead tr');
+                widget.columns.forEach(column => {
+                    header.appendChild(document.createElement('th')).textContent = column.label;
+                });
+            }
+        } else {
+            console.warn(`Skipping widget ${widget.id} of unknown type ${widget.type}`);
+            return;
+        }
+        element.id = `widget-${widget.id}`;
+        element.style.display = 'none';
+        element.querySelector('h2, h3').textContent = widget.title;
+    }
+
+    async loadWidgets() {
+        await Promise.all((this.widgets || []).map(widget => this.loadWidget(widget)));
+    }
+
+    async loadWidget(widget) {
+        const element = document.getElementById(`widget-${widget.id}`);
+        if (!element) {
+            return;
+        }
+        const params = new URLSearchParams(widget.params || {});
+        if (widget.range) {
+            if (this.customDateRange) {
+                params.set('start', this.customDateRange.start.toISOString());
+                params.set('end', this.customDateRange.end.toISOString());
+            } else if (this.currentTimeRange) {
+                params.set('hours', this.currentTimeRange);
+            }
+        }
+        try {
+            const response = await fetch(this.datasetUrl(`${widget.endpoint}?${params}`));
+            const result = await response.json();
+            // Widgets whose data is unavailable, e.g. behind a disabled
+            // feature flag, stay hidden
+            const value = result.success ? this.widgetField(result.data, widget.field) : undefined;
+            if (value === undefined || value === null) {
+                element.style.display = 'none';
+                return;
+            }
+            element.style.display = '';
+            if (widget.type === 'stat') {
+                element.querySelector('span').textContent = this.formatWidgetValue(value, widget.format, result.data);
+            } else if (widget.type === 'chart') {
+                this.updateWidgetChart(widget, element, value);
+            } else {
+                this.updateWidgetTable(widget, element, value, result.data);
+            }
+        } catch (error) {
+            console.error(`Error loading widget ${widget.id}:`, error);
+        }
+    }
+
+    widgetField(data, path) {
+        if (!path) {
+            return data;
+        }
+        return path.split('.').reduce((value, key) => (value == null ? undefined : value[key]), data);
+    }
+
+    formatWidgetValue(value, format, data) {
+        switch (format) {
+            case 'bytes':
+                return this.formatBytes(Math.round(value));
+            case 'percent':
+                return `${value.toFixed(2)}%`;
+            case 'currency':
+                return `${value.toFixed(2)} ${data.currency || ''}`.trim();
+            case 'datetime':
+                return this.formatDateTime(value);
+            default:
+                return typeof value === 'number' ? value.toLocaleString() : String(value);
+        }
+    }
+
+    updateWidgetChart(widget, element, rows) {
+        const colors = ['#667eea', '#764ba2', '#f093fb', '#f5576c', '#4facfe', '#43e97b'];
+        if (this.widgetCharts[widget.id]) {
+            this.widgetCharts[widget.id].destroy();
+        }
+        this.widgetCharts[widget.id] = new Chart(element.querySelector('canvas').getContext('2d'), {
+            type: 'line',
+            data: {
+                labels: rows.map(row => isNaN(Date.parse(row[widget.x])) ? row[widget.x] : this.formatDateTime(row[widget.x])),
+                datasets: widget.columns.map((column, i) => ({
+                    label: column.label,
+                    data: rows.map(row => row[column.field]),
+                    borderColor: colors[i % colors.length],
+                    tension: 0.4
+                }))
+            },
+            options: {
+                responsive: true,
+                plugins: {
+                    legend: {
+                        position: 'top',
+                    }
+                }
+            }
+        });
+    }
+
+    updateWidgetTable(widget, element, rows, data) {
+        const tbody = element.querySelector('tbody');
+        tbody.innerHTML = '';
+        rows.forEach(row => {
+            const tr = tbody.insertRow();
+            widget.columns.forEach(column => {
+                const value = row[column.field];
+                tr.insertCell().textContent = value == null ? '-' : this.formatWidgetValue(value, column.format, data);
+            });
+        });
+    }
+
+    updateRecommendations(recommendations) {
+        const tbody = document.getElementById('recommendations-tbody');
+        tbody.innerHTML = '';
+        document.getElementById('recommendations-section').style.display = recommendations.length ? 'block' : 'none';
+
+        recommendations.forEach(rec => {
+            const row = tbody.insertRow();
+            const savings = this.formatBytes(rec.saved_bytes) +
+                (rec.currency ? ` (${rec.saved_cost.toFixed(2)} ${rec.currency})` : '');
+            [rec.rank, rec.summary, rec.evidence, savings].forEach(value => {
+                row.insertCell().textContent = value;
+            });
+        });
+    }
+
     updateStatsCards(stats) {
         document.getElementById('total-records').textContent = stats.total_records?.toLocaleString() || '0';
+        document.getElementById('record-count').textContent = stats.record_count?.toLocaleString() || '0';
+        document.getElementById('record-detail').textContent = stats.record_count ?
+            `${Math.round(stats.avg_records_per_batch).toLocaleString()} per batch · ${this.formatBytes(Math.round(stats.avg_record_size))} each` : '';
         document.getElementById('total-size').textContent = this.formatBytes(stats.total_size || 0);
         document.getElementById('average-size').textContent = this.formatBytes(Math.round(stats.average_size || 0));
         document.getElementById('last-updated').textContent = stats.last_updated ? 
-            new Date(stats.last_updated).toLocaleString() : 'Never';
+            this.formatDateTime(stats.last_updated) : 'Never';
     }
 
-    updateTimeSeriesChart(data) {
+    formatBucketLabel(timestamp, interval) {
+        // Day buckets are labelled by date, hour buckets over several days by date and time
+        const date = new Date(timestamp);
+        if (interval === 'day') {
+            return date.toLocaleDateString();
+        }
+        if (interval === 'hour' && (this.customDateRange || this.currentTimeRange > 24)) {
+            return date.toLocaleString([], { month: 'short', day: 'numeric', hour: '2-digit', minute: '2-digit' });
+        }
+        return date.toLocaleTimeString();
+    }
+
+    updateTimeSeriesChart(data, interval = null, annotations = []) {
         const ctx = document.getElementById('timeSeriesChart').getContext('2d');
         
         if (this.charts.timeSeries) {
@@ -312,8 +769,9 @@ class LogPushDashboard {
 
         this.charts.timeSeries = new Chart(ctx, {
             type: 'line',
+            plugins: [this.annotationBands(data, annotations)],
             data: {
-                labels: data.map(point => new Date(point.timestamp).toLocaleTimeString()),
+                labels: data.map(point => this.formatBucketLabel(point.timestamp, interval)),
                 datasets: [{
                     label: 'Log Count',
                     data: data.map(point => point.count),
@@ -321,6 +779,13 @@ class LogPushDashboard {
                     backgroundColor: 'rgba(102, 126, 234, 0.1)',
                     tension: 0.4,
                     fill: true
+                }, {
+                    // Logpush bills and throttles on records as well as bytes
+                    label: 'Record Count',
+                    data: data.map(point => point.records || 0),
+                    borderColor: '#38a169',
+                    backgroundColor: 'rgba(56, 161, 105, 0.1)',
+                    tension: 0.4
                 }, {
                     label: 'Total Size (MB)',
                     data: data.map(point => point.total_size / (1024 * 1024)),
@@ -344,7 +809,7 @@ class LogPushDashboard {
                         position: 'left',
                         title: {
                             display: true,
-                            text: 'Log Count'
+                            text: 'Batches / Records'
                         }
                     },
                     y1: {
@@ -364,6 +829,41 @@ class LogPushDashboard {
         });
     }
 
+    annotationBands(data, annotations) {
+        // Shades the buckets each annotated period overlaps, so a delivery
+        // gap during a Cloudflare incident stands out from a local outage
+        const times = data.map(point => new Date(point.timestamp).getTime());
+        return {
+            id: 'annotationBands',
+            beforeDatasetsDraw: (chart) => {
+                if (!annotations.length || times.length < 2) {
+                    return;
+                }
+                const { ctx, chartArea, scales } = chart;
+                const step = times[1] - times[0];
+                const half = chartArea.width / times.length / 2;
+                ctx.save();
+                annotations.forEach(annotation => {
+                    const start = new Date(annotation.started_at).getTime();
+                    const end = annotation.ended_at ? new Date(annotation.ended_at).getTime() : Date.now();
+                    const first = times.findIndex(t => t + step > start);
+                    const last = times.findLastIndex(t => t <= end);
+                    if (first === -1 || last < first) {
+                        return;
+                    }
+                    const left = Math.max(chartArea.left, scales.x.getPixelForValue(first) - half);
+                    const right = Math.min(chartArea.right, scales.x.getPixelForValue(last) + half);
+                    ctx.fillStyle = 'rgba(229, 62, 62, 0.12)';
+                    ctx.fillRect(left, chartArea.top, right - left, chartArea.bottom - chartArea.top);
+                    ctx.fillStyle = '#c53030';
+                    ctx.font = '11px sans-serif';
+                    ctx.fillText(annotation.title, left + 4, chartArea.top + 12, Math.max(right - left - 8, 40));
+                });
+                ctx.restore();
+            }
+        };
+    }
+
     updateSizeDistributionChart(data) {
         const ctx = document.getElementById('sizeDistributionChart').getContext('2d');
         
@@ -419,7 +919,7 @@ class LogPushDashboard {
             const row = tbody.insertRow();
             row.innerHTML = `
                 <td>${log.ID}</td>
-                <td>${new Date(log.Timestamp).toLocaleString()}</td>
+                <td>${this.formatDateTime(log.Timestamp)}</td>
                 <td>${log.Filesize.toLocaleString()}</td>
                 <td>${this.formatBytes(log.Filesize)}</td>
             `;
@@ -449,10 +949,54 @@ class LogPushDashboard {
         });
     }
 
+    async loadChartTable(name) {
+        // Only open tables are fetched; the API formats the values in the
+        // same units as the charts
+        const details = document.querySelector(`details.chart-table[data-table-for="${name}"]`);
+        const url = this.tableUrls[name];
+        if (!details || !details.open || !url) {
+            return;
+        }
+        const separator = url.includes('?') ? '&' : '?';
+        try {
+            const response = await fetch(`${url}${separator}format=table&units=${this.units}&lang=${this.locale}`);
+            const result = await response.json();
+            if (result.success) {
+                this.renderChartTable(details.querySelector('table'), result.data);
+            }
+        } catch (error) {
+            console.error(`Failed to load ${name} table:`, error);
+        }
+    }
+
+    renderChartTable(element, table) {
+        element.innerHTML = '';
+        element.createCaption().textContent = table.caption;
+        const header = element.createTHead().insertRow();
+        table.columns.forEach(column => {
+            const th = document.createElement('th');
+            th.scope = 'col';
+            th.textContent = column;
+            header.appendChild(th);
+        });
+        const tbody = element.createTBody();
+        table.rows.forEach(row => {
+            const tr = tbody.insertRow();
+            const th = document.createElement('th');
+            th.scope = 'row';
+            th.textContent = row.label;
+            tr.appendChild(th);
+            row.cells.forEach(cell => {
+                tr.insertCell().textContent = cell;
+            });
+        });
+    }
+
     formatBytes(bytes) {
         if (bytes === 0) return '0 B';
-        const k = 1024;
-        const sizes = ['B', 'KB', 'MB', 'GB', 'TB'];
+        const decimal = this.units === 'decimal';
+        const k = decimal ? 1000 : 1024;
+        const sizes = decimal ? ['B', 'KB', 'MB', 'GB', 'TB'] : ['B', 'KiB', 'MiB', 'GiB', 'TiB'];
         const i = Math.floor(Math.log(bytes) / Math.log(k));
         return parseFloat((bytes / Math.pow(k, i)).toFixed(2)) + ' ' + sizes[i];
     }
@@ -521,6 +1065,15 @@ class LogPushDashboard {
         
         console.log('⏰ Auto-refresh enabled (30s interval)');
     }
+
+    startThroughputRefresh() {
+        if (this.tenant) {
+            document.getElementById('throughput-card').style.display = 'none';
+            return;
+        }
+        this.loadThroughput();
+        setInterval(() => this.loadThroughput(), 5000);
+    }
 }
 
 // Initialize dashboard when DOM is loaded
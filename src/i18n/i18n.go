@@ -0,0 +1,206 @@
+// Package i18n translates the text LogpushEstimator renders on the server
+// (the dashboard template, chart tables and report headings) and formats
+// numbers, percentages and byte sizes the way each locale writes them.
+//
+// Messages are looked up by their English text, so untranslated strings
+// and unsupported locales fall back to English rather than to a key.
+//
+// # Locale Selection
+//
+// A locale saved in the dashboard preferences wins; otherwise the
+// Accept-Language header is negotiated against the supported locales:
+//
+//	p := i18n.For(i18n.Negotiate(r.Header.Get("Accept-Language")))
+//	p.T("Total Records")          // "Datensätze gesamt" for de
+//	p.Bytes(1536, false)          // "1,5 KiB" for de
+//	p.T("Last %d Hours", 48)      // "Letzte 48 Stunden" for de
+package i18n
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default is the locale used when no supported locale is requested.
+const Default = "en"
+
+// format holds how a locale writes numbers.
+type format struct {
+	decimal      string   // Decimal separator
+	group        string   // Thousands separator
+	percentSpace bool     // Whether a space precedes the percent sign
+	binaryUnits  []string // Byte units for powers of 1024
+	decimalUnits []string // Byte units for powers of 1000
+}
+
+var (
+	englishBinary = []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	englishSI     = []string{"B", "KB", "MB", "GB", "TB"}
+)
+
+// formats lists every supported locale and its number format.
+var formats = map[string]format{
+	"en": {decimal: ".", group: ",", binaryUnits: englishBinary, decimalUnits: englishSI},
+	"de": {decimal: ",", group: ".", percentSpace: true, binaryUnits: englishBinary, decimalUnits: englishSI},
+	"es": {decimal: ",", group: ".", percentSpace: true, binaryUnits: englishBinary, decimalUnits: englishSI},
+	"fr": {
+		decimal: ",", group: "\u202f", percentSpace: true,
+		binaryUnits:  []string{"o", "Kio", "Mio", "Gio", "Tio"},
+		decimalUnits: []string{"o", "Ko", "Mo", "Go", "To"},
+	},
+}
+
+// Supported returns the supported locales, sorted.
+func Supported() []string {
+	locales := make([]string, 0, len(formats))
+	for l := range formats {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Valid reports whether locale is supported.
+func Valid(locale string) bool {
+	_, ok := formats[locale]
+	return ok
+}
+
+// Negotiate picks the supported locale the client prefers most from an
+// Accept-Language header such as "de-CH, de;q=0.9, en;q=0.8", matching on
+// the primary language. It returns Default when nothing matches.
+//
+// Parameters:
+//   - header: Accept-Language header value, possibly empty
+//
+// Returns:
+//   - string: Supported locale to render in
+func Negotiate(header string) string {
+	best, bestQ := Default, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		// Earlier entries win ties, as clients list them by preference
+		if Valid(lang) && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// Printer translates messages and formats numbers for one locale.
+type Printer struct {
+	locale   string
+	messages map[string]string
+	format   format
+}
+
+// For returns the Printer for locale, or for Default when locale is not
+// supported.
+func For(locale string) *Printer {
+	if !Valid(locale) {
+		locale = Default
+	}
+	return &Printer{locale: locale, messages: catalogs[locale], format: formats[locale]}
+}
+
+// Locale returns the locale p renders in.
+func (p *Printer) Locale() string {
+	return p.locale
+}
+
+// T translates msg, the English text, and formats the translation with args
+// as fmt.Sprintf does when any are given. Numeric args are formatted for
+// the locale.
+func (p *Printer) T(msg string, args ...any) string {
+	if translated, ok := p.messages[msg]; ok {
+		msg = translated
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	args = slices.Clone(args)
+	for i, arg := range args {
+		switch n := arg.(type) {
+		case int:
+			args[i] = p.Number(int64(n))
+		case int64:
+			args[i] = p.Number(n)
+		}
+	}
+	return fmt.Sprintf(strings.ReplaceAll(msg, "%d", "%s"), args...)
+}
+
+// Number formats n with the locale's thousands separator, e.g. "12,345".
+func (p *Printer) Number(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if len(digits) <= 3 {
+		return sign + digits
+	}
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(p.format.group)
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String()
+}
+
+// Decimal formats v rounded to at most places decimal places, dropping
+// trailing zeros, with the locale's decimal separator.
+func (p *Printer) Decimal(v float64, places int) string {
+	scale := math.Pow(10, float64(places))
+	whole, frac, _ := strings.Cut(strconv.FormatFloat(math.Round(v*scale)/scale, 'f', -1, 64), ".")
+	n, _ := strconv.ParseInt(whole, 10, 64)
+	out := p.Number(n)
+	if n == 0 && strings.HasPrefix(whole, "-") {
+		out = "-" + out
+	}
+	if frac != "" {
+		out += p.format.decimal + frac
+	}
+	return out
+}
+
+// Percent formats a percentage with one decimal place, e.g. "12.5%" or
+// "12,5 %".
+func (p *Printer) Percent(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 1, 64)
+	s = strings.Replace(s, ".", p.format.decimal, 1)
+	if p.format.percentSpace {
+		return s + " %"
+	}
+	return s + "%"
+}
+
+// Bytes formats n as a size the way the dashboard does, in binary units
+// (KiB, MiB) or, with decimal, in SI units (KB, MB), to two decimal places.
+func (p *Printer) Bytes(n int64, decimal bool) string {
+	k, units := 1024.0, p.format.binaryUnits
+	if decimal {
+		k, units = 1000, p.format.decimalUnits
+	}
+	v, i := float64(n), 0
+	for math.Abs(v) >= k && i < len(units)-1 {
+		v /= k
+		i++
+	}
+	return p.Decimal(v, 2) + " " + units[i]
+}
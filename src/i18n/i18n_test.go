@@ -0,0 +1,72 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"de-DE,de;q=0.9,en;q=0.8", "de"},
+		{"ja, fr-CA;q=0.5, es;q=0.7", "es"},
+		{"en;q=0.2, fr;q=0.9", "fr"},
+		{"fr;q=0.5, de;q=0.5", "fr"},
+		{"pt-BR", "en"},
+		{"de;q=bogus", "en"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestPrinterFormats(t *testing.T) {
+	tests := []struct {
+		locale string
+		got    func(p *Printer) string
+		want   string
+	}{
+		{"en", func(p *Printer) string { return p.Number(1234567) }, "1,234,567"},
+		{"de", func(p *Printer) string { return p.Number(-1234567) }, "-1.234.567"},
+		{"fr", func(p *Printer) string { return p.Number(12345) }, "12 345"},
+		{"en", func(p *Printer) string { return p.Number(999) }, "999"},
+		{"en", func(p *Printer) string { return p.Bytes(0, false) }, "0 B"},
+		{"en", func(p *Printer) string { return p.Bytes(1536, false) }, "1.5 KiB"},
+		{"en", func(p *Printer) string { return p.Bytes(1536, true) }, "1.54 KB"},
+		{"de", func(p *Printer) string { return p.Bytes(5*1024*1024, false) }, "5 MiB"},
+		{"fr", func(p *Printer) string { return p.Bytes(1536, true) }, "1,54 Ko"},
+		{"de", func(p *Printer) string { return p.Decimal(1234.5, 2) }, "1.234,5"},
+		{"en", func(p *Printer) string { return p.Percent(12.34) }, "12.3%"},
+		{"es", func(p *Printer) string { return p.Percent(12.34) }, "12,3 %"},
+		{"de", func(p *Printer) string { return p.T("Last %d Hours", 48) }, "Letzte 48 Stunden"},
+		{"en", func(p *Printer) string { return p.T("Last %d Hours", 8760) }, "Last 8,760 Hours"},
+		{"fr", func(p *Printer) string { return p.T("Not in any catalog") }, "Not in any catalog"},
+		{"xx", func(p *Printer) string { return p.T("Total Records") }, "Total Records"},
+	}
+	for _, tt := range tests {
+		if got := tt.got(For(tt.locale)); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestCatalogsCoverTheSameMessages(t *testing.T) {
+	for _, locale := range Supported() {
+		if locale == Default {
+			continue
+		}
+		if _, ok := catalogs[locale]; !ok {
+			t.Errorf("Locale %s has no catalog", locale)
+		}
+		for msg := range catalogs["de"] {
+			if _, ok := catalogs[locale][msg]; !ok {
+				t.Errorf("Locale %s is missing %q", locale, msg)
+			}
+		}
+		if len(catalogs[locale]) != len(catalogs["de"]) {
+			t.Errorf("Locale %s translates %d messages, de translates %d", locale, len(catalogs[locale]), len(catalogs["de"]))
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package i18n
+
+// catalogs maps each locale to translations of the English messages. English
+// itself needs no catalog. A message missing from a catalog is rendered in
+// English.
+var catalogs = map[string]map[string]string{
+	"de": {
+		"LogpushEstimator Dashboard":              "LogpushEstimator-Dashboard",
+		"Real-time log size ingestion monitoring": "Echtzeitüberwachung der Log-Volumen",
+		"Tenant:":                        "Mandant:",
+		"Saved view:":                    "Gespeicherte Ansicht:",
+		"Refresh":                        "Aktualisieren",
+		"Last:":                          "Zuletzt:",
+		"Time Range:":                    "Zeitraum:",
+		"Custom Range":                   "Benutzerdefiniert",
+		"Dataset:":                       "Datensatztyp:",
+		"All datasets":                   "Alle Datensatztypen",
+		"Save as Default":                "Als Standard speichern",
+		"Save View":                      "Ansicht speichern",
+		"From:":                          "Von:",
+		"To:":                            "Bis:",
+		"Apply":                          "Anwenden",
+		"Total Records":                  "Datensätze gesamt",
+		"Log Lines":                      "Log-Zeilen",
+		"Total Size":                     "Gesamtgröße",
+		"Average Size":                   "Durchschnittsgröße",
+		"Last Updated":                   "Zuletzt aktualisiert",
+		"Current Throughput":             "Aktueller Durchsatz",
+		"Ingestion Over Time":            "Aufnahme im Zeitverlauf",
+		"Show data as a table":           "Daten als Tabelle anzeigen",
+		"View this data as a table":      "Diese Daten als Tabelle ansehen",
+		"File Size Distribution":         "Verteilung der Dateigrößen",
+		"Cost Reduction Recommendations": "Empfehlungen zur Kostensenkung",
+		"Suggestion":                     "Vorschlag",
+		"Based On":                       "Grundlage",
+		"Saves / Month":                  "Ersparnis / Monat",
+		"Recent Log Entries":             "Neueste Log-Einträge",
+		"Timestamp":                      "Zeitstempel",
+		"File Size":                      "Dateigröße",
+		"Size (Human)":                   "Größe (lesbar)",
+		"Size Breakdown Analysis":        "Aufschlüsselung nach Größe",
+		"Size Range":                     "Größenbereich",
+		"Count":                          "Anzahl",
+		"Percentage":                     "Anteil",
+		"Visual":                         "Grafik",
+		"LogpushEstimator - Real-time monitoring dashboard": "LogpushEstimator - Echtzeit-Überwachungsdashboard",
+		"Last refresh:":                    "Letzte Aktualisierung:",
+		"Last Hour":                        "Letzte Stunde",
+		"Last 6 Hours":                     "Letzte 6 Stunden",
+		"Last 24 Hours":                    "Letzte 24 Stunden",
+		"Last 7 Days":                      "Letzte 7 Tage",
+		"Last 30 Days":                     "Letzte 30 Tage",
+		"Last %d Hours":                    "Letzte %d Stunden",
+		"Ingestion over time":              "Aufnahme im Zeitverlauf",
+		"Time (UTC)":                       "Zeit (UTC)",
+		"Records":                          "Datensätze",
+		"Total size":                       "Gesamtgröße",
+		"File size distribution":           "Verteilung der Dateigrößen",
+		"File size":                        "Dateigröße",
+		"Batches":                          "Batches",
+		"Share":                            "Anteil",
+		"Average record size by hour":      "Durchschnittliche Datensatzgröße pro Stunde",
+		"Hour (UTC)":                       "Stunde (UTC)",
+		"Average record size":              "Durchschnittliche Datensatzgröße",
+		"Average record size distribution": "Verteilung der durchschnittlichen Datensatzgröße",
+		"Ingestion per minute":             "Aufnahme pro Minute",
+		"Volume by dimension":              "Volumen nach Dimension",
+		"Minute (UTC)":                     "Minute (UTC)",
+		"No data for this range":           "Keine Daten in diesem Zeitraum",
+		"Month":                            "Monat",
+		"Tenant":                           "Mandant",
+		"Bytes":                            "Bytes",
+		"Requests":                         "Anfragen",
+		"Cost":                             "Kosten",
+		"Currency":                         "Währung",
+	},
+	"es": {
+		"LogpushEstimator Dashboard":              "Panel de LogpushEstimator",
+		"Real-time log size ingestion monitoring": "Supervisión en tiempo real del volumen de logs",
+		"Tenant:":                        "Cliente:",
+		"Saved view:":                    "Vista guardada:",
+		"Refresh":                        "Actualizar",
+		"Last:":                          "Última:",
+		"Time Range:":                    "Periodo:",
+		"Custom Range":                   "Personalizado",
+		"Dataset:":                       "Conjunto de datos:",
+		"All datasets":                   "Todos los conjuntos",
+		"Save as Default":                "Guardar como predeterminado",
+		"Save View":                      "Guardar vista",
+		"From:":                          "Desde:",
+		"To:":                            "Hasta:",
+		"Apply":                          "Aplicar",
+		"Total Records":                  "Registros totales",
+		"Log Lines":                      "Líneas de log",
+		"Total Size":                     "Tamaño total",
+		"Average Size":                   "Tamaño medio",
+		"Last Updated":                   "Última actualización",
+		"Current Throughput":             "Rendimiento actual",
+		"Ingestion Over Time":            "Ingesta a lo largo del tiempo",
+		"Show data as a table":           "Mostrar los datos como tabla",
+		"View this data as a table":      "Ver estos datos como tabla",
+		"File Size Distribution":         "Distribución del tamaño de archivo",
+		"Cost Reduction Recommendations": "Recomendaciones para reducir costes",
+		"Suggestion":                     "Sugerencia",
+		"Based On":                       "Basado en",
+		"Saves / Month":                  "Ahorro / mes",
+		"Recent Log Entries":             "Entradas de log recientes",
+		"Timestamp":                      "Fecha y hora",
+		"File Size":                      "Tamaño de archivo",
+		"Size (Human)":                   "Tamaño (legible)",
+		"Size Breakdown Analysis":        "Desglose por tamaño",
+		"Size Range":                     "Rango de tamaño",
+		"Count":                          "Cantidad",
+		"Percentage":                     "Porcentaje",
+		"Visual":                         "Gráfico",
+		"LogpushEstimator - Real-time monitoring dashboard": "LogpushEstimator - Panel de supervisión en tiempo real",
+		"Last refresh:":                    "Última actualización:",
+		"Last Hour":                        "Última hora",
+		"Last 6 Hours":                     "Últimas 6 horas",
+		"Last 24 Hours":                    "Últimas 24 horas",
+		"Last 7 Days":                      "Últimos 7 días",
+		"Last 30 Days":                     "Últimos 30 días",
+		"Last %d Hours":                    "Últimas %d horas",
+		"Ingestion over time":              "Ingesta a lo largo del tiempo",
+		"Time (UTC)":                       "Hora (UTC)",
+		"Records":                          "Registros",
+		"Total size":                       "Tamaño total",
+		"File size distribution":           "Distribución del tamaño de archivo",
+		"File size":                        "Tamaño de archivo",
+		"Batches":                          "Lotes",
+		"Share":                            "Proporción",
+		"Average record size by hour":      "Tamaño medio de registro por hora",
+		"Hour (UTC)":                       "Hora (UTC)",
+		"Average record size":              "Tamaño medio de registro",
+		"Average record size distribution": "Distribución del tamaño medio de registro",
+		"Ingestion per minute":             "Ingesta por minuto",
+		"Volume by dimension":              "Volumen por dimensión",
+		"Minute (UTC)":                     "Minuto (UTC)",
+		"No data for this range":           "No hay datos en este periodo",
+		"Month":                            "Mes",
+		"Tenant":                           "Cliente",
+		"Bytes":                            "Bytes",
+		"Requests":                         "Solicitudes",
+		"Cost":                             "Coste",
+		"Currency":                         "Moneda",
+	},
+	"fr": {
+		"LogpushEstimator Dashboard":              "Tableau de bord LogpushEstimator",
+		"Real-time log size ingestion monitoring": "Suivi en temps réel du volume de logs ingérés",
+		"Tenant:":                        "Client :",
+		"Saved view:":                    "Vue enregistrée :",
+		"Refresh":                        "Actualiser",
+		"Last:":                          "Dernière :",
+		"Time Range:":                    "Période :",
+		"Custom Range":                   "Personnalisée",
+		"Dataset:":                       "Jeu de données :",
+		"All datasets":                   "Tous les jeux de données",
+		"Save as Default":                "Définir par défaut",
+		"Save View":                      "Enregistrer la vue",
+		"From:":                          "Du :",
+		"To:":                            "Au :",
+		"Apply":                          "Appliquer",
+		"Total Records":                  "Enregistrements",
+		"Log Lines":                      "Lignes de log",
+		"Total Size":                     "Taille totale",
+		"Average Size":                   "Taille moyenne",
+		"Last Updated":                   "Dernière mise à jour",
+		"Current Throughput":             "Débit actuel",
+		"Ingestion Over Time":            "Ingestion dans le temps",
+		"Show data as a table":           "Afficher les données sous forme de tableau",
+		"View this data as a table":      "Voir ces données sous forme de tableau",
+		"File Size Distribution":         "Répartition des tailles de fichier",
+		"Cost Reduction Recommendations": "Recommandations de réduction des coûts",
+		"Suggestion":                     "Suggestion",
+		"Based On":                       "D'après",
+		"Saves / Month":                  "Économie / mois",
+		"Recent Log Entries":             "Entrées de log récentes",
+		"Timestamp":                      "Horodatage",
+		"File Size":                      "Taille du fichier",
+		"Size (Human)":                   "Taille (lisible)",
+		"Size Breakdown Analysis":        "Répartition par taille",
+		"Size Range":                     "Tranche de taille",
+		"Count":                          "Nombre",
+		"Percentage":                     "Pourcentage",
+		"Visual":                         "Graphique",
+		"LogpushEstimator - Real-time monitoring dashboard": "LogpushEstimator - Tableau de bord de suivi en temps réel",
+		"Last refresh:":                    "Dernière actualisation :",
+		"Last Hour":                        "Dernière heure",
+		"Last 6 Hours":                     "6 dernières heures",
+		"Last 24 Hours":                    "24 dernières heures",
+		"Last 7 Days":                      "7 derniers jours",
+		"Last 30 Days":                     "30 derniers jours",
+		"Last %d Hours":                    "%d dernières heures",
+		"Ingestion over time":              "Ingestion dans le temps",
+		"Time (UTC)":                       "Heure (UTC)",
+		"Records":                          "Enregistrements",
+		"Total size":                       "Taille totale",
+		"File size distribution":           "Répartition des tailles de fichier",
+		"File size":                        "Taille du fichier",
+		"Batches":                          "Lots",
+		"Share":                            "Part",
+		"Average record size by hour":      "Taille moyenne des enregistrements par heure",
+		"Hour (UTC)":                       "Heure (UTC)",
+		"Average record size":              "Taille moyenne des enregistrements",
+		"Average record size distribution": "Répartition de la taille moyenne des enregistrements",
+		"Ingestion per minute":             "Ingestion par minute",
+		"Volume by dimension":              "Volume par dimension",
+		"Minute (UTC)":                     "Minute (UTC)",
+		"No data for this range":           "Aucune donnée sur cette période",
+		"Month":                            "Mois",
+		"Tenant":                           "Client",
+		"Bytes":                            "Octets",
+		"Requests":                         "Requêtes",
+		"Cost":                             "Coût",
+		"Currency":                         "Devise",
+	},
+}
@@ -0,0 +1,57 @@
+package ingest
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// TokenHeader carries an ingest token for destinations configured with a
+// custom header, e.g. destination_conf
+// https://estimator.example.com/ingest?header_X-Logpush-Token=<token>.
+const TokenHeader = "X-Logpush-Token"
+
+// TokenParam carries an ingest token as a URL parameter, for destinations
+// configured as https://estimator.example.com/ingest?secret=<token>.
+const TokenParam = "secret"
+
+// Credentials returns the ingest token candidates a request presents, in
+// the forms a Logpush HTTP destination can send them:
+//
+//   - Authorization: Bearer <token>, or the bare token, from
+//     header_Authorization in the destination_conf
+//   - Authorization: Basic with the token as the password (the user name
+//     is ignored)
+//   - the X-Logpush-Token header, from header_X-Logpush-Token
+//   - the ?secret= URL parameter
+//
+// Parameters:
+//   - r: Ingest request
+//
+// Returns:
+//   - []string: Non-empty candidates, to be compared in constant time
+func Credentials(r *http.Request) []string {
+	var out []string
+	if auth := strings.TrimSpace(r.Header.Get("Authorization")); auth != "" {
+		scheme, value, found := strings.Cut(auth, " ")
+		switch {
+		case found && strings.EqualFold(scheme, "Bearer"):
+			out = append(out, strings.TrimSpace(value))
+		case found && strings.EqualFold(scheme, "Basic"):
+			if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value)); err == nil {
+				if _, password, ok := strings.Cut(string(decoded), ":"); ok {
+					out = append(out, password)
+				}
+			}
+		default:
+			out = append(out, auth)
+		}
+	}
+	if v := strings.TrimSpace(r.Header.Get(TokenHeader)); v != "" {
+		out = append(out, v)
+	}
+	if v := r.URL.Query().Get(TokenParam); v != "" {
+		out = append(out, v)
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Label cardinality policies: what happens to a batch whose labels would
+// exceed LabelLimits.
+const (
+	// LabelPolicyMerge stores a new value beyond the limit as
+	// LabelOverflowValue, and drops a new key beyond the limit, so the
+	// batch is still counted
+	LabelPolicyMerge = "merge"
+	// LabelPolicyReject refuses the batch
+	LabelPolicyReject = "reject"
+)
+
+// LabelOverflowValue replaces the values merged under LabelPolicyMerge. It
+// does not count towards a key's limit.
+const LabelOverflowValue = "_other"
+
+// Defaults for LabelLimits.
+const (
+	DefaultMaxLabelKeys   = 32
+	DefaultMaxLabelValues = 100
+)
+
+// ErrLabelCardinality is returned by LabelGuard.Admit for a batch refused
+// under LabelPolicyReject.
+var ErrLabelCardinality = errors.New("label cardinality limit reached")
+
+// LabelLimits bounds how many distinct labels are stored, since every
+// distinct key and value adds to what label-filtered queries scan.
+type LabelLimits struct {
+	MaxKeys   int    `json:"max_keys"`   // Distinct label keys
+	MaxValues int    `json:"max_values"` // Distinct values per key
+	Policy    string `json:"policy"`     // LabelPolicyMerge or LabelPolicyReject
+}
+
+// LabelCardinality reports the distinct values of one label key and how
+// often its limit applied since the process started.
+type LabelCardinality struct {
+	Key      string `json:"key"`      // Label key
+	Values   int    `json:"values"`   // Distinct values stored or admitted, not counting LabelOverflowValue
+	Rejected int64  `json:"rejected"` // Batches refused because of this key
+	Merged   int64  `json:"merged"`   // Batches whose value was merged, or whose label was dropped
+}
+
+// LabelGuard tracks the distinct label keys and values and enforces
+// LabelLimits on the labels of incoming batches. It is safe for concurrent
+// use.
+type LabelGuard struct {
+	mu       sync.Mutex
+	values   map[string]map[string]bool // Distinct values by key
+	rejected map[string]int64           // Batches refused, by key
+	merged   map[string]int64           // Batches merged or dropped, by key
+}
+
+// NewLabelGuard returns a guard that has seen no labels. Seed it with
+// Observe from the labels already stored.
+func NewLabelGuard() *LabelGuard {
+	return &LabelGuard{values: map[string]map[string]bool{}, rejected: map[string]int64{}, merged: map[string]int64{}}
+}
+
+// Observe records a stored label without applying the limits, so labels
+// stored before a restart, or before the limits were lowered, keep their
+// place.
+func (g *LabelGuard) Observe(key, value string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.add(key, value)
+}
+
+// add records key=value. The caller holds mu.
+func (g *LabelGuard) add(key, value string) {
+	if g.values[key] == nil {
+		g.values[key] = map[string]bool{}
+	}
+	g.values[key][value] = true
+}
+
+// Admit applies limits to the labels of a batch and records the labels
+// that are kept. Known keys and values are always kept. Under
+// LabelPolicyReject nothing is recorded for a refused batch.
+//
+// Parameters:
+//   - labels: The batch's labels, as returned by LabelsFromHeader
+//   - limits: Limits to apply; zero fields select the defaults
+//
+// Returns:
+//   - map[string]string: Labels to store; labels itself when unchanged
+//   - error: ErrLabelCardinality, wrapped with the key that exceeds its
+//     limit, when the batch is refused
+func (g *LabelGuard) Admit(labels map[string]string, limits LabelLimits) (map[string]string, error) {
+	if len(labels) == 0 {
+		return labels, nil
+	}
+	maxKeys, maxValues := limits.MaxKeys, limits.MaxValues
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxLabelKeys
+	}
+	if maxValues <= 0 {
+		maxValues = DefaultMaxLabelValues
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Decide every label before recording any, so a refused batch leaves
+	// no trace; keys are visited in order so the outcome is reproducible
+	out, copied := labels, false
+	newKeys := 0
+	for _, k := range keys {
+		v := labels[k]
+		known, seen := g.values[k]
+		values := len(known)
+		if known[LabelOverflowValue] {
+			values--
+		}
+		switch {
+		case seen && (known[v] || v == LabelOverflowValue):
+			continue
+		case !seen && len(g.values)+newKeys < maxKeys:
+			newKeys++
+			continue
+		case seen && values < maxValues:
+			continue
+		}
+		if limits.Policy == LabelPolicyReject {
+			g.rejected[k]++
+			if !seen {
+				return nil, fmt.Errorf("%w: at most %d label keys are allowed, and %q is new", ErrLabelCardinality, maxKeys, k)
+			}
+			return nil, fmt.Errorf("%w: label %q already has %d values", ErrLabelCardinality, k, values)
+		}
+		if !copied {
+			out, copied = make(map[string]string, len(labels)), true
+			for key, value := range labels {
+				out[key] = value
+			}
+		}
+		g.merged[k]++
+		if seen {
+			out[k] = LabelOverflowValue
+		} else {
+			delete(out, k)
+		}
+	}
+	for k, v := range out {
+		g.add(k, v)
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// Forget removes a key's value, or with an empty value every value of the
+// key, after the label was removed from the stored records, so it no longer
+// counts towards the limits.
+func (g *LabelGuard) Forget(key, value string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if value == "" {
+		delete(g.values, key)
+		return
+	}
+	delete(g.values[key], value)
+	if len(g.values[key]) == 0 {
+		delete(g.values, key)
+	}
+}
+
+// Cardinality returns every key that has values or whose limit applied,
+// ordered by key.
+func (g *LabelGuard) Cardinality() []LabelCardinality {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	byKey := map[string]*LabelCardinality{}
+	entry := func(k string) *LabelCardinality {
+		if byKey[k] == nil {
+			byKey[k] = &LabelCardinality{Key: k}
+		}
+		return byKey[k]
+	}
+	for k, values := range g.values {
+		entry(k).Values = len(values)
+		if values[LabelOverflowValue] {
+			entry(k).Values--
+		}
+	}
+	for k, n := range g.rejected {
+		entry(k).Rejected = n
+	}
+	for k, n := range g.merged {
+		entry(k).Merged = n
+	}
+	out := make([]LabelCardinality, 0, len(byKey))
+	for _, c := range byKey {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
@@ -0,0 +1,73 @@
+package ingest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLabelGuardMerge(t *testing.T) {
+	g := NewLabelGuard()
+	g.Observe("env", "prod")
+	limits := LabelLimits{MaxKeys: 2, MaxValues: 2, Policy: LabelPolicyMerge}
+
+	steps := []struct {
+		in, want map[string]string
+	}{
+		{map[string]string{"env": "prod"}, map[string]string{"env": "prod"}},
+		{map[string]string{"env": "dev"}, map[string]string{"env": "dev"}},
+		// A third value is merged; known values and the overflow value stay
+		{map[string]string{"env": "qa"}, map[string]string{"env": LabelOverflowValue}},
+		{map[string]string{"env": "dev"}, map[string]string{"env": "dev"}},
+		{map[string]string{"env": "staging", "team": "edge"}, map[string]string{"env": LabelOverflowValue, "team": "edge"}},
+		// A third key is dropped
+		{map[string]string{"region": "eu", "team": "edge"}, map[string]string{"team": "edge"}},
+		{map[string]string{"region": "us"}, nil},
+	}
+	for i, step := range steps {
+		got, err := g.Admit(step.in, limits)
+		if err != nil || !reflect.DeepEqual(got, step.want) {
+			t.Errorf("Step %d: expected %v, got %v (%v)", i, step.want, got, err)
+		}
+	}
+
+	want := []LabelCardinality{
+		{Key: "env", Values: 2, Merged: 2},
+		{Key: "region", Merged: 2},
+		{Key: "team", Values: 1},
+	}
+	if got := g.Cardinality(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	// Forgotten values make room again
+	g.Forget("env", "dev")
+	if got, _ := g.Admit(map[string]string{"env": "qa"}, limits); got["env"] != "qa" {
+		t.Errorf("Expected a forgotten value to free its place, got %v", got)
+	}
+}
+
+func TestLabelGuardReject(t *testing.T) {
+	g := NewLabelGuard()
+	limits := LabelLimits{MaxKeys: 1, MaxValues: 1, Policy: LabelPolicyReject}
+
+	if _, err := g.Admit(map[string]string{"env": "prod"}, limits); err != nil {
+		t.Fatalf("Expected the first label admitted, got %v", err)
+	}
+	for _, labels := range []map[string]string{{"env": "dev"}, {"env": "prod", "team": "edge"}} {
+		if _, err := g.Admit(labels, limits); !errors.Is(err, ErrLabelCardinality) {
+			t.Errorf("Expected %v refused, got %v", labels, err)
+		}
+	}
+
+	// A refused batch records nothing
+	want := []LabelCardinality{{Key: "env", Values: 1, Rejected: 1}, {Key: "team", Rejected: 1}}
+	if got := g.Cardinality(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	// Defaults apply to zero limits
+	if got, err := g.Admit(map[string]string{"env": "dev"}, LabelLimits{}); err != nil || got["env"] != "dev" {
+		t.Errorf("Expected default limits to admit a second value, got %v (%v)", got, err)
+	}
+}
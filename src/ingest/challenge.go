@@ -0,0 +1,37 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// ownershipChallenge is the payload Cloudflare uploads to an HTTP
+// destination to validate it before creating a Logpush job.
+var ownershipChallenge = []byte(`{"content":"tests"}`)
+
+// IsOwnershipChallenge reports whether body is a Logpush destination
+// validation upload rather than a batch of log records. The challenge is
+// usually gzip-compressed; only small bodies are inspected.
+//
+// Parameters:
+//   - body: Request body as received
+//   - contentEncoding: The request's Content-Encoding header
+//
+// Returns:
+//   - bool: True for the ownership challenge
+func IsOwnershipChallenge(body []byte, contentEncoding string) bool {
+	if len(body) > 256 {
+		return false
+	}
+	if contentEncoding == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return false
+		}
+		if body, err = io.ReadAll(io.LimitReader(zr, 256)); err != nil {
+			return false
+		}
+	}
+	return bytes.Equal(bytes.TrimSpace(body), ownershipChallenge)
+}
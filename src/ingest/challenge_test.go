@@ -0,0 +1,32 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func TestIsOwnershipChallenge(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(`{"content":"tests"}`))
+	zw.Close()
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		want     bool
+	}{
+		{"gzip challenge", gz.Bytes(), "gzip", true},
+		{"plain challenge", []byte("{\"content\":\"tests\"}\n"), "", true},
+		{"gzip body without header", gz.Bytes(), "", false},
+		{"record", []byte(`{"EdgeResponseStatus":200}`), "", false},
+		{"invalid gzip", []byte("not gzip"), "gzip", false},
+	}
+	for _, tt := range tests {
+		if got := IsOwnershipChallenge(tt.body, tt.encoding); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package ingest
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// DatasetHeader names the dataset of a batch when the ?dataset= parameter
+// is absent, for destinations configured with custom headers.
+const DatasetHeader = "X-Logpush-Dataset"
+
+// datasetNamePattern matches Logpush dataset names such as http_requests.
+var datasetNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ValidDataset reports whether name can be stored as a dataset: 1 to 64
+// lowercase letters, digits or underscores, starting with a letter.
+func ValidDataset(name string) bool {
+	return datasetNamePattern.MatchString(name)
+}
+
+// Datasets with a dimension parser.
+const (
+	DatasetHTTPRequests       = "http_requests"
+	DatasetFirewallEvents     = "firewall_events"
+	DatasetWorkersTraceEvents = "workers_trace_events"
+)
+
+// DimensionCount is the number and size of a batch's records sharing one
+// dimension value, e.g. http_requests records with status_class=5xx.
+type DimensionCount struct {
+	Dataset   string // Dataset the records belong to
+	Dimension string // Dimension name, e.g. "status_class"
+	Value     string // Dimension value, e.g. "5xx"
+	Records   int64  // Number of records with this value
+	Bytes     int64  // Total size of those records, excluding line terminators
+}
+
+// StatusClass buckets an HTTP status code as "1xx" through "5xx". Codes
+// outside 100-599 (including 0 for requests without a response) are "other".
+func StatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "other"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// ScaleDimensions multiplies counts in place by factor, to estimate the
+// totals of every batch when only one in factor batches is parsed.
+//
+// Parameters:
+//   - counts: Counts from ExtractDimensions
+//   - factor: Number of batches each parsed batch stands for
+func ScaleDimensions(counts []DimensionCount, factor int64) {
+	for i := range counts {
+		counts[i].Records *= factor
+		counts[i].Bytes *= factor
+	}
+}
+
+// ExtractDimensions parses every record in an NDJSON batch and counts records
+// and bytes per value of each dimension registered for the record's dataset
+// (see RegisterDimension).
+//
+// Parameters:
+//   - dataset: Dataset the batch belongs to, or empty to detect it per record
+//   - body: Raw NDJSON batch
+//
+// Returns:
+//   - []DimensionCount: Counts ordered by dataset, dimension and value; records
+//     that are not JSON or belong to another dataset are skipped
+func ExtractDimensions(dataset string, body []byte) []DimensionCount {
+	type key struct{ dataset, dimension, value string }
+	totals := make(map[key]*DimensionCount)
+	eachRecord(body, func(line []byte) bool {
+		// Top-level values stay raw, so only the fields a dimension reads
+		// are decoded further
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(line, &fields) != nil {
+			return true
+		}
+		recordDataset := dataset
+		if recordDataset == "" {
+			recordDataset = detectDataset(fields)
+		}
+		for _, d := range datasetDimensions(recordDataset) {
+			value := d.value(fields)
+			if value == "" {
+				continue
+			}
+			k := key{recordDataset, d.Name, value}
+			c, ok := totals[k]
+			if !ok {
+				c = &DimensionCount{Dataset: k.dataset, Dimension: k.dimension, Value: k.value}
+				totals[k] = c
+			}
+			c.Records++
+			c.Bytes += int64(len(line))
+		}
+		return true
+	})
+
+	out := make([]DimensionCount, 0, len(totals))
+	for _, c := range totals {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Dataset != out[j].Dataset {
+			return out[i].Dataset < out[j].Dataset
+		}
+		if out[i].Dimension != out[j].Dimension {
+			return out[i].Dimension < out[j].Dimension
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
@@ -0,0 +1,52 @@
+package ingest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractDimensions(t *testing.T) {
+	body := `{"EdgeResponseStatus":200,"ClientRequestHost":"a.example.com"}
+{"EdgeResponseStatus":503}
+{"Action":"block","Source":"firewallManaged"}
+{"ScriptName":"api","Outcome":"ok"}
+not json
+{"EdgeResponseStatus":201}
+`
+	got := ExtractDimensions("", []byte(body))
+	want := []DimensionCount{
+		{Dataset: DatasetFirewallEvents, Dimension: "action", Value: "block", Records: 1, Bytes: 45},
+		{Dataset: DatasetFirewallEvents, Dimension: "source", Value: "firewallManaged", Records: 1, Bytes: 45},
+		{Dataset: DatasetHTTPRequests, Dimension: "status_class", Value: "2xx", Records: 2, Bytes: 88},
+		{Dataset: DatasetHTTPRequests, Dimension: "status_class", Value: "5xx", Records: 1, Bytes: 26},
+		{Dataset: DatasetWorkersTraceEvents, Dimension: "outcome", Value: "ok", Records: 1, Bytes: 35},
+		{Dataset: DatasetWorkersTraceEvents, Dimension: "script", Value: "api", Records: 1, Bytes: 35},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractDimensions() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestExtractDimensionsExplicitDataset(t *testing.T) {
+	// With an explicit dataset, records are not reclassified by their fields
+	got := ExtractDimensions(DatasetFirewallEvents, []byte(`{"EdgeResponseStatus":200}`))
+	if len(got) != 0 {
+		t.Errorf("Expected no firewall dimensions from an HTTP record, got %+v", got)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	for status, want := range map[int]string{0: "other", 101: "1xx", 204: "2xx", 404: "4xx", 599: "5xx", 600: "other"} {
+		if got := StatusClass(status); got != want {
+			t.Errorf("StatusClass(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestScaleDimensions(t *testing.T) {
+	counts := []DimensionCount{{Dataset: DatasetHTTPRequests, Dimension: "status_class", Value: "2xx", Records: 3, Bytes: 120}}
+	ScaleDimensions(counts, 20)
+	if counts[0].Records != 60 || counts[0].Bytes != 2400 {
+		t.Errorf("Expected counts scaled by 20, got %+v", counts[0])
+	}
+}
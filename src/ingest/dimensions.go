@@ -0,0 +1,173 @@
+package ingest
+
+import (
+	"encoding/json"
+	"mime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Dimension describes one value extracted from the records of a dataset and
+// rolled up per hour, e.g. the status class of HTTP requests. Dimensions are
+// registered with RegisterDimension; the rollup table and query endpoints are
+// generic, so adding one needs no schema change.
+type Dimension struct {
+	Name        string `json:"name"`        // Dimension name, e.g. "status_class"
+	Dataset     string `json:"dataset"`     // Dataset whose records carry the field
+	Field       string `json:"field"`       // Top-level record field the value is read from
+	Description string `json:"description"` // Human-readable summary
+
+	// Value converts the raw JSON of Field into the stored value. An empty
+	// result skips the record for this dimension. Nil stores JSON strings
+	// unchanged.
+	Value func(raw json.RawMessage) string `json:"-"`
+}
+
+// datasetMarker is a field whose presence identifies the dataset of a record
+// when a batch does not name one.
+type datasetMarker struct {
+	dataset string
+	field   string
+}
+
+// datasetMarkers are checked in order, so the more specific datasets come
+// first: Workers trace events carry a script name and firewall events an
+// action, and either may also include a response status.
+var datasetMarkers = []datasetMarker{
+	{DatasetWorkersTraceEvents, "ScriptName"},
+	{DatasetFirewallEvents, "Action"},
+	{DatasetHTTPRequests, "EdgeResponseStatus"},
+}
+
+var (
+	dimensionsMu sync.RWMutex
+	dimensions   = map[string][]Dimension{} // By dataset, ordered by name
+)
+
+func init() {
+	for _, d := range []Dimension{
+		{Name: "status_class", Dataset: DatasetHTTPRequests, Field: "EdgeResponseStatus", Description: "Response status class (1xx-5xx, other)", Value: statusClassValue},
+		{Name: "colo", Dataset: DatasetHTTPRequests, Field: "EdgeColoCode", Description: "Cloudflare data center that served the request"},
+		{Name: "content_type", Dataset: DatasetHTTPRequests, Field: "EdgeResponseContentType", Description: "Response media type without parameters", Value: contentTypeValue},
+		{Name: "action", Dataset: DatasetFirewallEvents, Field: "Action", Description: "Action taken by the firewall"},
+		{Name: "source", Dataset: DatasetFirewallEvents, Field: "Source", Description: "Product that triggered the event"},
+		{Name: "script", Dataset: DatasetWorkersTraceEvents, Field: "ScriptName", Description: "Worker script name"},
+		{Name: "outcome", Dataset: DatasetWorkersTraceEvents, Field: "Outcome", Description: "Worker invocation outcome"},
+	} {
+		RegisterDimension(d)
+	}
+}
+
+// RegisterDimension adds a dimension to the parsers, replacing any dimension
+// of the same dataset and name. Records received from then on are counted by
+// it; earlier hours have no values for it.
+//
+// Parameters:
+//   - d: Dimension to register; Name, Dataset and Field are required
+//
+// Returns:
+//   - bool: False if the dimension was not registered because Name, Dataset
+//     or Field is missing or Dataset is not a valid dataset name
+func RegisterDimension(d Dimension) bool {
+	if d.Name == "" || d.Field == "" || !ValidDataset(d.Dataset) {
+		return false
+	}
+	dimensionsMu.Lock()
+	defer dimensionsMu.Unlock()
+	// Copy on write: parsers range over the previous slice without the lock
+	list := append([]Dimension(nil), dimensions[d.Dataset]...)
+	for i := range list {
+		if list[i].Name == d.Name {
+			list[i] = d
+			dimensions[d.Dataset] = list
+			return true
+		}
+	}
+	list = append(list, d)
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	dimensions[d.Dataset] = list
+	return true
+}
+
+// Dimensions returns the registered dimensions ordered by dataset and name.
+func Dimensions() []Dimension {
+	dimensionsMu.RLock()
+	defer dimensionsMu.RUnlock()
+	var out []Dimension
+	for _, list := range dimensions {
+		out = append(out, list...)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Dataset != out[j].Dataset {
+			return out[i].Dataset < out[j].Dataset
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// datasetDimensions returns the dimensions registered for dataset.
+func datasetDimensions(dataset string) []Dimension {
+	dimensionsMu.RLock()
+	defer dimensionsMu.RUnlock()
+	return dimensions[dataset]
+}
+
+// detectDataset infers the dataset of a record from the fields it carries.
+func detectDataset(fields map[string]json.RawMessage) string {
+	for _, m := range datasetMarkers {
+		if raw, ok := fields[m.field]; ok && !emptyJSON(raw) {
+			return m.dataset
+		}
+	}
+	return ""
+}
+
+// value extracts the dimension's value from a decoded record, or "" if the
+// field is absent, null or empty.
+func (d Dimension) value(fields map[string]json.RawMessage) string {
+	raw, ok := fields[d.Field]
+	if !ok || emptyJSON(raw) {
+		return ""
+	}
+	if d.Value != nil {
+		return d.Value(raw)
+	}
+	return stringValue(raw)
+}
+
+// emptyJSON reports whether raw is null or an empty string.
+func emptyJSON(raw json.RawMessage) bool {
+	s := string(raw)
+	return s == "null" || s == `""`
+}
+
+// stringValue returns raw as a string if it is a JSON string, else "".
+func stringValue(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) != nil {
+		return ""
+	}
+	return s
+}
+
+// statusClassValue buckets a numeric status code with StatusClass.
+func statusClassValue(raw json.RawMessage) string {
+	var status int
+	if json.Unmarshal(raw, &status) != nil {
+		return ""
+	}
+	return StatusClass(status)
+}
+
+// contentTypeValue reduces a Content-Type to its lowercased media type, so
+// "text/html; charset=utf-8" and "text/html" share one value.
+func contentTypeValue(raw json.RawMessage) string {
+	s := stringValue(raw)
+	if mediaType, _, err := mime.ParseMediaType(s); err == nil {
+		return mediaType
+	}
+	mediaType, _, _ := strings.Cut(s, ";")
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
@@ -0,0 +1,74 @@
+package ingest
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractDimensionsColoAndContentType(t *testing.T) {
+	body := `{"EdgeResponseStatus":200,"EdgeColoCode":"FRA","EdgeResponseContentType":"text/html; charset=utf-8"}
+{"EdgeResponseStatus":200,"EdgeColoCode":"FRA","EdgeResponseContentType":"TEXT/HTML"}
+{"EdgeResponseStatus":404,"EdgeColoCode":"","EdgeResponseContentType":null}
+`
+	got := map[string]int64{}
+	for _, c := range ExtractDimensions(DatasetHTTPRequests, []byte(body)) {
+		got[c.Dimension+"="+c.Value] = c.Records
+	}
+	want := map[string]int64{
+		"colo=FRA":               2,
+		"content_type=text/html": 2,
+		"status_class=2xx":       2,
+		"status_class=4xx":       1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractDimensions() = %v, want %v", got, want)
+	}
+}
+
+func TestRegisterDimension(t *testing.T) {
+	if RegisterDimension(Dimension{Name: "x", Dataset: "Bad-Name", Field: "X"}) {
+		t.Error("Expected an invalid dataset name to be rejected")
+	}
+	if RegisterDimension(Dimension{Name: "x", Dataset: "test_events"}) {
+		t.Error("Expected a dimension without a field to be rejected")
+	}
+
+	ok := RegisterDimension(Dimension{Name: "host", Dataset: "test_events", Field: "Host", Value: func(raw json.RawMessage) string {
+		return strings.ToUpper(stringValue(raw))
+	}})
+	if !ok {
+		t.Fatal("Expected the dimension to be registered")
+	}
+	got := ExtractDimensions("test_events", []byte(`{"Host":"a.example.com"}`+"\n"+`{"Host":7}`))
+	want := []DimensionCount{{Dataset: "test_events", Dimension: "host", Value: "A.EXAMPLE.COM", Records: 1, Bytes: 24}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractDimensions() = %+v, want %+v", got, want)
+	}
+
+	// Registering the same name again replaces the dimension
+	RegisterDimension(Dimension{Name: "host", Dataset: "test_events", Field: "Host"})
+	var found int
+	for _, d := range Dimensions() {
+		if d.Dataset == "test_events" {
+			found++
+			if d.Value != nil {
+				t.Error("Expected the replacement to drop the value function")
+			}
+		}
+	}
+	if found != 1 {
+		t.Errorf("Expected one test_events dimension, got %d", found)
+	}
+}
+
+func TestDimensionsOrdered(t *testing.T) {
+	dims := Dimensions()
+	for i := 1; i < len(dims); i++ {
+		a, b := dims[i-1], dims[i]
+		if a.Dataset > b.Dataset || (a.Dataset == b.Dataset && a.Name >= b.Name) {
+			t.Errorf("Dimensions() not ordered at %d: %s/%s before %s/%s", i, a.Dataset, a.Name, b.Dataset, b.Name)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// Package ingest analyzes Logpush batch payloads received by LogpushEstimator.
+//
+// Cloudflare Logpush delivers batches as newline-delimited JSON (NDJSON), one
+// log record per line. This package inspects a batch body and derives
+// per-record statistics without retaining any of the payload contents, so the
+// estimator can tell whether volume growth comes from more events or from
+// bigger events.
+//
+// Optional dataset parsers decode the records of known Logpush datasets (HTTP
+// requests, firewall events, Workers trace events) and count them by a few
+// dimensions such as status class or script name. Dimensions are registered
+// per dataset with RegisterDimension, so new ones only need the field they
+// read. Parsing costs CPU on every batch, so callers only run it when enabled.
+//
+// # Usage
+//
+//	stats := ingest.AnalyzeRecords(body)
+//	fmt.Printf("%d records, avg %.1f bytes\n", stats.Count, stats.AvgSize)
+//
+//	for _, d := range ingest.ExtractDimensions("http_requests", body) {
+//		fmt.Printf("%s=%s: %d records\n", d.Dimension, d.Value, d.Records)
+//	}
+package ingest
@@ -0,0 +1,56 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxDecodedBytes bounds the decompressed size of a batch, so a small
+// malicious body cannot expand to exhaust memory. Logpush uploads are far
+// smaller.
+const MaxDecodedBytes = 1 << 30
+
+// Errors returned by Decode.
+var (
+	ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+	ErrDecodedTooLarge     = errors.New("decompressed batch is too large")
+)
+
+// Decode removes a batch's Content-Encoding. Logpush compresses batches
+// with gzip; bodies sent without an encoding, or as "identity", are returned
+// unchanged.
+//
+// Parameters:
+//   - body: Request body as received
+//   - contentEncoding: The request's Content-Encoding header
+//
+// Returns:
+//   - []byte: The decompressed NDJSON batch
+//   - error: ErrUnsupportedEncoding, ErrDecodedTooLarge, or an error for a
+//     corrupt gzip stream
+func Decode(body []byte, contentEncoding string) ([]byte, error) {
+	switch contentEncoding {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedEncoding, contentEncoding)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("read gzip header: %w", err)
+	}
+	defer zr.Close()
+	decoded, err := io.ReadAll(io.LimitReader(zr, MaxDecodedBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("decompress gzip body: %w", err)
+	}
+	if len(decoded) > MaxDecodedBytes {
+		return nil, ErrDecodedTooLarge
+	}
+	return decoded, nil
+}
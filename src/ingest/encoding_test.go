@@ -0,0 +1,37 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	batch := []byte("{\"a\":1}\n{\"a\":2}\n")
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(batch)
+	zw.Close()
+
+	for _, encoding := range []string{"gzip", "x-gzip"} {
+		if got, err := Decode(gz.Bytes(), encoding); err != nil || !bytes.Equal(got, batch) {
+			t.Errorf("%s: expected the batch, got %q (%v)", encoding, got, err)
+		}
+	}
+	for _, encoding := range []string{"", "identity"} {
+		if got, err := Decode(batch, encoding); err != nil || !bytes.Equal(got, batch) {
+			t.Errorf("%q: expected the body unchanged, got %q (%v)", encoding, got, err)
+		}
+	}
+
+	if _, err := Decode(batch, "gzip"); err == nil {
+		t.Error("Expected an error for a body that is not gzip")
+	}
+	if _, err := Decode(gz.Bytes()[:gz.Len()-4], "gzip"); err == nil {
+		t.Error("Expected an error for a truncated gzip stream")
+	}
+	if _, err := Decode(batch, "br"); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding, got %v", err)
+	}
+}
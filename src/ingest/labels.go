@@ -0,0 +1,79 @@
+package ingest
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// LabelHeaderPrefix starts the headers that label a batch: each
+// X-LPE-Label-{key}: {value} header stores the label key=value with the
+// batch, e.g. X-LPE-Label-Env: prod. Keys are case-insensitive and stored
+// in lowercase.
+const LabelHeaderPrefix = "X-Lpe-Label-"
+
+// Bounds on the labels of one batch, so headers cannot grow the stored
+// records without limit.
+const (
+	MaxLabels           = 8  // Labels per batch
+	MaxLabelKeyLength   = 32 // Bytes per key
+	MaxLabelValueLength = 64 // Bytes per value
+)
+
+var (
+	// labelKeyPattern matches lowercase label keys such as env or team_name.
+	labelKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	// labelValuePattern matches label values such as prod, eu-west-1 or
+	// v1.2.3; commas and equals signs separate stored labels.
+	labelValuePattern = regexp.MustCompile(`^[A-Za-z0-9._:/@+-]+$`)
+)
+
+// ValidLabelKey reports whether key can be the key of a label (see
+// ValidLabel).
+func ValidLabelKey(key string) bool {
+	return len(key) <= MaxLabelKeyLength && labelKeyPattern.MatchString(key)
+}
+
+// ValidLabel reports whether key and value can be stored as a label. Keys
+// are 1 to MaxLabelKeyLength lowercase letters, digits, underscores or
+// hyphens starting with a letter or digit. Values are 1 to
+// MaxLabelValueLength letters, digits or any of . _ : / @ + -.
+func ValidLabel(key, value string) bool {
+	return ValidLabelKey(key) && len(value) <= MaxLabelValueLength && labelValuePattern.MatchString(value)
+}
+
+// LabelsFromHeader reads a batch's labels from its X-LPE-Label-* headers.
+//
+// Parameters:
+//   - h: Request headers
+//
+// Returns:
+//   - map[string]string: Labels by lowercase key; nil without label headers
+//   - error: Any label that is invalid (see ValidLabel), repeated, or beyond
+//     the first MaxLabels
+func LabelsFromHeader(h http.Header) (map[string]string, error) {
+	var labels map[string]string
+	for name, values := range h {
+		key, ok := strings.CutPrefix(http.CanonicalHeaderKey(name), LabelHeaderPrefix)
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(key)
+		if len(values) != 1 {
+			return nil, fmt.Errorf("label %q is repeated", key)
+		}
+		value := strings.TrimSpace(values[0])
+		if !ValidLabel(key, value) {
+			return nil, fmt.Errorf("invalid label %q", key)
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[key] = value
+	}
+	if len(labels) > MaxLabels {
+		return nil, fmt.Errorf("at most %d labels are allowed, got %d", MaxLabels, len(labels))
+	}
+	return labels, nil
+}
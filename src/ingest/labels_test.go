@@ -0,0 +1,59 @@
+package ingest
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidLabel(t *testing.T) {
+	for _, l := range [][2]string{{"env", "prod"}, {"team_name", "edge-eu"}, {"cost-center", "v1.2.3"}, {"0", "a:b/c@d+e"}} {
+		if !ValidLabel(l[0], l[1]) {
+			t.Errorf("Expected %s=%s to be a valid label", l[0], l[1])
+		}
+	}
+	for _, l := range [][2]string{{"", "prod"}, {"env", ""}, {"Env", "prod"}, {"_env", "prod"}, {"env", "a,b"}, {"env", "a=b"}, {"env", "two words"},
+		{strings.Repeat("k", 33), "v"}, {"k", strings.Repeat("v", 65)}} {
+		if ValidLabel(l[0], l[1]) {
+			t.Errorf("Expected %q=%q to be rejected", l[0], l[1])
+		}
+	}
+}
+
+func TestLabelsFromHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set("X-LPE-Label-Env", " prod ")
+	h.Set("x-lpe-label-cost-center", "1234")
+	h.Set("X-Logpush-Zone", "example.com")
+	labels, err := LabelsFromHeader(h)
+	if err != nil {
+		t.Fatalf("LabelsFromHeader failed: %v", err)
+	}
+	if want := map[string]string{"env": "prod", "cost-center": "1234"}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("LabelsFromHeader() = %v, want %v", labels, want)
+	}
+	if labels, err := LabelsFromHeader(http.Header{"Content-Type": {"text/plain"}}); labels != nil || err != nil {
+		t.Errorf("Expected no labels without label headers, got %v, %v", labels, err)
+	}
+
+	h = http.Header{}
+	h.Add("X-LPE-Label-Env", "prod")
+	h.Add("X-LPE-Label-Env", "staging")
+	if _, err := LabelsFromHeader(h); err == nil {
+		t.Error("Expected a repeated label to be rejected")
+	}
+	h = http.Header{}
+	h.Set("X-LPE-Label-Env", "a,b")
+	if _, err := LabelsFromHeader(h); err == nil {
+		t.Error("Expected an invalid value to be rejected")
+	}
+	h = http.Header{}
+	for i := 0; i <= MaxLabels; i++ {
+		h.Set(fmt.Sprintf("X-LPE-Label-K%d", i), "v")
+	}
+	if _, err := LabelsFromHeader(h); err == nil {
+		t.Errorf("Expected more than %d labels to be rejected", MaxLabels)
+	}
+}
@@ -0,0 +1,75 @@
+package ingest
+
+import (
+	"errors"
+	"time"
+)
+
+// StoredHeader reports, on a response to POST /ingest/measurements, how
+// many of the posted measurements were stored, so a sender can retry only
+// the rest after a failure.
+const StoredHeader = "X-Measurements-Stored"
+
+// Measurement is everything the estimator stores about one batch. A
+// collector measures batches where they are delivered and forwards these,
+// rather than the batches themselves, to POST /ingest/measurements.
+type Measurement struct {
+	Timestamp        time.Time `json:"timestamp"`                  // When the batch was received; zero for the time it is stored
+	Dataset          string    `json:"dataset,omitempty"`          // Dataset named by the delivery, if any
+	Zone             string    `json:"zone,omitempty"`             // Zone named by the delivery, if any
+	Filesize         int64     `json:"filesize"`                   // Bytes received
+	UncompressedSize int64     `json:"uncompressed_size"`          // Bytes after removing the Content-Encoding
+	Encoding         string    `json:"content_encoding,omitempty"` // Content-Encoding the batch was sent with, e.g. "gzip"
+	RecordCount      int64     `json:"record_count"`               // Records in the batch
+	MinRecordSize    int64     `json:"min_record_size"`            // Smallest record in bytes
+	MaxRecordSize    int64     `json:"max_record_size"`            // Largest record in bytes
+	AvgRecordSize    float64   `json:"avg_record_size"`            // Average record size in bytes
+}
+
+// Measure decodes a batch and measures it as the estimator's ingestion
+// endpoint does.
+//
+// Parameters:
+//   - body: Request body as received
+//   - contentEncoding: The request's Content-Encoding header, lowercased
+//
+// Returns:
+//   - Measurement: Sizes and record statistics of the batch, with no
+//     timestamp, dataset or zone
+//   - error: Any error from Decode
+func Measure(body []byte, contentEncoding string) (Measurement, error) {
+	payload, err := Decode(body, contentEncoding)
+	if err != nil {
+		return Measurement{}, err
+	}
+	if contentEncoding == "identity" {
+		contentEncoding = ""
+	}
+	records := AnalyzeRecords(payload)
+	return Measurement{
+		Filesize:         int64(len(body)),
+		UncompressedSize: int64(len(payload)),
+		Encoding:         contentEncoding,
+		RecordCount:      records.Count,
+		MinRecordSize:    records.MinSize,
+		MaxRecordSize:    records.MaxSize,
+		AvgRecordSize:    records.AvgSize,
+	}, nil
+}
+
+// Validate checks that m describes a batch that could have been received.
+func (m Measurement) Validate() error {
+	switch {
+	case m.Filesize <= 0:
+		return errors.New("filesize must be positive")
+	case m.UncompressedSize < 0 || m.RecordCount < 0 || m.MinRecordSize < 0 || m.MaxRecordSize < m.MinRecordSize || m.AvgRecordSize < 0:
+		return errors.New("sizes and counts cannot be negative, and max_record_size cannot be below min_record_size")
+	case m.Dataset != "" && !ValidDataset(m.Dataset):
+		return errors.New("invalid dataset name")
+	case m.Zone != "" && !ValidZone(m.Zone):
+		return errors.New("invalid zone name")
+	case m.Encoding != "" && m.Encoding != "gzip" && m.Encoding != "x-gzip":
+		return errors.New("content_encoding must be gzip or empty")
+	}
+	return nil
+}
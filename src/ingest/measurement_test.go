@@ -0,0 +1,37 @@
+package ingest
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMeasure(t *testing.T) {
+	batch := []byte("{\"a\":1}\n{\"bb\":22}\n")
+	m, err := Measure(batch, "identity")
+	if err != nil {
+		t.Fatalf("Measure failed: %v", err)
+	}
+	want := Measurement{Filesize: 18, UncompressedSize: 18, RecordCount: 2, MinRecordSize: 7, MaxRecordSize: 9, AvgRecordSize: 8}
+	if m != want {
+		t.Errorf("Expected %+v, got %+v", want, m)
+	}
+	if err := m.Validate(); err != nil {
+		t.Errorf("Expected a measured batch to be valid, got %v", err)
+	}
+	if _, err := Measure(batch, "br"); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding, got %v", err)
+	}
+
+	for _, bad := range []Measurement{
+		{},
+		{Filesize: 10, RecordCount: -1},
+		{Filesize: 10, MinRecordSize: 5, MaxRecordSize: 4},
+		{Filesize: 10, Dataset: "Bad Name"},
+		{Filesize: 10, Zone: "example.com/"},
+		{Filesize: 10, Encoding: "br"},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", bad)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package ingest
+
+import "bytes"
+
+// RecordStats summarizes the records contained in a single batch.
+type RecordStats struct {
+	Count   int64   // Number of non-empty records (lines) in the batch
+	MinSize int64   // Smallest record in bytes, excluding the line terminator
+	MaxSize int64   // Largest record in bytes, excluding the line terminator
+	AvgSize float64 // Average record size in bytes
+}
+
+// AnalyzeRecords splits body into newline-delimited records and computes their
+// count and size distribution. Blank lines are ignored and both "\n" and
+// "\r\n" terminators are accepted. A body without any newline is treated as a
+// single record.
+func AnalyzeRecords(body []byte) RecordStats {
+	var stats RecordStats
+	var total int64
+	eachRecord(body, func(line []byte) bool {
+		size := int64(len(line))
+		if stats.Count == 0 || size < stats.MinSize {
+			stats.MinSize = size
+		}
+		if size > stats.MaxSize {
+			stats.MaxSize = size
+		}
+		total += size
+		stats.Count++
+		return true
+	})
+	if stats.Count > 0 {
+		stats.AvgSize = float64(total) / float64(stats.Count)
+	}
+	return stats
+}
+
+// eachRecord calls fn for every non-blank newline-delimited record in body,
+// with any "\r\n" terminator stripped, until fn returns false.
+func eachRecord(body []byte, fn func(line []byte) bool) {
+	for len(body) > 0 {
+		line := body
+		if i := bytes.IndexByte(body, '\n'); i >= 0 {
+			line = body[:i]
+			body = body[i+1:]
+		} else {
+			body = nil
+		}
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if !fn(line) {
+			return
+		}
+	}
+}
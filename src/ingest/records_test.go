@@ -0,0 +1,26 @@
+package ingest
+
+import "testing"
+
+func TestAnalyzeRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected RecordStats
+	}{
+		{"Empty body", "", RecordStats{}},
+		{"Single record without newline", `{"a":1}`, RecordStats{Count: 1, MinSize: 7, MaxSize: 7, AvgSize: 7}},
+		{"NDJSON batch", "{\"a\":1}\n{\"bb\":22}\n", RecordStats{Count: 2, MinSize: 7, MaxSize: 9, AvgSize: 8}},
+		{"CRLF terminators", "abcd\r\nab\r\n", RecordStats{Count: 2, MinSize: 2, MaxSize: 4, AvgSize: 3}},
+		{"Blank lines ignored", "abc\n\n  \nabc\n", RecordStats{Count: 2, MinSize: 3, MaxSize: 3, AvgSize: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AnalyzeRecords([]byte(tt.body))
+			if got != tt.expected {
+				t.Errorf("AnalyzeRecords(%q) = %+v, want %+v", tt.body, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// RedactedValue replaces the values of redacted fields and text matched by
+// redaction patterns.
+const RedactedValue = "[REDACTED]"
+
+// RedactPattern is a named regular expression whose matches are redacted
+// from string values wherever they appear in a record.
+type RedactPattern struct {
+	Name  string         // Rule name reported in the audit
+	Regex *regexp.Regexp // Expression whose matches are replaced
+}
+
+// Redactions counts the values or matches each rule redacted. Field rules
+// are keyed "field:<name>" and pattern rules "pattern:<name>".
+type Redactions map[string]int
+
+// Total returns the number of redactions across all rules.
+func (r Redactions) Total() int {
+	total := 0
+	for _, n := range r {
+		total += n
+	}
+	return total
+}
+
+// RedactionPolicy is a set of redaction rules. Field rules replace the whole
+// value of matching object keys (case-insensitively, at any depth); pattern
+// rules then replace matching text inside the remaining string values.
+type RedactionPolicy struct {
+	fields   map[string]string // Lower-cased field name to rule key
+	patterns []RedactPattern
+}
+
+// NewRedactionPolicy creates a policy from field names and patterns.
+//
+// Parameters:
+//   - fields: Field names whose values are redacted
+//   - patterns: Patterns redacted from string values
+//
+// Returns:
+//   - *RedactionPolicy: Policy applying both kinds of rule
+func NewRedactionPolicy(fields []string, patterns []RedactPattern) *RedactionPolicy {
+	p := &RedactionPolicy{fields: make(map[string]string, len(fields)), patterns: patterns}
+	for _, f := range fields {
+		p.fields[strings.ToLower(f)] = "field:" + f
+	}
+	return p
+}
+
+// Redact applies the policy to a single JSON record. Numbers and characters
+// such as & are kept as written, but keys are re-encoded in sorted order.
+//
+// Parameters:
+//   - record: A single JSON record
+//
+// Returns:
+//   - []byte: The redacted record
+//   - Redactions: What each rule redacted
+//   - error: If record is not valid JSON
+func (p *RedactionPolicy) Redact(record []byte) ([]byte, Redactions, error) {
+	dec := json.NewDecoder(bytes.NewReader(record))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, nil, err
+	}
+	audit := Redactions{}
+	v = p.redactValue(v, audit)
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, nil, err
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), audit, nil
+}
+
+// RedactText applies only the pattern rules to arbitrary text. It is used
+// for content that can no longer be parsed as JSON, such as truncated
+// samples.
+//
+// Parameters:
+//   - text: Text to redact
+//
+// Returns:
+//   - string: The redacted text
+//   - Redactions: What each pattern redacted
+func (p *RedactionPolicy) RedactText(text string) (string, Redactions) {
+	audit := Redactions{}
+	return p.redactString(text, audit), audit
+}
+
+// redactValue redacts v in place where possible, recording each redaction in
+// audit, and returns the redacted value.
+func (p *RedactionPolicy) redactValue(v any, audit Redactions) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			if rule, ok := p.fields[strings.ToLower(k)]; ok {
+				t[k] = RedactedValue
+				audit[rule]++
+				continue
+			}
+			t[k] = p.redactValue(child, audit)
+		}
+	case []any:
+		for i, child := range t {
+			t[i] = p.redactValue(child, audit)
+		}
+	case string:
+		return p.redactString(t, audit)
+	}
+	return v
+}
+
+// redactString replaces every pattern match in s.
+func (p *RedactionPolicy) redactString(s string, audit Redactions) string {
+	for _, pattern := range p.patterns {
+		if n := len(pattern.Regex.FindAllStringIndex(s, -1)); n > 0 {
+			s = pattern.Regex.ReplaceAllLiteralString(s, RedactedValue)
+			audit["pattern:"+pattern.Name] += n
+		}
+	}
+	return s
+}
+
+// RedactRecord replaces the value of every object key in a JSON record that
+// matches one of fields (case-insensitively), at any depth. It is shorthand
+// for a RedactionPolicy without patterns.
+//
+// Parameters:
+//   - record: A single JSON record
+//   - fields: Field names to redact
+//
+// Returns:
+//   - []byte: The redacted record
+//   - int: Number of values redacted
+//   - error: If record is not valid JSON
+func RedactRecord(record []byte, fields []string) ([]byte, int, error) {
+	out, audit, err := NewRedactionPolicy(fields, nil).Redact(record)
+	return out, audit.Total(), err
+}
@@ -0,0 +1,48 @@
+package ingest
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestRedactRecord(t *testing.T) {
+	record := `{"ClientIP":"192.0.2.1","EdgeResponseStatus":200,"RequestHeaders":{"cookie":"a=b"},"Nested":{"clientip":"192.0.2.2"},"Size":12345678901234567890}`
+	got, count, err := RedactRecord([]byte(record), []string{"ClientIP", "RequestHeaders"})
+	if err != nil {
+		t.Fatalf("Failed to redact: %v", err)
+	}
+	want := `{"ClientIP":"[REDACTED]","EdgeResponseStatus":200,"Nested":{"clientip":"[REDACTED]"},"RequestHeaders":"[REDACTED]","Size":12345678901234567890}`
+	if string(got) != want {
+		t.Errorf("RedactRecord() = %s, want %s", got, want)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 redactions, got %d", count)
+	}
+
+	if _, _, err := RedactRecord([]byte("not json"), nil); err == nil {
+		t.Error("Expected an error for a non-JSON record")
+	}
+}
+
+func TestRedactionPolicyPatterns(t *testing.T) {
+	policy := NewRedactionPolicy([]string{"ClientIP"}, []RedactPattern{
+		{Name: "email", Regex: regexp.MustCompile(`[\w.+-]+@[\w-]+\.[\w.]+`)},
+	})
+	record := `{"ClientIP":"192.0.2.1","ClientRequestURI":"/reset?to=a@example.com&cc=b@example.org","Tags":["c@example.net"],"Status":200}`
+	got, audit, err := policy.Redact([]byte(record))
+	if err != nil {
+		t.Fatalf("Failed to redact: %v", err)
+	}
+	want := `{"ClientIP":"[REDACTED]","ClientRequestURI":"/reset?to=[REDACTED]&cc=[REDACTED]","Status":200,"Tags":["[REDACTED]"]}`
+	if string(got) != want {
+		t.Errorf("Redact() = %s, want %s", got, want)
+	}
+	if audit["field:ClientIP"] != 1 || audit["pattern:email"] != 3 || audit.Total() != 4 {
+		t.Errorf("Unexpected audit %v", audit)
+	}
+
+	text, audit := policy.RedactText(`{"ClientRequestURI":"/?to=a@example.com`)
+	if text != `{"ClientRequestURI":"/?to=[REDACTED]` || audit["pattern:email"] != 1 {
+		t.Errorf("RedactText() = %q, %v", text, audit)
+	}
+}
@@ -0,0 +1,96 @@
+package ingest
+
+import (
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Sampler selects every Nth ingested batch for sampling. It is safe for
+// concurrent use.
+type Sampler struct {
+	batches atomic.Int64
+}
+
+// Next counts a batch and reports whether it is an every-Nth batch. An
+// everyN of zero or less never samples.
+func (s *Sampler) Next(everyN int) bool {
+	n := s.batches.Add(1)
+	return everyN > 0 && n%int64(everyN) == 0
+}
+
+// maxSamplerScopes bounds how many scopes a ScopedSampler counts batches
+// for; beyond it the counts start over, since scopes come from requests.
+const maxSamplerScopes = 1024
+
+// ScopedSampler selects every Nth batch within each scope, such as a
+// tenant, dataset, zone and label combination, so counts scaled by N stand
+// for that scope's batches whatever the mix of traffic across scopes. It is
+// safe for concurrent use.
+type ScopedSampler struct {
+	mu     sync.Mutex
+	scopes map[string]*Sampler
+}
+
+// Next counts a batch of scope and reports whether it is the scope's
+// every-Nth batch. An everyN of zero or less never samples.
+func (s *ScopedSampler) Next(scope string, everyN int) bool {
+	s.mu.Lock()
+	sampler, ok := s.scopes[scope]
+	if !ok {
+		if s.scopes == nil || len(s.scopes) >= maxSamplerScopes {
+			s.scopes = make(map[string]*Sampler)
+		}
+		sampler = &Sampler{}
+		s.scopes[scope] = sampler
+	}
+	s.mu.Unlock()
+	return sampler.Next(everyN)
+}
+
+// Sample is a redacted excerpt of a batch's first record.
+type Sample struct {
+	Content   string     // Redacted record, possibly truncated
+	Redacted  int        // Number of values and matches redacted
+	Rules     Redactions // What each redaction rule redacted
+	Truncated bool       // Whether Content was cut to the size limit
+}
+
+// SampleFirstRecord redacts the first record of an NDJSON batch for storage.
+// Records that are not JSON cannot be redacted field by field, so they are
+// never sampled. Redaction happens before truncation, so a value cut short
+// by the size limit has still been checked against every rule.
+//
+// Parameters:
+//   - body: Raw NDJSON batch
+//   - policy: Redaction rules to apply
+//   - maxBytes: Length the redacted record is truncated to
+//
+// Returns:
+//   - Sample: The redacted sample
+//   - bool: Whether a sample could be taken
+func SampleFirstRecord(body []byte, policy *RedactionPolicy, maxBytes int) (Sample, bool) {
+	var first []byte
+	eachRecord(body, func(line []byte) bool {
+		first = line
+		return false
+	})
+	if first == nil {
+		return Sample{}, false
+	}
+	redacted, audit, err := policy.Redact(first)
+	if err != nil {
+		return Sample{}, false
+	}
+	sample := Sample{Content: string(redacted), Redacted: audit.Total(), Rules: audit}
+	if len(sample.Content) > maxBytes {
+		// Cut on a rune boundary so the sample stays valid UTF-8
+		cut := maxBytes
+		for cut > 0 && !utf8.RuneStart(sample.Content[cut]) {
+			cut--
+		}
+		sample.Content = sample.Content[:cut]
+		sample.Truncated = true
+	}
+	return sample, true
+}
@@ -0,0 +1,62 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSamplerEveryN(t *testing.T) {
+	var s Sampler
+	var sampled []int
+	for i := 1; i <= 9; i++ {
+		if s.Next(3) {
+			sampled = append(sampled, i)
+		}
+	}
+	if len(sampled) != 3 || sampled[0] != 3 || sampled[2] != 9 {
+		t.Errorf("Expected batches 3, 6 and 9 to be sampled, got %v", sampled)
+	}
+	if s.Next(0) {
+		t.Error("Expected every_n=0 to disable sampling")
+	}
+}
+
+func TestScopedSamplerCountsEachScope(t *testing.T) {
+	var s ScopedSampler
+	// Alternating scopes would leave one of them never sampled under a
+	// single every-2nd counter
+	sampled := map[string]int{}
+	for i := 0; i < 8; i++ {
+		scope := []string{"http_requests", "firewall_events"}[i%2]
+		if s.Next(scope, 2) {
+			sampled[scope]++
+		}
+	}
+	if sampled["http_requests"] != 2 || sampled["firewall_events"] != 2 {
+		t.Errorf("Expected every 2nd batch of each scope to be sampled, got %v", sampled)
+	}
+	if s.Next("http_requests", 0) {
+		t.Error("Expected every_n=0 to disable sampling")
+	}
+}
+
+func TestSampleFirstRecord(t *testing.T) {
+	body := "\n{\"ClientIP\":\"192.0.2.1\",\"Path\":\"/é\"}\n{\"ClientIP\":\"192.0.2.2\"}\n"
+	sample, ok := SampleFirstRecord([]byte(body), NewRedactionPolicy([]string{"ClientIP"}, nil), 1024)
+	if !ok {
+		t.Fatal("Expected a sample")
+	}
+	if sample.Content != `{"ClientIP":"[REDACTED]","Path":"/é"}` || sample.Redacted != 1 || sample.Truncated {
+		t.Errorf("Unexpected sample %+v", sample)
+	}
+
+	// Truncation never splits a multi-byte character
+	sample, _ = SampleFirstRecord([]byte(body), NewRedactionPolicy([]string{"ClientIP"}, nil), 35)
+	if !sample.Truncated || !strings.HasSuffix(sample.Content, "/") {
+		t.Errorf("Expected truncation before the multi-byte rune, got %q", sample.Content)
+	}
+
+	if _, ok := SampleFirstRecord([]byte("plain text log line"), NewRedactionPolicy(nil, nil), 1024); ok {
+		t.Error("Expected non-JSON records not to be sampled")
+	}
+}
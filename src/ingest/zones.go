@@ -0,0 +1,18 @@
+package ingest
+
+import "regexp"
+
+// ZoneHeader names the zone of a batch when neither the path nor the ?zone=
+// parameter does, for destinations configured with custom headers.
+const ZoneHeader = "X-Logpush-Zone"
+
+// zoneNamePattern matches zone names such as example.com and the 32-digit
+// hexadecimal zone IDs of the Cloudflare API.
+var zoneNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]{0,251}[a-z0-9])?$`)
+
+// ValidZone reports whether name can be stored as a zone: 1 to 253
+// lowercase letters, digits, dots or hyphens, starting and ending with a
+// letter or digit. Both zone names and zone IDs qualify.
+func ValidZone(name string) bool {
+	return zoneNamePattern.MatchString(name)
+}
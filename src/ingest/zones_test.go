@@ -0,0 +1,19 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidZone(t *testing.T) {
+	for _, name := range []string{"example.com", "shop.example.co.uk", "023e105f4ecef8ad9ca31a8372d0c353", "a", "my-zone"} {
+		if !ValidZone(name) {
+			t.Errorf("Expected %q to be a valid zone", name)
+		}
+	}
+	for _, name := range []string{"", "Example.com", ".example.com", "example.com.", "-example", "example com", "example/com", strings.Repeat("a", 254)} {
+		if ValidZone(name) {
+			t.Errorf("Expected %q to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+// Package logpushestimator assembles the estimator's ingestion endpoint,
+// dashboard and background jobs so they can be mounted inside another Go
+// service instead of running the logpush-estimator binary.
+//
+// New returns the two HTTP handlers the binary serves on its ingestion and
+// GUI ports, and a Runner for the periodic jobs (aggregate pruning,
+// integrity checks, trash purging, Cloudflare and Google Sheets syncs,
+// webhook delivery and dashboard cache warming) that keep the stored data
+// tidy.
+//
+// # Usage
+//
+//	db, err := database.NewSQLiteController("estimator.db", logger)
+//	if err != nil {
+//		return err
+//	}
+//	est, err := logpushestimator.New(logpushestimator.Config{DB: db, Logger: logger})
+//	if err != nil {
+//		return err
+//	}
+//	mux.Handle("/logpush/", http.StripPrefix("/logpush", est.IngestHandler))
+//	go est.Runner.Run(ctx)
+//
+// The GUI handler serves the dashboard and API at absolute paths (/,
+// /api/..., /static/...), so it should be given its own listener or host.
+//
+// With Config.Relay the ingestion endpoint forwards each batch to the
+// destination it was meant for and answers with that destination's status,
+// so the estimator can sit transparently in an existing pipeline. Batches
+// the destination cannot take are queued in the database and retried by
+// the Runner, so an upstream outage is not passed on to Logpush.
+//
+// With Config.ReadOnly the estimator only serves dashboards, for example
+// publicly from a replica or backup of a writable instance's database:
+// ingestion answers 403, so do API requests that would change state, and
+// the Runner only warms the dashboard cache.
+package logpushestimator
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/clock"
+	"github.com/melatonein5/LogpushEstimator/src/cloudflare"
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/encryption"
+	"github.com/melatonein5/LogpushEstimator/src/events"
+	"github.com/melatonein5/LogpushEstimator/src/features"
+	"github.com/melatonein5/LogpushEstimator/src/gui/handlers"
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+	"github.com/melatonein5/LogpushEstimator/src/notify"
+	"github.com/melatonein5/LogpushEstimator/src/relay"
+	"github.com/melatonein5/LogpushEstimator/src/secrets"
+	"github.com/melatonein5/LogpushEstimator/src/sheets"
+)
+
+// Default intervals of the jobs run by Runner.
+const (
+	DefaultMinutePruneInterval      = 10 * time.Minute
+	DefaultIntegrityCheckInterval   = time.Hour
+	DefaultTrashPurgeInterval       = time.Hour
+	DefaultRetentionInterval        = time.Hour
+	DefaultZoneTrafficSyncInterval  = time.Hour
+	DefaultLogpushJobHealthInterval = 10 * time.Minute
+	DefaultStatusIncidentInterval   = 10 * time.Minute
+	DefaultBudgetCheckInterval      = 10 * time.Minute
+	DefaultConfigReloadInterval     = 30 * time.Second
+	DefaultOutboxInterval           = notify.DefaultInterval
+	DefaultRelayRetryInterval       = relay.DefaultRetryInterval
+	DefaultShardRotateInterval      = time.Hour
+)
+
+// Config configures an Estimator. Only DB is required; zero values select
+// the defaults used by the logpush-estimator binary.
+type Config struct {
+	DB      *database.SQLiteController // Database to store and read records; required
+	Logger  *slog.Logger               // Structured logger (default slog.Default())
+	Version string                     // Version reported by /api/version (default "dev")
+
+	Features      *features.Registry  // Feature flags (default features.Defaults(), all off)
+	Cloudflare    cloudflare.Settings // Cloudflare integration; disabled without a token
+	Sheets        sheets.Settings     // Google Sheets report push; disabled without a spreadsheet
+	Relay         relay.Settings      // Upstream ingested batches are forwarded to; disabled without a URL
+	EncryptionKey *encryption.Key     // Seals exports and backups; nil writes plaintext
+	Secrets       *secrets.Resolver   // Resolves token secret references; nil leaves them as written
+	TenantDomain  string              // Parent domain of tenant subdomains; empty disables them
+	TemplateDir   string              // Directory whose dashboard.html overrides the built-in one
+	ExportDir     string              // Directory exports built by /api/exports are kept in; empty disables them
+	Clock         clock.Clock         // Source of the current time for records, windows and retention (default the system clock)
+	Events        *events.Bus         // Bus domain events are published on (default a new bus)
+	SnapshotFile  string              // File in-memory caches are saved to on shutdown and loaded from; empty disables it
+	ReadOnly      bool                // Serve dashboards only: no ingestion, no state-changing API requests, no background jobs
+
+	IngestBufferSize    int           // Batches queued for asynchronous writes; 0 writes each batch before answering
+	IngestFlushSize     int           // Queued batches written per transaction (default database.DefaultWriteFlushSize)
+	IngestFlushInterval time.Duration // Longest a queued batch waits to be written (default database.DefaultWriteFlushInterval)
+	IngestAutoTune      bool          // Tune the flush size and interval from queue depth and write latency, from the values above
+
+	MinutePruneInterval      time.Duration // How often expired per-minute aggregates are removed
+	IntegrityCheckInterval   time.Duration // How often derived data is checked and repaired
+	TrashPurgeInterval       time.Duration // How often expired trash batches are removed
+	RetentionInterval        time.Duration // How often raw records past retention.raw_days are pruned
+	ZoneTrafficSyncInterval  time.Duration // How often zone request counts are pulled from Cloudflare
+	LogpushJobHealthInterval time.Duration // How often tracked Logpush job status is pulled
+	StatusIncidentInterval   time.Duration // How often the Cloudflare status page is polled for incidents
+	BudgetCheckInterval      time.Duration // How often budgets are checked for breaches
+	ConfigReloadInterval     time.Duration // How long ingestion caches the configuration
+	OutboxInterval           time.Duration // How often due webhook notifications are delivered
+	RelayRetryInterval       time.Duration // How often queued batches are retried against the relay upstream
+	ShardRotateInterval      time.Duration // How often closed months are moved to their shard, when sharded by month
+}
+
+// withDefaults returns c with zero values replaced by defaults.
+func (c Config) withDefaults() Config {
+	if c.Logger == nil {
+		c.Logger = slog.Default()
+	}
+	if c.Version == "" {
+		c.Version = "dev"
+	}
+	if c.Features == nil {
+		c.Features = features.NewRegistry(features.Defaults()...)
+	}
+	if c.Clock == nil {
+		c.Clock = clock.System
+	}
+	if c.Events == nil {
+		c.Events = events.NewBus(c.Clock, c.Logger)
+	}
+	setDefault(&c.MinutePruneInterval, DefaultMinutePruneInterval)
+	setDefault(&c.IntegrityCheckInterval, DefaultIntegrityCheckInterval)
+	setDefault(&c.TrashPurgeInterval, DefaultTrashPurgeInterval)
+	setDefault(&c.RetentionInterval, DefaultRetentionInterval)
+	setDefault(&c.ZoneTrafficSyncInterval, DefaultZoneTrafficSyncInterval)
+	setDefault(&c.LogpushJobHealthInterval, DefaultLogpushJobHealthInterval)
+	setDefault(&c.StatusIncidentInterval, DefaultStatusIncidentInterval)
+	setDefault(&c.BudgetCheckInterval, DefaultBudgetCheckInterval)
+	setDefault(&c.ConfigReloadInterval, DefaultConfigReloadInterval)
+	setDefault(&c.OutboxInterval, DefaultOutboxInterval)
+	setDefault(&c.RelayRetryInterval, DefaultRelayRetryInterval)
+	setDefault(&c.ShardRotateInterval, DefaultShardRotateInterval)
+	return c
+}
+
+// setDefault sets *d to def when it is not positive.
+func setDefault(d *time.Duration, def time.Duration) {
+	if *d <= 0 {
+		*d = def
+	}
+}
+
+// Estimator is an assembled estimator ready to be mounted.
+type Estimator struct {
+	// IngestHandler serves POST /ingest, POST /t/{tenant}/ingest and
+	// GET /health, as the binary does on its ingestion port.
+	IngestHandler http.Handler
+	// GUIHandler serves the dashboard, the /api/ endpoints, static
+	// assets and GET /health, as the binary does on its GUI port.
+	GUIHandler http.Handler
+	// Runner runs the background jobs; the handlers work without it, but
+	// aggregates and trash then grow unbounded.
+	Runner *Runner
+	// Listeners is reported by /api/version; record the addresses the
+	// handlers are served on, e.g. Listeners.Set("gui", ln.Addr().String()).
+	Listeners *handlers.Listeners
+	// Events is the bus ingestion and the background jobs publish domain
+	// events on; subscribe to it to integrate with other systems.
+	Events *events.Bus
+
+	writes *database.WriteBuffer // Asynchronous ingestion writes; nil when disabled
+}
+
+// Close writes out the batches still queued for asynchronous writes (see
+// Config.IngestBufferSize) and stops queueing; batches ingested afterwards
+// are written before they are answered. Call it once the ingestion handler
+// has stopped receiving requests, before closing the database. Closing a
+// nil Estimator does nothing.
+func (e *Estimator) Close() {
+	if e != nil && e.writes != nil {
+		e.writes.Close()
+	}
+}
+
+// New assembles an estimator from cfg. The dashboard template override
+// applies process-wide, so estimators in one process share it.
+//
+// Parameters:
+//   - cfg: Configuration; cfg.DB is required
+//
+// Returns:
+//   - *Estimator: Handlers and runner sharing one statistics cache,
+//     expensive-request limiter, set of API metrics and event bus
+//   - error: When cfg.DB is nil or cfg.Relay is invalid
+func New(cfg Config) (*Estimator, error) {
+	if cfg.DB == nil {
+		return nil, errors.New("logpushestimator: Config.DB is required")
+	}
+	if cfg.Relay.Enabled() {
+		if err := cfg.Relay.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	cfg = cfg.withDefaults()
+	if cfg.TemplateDir != "" {
+		handlers.SetTemplateOverrideDir(cfg.TemplateDir)
+	}
+	cfg.DB.SetClock(cfg.Clock)
+	handlers.SetClock(cfg.Clock)
+
+	// One watcher serves the stored configuration to ingestion, the admin
+	// token checks and the webhooks. Events for configured webhooks are
+	// queued in the outbox as they are published; the Runner delivers them
+	settings := config.NewWatcher(cfg.DB, cfg.ConfigReloadInterval, cfg.Secrets)
+	notifier := notify.New(notify.Config{
+		DB:       cfg.DB,
+		Webhooks: func() []config.Webhook { return settings.Current().Webhooks },
+		Clock:    cfg.Clock,
+		Logger:   cfg.Logger,
+	})
+	notifier.Subscribe(cfg.Events)
+
+	// State saved by the previous process, if any, is restored before the
+	// handlers serve anything; the Runner saves it again when it stops
+	cache := handlers.NewStatsCache(handlers.DefaultStatsCacheTTL, handlers.DefaultStatsCacheMaxStale)
+	metrics := handlers.NewAPIMetrics()
+	eventLog := handlers.NewEventLog(cfg.Events)
+	loadSnapshot(cfg, cache, metrics, eventLog)
+	rates := handlers.NewByteRates(cfg.Events)
+	limiter := handlers.NewExpensiveLimiter()
+
+	// Exports the previous process was building will never finish
+	if !cfg.ReadOnly {
+		if n, err := cfg.DB.FailUnfinishedExportJobs("Interrupted by a restart; request the export again"); err != nil {
+			cfg.Logger.Error("Failed to fail unfinished export jobs", "error", err)
+		} else if n > 0 {
+			cfg.Logger.Warn("Export jobs interrupted by a restart", "jobs", n)
+		}
+	}
+	pipeline := handlers.NewIngestPipeline()
+
+	// The label limits count the labels already stored
+	labels := ingest.NewLabelGuard()
+	if usage, err := cfg.DB.QueryLabelUsage(time.Time{}, time.Time{}); err != nil {
+		cfg.Logger.Error("Failed to load stored labels; label limits count new labels only", "error", err)
+	} else {
+		for _, u := range usage {
+			labels.Observe(u.Key, u.Value)
+		}
+	}
+
+	// Batches the relay upstream cannot take are queued; the Runner
+	// retries them
+	var relayQueue *relay.Queue
+	if cfg.Relay.Enabled() {
+		relayQueue = relay.NewQueue(relay.New(cfg.Relay), relay.QueueConfig{
+			DB:       cfg.DB,
+			Clock:    cfg.Clock,
+			Logger:   cfg.Logger,
+			MaxBytes: cfg.Relay.QueueMaxBytes,
+		})
+	}
+
+	// With a write buffer, ingestion answers once a batch is queued; the
+	// outcome, latency and event of each batch follow when it is written
+	var writes *database.WriteBuffer
+	if cfg.IngestBufferSize > 0 && !cfg.ReadOnly {
+		writes = cfg.DB.NewWriteBuffer(database.WriteBufferConfig{
+			Size:          cfg.IngestBufferSize,
+			FlushSize:     cfg.IngestFlushSize,
+			FlushInterval: cfg.IngestFlushInterval,
+			AutoTune:      cfg.IngestAutoTune,
+			OnFlush: func(entries []database.LogSize, elapsed time.Duration, err error) {
+				for _, entry := range entries {
+					pipeline.Observe(elapsed, err == nil)
+					if err != nil {
+						cfg.DB.RecordIngestOutcome(false)
+						continue
+					}
+					ingestStored(cfg, entry)
+				}
+			},
+		})
+		pipeline.UseBuffer(writes)
+	}
+
+	listeners := handlers.NewListeners()
+	return &Estimator{
+		IngestHandler: newIngestMux(cfg, settings, cache, pipeline, labels, relayQueue, writes),
+		GUIHandler:    newGUIMux(cfg, settings, cache, limiter, metrics, eventLog, rates, pipeline, labels, relayQueue, listeners),
+		Runner: &Runner{cfg: cfg, cache: cache, limiter: limiter, metrics: metrics, eventLog: eventLog, notifier: notifier,
+			relay: relayQueue, ready: make(chan struct{})},
+		Listeners: listeners,
+		Events:    cfg.Events,
+		writes:    writes,
+	}, nil
+}
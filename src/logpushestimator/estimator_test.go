@@ -0,0 +1,833 @@
+package logpushestimator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/events"
+	"github.com/melatonein5/LogpushEstimator/src/gui/handlers"
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+	"github.com/melatonein5/LogpushEstimator/src/relay"
+	"github.com/melatonein5/LogpushEstimator/src/testsupport"
+)
+
+func setupTestEstimator(t *testing.T, tempFile string) (*Estimator, *database.SQLiteController) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tempFile)
+	})
+	est, err := New(Config{DB: db, Logger: logger, Version: "1.2.3", MinutePruneInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return est, db
+}
+
+func TestNewRequiresDB(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Error("Expected an error without a database")
+	}
+}
+
+func TestEstimatorHandlers(t *testing.T) {
+	est, db := setupTestEstimator(t, "test_estimator_handlers.db")
+
+	rr := httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("POST", "/ingest", strings.NewReader("{\"a\":1}\n")))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected ingest to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if logs, err := db.QuerySince(0, 10); err != nil || len(logs) != 1 {
+		t.Errorf("Expected 1 stored record, got %d (%v)", len(logs), err)
+	}
+
+	rr = httptest.NewRecorder()
+	est.GUIHandler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/version", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "1.2.3") {
+		t.Errorf("Expected the configured version, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// The batch counts towards the ingest rate gauges without a query
+	rr = httptest.NewRecorder()
+	est.GUIHandler.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(rr.Body.String(), `lpe_ingest_batches_per_second{window="1m"} 0.016666666666666666`) {
+		t.Errorf("Expected the batch in the ingest rate gauges, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// The dataset is taken from the query, or else the header
+	req := httptest.NewRequest("POST", "/ingest", strings.NewReader("{\"b\":2}\n"))
+	req.Header.Set("X-Logpush-Dataset", "firewall_events")
+	rr = httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, req)
+	if logs, err := db.QuerySince(1, 10); err != nil || len(logs) != 1 || logs[0].Dataset != "firewall_events" {
+		t.Errorf("Expected the batch stored under the header's dataset, got %+v (%v)", logs, err)
+	}
+	rr = httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("POST", "/ingest?dataset=Bad%20Name", strings.NewReader("x\n")))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid dataset name, got %d", rr.Code)
+	}
+
+	// Gzip batches are stored with both sizes and their records counted
+	// after decompression
+	ndjson := strings.Repeat("{\"ClientRequestHost\":\"example.com\"}\n", 100)
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(ndjson))
+	zw.Close()
+	req = httptest.NewRequest("POST", "/ingest", bytes.NewReader(gz.Bytes()))
+	req.Header.Set("Content-Encoding", "gzip")
+	rr = httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, req)
+	logs, err := db.QuerySince(2, 10)
+	if rr.Code != http.StatusOK || err != nil || len(logs) != 1 {
+		t.Fatalf("Expected the gzip batch to be stored, got %d: %s (%v)", rr.Code, rr.Body.String(), err)
+	}
+	if l := logs[0]; l.Filesize != int64(gz.Len()) || l.UncompressedSize != int64(len(ndjson)) || l.Encoding != "gzip" || l.RecordCount != 100 {
+		t.Errorf("Expected %d bytes received and %d decompressed in 100 records, got %+v", gz.Len(), len(ndjson), l)
+	}
+	for encoding, code := range map[string]int{"gzip": http.StatusBadRequest, "br": http.StatusUnsupportedMediaType} {
+		req = httptest.NewRequest("POST", "/ingest", strings.NewReader("not compressed\n"))
+		req.Header.Set("Content-Encoding", encoding)
+		rr = httptest.NewRecorder()
+		est.IngestHandler.ServeHTTP(rr, req)
+		if rr.Code != code {
+			t.Errorf("%s: expected %d, got %d", encoding, code, rr.Code)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	est.GUIHandler.ServeHTTP(rr, httptest.NewRequest("GET", "/ingest", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected the GUI handler not to serve ingestion, got %d", rr.Code)
+	}
+}
+
+func TestMeasurementIngest(t *testing.T) {
+	est, db := setupTestEstimator(t, "test_estimator_measurements.db")
+	post := func(target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("POST", target, strings.NewReader(body)))
+		return rr
+	}
+
+	// Measurements are stored as the batches they describe would have been
+	rr := post("/t/acme/ingest/measurements", `{"measurements": [
+		{"timestamp": "2025-09-15T12:00:00Z", "dataset": "http_requests", "filesize": 400, "uncompressed_size": 3500,
+		 "content_encoding": "gzip", "record_count": 100, "min_record_size": 30, "max_record_size": 40, "avg_record_size": 35},
+		{"filesize": 8, "record_count": 1, "min_record_size": 7, "max_record_size": 7, "avg_record_size": 7}
+	]}`)
+	if rr.Code != http.StatusOK || rr.Header().Get("X-Measurements-Stored") != "2" {
+		t.Fatalf("Expected both measurements to be stored, got %d: %s", rr.Code, rr.Body.String())
+	}
+	logs, err := db.ForTenant("acme").QuerySince(0, 10)
+	if err != nil || len(logs) != 2 {
+		t.Fatalf("Expected 2 stored records, got %d (%v)", len(logs), err)
+	}
+	if l := logs[0]; !l.Timestamp.Equal(time.Date(2025, 9, 15, 12, 0, 0, 0, time.UTC)) || l.Filesize != 400 || l.UncompressedSize != 3500 ||
+		l.Encoding != "gzip" || l.RecordCount != 100 || l.Dataset != "http_requests" || l.Tenant != "acme" {
+		t.Errorf("Expected the measurement's values, got %+v", l)
+	}
+	if l := logs[1]; l.Timestamp.IsZero() || l.UncompressedSize != 8 {
+		t.Errorf("Expected the receive time and the received size, got %+v", l)
+	}
+
+	// A bad entry rejects the whole request
+	for _, body := range []string{
+		`not json`,
+		`{"measurements": []}`,
+		`{"measurements": [{"filesize": 8}, {"filesize": 0}]}`,
+		`{"measurements": [{"filesize": 8, "dataset": "Bad Name"}]}`,
+		`{"measurements": [{"filesize": 8, "content_encoding": "br"}]}`,
+	} {
+		if rr := post("/ingest/measurements", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rr.Code)
+		}
+	}
+	if logs, _ := db.QuerySince(0, 10); len(logs) != 2 {
+		t.Errorf("Expected nothing stored from rejected requests, got %d records", len(logs)-2)
+	}
+	rr = httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("GET", "/ingest/measurements", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET, got %d", rr.Code)
+	}
+	if rr := post("/t/Bad/ingest/measurements", `{"measurements": [{"filesize": 8}]}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an invalid tenant, got %d", rr.Code)
+	}
+}
+
+func TestRelayIngest(t *testing.T) {
+	var mu sync.Mutex
+	status, received := http.StatusOK, []string{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r.Header.Get("Authorization")+" "+string(body))
+		w.WriteHeader(status)
+		w.Write([]byte("upstream says hi"))
+	}))
+	defer upstream.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController("test_estimator_relay.db", logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove("test_estimator_relay.db")
+	})
+	if _, err := New(Config{DB: db, Logger: logger, Relay: relay.Settings{URL: upstream.URL, Kind: "kafka"}}); err == nil {
+		t.Fatal("Expected an invalid relay to be rejected")
+	}
+	est, err := New(Config{DB: db, Logger: logger, Relay: relay.Settings{URL: upstream.URL, Kind: relay.KindSplunkHEC, Token: "hec"}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	postTo := func(target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("POST", target, strings.NewReader(body)))
+		return rr
+	}
+	post := func(body string) *httptest.ResponseRecorder {
+		return postTo("/ingest", body)
+	}
+	stored := func() int {
+		logs, err := db.QuerySince(0, 10)
+		if err != nil {
+			t.Fatalf("QuerySince failed: %v", err)
+		}
+		return len(logs)
+	}
+
+	// Accepted batches are stored and answered with the upstream's response
+	if rr := post("{\"a\":1}\n"); rr.Code != http.StatusOK || rr.Body.String() != "upstream says hi" || stored() != 1 {
+		t.Fatalf("Expected the upstream's answer and one record, got %d %q with %d records", rr.Code, rr.Body.String(), stored())
+	}
+	if rr := post(`{"content":"tests"}`); rr.Code != http.StatusOK || stored() != 1 {
+		t.Errorf("Expected the ownership challenge relayed but not stored, got %d with %d records", rr.Code, stored())
+	}
+
+	// A dataset the estimator cannot store under does not stop the relay
+	if rr := postTo("/ingest?dataset=HTTP%20requests", "{\"e\":5}\n"); rr.Code != http.StatusOK || rr.Body.String() != "upstream says hi" || stored() != 1 {
+		t.Errorf("Expected an invalid dataset relayed but not stored, got %d %q with %d records", rr.Code, rr.Body.String(), stored())
+	}
+	for _, encoding := range []string{"br", "gzip"} {
+		req := httptest.NewRequest("POST", "/ingest", strings.NewReader("not compressed"))
+		req.Header.Set("Content-Encoding", encoding)
+		rr := httptest.NewRecorder()
+		est.IngestHandler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK || rr.Body.String() != "upstream says hi" || stored() != 1 {
+			t.Errorf("Expected an undecodable %s body relayed but not stored, got %d %q with %d records", encoding, rr.Code, rr.Body.String(), stored())
+		}
+	}
+
+	answer := func(code int) {
+		mu.Lock()
+		defer mu.Unlock()
+		status = code
+	}
+
+	// Refused batches are not counted, since Logpush sends them again
+	answer(http.StatusBadRequest)
+	if rr := post("{\"b\":2}\n"); rr.Code != http.StatusBadRequest || stored() != 1 {
+		t.Errorf("Expected the upstream's 400 and nothing stored, got %d with %d records", rr.Code, stored())
+	}
+	mu.Lock()
+	if len(received) != 6 || received[0] != "Splunk hec {\"a\":1}\n" || received[3] != "Splunk hec not compressed" {
+		t.Errorf("Expected six relayed bodies, unchanged, with the HEC token, got %q", received)
+	}
+	mu.Unlock()
+
+	// Upstream failures are queued and acknowledged, so Logpush is not
+	// held back by the upstream, and the batches are counted
+	answer(http.StatusServiceUnavailable)
+	if rr := post("{\"c\":3}\n"); rr.Code != http.StatusOK || stored() != 2 {
+		t.Errorf("Expected a 503 from the upstream queued, got %d with %d records", rr.Code, stored())
+	}
+	if rr := postTo("/ingest/bad%20zone", "{\"f\":6}\n"); rr.Code != http.StatusOK || stored() != 2 {
+		t.Errorf("Expected an invalid zone queued but not stored, got %d with %d records", rr.Code, stored())
+	}
+	upstream.Close()
+	if rr := post("{\"d\":4}\n"); rr.Code != http.StatusOK || stored() != 3 {
+		t.Errorf("Expected a batch for an unreachable upstream queued, got %d with %d records", rr.Code, stored())
+	}
+
+	rr := httptest.NewRecorder()
+	est.GUIHandler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/relay", nil))
+	var resp struct {
+		Data struct {
+			Enabled bool `json:"enabled"`
+			Queue   struct {
+				Batches int64 `json:"batches"`
+			} `json:"queue"`
+			Outcomes map[string]int64 `json:"outcomes_24h"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid relay status %s: %v", rr.Body.String(), err)
+	}
+	if !resp.Data.Enabled || resp.Data.Queue.Batches != 3 || resp.Data.Outcomes[relay.OutcomeDelivered] != 5 || resp.Data.Outcomes[relay.OutcomeRefused] != 1 {
+		t.Errorf("Unexpected relay status %s", rr.Body.String())
+	}
+}
+
+func TestIngestAuthentication(t *testing.T) {
+	est, db := setupTestEstimator(t, "test_estimator_ingest_auth.db")
+	doc := config.NewDocument()
+	doc.Tenants = []config.Tenant{{Name: "acme"}}
+	doc.Tokens = []config.Token{
+		{Name: "logpush", Scopes: []string{"ingest"}, Secret: "s3cret"},
+		{Name: "acme-logpush", Scopes: []string{"ingest"}, Secret: "acme-s3cret", Tenant: "acme"},
+	}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	post := func(target string, header http.Header) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", target, strings.NewReader("{\"a\":1}\n"))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		est.IngestHandler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, tt := range []struct {
+		name   string
+		target string
+		header http.Header
+		want   int
+	}{
+		{"no token", "/ingest", nil, http.StatusUnauthorized},
+		{"wrong token", "/ingest?secret=wrong", nil, http.StatusUnauthorized},
+		{"URL parameter", "/ingest?secret=s3cret", nil, http.StatusOK},
+		{"custom header", "/ingest", http.Header{"X-Logpush-Token": {"s3cret"}}, http.StatusOK},
+		{"bearer", "/ingest", http.Header{"Authorization": {"Bearer s3cret"}}, http.StatusOK},
+		{"tenant token", "/t/acme/ingest?secret=acme-s3cret", nil, http.StatusOK},
+		{"tenant token outside its tenant", "/ingest?secret=acme-s3cret", nil, http.StatusUnauthorized},
+		{"measurements", "/ingest/measurements", nil, http.StatusUnauthorized},
+	} {
+		rr := post(tt.target, tt.header)
+		if rr.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d: %s", tt.name, tt.want, rr.Code, rr.Body.String())
+		}
+		if rr.Code == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: expected a WWW-Authenticate challenge", tt.name)
+		}
+	}
+	if logs, err := db.QuerySince(0, 10); err != nil || len(logs) != 4 {
+		t.Errorf("Expected only the authenticated batches stored, got %d, %v", len(logs), err)
+	}
+}
+
+func TestReadOnlyEstimator(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController("test_estimator_read_only.db", logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove("test_estimator_read_only.db")
+	})
+	est, err := New(Config{DB: db, Logger: logger, ReadOnly: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	serve := func(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rr
+	}
+	for _, target := range []string{"/ingest", "/t/acme/ingest", "/ingest/measurements", "/t/acme/ingest/measurements"} {
+		if rr := serve(est.IngestHandler, "POST", target, "{\"a\":1}\n"); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected ingestion at %s to be refused, got %d", target, rr.Code)
+		}
+	}
+	if logs, _ := db.QuerySince(0, 10); len(logs) != 0 {
+		t.Errorf("Expected nothing stored, got %d records", len(logs))
+	}
+	if rr := serve(est.IngestHandler, "GET", "/health", ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected /health to be served, got %d", rr.Code)
+	}
+
+	if rr := serve(est.GUIHandler, "GET", "/api/stats/summary", ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected reads to be served, got %d", rr.Code)
+	}
+	if rr := serve(est.GUIHandler, "POST", "/api/views", `{"name":"x"}`); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected state changes to be refused, got %d", rr.Code)
+	}
+	// The tenant router only serves tenants with records
+	if err := db.ForTenant("acme").InsertLog(database.LogSize{Filesize: 10, RecordCount: 1}); err != nil {
+		t.Fatalf("InsertLog failed: %v", err)
+	}
+	if rr := serve(est.GUIHandler, "PUT", "/t/acme/api/preferences", "{}"); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected tenant-scoped state changes to be refused, got %d", rr.Code)
+	}
+}
+
+func TestRunnerWarmsCacheAndStops(t *testing.T) {
+	est, _ := setupTestEstimator(t, "test_estimator_runner.db")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- est.Runner.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rr := httptest.NewRecorder()
+		est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+		var health struct {
+			Warmup handlers.WarmupProgress `json:"warmup"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &health); err != nil {
+			t.Fatalf("Failed to parse health response: %v", err)
+		}
+		if health.Warmup.State == handlers.WarmupDone {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Cache warming did not finish: %+v", health.Warmup)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case <-est.Runner.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Runner not ready after warming")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after the context was cancelled")
+	}
+}
+
+func TestHealthChecksDatabase(t *testing.T) {
+	est, db := setupTestEstimator(t, "test_estimator_health.db")
+
+	for _, handler := range []http.Handler{est.IngestHandler, est.GUIHandler} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+		var health HealthResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &health); err != nil {
+			t.Fatalf("Failed to parse health response: %v", err)
+		}
+		if rr.Code != http.StatusOK || health.Status != HealthOK || health.Database != HealthOK {
+			t.Errorf("Expected a healthy response, got %d %+v", rr.Code, health)
+		}
+	}
+
+	db.Close()
+	rr := httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+	var health HealthResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &health); err != nil {
+		t.Fatalf("Failed to parse health response: %v", err)
+	}
+	if rr.Code != http.StatusServiceUnavailable || health.Status != HealthUnavailable || health.Database == HealthOK {
+		t.Errorf("Expected 503 for an unreadable database, got %d %+v", rr.Code, health)
+	}
+}
+
+func TestInjectedDatabaseFaults(t *testing.T) {
+	est, db := setupTestEstimator(t, "test_estimator_faults.db")
+
+	db.SetFaults(database.Faults{ErrorRate: 1})
+	rr := httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("POST", "/ingest", strings.NewReader("line\n")))
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 while writes fail, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while reads fail, got %d", rr.Code)
+	}
+
+	db.SetFaults(database.Faults{})
+	rr = httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("POST", "/ingest", strings.NewReader("line\n")))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected ingestion to recover once faults stop, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected health to recover once faults stop, got %d", rr.Code)
+	}
+}
+
+func TestEstimatorIngestZones(t *testing.T) {
+	est, db := setupTestEstimator(t, "test_estimator_zones.db")
+
+	// The zone is taken from the path, the query or the header, in that order
+	for _, tt := range []struct {
+		target, header, zone string
+	}{
+		{"/ingest/example.com?zone=example.org", "", "example.com"},
+		{"/ingest?zone=example.org", "example.net", "example.org"},
+		{"/ingest", "023e105f4ecef8ad9ca31a8372d0c353", "023e105f4ecef8ad9ca31a8372d0c353"},
+	} {
+		req := httptest.NewRequest("POST", tt.target, strings.NewReader("{\"c\":3}\n"))
+		req.Header.Set("X-Logpush-Zone", tt.header)
+		rr := httptest.NewRecorder()
+		est.IngestHandler.ServeHTTP(rr, req)
+		if logs, err := db.ForZone(tt.zone).QuerySince(0, 10); rr.Code != http.StatusOK || err != nil || len(logs) != 1 {
+			t.Errorf("%s: expected the batch stored under %s, got %d %+v (%v)", tt.target, tt.zone, rr.Code, logs, err)
+		}
+	}
+	for target, code := range map[string]int{
+		"/ingest/Example.com":    http.StatusBadRequest,
+		"/ingest?zone=a/b":       http.StatusBadRequest,
+		"/ingest/example.com/x":  http.StatusNotFound,
+		"/t/acme/ingest/a/b":     http.StatusNotFound,
+		"/t/acme/other/zone.com": http.StatusNotFound,
+	} {
+		rr := httptest.NewRecorder()
+		est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("POST", target, strings.NewReader("x\n")))
+		if rr.Code != code {
+			t.Errorf("%s: expected %d, got %d", target, code, rr.Code)
+		}
+	}
+}
+
+func TestEstimatorIngestLabels(t *testing.T) {
+	est, db := setupTestEstimator(t, "test_estimator_labels.db")
+
+	req := httptest.NewRequest("POST", "/ingest/example.com", strings.NewReader("{\"c\":3}\n"))
+	req.Header.Set("X-LPE-Label-Env", "prod")
+	req.Header.Set("X-LPE-Label-Team", "edge")
+	rr := httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, req)
+	logs, err := db.ForLabels(map[string]string{"env": "prod"}).QuerySince(0, 10)
+	if rr.Code != http.StatusOK || err != nil || len(logs) != 1 || logs[0].Labels["team"] != "edge" || logs[0].Zone != "example.com" {
+		t.Errorf("Expected the batch stored with its labels, got %d %+v (%v)", rr.Code, logs, err)
+	}
+
+	req = httptest.NewRequest("POST", "/ingest", strings.NewReader("x\n"))
+	req.Header.Set("X-LPE-Label-Env", "not valid")
+	rr = httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid label, got %d", rr.Code)
+	}
+}
+
+func TestEstimatorIngestLabelLimits(t *testing.T) {
+	est, db := setupTestEstimator(t, "test_estimator_label_limits.db")
+	doc := config.NewDocument()
+	doc.LabelLimits = config.LabelLimits{MaxValues: 1, Policy: ingest.LabelPolicyReject}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Failed to import config: %v", err)
+	}
+
+	post := func(env, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/ingest", strings.NewReader(body))
+		req.Header.Set("X-LPE-Label-Env", env)
+		rr := httptest.NewRecorder()
+		est.IngestHandler.ServeHTTP(rr, req)
+		return rr
+	}
+	// A batch that is not stored does not take a value
+	if rr := post("staging", ""); rr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected an empty batch rejected, got %d", rr.Code)
+	}
+	if rr := post("prod", "x\n"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the first stored value accepted, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := post("dev", "x\n"); rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "already has 1 values") {
+		t.Errorf("Expected a second value rejected, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr := httptest.NewRecorder()
+	est.GUIHandler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/labels", nil))
+	var report handlers.LabelAdminReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &handlers.APIResponse{Data: &report}); err != nil {
+		t.Fatalf("Failed to decode label report: %v", err)
+	}
+	if len(report.Keys) != 1 || report.Keys[0].Rejected != 1 || !report.Keys[0].AtLimit || report.Limits.Policy != ingest.LabelPolicyReject {
+		t.Errorf("Expected env at its limit with one rejection, got %+v", report)
+	}
+}
+
+func TestEstimatorIngestBuffer(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController("test_estimator_ingest_buffer.db", logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove("test_estimator_ingest_buffer.db")
+	})
+	clk := testsupport.NewClock(testsupport.Epoch)
+	est, err := New(Config{DB: db, Logger: logger, Clock: clk, IngestBufferSize: 10, IngestFlushSize: 10, IngestFlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	var published []events.Event
+	est.Events.Subscribe(func(e events.Event) { published = append(published, e) })
+
+	rr := httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("POST", "/ingest/example.com", strings.NewReader("a\nb\n")))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+
+	// Close flushes the queued batch before returning, stamped with the
+	// time it was received rather than the time of the flush
+	clk.Advance(90 * time.Minute)
+	est.Close()
+	logs, err := db.QuerySince(0, 10)
+	if err != nil || len(logs) != 1 || logs[0].Filesize != 4 || logs[0].Zone != "example.com" {
+		t.Errorf("Expected the buffered batch stored on Close, got %+v (%v)", logs, err)
+	}
+	if len(logs) == 1 && !logs[0].Timestamp.Equal(testsupport.Epoch) {
+		t.Errorf("Expected the batch recorded at %v, got %v", testsupport.Epoch, logs[0].Timestamp)
+	}
+	if len(published) != 1 || published[0].Topic != events.TopicIngestReceived {
+		t.Errorf("Expected one ingest.received event after the flush, got %+v", published)
+	}
+
+	// Once closed, batches are written synchronously
+	rr = httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("POST", "/ingest", strings.NewReader("c\n")))
+	if logs, _ := db.QuerySince(0, 10); rr.Code != http.StatusOK || len(logs) != 2 {
+		t.Errorf("Expected a direct insert after Close, got %d with %d records", rr.Code, len(logs))
+	}
+}
+
+func TestEstimatorPublishesEvents(t *testing.T) {
+	est, db := setupTestEstimator(t, "test_estimator_events.db")
+	var published []events.Event
+	est.Events.Subscribe(func(e events.Event) { published = append(published, e) })
+
+	rr := httptest.NewRecorder()
+	est.IngestHandler.ServeHTTP(rr, httptest.NewRequest("POST", "/t/acme/ingest/example.com?dataset=http_requests", strings.NewReader("a\nb\n")))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	if len(published) != 1 || published[0].Topic != events.TopicIngestReceived {
+		t.Fatalf("Expected one ingest.received event, got %+v", published)
+	}
+	want := events.IngestReceived{Tenant: "acme", Dataset: "http_requests", Zone: "example.com", Bytes: 4, Records: 2}
+	if got := published[0].Data.(events.IngestReceived); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	// A job alerts once when it starts failing, not on every sync
+	now := time.Now().UTC()
+	job := database.LogpushJob{ID: 42, Scope: "zones", ScopeID: "z", Name: "http", Enabled: true, LastError: &now, ErrorMessage: "403"}
+	if err := db.SaveLogpushJob(job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UpdateLogpushJobHealth(job); err != nil {
+		t.Fatal(err)
+	}
+	published = nil
+	health := make(map[int64]string)
+	est.Runner.publishJobHealth(health)
+	est.Runner.publishJobHealth(health)
+	var synced, alerts int
+	for _, e := range published {
+		switch e.Topic {
+		case events.TopicJobSynced:
+			synced++
+		case events.TopicAlertFired:
+			alerts++
+		}
+	}
+	if synced != 2 || alerts != 1 {
+		t.Errorf("Expected 2 job.synced and 1 alert.fired events, got %d and %d", synced, alerts)
+	}
+
+	// The GUI's event log records everything published on the bus
+	rr = httptest.NewRecorder()
+	est.GUIHandler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/events", nil))
+	var response struct {
+		Data handlers.EventLogReport `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse events response: %v", err)
+	}
+	if response.Data.Counts[events.TopicIngestReceived] != 1 || response.Data.Counts[events.TopicAlertFired] != 1 {
+		t.Errorf("Unexpected event counts: %v", response.Data.Counts)
+	}
+}
+
+func TestRunnerPrunesExpiredRecords(t *testing.T) {
+	est, db := setupTestEstimator(t, "test_estimator_retention.db")
+	var published []events.Event
+	est.Events.Subscribe(func(e events.Event) { published = append(published, e) })
+
+	for _, age := range []int{120, 100, 10} {
+		if err := db.InsertLog(database.LogSize{Timestamp: time.Now().AddDate(0, 0, -age), Filesize: 100}); err != nil {
+			t.Fatalf("InsertLog failed: %v", err)
+		}
+	}
+
+	// Without raw_days nothing expires
+	est.Runner.pruneExpired()
+	if logs, _ := db.GetAll(); len(logs) != 3 || len(published) != 0 {
+		t.Fatalf("Expected every record kept, got %d records and %+v", len(logs), published)
+	}
+
+	retention := config.DefaultRetention()
+	retention.RawDays = 90
+	if _, err := config.SaveRetention(db, retention, time.Time{}); err != nil {
+		t.Fatalf("SaveRetention failed: %v", err)
+	}
+	est.Runner.pruneExpired()
+	if logs, _ := db.GetAll(); len(logs) != 1 {
+		t.Errorf("Expected only the recent record kept, got %d", len(logs))
+	}
+	if trash, _ := db.ListTrash(); len(trash) != 1 || trash[0].Rows != 2 {
+		t.Errorf("Expected the expired records in one trash batch, got %+v", trash)
+	}
+	if len(published) != 1 {
+		t.Fatalf("Expected one retention.pruned event, got %+v", published)
+	}
+	if pruned := published[0].Data.(events.RetentionPruned); pruned.Kind != "log_sizes" || pruned.Removed != 2 {
+		t.Errorf("Expected 2 log_sizes pruned, got %+v", pruned)
+	}
+}
+
+func TestRunnerAlertsOnBudgetBreach(t *testing.T) {
+	est, db := setupTestEstimator(t, "test_estimator_budgets.db")
+	doc := config.NewDocument()
+	doc.Budgets = []config.Budget{{Name: "daily", Period: "daily", LimitBytes: 4}}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	var alerts []events.AlertFired
+	est.Events.Subscribe(func(e events.Event) { alerts = append(alerts, e.Data.(events.AlertFired)) }, events.TopicAlertFired)
+
+	breached := make(map[string]time.Time)
+	est.Runner.checkBudgets(breached)
+	if len(alerts) != 0 {
+		t.Fatalf("Expected no alert under the limit, got %+v", alerts)
+	}
+
+	// A breach alerts once per period, not on every check
+	if err := db.InsertLogSize(4); err != nil {
+		t.Fatal(err)
+	}
+	est.Runner.checkBudgets(breached)
+	est.Runner.checkBudgets(breached)
+	if len(alerts) != 1 || alerts[0].Alert != "budget_exceeded" || alerts[0].Subject != "daily" || alerts[0].Path == "" {
+		t.Errorf("Expected one budget_exceeded alert, got %+v", alerts)
+	}
+}
+
+func TestSnapshotRestoresCachesAcrossRestart(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController("test_estimator_snapshot.db", logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove("test_estimator_snapshot.db")
+	})
+	cfg := Config{DB: db, Logger: logger, SnapshotFile: filepath.Join(t.TempDir(), "snapshot.json")}
+	get := func(est *Estimator, path string) string {
+		rr := httptest.NewRecorder()
+		est.GUIHandler.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", path, rr.Code, rr.Body.String())
+		}
+		return rr.Body.String()
+	}
+
+	est, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	est.IngestHandler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/ingest", strings.NewReader("a\n")))
+	summary := get(est, "/api/stats/summary")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- est.Runner.Run(ctx) }()
+	<-est.Runner.Ready()
+	cancel()
+	<-done
+
+	// Records written while the process is down are not seen until the
+	// restored responses are refreshed, showing they were not recomputed
+	if err := db.InsertLogSize(100); err != nil {
+		t.Fatal(err)
+	}
+	est, err = New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := get(est, "/api/stats/summary"); got != summary {
+		t.Errorf("Expected the restored summary %s, got %s", summary, got)
+	}
+	var stats struct {
+		Data handlers.APIStatsReport `json:"data"`
+	}
+	json.Unmarshal([]byte(get(est, "/api/admin/api-stats")), &stats)
+	if len(stats.Data.Routes) == 0 || stats.Data.Routes[0].Route != "/api/stats/summary" || stats.Data.Routes[0].Requests != 2 {
+		t.Errorf("Expected the requests before and after the restart to be counted, got %+v", stats.Data.Routes)
+	}
+	var eventLog struct {
+		Data handlers.EventLogReport `json:"data"`
+	}
+	json.Unmarshal([]byte(get(est, "/api/admin/events")), &eventLog)
+	if eventLog.Data.Counts[events.TopicIngestReceived] != 1 {
+		t.Errorf("Expected the ingest before the restart to be counted, got %v", eventLog.Data.Counts)
+	}
+
+	// After an unclean shutdown the saved responses are recomputed
+	if _, err := db.Recover([]string{"process 1 did not release the lock"}, true); err != nil {
+		t.Fatal(err)
+	}
+	est, err = New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := get(est, "/api/stats/summary"); got == summary {
+		t.Errorf("Expected the summary recomputed after an unclean shutdown, got the restored %s", got)
+	}
+
+	// A corrupt snapshot is ignored
+	if err := os.WriteFile(cfg.SnapshotFile, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(cfg); err != nil {
+		t.Errorf("Expected a corrupt snapshot to be ignored, got %v", err)
+	}
+}
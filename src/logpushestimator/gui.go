@@ -0,0 +1,112 @@
+package logpushestimator
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/melatonein5/LogpushEstimator/src/cloudflare"
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/gui/handlers"
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+	"github.com/melatonein5/LogpushEstimator/src/relay"
+)
+
+// newGUIMux builds the dashboard handler. API routes have their methods
+// checked and OPTIONS preflights answered per route, experimental
+// endpoints gated by feature flags, state-changing browser requests checked
+// for a CSRF token (or, with cfg.ReadOnly, rejected), and every route
+// instrumented for /api/admin/api-stats.
+//
+// Endpoints:
+//   - GET /: Main dashboard interface
+//   - GET /dashboard: Alternative dashboard path
+//   - GET /views/{name}: Dashboard rendered from a saved view
+//   - GET /api/*: REST API endpoints for data access
+//   - GET /t/{tenant}/, /t/{tenant}/api/*: Tenant-scoped dashboard and API
+//   - GET /static/*: Static assets (CSS, JS, images)
+//   - GET /health: Health check endpoint
+//   - GET /metrics: Prometheus ingest rate gauges
+func newGUIMux(cfg Config, settings *config.Watcher, cache *handlers.StatsCache, limiter *handlers.ConcurrencyLimiter, metrics *handlers.APIMetrics, eventLog *handlers.EventLog, rates *handlers.ByteRates, pipeline *handlers.IngestPipeline, labels *ingest.LabelGuard, relayQueue *relay.Queue, listeners *handlers.Listeners) http.Handler {
+	db, logger, flags := cfg.DB, cfg.Logger, cfg.Features
+	mux := http.NewServeMux()
+
+	// Dashboard routes (specific paths only), rendered with saved preferences
+	dashboardHandler := handlers.MakeDashboardHandlerWithPreferences(db, logger)
+	mux.HandleFunc("/dashboard", dashboardHandler)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// Only serve dashboard for exact root path, otherwise 404
+		if r.URL.Path == "/" {
+			dashboardHandler(w, r)
+		} else {
+			http.NotFound(w, r)
+		}
+	})
+	mux.HandleFunc("/views/", handlers.MakeViewPageHandler(db, logger))
+
+	apiHandlers := handlers.MakeAPIHandlersWithLimiter(db, logger, cache, cfg.EncryptionKey, limiter)
+	apiHandlers["/api/version"] = handlers.MakeVersionHandler(cfg.Version, flags, listeners, logger)
+	apiHandlers["/api/admin/api-stats"] = handlers.MakeAPIStatsHandler(metrics, logger)
+	apiHandlers["/api/admin/events"] = handlers.MakeEventLogHandler(eventLog, logger)
+	apiHandlers["/api/stats/rates"] = handlers.MakeRatesHandler(rates, logger)
+	apiHandlers["/api/admin/ingest-pipeline"] = handlers.MakeIngestPipelineHandler(pipeline, logger)
+	apiHandlers["/api/admin/labels"] = handlers.InvalidatesCache(cache, handlers.MakeLabelAdminHandler(labels, db, logger))
+	apiHandlers["/api/admin/relay"] = handlers.MakeRelayStatusHandler(relayQueue, logger)
+	apiHandlers["/api/alerts/"] = handlers.MakeAlertHandler(cfg.Events, db, logger)
+	apiHandlers["/api/budgets/"] = handlers.MakeBudgetHandler(cfg.Events, db, logger)
+	var cloudflareClient *cloudflare.Client
+	if cfg.Cloudflare.Enabled() {
+		cloudflareClient = cloudflare.NewClient(cfg.Cloudflare.APIToken)
+	}
+	apiHandlers["/api/cloudflare/jobs/create"] = handlers.MakeLogpushJobCreateHandler(cloudflareClient, cfg.Cloudflare, db, logger)
+	apiHandlers["/api/export/csv"] = limiter.Wrap(handlers.MakeExportCSVHandler(cfg.EncryptionKey, db, logger))
+	if cfg.ExportDir != "" {
+		exportJobs := handlers.MakeExportJobsHandler(handlers.NewExportJobs(cfg.ExportDir, cfg.EncryptionKey, db, logger), logger)
+		apiHandlers["/api/exports"] = limiter.Wrap(exportJobs)
+		apiHandlers["/api/exports/"] = exportJobs
+	}
+	apiHandlers["/api/admin/backup"] = handlers.MakeBackupHandler(cfg.EncryptionKey, db, logger)
+	apiHandlers["/api/admin/prune"] = handlers.InvalidatesCache(cache, handlers.MakePruneHandler(cfg.EncryptionKey, db, logger))
+	apiHandlers["/api/reports/chargeback"] = handlers.MakeChargebackHandler(cfg.EncryptionKey, db, logger)
+
+	// Endpoints whose responses carry token secrets (a backup holds the
+	// stored configuration) need an admin token for every request, and the
+	// other admin endpoints for the requests that change state
+	secretEndpoints := map[string]bool{"/api/admin/tenants/bulk": true, "/api/admin/tokens/": true, "/api/admin/backup": true}
+	for path, handler := range apiHandlers {
+		if secretEndpoints[path] {
+			apiHandlers[path] = handlers.WithAdminToken(settings, logger, handler)
+		} else if strings.HasPrefix(path, "/api/admin/") {
+			apiHandlers[path] = handlers.WithAdminWrites(settings, logger, path, handler)
+		}
+	}
+	for path, handler := range apiHandlers {
+		if cfg.ReadOnly {
+			handler = handlers.WithReadOnly(path, handler)
+		}
+		apiHandlers[path] = handlers.WithCSRFProtection(logger, handlers.WithFeatureGate(flags, path, handler))
+	}
+	apiHandlers.RegisterRoutes(handlers.RouterFunc(func(pattern string, handler http.Handler) {
+		mux.HandleFunc(pattern, metrics.Wrap(pattern, handler.ServeHTTP))
+	}))
+
+	// Tenant-scoped dashboards and API, wrapped like the routes above and
+	// reported under /t/{tenant}/...
+	mux.Handle("/t/", handlers.NewTenantRouter(db, logger, cache, cfg.EncryptionKey, limiter, func(path string, handler http.HandlerFunc) http.HandlerFunc {
+		if cfg.ReadOnly {
+			handler = handlers.WithReadOnly(path, handler)
+		}
+		return metrics.Wrap("/t/{tenant}"+path, handlers.WithMethods(path, handlers.WithInt64Strings(handlers.WithCSRFProtection(logger, handlers.WithFeatureGate(flags, path, handler)))))
+	}))
+
+	// Static file serving
+	mux.HandleFunc("/static/", handlers.MakeStaticFileHandler(logger))
+
+	// Health check, as on the ingestion handler, for probes that can only
+	// reach the GUI port
+	mux.HandleFunc("/health", makeHealthHandler(logger, db, cache))
+
+	// Ingest rate gauges for Prometheus scrapes
+	mux.HandleFunc("/metrics", handlers.MakeRatesMetricsHandler(rates, logger))
+
+	return handlers.WithTenantHost(cfg.TenantDomain, mux)
+}
@@ -0,0 +1,50 @@
+package logpushestimator
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/gui/handlers"
+)
+
+// Health statuses reported by /health.
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// HealthResponse is the body of /health.
+type HealthResponse struct {
+	Status   string                  `json:"status"`   // ok, or unavailable when a check failed
+	Service  string                  `json:"service"`  // Always LogpushEstimator
+	Database string                  `json:"database"` // ok, or why the database cannot be read
+	Warmup   handlers.WarmupProgress `json:"warmup"`   // Progress of warming the dashboard cache
+}
+
+// makeHealthHandler creates a health check endpoint that returns service
+// status. It responds with a JSON object containing the service status and
+// name, whether the database can be read, and the progress of warming the
+// dashboard statistics cache after startup. It answers 503 Service
+// Unavailable when the database cannot be read, so probes fail.
+func makeHealthHandler(logger *slog.Logger, db *database.SQLiteController, cache *handlers.StatsCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("Health check request", "remote_addr", r.RemoteAddr)
+		response := HealthResponse{
+			Status:   HealthOK,
+			Service:  "LogpushEstimator",
+			Database: HealthOK,
+			Warmup:   cache.Progress(),
+		}
+		status := http.StatusOK
+		if err := db.Ping(); err != nil {
+			logger.Error("Health check failed to read database", "error", err)
+			response.Status, response.Database = HealthUnavailable, err.Error()
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+	}
+}
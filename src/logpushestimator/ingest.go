@@ -0,0 +1,568 @@
+package logpushestimator
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/events"
+	"github.com/melatonein5/LogpushEstimator/src/gui/handlers"
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+	"github.com/melatonein5/LogpushEstimator/src/relay"
+)
+
+// newIngestMux builds the ingestion handler.
+//
+// Endpoints:
+//   - POST /ingest: Accept log data for size tracking
+//   - POST /ingest/{zone}: Accept log data for a zone
+//   - POST /t/{tenant}/ingest: Accept log data for a tenant
+//   - POST /t/{tenant}/ingest/{zone}: Accept log data for a tenant's zone
+//   - POST /ingest/measurements: Accept batches measured by a collector
+//   - POST /t/{tenant}/ingest/measurements: Accept measurements for a tenant
+//   - GET /health: Health check endpoint
+//
+// Once the configuration holds a token with the ingest scope, the ingest
+// endpoints require one; see requireIngestToken.
+func newIngestMux(cfg Config, settings *config.Watcher, cache *handlers.StatsCache, pipeline *handlers.IngestPipeline, labels *ingest.LabelGuard, relayQueue *relay.Queue, writes *database.WriteBuffer) *http.ServeMux {
+	mux := http.NewServeMux()
+	ingestionHandler := makeIngestionHandler(cfg, settings, pipeline, labels, relayQueue, writes)
+	measurementHandler := makeMeasurementHandler(cfg, pipeline)
+	if cfg.ReadOnly {
+		ingestionHandler = rejectIngestion(cfg)
+		measurementHandler = ingestionHandler
+	} else {
+		ingestionHandler = requireIngestToken(cfg, settings, ingestionHandler)
+		measurementHandler = requireIngestToken(cfg, settings, measurementHandler)
+	}
+	mux.HandleFunc("/ingest", ingestionHandler)
+	mux.HandleFunc("/ingest/", ingestionHandler)
+	mux.HandleFunc("/ingest/measurements", measurementHandler)
+	mux.HandleFunc("/t/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/ingest/measurements") {
+			measurementHandler(w, r)
+			return
+		}
+		ingestionHandler(w, r)
+	})
+	mux.HandleFunc("/health", makeHealthHandler(cfg.Logger, cfg.DB, cache))
+	return mux
+}
+
+// requireIngestToken answers ingestion requests that do not present a
+// valid ingest token with 401, once the configuration holds a token with
+// the ingest scope. The token is accepted in every form a Logpush HTTP
+// destination can send from its destination_conf (see ingest.Credentials),
+// so jobs authenticate without a proxy in front of the estimator. On
+// /t/{tenant}/ paths the tenant's own tokens are accepted as well as
+// instance-wide ones.
+func requireIngestToken(cfg Config, settings *config.Watcher, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant := ""
+		if strings.HasPrefix(r.URL.Path, "/t/") {
+			tenant, _, _ = handlers.ParseTenantPath(r.URL.Path)
+		}
+		doc := settings.Current()
+		if !doc.AuthenticateIngest(ingest.Credentials(r), tenant, cfg.Clock.Now()) {
+			cfg.Logger.Warn("Rejected ingestion without a valid token", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ingest"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("Missing or invalid ingest token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// rejectIngestion answers ingestion requests on a read-only instance with
+// 403, so a Logpush job pointed at it fails visibly rather than having its
+// batches silently dropped.
+func rejectIngestion(cfg Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg.Logger.Warn("Rejected ingestion on a read-only instance", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Ingestion is disabled on this read-only instance"))
+	}
+}
+
+// makeIngestionHandler creates an HTTP handler for log data ingestion.
+// It accepts POST requests containing log data and stores the payload size,
+// per-record statistics, and a timestamp in the database for monitoring purposes.
+//
+// The handler validates the HTTP method (must be POST), reads the request body,
+// measures its size, and stores this information in the database using the
+// provided SQLiteController. Each request that reaches the database is counted
+// as a success or failure for the ingest availability SLO.
+// Each batch is stored under the dataset named by the optional ?dataset=
+// parameter or X-Logpush-Dataset header, and under the zone named by the
+// path (/ingest/{zone}), the ?zone= parameter or the X-Logpush-Zone header,
+// so one instance can tell apart the zones pushing to it. Each
+// X-LPE-Label-{key} header is stored as a label of the batch, within the
+// label_limits enforced by labels. With the dataset-parsers feature
+// flag enabled, records are also parsed into dimensions for that dataset,
+// or the dataset detected from each record's fields. Every sampling.every_n-th
+// batch has its first record stored, with sensitive fields redacted.
+// Mounted at /t/{tenant}/ingest, the batch is stored under the tenant.
+// Each stored batch is published as an ingest.received event, and every
+// insert's latency is reported to pipeline for /api/admin/ingest-pipeline.
+// Logpush's destination ownership challenge is answered with 200 without
+// being stored. Batches sent with Content-Encoding: gzip, as Logpush sends
+// them, are decompressed before their records are analyzed, and both the
+// received and the decompressed size are stored.
+//
+// With a relay queue, each batch, and the ownership challenge, is also
+// forwarded unchanged to the upstream destination, and the upstream's
+// status and response are returned in place of the codes below. A batch
+// the upstream cannot take right now is queued for retry and answered as
+// without relaying, so an upstream outage does not fail delivery. A batch
+// with an invalid dataset, zone or label, or a body that cannot be
+// decoded, is relayed all the same and only left out of the local counts;
+// it is answered with the upstream's response, or 200 once queued. A batch
+// the upstream refuses is answered with its status and not stored: Logpush
+// retries it and it is counted then. When the queue is full the batch is
+// answered with 503, so Logpush keeps it.
+//
+// With a write buffer, a batch is answered as soon as it is queued and
+// written in a group with others shortly after, which keeps SQLite commits
+// out of the request path. Its outcome, insert latency and event are
+// recorded when it is written. When the buffer is full or closed the batch
+// is written before answering, as without one.
+//
+// Returns appropriate HTTP status codes:
+//   - 200 OK: Successfully processed and stored the log data
+//   - 400 Bad Request: Empty body, failed to read body, corrupt gzip body,
+//     invalid dataset, zone or label, or a label beyond label_limits under
+//     the reject policy
+//   - 404 Not Found: Invalid tenant path
+//   - 405 Method Not Allowed: Non-POST requests
+//   - 413 Request Entity Too Large: Body decompresses beyond ingest.MaxDecodedBytes
+//   - 415 Unsupported Media Type: Content-Encoding other than gzip or identity
+//   - 500 Internal Server Error: Database insertion failures
+func makeIngestionHandler(cfg Config, settings *config.Watcher, pipeline *handlers.IngestPipeline, guard *ingest.LabelGuard, upstream *relay.Queue, writes *database.WriteBuffer) http.HandlerFunc {
+	db, logger := cfg.DB, cfg.Logger
+	sampler, parser := &ingest.Sampler{}, &ingest.ScopedSampler{}
+	// forward relays body when an upstream is configured. It returns false
+	// once it has answered the request itself, because the upstream refused
+	// the batch or it could be neither delivered nor queued, and the
+	// upstream's response when it accepted the batch.
+	forward := func(w http.ResponseWriter, r *http.Request, body []byte) (*relay.Response, bool) {
+		if upstream == nil {
+			return nil, true
+		}
+		result, err := upstream.Send(r.Context(), body, r.Header)
+		if err != nil {
+			logger.Error("Failed to relay batch", "error", err, "remote_addr", r.RemoteAddr)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("Upstream destination unavailable; retry later"))
+			return nil, false
+		}
+		switch result.Outcome {
+		case relay.OutcomeRefused:
+			logger.Warn("Upstream refused relayed batch", "status", result.Response.Status, "remote_addr", r.RemoteAddr)
+			result.Response.WriteTo(w)
+			return nil, false
+		case relay.OutcomeQueued:
+			return nil, true
+		}
+		return &result.Response, true
+	}
+	// skip answers a batch that is not stored. Once the upstream has it,
+	// failing would make Logpush deliver it twice, so a relayed batch is
+	// answered with the upstream's response and a queued one with 200;
+	// without an upstream the batch is answered with status and message.
+	skip := func(w http.ResponseWriter, relayed *relay.Response, status int, message string) {
+		switch {
+		case relayed != nil:
+			relayed.WriteTo(w)
+		case upstream != nil:
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("OK"))
+		default:
+			w.WriteHeader(status)
+			w.Write([]byte(message))
+		}
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("Ingestion request received",
+			"method", r.Method,
+			"remote_addr", r.RemoteAddr,
+			"user_agent", r.UserAgent(),
+			"content_length", r.ContentLength)
+
+		tenant, zone, ok := parseIngestPath(r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		store := db.ForTenant(tenant)
+
+		if r.Method != http.MethodPost {
+			logger.Warn("Invalid HTTP method", "method", r.Method, "remote_addr", r.RemoteAddr)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte("Method not allowed"))
+			return
+		}
+
+		// A batch with an invalid dataset, zone or label cannot be stored,
+		// but is still relayed: the upstream does not depend on them
+		var invalid string
+		dataset := r.URL.Query().Get("dataset")
+		if dataset == "" {
+			dataset = r.Header.Get(ingest.DatasetHeader)
+		}
+		if dataset != "" && !ingest.ValidDataset(dataset) {
+			logger.Warn("Invalid dataset name", "dataset", dataset, "remote_addr", r.RemoteAddr)
+			invalid = "Invalid dataset name"
+		}
+		if zone == "" {
+			zone = r.URL.Query().Get("zone")
+		}
+		if zone == "" {
+			zone = r.Header.Get(ingest.ZoneHeader)
+		}
+		if zone != "" && !ingest.ValidZone(zone) && invalid == "" {
+			logger.Warn("Invalid zone name", "zone", zone, "remote_addr", r.RemoteAddr)
+			invalid = "Invalid zone name"
+		}
+		labels, err := ingest.LabelsFromHeader(r.Header)
+		if err != nil && invalid == "" {
+			logger.Warn("Invalid labels", "error", err, "remote_addr", r.RemoteAddr)
+			invalid = "Invalid labels: " + err.Error()
+		}
+		if invalid != "" && upstream == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(invalid))
+			return
+		}
+
+		// Read the entire request body to measure its size
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			logger.Error("Failed to read request body", "error", err, "remote_addr", r.RemoteAddr)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Failed to read request body"))
+			return
+		}
+		defer r.Body.Close()
+
+		// Calculate the actual body size
+		bodySize := int64(len(body))
+
+		// Validate body size is positive (not empty)
+		if bodySize <= 0 {
+			logger.Warn("Empty request body received", "body_size", bodySize, "remote_addr", r.RemoteAddr)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Request body cannot be empty"))
+			return
+		}
+
+		// Cloudflare validates a destination with a test upload before
+		// creating a job; it is acknowledged but is not log traffic
+		if ingest.IsOwnershipChallenge(body, r.Header.Get("Content-Encoding")) {
+			logger.Info("Logpush ownership challenge accepted", "remote_addr", r.RemoteAddr)
+			relayed, ok := forward(w, r, body)
+			if !ok {
+				return
+			}
+			if relayed != nil {
+				relayed.WriteTo(w)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("OK"))
+			return
+		}
+
+		// The raw body is relayed before it is decoded, so the upstream
+		// receives batches the estimator cannot read as well
+		relayed, ok := forward(w, r, body)
+		if !ok {
+			return
+		}
+		if invalid != "" {
+			skip(w, relayed, http.StatusBadRequest, invalid)
+			return
+		}
+
+		// Records are counted and sized as written, so a compressed batch
+		// is decompressed first
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+		payload, err := ingest.Decode(body, encoding)
+		if err != nil {
+			logger.Warn("Failed to decode request body", "error", err, "content_encoding", encoding, "remote_addr", r.RemoteAddr)
+			switch {
+			case errors.Is(err, ingest.ErrUnsupportedEncoding):
+				skip(w, relayed, http.StatusUnsupportedMediaType, "Content-Encoding must be gzip or identity")
+			case errors.Is(err, ingest.ErrDecodedTooLarge):
+				skip(w, relayed, http.StatusRequestEntityTooLarge, "Decompressed body is too large")
+			default:
+				skip(w, relayed, http.StatusBadRequest, "Failed to decompress request body")
+			}
+			return
+		}
+		if encoding == "identity" {
+			encoding = ""
+		}
+
+		// Derive per-record statistics from the newline-delimited batch
+		records := ingest.AnalyzeRecords(payload)
+
+		// Labels take a slot in the label limits only once the batch is
+		// about to be stored, so rejected, empty or challenge requests
+		// cannot use them up
+		labels, err = guard.Admit(labels, settings.Current().LabelLimits.Limits())
+		if err != nil {
+			logger.Warn("Rejected labels beyond the label limits", "error", err, "remote_addr", r.RemoteAddr)
+			skip(w, relayed, http.StatusBadRequest, "Rejected labels: "+err.Error())
+			return
+		}
+
+		// Insert the computed body size and record statistics into
+		// database, or queue them when a write buffer has room
+		entry := database.LogSize{
+			Timestamp:     cfg.Clock.Now(),
+			Filesize:      bodySize,
+			RecordCount:   records.Count,
+			MinRecordSize: records.MinSize,
+			MaxRecordSize: records.MaxSize,
+			AvgRecordSize: records.AvgSize,
+			Tenant:        tenant,
+			Dataset:       dataset,
+			Zone:          zone,
+			Labels:        labels,
+
+			UncompressedSize: int64(len(payload)),
+			Encoding:         encoding,
+		}
+		if writes == nil || !writes.Enqueue(entry) {
+			started := time.Now()
+			err = store.InsertLog(entry)
+			pipeline.Observe(time.Since(started), err == nil)
+			if err != nil {
+				logger.Error("Failed to insert log size", "error", err, "body_size", bodySize, "remote_addr", r.RemoteAddr)
+				db.RecordIngestOutcome(false)
+				// The upstream already has the batch; failing would make
+				// Logpush deliver it twice
+				if relayed != nil {
+					relayed.WriteTo(w)
+					return
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("Failed to write log size"))
+				return
+			}
+			ingestStored(cfg, entry)
+		}
+
+		// Dataset parsing decodes every record, so it only runs when enabled,
+		// and at high ingest rates only on one in parse_every_n batches of
+		// each scope, whose counts then stand for the scope's batches
+		// skipped. Dimensions are supplementary: failing to store them does
+		// not fail the request.
+		parseEvery := settings.Current().Sampling.ParseInterval()
+		if cfg.Features.Enabled("dataset-parsers") && parser.Next(entryScope(entry), parseEvery) {
+			counts := ingest.ExtractDimensions(dataset, payload)
+			ingest.ScaleDimensions(counts, int64(parseEvery))
+			rows := make([]database.DimensionCount, 0, len(counts))
+			for _, c := range counts {
+				rows = append(rows, database.DimensionCount(c))
+			}
+			if err := store.ForZone(zone).ForLabels(labels).AddDimensionCounts(cfg.Clock.Now(), rows); err != nil {
+				logger.Warn("Failed to store dimension counts", "error", err, "remote_addr", r.RemoteAddr)
+			}
+		}
+
+		// Store a redacted excerpt of every Nth batch for /api/samples. If
+		// the redaction rules cannot be compiled nothing is stored, since an
+		// unredacted sample must never reach the database.
+		if sampling := settings.Current().Sampling; sampler.Next(sampling.EveryN) {
+			policy, err := sampling.Policy()
+			if err != nil {
+				logger.Warn("Skipping payload sample", "error", err)
+			} else if sample, ok := ingest.SampleFirstRecord(payload, policy, sampling.SampleBytes()); ok {
+				err := db.InsertSample(database.PayloadSample{
+					Dataset:        dataset,
+					BatchBytes:     bodySize,
+					RecordCount:    records.Count,
+					Content:        sample.Content,
+					RedactedFields: sample.Redacted,
+					Truncated:      sample.Truncated,
+					Redactions:     sample.Rules,
+				}, sampling.KeepSamples())
+				if err != nil {
+					logger.Warn("Failed to store payload sample", "error", err, "remote_addr", r.RemoteAddr)
+				}
+			}
+		}
+
+		logger.Info("Log size inserted successfully", "body_size", bodySize, "uncompressed_size", len(payload), "record_count", records.Count, "remote_addr", r.RemoteAddr)
+		if relayed != nil {
+			relayed.WriteTo(w)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	}
+}
+
+// ingestStored records a batch written by the ingestion endpoint as a
+// successful ingest and publishes its ingest.received event.
+func ingestStored(cfg Config, entry database.LogSize) {
+	cfg.DB.RecordIngestOutcome(true)
+	cfg.Events.Publish(events.TopicIngestReceived, events.IngestReceived{
+		Tenant:  entry.Tenant,
+		Dataset: entry.Dataset,
+		Zone:    entry.Zone,
+		Bytes:   entry.Filesize,
+		Records: entry.RecordCount,
+	})
+}
+
+// entryScope identifies the tenant, dataset, zone and labels of entry, the
+// scope dimension counts are stored under.
+func entryScope(entry database.LogSize) string {
+	scope := []string{entry.Tenant, entry.Dataset, entry.Zone}
+	for _, key := range slices.Sorted(maps.Keys(entry.Labels)) {
+		scope = append(scope, key+"="+entry.Labels[key])
+	}
+	return strings.Join(scope, "\x00")
+}
+
+// parseIngestPath splits an ingestion path, /ingest or /ingest/{zone},
+// optionally under /t/{tenant}/, into its tenant and zone, either of which
+// is empty when the path does not name one. ok is false for any other path.
+func parseIngestPath(path string) (tenant, zone string, ok bool) {
+	rest := path
+	if strings.HasPrefix(path, "/t/") {
+		if tenant, rest, ok = handlers.ParseTenantPath(path); !ok {
+			return "", "", false
+		}
+	}
+	if rest == "/ingest" {
+		return tenant, "", true
+	}
+	zone, found := strings.CutPrefix(rest, "/ingest/")
+	if !found || zone == "" || strings.Contains(zone, "/") {
+		return "", "", false
+	}
+	return tenant, zone, true
+}
+
+// maxMeasurementsBody bounds a POST /ingest/measurements body. At about 250
+// bytes per measurement it holds tens of thousands of batches.
+const maxMeasurementsBody = 10 << 20
+
+// makeMeasurementHandler creates the handler for measurements forwarded by
+// a collector (see package collector), which measures batches where Logpush
+// delivers them and sends only their sizes and record statistics. The body
+// is {"measurements": [...]} with each entry an ingest.Measurement; every
+// entry is validated before any is stored, and each is then stored,
+// counted and published exactly as a batch posted to /ingest would be.
+// Mounted at /t/{tenant}/ingest/measurements, they are stored under the
+// tenant. The X-Measurements-Stored response header counts the entries
+// stored, so after a failure the sender can retry only the rest.
+//
+// Returns appropriate HTTP status codes:
+//   - 200 OK: Every measurement was stored
+//   - 400 Bad Request: Malformed body, no measurements or an invalid entry
+//   - 404 Not Found: Invalid tenant path
+//   - 405 Method Not Allowed: Non-POST requests
+//   - 413 Request Entity Too Large: Body larger than maxMeasurementsBody
+//   - 500 Internal Server Error: Database insertion failures
+func makeMeasurementHandler(cfg Config, pipeline *handlers.IngestPipeline) http.HandlerFunc {
+	db, logger := cfg.DB, cfg.Logger
+	return func(w http.ResponseWriter, r *http.Request) {
+		store, tenant := db, ""
+		if r.URL.Path != "/ingest/measurements" {
+			var rest string
+			var ok bool
+			tenant, rest, ok = handlers.ParseTenantPath(r.URL.Path)
+			if !ok || rest != "/ingest/measurements" {
+				http.NotFound(w, r)
+				return
+			}
+			store = db.ForTenant(tenant)
+		}
+
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte("Method not allowed"))
+			return
+		}
+
+		var body struct {
+			Measurements []ingest.Measurement `json:"measurements"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMeasurementsBody)).Decode(&body); err != nil {
+			logger.Warn("Failed to decode measurements", "error", err, "remote_addr", r.RemoteAddr)
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				w.Write([]byte("Request body is too large"))
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Request body must be a JSON object with a measurements array"))
+			return
+		}
+		if len(body.Measurements) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("No measurements given"))
+			return
+		}
+		for i, m := range body.Measurements {
+			if err := m.Validate(); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte("Measurement " + strconv.Itoa(i) + ": " + err.Error()))
+				return
+			}
+		}
+
+		for i, m := range body.Measurements {
+			started := time.Now()
+			err := store.InsertLog(database.LogSize{
+				Timestamp:     m.Timestamp,
+				Filesize:      m.Filesize,
+				RecordCount:   m.RecordCount,
+				MinRecordSize: m.MinRecordSize,
+				MaxRecordSize: m.MaxRecordSize,
+				AvgRecordSize: m.AvgRecordSize,
+				Dataset:       m.Dataset,
+				Zone:          m.Zone,
+
+				UncompressedSize: m.UncompressedSize,
+				Encoding:         m.Encoding,
+			})
+			pipeline.Observe(time.Since(started), err == nil)
+			if err != nil {
+				logger.Error("Failed to insert measurement", "error", err, "stored", i, "remote_addr", r.RemoteAddr)
+				db.RecordIngestOutcome(false)
+				w.Header().Set(ingest.StoredHeader, strconv.Itoa(i))
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("Failed to write log size"))
+				return
+			}
+			db.RecordIngestOutcome(true)
+			cfg.Events.Publish(events.TopicIngestReceived, events.IngestReceived{
+				Tenant:  tenant,
+				Dataset: m.Dataset,
+				Zone:    m.Zone,
+				Bytes:   m.Filesize,
+				Records: m.RecordCount,
+			})
+		}
+
+		logger.Info("Measurements inserted successfully", "count", len(body.Measurements), "remote_addr", r.RemoteAddr)
+		w.Header().Set(ingest.StoredHeader, strconv.Itoa(len(body.Measurements)))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	}
+}
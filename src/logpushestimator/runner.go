@@ -0,0 +1,365 @@
+package logpushestimator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/cloudflare"
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/events"
+	"github.com/melatonein5/LogpushEstimator/src/export"
+	"github.com/melatonein5/LogpushEstimator/src/gui/handlers"
+	"github.com/melatonein5/LogpushEstimator/src/notify"
+	"github.com/melatonein5/LogpushEstimator/src/relay"
+	"github.com/melatonein5/LogpushEstimator/src/reports"
+	"github.com/melatonein5/LogpushEstimator/src/sheets"
+)
+
+// Runner runs an Estimator's background jobs.
+type Runner struct {
+	cfg      Config
+	cache    *handlers.StatsCache
+	limiter  *handlers.ConcurrencyLimiter // Shared with the GUI's expensive endpoints
+	metrics  *handlers.APIMetrics
+	eventLog *handlers.EventLog
+	notifier *notify.Dispatcher
+	relay    *relay.Queue // nil when relaying is disabled
+
+	ready     chan struct{} // Closed once the statistics cache is warm
+	readyOnce sync.Once
+}
+
+// Ready returns a channel that is closed once Run has warmed the dashboard
+// statistics cache, so the dashboard's first load is served from memory.
+// Process managers should be told the service is ready only after that.
+func (r *Runner) Ready() <-chan struct{} {
+	return r.ready
+}
+
+// Run warms the dashboard statistics cache and runs the periodic jobs until
+// ctx is done, then waits for any job in progress to finish and saves the
+// in-memory caches to Config.SnapshotFile, if set. With Config.ReadOnly
+// only the cache is warmed and saved. The jobs are:
+//
+//   - removing per-minute aggregates older than database.MinuteAggregateWindow
+//   - checking derived data against raw records and repairing it
+//   - pruning raw records older than the configured retention.raw_days to
+//     the trash, archiving them first under the archive raw_policy
+//   - purging trash batches older than the configured trash retention
+//   - delivering due webhook notifications from the outbox, and removing
+//     delivered ones after notify.DeliveredRetention
+//   - retrying batches queued for the relay upstream, when relaying
+//   - checking budgets against the current period's usage
+//   - syncing zone request counts, when Cloudflare zones are configured
+//   - syncing tracked Logpush job status, when a Cloudflare token is set
+//   - annotating charts with Cloudflare status page incidents affecting
+//     Logpush, when enabled
+//   - pushing reports to Google Sheets, when a spreadsheet is configured
+//
+// Pruning publishes retention.pruned events, and each job status sync
+// publishes job.synced for every tracked job plus alert.fired for jobs
+// that have started failing. Budget checks publish alert.fired once per
+// period for each budget that has been exceeded.
+//
+// Parameters:
+//   - ctx: Context whose cancellation stops the jobs
+//
+// Returns:
+//   - error: Always nil; jobs log their failures and retry on the next tick
+func (r *Runner) Run(ctx context.Context) error {
+	cfg := r.cfg
+	db, logger := cfg.DB, cfg.Logger
+	var wg sync.WaitGroup
+	every := func(interval time.Duration, runNow bool, job func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if runNow {
+				job()
+			}
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					job()
+				}
+			}
+		}()
+	}
+
+	// Compute the dashboard's first responses in the background so the
+	// first page load after a restart does not wait on a full scan
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.cache.Warm(handlers.MakeAPIHandlersWithLimiter(db, logger, r.cache, cfg.EncryptionKey, r.limiter), logger)
+		r.readyOnce.Do(func() { close(r.ready) })
+	}()
+
+	// A read-only instance serves a copy of the writable instance's
+	// database; the jobs run there, and running them here as well would
+	// write to the copy and send notifications and reports twice
+	if cfg.ReadOnly {
+		logger.Info("Read-only mode: background jobs disabled")
+	} else {
+		r.schedule(ctx, every)
+	}
+
+	<-ctx.Done()
+	wg.Wait()
+	if err := saveSnapshot(cfg, r.cache, r.metrics, r.eventLog); err != nil {
+		logger.Error("Failed to save cache snapshot", "path", cfg.SnapshotFile, "error", err)
+	} else if cfg.SnapshotFile != "" {
+		logger.Info("Saved cache snapshot", "path", cfg.SnapshotFile)
+	}
+	return nil
+}
+
+// schedule starts the periodic jobs listed on Run with every, which runs
+// a job every interval, and once right away when runNow is set, until ctx
+// is done.
+func (r *Runner) schedule(ctx context.Context, every func(interval time.Duration, runNow bool, job func())) {
+	cfg := r.cfg
+	db, logger := cfg.DB, cfg.Logger
+
+	// Expired per-minute aggregates are removed so the high-resolution
+	// table stays bounded while long-term data remains in log_sizes
+	every(cfg.MinutePruneInterval, false, func() {
+		cutoff := r.cfg.Clock.Now().Add(-database.MinuteAggregateWindow)
+		removed, err := db.PruneMinuteAggregates(cutoff)
+		if err != nil {
+			logger.Error("Failed to prune minute aggregates", "error", err)
+			return
+		}
+		r.publishPruned("minute_aggregates", removed, cutoff)
+	})
+
+	// Rollups that drifted from the raw data after crashes or manual edits
+	// are repaired; discrepancies are reported at /api/admin/integrity
+	every(cfg.IntegrityCheckInterval, false, func() {
+		if _, err := db.CheckIntegrity(true); err != nil {
+			logger.Error("Failed to run integrity checks", "error", err)
+		}
+	})
+
+	// Without a retention policy the database grows without bound; expired
+	// raw records go to the trash, so a mistaken policy can be undone
+	every(cfg.RetentionInterval, false, r.pruneExpired)
+
+	// Deleted records can be restored through /api/admin/trash/restore
+	// until the trash retention (config retention.trash_days) expires
+	every(cfg.TrashPurgeInterval, false, func() {
+		doc, err := config.Export(db)
+		if err != nil {
+			logger.Error("Failed to read trash retention", "error", err)
+			return
+		}
+		cutoff := r.cfg.Clock.Now().Add(-doc.Retention.TrashRetention())
+		removed, err := db.PurgeTrash(cutoff)
+		if err != nil {
+			logger.Error("Failed to purge trash", "error", err)
+			return
+		}
+		r.publishPruned("trash", removed, cutoff)
+	})
+
+	// Notifications queued for webhooks are delivered with retries; what
+	// is pending or dead-lettered is listed at /api/admin/outbox
+	every(cfg.OutboxInterval, true, func() {
+		if _, err := r.notifier.DeliverDue(ctx); err != nil {
+			logger.Error("Failed to deliver webhook notifications", "error", err)
+		}
+	})
+	every(cfg.TrashPurgeInterval, false, func() {
+		if _, err := db.PruneOutbox(r.cfg.Clock.Now().Add(-notify.DeliveredRetention)); err != nil {
+			logger.Error("Failed to prune outbox", "error", err)
+		}
+	})
+
+	// Batches the relay upstream could not take are retried with backoff;
+	// the queue and circuit breaker are reported at /api/admin/relay
+	if r.relay != nil {
+		every(cfg.RelayRetryInterval, true, func() {
+			if _, err := r.relay.RetryDue(ctx); err != nil {
+				logger.Error("Failed to retry queued relay batches", "error", err)
+			}
+		})
+	}
+
+	// With the database sharded by month, each month moves to its own file
+	// once it has closed; listed at /api/admin/shards
+	if db.ShardedByMonth() {
+		every(cfg.ShardRotateInterval, false, func() {
+			if _, err := db.RotateShards(); err != nil {
+				logger.Error("Failed to rotate monthly shards", "error", err)
+			}
+		})
+	}
+
+	// Budgets are checked against the running day or month, so a breach
+	// is reported while it can still be acted on
+	breached := make(map[string]time.Time)
+	every(cfg.BudgetCheckInterval, true, func() {
+		r.checkBudgets(breached)
+	})
+
+	if cfg.Cloudflare.Enabled() {
+		client := cloudflare.NewClient(cfg.Cloudflare.APIToken)
+
+		// Each zone's hourly request counts for the last day back
+		// /api/estimates/coverage; the whole day is re-fetched each time so
+		// late analytics replace early counts
+		if zones := cfg.Cloudflare.Zones; len(zones) > 0 {
+			logger.Info("Cloudflare zone analytics sync enabled", "zones", len(zones))
+			every(cfg.ZoneTrafficSyncInterval, true, func() {
+				end := r.cfg.Clock.Now().UTC().Truncate(time.Hour)
+				if err := cloudflare.SyncZoneTraffic(ctx, client, db, zones, end.Add(-24*time.Hour), end); err != nil {
+					logger.Error("Failed to sync zone traffic", "error", err)
+				}
+			})
+		}
+
+		// The last push status of every tracked Logpush job backs
+		// /api/cloudflare/jobs/{id}/health
+		jobHealth := make(map[int64]string)
+		every(cfg.LogpushJobHealthInterval, true, func() {
+			if err := cloudflare.SyncLogpushJobHealth(ctx, client, db); err != nil {
+				logger.Error("Failed to sync logpush job health", "error", err)
+			}
+			r.publishJobHealth(jobHealth)
+		})
+	}
+
+	// Logpush incidents on the public status page are drawn over the time
+	// series from /api/annotations; no token is needed
+	if cfg.Cloudflare.StatusIncidents {
+		client := cloudflare.NewStatusClient()
+		logger.Info("Cloudflare status incident annotations enabled", "interval", cfg.StatusIncidentInterval)
+		every(cfg.StatusIncidentInterval, true, func() {
+			if _, err := cloudflare.SyncStatusIncidents(ctx, client, db); err != nil {
+				logger.Error("Failed to sync Cloudflare status incidents", "error", err)
+			}
+		})
+	}
+
+	// Monthly totals and the other configured reports replace their tabs
+	// in the spreadsheet on every push
+	if cfg.Sheets.Enabled() {
+		client := sheets.NewClient(cfg.Sheets.Credentials)
+		logger.Info("Google Sheets report push enabled", "reports", cfg.Sheets.Reports, "interval", cfg.Sheets.Interval)
+		every(cfg.Sheets.Interval, true, func() {
+			if err := sheets.Sync(ctx, client, db, cfg.Sheets, r.cfg.Clock.Now()); err != nil {
+				logger.Error("Failed to push reports to Google Sheets", "error", err)
+			}
+		})
+	}
+}
+
+// pruneExpired moves raw records older than the configured
+// retention.raw_days to the trash, as POST /api/admin/prune does. Under the
+// archive raw_policy they are first written to retention.archive_dir, and
+// nothing is deleted if that fails.
+func (r *Runner) pruneExpired() {
+	db, logger := r.cfg.DB, r.cfg.Logger
+	doc, err := config.Export(db)
+	if err != nil {
+		logger.Error("Failed to read retention settings", "error", err)
+		return
+	}
+	cutoff, ok := doc.Retention.RawCutoff(r.cfg.Clock.Now())
+	if !ok {
+		return
+	}
+	if doc.Retention.Archives() {
+		archived, err := export.Archive(db, doc.Retention.ArchiveDir, time.Unix(0, 0), cutoff, r.cfg.EncryptionKey)
+		if err != nil {
+			logger.Error("Failed to archive expired records; nothing was pruned", "error", err)
+			return
+		}
+		if archived.File != "" {
+			logger.Info("Expired records archived", "file", archived.File, "rows", archived.Manifest.Rows)
+		}
+	}
+	deleted, err := db.DeleteOlderThan(cutoff)
+	if err != nil {
+		logger.Error("Failed to prune expired records", "error", err)
+		return
+	}
+	if deleted.Rows > 0 {
+		r.cache.Invalidate()
+		logger.Info("Expired records pruned", "rows", deleted.Rows, "bytes", deleted.Bytes, "cutoff", cutoff, "trash_id", deleted.TrashID)
+	}
+	r.publishPruned("log_sizes", deleted.Rows, cutoff)
+}
+
+// publishPruned publishes a retention.pruned event when anything was
+// removed.
+func (r *Runner) publishPruned(kind string, removed int64, cutoff time.Time) {
+	if removed > 0 {
+		r.cfg.Events.Publish(events.TopicRetentionPruned, events.RetentionPruned{Kind: kind, Removed: removed, Cutoff: cutoff.UTC()})
+	}
+}
+
+// checkBudgets publishes alert.fired for each budget exceeded in its
+// current period, once per period.
+//
+// Parameters:
+//   - breached: Start of the period each budget was last reported exceeded in, updated in place
+func (r *Runner) checkBudgets(breached map[string]time.Time) {
+	db, logger := r.cfg.DB, r.cfg.Logger
+	doc, err := config.Export(db)
+	if err != nil {
+		logger.Error("Failed to read budgets", "error", err)
+		return
+	}
+	now := r.cfg.Clock.Now()
+	for _, b := range doc.Budgets {
+		start, _ := reports.BudgetPeriod(b.Period, now)
+		if breached[b.Name].Equal(start) {
+			continue
+		}
+		status, err := handlers.EvaluateBudgetNow(db, doc, b, now)
+		if err != nil {
+			logger.Error("Failed to query budget usage", "budget", b.Name, "error", err)
+			continue
+		}
+		if !status.Exceeded {
+			continue
+		}
+		breached[b.Name] = start
+		r.cfg.Events.Publish(events.TopicAlertFired, handlers.BudgetAlert(b, status))
+	}
+}
+
+// publishJobHealth publishes job.synced for every tracked job and
+// alert.fired for each job whose health changed to failing since the
+// previous call.
+//
+// Parameters:
+//   - previous: Health of each job at the previous call, updated in place
+func (r *Runner) publishJobHealth(previous map[int64]string) {
+	jobs, err := r.cfg.DB.ListLogpushJobs()
+	if err != nil {
+		r.cfg.Logger.Error("Failed to list logpush jobs", "error", err)
+		return
+	}
+	for _, job := range jobs {
+		health := job.Health()
+		r.cfg.Events.Publish(events.TopicJobSynced, events.JobSynced{JobID: job.ID, Name: job.Name, Health: health})
+		if health == database.JobHealthFailing && previous[job.ID] != database.JobHealthFailing {
+			r.cfg.Events.Publish(events.TopicAlertFired, events.AlertFired{
+				Alert:   "logpush_job_failing",
+				Subject: fmt.Sprintf("%d", job.ID),
+				Message: fmt.Sprintf("Logpush job %q is failing: %s", job.Name, job.ErrorMessage),
+				Path:    fmt.Sprintf("/api/cloudflare/jobs/%d/health", job.ID),
+			})
+		}
+		previous[job.ID] = health
+	}
+}
@@ -0,0 +1,109 @@
+package logpushestimator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/gui/handlers"
+)
+
+// snapshotVersion is the format of the snapshot file; files in another
+// format are ignored.
+const snapshotVersion = 1
+
+// snapshot is the in-memory state saved to Config.SnapshotFile when the
+// Runner stops and loaded by New, so a restart during peak ingest starts
+// with a warm dashboard instead of every client waiting on the same full
+// scans. The database's recent record buffer is not included: it is
+// refilled by a single query, while the cached responses each scan the
+// history.
+type snapshot struct {
+	Version    int                      `json:"version"`
+	SavedAt    time.Time                `json:"saved_at"`
+	StatsCache handlers.StatsCacheState `json:"stats_cache"`
+	APIMetrics handlers.APIMetricsState `json:"api_metrics"`
+	Events     handlers.EventLogState   `json:"events"`
+}
+
+// loadSnapshot restores the state saved by saveSnapshot into the cache,
+// metrics and event log. A missing file is not an error; an unreadable or
+// incompatible one is logged and the estimator starts cold. After an
+// unclean shutdown (see database.SQLiteController.Recover) the snapshot
+// predates whatever was ingested before the crash, so cached responses are
+// not restored and are recomputed instead.
+func loadSnapshot(cfg Config, cache *handlers.StatsCache, metrics *handlers.APIMetrics, eventLog *handlers.EventLog) {
+	if cfg.SnapshotFile == "" {
+		return
+	}
+	data, err := os.ReadFile(cfg.SnapshotFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	var snap snapshot
+	if err == nil {
+		err = json.Unmarshal(data, &snap)
+	}
+	if err == nil && snap.Version != snapshotVersion {
+		err = fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	if err != nil {
+		cfg.Logger.Warn("Ignoring cache snapshot", "path", cfg.SnapshotFile, "error", err)
+		return
+	}
+
+	restored := 0
+	if rec, ok := cfg.DB.LastRecovery(); ok && rec.Unclean {
+		cfg.Logger.Warn("Discarding cached responses saved before an unclean shutdown", "saved_at", snap.SavedAt)
+	} else {
+		restored = cache.Restore(snap.StatsCache)
+	}
+	metrics.Restore(snap.APIMetrics)
+	eventLog.Restore(snap.Events)
+	cfg.Logger.Info("Restored cache snapshot", "path", cfg.SnapshotFile, "saved_at", snap.SavedAt,
+		"cached_responses", restored, "routes", len(snap.APIMetrics.Routes), "events", len(snap.Events.Recent))
+}
+
+// saveSnapshot writes the cache, metrics and event log to
+// cfg.SnapshotFile, replacing it atomically so a crash mid-write leaves
+// the previous snapshot intact.
+func saveSnapshot(cfg Config, cache *handlers.StatsCache, metrics *handlers.APIMetrics, eventLog *handlers.EventLog) error {
+	if cfg.SnapshotFile == "" {
+		return nil
+	}
+	cached, err := cache.State()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot{
+		Version:    snapshotVersion,
+		SavedAt:    cfg.Clock.Now().UTC(),
+		StatsCache: cached,
+		APIMetrics: metrics.State(),
+		Events:     eventLog.State(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.SnapshotFile), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(cfg.SnapshotFile), filepath.Base(cfg.SnapshotFile)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cfg.SnapshotFile)
+}
@@ -0,0 +1,234 @@
+// Package notify delivers domain events to the webhooks in the estimator
+// configuration through a transactional outbox.
+//
+// Plain webhooks receive the event JSON. GitHub and Jira webhooks instead
+// open an issue for every alert.fired event, carrying the alert details and
+// links into the dashboard; their credentials are looked up from the
+// configuration at delivery time, so they are never written to the outbox.
+//
+// The subscriber writes each event published on the bus to the outbox
+// table, one message per webhook subscribed to its topic, before Publish
+// returns. A worker (Dispatcher.DeliverDue, run periodically by the
+// estimator's Runner) POSTs the due messages, retries failures with
+// exponential backoff, and dead-letters messages that are rejected or keep
+// failing. The queue lives in the database, so notifications survive
+// restarts. Delivery is at least once; every request carries the message ID
+// in the X-LPE-Delivery header so receivers can discard duplicates.
+//
+// # Usage
+//
+//	d := notify.New(notify.Config{
+//		DB:       db,
+//		Webhooks: func() []config.Webhook { return watcher.Current().Webhooks },
+//	})
+//	defer d.Subscribe(bus)()
+//	for range time.Tick(notify.DefaultInterval) {
+//		d.DeliverDue(ctx)
+//	}
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/clock"
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/events"
+	"github.com/melatonein5/LogpushEstimator/src/retry"
+)
+
+// Delivery defaults.
+const (
+	DefaultInterval    = 10 * time.Second   // How often the Runner delivers due messages
+	DefaultMaxAttempts = 8                  // Attempts before a message is dead-lettered
+	DefaultBackoff     = 30 * time.Second   // Wait after the first failure, doubled for each further one
+	DefaultMaxBackoff  = time.Hour          // Longest wait between attempts
+	DefaultBatchSize   = 100                // Messages sent per DeliverDue call
+	DefaultTimeout     = 10 * time.Second   // Time allowed for one delivery
+	DeliveredRetention = 7 * 24 * time.Hour // How long delivered messages are kept
+)
+
+// Config configures a Dispatcher. Zero values use the defaults above.
+type Config struct {
+	DB       *database.SQLiteController // Database holding the outbox; required
+	Webhooks func() []config.Webhook    // Returns the configured webhooks; required
+
+	Client      *http.Client  // HTTP client (default one with DefaultTimeout)
+	Clock       clock.Clock   // Source of the current time (default the system clock)
+	Logger      *slog.Logger  // Structured logger (default slog.Default())
+	MaxAttempts int           // Attempts before a message is dead-lettered
+	Backoff     time.Duration // Wait after the first failure
+	MaxBackoff  time.Duration // Longest wait between attempts
+	BatchSize   int           // Messages sent per DeliverDue call
+}
+
+// Dispatcher queues events for webhooks and delivers them.
+type Dispatcher struct {
+	cfg Config
+}
+
+// New creates a dispatcher.
+//
+// Parameters:
+//   - cfg: Outbox database, webhooks and delivery policy
+//
+// Returns:
+//   - *Dispatcher: Dispatcher; call Subscribe to start queueing events
+func New(cfg Config) *Dispatcher {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.System
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	return &Dispatcher{cfg: cfg}
+}
+
+// Subscribe queues every event published on bus for the webhooks
+// subscribed to its topic.
+//
+// Parameters:
+//   - bus: Bus to receive events from
+//
+// Returns:
+//   - func(): Stops queueing events
+func (d *Dispatcher) Subscribe(bus *events.Bus) func() {
+	return bus.Subscribe(d.Enqueue)
+}
+
+// Enqueue writes e to the outbox once for each webhook subscribed to its
+// topic, rendered as a ticket for GitHub and Jira webhooks. A failure is
+// logged; the event is then not delivered.
+func (d *Dispatcher) Enqueue(e events.Event) {
+	var msgs []database.OutboxMessage
+	var payload []byte
+	for _, h := range d.cfg.Webhooks() {
+		if !slices.Contains(h.Topics, e.Topic) {
+			continue
+		}
+		if h.Ticket() {
+			ticket, ok, err := ticketPayload(h, e)
+			if err != nil {
+				d.cfg.Logger.Error("Failed to encode ticket", "error", err, "webhook", h.Name)
+			}
+			if ok && err == nil {
+				msgs = append(msgs, database.OutboxMessage{Destination: h.Name, URL: h.URL, Topic: e.Topic, Payload: ticket})
+			}
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(e); err != nil {
+				d.cfg.Logger.Error("Failed to encode event for webhooks", "error", err, "topic", e.Topic)
+				return
+			}
+		}
+		msgs = append(msgs, database.OutboxMessage{Destination: h.Name, URL: h.URL, Topic: e.Topic, Payload: payload})
+	}
+	if err := d.cfg.DB.EnqueueOutbox(msgs); err != nil {
+		d.cfg.Logger.Error("Failed to queue webhook notifications", "error", err, "topic", e.Topic)
+	}
+}
+
+// DeliverDue sends every message whose next attempt is due, up to the
+// batch size, and records the outcome of each.
+//
+// Parameters:
+//   - ctx: Cancels in-flight deliveries; unsent messages stay due
+//
+// Returns:
+//   - int: Messages delivered
+//   - error: If the outbox could not be read
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	msgs, err := d.cfg.DB.DueOutbox(d.cfg.Clock.Now(), d.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	for _, m := range msgs {
+		if ctx.Err() != nil {
+			break
+		}
+		err := d.post(ctx, m)
+		if err == nil {
+			if d.cfg.DB.MarkOutboxDelivered(m.ID) == nil {
+				delivered++
+			}
+			continue
+		}
+		var retryAt time.Time // zero dead-letters the message
+		if attempts := m.Attempts + 1; !errors.Is(err, errRejected) && attempts < d.cfg.MaxAttempts {
+			retryAt = d.cfg.Clock.Now().Add(d.backoff(attempts))
+		}
+		d.cfg.Logger.Warn("Webhook delivery failed", "id", m.ID, "destination", m.Destination, "topic", m.Topic,
+			"attempt", m.Attempts+1, "dead_letter", retryAt.IsZero(), "error", err)
+		d.cfg.DB.MarkOutboxFailed(m.ID, err.Error(), retryAt)
+	}
+	return delivered, nil
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	return retry.Backoff(d.cfg.Backoff, d.cfg.MaxBackoff, attempts)
+}
+
+// errRejected marks responses that retrying cannot fix.
+var errRejected = errors.New("rejected")
+
+// post makes one delivery attempt.
+func (d *Dispatcher) post(ctx context.Context, m database.OutboxMessage) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.URL, bytes.NewReader(m.Payload))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errRejected, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "logpush-estimator")
+	req.Header.Set("X-LPE-Event", m.Topic)
+	req.Header.Set("X-LPE-Delivery", strconv.FormatInt(m.ID, 10))
+	for _, h := range d.cfg.Webhooks() {
+		if h.Name == m.Destination && h.Ticket() {
+			authorize(req, h)
+		}
+	}
+
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	default:
+		return fmt.Errorf("%w: webhook answered %s", errRejected, resp.Status)
+	}
+}
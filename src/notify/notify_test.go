@@ -0,0 +1,226 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/events"
+	"github.com/melatonein5/LogpushEstimator/src/testsupport"
+)
+
+// receiver answers webhook requests with queued statuses, then 200.
+type receiver struct {
+	mu       sync.Mutex
+	status   []int
+	received []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.received = append(r.received, req)
+	r.bodies = append(r.bodies, body)
+	if len(r.status) > 0 {
+		w.WriteHeader(r.status[0])
+		r.status = r.status[1:]
+	}
+}
+
+func (r *receiver) setStatus(status ...int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func (r *receiver) requests() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.received)
+}
+
+func setupDispatcher(t *testing.T, url string) (*Dispatcher, *database.SQLiteController, *testsupport.Clock, *events.Bus) {
+	t.Helper()
+	db := testsupport.NewDatabase(t)
+	clk := testsupport.NewClock(testsupport.Epoch)
+	db.SetClock(clk)
+	webhooks := []config.Webhook{
+		{Name: "alerts", URL: url, Topics: []string{events.TopicAlertFired}},
+		{Name: "everything", URL: url, Topics: events.Topics},
+	}
+	d := New(Config{
+		DB:       db,
+		Webhooks: func() []config.Webhook { return webhooks },
+		Clock:    clk,
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Backoff:  time.Minute,
+	})
+	bus := events.NewBus(clk, nil)
+	t.Cleanup(d.Subscribe(bus))
+	return d, db, clk, bus
+}
+
+func TestDispatcherDeliversToSubscribedWebhooks(t *testing.T) {
+	rcv := &receiver{}
+	server := httptest.NewServer(rcv)
+	defer server.Close()
+	d, db, _, bus := setupDispatcher(t, server.URL)
+
+	bus.Publish(events.TopicAlertFired, events.AlertFired{Alert: "test", Subject: "1"})
+	bus.Publish(events.TopicJobSynced, events.JobSynced{JobID: 1})
+
+	delivered, err := d.DeliverDue(context.Background())
+	if err != nil || delivered != 3 {
+		t.Fatalf("Expected 3 deliveries (2 webhooks for the alert, 1 for the sync), got %d, %v", delivered, err)
+	}
+	if got := rcv.received[0].Header.Get("X-LPE-Event"); got != events.TopicAlertFired {
+		t.Errorf("Expected X-LPE-Event alert.fired, got %q", got)
+	}
+	var event struct {
+		Topic string            `json:"topic"`
+		Data  events.AlertFired `json:"data"`
+	}
+	if err := json.Unmarshal(rcv.bodies[0], &event); err != nil || event.Data.Alert != "test" {
+		t.Errorf("Unexpected body %s: %v", rcv.bodies[0], err)
+	}
+
+	counts, _ := db.CountOutbox()
+	if counts[database.OutboxDelivered] != 3 || counts[database.OutboxPending] != 0 {
+		t.Errorf("Expected every message delivered, got %v", counts)
+	}
+	if delivered, _ := d.DeliverDue(context.Background()); delivered != 0 {
+		t.Errorf("Expected delivered messages not to be resent, got %d", delivered)
+	}
+}
+
+func TestDispatcherRetriesAndDeadLetters(t *testing.T) {
+	rcv := &receiver{status: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}}
+	server := httptest.NewServer(rcv)
+	defer server.Close()
+	d, db, clk, bus := setupDispatcher(t, server.URL)
+	d.cfg.Webhooks = func() []config.Webhook {
+		return []config.Webhook{{Name: "alerts", URL: server.URL, Topics: []string{events.TopicAlertFired}}}
+	}
+
+	bus.Publish(events.TopicAlertFired, events.AlertFired{Alert: "test"})
+	if n, _ := d.DeliverDue(context.Background()); n != 0 {
+		t.Fatalf("Expected the first attempt to fail, got %d deliveries", n)
+	}
+	// Not due again until the backoff has passed
+	if n, _ := d.DeliverDue(context.Background()); n != 0 || rcv.requests() != 1 {
+		t.Fatalf("Expected no attempt before the backoff, got %d requests", rcv.requests())
+	}
+	clk.Advance(time.Minute)
+	d.DeliverDue(context.Background())
+	clk.Advance(2 * time.Minute)
+	if n, _ := d.DeliverDue(context.Background()); n != 1 {
+		t.Fatalf("Expected delivery on the third attempt, got %d requests", rcv.requests())
+	}
+
+	// A rejected message is dead-lettered at once and can be retried
+	rcv.setStatus(http.StatusBadRequest)
+	bus.Publish(events.TopicAlertFired, events.AlertFired{Alert: "test"})
+	d.DeliverDue(context.Background())
+	dead, _ := db.ListOutbox(database.OutboxDead, 10)
+	if len(dead) != 1 || dead[0].Attempts != 1 || dead[0].LastError == "" {
+		t.Fatalf("Expected one dead letter, got %+v", dead)
+	}
+	if ok, err := db.RetryOutbox(dead[0].ID); !ok || err != nil {
+		t.Fatalf("RetryOutbox: %v, %v", ok, err)
+	}
+	if n, _ := d.DeliverDue(context.Background()); n != 1 {
+		t.Errorf("Expected the retried dead letter to be delivered, got %d", n)
+	}
+}
+
+func TestDispatcherDeadLettersAfterMaxAttempts(t *testing.T) {
+	rcv := &receiver{status: []int{500, 500, 500}}
+	server := httptest.NewServer(rcv)
+	defer server.Close()
+	d, db, clk, bus := setupDispatcher(t, server.URL)
+	d.cfg.MaxAttempts = 3
+	d.cfg.Webhooks = func() []config.Webhook {
+		return []config.Webhook{{Name: "alerts", URL: server.URL, Topics: []string{events.TopicAlertFired}}}
+	}
+
+	bus.Publish(events.TopicAlertFired, events.AlertFired{})
+	for i := 0; i < 5; i++ {
+		d.DeliverDue(context.Background())
+		clk.Advance(time.Hour)
+	}
+	counts, _ := db.CountOutbox()
+	if counts[database.OutboxDead] != 1 || rcv.requests() != 3 {
+		t.Errorf("Expected the message dead after 3 attempts, got %v after %d requests", counts, rcv.requests())
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := New(Config{Backoff: time.Second, MaxBackoff: 5 * time.Second})
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 60: 5 * time.Second} {
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestDispatcherOpensTickets(t *testing.T) {
+	rcv := &receiver{}
+	server := httptest.NewServer(rcv)
+	defer server.Close()
+	d, _, _, bus := setupDispatcher(t, server.URL)
+	d.cfg.Webhooks = func() []config.Webhook {
+		return []config.Webhook{
+			{Name: "github", URL: server.URL, Topics: []string{events.TopicAlertFired}, Kind: config.WebhookGitHub,
+				Secret: "ghp_token", Labels: []string{"cost"}, DashboardURL: "https://lpe.example.com/"},
+			{Name: "jira", URL: server.URL, Topics: []string{events.TopicAlertFired}, Kind: config.WebhookJira,
+				Secret: "ops@example.com:api-token", Project: "OPS"},
+		}
+	}
+
+	bus.Publish(events.TopicAlertFired, events.AlertFired{Alert: "budget_exceeded", Subject: "monthly",
+		Message: "Budget \"monthly\" is at 104.0% of its monthly limit", Path: "/api/reports/chargeback?month=2025-01"})
+	if n, err := d.DeliverDue(context.Background()); n != 2 || err != nil {
+		t.Fatalf("Expected 2 tickets, got %d, %v", n, err)
+	}
+
+	if got := rcv.received[0].Header.Get("Authorization"); got != "Bearer ghp_token" {
+		t.Errorf("Expected the GitHub token, got %q", got)
+	}
+	var issue struct {
+		Title  string   `json:"title"`
+		Body   string   `json:"body"`
+		Labels []string `json:"labels"`
+	}
+	if err := json.Unmarshal(rcv.bodies[0], &issue); err != nil {
+		t.Fatalf("Unexpected GitHub body %s: %v", rcv.bodies[0], err)
+	}
+	if issue.Title != "LogpushEstimator alert: budget_exceeded monthly" || len(issue.Labels) != 1 ||
+		!strings.Contains(issue.Body, "Details: https://lpe.example.com/api/reports/chargeback?month=2025-01") {
+		t.Errorf("Unexpected issue %+v", issue)
+	}
+
+	if got := rcv.received[1].Header.Get("Authorization"); got != "Basic b3BzQGV4YW1wbGUuY29tOmFwaS10b2tlbg==" {
+		t.Errorf("Expected basic authentication, got %q", got)
+	}
+	var jira struct {
+		Fields struct {
+			Project   struct{ Key string }  `json:"project"`
+			IssueType struct{ Name string } `json:"issuetype"`
+			Summary   string                `json:"summary"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal(rcv.bodies[1], &jira); err != nil || jira.Fields.Project.Key != "OPS" || jira.Fields.IssueType.Name != "Task" {
+		t.Errorf("Unexpected Jira body %s: %v", rcv.bodies[1], err)
+	}
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/events"
+)
+
+// defaultIssueType is the Jira issue type opened when a webhook names none.
+const defaultIssueType = "Task"
+
+// ticketPayload renders the request body that opens a ticket for an alert
+// through a GitHub or Jira webhook. Events other than alerts open nothing.
+//
+// Parameters:
+//   - h: Ticket webhook
+//   - e: Published event
+//
+// Returns:
+//   - []byte: JSON request body
+//   - bool: Whether the event opens a ticket
+//   - error: If the body could not be encoded
+func ticketPayload(h config.Webhook, e events.Event) ([]byte, bool, error) {
+	alert, ok := e.Data.(events.AlertFired)
+	if !ok {
+		return nil, false, nil
+	}
+	title := fmt.Sprintf("LogpushEstimator alert: %s %s", alert.Alert, alert.Subject)
+
+	lines := []string{
+		alert.Message,
+		"",
+		"Alert: " + alert.Alert,
+		"Subject: " + alert.Subject,
+		"Fired at: " + e.Time.UTC().Format(time.RFC3339),
+	}
+	if base := strings.TrimSuffix(h.DashboardURL, "/"); base != "" {
+		lines = append(lines, "Dashboard: "+base+"/")
+		if alert.Path != "" {
+			lines = append(lines, "Details: "+base+alert.Path)
+		}
+	}
+	description := strings.Join(lines, "\n")
+
+	labels := h.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	var body any
+	switch h.Kind {
+	case config.WebhookGitHub:
+		body = map[string]any{"title": title, "body": description, "labels": labels}
+	case config.WebhookJira:
+		issueType := h.IssueType
+		if issueType == "" {
+			issueType = defaultIssueType
+		}
+		body = map[string]any{"fields": map[string]any{
+			"project":     map[string]string{"key": h.Project},
+			"issuetype":   map[string]string{"name": issueType},
+			"summary":     title,
+			"description": description,
+			"labels":      labels,
+		}}
+	default:
+		return nil, false, nil
+	}
+	payload, err := json.Marshal(body)
+	return payload, true, err
+}
+
+// authorize adds the credentials of a ticket webhook to a delivery: a
+// bearer token for GitHub and basic authentication for Jira Cloud.
+func authorize(req *http.Request, h config.Webhook) {
+	switch h.Kind {
+	case config.WebhookGitHub:
+		req.Header.Set("Authorization", "Bearer "+h.Secret)
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	case config.WebhookJira:
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(h.Secret)))
+		req.Header.Set("Accept", "application/json")
+	}
+}
@@ -0,0 +1,170 @@
+// Package paths resolves where the estimator keeps its files, following
+// each platform's conventions instead of writing into the working
+// directory:
+//
+//   - Linux and other Unix: $XDG_DATA_HOME/logpush-estimator (default
+//     ~/.local/share/logpush-estimator) for data and
+//     $XDG_CONFIG_HOME/logpush-estimator (default ~/.config/...) for
+//     templates, or systemd's $STATE_DIRECTORY and $CONFIGURATION_DIRECTORY
+//     when run as a unit with StateDirectory= and ConfigurationDirectory=
+//   - macOS: ~/Library/Application Support/LogpushEstimator
+//   - Windows: %LOCALAPPDATA%\LogpushEstimator for data and
+//     %APPDATA%\LogpushEstimator for templates
+//
+// Each path can be overridden: LPE_DATA_DIR moves the data directory, and
+// LPE_DB_PATH, LPE_TEMPLATE_DIR, LPE_EXPORT_DIR and LPE_SNAPSHOT_FILE set
+// individual paths; LPE_SNAPSHOT_FILE=off disables the cache snapshot.
+//
+// A logpush.db left in the working directory by earlier versions keeps
+// being used until it is moved, so upgrading does not start an empty
+// database.
+package paths
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// DatabaseFile is the database file name within the data directory.
+const DatabaseFile = "logpush.db"
+
+// SnapshotFile is the cache snapshot file name within the data directory.
+const SnapshotFile = "cache-snapshot.json"
+
+// Paths are the resolved file locations.
+type Paths struct {
+	DataDir   string // Directory holding the database and exports
+	Database  string // SQLite database file
+	Templates string // Directory whose dashboard.html overrides the built-in template
+	Exports   string // Directory exports written to disk are kept in
+	Snapshot  string // File in-memory caches are saved to across restarts; empty when disabled
+	Legacy    bool   // Whether Database is a logpush.db found in the working directory
+}
+
+// Resolve returns the paths for this platform and environment.
+//
+// Parameters:
+//   - getenv: Environment lookup, normally os.Getenv
+//
+// Returns:
+//   - Paths: Resolved locations; directories are not created
+//   - error: When no home directory can be determined for a default
+func Resolve(getenv func(string) string) (Paths, error) {
+	return resolve(getenv, runtime.GOOS, fileExists)
+}
+
+// resolve implements Resolve for the named platform.
+func resolve(getenv func(string) string, goos string, exists func(string) bool) (Paths, error) {
+	var p Paths
+	var err error
+
+	if p.DataDir, err = dataDir(getenv, goos); err != nil {
+		return p, err
+	}
+	switch {
+	case getenv("LPE_DB_PATH") != "":
+		p.Database = getenv("LPE_DB_PATH")
+	case getenv("LPE_DATA_DIR") == "" && exists(DatabaseFile):
+		p.Database, p.Legacy = DatabaseFile, true
+	default:
+		p.Database = filepath.Join(p.DataDir, DatabaseFile)
+	}
+
+	if p.Exports = getenv("LPE_EXPORT_DIR"); p.Exports == "" {
+		p.Exports = filepath.Join(p.DataDir, "exports")
+	}
+
+	switch p.Snapshot = getenv("LPE_SNAPSHOT_FILE"); p.Snapshot {
+	case "":
+		p.Snapshot = filepath.Join(p.DataDir, SnapshotFile)
+	case "off":
+		p.Snapshot = ""
+	}
+
+	if p.Templates = getenv("LPE_TEMPLATE_DIR"); p.Templates == "" {
+		config, err := configDir(getenv, goos)
+		if err != nil {
+			return p, err
+		}
+		p.Templates = filepath.Join(config, "templates")
+	}
+	return p, nil
+}
+
+// dataDir returns the directory for the database and exports.
+func dataDir(getenv func(string) string, goos string) (string, error) {
+	if dir := getenv("LPE_DATA_DIR"); dir != "" {
+		return dir, nil
+	}
+	if dir := firstDir(getenv("STATE_DIRECTORY")); dir != "" {
+		return dir, nil
+	}
+	switch goos {
+	case "windows":
+		if dir := getenv("LOCALAPPDATA"); dir != "" {
+			return filepath.Join(dir, "LogpushEstimator"), nil
+		}
+		return "", errors.New("LOCALAPPDATA is not set; set LPE_DATA_DIR")
+	case "darwin", "ios":
+		home, err := homeDir(getenv)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, "Library", "Application Support", "LogpushEstimator"), nil
+	}
+	if dir := getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "logpush-estimator"), nil
+	}
+	home, err := homeDir(getenv)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "logpush-estimator"), nil
+}
+
+// configDir returns the directory for user-supplied configuration files.
+func configDir(getenv func(string) string, goos string) (string, error) {
+	if dir := firstDir(getenv("CONFIGURATION_DIRECTORY")); dir != "" {
+		return dir, nil
+	}
+	switch goos {
+	case "windows":
+		if dir := getenv("APPDATA"); dir != "" {
+			return filepath.Join(dir, "LogpushEstimator"), nil
+		}
+		return "", errors.New("APPDATA is not set; set LPE_TEMPLATE_DIR")
+	case "darwin", "ios":
+		return dataDir(getenv, goos)
+	}
+	if dir := getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "logpush-estimator"), nil
+	}
+	home, err := homeDir(getenv)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "logpush-estimator"), nil
+}
+
+// firstDir returns the first of systemd's colon-separated directory list.
+func firstDir(list string) string {
+	dir, _, _ := strings.Cut(list, ":")
+	return dir
+}
+
+// homeDir returns $HOME.
+func homeDir(getenv func(string) string) (string, error) {
+	if home := getenv("HOME"); home != "" {
+		return home, nil
+	}
+	return "", errors.New("HOME is not set; set LPE_DATA_DIR")
+}
+
+// fileExists reports whether path names an existing file.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,143 @@
+package paths
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+func noFiles(string) bool { return false }
+
+func TestResolveDefaults(t *testing.T) {
+	tests := []struct {
+		name      string
+		goos      string
+		vars      map[string]string
+		database  string
+		templates string
+	}{
+		{
+			name:      "linux",
+			goos:      "linux",
+			vars:      map[string]string{"HOME": "/home/lpe"},
+			database:  "/home/lpe/.local/share/logpush-estimator/logpush.db",
+			templates: "/home/lpe/.config/logpush-estimator/templates",
+		},
+		{
+			name:      "xdg",
+			goos:      "linux",
+			vars:      map[string]string{"HOME": "/home/lpe", "XDG_DATA_HOME": "/data", "XDG_CONFIG_HOME": "/conf"},
+			database:  "/data/logpush-estimator/logpush.db",
+			templates: "/conf/logpush-estimator/templates",
+		},
+		{
+			name:      "relative xdg ignored",
+			goos:      "freebsd",
+			vars:      map[string]string{"HOME": "/home/lpe", "XDG_DATA_HOME": "data"},
+			database:  "/home/lpe/.local/share/logpush-estimator/logpush.db",
+			templates: "/home/lpe/.config/logpush-estimator/templates",
+		},
+		{
+			name:      "systemd",
+			goos:      "linux",
+			vars:      map[string]string{"STATE_DIRECTORY": "/var/lib/lpe:/var/lib/other", "CONFIGURATION_DIRECTORY": "/etc/lpe"},
+			database:  "/var/lib/lpe/logpush.db",
+			templates: "/etc/lpe/templates",
+		},
+		{
+			name:      "darwin",
+			goos:      "darwin",
+			vars:      map[string]string{"HOME": "/Users/lpe"},
+			database:  "/Users/lpe/Library/Application Support/LogpushEstimator/logpush.db",
+			templates: "/Users/lpe/Library/Application Support/LogpushEstimator/templates",
+		},
+		{
+			name:      "windows",
+			goos:      "windows",
+			vars:      map[string]string{"LOCALAPPDATA": `C:\Users\lpe\AppData\Local`, "APPDATA": `C:\Users\lpe\AppData\Roaming`},
+			database:  filepath.Join(`C:\Users\lpe\AppData\Local`, "LogpushEstimator", "logpush.db"),
+			templates: filepath.Join(`C:\Users\lpe\AppData\Roaming`, "LogpushEstimator", "templates"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := resolve(env(tt.vars), tt.goos, noFiles)
+			if err != nil {
+				t.Fatalf("resolve failed: %v", err)
+			}
+			if p.Database != tt.database {
+				t.Errorf("Expected database %s, got %s", tt.database, p.Database)
+			}
+			if p.Templates != tt.templates {
+				t.Errorf("Expected templates %s, got %s", tt.templates, p.Templates)
+			}
+			if want := filepath.Join(p.DataDir, "exports"); p.Exports != want {
+				t.Errorf("Expected exports %s, got %s", want, p.Exports)
+			}
+			if want := filepath.Join(p.DataDir, SnapshotFile); p.Snapshot != want {
+				t.Errorf("Expected snapshot %s, got %s", want, p.Snapshot)
+			}
+		})
+	}
+}
+
+func TestResolveOverrides(t *testing.T) {
+	p, err := resolve(env(map[string]string{
+		"LPE_DB_PATH":       "/srv/lpe.db",
+		"LPE_TEMPLATE_DIR":  "/srv/templates",
+		"LPE_EXPORT_DIR":    "/srv/exports",
+		"LPE_SNAPSHOT_FILE": "/run/lpe/snapshot.json",
+		"HOME":              "/home/lpe",
+	}), "linux", noFiles)
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if p.Database != "/srv/lpe.db" || p.Templates != "/srv/templates" || p.Exports != "/srv/exports" || p.Snapshot != "/run/lpe/snapshot.json" {
+		t.Errorf("Expected overrides to apply, got %+v", p)
+	}
+
+	p, err = resolve(env(map[string]string{"LPE_DATA_DIR": "/srv/lpe", "XDG_CONFIG_HOME": "/conf"}), "linux", noFiles)
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if p.Database != "/srv/lpe/logpush.db" || p.Exports != "/srv/lpe/exports" {
+		t.Errorf("Expected LPE_DATA_DIR to move data, got %+v", p)
+	}
+
+	p, err = resolve(env(map[string]string{"LPE_SNAPSHOT_FILE": "off", "HOME": "/home/lpe"}), "linux", noFiles)
+	if err != nil || p.Snapshot != "" {
+		t.Errorf("Expected LPE_SNAPSHOT_FILE=off to disable the snapshot, got %+v, %v", p, err)
+	}
+}
+
+func TestResolveLegacyDatabase(t *testing.T) {
+	exists := func(path string) bool { return path == DatabaseFile }
+
+	p, err := resolve(env(map[string]string{"HOME": "/home/lpe"}), "linux", exists)
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if p.Database != DatabaseFile || !p.Legacy {
+		t.Errorf("Expected the working directory database to be kept, got %+v", p)
+	}
+
+	p, err = resolve(env(map[string]string{"LPE_DATA_DIR": "/srv/lpe", "HOME": "/home/lpe"}), "linux", exists)
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if p.Legacy {
+		t.Error("Expected an explicit data directory to take precedence")
+	}
+}
+
+func TestResolveWithoutHome(t *testing.T) {
+	if _, err := resolve(env(nil), "linux", noFiles); err == nil {
+		t.Error("Expected an error without HOME")
+	}
+	if _, err := resolve(env(map[string]string{"LPE_DATA_DIR": "/srv", "LPE_TEMPLATE_DIR": "/srv/t"}), "linux", noFiles); err != nil {
+		t.Errorf("Expected explicit paths to need no HOME, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package relay
+
+import (
+	"sync"
+	"time"
+)
+
+// Circuit breaker states.
+const (
+	BreakerClosed   = "closed"    // Batches are forwarded
+	BreakerOpen     = "open"      // The upstream keeps failing; batches are queued without trying it
+	BreakerHalfOpen = "half-open" // The cooldown has passed; the next batch probes the upstream
+)
+
+// breaker stops forwarding to an upstream that keeps failing, so each
+// ingest request is not held for the forwarding timeout while it is down.
+// After threshold consecutive failures it opens for cooldown, then lets one
+// probe through: success closes it, failure opens it again.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int       // Consecutive failures
+	openedAt  time.Time // When the last failure at or past the threshold happened
+	probing   bool      // A probe is in flight
+}
+
+// allow reports whether a request may be sent to the upstream at now.
+// Every allowed request must be followed by record.
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || now.Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record notes the result of a request allowed at now.
+func (b *breaker) record(ok bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if ok {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = now
+	}
+}
+
+// state returns the breaker's state at now and its consecutive failures.
+func (b *breaker) state(now time.Time) (string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.failures < b.threshold:
+		return BreakerClosed, b.failures
+	case now.Sub(b.openedAt) < b.cooldown:
+		return BreakerOpen, b.failures
+	default:
+		return BreakerHalfOpen, b.failures
+	}
+}
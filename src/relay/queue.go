@@ -0,0 +1,266 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/clock"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/retry"
+)
+
+// Queue defaults.
+const (
+	DefaultRetryInterval    = 10 * time.Second // How often the Runner retries due batches
+	DefaultBackoff          = 10 * time.Second // Wait after the first failure, doubled for each further one
+	DefaultMaxBackoff       = 10 * time.Minute // Longest wait between attempts
+	DefaultBatchSize        = 20               // Batches retried per RetryDue call
+	DefaultMaxQueueBytes    = 1 << 30          // Most bytes of batches held for retry
+	DefaultBreakerThreshold = 5                // Consecutive failures that open the circuit breaker
+	DefaultBreakerCooldown  = 30 * time.Second // How long the breaker stays open before probing
+)
+
+// Outcomes of relaying a batch, counted per hour in the database.
+const (
+	OutcomeDelivered = "delivered" // The upstream accepted the batch when it was received
+	OutcomeQueued    = "queued"    // The upstream failed or the breaker was open; the batch was queued
+	OutcomeRefused   = "refused"   // The upstream refused the batch; its answer went back to the sender
+	OutcomeRetried   = "retried"   // The upstream accepted a queued batch
+	OutcomeDropped   = "dropped"   // The upstream refused a queued batch, which was discarded
+	OutcomeOverflow  = "overflow"  // The queue was full; the sender was asked to retry
+)
+
+// ErrQueueFull is returned by Send when a batch the upstream did not accept
+// cannot be queued without exceeding the queue's size limit.
+var ErrQueueFull = errors.New("relay: queue is full")
+
+// QueueConfig configures a Queue. Zero values use the defaults above.
+type QueueConfig struct {
+	DB *database.SQLiteController // Database holding the queue; required
+
+	Clock            clock.Clock   // Source of the current time (default the system clock)
+	Logger           *slog.Logger  // Structured logger (default slog.Default())
+	Backoff          time.Duration // Wait after the first failure
+	MaxBackoff       time.Duration // Longest wait between attempts
+	BatchSize        int           // Batches retried per RetryDue call
+	MaxBytes         int64         // Most bytes of batches held for retry
+	BreakerThreshold int           // Consecutive failures that open the circuit breaker
+	BreakerCooldown  time.Duration // How long the breaker stays open before probing
+}
+
+// Queue isolates senders from upstream failures. Batches the upstream
+// cannot take right now, because it is unreachable, answers 408, 429 or
+// 5xx, or has failed repeatedly enough to open the circuit breaker, are
+// written to the database and retried with exponential backoff, so the
+// sender is acknowledged and never retries because of the upstream.
+// Batches the upstream refuses outright are not queued: its answer goes
+// back to the sender, as it would without the estimator.
+//
+// Queued batches may reach the upstream after batches received later.
+type Queue struct {
+	relay   *Relay
+	cfg     QueueConfig
+	breaker *breaker
+}
+
+// Result is how Send disposed of a batch.
+type Result struct {
+	Outcome  string   // OutcomeDelivered, OutcomeQueued or OutcomeRefused
+	Response Response // The upstream's answer; zero when the batch was queued
+}
+
+// QueueStatus describes a queue for /api/admin/relay.
+type QueueStatus struct {
+	Kind                string                  `json:"kind"`                 // Upstream kind
+	Breaker             string                  `json:"breaker"`              // BreakerClosed, BreakerOpen or BreakerHalfOpen
+	ConsecutiveFailures int                     `json:"consecutive_failures"` // Failures since the upstream last answered
+	Queue               database.RelayQueueSize `json:"queue"`                // Batches waiting to be retried
+	MaxBytes            int64                   `json:"max_bytes"`            // Size limit of the queue
+	Outcomes            map[string]int64        `json:"outcomes_24h"`         // Outcomes over the last 24 hours
+}
+
+// NewQueue creates a queue in front of r.
+//
+// Parameters:
+//   - r: Relay to the upstream
+//   - cfg: Queue database and retry policy
+//
+// Returns:
+//   - *Queue: Queue; call RetryDue periodically to drain it
+func NewQueue(r *Relay, cfg QueueConfig) *Queue {
+	if cfg.Clock == nil {
+		cfg.Clock = clock.System
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxQueueBytes
+	}
+	if cfg.BreakerThreshold <= 0 {
+		cfg.BreakerThreshold = DefaultBreakerThreshold
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = DefaultBreakerCooldown
+	}
+	return &Queue{
+		relay:   r,
+		cfg:     cfg,
+		breaker: &breaker{threshold: cfg.BreakerThreshold, cooldown: cfg.BreakerCooldown},
+	}
+}
+
+// Send forwards a batch to the upstream, or queues it when the upstream
+// cannot take it. The forwarding request is not cancelled with ctx, so a
+// sender that disconnects does not leave the upstream with half a batch.
+//
+// Parameters:
+//   - ctx: Context of the ingest request
+//   - body: Batch as received, still content-encoded
+//   - header: Headers of the ingest request
+//
+// Returns:
+//   - Result: Whether the batch was delivered, queued or refused
+//   - error: ErrQueueFull, or a database error, when the batch was neither
+//     delivered nor queued
+func (q *Queue) Send(ctx context.Context, body []byte, header http.Header) (Result, error) {
+	now := q.cfg.Clock.Now()
+	reason := "circuit breaker open"
+	if q.breaker.allow(now) {
+		resp, err := q.forward(context.WithoutCancel(ctx), body, header)
+		q.breaker.record(err == nil && !retryable(resp.Status), now)
+		switch {
+		case err != nil:
+			reason = err.Error()
+		case resp.OK():
+			q.cfg.DB.RecordRelayOutcome(OutcomeDelivered)
+			return Result{Outcome: OutcomeDelivered, Response: resp}, nil
+		case retryable(resp.Status):
+			reason = fmt.Sprintf("upstream answered %d", resp.Status)
+		default:
+			q.cfg.DB.RecordRelayOutcome(OutcomeRefused)
+			return Result{Outcome: OutcomeRefused, Response: resp}, nil
+		}
+	}
+
+	size, err := q.cfg.DB.RelayQueueSize()
+	if err != nil {
+		return Result{}, err
+	}
+	if size.Bytes+int64(len(body)) > q.cfg.MaxBytes {
+		q.cfg.DB.RecordRelayOutcome(OutcomeOverflow)
+		q.cfg.Logger.Error("Relay queue is full", "reason", reason, "queued_bytes", size.Bytes, "max_bytes", q.cfg.MaxBytes)
+		return Result{}, ErrQueueFull
+	}
+	_, err = q.cfg.DB.EnqueueRelay(database.RelayBatch{
+		ContentType:     header.Get("Content-Type"),
+		ContentEncoding: header.Get("Content-Encoding"),
+		Body:            body,
+		LastError:       reason,
+	}, now.Add(q.backoff(1)))
+	if err != nil {
+		return Result{}, err
+	}
+	q.cfg.DB.RecordRelayOutcome(OutcomeQueued)
+	q.cfg.Logger.Warn("Queued batch for the upstream", "reason", reason, "size", len(body))
+	return Result{Outcome: OutcomeQueued}, nil
+}
+
+// RetryDue forwards queued batches whose next attempt is due, oldest
+// first, up to the batch size. It stops early while the circuit breaker
+// is open; the remaining batches stay due.
+//
+// Parameters:
+//   - ctx: Cancels in-flight forwarding; unsent batches stay due
+//
+// Returns:
+//   - int: Batches the upstream accepted
+//   - error: If the queue could not be read
+func (q *Queue) RetryDue(ctx context.Context) (int, error) {
+	batches, err := q.cfg.DB.DueRelay(q.cfg.Clock.Now(), q.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	for _, b := range batches {
+		now := q.cfg.Clock.Now()
+		if ctx.Err() != nil || !q.breaker.allow(now) {
+			break
+		}
+		header := http.Header{}
+		header.Set("Content-Type", b.ContentType)
+		header.Set("Content-Encoding", b.ContentEncoding)
+		resp, err := q.forward(ctx, b.Body, header)
+		q.breaker.record(err == nil && !retryable(resp.Status), now)
+		switch {
+		case err == nil && resp.OK():
+			if q.cfg.DB.DeleteRelay(b.ID) == nil {
+				q.cfg.DB.RecordRelayOutcome(OutcomeRetried)
+				delivered++
+			}
+		case err == nil && !retryable(resp.Status):
+			q.cfg.Logger.Error("Upstream refused queued batch; discarding it", "id", b.ID, "status", resp.Status,
+				"attempt", b.Attempts+1, "queued_at", b.CreatedAt)
+			if q.cfg.DB.DeleteRelay(b.ID) == nil {
+				q.cfg.DB.RecordRelayOutcome(OutcomeDropped)
+			}
+		default:
+			reason := fmt.Sprintf("upstream answered %d", resp.Status)
+			if err != nil {
+				reason = err.Error()
+			}
+			q.cfg.Logger.Warn("Retrying queued batch failed", "id", b.ID, "attempt", b.Attempts+1, "error", reason)
+			q.cfg.DB.RescheduleRelay(b.ID, reason, now.Add(q.backoff(b.Attempts+1)))
+		}
+	}
+	return delivered, nil
+}
+
+// Status reports the breaker, the queued batches and the last day's
+// outcomes.
+func (q *Queue) Status() (QueueStatus, error) {
+	now := q.cfg.Clock.Now()
+	s := QueueStatus{Kind: q.relay.settings.Kind, MaxBytes: q.cfg.MaxBytes}
+	s.Breaker, s.ConsecutiveFailures = q.breaker.state(now)
+	var err error
+	if s.Queue, err = q.cfg.DB.RelayQueueSize(); err != nil {
+		return QueueStatus{}, err
+	}
+	if s.Outcomes, err = q.cfg.DB.RelayOutcomesSince(now.Add(-24 * time.Hour)); err != nil {
+		return QueueStatus{}, err
+	}
+	return s, nil
+}
+
+// forward sends a batch upstream and records the request's latency and
+// status for the destination.
+func (q *Queue) forward(ctx context.Context, body []byte, header http.Header) (Response, error) {
+	started := time.Now()
+	resp, err := q.relay.Forward(ctx, body, header)
+	q.cfg.DB.RecordRelayForward(q.relay.Destination(), resp.Status, time.Since(started))
+	return resp, err
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func (q *Queue) backoff(attempts int) time.Duration {
+	return retry.Backoff(q.cfg.Backoff, q.cfg.MaxBackoff, attempts)
+}
+
+// retryable reports whether an upstream status means the batch may be
+// accepted later: a timeout, rate limiting or a server error.
+func retryable(status int) bool {
+	return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500
+}
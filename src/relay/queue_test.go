@@ -0,0 +1,161 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/clock"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestQueue(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController("test_relay_queue.db", logger)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer func() {
+		db.Close()
+		os.Remove("test_relay_queue.db")
+	}()
+
+	var (
+		mu       sync.Mutex
+		status   = http.StatusOK
+		received []string
+	)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, string(data)+" "+r.Header.Get("Content-Encoding"))
+		w.WriteHeader(status)
+	}))
+	defer upstream.Close()
+	answer := func(code int) {
+		mu.Lock()
+		defer mu.Unlock()
+		status, received = code, nil
+	}
+	hits := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received)
+	}
+
+	now := time.Date(2025, 9, 15, 12, 0, 0, 0, time.UTC)
+	clk := clock.Func(func() time.Time { return now })
+	db.SetClock(clk)
+	q := NewQueue(New(Settings{URL: upstream.URL, Kind: KindHTTP}), QueueConfig{
+		DB:               db,
+		Clock:            clk,
+		Logger:           logger,
+		Backoff:          time.Minute,
+		MaxBytes:         20,
+		BreakerThreshold: 2,
+		BreakerCooldown:  5 * time.Minute,
+	})
+	ctx := context.Background()
+	gzip := http.Header{"Content-Encoding": {"gzip"}}
+	send := func(body string, want string) {
+		t.Helper()
+		result, err := q.Send(ctx, []byte(body), gzip)
+		if err != nil || result.Outcome != want {
+			t.Fatalf("Expected %s for %q, got %+v, %v", want, body, result, err)
+		}
+	}
+
+	send("accepted", OutcomeDelivered)
+	answer(http.StatusBadRequest)
+	send("refused", OutcomeRefused)
+
+	// Two failures open the breaker; the third batch is queued without
+	// trying the upstream
+	answer(http.StatusServiceUnavailable)
+	send("one", OutcomeQueued)
+	send("two", OutcomeQueued)
+	send("three", OutcomeQueued)
+	if hits() != 2 {
+		t.Errorf("Expected the open breaker to skip the upstream, got %d requests", hits())
+	}
+	if s, _ := q.Status(); s.Breaker != BreakerOpen || s.Queue.Batches != 3 || s.Queue.Bytes != 11 {
+		t.Errorf("Unexpected status %+v", s)
+	}
+	if _, err := q.Send(ctx, []byte("too large to queue"), gzip); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	// Nothing is retried before the backoff, or while the breaker is open
+	if n, err := q.RetryDue(ctx); err != nil || n != 0 || hits() != 2 {
+		t.Errorf("Expected nothing retried yet, got %d, %v", n, err)
+	}
+
+	// Once the cooldown has passed, the probe succeeds and the queue drains
+	// in order, keeping each batch's encoding
+	now = now.Add(5 * time.Minute)
+	answer(http.StatusOK)
+	if n, err := q.RetryDue(ctx); err != nil || n != 3 {
+		t.Fatalf("Expected three batches retried, got %d, %v", n, err)
+	}
+	mu.Lock()
+	if len(received) != 3 || received[0] != "one gzip" || received[2] != "three gzip" {
+		t.Errorf("Unexpected retried batches %q", received)
+	}
+	mu.Unlock()
+
+	// A queued batch the upstream later refuses is dropped
+	answer(http.StatusBadGateway)
+	send("four", OutcomeQueued)
+	now = now.Add(time.Minute)
+	answer(http.StatusForbidden)
+	if n, err := q.RetryDue(ctx); err != nil || n != 0 {
+		t.Errorf("Expected the refused batch not to count as delivered, got %d, %v", n, err)
+	}
+
+	s, err := q.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	want := map[string]int64{OutcomeDelivered: 1, OutcomeRefused: 1, OutcomeQueued: 4, OutcomeOverflow: 1, OutcomeRetried: 3, OutcomeDropped: 1}
+	if s.Breaker != BreakerClosed || s.Queue.Batches != 0 || len(s.Outcomes) != len(want) {
+		t.Errorf("Unexpected status %+v", s)
+	}
+	for outcome, n := range want {
+		if s.Outcomes[outcome] != n {
+			t.Errorf("Expected %d %s, got %d", n, outcome, s.Outcomes[outcome])
+		}
+	}
+
+	// Every request sent upstream is recorded for /api/stats/forwarding
+	forwards, err := db.RelayForwardsByTimeRange(now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("RelayForwardsByTimeRange failed: %v", err)
+	}
+	requests := map[int]int64{}
+	for _, f := range forwards {
+		if f.Destination != upstream.Listener.Addr().String() {
+			t.Errorf("Expected the upstream's host as destination, got %q", f.Destination)
+		}
+		requests[f.Status] += f.Requests
+	}
+	if requests[200] != 4 || requests[400] != 1 || requests[503] != 2 || requests[502] != 1 || requests[403] != 1 {
+		t.Errorf("Unexpected forwarding requests per status %v", requests)
+	}
+}
+
+func TestQueueBackoff(t *testing.T) {
+	q := NewQueue(New(Settings{URL: "http://upstream"}), QueueConfig{Backoff: time.Minute, MaxBackoff: 5 * time.Minute})
+	for attempts, want := range map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 3: 4 * time.Minute, 4: 5 * time.Minute, 20: 5 * time.Minute} {
+		if got := q.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Package relay forwards ingested Logpush batches to the destination they
+// were meant for, so the estimator can be placed transparently in front of
+// an existing pipeline: Logpush pushes to the estimator, which measures each
+// batch and passes it on unchanged.
+//
+// The estimator must never be the reason delivery fails, so a Queue stands
+// between senders and the upstream. A batch the upstream accepts is
+// answered with the upstream's status. A batch it cannot take right now,
+// because it is unreachable, answers 408, 429 or 5xx, or has failed often
+// enough to open the circuit breaker, is queued in the database,
+// acknowledged with 200, and retried with exponential backoff until the
+// upstream accepts or refuses it. A batch the upstream refuses with any
+// other status is answered with that status, so the sender sees the error.
+// Outcomes are counted per hour and reported with the queue and breaker
+// state at /api/admin/relay. The latency and status code of every request
+// sent upstream are recorded per destination for /api/stats/forwarding.
+//
+// Three kinds of upstream are supported:
+//
+//   - http: any HTTP endpoint; batches are POSTed as received, with the
+//     token, if set, sent as a bearer token
+//   - s3: an S3 (or S3-compatible) presigned PUT URL; batches are PUT as
+//     received. A presigned URL names a single object, so every batch
+//     replaces the last one unless the bucket keeps versions
+//   - splunk-hec: a Splunk HTTP Event Collector endpoint such as
+//     https://splunk.example.com:8088/services/collector/raw; the token is
+//     sent as "Authorization: Splunk <token>"
+//
+// Batches keep their Content-Encoding, so gzip batches from Logpush reach
+// the upstream compressed, as they would without the estimator.
+//
+// # Configuration
+//
+//	LPE_RELAY_URL=https://hec.example.com:8088/services/collector/raw  Upstream destination; unset disables relaying
+//	LPE_RELAY_KIND=splunk-hec                                          http, s3 or splunk-hec (default http)
+//	LPE_RELAY_TOKEN=...                                                Bearer or HEC token; required for splunk-hec
+//	LPE_RELAY_TIMEOUT=30s                                              Time allowed for one forwarding request (default 30s)
+//	LPE_RELAY_QUEUE_MAX_MB=1024                                        Most megabytes of batches queued for retry (default 1024)
+//
+// # Usage
+//
+//	settings, err := relay.SettingsFromEnv(os.Getenv)
+//	if err != nil {
+//		return err
+//	}
+//	if settings.Enabled() {
+//		q := relay.NewQueue(relay.New(settings), relay.QueueConfig{DB: db, MaxBytes: settings.QueueMaxBytes})
+//		result, err := q.Send(ctx, body, r.Header)
+//		go func() {
+//			for range time.Tick(relay.DefaultRetryInterval) {
+//				q.RetryDue(ctx)
+//			}
+//		}()
+//	}
+package relay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Upstream kinds.
+const (
+	KindHTTP      = "http"
+	KindS3        = "s3"
+	KindSplunkHEC = "splunk-hec"
+)
+
+// DefaultTimeout is the time allowed for one forwarding request when
+// LPE_RELAY_TIMEOUT is not set.
+const DefaultTimeout = 30 * time.Second
+
+// maxResponseBody is the most of an upstream's response body passed back
+// to the sender; upstream answers to Logpush are short acknowledgements.
+const maxResponseBody = 64 << 10
+
+// forwardedHeaders are the request headers passed on to the upstream.
+var forwardedHeaders = []string{"Content-Type", "Content-Encoding"}
+
+// Settings configures the upstream batches are relayed to.
+type Settings struct {
+	URL     string        // Upstream destination; empty disables relaying
+	Kind    string        // KindHTTP, KindS3 or KindSplunkHEC
+	Token   string        // Bearer token for http, HEC token for splunk-hec; unused for s3
+	Timeout time.Duration // Time allowed for one forwarding request
+
+	QueueMaxBytes int64 // Most bytes of batches queued for retry (default DefaultMaxQueueBytes)
+}
+
+// SettingsFromEnv reads the LPE_RELAY_* variables.
+//
+// Parameters:
+//   - getenv: Environment lookup, normally os.Getenv
+//
+// Returns:
+//   - Settings: Parsed settings; disabled when no URL is configured
+//   - error: If relaying is configured but invalid
+func SettingsFromEnv(getenv func(string) string) (Settings, error) {
+	s := Settings{
+		URL:     strings.TrimSpace(getenv("LPE_RELAY_URL")),
+		Kind:    strings.TrimSpace(getenv("LPE_RELAY_KIND")),
+		Token:   strings.TrimSpace(getenv("LPE_RELAY_TOKEN")),
+		Timeout: DefaultTimeout,
+	}
+	if s.URL == "" {
+		return s, nil
+	}
+	if s.Kind == "" {
+		s.Kind = KindHTTP
+	}
+	if v := getenv("LPE_RELAY_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			return s, fmt.Errorf("relay: LPE_RELAY_TIMEOUT must be a positive duration such as 30s, got %q", v)
+		}
+		s.Timeout = timeout
+	}
+	if v := getenv("LPE_RELAY_QUEUE_MAX_MB"); v != "" {
+		mb, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || mb <= 0 {
+			return s, fmt.Errorf("relay: LPE_RELAY_QUEUE_MAX_MB must be a positive number of megabytes, got %q", v)
+		}
+		s.QueueMaxBytes = mb << 20
+	}
+	return s, s.Validate()
+}
+
+// Validate checks that s names a usable upstream.
+func (s Settings) Validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("relay: LPE_RELAY_URL must be an http or https URL, got %q", s.URL)
+	}
+	switch s.Kind {
+	case KindHTTP, KindS3:
+	case KindSplunkHEC:
+		if s.Token == "" {
+			return errors.New("relay: LPE_RELAY_TOKEN is required for splunk-hec")
+		}
+	default:
+		return fmt.Errorf("relay: unknown LPE_RELAY_KIND %q (use http, s3 or splunk-hec)", s.Kind)
+	}
+	return nil
+}
+
+// Enabled reports whether an upstream is configured.
+func (s Settings) Enabled() bool {
+	return s.URL != ""
+}
+
+// Response is an upstream's answer to a forwarded batch.
+type Response struct {
+	Status      int    // HTTP status code
+	ContentType string // Content-Type of Body, if any
+	Body        []byte // Start of the response body
+}
+
+// OK reports whether the upstream accepted the batch.
+func (r Response) OK() bool {
+	return r.Status/100 == 2
+}
+
+// WriteTo answers a request with the upstream's response.
+func (r Response) WriteTo(w http.ResponseWriter) {
+	if r.ContentType != "" {
+		w.Header().Set("Content-Type", r.ContentType)
+	}
+	w.WriteHeader(r.Status)
+	w.Write(r.Body)
+}
+
+// Relay forwards batches to one upstream. It is safe for concurrent use.
+type Relay struct {
+	settings Settings
+	client   *http.Client
+}
+
+// New creates a relay to the upstream in s, which should be valid.
+func New(s Settings) *Relay {
+	if s.Timeout <= 0 {
+		s.Timeout = DefaultTimeout
+	}
+	return &Relay{settings: s, client: &http.Client{Timeout: s.Timeout}}
+}
+
+// Destination names the upstream in forwarding statistics: the host and
+// port of its URL. The path and query are left out, since a presigned S3
+// URL carries its signature there.
+func (r *Relay) Destination() string {
+	u, err := url.Parse(r.settings.URL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// Forward sends a batch to the upstream as it was received.
+//
+// Parameters:
+//   - ctx: Cancels the forwarding request, e.g. the ingest request's context
+//   - body: Batch as received, still content-encoded
+//   - header: Headers of the ingest request; Content-Type and
+//     Content-Encoding are passed on
+//
+// Returns:
+//   - Response: The upstream's status and response
+//   - error: When the upstream could not be reached
+func (r *Relay) Forward(ctx context.Context, body []byte, header http.Header) (Response, error) {
+	method := http.MethodPost
+	if r.settings.Kind == KindS3 {
+		method = http.MethodPut
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.settings.URL, bytes.NewReader(body))
+	if err != nil {
+		return Response{}, err
+	}
+	for _, name := range forwardedHeaders {
+		if v := header.Get(name); v != "" {
+			req.Header.Set(name, v)
+		}
+	}
+	switch {
+	case r.settings.Kind == KindSplunkHEC:
+		req.Header.Set("Authorization", "Splunk "+r.settings.Token)
+	case r.settings.Kind == KindHTTP && r.settings.Token != "":
+		req.Header.Set("Authorization", "Bearer "+r.settings.Token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return Response{}, fmt.Errorf("relay: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		return Response{}, fmt.Errorf("relay: read upstream response: %w", err)
+	}
+	return Response{Status: resp.StatusCode, ContentType: resp.Header.Get("Content-Type"), Body: data}, nil
+}
@@ -0,0 +1,94 @@
+package relay
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSettingsFromEnv(t *testing.T) {
+	env := map[string]string{
+		"LPE_RELAY_URL":     " https://hec.example.com:8088/services/collector/raw ",
+		"LPE_RELAY_KIND":    "splunk-hec",
+		"LPE_RELAY_TOKEN":   "hec-token",
+		"LPE_RELAY_TIMEOUT": "5s",
+
+		"LPE_RELAY_QUEUE_MAX_MB": "64",
+	}
+	s, err := SettingsFromEnv(func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatalf("SettingsFromEnv failed: %v", err)
+	}
+	if !s.Enabled() || s.URL != "https://hec.example.com:8088/services/collector/raw" || s.Kind != KindSplunkHEC || s.Timeout != 5*time.Second || s.QueueMaxBytes != 64<<20 {
+		t.Errorf("Unexpected settings %+v", s)
+	}
+
+	if s, err := SettingsFromEnv(func(string) string { return "" }); err != nil || s.Enabled() {
+		t.Errorf("Expected relaying disabled without a URL, got %+v, %v", s, err)
+	}
+	if s, err := SettingsFromEnv(func(k string) string { return map[string]string{"LPE_RELAY_URL": "http://upstream"}[k] }); err != nil || s.Kind != KindHTTP {
+		t.Errorf("Expected the http kind by default, got %+v, %v", s, err)
+	}
+
+	for name, bad := range map[string]map[string]string{
+		"relative URL":   {"LPE_RELAY_URL": "/ingest"},
+		"unknown kind":   {"LPE_RELAY_URL": "http://upstream", "LPE_RELAY_KIND": "kafka"},
+		"missing token":  {"LPE_RELAY_URL": "http://upstream", "LPE_RELAY_KIND": "splunk-hec"},
+		"bad timeout":    {"LPE_RELAY_URL": "http://upstream", "LPE_RELAY_TIMEOUT": "soon"},
+		"zero timeout":   {"LPE_RELAY_URL": "http://upstream", "LPE_RELAY_TIMEOUT": "0s"},
+		"unknown scheme": {"LPE_RELAY_URL": "ftp://upstream"},
+		"bad queue size": {"LPE_RELAY_URL": "http://upstream", "LPE_RELAY_QUEUE_MAX_MB": "1GB"},
+	} {
+		if _, err := SettingsFromEnv(func(k string) string { return bad[k] }); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestForward(t *testing.T) {
+	var method, auth, encoding, body string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, auth, encoding, body = r.Method, r.Header.Get("Authorization"), r.Header.Get("Content-Encoding"), string(data)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer upstream.Close()
+
+	header := http.Header{"Content-Encoding": {"gzip"}, "X-Forwarded-For": {"203.0.113.7"}}
+	for _, tt := range []struct {
+		kind, token, method, auth string
+	}{
+		{KindHTTP, "", "POST", ""},
+		{KindHTTP, "secret", "POST", "Bearer secret"},
+		{KindS3, "ignored", "PUT", ""},
+		{KindSplunkHEC, "hec-token", "POST", "Splunk hec-token"},
+	} {
+		resp, err := New(Settings{URL: upstream.URL, Kind: tt.kind, Token: tt.token}).Forward(context.Background(), []byte("batch"), header)
+		if err != nil {
+			t.Fatalf("%s: Forward failed: %v", tt.kind, err)
+		}
+		if !resp.OK() || resp.ContentType != "application/json" || string(resp.Body) != `{"text":"Success","code":0}` {
+			t.Errorf("%s: unexpected response %+v", tt.kind, resp)
+		}
+		if method != tt.method || auth != tt.auth || encoding != "gzip" || body != "batch" {
+			t.Errorf("%s: upstream got %s with %q, %q and %q", tt.kind, method, auth, encoding, body)
+		}
+	}
+
+	// Refusals are returned as responses; only unreachable upstreams fail
+	refusing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "slow down", http.StatusServiceUnavailable)
+	}))
+	resp, err := New(Settings{URL: refusing.URL, Kind: KindHTTP}).Forward(context.Background(), []byte("batch"), nil)
+	if err != nil || resp.OK() || resp.Status != http.StatusServiceUnavailable {
+		t.Errorf("Expected the upstream's 503, got %+v, %v", resp, err)
+	}
+	refusing.Close()
+	if _, err := New(Settings{URL: refusing.URL, Kind: KindHTTP}).Forward(context.Background(), []byte("batch"), nil); err == nil {
+		t.Error("Expected an error for an unreachable upstream")
+	}
+}
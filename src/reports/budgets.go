@@ -0,0 +1,77 @@
+package reports
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// BudgetStatus is a budget's spend so far in its current period.
+type BudgetStatus struct {
+	Budget   string    `json:"budget"`   // Budget name
+	Period   string    `json:"period"`   // "daily" or "monthly"
+	Start    time.Time `json:"start"`    // First instant of the period, UTC
+	End      time.Time `json:"end"`      // First instant of the next period, UTC
+	Bytes    int64     `json:"bytes"`    // Bytes ingested in the period
+	Cost     float64   `json:"cost"`     // Cost of the period's usage, rounded to cents; 0 without a cost limit
+	Currency string    `json:"currency"` // Currency of Cost
+	Percent  float64   `json:"percent"`  // Highest share of a limit used, in percent
+	Exceeded bool      `json:"exceeded"` // Whether a limit has been reached
+}
+
+// BudgetPeriod returns the bounds of the budget period containing t: the
+// UTC day for daily budgets and the UTC month otherwise.
+//
+// Parameters:
+//   - period: "daily" or "monthly"
+//   - t: Time within the period
+//
+// Returns:
+//   - time.Time: First instant of the period, UTC
+//   - time.Time: First instant of the next period, UTC
+func BudgetPeriod(period string, t time.Time) (time.Time, time.Time) {
+	if period == "daily" {
+		start := t.UTC().Truncate(24 * time.Hour)
+		return start, start.AddDate(0, 0, 1)
+	}
+	return MonthBounds(t)
+}
+
+// EvaluateBudget compares the usage of a budget period with the budget's
+// limits. The cost limit is checked against the usage priced with model,
+// like a chargeback. A budget scoped to a tenant only counts that tenant's
+// usage.
+//
+// Parameters:
+//   - b: Budget to evaluate
+//   - model: Pricing model of the budget's cost limit
+//   - start: First instant of the period
+//   - usage: Usage per tenant in the period
+//   - requests: Requests per tenant as for Allocate; nil prices batches as requests
+//
+// Returns:
+//   - BudgetStatus: Spend against the limits
+func EvaluateBudget(b config.Budget, model config.PricingModel, start time.Time, usage []database.TenantUsage, requests map[string]int64) BudgetStatus {
+	_, end := BudgetPeriod(b.Period, start)
+	status := BudgetStatus{Budget: b.Name, Period: b.Period, Start: start.UTC(), End: end}
+	if b.Tenant != "" {
+		usage = slices.DeleteFunc(slices.Clone(usage), func(u database.TenantUsage) bool { return u.Tenant != b.Tenant })
+	}
+	for _, u := range usage {
+		status.Bytes += u.TotalSize
+	}
+	if b.LimitBytes > 0 {
+		status.Percent = float64(status.Bytes) / float64(b.LimitBytes) * 100
+	}
+	if b.LimitCost > 0 {
+		priced := Allocate(start, model, usage, requests)
+		status.Cost, status.Currency = priced.TotalCost, priced.Currency
+		status.Percent = max(status.Percent, status.Cost/b.LimitCost*100)
+	}
+	status.Percent = math.Round(status.Percent*10) / 10
+	status.Exceeded = (b.LimitBytes > 0 && status.Bytes >= b.LimitBytes) || (b.LimitCost > 0 && status.Cost >= b.LimitCost)
+	return status
+}
@@ -0,0 +1,46 @@
+package reports
+
+import (
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestBudgetPeriod(t *testing.T) {
+	at := time.Date(2025, 9, 17, 8, 30, 0, 0, time.UTC)
+	start, end := BudgetPeriod("daily", at)
+	if !start.Equal(time.Date(2025, 9, 17, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 9, 18, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected daily period %v to %v", start, end)
+	}
+	start, end = BudgetPeriod("monthly", at)
+	if !start.Equal(time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected monthly period %v to %v", start, end)
+	}
+}
+
+func TestEvaluateBudget(t *testing.T) {
+	model := config.PricingModel{Name: "r2", Currency: "USD", PerGB: 1}
+	start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	usage := []database.TenantUsage{{Tenant: "a", TotalSize: 3e9}, {Tenant: "b", TotalSize: 1e9}}
+
+	status := EvaluateBudget(config.Budget{Name: "volume", Period: "monthly", LimitBytes: 8e9}, model, start, usage, nil)
+	if status.Exceeded || status.Bytes != 4e9 || status.Percent != 50 || status.Cost != 0 {
+		t.Errorf("Expected half the volume limit used, got %+v", status)
+	}
+
+	// The most used limit decides
+	status = EvaluateBudget(config.Budget{Name: "both", Period: "monthly", LimitBytes: 8e9, LimitCost: 4}, model, start, usage, nil)
+	if !status.Exceeded || status.Cost != 4 || status.Currency != "USD" || status.Percent != 100 {
+		t.Errorf("Expected the cost limit reached, got %+v", status)
+	}
+
+	status = EvaluateBudget(config.Budget{Name: "tenant-b", Period: "monthly", LimitBytes: 2e9, Tenant: "b"}, model, start, usage, nil)
+	if status.Exceeded || status.Bytes != 1e9 || status.Percent != 50 {
+		t.Errorf("Expected only tenant b's usage to count, got %+v", status)
+	}
+	if len(usage) != 2 {
+		t.Errorf("Expected the usage slice to be left alone, got %+v", usage)
+	}
+}
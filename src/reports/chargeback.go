@@ -0,0 +1,293 @@
+// Package reports builds periodic reports from stored usage for internal
+// consumers such as finance.
+//
+// A chargeback report prices a month of observed usage with a configured
+// pricing model and allocates the total across tenants in proportion to
+// what each tenant used. Costs are rounded to cents so that the lines
+// always add up to the total, which makes the report safe to bill from.
+// When the model describes the destination's batching, write requests are
+// priced from EstimateRequests rather than the ingested batch count.
+// Volume is priced per GB (10^9 bytes) or per GiB (2^30 bytes), following
+// the configured units.
+//
+// # Usage
+//
+//	usage, err := db.QueryTenantUsage(start, end)
+//	if err != nil {
+//		return err
+//	}
+//	var requests map[string]int64
+//	if model.Batching != nil {
+//		hourly, err := db.QueryTenantHourlyUsage(start, end)
+//		if err != nil {
+//			return err
+//		}
+//		requests = reports.EstimateRequests(*model.Batching, hourly)
+//	}
+//	report := reports.Allocate(start, model, usage, requests)
+//	err = report.WriteCSV(w)
+package reports
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/i18n"
+)
+
+// MonthLayout is the format of the month a report covers, e.g. "2025-09".
+const MonthLayout = "2006-01"
+
+// perMillion is the unit record and request rates are quoted in. Volume
+// rates are per the model's Units.
+const perMillion = 1e6
+
+// ErrInvalidMonth is returned by ParseMonth for values not in MonthLayout.
+var ErrInvalidMonth = errors.New("month must be formatted YYYY-MM")
+
+// ChargebackLine is one tenant's share of the month's cost.
+type ChargebackLine struct {
+	Tenant   string  `json:"tenant"`   // Tenant name; empty for the default tenant
+	Batches  int64   `json:"batches"`  // Batches ingested
+	Records  int64   `json:"records"`  // Log lines ingested
+	Bytes    int64   `json:"bytes"`    // Bytes ingested
+	Requests int64   `json:"requests"` // Destination write requests, priced per million
+	Share    float64 `json:"share"`    // Fraction of the total cost allocated to the tenant
+	Cost     float64 `json:"cost"`     // Allocated cost, rounded to cents
+}
+
+// CostItem is one component of the month's cost: ingested volume, records,
+// requests, or transfer along one egress route.
+type CostItem struct {
+	Item     string  `json:"item"`     // "storage", "records", "requests" or "egress"; estimates add "stored" and "download"
+	Route    string  `json:"route"`    // For egress, "from -> to"; empty otherwise
+	Quantity float64 `json:"quantity"` // Amount priced, in Unit
+	Unit     string  `json:"unit"`     // "GB" or "GiB", "GB-month" or "GiB-month", or "million"
+	Rate     float64 `json:"rate"`     // Price per Unit
+	Cost     float64 `json:"cost"`     // Quantity times Rate, rounded to cents
+}
+
+// Chargeback is a month's observed cost allocated across tenants.
+type Chargeback struct {
+	Month        string           `json:"month"`         // Month covered, formatted MonthLayout
+	Start        time.Time        `json:"start"`         // First instant of the month, UTC
+	End          time.Time        `json:"end"`           // First instant of the next month, UTC
+	PricingModel string           `json:"pricing_model"` // Name of the model the usage was priced with
+	Currency     string           `json:"currency"`      // Currency of every cost in the report
+	TotalCost    float64          `json:"total_cost"`    // Cost of all usage in the month, rounded to cents
+	Requests     int64            `json:"requests"`      // Destination write requests in the month
+	RequestCost  float64          `json:"request_cost"`  // Part of TotalCost from requests, rounded to cents
+	Estimated    bool             `json:"estimated"`     // Whether Requests were modelled from the model's batching
+	Breakdown    []CostItem       `json:"breakdown"`     // TotalCost by component; rounding may leave a cent unaccounted
+	Lines        []ChargebackLine `json:"lines"`         // One line per tenant, largest cost first
+}
+
+// ParseMonth parses a month formatted MonthLayout.
+//
+// Parameters:
+//   - value: Month such as "2025-09"
+//
+// Returns:
+//   - time.Time: First instant of the month, UTC
+//   - error: ErrInvalidMonth if value is malformed
+func ParseMonth(value string) (time.Time, error) {
+	month, err := time.Parse(MonthLayout, value)
+	if err != nil {
+		return time.Time{}, ErrInvalidMonth
+	}
+	return month, nil
+}
+
+// MonthBounds returns the first instant of the UTC month containing t and
+// of the month after it.
+func MonthBounds(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// cost prices usage with the model, unrounded. Every byte ingested is
+// charged once per egress route on its way to the destination.
+func cost(model config.PricingModel, bytes, records, requests int64) float64 {
+	perGB := model.PerGB
+	for _, e := range model.Egress {
+		perGB += e.PerGB
+	}
+	return float64(bytes)/model.Units.BytesPerGB()*perGB +
+		float64(records)/perMillion*model.PerMillionRecords +
+		float64(requests)/perMillion*model.PerMillionRequests
+}
+
+// Breakdown itemizes the cost of the month's totals by component.
+func Breakdown(model config.PricingModel, bytes, records, requests int64) []CostItem {
+	item := func(name, route string, quantity float64, unit string, rate float64) CostItem {
+		return CostItem{Item: name, Route: route, Quantity: quantity, Unit: unit, Rate: rate,
+			Cost: math.Round(quantity*rate*100) / 100}
+	}
+	gb := float64(bytes) / model.Units.BytesPerGB()
+	items := []CostItem{
+		item("storage", "", gb, model.Units.GB(), model.PerGB),
+		item("records", "", float64(records)/perMillion, "million", model.PerMillionRecords),
+		item("requests", "", float64(requests)/perMillion, "million", model.PerMillionRequests),
+	}
+	for _, e := range model.Egress {
+		items = append(items, item("egress", e.From+" -> "+e.To, gb, model.Units.GB(), e.PerGB))
+	}
+	return items
+}
+
+// Allocate prices the month's usage with model and splits the total across
+// tenants in proportion to their priced usage. Rounding uses the largest
+// remainder method, so line costs add up to TotalCost exactly.
+//
+// Parameters:
+//   - month: Any instant in the month being reported
+//   - model: Pricing model to apply
+//   - usage: Usage per tenant within the month
+//   - requests: Destination requests per tenant from EstimateRequests, or
+//     nil to price each ingested batch as one request
+//
+// Returns:
+//   - Chargeback: The allocated report
+func Allocate(month time.Time, model config.PricingModel, usage []database.TenantUsage, requests map[string]int64) Chargeback {
+	start, end := MonthBounds(month)
+	report := Chargeback{
+		Month:        start.Format(MonthLayout),
+		Start:        start,
+		End:          end,
+		PricingModel: model.Name,
+		Currency:     model.Currency,
+		Estimated:    requests != nil,
+		Lines:        make([]ChargebackLine, 0, len(usage)),
+	}
+
+	exact := make([]float64, len(usage))
+	var total float64
+	var bytes, records int64
+	for i, u := range usage {
+		n := u.Records
+		if requests != nil {
+			n = requests[u.Tenant]
+		}
+		exact[i] = cost(model, u.TotalSize, u.RecordCount, n)
+		total += exact[i]
+		report.Requests += n
+		bytes += u.TotalSize
+		records += u.RecordCount
+		report.Lines = append(report.Lines, ChargebackLine{
+			Tenant:   u.Tenant,
+			Batches:  u.Records,
+			Records:  u.RecordCount,
+			Bytes:    u.TotalSize,
+			Requests: n,
+		})
+	}
+	report.Breakdown = Breakdown(model, bytes, records, report.Requests)
+	report.RequestCost = report.Breakdown[2].Cost
+
+	totalCents := int64(math.Round(total * 100))
+	report.TotalCost = float64(totalCents) / 100
+	if total > 0 {
+		// Floor every line, then hand the remaining cents to the lines
+		// with the largest fractional remainders
+		cents := make([]int64, len(exact))
+		order := make([]int, len(exact))
+		var assigned int64
+		for i, c := range exact {
+			cents[i] = int64(math.Floor(c * 100))
+			assigned += cents[i]
+			order[i] = i
+			report.Lines[i].Share = c / total
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			ra := exact[order[a]]*100 - float64(cents[order[a]])
+			rb := exact[order[b]]*100 - float64(cents[order[b]])
+			return ra > rb
+		})
+		for i := 0; assigned < totalCents && i < len(order); i++ {
+			cents[order[i]]++
+			assigned++
+		}
+		for i := range report.Lines {
+			report.Lines[i].Cost = float64(cents[i]) / 100
+		}
+	}
+
+	sort.SliceStable(report.Lines, func(a, b int) bool {
+		if report.Lines[a].Cost != report.Lines[b].Cost {
+			return report.Lines[a].Cost > report.Lines[b].Cost
+		}
+		return report.Lines[a].Tenant < report.Lines[b].Tenant
+	})
+	return report
+}
+
+// csvHeader is the first line of a chargeback CSV.
+var csvHeader = []string{"month", "tenant", "batches", "records", "bytes", "requests", "share", "cost", "currency"}
+
+// csvTitles are the column titles of a translated chargeback CSV, in
+// csvHeader's order.
+var csvTitles = []string{"Month", "Tenant", "Batches", "Records", "Bytes", "Requests", "Share", "Cost", "Currency"}
+
+// WriteCSV writes the report as CSV, one row per tenant, for import into
+// billing systems.
+//
+// Parameters:
+//   - w: Destination for the CSV
+//
+// Returns:
+//   - error: Any error encountered while writing
+func (c Chargeback) WriteCSV(w io.Writer) error {
+	return c.writeCSV(w, csvHeader)
+}
+
+// WriteLocalizedCSV writes the report like WriteCSV, with column titles
+// translated by p for readers of the spreadsheet rather than billing
+// systems. Values keep WriteCSV's format, so spreadsheets parse them.
+//
+// Parameters:
+//   - w: Destination for the CSV
+//   - p: Printer for the readers' locale
+//
+// Returns:
+//   - error: Any error encountered while writing
+func (c Chargeback) WriteLocalizedCSV(w io.Writer, p *i18n.Printer) error {
+	header := make([]string, len(csvTitles))
+	for i, title := range csvTitles {
+		header[i] = p.T(title)
+	}
+	return c.writeCSV(w, header)
+}
+
+// writeCSV writes the report as CSV under header.
+func (c Chargeback) writeCSV(w io.Writer, header []string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, l := range c.Lines {
+		err := cw.Write([]string{
+			c.Month,
+			l.Tenant,
+			strconv.FormatInt(l.Batches, 10),
+			strconv.FormatInt(l.Records, 10),
+			strconv.FormatInt(l.Bytes, 10),
+			strconv.FormatInt(l.Requests, 10),
+			strconv.FormatFloat(l.Share, 'f', 6, 64),
+			strconv.FormatFloat(l.Cost, 'f', 2, 64),
+			c.Currency,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,138 @@
+package reports
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/i18n"
+)
+
+func TestParseMonth(t *testing.T) {
+	month, err := ParseMonth("2025-09")
+	if err != nil || !month.Equal(time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected September 2025, got %v (%v)", month, err)
+	}
+	for _, value := range []string{"", "2025-9", "2025-13", "September"} {
+		if _, err := ParseMonth(value); !errors.Is(err, ErrInvalidMonth) {
+			t.Errorf("ParseMonth(%q): expected ErrInvalidMonth, got %v", value, err)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	model := config.PricingModel{Name: "r2", Currency: "USD", PerGB: 1, PerMillionRequests: 1}
+	usage := []database.TenantUsage{
+		{Tenant: "a", Records: 0, TotalSize: 333333333},
+		{Tenant: "b", Records: 0, TotalSize: 333333333},
+		{Tenant: "c", Records: 0, TotalSize: 333333333},
+		{Tenant: "d", Records: 1e6, TotalSize: 1e9},
+	}
+	report := Allocate(time.Date(2025, 9, 17, 8, 0, 0, 0, time.UTC), model, usage, nil)
+
+	if report.Month != "2025-09" || !report.End.Equal(time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected period: %s to %v", report.Month, report.End)
+	}
+	if report.TotalCost != 3 {
+		t.Fatalf("Expected total cost 3.00, got %v", report.TotalCost)
+	}
+	if report.Lines[0].Tenant != "d" || report.Lines[0].Cost != 2 || report.Lines[0].Share < 0.66 || report.Lines[0].Share > 0.67 {
+		t.Errorf("Expected d to carry two thirds of the cost, got %+v", report.Lines[0])
+	}
+
+	// Thirds of a dollar cannot all round to 0.33; the cents must still add up
+	var sum float64
+	for _, l := range report.Lines {
+		sum += l.Cost
+	}
+	if int64(sum*100+0.5) != 300 {
+		t.Errorf("Expected line costs to add up to 3.00, got %v: %+v", sum, report.Lines)
+	}
+}
+
+func TestAllocateEstimatedRequests(t *testing.T) {
+	model := config.PricingModel{Name: "s3", Currency: "USD", PerMillionRequests: 5}
+	usage := []database.TenantUsage{
+		{Tenant: "a", Records: 10, TotalSize: 1e6},
+		{Tenant: "b", Records: 10, TotalSize: 1e6},
+	}
+	report := Allocate(time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), model, usage, map[string]int64{"a": 3e6, "b": 1e6})
+
+	if !report.Estimated || report.Requests != 4e6 || report.RequestCost != 20 || report.TotalCost != 20 {
+		t.Fatalf("Expected 4M estimated requests costing 20.00, got %+v", report)
+	}
+	if report.Lines[0].Tenant != "a" || report.Lines[0].Requests != 3e6 || report.Lines[0].Cost != 15 {
+		t.Errorf("Expected a to carry 3M requests costing 15.00, got %+v", report.Lines[0])
+	}
+}
+
+func TestAllocateEgress(t *testing.T) {
+	model := config.PricingModel{Name: "s3", Currency: "USD", PerGB: 0.023, Egress: []config.EgressRate{
+		{From: "aws:us-east-1", To: "aws:eu-west-1", PerGB: 0.02},
+		{From: "aws:eu-west-1", To: "siem", PerGB: 0.09},
+	}}
+	usage := []database.TenantUsage{{Tenant: "acme", Records: 1, TotalSize: 100e9}}
+	report := Allocate(time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), model, usage, nil)
+
+	if report.TotalCost != 13.3 || report.Lines[0].Cost != 13.3 {
+		t.Fatalf("Expected egress to triple the cost to 13.30, got %+v", report)
+	}
+	if len(report.Breakdown) != 5 {
+		t.Fatalf("Expected storage, records, requests and two egress items, got %+v", report.Breakdown)
+	}
+	egress := report.Breakdown[4]
+	if egress.Item != "egress" || egress.Route != "aws:eu-west-1 -> siem" || egress.Quantity != 100 || egress.Cost != 9 {
+		t.Errorf("Unexpected egress item %+v", egress)
+	}
+	if report.Breakdown[0].Item != "storage" || report.Breakdown[0].Cost != 2.3 {
+		t.Errorf("Unexpected storage item %+v", report.Breakdown[0])
+	}
+}
+
+func TestAllocateBinaryUnits(t *testing.T) {
+	model := config.PricingModel{Name: "r2", Currency: "USD", PerGB: 1, Units: config.UnitsBinary}
+	usage := []database.TenantUsage{{Tenant: "acme", TotalSize: 10 << 30}}
+	report := Allocate(time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), model, usage, nil)
+
+	// 10 GiB is 10.74 GB, but priced per GiB it costs exactly 10
+	if report.TotalCost != 10 || report.Breakdown[0].Unit != "GiB" || report.Breakdown[0].Quantity != 10 {
+		t.Errorf("Expected 10 GiB costing 10.00, got %+v", report)
+	}
+}
+
+func TestAllocateWithoutUsage(t *testing.T) {
+	report := Allocate(time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), config.PricingModel{Name: "r2", PerGB: 1}, nil, nil)
+	if report.TotalCost != 0 || len(report.Lines) != 0 {
+		t.Errorf("Expected an empty report, got %+v", report)
+	}
+}
+
+func TestChargebackWriteCSV(t *testing.T) {
+	report := Chargeback{
+		Month:    "2025-09",
+		Currency: "USD",
+		Lines:    []ChargebackLine{{Tenant: "acme", Batches: 2, Records: 10, Bytes: 2048, Requests: 2, Share: 1, Cost: 1.5}},
+	}
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	want := "month,tenant,batches,records,bytes,requests,share,cost,currency\n" +
+		"2025-09,acme,2,10,2048,2,1.000000,1.50,USD\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected CSV:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := report.WriteLocalizedCSV(&buf, i18n.For("de")); err != nil {
+		t.Fatalf("Failed to write localized CSV: %v", err)
+	}
+	want = "Monat,Mandant,Batches,Datensätze,Bytes,Anfragen,Anteil,Kosten,Währung\n" +
+		"2025-09,acme,2,10,2048,2,1.000000,1.50,USD\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected localized CSV:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
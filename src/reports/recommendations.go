@@ -0,0 +1,224 @@
+package reports
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// Recommendation kinds.
+const (
+	RecommendDropField   = "drop_field"  // Remove a field from the job's output fields
+	RecommendSampling    = "sampling"    // Sample a dataset
+	RecommendFilter      = "filter"      // Filter out records with a dimension value
+	RecommendCompression = "compression" // Store or ship payloads compressed
+)
+
+// Thresholds below which a suggestion is not worth making.
+const (
+	minFieldShare       = 0.05 // Share of a dataset's bytes a field must take up
+	minSamplingShare    = 0.25 // Share of all bytes a dataset must take up
+	minFilterShare      = 0.25 // Share of a dataset's bytes a dimension value must take up
+	minCompressionSaves = 0.5  // Fraction of bytes compression must save
+	suggestedSampleRate = 0.5  // Sample rate suggested for large datasets
+)
+
+// Recommendation is one suggested change to the Logpush setup with its
+// estimated monthly savings. Savings are each estimated on their own and
+// overlap, so they cannot be added up.
+type Recommendation struct {
+	Rank       int     `json:"rank"`        // Position by savings, starting at 1
+	Kind       string  `json:"kind"`        // One of the Recommend* kinds
+	Dataset    string  `json:"dataset"`     // Dataset concerned; empty for all data
+	Target     string  `json:"target"`      // Field, "dimension=value" or sample rate the suggestion is about
+	Summary    string  `json:"summary"`     // Human-readable suggestion
+	Evidence   string  `json:"evidence"`    // What the suggestion is based on
+	SavedBytes int64   `json:"saved_bytes"` // Estimated bytes saved per 30-day month
+	SavedCost  float64 `json:"saved_cost"`  // SavedBytes priced per GB, rounded to cents; 0 without a model
+	Currency   string  `json:"currency"`    // Currency of SavedCost
+}
+
+// RecommendationInput is the observed data recommendations are derived
+// from.
+type RecommendationInput struct {
+	Days       float64                   // Length of the observed window in days
+	Bytes      int64                     // Bytes ingested in the window
+	Samples    []database.PayloadSample  // Recent payload samples
+	Dimensions []database.DimensionCount // Dimension totals in the window
+	Model      *config.PricingModel      // Prices the savings; nil leaves SavedCost zero
+}
+
+// Recommend derives cost reduction suggestions from observed data, largest
+// savings first:
+//
+//   - drop_field: fields taking up at least 5% of a dataset's record bytes
+//     in the payload samples
+//   - sampling: sampling at 50% for datasets carrying at least a quarter of
+//     the volume, by the batch sizes of the samples
+//   - filter: dimension values, such as status_class=2xx, carrying at least
+//     a quarter of a dataset's bytes
+//   - compression: when gzip halves the sampled payloads or better
+//
+// Volumes are projected from the observed window to a 30-day month.
+//
+// Parameters:
+//   - in: Observed volume, samples, dimensions and pricing
+//
+// Returns:
+//   - []Recommendation: Ranked suggestions; empty without data
+func Recommend(in RecommendationInput) []Recommendation {
+	out := []Recommendation{}
+	if in.Days <= 0 {
+		return out
+	}
+	scale := projectionDays / in.Days
+	monthly := float64(in.Bytes) * scale
+
+	// Split the volume across datasets by the batches that were sampled
+	datasetBytes := map[string]int64{}
+	var sampledBytes int64
+	for _, s := range in.Samples {
+		datasetBytes[s.Dataset] += s.BatchBytes
+		sampledBytes += s.BatchBytes
+	}
+	datasetShare := func(dataset string) float64 {
+		if sampledBytes == 0 {
+			return 0
+		}
+		return float64(datasetBytes[dataset]) / float64(sampledBytes)
+	}
+
+	for dataset, fields := range fieldShares(in.Samples) {
+		for field, share := range fields {
+			if share < minFieldShare {
+				continue
+			}
+			out = append(out, Recommendation{
+				Kind:       RecommendDropField,
+				Dataset:    dataset,
+				Target:     field,
+				Summary:    fmt.Sprintf("Drop %s from the %s output fields", field, datasetName(dataset)),
+				Evidence:   fmt.Sprintf("%s takes up %.0f%% of each sampled record", field, share*100),
+				SavedBytes: int64(math.Round(monthly * datasetShare(dataset) * share)),
+			})
+		}
+	}
+
+	for dataset := range datasetBytes {
+		if share := datasetShare(dataset); share >= minSamplingShare {
+			out = append(out, Recommendation{
+				Kind:       RecommendSampling,
+				Dataset:    dataset,
+				Target:     fmt.Sprintf("%g", suggestedSampleRate),
+				Summary:    fmt.Sprintf("Sample %s at %.0f%%", datasetName(dataset), suggestedSampleRate*100),
+				Evidence:   fmt.Sprintf("%s carries %.0f%% of the sampled volume", datasetName(dataset), share*100),
+				SavedBytes: int64(math.Round(monthly * share * (1 - suggestedSampleRate))),
+			})
+		}
+	}
+
+	// Each dimension covers every record of its dataset that carries it
+	dimensionTotals := map[[2]string]int64{}
+	for _, d := range in.Dimensions {
+		dimensionTotals[[2]string{d.Dataset, d.Dimension}] += d.Bytes
+	}
+	for _, d := range in.Dimensions {
+		total := dimensionTotals[[2]string{d.Dataset, d.Dimension}]
+		if total == 0 || float64(d.Bytes)/float64(total) < minFilterShare {
+			continue
+		}
+		target := d.Dimension + "=" + d.Value
+		out = append(out, Recommendation{
+			Kind:       RecommendFilter,
+			Dataset:    d.Dataset,
+			Target:     target,
+			Summary:    fmt.Sprintf("Filter out %s records with %s in the Logpush job", datasetName(d.Dataset), target),
+			Evidence:   fmt.Sprintf("%s is %.0f%% of the %s bytes", target, float64(d.Bytes)/float64(total)*100, datasetName(d.Dataset)),
+			SavedBytes: int64(math.Round(float64(d.Bytes) * scale)),
+		})
+	}
+
+	if ratio, ok := compressionRatio(in.Samples); ok && 1-ratio >= minCompressionSaves {
+		out = append(out, Recommendation{
+			Kind:       RecommendCompression,
+			Target:     "gzip",
+			Summary:    "Store and ship payloads gzip-compressed at the destination",
+			Evidence:   fmt.Sprintf("Sampled records compress to %.0f%% of their size", ratio*100),
+			SavedBytes: int64(math.Round(monthly * (1 - ratio))),
+		})
+	}
+
+	sort.SliceStable(out, func(a, b int) bool {
+		if out[a].SavedBytes != out[b].SavedBytes {
+			return out[a].SavedBytes > out[b].SavedBytes
+		}
+		return out[a].Kind+out[a].Dataset+out[a].Target < out[b].Kind+out[b].Dataset+out[b].Target
+	})
+	for i := range out {
+		out[i].Rank = i + 1
+		if in.Model != nil {
+			out[i].SavedCost = math.Round(cost(*in.Model, out[i].SavedBytes, 0, 0)*100) / 100
+			out[i].Currency = in.Model.Currency
+		}
+	}
+	return out
+}
+
+// datasetName names a dataset in summaries.
+func datasetName(dataset string) string {
+	if dataset == "" {
+		return "all datasets"
+	}
+	return dataset
+}
+
+// fieldShares returns, per dataset, the share of the complete sampled
+// records' JSON bytes each field takes up.
+func fieldShares(samples []database.PayloadSample) map[string]map[string]float64 {
+	sizes := map[string]map[string]int{}
+	totals := map[string]int{}
+	for _, sample := range samples {
+		members := memberSizes(sample)
+		if len(members) == 0 {
+			continue
+		}
+		if sizes[sample.Dataset] == nil {
+			sizes[sample.Dataset] = map[string]int{}
+		}
+		for key, size := range members {
+			sizes[sample.Dataset][key] += size
+			totals[sample.Dataset] += size
+		}
+	}
+	shares := map[string]map[string]float64{}
+	for dataset, fields := range sizes {
+		shares[dataset] = map[string]float64{}
+		for field, size := range fields {
+			shares[dataset][field] = float64(size) / float64(totals[dataset])
+		}
+	}
+	return shares
+}
+
+// compressionRatio returns the gzip-compressed size of the sampled records
+// as a fraction of their size.
+func compressionRatio(samples []database.PayloadSample) (float64, bool) {
+	var raw bytes.Buffer
+	for _, s := range samples {
+		raw.WriteString(s.Content)
+		raw.WriteByte('\n')
+	}
+	if len(samples) == 0 {
+		return 0, false
+	}
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(raw.Bytes())
+	zw.Close()
+	return float64(compressed.Len()) / float64(raw.Len()), true
+}
@@ -0,0 +1,56 @@
+package reports
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestRecommend(t *testing.T) {
+	headers := strings.Repeat("x", 300)
+	samples := []database.PayloadSample{
+		{Dataset: "http_requests", BatchBytes: 3000, Content: `{"RequestHeaders":"` + headers + `","Status":200}`},
+		{Dataset: "firewall_events", BatchBytes: 1000, Content: `{"Action":"block"}`},
+	}
+	dimensions := []database.DimensionCount{
+		{Dataset: "http_requests", Dimension: "status_class", Value: "2xx", Bytes: 9e9},
+		{Dataset: "http_requests", Dimension: "status_class", Value: "5xx", Bytes: 1e9},
+	}
+	model := config.PricingModel{Name: "r2", Currency: "USD", PerGB: 0.5}
+	recs := Recommend(RecommendationInput{Days: 3, Bytes: 4e9, Samples: samples, Dimensions: dimensions, Model: &model})
+
+	// A month is 40 GB, of which http_requests carries 30 GB
+	byTarget := map[string]Recommendation{}
+	for i, r := range recs {
+		if r.Rank != i+1 || (i > 0 && r.SavedBytes > recs[i-1].SavedBytes) {
+			t.Fatalf("Expected recommendations ranked by savings, got %+v", recs)
+		}
+		byTarget[r.Kind+":"+r.Dataset+":"+r.Target] = r
+	}
+	if r := byTarget["filter:http_requests:status_class=2xx"]; r.Rank != 1 || r.SavedBytes != 90e9 || r.SavedCost != 45 {
+		t.Errorf("Expected filtering 2xx to rank first saving 90 GB, got %+v", r)
+	}
+	if r, ok := byTarget["drop_field:http_requests:RequestHeaders"]; !ok || r.SavedBytes < 25e9 || r.SavedBytes > 30e9 {
+		t.Errorf("Expected dropping RequestHeaders to save most of 30 GB, got %+v", r)
+	}
+	if r := byTarget["sampling:http_requests:0.5"]; r.SavedBytes != 15e9 || r.Currency != "USD" {
+		t.Errorf("Expected sampling http_requests at 50%% to save 15 GB, got %+v", r)
+	}
+	if r, ok := byTarget["sampling:firewall_events:0.5"]; !ok || r.SavedBytes != 5e9 {
+		t.Errorf("Expected sampling firewall_events at 50%% to save 5 GB, got %+v", r)
+	}
+	if _, ok := byTarget["filter:http_requests:status_class=5xx"]; ok {
+		t.Error("Expected no suggestion for a value under a quarter of the dataset")
+	}
+	if _, ok := byTarget["drop_field:http_requests:Status"]; ok {
+		t.Error("Expected no suggestion for a field under 5% of the record")
+	}
+}
+
+func TestRecommendWithoutData(t *testing.T) {
+	if recs := Recommend(RecommendationInput{Days: 7}); len(recs) != 0 {
+		t.Errorf("Expected no recommendations without data, got %+v", recs)
+	}
+}
@@ -0,0 +1,50 @@
+package reports
+
+import (
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// EstimateRequests models how many objects or write requests a destination
+// batching as policy creates from each tenant's usage, hour by hour.
+//
+// In an hour the destination writes at least one object per MaxBytes and
+// per MaxRecords received. With MaxAgeSeconds it also writes whatever it
+// holds every MaxAgeSeconds, but no more often than batches arrive: a
+// quiet hour with two Logpush batches yields at most two objects however
+// short the age limit is.
+//
+// Parameters:
+//   - policy: Destination batching limits
+//   - usage: Usage per tenant and hour, from QueryTenantHourlyUsage
+//
+// Returns:
+//   - map[string]int64: Estimated requests per tenant
+func EstimateRequests(policy config.BatchPolicy, usage []database.TenantHourUsage) map[string]int64 {
+	requests := make(map[string]int64)
+	for _, u := range usage {
+		if u.Records == 0 {
+			continue
+		}
+		n := int64(1)
+		if policy.MaxBytes > 0 {
+			n = max(n, ceilDiv(u.TotalSize, policy.MaxBytes))
+		}
+		if policy.MaxRecords > 0 {
+			n = max(n, ceilDiv(u.RecordCount, policy.MaxRecords))
+		}
+		if policy.MaxAgeSeconds > 0 {
+			flushes := ceilDiv(int64(time.Hour/time.Second), int64(policy.MaxAgeSeconds))
+			n = max(n, min(flushes, u.Records))
+		}
+		requests[u.Tenant] += n
+	}
+	return requests
+}
+
+// ceilDiv returns a divided by b, rounded up.
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
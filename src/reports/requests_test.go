@@ -0,0 +1,38 @@
+package reports
+
+import (
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestEstimateRequests(t *testing.T) {
+	hour := func(tenant string, batches, records, bytes int64) database.TenantHourUsage {
+		return database.TenantHourUsage{TenantUsage: database.TenantUsage{Tenant: tenant, Records: batches, RecordCount: records, TotalSize: bytes}}
+	}
+	usage := []database.TenantHourUsage{
+		hour("busy", 500, 1000, 250e6), // size limit: 3 objects, age limit: 12
+		hour("busy", 500, 1000, 2e9),   // size limit: 20 objects
+		hour("quiet", 2, 10, 1000),     // age limit capped by the 2 batches
+		hour("bulk", 1, 5000, 1000),    // record limit: 5 objects
+	}
+	tests := []struct {
+		name   string
+		policy config.BatchPolicy
+		want   map[string]int64
+	}{
+		{"Size and age", config.BatchPolicy{MaxBytes: 100e6, MaxAgeSeconds: 300}, map[string]int64{"busy": 32, "quiet": 2, "bulk": 1}},
+		{"Records", config.BatchPolicy{MaxRecords: 1000}, map[string]int64{"busy": 2, "quiet": 1, "bulk": 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EstimateRequests(tt.policy, usage)
+			for tenant, want := range tt.want {
+				if got[tenant] != want {
+					t.Errorf("%s: expected %d requests, got %d", tenant, want, got[tenant])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package reports
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// projectionDays is the length of the month scenarios are projected to.
+const projectionDays = 30
+
+// ScenarioResult is one scenario's projected month of shipped volume and
+// cost, one row of the comparison matrix.
+type ScenarioResult struct {
+	Scenario      string     `json:"scenario"`       // Scenario name
+	PricingModel  string     `json:"pricing_model"`  // Model the scenario was priced with
+	Currency      string     `json:"currency"`       // Currency of the costs
+	FieldRatio    float64    `json:"field_ratio"`    // Fraction of record bytes the scenario's fields keep
+	FieldSamples  int        `json:"field_samples"`  // Payload samples FieldRatio was measured on
+	SampleRate    float64    `json:"sample_rate"`    // Fraction of records shipped
+	Records       int64      `json:"records"`        // Records shipped per month
+	Bytes         int64      `json:"bytes"`          // Bytes shipped per month
+	Requests      int64      `json:"requests"`       // Destination write requests per month
+	RetainedBytes int64      `json:"retained_bytes"` // Bytes held at the destination once retention is reached
+	MonthlyCost   float64    `json:"monthly_cost"`   // Cost of a month, rounded to cents
+	Delta         float64    `json:"delta"`          // MonthlyCost minus the first scenario's
+	Breakdown     []CostItem `json:"breakdown"`      // MonthlyCost by component
+}
+
+// ProjectScenario projects the usage observed over the given number of days
+// to a 30-day month shipped as the scenario describes. Records are thinned
+// by the sample rate, and bytes additionally by the share of each record
+// the kept fields take up, measured on the payload samples of the
+// scenario's dataset. Without samples to measure on, fields are assumed to
+// keep every byte and FieldSamples is zero.
+//
+// Parameters:
+//   - s: Scenario to project
+//   - model: Pricing model of the scenario's destination
+//   - hourly: Observed usage per tenant and hour
+//   - days: Length of the observed window in days
+//   - samples: Recent payload samples
+//
+// Returns:
+//   - ScenarioResult: The projected month; Delta is left zero
+func ProjectScenario(s database.Scenario, model config.PricingModel, hourly []database.TenantHourUsage, days float64, samples []database.PayloadSample) ScenarioResult {
+	result := ScenarioResult{
+		Scenario:     s.Name,
+		PricingModel: model.Name,
+		Currency:     model.Currency,
+		FieldRatio:   1,
+		SampleRate:   s.SampleRate,
+	}
+	if len(s.Fields) > 0 {
+		if ratio, n := fieldRatio(s.Fields, s.Dataset, samples); n > 0 {
+			result.FieldRatio, result.FieldSamples = ratio, n
+		}
+	}
+
+	// Scale every hour to a month of shipped volume, keeping the batch
+	// count so that batching is modelled on the thinned hours
+	scale := projectionDays / days
+	shipped := make([]database.TenantHourUsage, len(hourly))
+	var usage database.TenantUsage
+	for i, h := range hourly {
+		h.RecordCount = int64(math.Round(float64(h.RecordCount) * s.SampleRate))
+		h.TotalSize = int64(math.Round(float64(h.TotalSize) * s.SampleRate * result.FieldRatio))
+		shipped[i] = h
+		usage.Records += h.Records
+		usage.RecordCount += h.RecordCount
+		usage.TotalSize += h.TotalSize
+	}
+	requests := usage.Records
+	if model.Batching != nil {
+		requests = 0
+		for _, n := range EstimateRequests(*model.Batching, shipped) {
+			requests += n
+		}
+	}
+	usage = database.TenantUsage{
+		Tenant:      s.Name,
+		Records:     int64(math.Round(float64(usage.Records) * scale)),
+		RecordCount: int64(math.Round(float64(usage.RecordCount) * scale)),
+		TotalSize:   int64(math.Round(float64(usage.TotalSize) * scale)),
+	}
+	requests = int64(math.Round(float64(requests) * scale))
+
+	priced := Allocate(time.Time{}, model, []database.TenantUsage{usage}, map[string]int64{s.Name: requests})
+	result.Records = usage.RecordCount
+	result.Bytes = usage.TotalSize
+	result.Requests = requests
+	result.RetainedBytes = int64(math.Round(float64(usage.TotalSize) / projectionDays * float64(s.RetentionDays)))
+	result.MonthlyCost = priced.TotalCost
+	result.Breakdown = priced.Breakdown
+	return result
+}
+
+// fieldRatio returns the fraction of the JSON bytes of the sampled records
+// taken up by the given fields, and the number of samples measured. Only
+// complete samples of dataset (of any dataset when empty) are measured.
+func fieldRatio(fields []string, dataset string, samples []database.PayloadSample) (float64, int) {
+	var kept, total, n int
+	for _, sample := range samples {
+		if dataset != "" && sample.Dataset != dataset {
+			continue
+		}
+		sizes := memberSizes(sample)
+		if len(sizes) == 0 {
+			continue
+		}
+		for key, size := range sizes {
+			total += size
+			if slices.Contains(fields, key) {
+				kept += size
+			}
+		}
+		n++
+	}
+	if total == 0 {
+		return 1, 0
+	}
+	return float64(kept) / float64(total), n
+}
+
+// memberSizes returns the bytes each field takes up in a sampled record:
+// its quoted key, a colon, its value and a comma. Truncated samples and
+// samples that are not JSON objects yield nothing.
+func memberSizes(sample database.PayloadSample) map[string]int {
+	var record map[string]json.RawMessage
+	if sample.Truncated || json.Unmarshal([]byte(sample.Content), &record) != nil {
+		return nil
+	}
+	sizes := make(map[string]int, len(record))
+	for key, value := range record {
+		sizes[key] = len(key) + len(value) + 4
+	}
+	return sizes
+}
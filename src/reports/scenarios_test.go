@@ -0,0 +1,44 @@
+package reports
+
+import (
+	"testing"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+func TestProjectScenario(t *testing.T) {
+	model := config.PricingModel{Name: "r2", Currency: "USD", PerGB: 1, PerMillionRequests: 1e6}
+	// Three days with one hour of 10 GB in 100 batches
+	hourly := []database.TenantHourUsage{{TenantUsage: database.TenantUsage{Records: 100, RecordCount: 1000, TotalSize: 10e9}}}
+	samples := []database.PayloadSample{
+		{Dataset: "http_requests", Content: `{"a":"xxxxxx","b":"xxxxxx"}`},
+		{Dataset: "http_requests", Content: `{"a":"xx`, Truncated: true},
+		{Dataset: "firewall_events", Content: `{"a":1,"c":"xxxxxxxxxxxxxxxxxxxxxx"}`},
+	}
+
+	current := ProjectScenario(database.Scenario{Name: "current", SampleRate: 1, RetentionDays: 30}, model, hourly, 3, samples)
+	if current.Bytes != 100e9 || current.Records != 10000 || current.Requests != 1000 {
+		t.Fatalf("Expected three days scaled to a 30-day month, got %+v", current)
+	}
+	if current.MonthlyCost != 1100 || current.RetainedBytes != 100e9 || current.FieldSamples != 0 {
+		t.Errorf("Unexpected projection %+v", current)
+	}
+
+	// Fields are sized on the one complete http_requests sample, where a
+	// takes half the bytes
+	trimmed := ProjectScenario(database.Scenario{Name: "trimmed", Dataset: "http_requests", Fields: []string{"a"}, SampleRate: 0.1}, model, hourly, 3, samples)
+	if trimmed.FieldSamples != 1 || trimmed.FieldRatio != 0.5 {
+		t.Fatalf("Expected a to keep half of one sample, got ratio %v over %d", trimmed.FieldRatio, trimmed.FieldSamples)
+	}
+	if trimmed.Bytes != 5e9 || trimmed.Records != 1000 || trimmed.Requests != 1000 || trimmed.MonthlyCost != 1005 {
+		t.Errorf("Unexpected projection %+v", trimmed)
+	}
+
+	// With batching, the thinned hour fits in fewer objects
+	model.Batching = &config.BatchPolicy{MaxBytes: 1e8}
+	batched := ProjectScenario(database.Scenario{Name: "batched", SampleRate: 0.1}, model, hourly, 3, nil)
+	if batched.Requests != 100 {
+		t.Errorf("Expected ten 100 MB objects a day, got %d requests a month", batched.Requests)
+	}
+}
@@ -0,0 +1,28 @@
+// Package retry computes the waits between attempts of deliveries that are
+// retried in the background, such as webhook notifications and relayed
+// batches.
+//
+// # Usage
+//
+//	retryAt := now.Add(retry.Backoff(30*time.Second, time.Hour, attempts))
+package retry
+
+import "time"
+
+// Backoff returns the wait after the given number of failed attempts: base
+// after the first failure, doubled for each further one, and capped at max.
+//
+// Parameters:
+//   - base: Wait after the first failure
+//   - max: Longest wait
+//   - attempts: Failed attempts so far, at least 1
+//
+// Returns:
+//   - time.Duration: Wait before the next attempt
+func Backoff(base, max time.Duration, attempts int) time.Duration {
+	wait := base
+	for i := 1; i < attempts && wait < max; i++ {
+		wait *= 2
+	}
+	return min(wait, max)
+}
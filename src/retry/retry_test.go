@@ -0,0 +1,22 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		0:  30 * time.Second,
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		7:  32 * time.Minute,
+		8:  time.Hour,
+		50: time.Hour,
+	} {
+		if got := Backoff(30*time.Second, time.Hour, attempts); got != want {
+			t.Errorf("After %d attempts: expected %s, got %s", attempts, want, got)
+		}
+	}
+}
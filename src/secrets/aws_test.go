@@ -0,0 +1,64 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignV4(t *testing.T) {
+	// Example request from the AWS Signature Version 4 documentation
+	req, _ := http.NewRequest("GET", "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	cfg := AWSConfig{Region: "us-east-1", AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signV4(req, nil, "iam", cfg, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %s, want %s", got, want)
+	}
+}
+
+func TestResolveAWSSecretsManager(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" || !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var body struct{ SecretId string }
+		json.NewDecoder(r.Body).Decode(&body)
+		switch body.SecretId {
+		case "prod/lpe":
+			w.Write([]byte(`{"SecretString":"{\"api_token\":\"cf-token\"}"}`))
+		case "plain":
+			w.Write([]byte(`{"SecretString":"just-a-string"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException"}`))
+		}
+	}))
+	defer server.Close()
+
+	r := NewResolver(func(string) string { return "" })
+	r.AWS = AWSConfig{Region: "eu-west-1", AccessKeyID: "AKID", SecretAccessKey: "secret", Endpoint: server.URL}
+
+	ctx := context.Background()
+	if got, err := r.Resolve(ctx, "${aws-sm:prod/lpe#api_token}"); err != nil || got != "cf-token" {
+		t.Errorf("Resolve(key) = %q, %v", got, err)
+	}
+	if got, err := r.Resolve(ctx, "${aws-sm:plain}"); err != nil || got != "just-a-string" {
+		t.Errorf("Resolve(whole) = %q, %v", got, err)
+	}
+	if _, err := r.Resolve(ctx, "${aws-sm:missing}"); err == nil {
+		t.Error("Expected an error for a missing secret")
+	}
+	if _, err := r.Resolve(ctx, "${aws-sm:plain#key}"); err == nil {
+		t.Error("Expected an error selecting a key from a non-JSON secret")
+	}
+}
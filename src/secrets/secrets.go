@@ -0,0 +1,214 @@
+// Package secrets resolves references to secrets held outside the estimator,
+// so tokens and keys do not have to be written in plaintext into the
+// environment or the configuration document.
+//
+// A reference replaces the whole value and names a provider:
+//
+//	${env:LPE_INGEST_TOKEN}              Another environment variable
+//	${file:/run/secrets/api_token}       A file, e.g. a mounted Kubernetes secret
+//	${vault:secret/data/lpe#api_token}   A HashiCorp Vault secret and key
+//	${aws-sm:prod/lpe#api_token}         An AWS Secrets Manager secret, optionally a JSON key
+//
+// Values that are not references are returned unchanged.
+//
+// # Configuration
+//
+// Vault is reached through VAULT_ADDR with VAULT_TOKEN (and VAULT_NAMESPACE
+// for Vault Enterprise). AWS Secrets Manager uses AWS_REGION (or
+// AWS_DEFAULT_REGION) and static credentials from AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and, for temporary credentials, AWS_SESSION_TOKEN.
+//
+// # Usage
+//
+//	resolver := secrets.NewResolver(os.Getenv)
+//	token, err := resolver.Resolve(ctx, os.Getenv("LPE_CLOUDFLARE_API_TOKEN"))
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Providers a reference can name.
+const (
+	ProviderEnv   = "env"
+	ProviderFile  = "file"
+	ProviderVault = "vault"
+	ProviderAWSSM = "aws-sm"
+)
+
+// requestTimeout bounds every Vault and AWS request.
+const requestTimeout = 10 * time.Second
+
+// referencePattern matches a whole-value reference such as ${vault:path#key}.
+var referencePattern = regexp.MustCompile(`^\$\{([a-z-]+):([^}]+)\}$`)
+
+// Reference is a parsed secret reference.
+type Reference struct {
+	Provider string // One of the Provider constants
+	Path     string // Variable name, file path or secret path
+	Key      string // Key within the secret, after '#'; empty for the whole secret
+}
+
+// String returns the reference in ${provider:path#key} form.
+func (r Reference) String() string {
+	s := "${" + r.Provider + ":" + r.Path
+	if r.Key != "" {
+		s += "#" + r.Key
+	}
+	return s + "}"
+}
+
+// ParseReference parses value as a secret reference.
+//
+// Parameters:
+//   - value: Candidate reference
+//
+// Returns:
+//   - Reference: The parsed reference
+//   - bool: Whether value is written as a reference at all
+//   - error: If value looks like a reference but is not a valid one
+func ParseReference(value string) (Reference, bool, error) {
+	if !strings.HasPrefix(value, "${") {
+		return Reference{}, false, nil
+	}
+	m := referencePattern.FindStringSubmatch(value)
+	if m == nil {
+		return Reference{}, true, fmt.Errorf("secrets: malformed reference %q", value)
+	}
+	ref := Reference{Provider: m[1], Path: m[2]}
+	if path, key, ok := strings.Cut(ref.Path, "#"); ok {
+		ref.Path, ref.Key = path, key
+	}
+	switch ref.Provider {
+	case ProviderEnv, ProviderFile:
+		if ref.Key != "" {
+			return ref, true, fmt.Errorf("secrets: %s references do not take a #key", ref.Provider)
+		}
+	case ProviderVault:
+		if ref.Key == "" {
+			return ref, true, fmt.Errorf("secrets: vault references need a #key")
+		}
+	case ProviderAWSSM:
+	default:
+		return ref, true, fmt.Errorf("secrets: unknown provider %q", ref.Provider)
+	}
+	if ref.Path == "" {
+		return ref, true, fmt.Errorf("secrets: reference %q has no path", value)
+	}
+	return ref, true, nil
+}
+
+// Resolver resolves secret references against the configured providers.
+type Resolver struct {
+	HTTPClient *http.Client // Transport for Vault and AWS requests
+
+	// VaultAddr, VaultToken and VaultNamespace configure the Vault provider
+	VaultAddr      string
+	VaultToken     string
+	VaultNamespace string
+
+	// AWS configures the Secrets Manager provider
+	AWS AWSConfig
+
+	getenv func(string) string
+}
+
+// NewResolver creates a resolver configured from the environment.
+//
+// Parameters:
+//   - getenv: Environment lookup, normally os.Getenv
+//
+// Returns:
+//   - *Resolver: Resolver for all providers
+func NewResolver(getenv func(string) string) *Resolver {
+	region := getenv("AWS_REGION")
+	if region == "" {
+		region = getenv("AWS_DEFAULT_REGION")
+	}
+	return &Resolver{
+		HTTPClient:     &http.Client{Timeout: requestTimeout},
+		VaultAddr:      strings.TrimSuffix(getenv("VAULT_ADDR"), "/"),
+		VaultToken:     getenv("VAULT_TOKEN"),
+		VaultNamespace: getenv("VAULT_NAMESPACE"),
+		AWS: AWSConfig{
+			Region:          region,
+			AccessKeyID:     getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    getenv("AWS_SESSION_TOKEN"),
+		},
+		getenv: getenv,
+	}
+}
+
+// Resolve returns the secret value refers to, or value itself when it is
+// not a reference.
+//
+// Parameters:
+//   - ctx: Context for provider requests
+//   - value: Plain value or reference
+//
+// Returns:
+//   - string: The resolved secret
+//   - error: If the reference is invalid or cannot be resolved
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	ref, ok, err := ParseReference(value)
+	if !ok || err != nil {
+		return value, err
+	}
+	var secret string
+	switch ref.Provider {
+	case ProviderEnv:
+		secret = r.getenv(ref.Path)
+		if secret == "" {
+			err = fmt.Errorf("environment variable %s is not set", ref.Path)
+		}
+	case ProviderFile:
+		var data []byte
+		if data, err = os.ReadFile(ref.Path); err == nil {
+			secret = strings.TrimRight(string(data), "\r\n")
+		}
+	case ProviderVault:
+		secret, err = r.resolveVault(ctx, ref)
+	case ProviderAWSSM:
+		secret, err = r.resolveAWS(ctx, ref)
+	}
+	if err != nil {
+		return "", fmt.Errorf("secrets: resolve %s: %w", ref, err)
+	}
+	return secret, nil
+}
+
+// Getenv resolves the named environment variables up front and returns a
+// lookup serving the resolved values. Other variables are passed through
+// unchanged, so the result can stand in for os.Getenv.
+//
+// Parameters:
+//   - ctx: Context for provider requests
+//   - getenv: Underlying environment lookup
+//   - names: Variables that may hold references
+//
+// Returns:
+//   - func(string) string: Lookup with references resolved
+//   - error: The first reference that could not be resolved
+func (r *Resolver) Getenv(ctx context.Context, getenv func(string) string, names ...string) (func(string) string, error) {
+	resolved := make(map[string]string, len(names))
+	for _, name := range names {
+		value, err := r.Resolve(ctx, getenv(name))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		resolved[name] = value
+	}
+	return func(name string) string {
+		if value, ok := resolved[name]; ok {
+			return value
+		}
+		return getenv(name)
+	}, nil
+}
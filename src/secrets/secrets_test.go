@@ -0,0 +1,69 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Reference
+		isRef   bool
+		wantErr bool
+	}{
+		{"plain-token", Reference{}, false, false},
+		{"${env:TOKEN}", Reference{Provider: ProviderEnv, Path: "TOKEN"}, true, false},
+		{"${vault:secret/data/lpe#api_token}", Reference{Provider: ProviderVault, Path: "secret/data/lpe", Key: "api_token"}, true, false},
+		{"${aws-sm:prod/lpe}", Reference{Provider: ProviderAWSSM, Path: "prod/lpe"}, true, false},
+		{"${vault:secret/lpe}", Reference{}, true, true},
+		{"${gcp:projects/x}", Reference{}, true, true},
+		{"${env:TOKEN", Reference{}, true, true},
+	}
+	for _, tt := range tests {
+		got, isRef, err := ParseReference(tt.value)
+		if isRef != tt.isRef || (err != nil) != tt.wantErr {
+			t.Errorf("ParseReference(%q) = %v, %v; want reference=%v error=%v", tt.value, isRef, err, tt.isRef, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseReference(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestResolveEnvAndFile(t *testing.T) {
+	secretFile := "test_secret.txt"
+	defer os.Remove(secretFile)
+	if err := os.WriteFile(secretFile, []byte("file-token\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+
+	env := map[string]string{
+		"REAL_TOKEN":               "env-token",
+		"LPE_CLOUDFLARE_API_TOKEN": "${env:REAL_TOKEN}",
+		"LPE_ENCRYPTION_KEY":       "${file:" + secretFile + "}",
+		"UNRELATED":                "${left:alone}",
+	}
+	getenv := func(k string) string { return env[k] }
+	r := NewResolver(getenv)
+
+	lookup, err := r.Getenv(context.Background(), getenv, "LPE_CLOUDFLARE_API_TOKEN", "LPE_ENCRYPTION_KEY")
+	if err != nil {
+		t.Fatalf("Failed to resolve environment: %v", err)
+	}
+	if got := lookup("LPE_CLOUDFLARE_API_TOKEN"); got != "env-token" {
+		t.Errorf("Expected the env reference to resolve, got %q", got)
+	}
+	if got := lookup("LPE_ENCRYPTION_KEY"); got != "file-token" {
+		t.Errorf("Expected the file reference to resolve without its newline, got %q", got)
+	}
+	if got := lookup("UNRELATED"); got != "${left:alone}" {
+		t.Errorf("Expected unlisted variables to pass through, got %q", got)
+	}
+
+	if _, err := r.Resolve(context.Background(), "${env:UNSET}"); err == nil {
+		t.Error("Expected an error for an unset variable")
+	}
+}
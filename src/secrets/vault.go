@@ -0,0 +1,55 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// resolveVault reads ref.Key from the Vault secret at ref.Path. The path is
+// the API path after /v1/, so KV version 2 secrets include the data segment
+// (secret/data/lpe) while version 1 secrets do not (secret/lpe).
+func (r *Resolver) resolveVault(ctx context.Context, ref Reference) (string, error) {
+	if r.VaultAddr == "" || r.VaultToken == "" {
+		return "", fmt.Errorf("VAULT_ADDR and VAULT_TOKEN must be set")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.VaultAddr+"/v1/"+ref.Path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", r.VaultToken)
+	if r.VaultNamespace != "" {
+		req.Header.Set("X-Vault-Namespace", r.VaultNamespace)
+	}
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	var secret struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("decode vault response: %w", err)
+	}
+	// KV version 2 nests the key/value pairs one level deeper
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		data = nested
+	}
+	value, ok := data[ref.Key].(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret has no string key %q", ref.Key)
+	}
+	return value, nil
+}
@@ -0,0 +1,45 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/lpe":
+			w.Write([]byte(`{"data":{"data":{"api_token":"kv2-token"},"metadata":{"version":3}}}`))
+		case "/v1/kv/lpe":
+			w.Write([]byte(`{"data":{"api_token":"kv1-token"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	env := map[string]string{"VAULT_ADDR": server.URL + "/", "VAULT_TOKEN": "vault-token"}
+	r := NewResolver(func(k string) string { return env[k] })
+
+	ctx := context.Background()
+	for ref, want := range map[string]string{
+		"${vault:secret/data/lpe#api_token}": "kv2-token",
+		"${vault:kv/lpe#api_token}":          "kv1-token",
+	} {
+		if got, err := r.Resolve(ctx, ref); err != nil || got != want {
+			t.Errorf("Resolve(%s) = %q, %v; want %q", ref, got, err, want)
+		}
+	}
+	if _, err := r.Resolve(ctx, "${vault:secret/data/lpe#missing}"); err == nil {
+		t.Error("Expected an error for a missing key")
+	}
+	if _, err := r.Resolve(ctx, "${vault:secret/data/other#api_token}"); err == nil {
+		t.Error("Expected an error for a missing secret")
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notify sends a state line such as READY=1 to the service manager socket
+// named by $NOTIFY_SOCKET, as sd_notify(3) does.
+//
+// Parameters:
+//   - state: Newline-separated VARIABLE=value assignments
+//
+// Returns:
+//   - bool: Whether the state was sent; false when $NOTIFY_SOCKET is unset
+//   - error: Any error encountered while sending
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:] // Linux abstract namespace
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the watchdog interval systemd expects pings
+// within, from $WATCHDOG_USEC, or 0 when the watchdog is disabled or meant
+// for another process ($WATCHDOG_PID), as sd_watchdog_enabled(3) does.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
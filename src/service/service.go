@@ -0,0 +1,105 @@
+// Package service integrates the estimator with process managers, so they
+// consider it started only once it can serve requests rather than as soon
+// as the process is executed.
+//
+// Outside the Windows Service Control Manager, Run cancels the
+// application's context on SIGINT or SIGTERM.
+//
+// Under systemd (Type=notify), Run sends READY=1 over $NOTIFY_SOCKET when
+// the application reports it is ready, STOPPING=1 when it returns, and, when
+// WatchdogSec= is set, WATCHDOG=1 pings at half the watchdog interval for as
+// long as the health check passes. Without $NOTIFY_SOCKET these are no-ops.
+//
+// Started by the Windows Service Control Manager, Run reports
+// SERVICE_START_PENDING until the application is ready, SERVICE_RUNNING
+// afterwards, and cancels the application's context when the service is
+// stopped or the system shuts down.
+//
+// # Usage
+//
+//	err := service.Run(service.Options{Name: "LogpushEstimator", Logger: logger, Healthy: db.Ping},
+//		func(ctx context.Context, ready func()) error {
+//			// start listening, then
+//			ready()
+//			<-ctx.Done()
+//			return nil
+//		})
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// App is the application run by Run. It calls ready once it can serve
+// requests and returns once ctx is cancelled, or earlier on failure.
+type App func(ctx context.Context, ready func()) error
+
+// Options configures Run.
+type Options struct {
+	Name    string       // Windows service name
+	Logger  *slog.Logger // Structured logger (default slog.Default())
+	Healthy func() error // Checked before each watchdog ping; nil is always healthy
+}
+
+// runNotify runs app reporting its lifecycle through sd_notify, cancelling
+// its context on SIGINT or SIGTERM. It is how Run behaves outside the
+// Windows Service Control Manager.
+func runNotify(ctx context.Context, opts Options, app App) error {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if interval := WatchdogInterval(); interval > 0 {
+		logger.Info("Systemd watchdog enabled", "interval", interval)
+		go watchdog(ctx, interval/2, opts.Healthy, logger)
+	}
+
+	var once sync.Once
+	ready := func() {
+		once.Do(func() {
+			if sent, err := Notify("READY=1"); err != nil {
+				logger.Warn("Failed to notify systemd of readiness", "error", err)
+			} else if sent {
+				logger.Info("Notified systemd of readiness")
+			}
+		})
+	}
+	err := app(ctx, ready)
+	if _, nerr := Notify("STOPPING=1"); nerr != nil {
+		logger.Warn("Failed to notify systemd of shutdown", "error", nerr)
+	}
+	return err
+}
+
+// watchdog sends WATCHDOG=1 every period while healthy passes, until ctx is
+// done. A failing check skips the ping, so systemd restarts the service if
+// it keeps failing for the whole watchdog interval.
+func watchdog(ctx context.Context, period time.Duration, healthy func() error, logger *slog.Logger) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if healthy != nil {
+			if err := healthy(); err != nil {
+				logger.Warn("Skipping watchdog ping, health check failed", "error", err)
+				continue
+			}
+		}
+		if _, err := Notify("WATCHDOG=1"); err != nil {
+			logger.Warn("Failed to send watchdog ping", "error", err)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package service
+
+import "context"
+
+// Run runs app until it returns, reporting readiness and shutdown through
+// sd_notify. The Windows build also runs app under the Service Control
+// Manager.
+//
+// Parameters:
+//   - opts: Service name, logger and watchdog health check
+//   - app: Application to run
+//
+// Returns:
+//   - error: The error app returned
+func Run(opts Options, app App) error {
+	return runNotify(context.Background(), opts, app)
+}
@@ -0,0 +1,139 @@
+//go:build !windows
+
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenNotify points $NOTIFY_SOCKET at a new socket and returns it.
+func listenNotify(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+// readState returns the next state line sent to conn.
+func readState(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 256)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("No state received: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := Notify("READY=1"); sent || err != nil {
+		t.Errorf("Expected a no-op without NOTIFY_SOCKET, got %v, %v", sent, err)
+	}
+
+	conn := listenNotify(t)
+	if sent, err := Notify("READY=1"); !sent || err != nil {
+		t.Fatalf("Notify failed: %v, %v", sent, err)
+	}
+	if state := readState(t, conn); state != "READY=1" {
+		t.Errorf("Expected READY=1, got %q", state)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		usec, pid string
+		want      time.Duration
+	}{
+		{"", "", 0},
+		{"abc", "", 0},
+		{"30000000", "", 30 * time.Second},
+		{"30000000", strconv.Itoa(os.Getpid()), 30 * time.Second},
+		{"30000000", "1", 0},
+	}
+	for _, tt := range tests {
+		t.Setenv("WATCHDOG_USEC", tt.usec)
+		t.Setenv("WATCHDOG_PID", tt.pid)
+		if got := WatchdogInterval(); got != tt.want {
+			t.Errorf("WATCHDOG_USEC=%q WATCHDOG_PID=%q: expected %v, got %v", tt.usec, tt.pid, tt.want, got)
+		}
+	}
+}
+
+func TestRunReportsReadinessAndShutdown(t *testing.T) {
+	conn := listenNotify(t)
+	t.Setenv("WATCHDOG_USEC", "")
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	appErr := errors.New("stopped")
+	err := Run(Options{Name: "test", Logger: logger}, func(ctx context.Context, ready func()) error {
+		if state := readStateNonBlocking(conn); state != "" {
+			t.Errorf("Expected nothing before ready, got %q", state)
+		}
+		ready()
+		ready()
+		return appErr
+	})
+	if err != appErr {
+		t.Errorf("Expected the app's error, got %v", err)
+	}
+	if state := readState(t, conn); state != "READY=1" {
+		t.Errorf("Expected READY=1, got %q", state)
+	}
+	if state := readState(t, conn); state != "STOPPING=1" {
+		t.Errorf("Expected STOPPING=1 after a single READY=1, got %q", state)
+	}
+}
+
+// readStateNonBlocking returns a state already sent to conn, or "".
+func readStateNonBlocking(conn *net.UnixConn) string {
+	buf := make([]byte, 256)
+	conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	n, _ := conn.Read(buf)
+	return string(buf[:n])
+}
+
+func TestWatchdogSkipsPingsWhileUnhealthy(t *testing.T) {
+	conn := listenNotify(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	healthy := make(chan bool, 1)
+	healthy <- false
+	check := func() error {
+		select {
+		case ok := <-healthy:
+			if !ok {
+				return errors.New("database unreadable")
+			}
+		default:
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchdog(ctx, 5*time.Millisecond, check, logger)
+
+	start := time.Now()
+	if state := readState(t, conn); !strings.HasPrefix(state, "WATCHDOG=1") {
+		t.Errorf("Expected a watchdog ping, got %q", state)
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Error("Expected the first, unhealthy tick to be skipped")
+	}
+}
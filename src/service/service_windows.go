@@ -0,0 +1,213 @@
+//go:build windows
+
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32                          = syscall.NewLazyDLL("advapi32.dll")
+	procStartServiceCtrlDispatcherW   = advapi32.NewProc("StartServiceCtrlDispatcherW")
+	procRegisterServiceCtrlHandlerExW = advapi32.NewProc("RegisterServiceCtrlHandlerExW")
+	procSetServiceStatus              = advapi32.NewProc("SetServiceStatus")
+)
+
+// Service Control Manager constants from winsvc.h and winerror.h.
+const (
+	serviceWin32OwnProcess = 0x10
+
+	serviceStopped      = 1
+	serviceStartPending = 2
+	serviceStopPending  = 3
+	serviceRunning      = 4
+
+	serviceAcceptStop     = 0x1
+	serviceAcceptShutdown = 0x4
+
+	serviceControlStop        = 1
+	serviceControlInterrogate = 4
+	serviceControlShutdown    = 5
+
+	errorCallNotImplemented             = 120
+	errorFailedServiceControllerConnect = 1063
+
+	// startWaitHint is how long, in milliseconds, the SCM is told to wait
+	// between progress reports while the application warms up.
+	startWaitHint = 60000
+)
+
+// serviceStatus is SERVICE_STATUS.
+type serviceStatus struct {
+	ServiceType             uint32
+	CurrentState            uint32
+	ControlsAccepted        uint32
+	Win32ExitCode           uint32
+	ServiceSpecificExitCode uint32
+	CheckPoint              uint32
+	WaitHint                uint32
+}
+
+// serviceTableEntry is SERVICE_TABLE_ENTRYW.
+type serviceTableEntry struct {
+	ServiceName *uint16
+	ServiceProc uintptr
+}
+
+// windowsService is the state shared between Run and the callbacks the
+// Service Control Manager invokes on its own threads.
+type windowsService struct {
+	opts    Options
+	app     App
+	started chan struct{} // Closed when the SCM calls ServiceMain
+	done    chan error    // Receives app's result
+
+	mu     sync.Mutex
+	handle uintptr
+	status serviceStatus
+	cancel context.CancelFunc
+}
+
+// current is the service being run; the SCM callbacks carry no Go state.
+var current *windowsService
+
+var (
+	serviceMainCallback = syscall.NewCallback(serviceMain)
+	ctrlHandlerCallback = syscall.NewCallback(ctrlHandler)
+)
+
+// Run runs app. Started by the Service Control Manager, it registers as
+// opts.Name and reports the service's state to the SCM; otherwise it runs
+// app like other platforms do, reporting through sd_notify if configured.
+//
+// Parameters:
+//   - opts: Service name, logger and watchdog health check
+//   - app: Application to run
+//
+// Returns:
+//   - error: The error app returned, or why the service could not start
+func Run(opts Options, app App) error {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	name, err := syscall.UTF16PtrFromString(opts.Name)
+	if err != nil {
+		return err
+	}
+	s := &windowsService{opts: opts, app: app, started: make(chan struct{}), done: make(chan error, 1)}
+	current = s
+
+	// StartServiceCtrlDispatcherW blocks its thread until the service stops
+	dispatched := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		table := []serviceTableEntry{{ServiceName: name, ServiceProc: serviceMainCallback}, {}}
+		if r, _, err := procStartServiceCtrlDispatcherW.Call(uintptr(unsafe.Pointer(&table[0]))); r == 0 {
+			dispatched <- err
+			return
+		}
+		dispatched <- nil
+	}()
+
+	select {
+	case err := <-dispatched:
+		var errno syscall.Errno
+		if errors.As(err, &errno) && errno == errorFailedServiceControllerConnect {
+			// Not started by the SCM, e.g. run from a console
+			return runNotify(context.Background(), opts, app)
+		}
+		if err == nil {
+			err = errors.New("service dispatcher returned before the service started")
+		}
+		return err
+	case <-s.started:
+		err := <-s.done
+		<-dispatched
+		return err
+	}
+}
+
+// setStatus reports state to the SCM. The caller holds s.mu.
+func (s *windowsService) setStatus(state, accepts uint32, exitCode uint32) {
+	s.status = serviceStatus{
+		ServiceType:      serviceWin32OwnProcess,
+		CurrentState:     state,
+		ControlsAccepted: accepts,
+		Win32ExitCode:    exitCode,
+	}
+	if state == serviceStartPending || state == serviceStopPending {
+		s.status.CheckPoint = 1
+		s.status.WaitHint = startWaitHint
+	}
+	if r, _, err := procSetServiceStatus.Call(s.handle, uintptr(unsafe.Pointer(&s.status))); r == 0 {
+		s.opts.Logger.Warn("Failed to report service status", "state", state, "error", err)
+	}
+}
+
+// serviceMain is the ServiceMain the SCM calls once the service starts. It
+// runs the application and returns once it has stopped.
+func serviceMain(argc, argv uintptr) uintptr {
+	s := current
+	name, _ := syscall.UTF16PtrFromString(s.opts.Name)
+	handle, _, err := procRegisterServiceCtrlHandlerExW.Call(uintptr(unsafe.Pointer(name)), ctrlHandlerCallback, 0)
+	if handle == 0 {
+		close(s.started)
+		s.done <- err
+		return 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.handle, s.cancel = handle, cancel
+	s.setStatus(serviceStartPending, 0, 0)
+	s.mu.Unlock()
+	close(s.started)
+	s.opts.Logger.Info("Running as a Windows service", "name", s.opts.Name)
+
+	var once sync.Once
+	ready := func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.status.CurrentState == serviceStartPending {
+				s.setStatus(serviceRunning, serviceAcceptStop|serviceAcceptShutdown, 0)
+			}
+		})
+	}
+	appErr := s.app(ctx, ready)
+	cancel()
+
+	var exitCode uint32
+	if appErr != nil {
+		exitCode = 1
+	}
+	s.mu.Lock()
+	s.setStatus(serviceStopped, 0, exitCode)
+	s.mu.Unlock()
+	s.done <- appErr
+	return 0
+}
+
+// ctrlHandler is the HandlerEx the SCM calls with control requests.
+func ctrlHandler(ctrl, _, _, _ uintptr) uintptr {
+	s := current
+	switch ctrl {
+	case serviceControlStop, serviceControlShutdown:
+		s.mu.Lock()
+		s.setStatus(serviceStopPending, 0, 0)
+		cancel := s.cancel
+		s.mu.Unlock()
+		s.opts.Logger.Info("Windows service stop requested", "control", ctrl)
+		cancel()
+		return 0
+	case serviceControlInterrogate:
+		return 0
+	}
+	return errorCallNotImplemented
+}
@@ -0,0 +1,128 @@
+package sheets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+	"github.com/melatonein5/LogpushEstimator/src/reports"
+)
+
+// monthlyTotalsMonths is how many months, including the current one, the
+// monthly_totals report covers.
+const monthlyTotalsMonths = 12
+
+// Sync builds every configured report and replaces its tab in the
+// spreadsheet. Costs use the default pricing model (or the only one);
+// without one, cost columns are left empty and the chargeback report is
+// skipped.
+//
+// Parameters:
+//   - ctx: Request context
+//   - client: Google Sheets API client
+//   - db: Database controller to build the reports from
+//   - settings: Spreadsheet and reports to push
+//   - now: Time the reports are built at; the current month is now's
+//
+// Returns:
+//   - error: The first error encountered; reports before it are pushed
+func Sync(ctx context.Context, client *Client, db *database.SQLiteController, settings Settings, now time.Time) error {
+	doc, err := config.Export(db)
+	if err != nil {
+		return fmt.Errorf("sheets: load pricing models: %w", err)
+	}
+	model, priced := doc.PricingModel("")
+
+	for _, name := range settings.Reports {
+		var rows [][]any
+		switch name {
+		case ReportMonthlyTotals:
+			rows, err = monthlyTotals(db, model, priced, now)
+		case ReportDatasets:
+			rows, err = datasetBreakdown(db, now)
+		case ReportChargeback:
+			if !priced {
+				continue
+			}
+			rows, err = chargeback(db, model, now)
+		default:
+			err = errors.New("unknown report")
+		}
+		if err != nil {
+			return fmt.Errorf("sheets: report %s: %w", name, err)
+		}
+		if err := client.ReplaceSheet(ctx, settings.SpreadsheetID, name, rows); err != nil {
+			return fmt.Errorf("report %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// monthlyTotals builds one row per month, oldest first, of the last year.
+func monthlyTotals(db *database.SQLiteController, model config.PricingModel, priced bool, now time.Time) ([][]any, error) {
+	rows := [][]any{{"month", "batches", "records", "bytes", "cost", "currency"}}
+	current, _ := reports.MonthBounds(now)
+	for i := monthlyTotalsMonths - 1; i >= 0; i-- {
+		month := current.AddDate(0, -i, 0)
+		start, end := reports.MonthBounds(month)
+		usage, err := db.QueryTenantUsage(start, end)
+		if err != nil {
+			return nil, err
+		}
+		var total database.TenantUsage
+		for _, u := range usage {
+			total.Records += u.Records
+			total.RecordCount += u.RecordCount
+			total.TotalSize += u.TotalSize
+		}
+		row := []any{month.Format(reports.MonthLayout), total.Records, total.RecordCount, total.TotalSize, "", ""}
+		if priced {
+			report := reports.Allocate(month, model, usage, nil)
+			row[4], row[5] = report.TotalCost, report.Currency
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// datasetBreakdown builds one row per dataset dimension value in the
+// current month, largest first, from the dataset parsers' rollups.
+func datasetBreakdown(db *database.SQLiteController, now time.Time) ([][]any, error) {
+	start, end := reports.MonthBounds(now)
+	totals, err := db.QueryDimensionTotals(start, end)
+	if err != nil {
+		return nil, err
+	}
+	rows := [][]any{{"month", "dataset", "dimension", "value", "records", "bytes"}}
+	for _, d := range totals {
+		rows = append(rows, []any{start.Format(reports.MonthLayout), d.Dataset, d.Dimension, d.Value, d.Records, d.Bytes})
+	}
+	return rows, nil
+}
+
+// chargeback builds the current month's chargeback, one row per tenant.
+func chargeback(db *database.SQLiteController, model config.PricingModel, now time.Time) ([][]any, error) {
+	start, end := reports.MonthBounds(now)
+	usage, err := db.QueryTenantUsage(start, end)
+	if err != nil {
+		return nil, err
+	}
+	var requests map[string]int64
+	if model.Batching != nil {
+		hourly, err := db.QueryTenantHourlyUsage(start, end)
+		if err != nil {
+			return nil, err
+		}
+		requests = reports.EstimateRequests(*model.Batching, hourly)
+	}
+	report := reports.Allocate(start, model, usage, requests)
+	rows := [][]any{{"month", "tenant", "batches", "records", "bytes", "requests", "share", "cost", "currency"}}
+	for _, l := range report.Lines {
+		rows = append(rows, []any{report.Month, l.Tenant, l.Batches, l.Records, l.Bytes, l.Requests, math.Round(l.Share*1e6) / 1e6, l.Cost, report.Currency})
+	}
+	return rows, nil
+}
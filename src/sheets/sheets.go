@@ -0,0 +1,392 @@
+// Package sheets pushes estimator reports to a Google Sheet, for budgeting
+// workflows that live in spreadsheets.
+//
+// The integration is optional. It authenticates as a Google Cloud service
+// account; share the spreadsheet with the account's client_email as an
+// editor. Each report is written to its own tab, named after the report,
+// which is created if missing and replaced on every sync.
+//
+// # Configuration
+//
+//	LPE_SHEETS_SPREADSHEET_ID=...                 Spreadsheet to write to (from its URL)
+//	LPE_SHEETS_CREDENTIALS=<json>                 Service account key
+//	LPE_SHEETS_CREDENTIALS_FILE=/run/keys/sa.json File containing the key
+//	LPE_SHEETS_REPORTS=monthly_totals,datasets    Reports to push (default monthly_totals)
+//	LPE_SHEETS_INTERVAL=24h                       Time between syncs (default 24h)
+//
+// # Usage
+//
+//	settings, err := sheets.SettingsFromEnv(os.Getenv)
+//	if err != nil {
+//		return err
+//	}
+//	if settings.Enabled() {
+//		client := sheets.NewClient(settings.Credentials)
+//		err = sheets.Sync(ctx, client, db, settings, time.Now())
+//	}
+package sheets
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBaseURL is the Google Sheets API v4 endpoint.
+const DefaultBaseURL = "https://sheets.googleapis.com/v4"
+
+// DefaultInterval is the time between syncs when LPE_SHEETS_INTERVAL is
+// unset.
+const DefaultInterval = 24 * time.Hour
+
+// scope grants read and write access to spreadsheets shared with the
+// service account.
+const scope = "https://www.googleapis.com/auth/spreadsheets"
+
+// requestTimeout bounds every call to Google's APIs.
+const requestTimeout = 30 * time.Second
+
+// Reports that can be pushed.
+const (
+	ReportMonthlyTotals = "monthly_totals" // Volume and cost per month for the last year
+	ReportDatasets      = "datasets"       // Volume per dataset per month
+	ReportChargeback    = "chargeback"     // The current month's cost per tenant
+)
+
+// reportNames lists the supported reports in the order they are pushed.
+var reportNames = []string{ReportMonthlyTotals, ReportDatasets, ReportChargeback}
+
+// Credentials is the part of a service account key file the integration
+// uses.
+type Credentials struct {
+	ClientEmail string `json:"client_email"` // Service account identity
+	PrivateKey  string `json:"private_key"`  // PEM-encoded RSA key
+	TokenURI    string `json:"token_uri"`    // OAuth token endpoint
+
+	key *rsa.PrivateKey
+}
+
+// ParseCredentials parses a service account key file.
+//
+// Parameters:
+//   - data: JSON key file downloaded from Google Cloud
+//
+// Returns:
+//   - Credentials: Parsed credentials
+//   - error: If the file is not a usable service account key
+func ParseCredentials(data []byte) (Credentials, error) {
+	var c Credentials
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("sheets: credentials are not valid JSON: %w", err)
+	}
+	if c.ClientEmail == "" || c.TokenURI == "" {
+		return c, errors.New("sheets: credentials need client_email and token_uri")
+	}
+	block, _ := pem.Decode([]byte(c.PrivateKey))
+	if block == nil {
+		return c, errors.New("sheets: credentials private_key is not PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return c, fmt.Errorf("sheets: credentials private_key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return c, errors.New("sheets: credentials private_key is not an RSA key")
+	}
+	c.key = key
+	return c, nil
+}
+
+// Settings holds the Google Sheets integration configuration.
+type Settings struct {
+	SpreadsheetID string        // Spreadsheet to write to
+	Credentials   Credentials   // Service account to write as
+	Reports       []string      // Reports to push, one tab each
+	Interval      time.Duration // Time between syncs
+}
+
+// SettingsFromEnv reads the LPE_SHEETS_* variables.
+//
+// Parameters:
+//   - getenv: Environment lookup, normally os.Getenv
+//
+// Returns:
+//   - Settings: Parsed settings; disabled when no spreadsheet is configured
+//   - error: If the integration is configured but invalid
+func SettingsFromEnv(getenv func(string) string) (Settings, error) {
+	s := Settings{
+		SpreadsheetID: strings.TrimSpace(getenv("LPE_SHEETS_SPREADSHEET_ID")),
+		Reports:       []string{ReportMonthlyTotals},
+		Interval:      DefaultInterval,
+	}
+	if s.SpreadsheetID == "" {
+		return s, nil
+	}
+
+	data := []byte(getenv("LPE_SHEETS_CREDENTIALS"))
+	if len(data) == 0 {
+		path := strings.TrimSpace(getenv("LPE_SHEETS_CREDENTIALS_FILE"))
+		if path == "" {
+			return s, errors.New("sheets: LPE_SHEETS_CREDENTIALS or LPE_SHEETS_CREDENTIALS_FILE must be set")
+		}
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return s, fmt.Errorf("sheets: read credentials file: %w", err)
+		}
+	}
+	creds, err := ParseCredentials(data)
+	if err != nil {
+		return s, err
+	}
+	s.Credentials = creds
+
+	if v := getenv("LPE_SHEETS_REPORTS"); v != "" {
+		s.Reports = nil
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if !slices.Contains(reportNames, name) {
+				return s, fmt.Errorf("sheets: unknown report %q (use %s)", name, strings.Join(reportNames, ", "))
+			}
+			s.Reports = append(s.Reports, name)
+		}
+	}
+	if v := getenv("LPE_SHEETS_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval < time.Minute {
+			return s, fmt.Errorf("sheets: LPE_SHEETS_INTERVAL must be a duration of at least 1m, got %q", v)
+		}
+		s.Interval = interval
+	}
+	return s, nil
+}
+
+// Enabled reports whether a spreadsheet is configured.
+func (s Settings) Enabled() bool {
+	return s.SpreadsheetID != ""
+}
+
+// Client calls the Google Sheets API as a service account.
+type Client struct {
+	BaseURL    string       // API root, DefaultBaseURL unless overridden (e.g. in tests)
+	HTTPClient *http.Client // Transport used for requests
+	creds      Credentials
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewClient creates a client authenticating as the service account.
+func NewClient(creds Credentials) *Client {
+	return &Client{
+		BaseURL:    DefaultBaseURL,
+		HTTPClient: &http.Client{Timeout: requestTimeout},
+		creds:      creds,
+	}
+}
+
+// APIError is an error reported by a Google API.
+type APIError struct {
+	Status  int    // HTTP status code
+	Message string // Error message from the response body
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("sheets: HTTP %d", e.Status)
+	}
+	return fmt.Sprintf("sheets: HTTP %d: %s", e.Status, e.Message)
+}
+
+// accessToken returns a cached OAuth access token, exchanging a freshly
+// signed JWT assertion for a new one shortly before the old one expires.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.expires.Add(-time.Minute)) {
+		return c.token, nil
+	}
+
+	assertion, err := c.signAssertion(time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.creds.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	data, err := c.send(req)
+	if err != nil {
+		return "", err
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(data, &token); err != nil || token.AccessToken == "" {
+		return "", errors.New("sheets: token endpoint returned no access token")
+	}
+	c.token = token.AccessToken
+	c.expires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return c.token, nil
+}
+
+// signAssertion creates the RS256-signed JWT that authenticates the service
+// account to the token endpoint.
+func (c *Client) signAssertion(now time.Time) (string, error) {
+	encode := func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data), err
+	}
+	header, err := encode(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := encode(map[string]any{
+		"iss":   c.creds.ClientEmail,
+		"scope": scope,
+		"aud":   c.creds.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + claims
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.creds.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// do sends an authenticated request to path with an optional JSON body and
+// returns the raw response body.
+func (c *Client) do(ctx context.Context, method, path string, body any) ([]byte, error) {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req)
+}
+
+// send performs req, returning non-2xx responses as *APIError carrying the
+// message from Google's error envelope.
+func (c *Client) send(req *http.Request) ([]byte, error) {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// The Sheets API nests the message in an error object; the token
+		// endpoint returns an OAuth error code with a description
+		apiErr := &APIError{Status: resp.StatusCode}
+		var envelope struct {
+			Error       json.RawMessage `json:"error"`
+			Description string          `json:"error_description"`
+		}
+		if json.Unmarshal(data, &envelope) == nil {
+			var nested struct {
+				Message string `json:"message"`
+			}
+			json.Unmarshal(envelope.Error, &nested)
+			apiErr.Message = nested.Message
+			if apiErr.Message == "" {
+				apiErr.Message = envelope.Description
+			}
+		}
+		return nil, apiErr
+	}
+	return data, nil
+}
+
+// ReplaceSheet replaces the contents of the named tab with rows, adding the
+// tab first if the spreadsheet does not have it.
+//
+// Parameters:
+//   - ctx: Request context
+//   - spreadsheetID: Spreadsheet to write to
+//   - title: Tab name
+//   - rows: Cell values, first row first
+//
+// Returns:
+//   - error: Any API error; the tab may be left cleared
+func (c *Client) ReplaceSheet(ctx context.Context, spreadsheetID, title string, rows [][]any) error {
+	base := "/spreadsheets/" + url.PathEscape(spreadsheetID)
+	data, err := c.do(ctx, http.MethodGet, base+"?fields=sheets.properties.title", nil)
+	if err != nil {
+		return err
+	}
+	var spreadsheet struct {
+		Sheets []struct {
+			Properties struct {
+				Title string `json:"title"`
+			} `json:"properties"`
+		} `json:"sheets"`
+	}
+	if err := json.Unmarshal(data, &spreadsheet); err != nil {
+		return fmt.Errorf("sheets: decode spreadsheet: %w", err)
+	}
+	exists := false
+	for _, sheet := range spreadsheet.Sheets {
+		exists = exists || sheet.Properties.Title == title
+	}
+	if !exists {
+		add := map[string]any{"requests": []any{map[string]any{"addSheet": map[string]any{"properties": map[string]string{"title": title}}}}}
+		if _, err := c.do(ctx, http.MethodPost, base+":batchUpdate", add); err != nil {
+			return err
+		}
+	}
+
+	rng := url.PathEscape("'" + strings.ReplaceAll(title, "'", "''") + "'")
+	if _, err := c.do(ctx, http.MethodPost, base+"/values/"+rng+":clear", map[string]any{}); err != nil {
+		return err
+	}
+	_, err = c.do(ctx, http.MethodPut, base+"/values/"+rng+"?valueInputOption=RAW", map[string]any{"majorDimension": "ROWS", "values": rows})
+	return err
+}
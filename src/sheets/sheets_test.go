@@ -0,0 +1,212 @@
+package sheets
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/config"
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// testCredentials returns a service account key file for a freshly generated
+// key, with tokens issued by tokenURI.
+func testCredentials(t *testing.T, tokenURI string) ([]byte, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	data, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "lpe@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenURI,
+	})
+	return data, key
+}
+
+func TestSettingsFromEnv(t *testing.T) {
+	creds, _ := testCredentials(t, "https://oauth2.googleapis.com/token")
+	env := map[string]string{
+		"LPE_SHEETS_SPREADSHEET_ID": " sheet-id ",
+		"LPE_SHEETS_CREDENTIALS":    string(creds),
+		"LPE_SHEETS_REPORTS":        "monthly_totals, chargeback",
+		"LPE_SHEETS_INTERVAL":       "6h",
+	}
+	s, err := SettingsFromEnv(func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatalf("SettingsFromEnv failed: %v", err)
+	}
+	if !s.Enabled() || s.SpreadsheetID != "sheet-id" || s.Credentials.ClientEmail != "lpe@project.iam.gserviceaccount.com" {
+		t.Errorf("Unexpected settings %+v", s)
+	}
+	if len(s.Reports) != 2 || s.Reports[1] != ReportChargeback || s.Interval != 6*time.Hour {
+		t.Errorf("Expected two reports every 6h, got %v every %v", s.Reports, s.Interval)
+	}
+
+	if s, err := SettingsFromEnv(func(string) string { return "" }); err != nil || s.Enabled() {
+		t.Errorf("Expected integration disabled without a spreadsheet, got %+v, %v", s, err)
+	}
+
+	invalid := map[string]map[string]string{
+		"missing credentials": {"LPE_SHEETS_SPREADSHEET_ID": "id"},
+		"malformed key":       {"LPE_SHEETS_SPREADSHEET_ID": "id", "LPE_SHEETS_CREDENTIALS": `{"client_email":"a","token_uri":"b","private_key":"x"}`},
+		"unknown report":      {"LPE_SHEETS_SPREADSHEET_ID": "id", "LPE_SHEETS_CREDENTIALS": string(creds), "LPE_SHEETS_REPORTS": "pivot"},
+		"short interval":      {"LPE_SHEETS_SPREADSHEET_ID": "id", "LPE_SHEETS_CREDENTIALS": string(creds), "LPE_SHEETS_INTERVAL": "1s"},
+	}
+	for name, env := range invalid {
+		if _, err := SettingsFromEnv(func(k string) string { return env[k] }); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	var key *rsa.PrivateKey
+	tokens := 0
+	var added []string
+	written := map[string][][]any{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			tokens++
+			r.ParseForm()
+			parts := strings.Split(r.PostForm.Get("assertion"), ".")
+			signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+			digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			if len(parts) != 3 || rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature) != nil {
+				t.Errorf("Expected an assertion signed by the service account key")
+			}
+			w.Write([]byte(`{"access_token":"access","expires_in":3600}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer access" {
+			t.Errorf("Expected bearer token, got %q", r.Header.Get("Authorization"))
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/spreadsheets/sheet-id":
+			w.Write([]byte(`{"sheets":[{"properties":{"title":"monthly_totals"}}]}`))
+		case r.URL.Path == "/spreadsheets/sheet-id:batchUpdate":
+			var body struct {
+				Requests []struct {
+					AddSheet struct {
+						Properties struct {
+							Title string `json:"title"`
+						} `json:"properties"`
+					} `json:"addSheet"`
+				} `json:"requests"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			added = append(added, body.Requests[0].AddSheet.Properties.Title)
+			w.Write([]byte(`{}`))
+		case strings.HasSuffix(r.URL.Path, ":clear"):
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPut:
+			var body struct {
+				Values [][]any `json:"values"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			written[strings.TrimPrefix(r.URL.Path, "/spreadsheets/sheet-id/values/")] = body.Values
+			w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"message":"Requested entity was not found."}}`))
+		}
+	}))
+	defer server.Close()
+
+	data, k := testCredentials(t, server.URL+"/token")
+	key = k
+	creds, err := ParseCredentials(data)
+	if err != nil {
+		t.Fatalf("ParseCredentials failed: %v", err)
+	}
+
+	tempFile := "test_sheets_sync.db"
+	defer os.Remove(tempFile)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(tempFile, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQLiteController: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2025, 9, 20, 12, 0, 0, 0, time.UTC)
+	if err := db.InsertLog(database.LogSize{Timestamp: now.Add(-time.Hour), Filesize: 2_000_000_000, RecordCount: 10, Tenant: "acme"}); err != nil {
+		t.Fatalf("InsertLog failed: %v", err)
+	}
+	doc := config.NewDocument()
+	doc.PricingModels = []config.PricingModel{{Name: "r2", Currency: "USD", PerGB: 0.015, Default: true}}
+	if err := config.Import(db, doc); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	client := NewClient(creds)
+	client.BaseURL = server.URL
+	settings := Settings{SpreadsheetID: "sheet-id", Credentials: creds, Reports: []string{ReportMonthlyTotals, ReportChargeback}}
+	if err := Sync(context.Background(), client, db, settings, now); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	if tokens != 1 {
+		t.Errorf("Expected the access token to be reused, got %d token requests", tokens)
+	}
+	if len(added) != 1 || added[0] != ReportChargeback {
+		t.Errorf("Expected only the chargeback tab to be added, got %v", added)
+	}
+	monthly := written["'monthly_totals'"]
+	if len(monthly) != monthlyTotalsMonths+1 {
+		t.Fatalf("Expected a header and %d months, got %v", monthlyTotalsMonths, monthly)
+	}
+	if last := monthly[len(monthly)-1]; last[0] != "2025-09" || last[3] != float64(2_000_000_000) || last[4] != 0.03 {
+		t.Errorf("Unexpected current month row %v", last)
+	}
+	if first := monthly[1]; first[0] != "2024-10" || first[3] != float64(0) {
+		t.Errorf("Unexpected first month row %v", first)
+	}
+	chargeback := written["'chargeback'"]
+	if len(chargeback) != 2 || chargeback[1][1] != "acme" || chargeback[1][7] != 0.03 {
+		t.Errorf("Unexpected chargeback rows %v", chargeback)
+	}
+}
+
+func TestReplaceSheetReportsAPIErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant","error_description":"Invalid JWT Signature."}`))
+		}
+	}))
+	defer server.Close()
+
+	data, _ := testCredentials(t, server.URL+"/token")
+	creds, err := ParseCredentials(data)
+	if err != nil {
+		t.Fatalf("ParseCredentials failed: %v", err)
+	}
+	client := NewClient(creds)
+	client.BaseURL = server.URL
+	err = client.ReplaceSheet(context.Background(), "sheet-id", "monthly_totals", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest || apiErr.Message != "Invalid JWT Signature." {
+		t.Errorf("Expected the token error, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+)
+
+// Datasets lists the datasets the simulator can generate records for.
+var Datasets = []string{
+	ingest.DatasetHTTPRequests,
+	ingest.DatasetFirewallEvents,
+	ingest.DatasetWorkersTraceEvents,
+}
+
+// httpRequestRecord is a trimmed http_requests record with the default
+// Logpush fields an estimate is most sensitive to.
+type httpRequestRecord struct {
+	ClientIP            string `json:"ClientIP"`
+	ClientRequestHost   string `json:"ClientRequestHost"`
+	ClientRequestMethod string `json:"ClientRequestMethod"`
+	ClientRequestURI    string `json:"ClientRequestURI"`
+	EdgeEndTimestamp    string `json:"EdgeEndTimestamp"`
+	EdgeResponseBytes   int    `json:"EdgeResponseBytes"`
+	EdgeResponseStatus  int    `json:"EdgeResponseStatus"`
+	EdgeStartTimestamp  string `json:"EdgeStartTimestamp"`
+	RayID               string `json:"RayID"`
+}
+
+// firewallEventRecord is a trimmed firewall_events record.
+type firewallEventRecord struct {
+	Action            string `json:"Action"`
+	ClientIP          string `json:"ClientIP"`
+	ClientRequestHost string `json:"ClientRequestHost"`
+	ClientRequestPath string `json:"ClientRequestPath"`
+	Datetime          string `json:"Datetime"`
+	RayID             string `json:"RayID"`
+	RuleID            string `json:"RuleID"`
+	Source            string `json:"Source"`
+}
+
+// workersTraceRecord is a trimmed workers_trace_events record.
+type workersTraceRecord struct {
+	CPUTimeMs        int    `json:"CPUTimeMs"`
+	EventTimestampMs int64  `json:"EventTimestampMs"`
+	EventType        string `json:"EventType"`
+	Outcome          string `json:"Outcome"`
+	ScriptName       string `json:"ScriptName"`
+	WallTimeMs       int    `json:"WallTimeMs"`
+}
+
+// Value pools, weighted by repetition.
+var (
+	statuses  = []int{200, 200, 200, 200, 200, 200, 204, 301, 304, 304, 404, 403, 429, 500, 502}
+	methods   = []string{"GET", "GET", "GET", "GET", "POST", "PUT", "HEAD"}
+	actions   = []string{"block", "challenge", "managed_challenge", "log", "skip"}
+	sources   = []string{"firewallrules", "waf", "ratelimit", "bic", "securitylevel"}
+	scripts   = []string{"api-router", "auth-edge", "image-resizer"}
+	outcomes  = []string{"ok", "ok", "ok", "ok", "exception", "exceededCpu", "canceled"}
+	hostNames = []string{"example.com", "www.example.com", "api.example.com"}
+)
+
+// Generator produces pseudo-random Logpush records for one dataset. The
+// same seed yields the same sequence of records apart from timestamps.
+type Generator struct {
+	dataset string
+	rng     *rand.Rand
+	now     func() time.Time
+}
+
+// NewGenerator creates a record generator.
+//
+// Parameters:
+//   - dataset: One of Datasets
+//   - seed: Seed for the record contents
+//
+// Returns:
+//   - *Generator: Record generator
+//   - error: If the dataset is not supported
+func NewGenerator(dataset string, seed int64) (*Generator, error) {
+	for _, d := range Datasets {
+		if d == dataset {
+			return &Generator{dataset: dataset, rng: rand.New(rand.NewSource(seed)), now: time.Now}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported dataset %q", dataset)
+}
+
+// Next returns one record as a JSON line without the trailing newline.
+func (g *Generator) Next() []byte {
+	now := g.now().UTC()
+	var record any
+	switch g.dataset {
+	case ingest.DatasetFirewallEvents:
+		record = firewallEventRecord{
+			Action:            pick(g.rng, actions),
+			ClientIP:          g.clientIP(),
+			ClientRequestHost: pick(g.rng, hostNames),
+			ClientRequestPath: g.path(),
+			Datetime:          now.Format(time.RFC3339),
+			RayID:             g.rayID(),
+			RuleID:            fmt.Sprintf("%032x", g.rng.Uint64()),
+			Source:            pick(g.rng, sources),
+		}
+	case ingest.DatasetWorkersTraceEvents:
+		cpu := g.rng.Intn(50)
+		record = workersTraceRecord{
+			CPUTimeMs:        cpu,
+			EventTimestampMs: now.UnixMilli(),
+			EventType:        "fetch",
+			Outcome:          pick(g.rng, outcomes),
+			ScriptName:       pick(g.rng, scripts),
+			WallTimeMs:       cpu + g.rng.Intn(500),
+		}
+	default:
+		start := now.Add(-time.Duration(g.rng.Intn(2000)) * time.Millisecond)
+		record = httpRequestRecord{
+			ClientIP:            g.clientIP(),
+			ClientRequestHost:   pick(g.rng, hostNames),
+			ClientRequestMethod: pick(g.rng, methods),
+			ClientRequestURI:    g.path(),
+			EdgeEndTimestamp:    now.Format(time.RFC3339Nano),
+			EdgeResponseBytes:   200 + g.rng.Intn(64*1024),
+			EdgeResponseStatus:  pick(g.rng, statuses),
+			EdgeStartTimestamp:  start.Format(time.RFC3339Nano),
+			RayID:               g.rayID(),
+		}
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		panic(err) // the record types always marshal
+	}
+	return line
+}
+
+// clientIP returns an address from the documentation ranges.
+func (g *Generator) clientIP() string {
+	return fmt.Sprintf("203.0.113.%d", g.rng.Intn(254)+1)
+}
+
+// path returns a request path of varying length.
+func (g *Generator) path() string {
+	return fmt.Sprintf("/api/v%d/items/%d?page=%d", g.rng.Intn(3)+1, g.rng.Intn(100000), g.rng.Intn(20))
+}
+
+// rayID returns a 16 hex digit Ray ID.
+func (g *Generator) rayID() string {
+	return fmt.Sprintf("%016x", g.rng.Uint64())
+}
+
+func pick[T any](rng *rand.Rand, values []T) T {
+	return values[rng.Intn(len(values))]
+}
@@ -0,0 +1,313 @@
+// Package simulator reproduces Cloudflare Logpush deliveries to an HTTP
+// destination, so a running estimator (or anything else listening for
+// Logpush) can be exercised without a Cloudflare account.
+//
+// Like Logpush, the simulator:
+//
+//   - validates the destination first with an ownership challenge, a gzip
+//     body holding {"content":"tests"} that must be answered with 2xx
+//   - collects records and uploads a batch when the push interval elapses
+//     or the batch reaches max_upload_records or max_upload_bytes
+//   - sends each batch as newline-delimited JSON compressed with gzip
+//     (Content-Encoding: gzip)
+//   - retries a failed upload with exponential backoff before dropping it
+//
+// Records are generated for the http_requests, firewall_events or
+// workers_trace_events dataset with plausible field values.
+//
+// # Usage
+//
+//	stats, err := simulator.Run(ctx, simulator.Config{
+//		URL:      "http://localhost:8080/ingest",
+//		Dataset:  "http_requests",
+//		Rate:     200,
+//		Duration: time.Minute,
+//	})
+//
+// The same is available from the command line:
+//
+//	logpush-estimator simulate -url http://localhost:8080/ingest -rate 200 -duration 1m
+package simulator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+)
+
+// Defaults matching Logpush's delivery settings.
+const (
+	DefaultRate             = 100                    // Records generated per second
+	DefaultInterval         = 30 * time.Second       // Longest wait between uploads
+	DefaultMaxUploadRecords = 100_000                // Records per batch before an early upload
+	DefaultMaxUploadBytes   = 5_000_000              // Uncompressed bytes per batch before an early upload
+	DefaultMaxRetries       = 5                      // Retries of a failed upload
+	DefaultRetryBackoff     = time.Second            // Wait before the first retry, doubled each time
+	DefaultTimeout          = 30 * time.Second       // Time allowed for one upload
+	generateTick            = 100 * time.Millisecond // How often records are generated
+)
+
+// OwnershipChallengeBody is the payload Logpush uploads, gzip-compressed, to
+// check that an HTTP destination accepts deliveries before a job is created.
+const OwnershipChallengeBody = `{"content":"tests"}`
+
+// Config controls a simulation. Zero values use the defaults above.
+type Config struct {
+	URL     string      // Destination URL, e.g. http://localhost:8080/ingest; required
+	Header  http.Header // Extra headers sent with every request, e.g. an auth header from destination_conf
+	Dataset string      // Dataset to generate (default http_requests)
+	Seed    int64       // Seed for record contents
+
+	Rate     float64       // Records generated per second
+	Duration time.Duration // How long to generate records; 0 runs until ctx is done
+
+	Interval         time.Duration // Longest wait between uploads
+	MaxUploadRecords int           // Records per batch before an early upload
+	MaxUploadBytes   int           // Uncompressed bytes per batch before an early upload
+
+	MaxRetries   int           // Retries of a failed upload; negative disables retries
+	RetryBackoff time.Duration // Wait before the first retry, doubled for each further retry
+
+	SkipOwnershipChallenge bool         // Start uploading without validating the destination
+	Client                 *http.Client // HTTP client (default one with DefaultTimeout)
+	Logger                 *slog.Logger // Progress logger (default discards)
+}
+
+// withDefaults returns c with zero values replaced by defaults.
+func (c Config) withDefaults() Config {
+	if c.Dataset == "" {
+		c.Dataset = ingest.DatasetHTTPRequests
+	}
+	if c.Rate <= 0 {
+		c.Rate = DefaultRate
+	}
+	if c.Interval <= 0 {
+		c.Interval = DefaultInterval
+	}
+	if c.MaxUploadRecords <= 0 {
+		c.MaxUploadRecords = DefaultMaxUploadRecords
+	}
+	if c.MaxUploadBytes <= 0 {
+		c.MaxUploadBytes = DefaultMaxUploadBytes
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = DefaultMaxRetries
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = DefaultRetryBackoff
+	}
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+	if c.Logger == nil {
+		c.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return c
+}
+
+// Stats summarizes a simulation.
+type Stats struct {
+	Batches           int64 `json:"batches"`            // Batches delivered
+	Records           int64 `json:"records"`            // Records in delivered batches
+	UncompressedBytes int64 `json:"uncompressed_bytes"` // NDJSON bytes in delivered batches
+	CompressedBytes   int64 `json:"compressed_bytes"`   // Bytes sent on the wire for delivered batches
+	Retries           int64 `json:"retries"`            // Upload attempts after a failure
+	FailedBatches     int64 `json:"failed_batches"`     // Batches dropped after exhausting retries
+	FailedRecords     int64 `json:"failed_records"`     // Records in dropped batches
+}
+
+// ErrRejected is returned by Deliver when the destination answers with a
+// status that retrying cannot fix (a 4xx other than 408 or 429).
+var ErrRejected = errors.New("upload rejected")
+
+// Run validates the destination, then generates records at cfg.Rate and
+// uploads them in batches until cfg.Duration has passed or ctx is done. The
+// last partial batch is uploaded before returning. Failed uploads are
+// counted in Stats rather than returned as errors.
+//
+// Parameters:
+//   - ctx: Stops the simulation early when cancelled
+//   - cfg: Simulation settings
+//
+// Returns:
+//   - Stats: What was delivered
+//   - error: If the configuration is invalid or the ownership challenge fails
+func Run(ctx context.Context, cfg Config) (Stats, error) {
+	var stats Stats
+	if cfg.URL == "" {
+		return stats, errors.New("simulator: Config.URL is required")
+	}
+	cfg = cfg.withDefaults()
+	gen, err := NewGenerator(cfg.Dataset, cfg.Seed)
+	if err != nil {
+		return stats, err
+	}
+
+	if !cfg.SkipOwnershipChallenge {
+		if err := OwnershipChallenge(ctx, cfg); err != nil {
+			return stats, fmt.Errorf("ownership challenge failed: %w", err)
+		}
+		cfg.Logger.Info("Ownership challenge accepted", "url", cfg.URL)
+	}
+
+	var stop <-chan time.Time
+	if cfg.Duration > 0 {
+		timer := time.NewTimer(cfg.Duration)
+		defer timer.Stop()
+		stop = timer.C
+	}
+	ticker := time.NewTicker(min(generateTick, cfg.Interval))
+	defer ticker.Stop()
+
+	var batch bytes.Buffer
+	var batchRecords int
+	var owed float64 // Records due but not yet generated
+	last, lastUpload := time.Now(), time.Now()
+
+	// Uploads ignore ctx's cancellation so the final batch is still sent
+	// when ctx ends the simulation.
+	upload := func() {
+		if batchRecords > 0 {
+			deliverBatch(context.WithoutCancel(ctx), cfg, batch.Bytes(), batchRecords, &stats)
+		}
+		batch.Reset()
+		batchRecords = 0
+		lastUpload = time.Now()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			upload()
+			return stats, nil
+		case <-stop:
+			upload()
+			return stats, nil
+		case now := <-ticker.C:
+			owed += now.Sub(last).Seconds() * cfg.Rate
+			last = now
+			for ; owed >= 1; owed-- {
+				batch.Write(gen.Next())
+				batch.WriteByte('\n')
+				batchRecords++
+				if batchRecords >= cfg.MaxUploadRecords || batch.Len() >= cfg.MaxUploadBytes {
+					upload()
+				}
+			}
+			if now.Sub(lastUpload) >= cfg.Interval {
+				upload()
+			}
+		}
+	}
+}
+
+// deliverBatch uploads one batch and records the outcome in stats.
+func deliverBatch(ctx context.Context, cfg Config, ndjson []byte, records int, stats *Stats) {
+	body := gzipBody(ndjson)
+	retries, err := Deliver(ctx, cfg, body)
+	stats.Retries += int64(retries)
+	if err != nil {
+		stats.FailedBatches++
+		stats.FailedRecords += int64(records)
+		cfg.Logger.Warn("Batch dropped", "records", records, "bytes", len(ndjson), "error", err)
+		return
+	}
+	stats.Batches++
+	stats.Records += int64(records)
+	stats.UncompressedBytes += int64(len(ndjson))
+	stats.CompressedBytes += int64(len(body))
+	cfg.Logger.Info("Batch delivered", "records", records, "bytes", len(ndjson), "compressed_bytes", len(body))
+}
+
+// OwnershipChallenge uploads the ownership challenge payload and reports
+// whether the destination accepted it. It is not retried.
+//
+// Parameters:
+//   - ctx: Request context
+//   - cfg: Destination, headers and client
+//
+// Returns:
+//   - error: If the destination could not be reached or answered non-2xx
+func OwnershipChallenge(ctx context.Context, cfg Config) error {
+	cfg.MaxRetries = -1
+	_, err := Deliver(ctx, cfg, gzipBody([]byte(OwnershipChallengeBody)))
+	return err
+}
+
+// Deliver POSTs a gzip-compressed body to cfg.URL, retrying network errors,
+// 408, 429 and 5xx responses up to cfg.MaxRetries times with exponential
+// backoff.
+//
+// Parameters:
+//   - ctx: Cancels waiting and in-flight requests
+//   - cfg: Destination, headers, client and retry policy
+//   - body: gzip-compressed NDJSON
+//
+// Returns:
+//   - int: Number of retries made
+//   - error: The last failure if the body was not accepted; wraps
+//     ErrRejected for statuses that are not retried
+func Deliver(ctx context.Context, cfg Config, body []byte) (int, error) {
+	cfg = cfg.withDefaults()
+	backoff := cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := post(ctx, cfg, body)
+		if err == nil || errors.Is(err, ErrRejected) || attempt >= cfg.MaxRetries {
+			return attempt, err
+		}
+		cfg.Logger.Warn("Upload failed, retrying", "error", err, "attempt", attempt+1, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post makes a single upload attempt.
+func post(ctx context.Context, cfg Config, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	for name, values := range cfg.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("User-Agent", "logpush-estimator-simulator")
+
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return fmt.Errorf("destination answered %s", resp.Status)
+	default:
+		return fmt.Errorf("%w: destination answered %s", ErrRejected, resp.Status)
+	}
+}
+
+// gzipBody compresses data as Logpush does.
+func gzipBody(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data) // writes to a bytes.Buffer cannot fail
+	zw.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,197 @@
+package simulator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/ingest"
+)
+
+// destination records the decompressed bodies it receives.
+type destination struct {
+	mu     sync.Mutex
+	bodies [][]byte
+	status []int // Statuses to answer with before answering 200
+}
+
+func (d *destination) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Encoding") != "gzip" {
+		http.Error(w, "expected gzip", http.StatusBadRequest)
+		return
+	}
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.status) > 0 {
+		status := d.status[0]
+		d.status = d.status[1:]
+		w.WriteHeader(status)
+		return
+	}
+	d.bodies = append(d.bodies, body)
+}
+
+func (d *destination) setStatus(status ...int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status = status
+}
+
+func (d *destination) received() [][]byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([][]byte(nil), d.bodies...)
+}
+
+func TestRunDeliversGzipBatches(t *testing.T) {
+	dest := &destination{}
+	server := httptest.NewServer(dest)
+	defer server.Close()
+
+	stats, err := Run(context.Background(), Config{
+		URL:      server.URL + "/ingest",
+		Rate:     500,
+		Duration: 450 * time.Millisecond,
+		Interval: 150 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	bodies := dest.received()
+	if len(bodies) < 3 {
+		t.Fatalf("Expected the ownership challenge and at least 2 batches, got %d requests", len(bodies))
+	}
+	if string(bodies[0]) != OwnershipChallengeBody {
+		t.Errorf("Expected the ownership challenge first, got %q", bodies[0])
+	}
+	var records, size int64
+	for _, body := range bodies[1:] {
+		records += int64(bytes.Count(body, []byte("\n")))
+		size += int64(len(body))
+	}
+	if stats.Batches != int64(len(bodies)-1) || stats.Records != records || stats.UncompressedBytes != size {
+		t.Errorf("Stats %+v do not match %d batches with %d records and %d bytes received", stats, len(bodies)-1, records, size)
+	}
+	if stats.Records < 150 || stats.Records > 250 {
+		t.Errorf("Expected about 225 records at 500/s for 450ms, got %d", stats.Records)
+	}
+	if stats.CompressedBytes <= 0 || stats.CompressedBytes >= stats.UncompressedBytes {
+		t.Errorf("Expected compressed bytes below %d, got %d", stats.UncompressedBytes, stats.CompressedBytes)
+	}
+}
+
+func TestRunSplitsBatchesAtUploadLimits(t *testing.T) {
+	dest := &destination{}
+	server := httptest.NewServer(dest)
+	defer server.Close()
+
+	stats, err := Run(context.Background(), Config{
+		URL:                    server.URL,
+		Rate:                   1000,
+		Duration:               250 * time.Millisecond,
+		MaxUploadRecords:       20,
+		SkipOwnershipChallenge: true,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for i, body := range dest.received() {
+		if n := bytes.Count(body, []byte("\n")); n > 20 {
+			t.Errorf("Batch %d holds %d records, above max_upload_records", i, n)
+		}
+	}
+	if stats.Batches < 5 {
+		t.Errorf("Expected early uploads at 20 records, got %d batches for %d records", stats.Batches, stats.Records)
+	}
+}
+
+func TestRunFailsOwnershipChallenge(t *testing.T) {
+	dest := &destination{status: []int{http.StatusForbidden}}
+	server := httptest.NewServer(dest)
+	defer server.Close()
+
+	_, err := Run(context.Background(), Config{URL: server.URL, Duration: time.Second})
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("Expected a rejected ownership challenge, got %v", err)
+	}
+	if len(dest.received()) != 0 {
+		t.Error("Expected no batches after a failed ownership challenge")
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	dest := &destination{status: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	server := httptest.NewServer(dest)
+	defer server.Close()
+
+	cfg := Config{URL: server.URL, RetryBackoff: time.Millisecond}
+	retries, err := Deliver(context.Background(), cfg, gzipBody([]byte("{}\n")))
+	if err != nil || retries != 2 {
+		t.Errorf("Expected delivery after 2 retries, got %d retries and %v", retries, err)
+	}
+
+	dest.setStatus(http.StatusBadRequest)
+	retries, err = Deliver(context.Background(), cfg, gzipBody([]byte("{}\n")))
+	if !errors.Is(err, ErrRejected) || retries != 0 {
+		t.Errorf("Expected a 400 to be rejected without retries, got %d retries and %v", retries, err)
+	}
+
+	dest.setStatus(500, 500, 500)
+	cfg.MaxRetries = 2
+	retries, err = Deliver(context.Background(), cfg, gzipBody([]byte("{}\n")))
+	if err == nil || retries != 2 {
+		t.Errorf("Expected failure after 2 retries, got %d retries and %v", retries, err)
+	}
+}
+
+func TestGeneratorDatasets(t *testing.T) {
+	for _, dataset := range Datasets {
+		gen, err := NewGenerator(dataset, 1)
+		if err != nil {
+			t.Fatalf("NewGenerator(%q): %v", dataset, err)
+		}
+		var batch bytes.Buffer
+		for i := 0; i < 50; i++ {
+			batch.Write(gen.Next())
+			batch.WriteByte('\n')
+		}
+		counts := ingest.ExtractDimensions("", batch.Bytes())
+		if len(counts) == 0 {
+			t.Errorf("%s: no dimensions extracted from generated records", dataset)
+		}
+		for _, c := range counts {
+			if c.Dataset != dataset {
+				t.Errorf("%s: record detected as %q", dataset, c.Dataset)
+			}
+		}
+	}
+
+	a, _ := NewGenerator(ingest.DatasetFirewallEvents, 7)
+	b, _ := NewGenerator(ingest.DatasetFirewallEvents, 7)
+	fixed := func() time.Time { return time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC) }
+	a.now, b.now = fixed, fixed
+	if !bytes.Equal(a.Next(), b.Next()) {
+		t.Error("Expected generators with the same seed to produce the same records")
+	}
+	if _, err := NewGenerator("dns_logs", 1); err == nil {
+		t.Error("Expected an unsupported dataset to be rejected")
+	}
+}
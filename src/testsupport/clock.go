@@ -0,0 +1,51 @@
+package testsupport
+
+import (
+	"sync"
+	"time"
+)
+
+// Epoch is the default seed for Clock: a fixed Monday at noon UTC, away
+// from day, month and DST boundaries.
+var Epoch = time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+// Clock is a deterministic clock for tests. It only moves when Advance or
+// Set is called, so handlers given its Now see the same time on every run.
+// It is safe for concurrent use.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock creates a clock reading seed.
+//
+// Parameters:
+//   - seed: Initial time, usually Epoch
+//
+// Returns:
+//   - *Clock: Stopped clock
+func NewClock(seed time.Time) *Clock {
+	return &Clock{now: seed}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d and returns the new time.
+func (c *Clock) Advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+// Set moves the clock to t.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
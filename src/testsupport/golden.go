@@ -0,0 +1,90 @@
+package testsupport
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update is set by go test -update to rewrite golden files with the
+// current output instead of comparing against them.
+var update = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+// AssertGoldenJSON compares a JSON document with the golden file
+// GoldenDir/<name>.json, failing the test with the first differing lines.
+// Both are indented the same way before comparison, so only content changes
+// are reported. With -update the golden file is written instead.
+//
+// Parameters:
+//   - t: Test to fail
+//   - name: Golden file name without extension
+//   - got: JSON document, e.g. a response body
+func AssertGoldenJSON(t testing.TB, name string, got []byte) {
+	t.Helper()
+	normalized, err := normalizeJSON(got)
+	if err != nil {
+		t.Fatalf("Response for golden file %s is not valid JSON: %v\n%s", name, err, got)
+	}
+
+	path := filepath.Join(GoldenDir, name+".json")
+	if *update {
+		if err := os.MkdirAll(GoldenDir, 0o755); err != nil {
+			t.Fatalf("Failed to create %s: %v", GoldenDir, err)
+		}
+		if err := os.WriteFile(path, normalized, 0o644); err != nil {
+			t.Fatalf("Failed to update golden file %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file %s (run with -update to create it): %v", path, err)
+	}
+	if want, err = normalizeJSON(want); err != nil {
+		t.Fatalf("Golden file %s is not valid JSON: %v", path, err)
+	}
+	if !bytes.Equal(normalized, want) {
+		t.Errorf("Response differs from golden file %s (run with -update if intended):\n%s", path, diffLines(string(want), string(normalized)))
+	}
+}
+
+// normalizeJSON re-indents a JSON document with two spaces and a trailing
+// newline.
+func normalizeJSON(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(data), "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// diffLines describes where want and got first differ, with the line
+// numbers and both versions of the differing lines.
+func diffLines(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	var b strings.Builder
+	shown := 0
+	for i := 0; i < max(len(wantLines), len(gotLines)) && shown < 10; i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w == g {
+			continue
+		}
+		fmt.Fprintf(&b, "line %d:\n  - %s\n  + %s\n", i+1, w, g)
+		shown++
+	}
+	return b.String()
+}
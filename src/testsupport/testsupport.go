@@ -0,0 +1,141 @@
+// Package testsupport provides fixtures, golden files and a deterministic
+// clock for LogpushEstimator's tests.
+//
+// API responses are checked against golden JSON files in the calling
+// package's testdata/golden directory, so a change to a response shows up as
+// a diff of the file rather than passing unnoticed by assertions on a few
+// fields. Log records are seeded from JSON fixtures in testdata/fixtures, with
+// timestamps relative to the clock, so the same fixture gives the same
+// responses on every run.
+//
+// # Usage
+//
+//	clock := testsupport.NewClock(testsupport.Epoch)
+//	handlers.SetClock(clock)
+//	defer handlers.SetClock(nil)
+//
+//	db := testsupport.NewDatabase(t)
+//	testsupport.SeedLogs(t, db, testsupport.LoadLogs(t, "logs.json", clock.Now()))
+//
+//	rec := httptest.NewRecorder()
+//	handler(rec, httptest.NewRequest(http.MethodGet, "/api/stats/summary", nil))
+//	testsupport.AssertGoldenJSON(t, "summary", rec.Body.Bytes())
+//
+// Run the tests with -update to rewrite golden files after an intended
+// change, and review the diff before committing it:
+//
+//	go test ./src/gui/handlers -run Golden -update
+package testsupport
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/melatonein5/LogpushEstimator/src/database"
+)
+
+// FixturesDir and GoldenDir are where fixtures and golden files are read
+// from, relative to the package under test.
+const (
+	FixturesDir = "testdata/fixtures"
+	GoldenDir   = "testdata/golden"
+)
+
+// LoadFixture returns the contents of a fixture file, failing the test if it
+// cannot be read.
+//
+// Parameters:
+//   - t: Test to fail
+//   - name: File name below FixturesDir
+//
+// Returns:
+//   - []byte: File contents
+func LoadFixture(t testing.TB, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(FixturesDir, name))
+	if err != nil {
+		t.Fatalf("Failed to load fixture %s: %v", name, err)
+	}
+	return data
+}
+
+// LogFixture is a log record as written in a fixture file. Timestamps are
+// given as an offset before the clock's current time, e.g. "90m", so
+// fixtures stay inside the windows handlers compute from now.
+type LogFixture struct {
+	Ago         string `json:"ago"`          // Duration before now, e.g. "2h30m"
+	Filesize    int64  `json:"filesize"`     // Batch size in bytes
+	RecordCount int64  `json:"record_count"` // Records in the batch
+	Tenant      string `json:"tenant"`       // Tenant, empty for the default tenant
+}
+
+// LoadLogs reads a fixture file holding a JSON array of LogFixture and
+// returns the records with timestamps relative to now.
+//
+// Parameters:
+//   - t: Test to fail
+//   - name: File name below FixturesDir
+//   - now: Time the offsets are measured back from
+//
+// Returns:
+//   - []database.LogSize: Records in fixture order
+func LoadLogs(t testing.TB, name string, now time.Time) []database.LogSize {
+	t.Helper()
+	var fixtures []LogFixture
+	if err := json.Unmarshal(LoadFixture(t, name), &fixtures); err != nil {
+		t.Fatalf("Invalid log fixture %s: %v", name, err)
+	}
+	logs := make([]database.LogSize, 0, len(fixtures))
+	for i, f := range fixtures {
+		ago, err := time.ParseDuration(f.Ago)
+		if err != nil {
+			t.Fatalf("Invalid ago %q in log fixture %s entry %d: %v", f.Ago, name, i, err)
+		}
+		logs = append(logs, database.LogSize{
+			Timestamp:   now.Add(-ago).UTC(),
+			Filesize:    f.Filesize,
+			RecordCount: f.RecordCount,
+			Tenant:      f.Tenant,
+		})
+	}
+	return logs
+}
+
+// NewDatabase opens an empty database in the test's temporary directory and
+// closes it when the test ends.
+//
+// Parameters:
+//   - t: Test owning the database
+//
+// Returns:
+//   - *database.SQLiteController: Open database
+func NewDatabase(t testing.TB) *database.SQLiteController {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := database.NewSQLiteController(filepath.Join(t.TempDir(), "test.db"), logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// SeedLogs inserts records into db in order, so their IDs follow the
+// fixture order.
+//
+// Parameters:
+//   - t: Test to fail
+//   - db: Database to insert into
+//   - logs: Records to insert
+func SeedLogs(t testing.TB, db *database.SQLiteController, logs []database.LogSize) {
+	t.Helper()
+	for _, l := range logs {
+		if err := db.InsertLog(l); err != nil {
+			t.Fatalf("Failed to seed log record: %v", err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package testsupport
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	clock := NewClock(Epoch)
+	if !clock.Now().Equal(Epoch) {
+		t.Errorf("Expected %v, got %v", Epoch, clock.Now())
+	}
+	if got := clock.Advance(90 * time.Minute); !got.Equal(Epoch.Add(90*time.Minute)) || !clock.Now().Equal(got) {
+		t.Errorf("Expected clock to advance 90m, got %v", got)
+	}
+	clock.Set(Epoch)
+	if !clock.Now().Equal(Epoch) {
+		t.Errorf("Expected Set to move the clock back, got %v", clock.Now())
+	}
+}
+
+func TestLoadLogsAndSeed(t *testing.T) {
+	logs := LoadLogs(t, "logs.json", Epoch)
+	if len(logs) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(logs))
+	}
+	if !logs[0].Timestamp.Equal(Epoch.Add(-2*time.Hour)) || logs[0].Filesize != 1024 || logs[0].RecordCount != 4 {
+		t.Errorf("Unexpected first record %+v", logs[0])
+	}
+	if !logs[1].Timestamp.Equal(Epoch) || logs[1].Tenant != "acme" {
+		t.Errorf("Unexpected second record %+v", logs[1])
+	}
+
+	db := NewDatabase(t)
+	SeedLogs(t, db, logs)
+	stored, err := db.QueryByTimeRange(Epoch.Add(-3*time.Hour), Epoch.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 || stored[0].ID != 1 || stored[1].Tenant != "acme" {
+		t.Errorf("Unexpected stored records %+v", stored)
+	}
+}
+
+func TestAssertGoldenJSONIgnoresFormatting(t *testing.T) {
+	AssertGoldenJSON(t, "compact", []byte(`{"success": true, "data": {"count": 2}}`))
+}
+
+func TestDiffLines(t *testing.T) {
+	diff := diffLines("{\n  \"count\": 2\n}\n", "{\n  \"count\": 3\n}\n")
+	if !strings.Contains(diff, "line 2:") || !strings.Contains(diff, `-   "count": 2`) || !strings.Contains(diff, `+   "count": 3`) {
+		t.Errorf("Unexpected diff:\n%s", diff)
+	}
+	if diff := diffLines("same\n", "same\n"); diff != "" {
+		t.Errorf("Expected no diff, got %q", diff)
+	}
+}
@@ -0,0 +1,667 @@
+package yamldoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// line is one non-blank line of a document, without its comment.
+type line struct {
+	number int    // 1-based line number, for errors
+	indent int    // Leading spaces
+	text   string // Content after the indentation
+	tab    bool   // Indentation contains a tab, which only flow lines allow
+}
+
+// parser reads the block structure of a document line by line.
+type parser struct {
+	lines []line
+	pos   int
+}
+
+// Unmarshal reads a YAML document into v as encoding/json would read the
+// equivalent JSON document, so v's json struct tags and Unmarshal methods
+// apply. Unknown fields are rejected.
+//
+// Parameters:
+//   - data: YAML (or JSON) document
+//   - v: Pointer to the value to fill
+//
+// Returns:
+//   - error: A syntax error naming the line, or any error from encoding/json
+func Unmarshal(data []byte, v any) error {
+	doc, err := ToJSON(data)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// ToJSON converts a YAML document to the equivalent JSON document.
+//
+// Parameters:
+//   - data: YAML (or JSON) document
+//
+// Returns:
+//   - []byte: JSON document
+//   - error: A syntax error naming the line, or an unsupported feature
+func ToJSON(data []byte) ([]byte, error) {
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("document is not valid UTF-8")
+	}
+	lines, err := splitLines(strings.TrimPrefix(string(data), "\uFEFF"))
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("document is empty")
+	}
+	p := &parser{lines: lines}
+	root, err := p.node(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		return nil, fmt.Errorf("line %d: unexpected content %q", l.number, l.text)
+	}
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, root); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// splitLines strips comments, blank lines and the optional document markers,
+// and measures each line's indentation.
+func splitLines(doc string) ([]line, error) {
+	var lines []line
+	for i, raw := range strings.Split(doc, "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		text := strings.TrimLeft(raw, " ")
+		indent := len(raw) - len(text)
+		tab := strings.HasPrefix(text, "\t")
+		text = strings.TrimLeft(text, " \t")
+		text = strings.TrimRight(stripComment(text), " \t")
+		if text == "" {
+			continue
+		}
+		if indent == 0 && (text == "---" || text == "...") {
+			if text == "---" && len(lines) > 0 {
+				return nil, fmt.Errorf("line %d: only one document is supported", i+1)
+			}
+			continue
+		}
+		if strings.HasPrefix(text, "%") && indent == 0 {
+			return nil, fmt.Errorf("line %d: directives are not supported", i+1)
+		}
+		lines = append(lines, line{number: i + 1, indent: indent, text: text, tab: tab})
+	}
+	return lines, nil
+}
+
+// stripComment removes a trailing comment: a # at the start of the text or
+// after whitespace, outside quotes.
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.ContainsRune(" \t[{,:-", rune(text[i-1])) {
+				quote = c
+			}
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// node reads the block node starting at the current line, whose indentation
+// must be at least min.
+func (p *parser) node(min int) (any, error) {
+	l := p.lines[p.pos]
+	if l.indent < min {
+		return nil, nil
+	}
+	if l.tab {
+		return nil, errTab(l)
+	}
+	switch {
+	case isDash(l.text):
+		return p.sequence(l.indent)
+	case strings.HasPrefix(l.text, "[") || strings.HasPrefix(l.text, "{"):
+		return p.flowValue()
+	}
+	if _, _, ok, err := splitEntry(l); err != nil {
+		return nil, err
+	} else if ok {
+		return p.mapping(l.indent)
+	}
+	p.pos++
+	return parseScalar(l.text, l.number)
+}
+
+// errTab reports a tab in the indentation of a block line.
+func errTab(l line) error {
+	return fmt.Errorf("line %d: tabs cannot be used for indentation", l.number)
+}
+
+// isDash reports whether text is a block sequence entry.
+func isDash(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// sequence reads the block sequence whose dashes are indented by indent.
+func (p *parser) sequence(indent int) (any, error) {
+	items := []any{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.tab {
+			return nil, errTab(l)
+		}
+		if l.indent != indent || !isDash(l.text) {
+			if l.indent > indent {
+				return nil, fmt.Errorf("line %d: bad indentation", l.number)
+			}
+			break
+		}
+		rest := strings.TrimLeft(l.text[1:], " ")
+		if rest == "" {
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				item, err := p.node(indent + 1)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			} else {
+				items = append(items, nil)
+			}
+			continue
+		}
+		// A collection on the line of its dash continues at the column
+		// its first entry starts in
+		p.lines[p.pos] = line{number: l.number, indent: indent + len(l.text) - len(rest), text: rest}
+		item, err := p.node(indent + 1)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// mapping reads the block mapping whose keys are indented by indent.
+func (p *parser) mapping(indent int) (any, error) {
+	o := newObject()
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.tab {
+			return nil, errTab(l)
+		}
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("line %d: bad indentation", l.number)
+		}
+		if isDash(l.text) {
+			break
+		}
+		key, rest, ok, err := splitEntry(l)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a key followed by a colon, got %q", l.number, l.text)
+		}
+		var value any
+		if rest != "" {
+			p.lines[p.pos] = line{number: l.number, indent: indent + 1, text: rest}
+			if value, err = p.inlineValue(); err != nil {
+				return nil, err
+			}
+		} else {
+			p.pos++
+			if p.pos < len(p.lines) {
+				next := p.lines[p.pos]
+				// A sequence may sit at the indentation of its key
+				if next.indent > indent || (next.indent == indent && isDash(next.text)) {
+					if value, err = p.node(indent); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		if err := o.set(key, value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", l.number, err)
+		}
+	}
+	return o, nil
+}
+
+// inlineValue reads the value following a key on the same line: a flow
+// collection, which may continue on later lines, or a scalar.
+func (p *parser) inlineValue() (any, error) {
+	l := p.lines[p.pos]
+	if strings.HasPrefix(l.text, "[") || strings.HasPrefix(l.text, "{") {
+		return p.flowValue()
+	}
+	if strings.HasPrefix(l.text, "- ") {
+		return nil, fmt.Errorf("line %d: a sequence must start on the line after its key", l.number)
+	}
+	p.pos++
+	return parseScalar(l.text, l.number)
+}
+
+// flowValue reads a flow collection starting at the current line, joining
+// following lines until it is closed.
+func (p *parser) flowValue() (any, error) {
+	start := p.lines[p.pos]
+	text := start.text
+	p.pos++
+	for !flowClosed(text) {
+		if p.pos >= len(p.lines) {
+			return nil, fmt.Errorf("line %d: unterminated flow collection", start.number)
+		}
+		text += " " + p.lines[p.pos].text
+		p.pos++
+	}
+	f := &flow{text: text, line: start.number}
+	value, err := f.value()
+	if err != nil {
+		return nil, err
+	}
+	f.skipSpace()
+	if f.pos < len(f.text) {
+		return nil, fmt.Errorf("line %d: unexpected %q after flow collection", start.number, f.text[f.pos:])
+	}
+	return value, nil
+}
+
+// flowClosed reports whether every bracket opened in text is closed.
+func flowClosed(text string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.ContainsRune(" \t[{,:", rune(text[i-1])) {
+				quote = c
+			}
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0 && quote == 0
+}
+
+// splitEntry splits a block mapping entry into its key and the text after
+// the colon; ok is false if l is not an entry.
+func splitEntry(l line) (key, rest string, ok bool, err error) {
+	text := l.text
+	if text[0] == '"' || text[0] == '\'' {
+		end := quotedEnd(text)
+		if end < 0 {
+			return "", "", false, fmt.Errorf("line %d: unterminated quoted string", l.number)
+		}
+		after := strings.TrimLeft(text[end:], " ")
+		if !strings.HasPrefix(after, ":") || (len(after) > 1 && after[1] != ' ') {
+			return "", "", false, nil
+		}
+		key, err := unquote(text[:end], l.number)
+		if err != nil {
+			return "", "", false, err
+		}
+		return key, strings.TrimSpace(after[1:]), true, nil
+	}
+	if strings.ContainsRune("[{&*!|>%@`", rune(text[0])) {
+		if text[0] == '&' || text[0] == '*' || text[0] == '!' || text[0] == '|' || text[0] == '>' {
+			return "", "", false, fmt.Errorf("line %d: anchors, aliases, tags and block scalars are not supported", l.number)
+		}
+		return "", "", false, nil
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return strings.TrimRight(text[:i], " "), strings.TrimSpace(text[i+1:]), true, nil
+		}
+	}
+	return "", "", false, nil
+}
+
+// quotedEnd returns the index just past the quoted string text starts with,
+// or -1 if it is not terminated.
+func quotedEnd(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote:
+			if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// parseScalar resolves a single-line block scalar.
+func parseScalar(text string, number int) (any, error) {
+	switch text[0] {
+	case '"', '\'':
+		end := quotedEnd(text)
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: unterminated quoted string", number)
+		}
+		if end != len(text) {
+			return nil, fmt.Errorf("line %d: unexpected %q after quoted string", number, text[end:])
+		}
+		return unquote(text, number)
+	case '&', '*', '!', '|', '>':
+		return nil, fmt.Errorf("line %d: anchors, aliases, tags and block scalars are not supported", number)
+	case '@', '`', '%':
+		return nil, fmt.Errorf("line %d: %q cannot start a plain scalar", number, text[0])
+	}
+	if strings.Contains(text, ": ") {
+		return nil, fmt.Errorf("line %d: unexpected mapping in %q", number, text)
+	}
+	return resolvePlain(text), nil
+}
+
+// unquote decodes a single- or double-quoted scalar, quotes included.
+func unquote(text string, number int) (string, error) {
+	body := text[1 : len(text)-1]
+	if text[0] == '\'' {
+		return strings.ReplaceAll(body, "''", "'"), nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(body) {
+			return "", fmt.Errorf("line %d: unterminated escape", number)
+		}
+		switch body[i] {
+		case '0':
+			b.WriteByte(0)
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 't', '\t':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'v':
+			b.WriteByte('\v')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case 'e':
+			b.WriteByte(0x1b)
+		case ' ', '"', '/', '\\':
+			b.WriteByte(body[i])
+		case 'x', 'u', 'U':
+			size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[body[i]]
+			if i+size >= len(body) {
+				return "", fmt.Errorf("line %d: short \\%c escape", number, body[i])
+			}
+			code, err := strconv.ParseUint(body[i+1:i+1+size], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("line %d: bad \\%c escape", number, body[i])
+			}
+			r := rune(code)
+			i += size
+			// JSON encodes characters outside the BMP as surrogate pairs
+			if r >= 0xD800 && r < 0xDC00 && i+7 <= len(body) && strings.HasPrefix(body[i+1:], `\u`) {
+				if low, err := strconv.ParseUint(body[i+3:i+7], 16, 32); err == nil && low >= 0xDC00 && low < 0xE000 {
+					r = (r-0xD800)<<10 + (rune(low) - 0xDC00) + 0x10000
+					i += 6
+				}
+			}
+			b.WriteRune(r)
+		default:
+			return "", fmt.Errorf("line %d: unknown escape \\%c", number, body[i])
+		}
+	}
+	return b.String(), nil
+}
+
+var (
+	intPattern   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	floatPattern = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// resolvePlain types a plain scalar with the YAML 1.2 core schema: null,
+// bool, json.Number, float64 for infinities and NaN, or string.
+func resolvePlain(text string) any {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+	if intPattern.MatchString(text) {
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return json.Number(strconv.FormatInt(n, 10))
+		}
+	}
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0o") {
+		if n, err := strconv.ParseInt(text[2:], map[byte]int{'x': 16, 'o': 8}[text[1]], 64); err == nil {
+			return json.Number(strconv.FormatInt(n, 10))
+		}
+	}
+	if floatPattern.MatchString(text) {
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	}
+	return text
+}
+
+// flow reads a flow collection, which includes any JSON value.
+type flow struct {
+	text string
+	pos  int
+	line int
+}
+
+func (f *flow) skipSpace() {
+	for f.pos < len(f.text) && (f.text[f.pos] == ' ' || f.text[f.pos] == '\t') {
+		f.pos++
+	}
+}
+
+func (f *flow) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: "+format, append([]any{f.line}, args...)...)
+}
+
+// value reads a flow node.
+func (f *flow) value() (any, error) {
+	f.skipSpace()
+	if f.pos >= len(f.text) {
+		return nil, f.errorf("unexpected end of flow collection")
+	}
+	switch f.text[f.pos] {
+	case '[':
+		f.pos++
+		items := []any{}
+		for {
+			f.skipSpace()
+			if f.pos < len(f.text) && f.text[f.pos] == ']' {
+				f.pos++
+				return items, nil
+			}
+			item, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			if err := f.separator(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		f.pos++
+		o := newObject()
+		for {
+			f.skipSpace()
+			if f.pos < len(f.text) && f.text[f.pos] == '}' {
+				f.pos++
+				return o, nil
+			}
+			key, err := f.scalar(":,}")
+			if err != nil {
+				return nil, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				k = fmt.Sprint(key)
+				if n, isNumber := key.(json.Number); isNumber {
+					k = n.String()
+				}
+			}
+			f.skipSpace()
+			var value any
+			if f.pos < len(f.text) && f.text[f.pos] == ':' {
+				f.pos++
+				if value, err = f.value(); err != nil {
+					return nil, err
+				}
+			}
+			if err := o.set(k, value); err != nil {
+				return nil, f.errorf("%v", err)
+			}
+			if err := f.separator('}'); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return f.scalar(",]}")
+}
+
+// separator consumes the comma between entries, or leaves the closing
+// bracket for the caller.
+func (f *flow) separator(closing byte) error {
+	f.skipSpace()
+	if f.pos >= len(f.text) {
+		return f.errorf("unterminated flow collection")
+	}
+	switch f.text[f.pos] {
+	case ',':
+		f.pos++
+		return nil
+	case closing:
+		return nil
+	}
+	return f.errorf("expected ',' or %q, got %q", closing, f.text[f.pos:])
+}
+
+// scalar reads a quoted scalar, or a plain one ending before any of stops.
+func (f *flow) scalar(stops string) (any, error) {
+	f.skipSpace()
+	rest := f.text[f.pos:]
+	if rest == "" {
+		return nil, f.errorf("unexpected end of flow collection")
+	}
+	if rest[0] == '"' || rest[0] == '\'' {
+		end := quotedEnd(rest)
+		if end < 0 {
+			return nil, f.errorf("unterminated quoted string")
+		}
+		f.pos += end
+		return unquote(rest[:end], f.line)
+	}
+	if strings.ContainsRune("&*!|>@`%", rune(rest[0])) {
+		return nil, f.errorf("anchors, aliases, tags and block scalars are not supported")
+	}
+	end := strings.IndexAny(rest, stops)
+	if end < 0 {
+		end = len(rest)
+	}
+	f.pos += end
+	return resolvePlain(strings.TrimSpace(rest[:end])), nil
+}
+
+// writeJSON writes a parsed node as JSON.
+func writeJSON(buf *bytes.Buffer, node any) error {
+	switch node := node.(type) {
+	case *object:
+		buf.WriteByte('{')
+		for i, key := range node.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			k, _ := json.Marshal(key)
+			buf.Write(k)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, node.values[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range node {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case float64:
+		return fmt.Errorf("%v cannot be represented in JSON", node)
+	case nil, bool, json.Number, string:
+		out, err := json.Marshal(node)
+		if err != nil {
+			return err
+		}
+		buf.Write(out)
+	default:
+		return fmt.Errorf("unexpected value %v", node)
+	}
+	return nil
+}
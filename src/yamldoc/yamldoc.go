@@ -0,0 +1,234 @@
+// Package yamldoc converts JSON-encoded values to and from YAML documents,
+// so configuration can be exchanged as YAML without depending on a YAML
+// library.
+//
+// Marshal writes block-style YAML with keys in the order encoding/json emits
+// them. ToJSON reads the subset of YAML 1.2 such documents use: block and
+// flow mappings and sequences, plain, single- and double-quoted scalars, and
+// comments. JSON is flow-style YAML, so JSON documents are read as well.
+// Anchors, aliases, tags, block scalars (| and >) and multi-document streams
+// are rejected.
+//
+// # Usage
+//
+//	out, err := yamldoc.Marshal(doc)
+//	...
+//	err = yamldoc.Unmarshal(body, &doc)
+package yamldoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// object is a mapping that keeps its keys in document order.
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+// set adds key to o, failing if it is already present.
+func (o *object) set(key string, value any) error {
+	if _, ok := o.values[key]; ok {
+		return fmt.Errorf("duplicate key %q", key)
+	}
+	o.keys = append(o.keys, key)
+	o.values[key] = value
+	return nil
+}
+
+func newObject() *object {
+	return &object{values: map[string]any{}}
+}
+
+// Marshal encodes v with encoding/json and returns the result as a YAML
+// document.
+//
+// Parameters:
+//   - v: Value to encode
+//
+// Returns:
+//   - []byte: YAML document, ending in a newline
+//   - error: Any error encountered while encoding v as JSON
+func Marshal(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	tree, err := decodeJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	switch node := tree.(type) {
+	case *object:
+		if len(node.keys) > 0 {
+			lines = emitObject(node, 0)
+		} else {
+			lines = []string{"{}"}
+		}
+	case []any:
+		if len(node) > 0 {
+			lines = emitSequence(node, 0)
+		} else {
+			lines = []string{"[]"}
+		}
+	default:
+		lines = []string{scalar(node)}
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// decodeJSON reads one JSON value from dec, keeping object keys in order.
+func decodeJSON(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		o := newObject()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			if err := o.set(key.(string), value); err != nil {
+				return nil, err
+			}
+		}
+		_, err := dec.Token()
+		return o, err
+	case json.Delim('['):
+		items := []any{}
+		for dec.More() {
+			item, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		_, err := dec.Token()
+		return items, err
+	}
+	return tok, nil
+}
+
+// inline reports whether node is written on the line of its key or dash.
+func inline(node any) bool {
+	switch node := node.(type) {
+	case *object:
+		return len(node.keys) == 0
+	case []any:
+		return len(node) == 0
+	}
+	return true
+}
+
+// emitObject writes the entries of a non-empty mapping indented by indent
+// spaces.
+func emitObject(o *object, indent int) []string {
+	pad := strings.Repeat(" ", indent)
+	var lines []string
+	for _, key := range o.keys {
+		value := o.values[key]
+		if inline(value) {
+			lines = append(lines, pad+scalar(key)+": "+scalar(value))
+			continue
+		}
+		lines = append(lines, pad+scalar(key)+":")
+		if items, ok := value.([]any); ok {
+			lines = append(lines, emitSequence(items, indent+2)...)
+		} else {
+			lines = append(lines, emitObject(value.(*object), indent+2)...)
+		}
+	}
+	return lines
+}
+
+// emitSequence writes the items of a non-empty sequence indented by indent
+// spaces. Collections in a sequence start on the line of their dash.
+func emitSequence(items []any, indent int) []string {
+	pad := strings.Repeat(" ", indent)
+	var lines []string
+	for _, item := range items {
+		if inline(item) {
+			lines = append(lines, pad+"- "+scalar(item))
+			continue
+		}
+		var nested []string
+		if seq, ok := item.([]any); ok {
+			nested = emitSequence(seq, indent+2)
+		} else {
+			nested = emitObject(item.(*object), indent+2)
+		}
+		nested[0] = pad + "- " + nested[0][indent+2:]
+		lines = append(lines, nested...)
+	}
+	return lines
+}
+
+// plainString matches strings that can be written without quotes, provided
+// they do not also read as another type.
+var plainString = regexp.MustCompile(`^[A-Za-z0-9_./][A-Za-z0-9_./@+:() -]*$`)
+
+// scalar writes a JSON scalar or empty collection as a YAML flow node.
+func scalar(node any) string {
+	switch node := node.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(node)
+	case json.Number:
+		return node.String()
+	case string:
+		if plainString.MatchString(node) && !strings.HasSuffix(node, ":") && !strings.HasSuffix(node, " ") &&
+			!strings.Contains(node, ": ") && !ambiguous(node) {
+			return node
+		}
+		return quote(node)
+	case *object:
+		return "{}"
+	case []any:
+		return "[]"
+	}
+	return fmt.Sprint(node)
+}
+
+// quote writes s as a double-quoted scalar. JSON string escapes are valid
+// YAML escapes.
+func quote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// timestamp matches the dates and times YAML 1.1 resolves to timestamps.
+var timestamp = regexp.MustCompile(`^[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}`)
+
+// ambiguous reports whether the plain scalar s would not read back as a
+// string, including the YAML 1.1 booleans and timestamps older tools still
+// resolve.
+func ambiguous(s string) bool {
+	switch strings.ToLower(s) {
+	case "y", "n", "yes", "no", "on", "off":
+		return true
+	}
+	if timestamp.MatchString(s) {
+		return true
+	}
+	_, isString := resolvePlain(s).(string)
+	return !isString
+}
@@ -0,0 +1,159 @@
+package yamldoc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type tier struct {
+	UpToGB float64 `json:"up_to_gb"`
+	PerGB  float64 `json:"per_gb"`
+}
+
+type model struct {
+	Name    string            `json:"name"`
+	Default bool              `json:"default"`
+	Storage []tier            `json:"storage,omitempty"`
+	Tags    map[string]string `json:"tags,omitempty"`
+}
+
+type document struct {
+	APIVersion string     `json:"apiVersion"`
+	Models     []model    `json:"models"`
+	Scopes     [][]string `json:"scopes"`
+	Note       string     `json:"note"`
+	Until      *time.Time `json:"until"`
+	Empty      []string   `json:"empty"`
+	Count      int64      `json:"count"`
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	until := time.Date(2025, 9, 15, 10, 0, 0, 0, time.UTC)
+	doc := document{
+		APIVersion: "logpush-estimator/v1",
+		Models: []model{
+			{Name: "R2 (EU) storage", Default: true, Storage: []tier{{UpToGB: 10, PerGB: 0}, {PerGB: 0.015}}},
+			{Name: "yes", Tags: map[string]string{"env": "prod: eu", "n": "42"}},
+		},
+		Scopes: [][]string{{"ingest", "admin"}, {}},
+		Note:   "line one\nsays \"hi\" # not a comment <b>",
+		Until:  &until,
+		Empty:  []string{},
+		Count:  1 << 40,
+	}
+	out, err := Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `apiVersion: logpush-estimator/v1
+models:
+  - name: R2 (EU) storage
+    default: true
+    storage:
+      - up_to_gb: 10
+        per_gb: 0
+      - up_to_gb: 0
+        per_gb: 0.015
+  - name: "yes"
+    default: false
+    tags:
+      env: "prod: eu"
+      "n": "42"
+scopes:
+  - - ingest
+    - admin
+  - []
+note: "line one\nsays \"hi\" # not a comment <b>"
+until: "2025-09-15T10:00:00Z"
+empty: []
+count: 1099511627776
+`
+	if string(out) != want {
+		t.Errorf("Unexpected YAML:\n%s", out)
+	}
+
+	var back document
+	if err := Unmarshal(out, &back); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(back, doc) {
+		t.Errorf("Round trip changed the document:\n%+v\n%+v", back, doc)
+	}
+}
+
+func TestUnmarshalHandWritten(t *testing.T) {
+	input := `# Pricing for GitOps
+---
+apiVersion: 'logpush-estimator/v1'   # quoted
+models:
+- name: r2
+  storage: [{up_to_gb: 10, per_gb: 0}, {per_gb: .015}]
+- name: "s3"
+  tags: {env: prod, "team": 'it''s'}
+scopes:
+  -
+    - ingest
+  - [admin,
+     ingest]
+note: plain text with a # comment
+until: ~
+count: 0x10
+`
+	want := document{
+		APIVersion: "logpush-estimator/v1",
+		Models:     []model{{Name: "r2", Storage: []tier{{UpToGB: 10}, {PerGB: 0.015}}}, {Name: "s3", Tags: map[string]string{"env": "prod", "team": "it's"}}},
+		Scopes:     [][]string{{"ingest"}, {"admin", "ingest"}},
+		Note:       "plain text with a",
+		Count:      16,
+	}
+	var doc document
+	if err := Unmarshal([]byte(input), &doc); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("Unexpected document:\n%+v\n%+v", doc, want)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	var doc document
+	err := Unmarshal([]byte(`{
+  "apiVersion": "v1",
+  "models": [{"name": "a#b", "default": true}],
+  "note": "é😀\t"
+}`), &doc)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if doc.APIVersion != "v1" || len(doc.Models) != 1 || doc.Models[0].Name != "a#b" || !doc.Models[0].Default || doc.Note != "é😀\t" {
+		t.Errorf("Unexpected document: %+v", doc)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "# nothing\n", "empty"},
+		{"tab indentation", "models:\n\t- name: a\n", "tabs"},
+		{"duplicate key", "note: a\nnote: b\n", "duplicate key"},
+		{"anchor", "note: &x a\n", "not supported"},
+		{"block scalar", "note: |\n  text\n", "not supported"},
+		{"bad indentation", "note: a\n  count: 1\n", "bad indentation"},
+		{"unterminated flow", "scopes: [a, b\n", "unterminated"},
+		{"unterminated quote", "note: \"a\n", "unterminated"},
+		{"second document", "note: a\n---\nnote: b\n", "one document"},
+		{"unknown field", "colour: blue\n", "unknown field"},
+		{"infinity", "count: .inf\n", "cannot be represented"},
+	} {
+		var doc document
+		err := Unmarshal([]byte(c.input), &doc)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", c.name, c.want, err)
+		}
+	}
+}